@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/alecanutto/fclx/chat-service/configs"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/storage"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/backupchats"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/restorechats"
+)
+
+// backupCommand backs the chats of the namespaces up every BACKUP_INTERVAL
+// until interrupted, or once.
+func backupCommand(args []string) int {
+	cfg, err := configs.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	once := flags.Bool("once", false, "run one backup and exit")
+	namespaces := flags.String("namespaces", cfg.Namespace, "comma separated namespaces to back up")
+	keep := flags.Int("keep", cfg.BackupKeep, "backups kept per namespace, 0 keeps them all")
+	flags.Parse(args)
+
+	if cfg.DatabaseURL == "" {
+		fmt.Fprintln(os.Stderr, "DATABASE_URL is not set")
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	clk := cfg.NewClock()
+	store, err := openChatStore(ctx, cfg, clk)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	defer store.close()
+	backups, err := newBackupStorage(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	uc := backupchats.NewBackupChatsUseCase(store.chats, backups, clk)
+	input := backupchats.BackupChatsInputDTO{Keep: *keep}
+	for _, ns := range strings.Split(*namespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			input.Namespaces = append(input.Namespaces, ns)
+		}
+	}
+	if *once {
+		if !runBackup(ctx, uc, input) {
+			return 1
+		}
+		return 0
+	}
+	ticker := clk.NewTicker(cfg.BackupInterval)
+	defer ticker.Stop()
+	for {
+		runBackup(ctx, uc, input)
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-ticker.C():
+		}
+	}
+}
+
+// runBackup runs a backup and prints its reports, it returns false when it
+// failed.
+func runBackup(ctx context.Context, uc *backupchats.BackupChatsUseCase, input backupchats.BackupChatsInputDTO) bool {
+	output, err := uc.Execute(ctx, input)
+	if output != nil {
+		for _, report := range output.Backups {
+			fmt.Printf("%s: backup %s of %d chats in %d parts, %d old backups deleted\n",
+				report.Namespace, report.BackupID, report.Chats, report.Parts, report.Pruned)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return false
+	}
+	return true
+}
+
+// restoreCommand restores the chats of a backup missing from its namespace.
+func restoreCommand(args []string) int {
+	cfg, err := configs.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	ns := flags.String("namespace", cfg.Namespace, "namespace to restore")
+	backupID := flags.String("backup", "", "id of the backup to restore, the latest by default")
+	flags.Parse(args)
+
+	if cfg.DatabaseURL == "" {
+		fmt.Fprintln(os.Stderr, "DATABASE_URL is not set")
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	store, err := openChatStore(ctx, cfg, cfg.NewClock())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	defer store.close()
+	backups, err := newBackupStorage(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	if err := namespace.Validate(*ns); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	if err := store.prepare(namespace.NewContext(ctx, *ns)); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	uc := restorechats.NewRestoreChatsUseCase(store.chats, store.annotations, store.attachments, backups)
+	output, err := uc.Execute(ctx, restorechats.RestoreChatsInputDTO{Namespace: *ns, BackupID: *backupID})
+	if output != nil {
+		fmt.Printf("%s: restored %d chats of backup %s, %d already there\n", *ns, output.Restored, output.BackupID, output.Skipped)
+		for _, failed := range output.Failed {
+			fmt.Fprintf(os.Stderr, "chat %s: %s\n", failed.ChatID, failed.Reason)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	if len(output.Failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func newBackupStorage(cfg *configs.Config) (gateway.FileStorage, error) {
+	if cfg.BackupStorage == "local" {
+		local, err := storage.NewLocalStorage(cfg.BackupDir)
+		if err != nil {
+			return nil, err
+		}
+		return local, nil
+	}
+	if cfg.BackupS3.Bucket == "" {
+		return nil, fmt.Errorf("BACKUP_S3_BUCKET is not set")
+	}
+	s3, err := storage.NewS3Storage(cfg.BackupS3, nil, clock.Real())
+	if err != nil {
+		return nil, err
+	}
+	return s3, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/alecanutto/fclx/chat-service/configs"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/chatcache"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/encryption"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/dynamodb"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/mongodb"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/postgres"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/sqlite"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/sqlrepo"
+)
+
+// chatStore holds the chat gateways of the configured database, not scoped
+// to a namespace, for the commands working across namespaces.
+type chatStore struct {
+	chats           gateway.ChatGateway
+	annotations     gateway.AnnotationGateway
+	attachments     gateway.AttachmentGateway
+	apiKeys         gateway.APIKeyGateway
+	webhooks        gateway.WebhookGateway
+	idempotencyKeys gateway.IdempotencyKeyGateway
+	usage           gateway.UsageGateway
+	auditLog        gateway.AuditLogGateway
+	drafts          gateway.DraftGateway
+	search          gateway.SearchGateway
+	chatSummaries   gateway.ChatReadModel
+	userData        gateway.UserDataGateway
+	outbox          gateway.OutboxGateway
+	retention       gateway.RetentionGateway
+	// unitOfWork commits the calls made in it to the repositories together
+	unitOfWork gateway.UnitOfWork
+	cipher     *encryption.Cipher
+	close      func()
+}
+
+func openChatStore(ctx context.Context, cfg *configs.Config, clk clock.Clock) (*chatStore, error) {
+	masterKey, err := newMasterKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+	store := &chatStore{}
+	switch cfg.DBDriver {
+	case "mongodb":
+		client, err := mongodb.Connect(ctx, cfg.DatabaseURL)
+		if err != nil {
+			return nil, err
+		}
+		db := client.Database(cfg.MongoDatabase)
+		opts := []mongodb.ChatRepositoryOption{mongodb.WithClock(clk)}
+		var cipher mongodb.ContentCipher
+		if masterKey != nil {
+			store.cipher = encryption.NewCipher(masterKey, mongodb.NewDataKeyRepository(db))
+			cipher = store.cipher
+			opts = append(opts, mongodb.WithContentCipher(store.cipher))
+		}
+		store.chats = mongodb.NewChatRepository(client, db, opts...)
+		store.annotations = mongodb.NewAnnotationRepository(db)
+		store.attachments = mongodb.NewAttachmentRepository(db)
+		store.apiKeys = mongodb.NewAPIKeyRepository(db)
+		store.webhooks = mongodb.NewWebhookRepository(db)
+		store.idempotencyKeys = mongodb.NewIdempotencyKeyRepository(db)
+		store.usage = mongodb.NewUsageRepository(db)
+		store.auditLog = mongodb.NewAuditLogRepository(db, cipher)
+		store.drafts = mongodb.NewDraftRepository(db)
+		store.search = mongodb.NewSearchRepository(db)
+		store.chatSummaries = mongodb.NewChatSummaryRepository(db)
+		store.userData = mongodb.NewUserDataRepository(client, db)
+		store.outbox = mongodb.NewOutboxRepository(db)
+		store.retention = mongodb.NewRetentionRepository(client, db)
+		store.unitOfWork = mongodb.NewUnitOfWork(client)
+		store.close = func() { client.Disconnect(context.Background()) }
+	case "dynamodb":
+		// the gateways DynamoDB doesn't implement stay on SQLite, the search,
+		// the exports and the idle retention only see the chats in it
+		if err := store.openSQL(ctx, "sqlite", cfg.DatabaseURL, masterKey, clk); err != nil {
+			return nil, err
+		}
+		client, err := dynamodb.NewClient(cfg.DynamoDB, nil, clock.Real())
+		if err != nil {
+			store.close()
+			return nil, err
+		}
+		opts := []dynamodb.ChatRepositoryOption{dynamodb.WithClock(clk)}
+		if store.cipher != nil {
+			opts = append(opts, dynamodb.WithContentCipher(store.cipher))
+		}
+		store.chats = dynamodb.NewChatRepository(client, opts...)
+		store.outbox = dynamodb.NewOutboxRepository(client)
+		// the writes to the table don't commit with those to SQLite
+		store.unitOfWork = nil
+	default:
+		if err := store.openSQL(ctx, cfg.DBDriver, cfg.DatabaseURL, masterKey, clk); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// openSQL opens the gateways of store on the postgres or sqlite database of
+// url.
+func (s *chatStore) openSQL(ctx context.Context, driver, url string, masterKey encryption.MasterKey, clk clock.Clock) error {
+	var db *sql.DB
+	var err error
+	if driver == "sqlite" {
+		db, err = sqlite.Open(ctx, url)
+	} else {
+		db, err = postgres.Open(url)
+	}
+	if err != nil {
+		return err
+	}
+	opts := []sqlrepo.ChatRepositoryOption{sqlrepo.WithClock(clk)}
+	var cipher sqlrepo.ContentCipher
+	if masterKey != nil {
+		s.cipher = encryption.NewCipher(masterKey, sqlrepo.NewDataKeyRepository(db))
+		cipher = s.cipher
+		opts = append(opts, sqlrepo.WithContentCipher(s.cipher))
+	}
+	s.chats = sqlrepo.NewChatRepository(db, opts...)
+	s.annotations = sqlrepo.NewAnnotationRepository(db)
+	s.attachments = sqlrepo.NewAttachmentRepository(db)
+	s.apiKeys = sqlrepo.NewAPIKeyRepository(db)
+	s.webhooks = sqlrepo.NewWebhookRepository(db)
+	s.idempotencyKeys = sqlrepo.NewIdempotencyKeyRepository(db)
+	s.usage = sqlrepo.NewUsageRepository(db)
+	s.auditLog = sqlrepo.NewAuditLogRepository(db, cipher)
+	s.drafts = sqlrepo.NewDraftRepository(db)
+	s.chatSummaries = sqlrepo.NewChatSummaryRepository(db)
+	s.search = sqlrepo.NewPostgresSearchRepository(db)
+	s.userData = sqlrepo.NewPostgresUserDataRepository(db)
+	s.outbox = sqlrepo.NewOutboxRepository(db)
+	s.retention = sqlrepo.NewRetentionRepository(db)
+	s.unitOfWork = sqlrepo.NewUnitOfWork(db)
+	if driver == "sqlite" {
+		s.search = sqlrepo.NewSQLiteSearchRepository(db)
+		s.userData = sqlrepo.NewSQLiteUserDataRepository(db)
+	}
+	s.close = func() { db.Close() }
+	return nil
+}
+
+// prepare creates the data key of the namespace of ctx before any write,
+// SQLite can't create it from inside the write transaction.
+func (s *chatStore) prepare(ctx context.Context) error {
+	if s.cipher == nil {
+		return nil
+	}
+	return s.cipher.Prepare(ctx)
+}
+
+// cacheOptions seal the cached chats with the cipher of the store, the cache
+// must not hold in the clear what the database holds encrypted.
+func (s *chatStore) cacheOptions() []chatcache.ChatGatewayOption {
+	if s.cipher == nil {
+		return nil
+	}
+	return []chatcache.ChatGatewayOption{chatcache.WithCipher(s.cipher)}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/alecanutto/fclx/chat-service/configs"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/chatcache"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/doctor"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/mongodb"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/postgres"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/sqlite"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/sqlrepo"
+)
+
+// databaseChecks returns what the doctor needs to check the configured
+// database. Both funcs are nil when there is nothing to check.
+func databaseChecks(cfg *configs.Config) (ping doctor.Pinger, schemaVersion func(ctx context.Context) (int, error), closeFunc func()) {
+	closeFunc = func() {}
+	if cfg.DatabaseURL == "" {
+		return nil, nil, closeFunc
+	}
+	switch cfg.DBDriver {
+	case "mongodb":
+		client, err := mongodb.Connect(context.Background(), cfg.DatabaseURL)
+		if err != nil {
+			return doctor.PingerFunc(func(ctx context.Context) error { return err }), nil, closeFunc
+		}
+		// MongoDB is schemaless, indexes are created on start
+		return doctor.PingerFunc(func(ctx context.Context) error { return client.Ping(ctx, nil) }), nil,
+			func() { client.Disconnect(context.Background()) }
+	default:
+		var db *sql.DB
+		var err error
+		// the other gateways of the dynamodb driver are on SQLite
+		if cfg.DBDriver == "sqlite" || cfg.DBDriver == "dynamodb" {
+			db, err = sqlite.Open(context.Background(), cfg.DatabaseURL)
+		} else {
+			db, err = postgres.Open(cfg.DatabaseURL)
+		}
+		if err != nil {
+			return doctor.PingerFunc(func(ctx context.Context) error { return err }), nil, closeFunc
+		}
+		return doctor.PingerFunc(db.PingContext), func(ctx context.Context) (int, error) {
+			return sqlrepo.CurrentSchemaVersion(ctx, db)
+		}, func() { db.Close() }
+	}
+}
+
+// cacheCheck returns the pinger of the chat cache, nil when it is disabled.
+func cacheCheck(cfg *configs.Config) (ping doctor.Pinger, closeFunc func()) {
+	closeFunc = func() {}
+	if cfg.RedisURL == "" {
+		return nil, closeFunc
+	}
+	client, err := chatcache.NewRedisClient(cfg.RedisURL)
+	if err != nil {
+		return doctor.PingerFunc(func(ctx context.Context) error { return err }), closeFunc
+	}
+	return chatcache.NewRedisStore(client), func() { client.Close() }
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/configs"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/doctor"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/encryption"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/sqlrepo"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/storage"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// doctorCommand checks the configuration end to end and exits non-zero when
+// something would keep the service from working.
+func doctorCommand(args []string) int {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of each check")
+	flags.Parse(args)
+
+	cfg, err := configs.Load()
+	if err != nil {
+		doctor.Print(os.Stdout, []doctor.Finding{{
+			Check:   "configuration",
+			Status:  doctor.StatusFailed,
+			Message: err.Error(),
+			Hint:    "fix the environment variable and run doctor again",
+		}})
+		return 1
+	}
+
+	var client *openai.Client
+	if cfg.OpenAIAPIKey != "" {
+		client = openai.NewClient(cfg.OpenAIAPIKey)
+	}
+	fileStorage, storageErr := newFileStorage(cfg)
+	masterKey, masterKeyErr := newMasterKey(cfg)
+	database, schemaVersion, closeDatabase := databaseChecks(cfg)
+	defer closeDatabase()
+	cache, closeCache := cacheCheck(cfg)
+	defer closeCache()
+
+	checks := []doctor.Check{
+		{Name: "configuration", Run: func(ctx context.Context) doctor.Finding {
+			if cfg.DatabaseURL == "" {
+				return doctor.Failed("DATABASE_URL is not set", "set it to the DSN of the "+cfg.DBDriver+" database")
+			}
+			if storageErr != nil {
+				return doctor.Failed(storageErr.Error(), "check the APP_STORAGE* and S3_* variables")
+			}
+			if cfg.Sandbox {
+				return doctor.Warning("sandbox mode is on, the clock can be advanced", "unset APP_SANDBOX outside of test environments")
+			}
+			return doctor.OK(fmt.Sprintf("namespace %q, %s database, %s storage", cfg.Namespace, cfg.DBDriver, cfg.Storage))
+		}},
+		doctor.ConnectivityCheck("database", database, "check DATABASE_URL and that the database accepts connections from this host"),
+		doctor.SchemaVersionCheck(schemaVersion, sqlrepo.SchemaVersion),
+		doctor.ConnectivityCheck("cache", cache, "check REDIS_URL, or unset it to run without the chat cache"),
+		// no broker backend is wired in this binary yet
+		doctor.ConnectivityCheck("broker", nil, ""),
+		doctor.OpenAICheck(client, cfg.Model),
+		doctor.TokenizerCheck(cfg.Model),
+		doctor.StorageCheck(fileStorage),
+		{Name: "encryption", Run: func(ctx context.Context) doctor.Finding {
+			if masterKeyErr != nil {
+				return doctor.Failed(masterKeyErr.Error(), "check APP_ENCRYPTION_KEY or the KMS_* variables")
+			}
+			if masterKey == nil {
+				return doctor.Skipped("message content is stored in clear")
+			}
+			// a round trip proves the key works, and on KMS the credentials
+			wrapped, err := masterKey.WrapKey(ctx, make([]byte, 32))
+			if err == nil {
+				_, err = masterKey.UnwrapKey(ctx, wrapped)
+			}
+			if err != nil {
+				return doctor.Failed(err.Error(), "check the master key and its permissions")
+			}
+			return doctor.OK(cfg.Encryption + " master key")
+		}},
+	}
+	findings := doctor.Run(context.Background(), *timeout, checks...)
+	doctor.Print(os.Stdout, findings)
+	if !doctor.Healthy(findings) {
+		return 1
+	}
+	return 0
+}
+
+// newMasterKey returns nil when encryption is off.
+func newMasterKey(cfg *configs.Config) (encryption.MasterKey, error) {
+	switch cfg.Encryption {
+	case "local":
+		return encryption.NewLocalMasterKey(cfg.EncryptionKey)
+	case "kms":
+		return encryption.NewKMSMasterKey(cfg.KMS, nil, clock.Real())
+	}
+	return nil, nil
+}
+
+func newFileStorage(cfg *configs.Config) (gateway.FileStorage, error) {
+	if cfg.Storage == "s3" {
+		s3, err := storage.NewS3Storage(cfg.S3, nil, clock.Real())
+		if err != nil {
+			return nil, err
+		}
+		return s3, nil
+	}
+	local, err := storage.NewLocalStorage(cfg.StorageDir)
+	if err != nil {
+		return nil, err
+	}
+	return local, nil
+}
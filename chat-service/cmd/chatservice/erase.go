@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alecanutto/fclx/chat-service/configs"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/chatcache"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/purgeuserdata"
+)
+
+// eraseCommand erases the data of a user of a namespace, for right to
+// erasure requests, and prints the deletion report.
+func eraseCommand(args []string) int {
+	cfg, err := configs.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	flags := flag.NewFlagSet("erase", flag.ExitOnError)
+	ns := flags.String("namespace", cfg.Namespace, "namespace of the user")
+	user := flags.String("user", "", "id of the user to erase")
+	confirm := flags.String("confirm", "", "the id of the user again, to confirm the erasure")
+	requestedBy := flags.String("requested-by", "", "who asked for the erasure, recorded with it")
+	flags.Parse(args)
+
+	if *requestedBy == "" {
+		fmt.Fprintln(os.Stderr, "-requested-by is not set")
+		return 1
+	}
+	if cfg.DatabaseURL == "" {
+		fmt.Fprintln(os.Stderr, "DATABASE_URL is not set")
+		return 1
+	}
+	if err := namespace.Validate(*ns); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	clk := cfg.NewClock()
+	store, err := openChatStore(ctx, cfg, clk)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	defer store.close()
+	fileStorage, err := newFileStorage(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	var userData gateway.UserDataGateway = store.userData
+	if cfg.RedisURL != "" {
+		client, err := chatcache.NewRedisClient(cfg.RedisURL)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+		defer client.Close()
+		userData = chatcache.NewUserDataGateway(userData, chatcache.NewRedisStore(client), func(err error) {
+			fmt.Fprintln(os.Stderr, err.Error())
+		})
+	}
+
+	uc := purgeuserdata.NewPurgeUserDataUseCase(namespace.NewUserDataGateway(userData, *ns), fileStorage, clk)
+	output, err := uc.Execute(ctx, purgeuserdata.PurgeUserDataInputDTO{
+		UserID:       *user,
+		Confirmation: *confirm,
+		RequestedBy:  *requestedBy,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	fmt.Printf("%s: erased user %s (erasure %s): %d chats, %d messages, %d annotations, %d attachments, %d embeddings, deleted %d files\n",
+		*ns, output.UserID, output.ErasureID, output.Chats, output.Messages, output.Annotations, output.Attachments,
+		output.Embeddings, output.FilesDeleted)
+	for _, key := range output.FailedFiles {
+		fmt.Fprintf(os.Stderr, "%s: could not delete file %s\n", *ns, key)
+	}
+	if len(output.FailedFiles) > 0 {
+		return 1
+	}
+	return 0
+}
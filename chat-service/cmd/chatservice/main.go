@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "doctor":
+		os.Exit(doctorCommand(os.Args[2:]))
+	case "erase":
+		os.Exit(eraseCommand(os.Args[2:]))
+	case "migrate":
+		os.Exit(migrateCommand(os.Args[2:]))
+	case "backup":
+		os.Exit(backupCommand(os.Args[2:]))
+	case "restore":
+		os.Exit(restoreCommand(os.Args[2:]))
+	case "retention":
+		os.Exit(retentionCommand(os.Args[2:]))
+	case "serve":
+		os.Exit(serveCommand(os.Args[2:]))
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: chatservice <command>")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  backup    back the chats up to the backup storage")
+	fmt.Fprintln(os.Stderr, "  doctor    validate the configuration and the dependencies")
+	fmt.Fprintln(os.Stderr, "  erase     erase the data of a user, for right to erasure requests")
+	fmt.Fprintln(os.Stderr, "  migrate   migrate the database schema to the version of this binary, or down")
+	fmt.Fprintln(os.Stderr, "  restore   restore the chats of a backup missing from the database")
+	fmt.Fprintln(os.Stderr, "  retention expire and purge the chats past the retention of their tenant")
+	fmt.Fprintln(os.Stderr, "  serve     serve the gRPC API")
+}
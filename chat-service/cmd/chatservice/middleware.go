@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/configs"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/errorreport"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/grpc/server"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/logging"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/middleware"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/oidc"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/requestid"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/apikey"
+)
+
+// middlewares are the cross-cutting concerns of the APIs, outermost first,
+// shared by every transport served. The calls authenticate with an API key,
+// a bearer token of the OIDC issuer or the AuthToken, tried in that order,
+// and are counted by metrics. Their panics are logged and reported to
+// reporter. The completions of an API key are of the tier of its tenant,
+// those of a token of the tier of its claim, else of its tenant.
+func middlewares(cfg *configs.Config, clk clock.Clock, apiKeys gateway.APIKeyGateway, metrics *middleware.Metrics, logger *logging.Logger, reporter *errorreport.Reporter) []middleware.Middleware {
+	authn := []middleware.Middleware{
+		middleware.APIKey(entity.APIKeyPrefix, authenticateAPIKey(apiKeys, cfg.TenantTiers), apiKeyScope),
+	}
+	auth := middleware.Auth(cfg.AuthToken)
+	if cfg.OIDCIssuer != "" {
+		authn = append(authn, middleware.BearerToken(verifyToken(cfg, clk)))
+		if cfg.AuthToken == "" {
+			auth = middleware.RequirePrincipal()
+		}
+	}
+	authn = append(authn, auth)
+	return []middleware.Middleware{
+		middleware.RequestID(),
+		middleware.Tracing(),
+		middleware.Logging(logger),
+		metrics.Middleware(),
+		middleware.Recovery(func(ctx context.Context, method string, recovered interface{}) {
+			logger.Error("panic", logging.String("method", method), logging.String("request_id", requestid.FromContext(ctx)), logging.String("panic", fmt.Sprint(recovered)))
+			reporter.CapturePanic(ctx, recovered, map[string]string{"method": method})
+		}),
+		middleware.Except(server.ReflectionPrefix, middleware.Chain(authn...)),
+	}
+}
+
+// verifyToken verifies the bearer tokens of the calls with the keys of the
+// configured issuer.
+func verifyToken(cfg *configs.Config, clk clock.Clock) func(ctx context.Context, token string) (*middleware.Principal, error) {
+	verifier := oidc.NewVerifier(cfg.OIDCIssuer, cfg.OIDCAudience, &http.Client{Timeout: 10 * time.Second}, clk)
+	verifier.RequiredScope = cfg.OIDCScope
+	verifier.OrgClaim = cfg.OIDCOrgClaim
+	verifier.TierClaim = cfg.OIDCTierClaim
+	verifier.Keys.TTL = cfg.OIDCKeysTTL
+	return func(ctx context.Context, token string) (*middleware.Principal, error) {
+		claims, err := verifier.Verify(ctx, token)
+		var invalid *oidc.TokenError
+		if errors.As(err, &invalid) {
+			return nil, &middleware.Error{Code: middleware.Unauthenticated, Message: invalid.Error()}
+		}
+		if err != nil {
+			logging.FromContext(ctx).Error("error authenticating", logging.Err(err))
+			return nil, err
+		}
+		tier := claims.Tier
+		if tier == "" {
+			tier = cfg.TenantTiers[claims.OrgID]
+		}
+		return &middleware.Principal{
+			UserID: claims.Subject,
+			OrgID:  claims.OrgID,
+			Scopes: claims.Scopes,
+			Tier:   tier,
+		}, nil
+	}
+}
+
+// authenticateAPIKey resolves the API keys of the calls with apiKeys, their
+// tier is the one tenantTiers has for the tenant of the key.
+func authenticateAPIKey(apiKeys gateway.APIKeyGateway, tenantTiers map[string]string) func(ctx context.Context, key string) (*middleware.Principal, error) {
+	uc := apikey.NewAuthenticateAPIKeyUseCase(apiKeys)
+	return func(ctx context.Context, key string) (*middleware.Principal, error) {
+		output, err := uc.Execute(ctx, key)
+		if errors.Is(err, apikey.ErrInvalidAPIKey) {
+			return nil, nil
+		}
+		if err != nil {
+			logging.FromContext(ctx).Error("error authenticating", logging.Err(err))
+			return nil, err
+		}
+		return &middleware.Principal{
+			KeyID:  output.KeyID,
+			UserID: output.UserID,
+			OrgID:  output.OrgID,
+			Scopes: output.Scopes,
+			Tier:   tenantTiers[output.OrgID],
+		}, nil
+	}
+}
+
+// apiKeyScope is the scope an API key needs for a call: completions to run,
+// stop, poll, watch and resume turns, usage:read for the usage, audit:read
+// for the audit log, chats:read to read and to query GraphQL and chats:write
+// for the rest. Keys can't manage keys or webhooks.
+func apiKeyScope(call *middleware.Call) string {
+	if call.Transport == middleware.GRPC {
+		switch call.Method[strings.LastIndex(call.Method, "/")+1:] {
+		case "ChatStream", "ChatSession", "Regenerate", "StopGeneration", "WatchChat", "ResumeStream":
+			return entity.ScopeCompletions
+		case "ListChats", "GetDraft":
+			return entity.ScopeChatsRead
+		case "RenameChat", "DeleteChat", "SaveDraft", "DiscardDraft":
+			return entity.ScopeChatsWrite
+		case "GetUsage":
+			return entity.ScopeUsageRead
+		case "ListAuditEntries":
+			return entity.ScopeAuditRead
+		}
+		return ""
+	}
+	// the path is /<version>/<route>
+	_, route, _ := strings.Cut(strings.TrimPrefix(call.Path, "/"), "/")
+	switch {
+	case strings.HasPrefix(route, "api-keys"), strings.HasPrefix(route, "webhooks"):
+		return ""
+	case route == "graphql":
+		// the completion subscription checks for the completions scope
+		return entity.ScopeChatsRead
+	case route == "ws", route == "completions", strings.HasPrefix(route, "streams/"), strings.HasSuffix(route, "/completions"), strings.HasSuffix(route, "/regenerate"), strings.HasSuffix(route, "/stop"), strings.HasSuffix(route, "/events"), strings.HasSuffix(route, "/watch"):
+		return entity.ScopeCompletions
+	case call.HTTPMethod == http.MethodGet:
+		return entity.ScopeChatsRead
+	}
+	return entity.ScopeChatsWrite
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/configs"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/dynamodb"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/mongodb"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/postgres"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/sqlite"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/sqlrepo"
+)
+
+// migrateCommand brings the configured database to the schema this binary
+// expects, running the migrations embedded in it, or with down reverts the
+// last -steps of them. On MongoDB it creates the indexes, on DynamoDB the
+// table beside the SQLite schema.
+func migrateCommand(args []string) int {
+	down := false
+	if len(args) > 0 && (args[0] == "up" || args[0] == "down") {
+		down = args[0] == "down"
+		args = args[1:]
+	}
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	timeout := flags.Duration("timeout", 5*time.Minute, "timeout of the whole migration")
+	steps := flags.Int("steps", 1, "number of migrations to revert with down")
+	flags.Parse(args)
+
+	cfg, err := configs.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	if cfg.DatabaseURL == "" {
+		fmt.Fprintln(os.Stderr, "DATABASE_URL is not set")
+		return 1
+	}
+	if down && *steps < 1 {
+		fmt.Fprintln(os.Stderr, "-steps must be at least 1")
+		return 1
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if down {
+		err = migrateDown(ctx, cfg, *steps)
+	} else {
+		err = migrate(ctx, cfg)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	return 0
+}
+
+func migrate(ctx context.Context, cfg *configs.Config) error {
+	switch cfg.DBDriver {
+	case "mongodb":
+		client, err := mongodb.Connect(ctx, cfg.DatabaseURL)
+		if err != nil {
+			return err
+		}
+		defer client.Disconnect(context.Background())
+		if err := mongodb.EnsureIndexes(ctx, client.Database(cfg.MongoDatabase)); err != nil {
+			return fmt.Errorf("error creating mongodb indexes: %s", err.Error())
+		}
+		fmt.Println("indexes are up to date")
+		return nil
+	case "dynamodb":
+		client, err := dynamodb.NewClient(cfg.DynamoDB, nil, clock.Real())
+		if err != nil {
+			return err
+		}
+		if err := client.EnsureTable(ctx); err != nil {
+			return fmt.Errorf("error creating dynamodb table: %s", err.Error())
+		}
+		fmt.Printf("table %s is up to date\n", cfg.DynamoDB.Table)
+	}
+	m, closeFunc, err := openMigrator(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeFunc()
+	applied, err := m.Up(ctx)
+	for _, version := range applied {
+		fmt.Printf("applied migration %d\n", version)
+	}
+	if err != nil {
+		return err
+	}
+	return printSchemaVersion(m)
+}
+
+func migrateDown(ctx context.Context, cfg *configs.Config, steps int) error {
+	if cfg.DBDriver == "mongodb" {
+		return fmt.Errorf("the mongodb driver has no migrations to revert")
+	}
+	m, closeFunc, err := openMigrator(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeFunc()
+	reverted, err := m.Down(ctx, steps)
+	for _, version := range reverted {
+		fmt.Printf("reverted migration %d\n", version)
+	}
+	if err != nil {
+		return err
+	}
+	return printSchemaVersion(m)
+}
+
+// openMigrator opens the SQL database of the driver, SQLite for the other
+// gateways of the dynamodb one, without migrating it.
+func openMigrator(ctx context.Context, cfg *configs.Config) (*sqlrepo.Migrator, func(), error) {
+	if cfg.DBDriver == "sqlite" || cfg.DBDriver == "dynamodb" {
+		db, err := sqlite.Connect(cfg.DatabaseURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		m, err := sqlite.NewMigrator(ctx, db)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return m, func() { db.Close() }, nil
+	}
+	db, err := postgres.Open(cfg.DatabaseURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := postgres.NewMigrator(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return m, func() { m.Close() }, nil
+}
+
+func printSchemaVersion(m *sqlrepo.Migrator) error {
+	version, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("schema is dirty at version %d, a migration failed halfway through", version)
+	}
+	fmt.Printf("schema is at version %d\n", version)
+	return nil
+}
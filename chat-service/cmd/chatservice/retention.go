@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/alecanutto/fclx/chat-service/configs"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/chatcache"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/prometheus"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/enforceretention"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/expirechats"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/purgechats"
+)
+
+// retentionCommand enforces the retention of a namespace every
+// APP_RETENTION_INTERVAL until interrupted, or once: it archives the expired
+// ephemeral chats, purges them after APP_EXPIRED_CHAT_RETENTION and the
+// soft-deleted chats after APP_DELETED_CHAT_RETENTION, then purges the chats
+// idle past the APP_RETENTION window of their tenant. Its metrics are served
+// on METRICS_PORT while it loops.
+func retentionCommand(args []string) int {
+	cfg, err := configs.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	flags := flag.NewFlagSet("retention", flag.ExitOnError)
+	ns := flags.String("namespace", cfg.Namespace, "namespace to enforce the retention of")
+	once := flags.Bool("once", false, "run the purge once and exit")
+	dryRun := flags.Bool("dry-run", cfg.RetentionDryRun, "only report the idle chats that would be purged")
+	flags.Parse(args)
+
+	if cfg.DatabaseURL == "" {
+		fmt.Fprintln(os.Stderr, "DATABASE_URL is not set")
+		return 1
+	}
+	if err := namespace.Validate(*ns); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = namespace.NewContext(ctx, *ns)
+
+	clk := cfg.NewClock()
+	store, err := openChatStore(ctx, cfg, clk)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	defer store.close()
+	// archiving an expired chat writes it
+	if err := store.prepare(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	fileStorage, err := newFileStorage(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	chats, retention := store.chats, store.retention
+	if cfg.RedisURL != "" {
+		client, err := chatcache.NewRedisClient(cfg.RedisURL)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+		defer client.Close()
+		onError := func(err error) {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
+		chats = chatcache.NewChatGateway(chats, chatcache.NewRedisStore(client), clk, cfg.ChatCacheTTL, onError, store.cacheOptions()...)
+		retention = chatcache.NewRetentionGateway(retention, chatcache.NewRedisStore(client), onError)
+	}
+
+	metrics := enforceretention.NewMetrics()
+	job := &retentionJob{
+		expire:      expirechats.NewExpireChatsUseCase(chats, clk),
+		purge:       purgechats.NewPurgeChatsUseCase(chats, clk),
+		enforce:     enforceretention.NewEnforceRetentionUseCase(retention, fileStorage, clk, metrics),
+		metrics:     metrics,
+		namespace:   *ns,
+		expireArgs:  expirechats.ExpireChatsInputDTO{PurgeAfter: cfg.ExpiredChatRetention},
+		purgeArgs:   purgechats.PurgeChatsInputDTO{Retention: cfg.DeletedChatRetention},
+		enforceArgs: enforceretention.EnforceRetentionInputDTO{DryRun: *dryRun},
+	}
+	for orgID, idleAfter := range cfg.Retention {
+		job.enforceArgs.Policies = append(job.enforceArgs.Policies, enforceretention.PolicyInputDTO{OrgID: orgID, IdleAfter: idleAfter})
+	}
+	sort.Slice(job.enforceArgs.Policies, func(i, j int) bool {
+		return job.enforceArgs.Policies[i].OrgID < job.enforceArgs.Policies[j].OrgID
+	})
+
+	if *once {
+		if !job.run(ctx) {
+			return 1
+		}
+		return 0
+	}
+	if cfg.MetricsPort != "" {
+		registry := prometheus.NewRegistry()
+		registry.Register(prometheus.Retention(metrics))
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", prometheus.Handler(registry))
+		metricsServer := newHTTPServer(cfg.MetricsPort, mux)
+		go func() {
+			if err := metricsServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				fmt.Fprintf(os.Stderr, "error serving metrics: %s\n", err.Error())
+				stop()
+			}
+		}()
+		defer metricsServer.Close()
+	}
+	ticker := clk.NewTicker(cfg.RetentionInterval)
+	defer ticker.Stop()
+	for {
+		job.run(ctx)
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-ticker.C():
+		}
+	}
+}
+
+type retentionJob struct {
+	expire      *expirechats.ExpireChatsUseCase
+	purge       *purgechats.PurgeChatsUseCase
+	enforce     *enforceretention.EnforceRetentionUseCase
+	metrics     *enforceretention.Metrics
+	namespace   string
+	expireArgs  expirechats.ExpireChatsInputDTO
+	purgeArgs   purgechats.PurgeChatsInputDTO
+	enforceArgs enforceretention.EnforceRetentionInputDTO
+}
+
+// run sweeps the expired and soft-deleted chats, but on a dry run, then
+// enforces the policies of the tenants and prints their reports. It returns
+// false when any of them failed.
+func (j *retentionJob) run(ctx context.Context) bool {
+	ok := true
+	if !j.enforceArgs.DryRun && !j.sweep(ctx) {
+		ok = false
+	}
+	if len(j.enforceArgs.Policies) == 0 {
+		return ok
+	}
+	output, err := j.enforce.Execute(ctx, j.enforceArgs)
+	if output != nil {
+		for _, report := range output.Reports {
+			if output.DryRun {
+				fmt.Printf("%s/%s: %d chats idle since before %s would be purged\n",
+					j.namespace, report.OrgID, report.Matched, report.IdleBefore.Format("2006-01-02 15:04"))
+				continue
+			}
+			fmt.Printf("%s/%s: purged %d chats idle since before %s, deleted %d files\n",
+				j.namespace, report.OrgID, report.Purged, report.IdleBefore.Format("2006-01-02 15:04"), report.FilesDeleted)
+			for _, key := range report.FailedFiles {
+				fmt.Fprintf(os.Stderr, "%s/%s: could not delete file %s\n", j.namespace, report.OrgID, key)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return false
+	}
+	return ok
+}
+
+// sweep archives the expired ephemeral chats and purges those archived for
+// long enough, then purges the soft-deleted chats past their retention.
+func (j *retentionJob) sweep(ctx context.Context) bool {
+	expired, err := j.expire.Execute(ctx, j.expireArgs)
+	var archived, expiredPurged, deletedPurged int64
+	if expired != nil {
+		archived, expiredPurged = int64(expired.Archived), expired.Purged
+	}
+	if err == nil {
+		var deleted *purgechats.PurgeChatsOutputDTO
+		deleted, err = j.purge.Execute(ctx, j.purgeArgs)
+		if deleted != nil {
+			deletedPurged = deleted.Purged
+		}
+	}
+	j.metrics.ObserveSweep(archived, expiredPurged, deletedPurged, err)
+	fmt.Printf("%s: archived %d expired chats, purged %d expired and %d deleted chats\n",
+		j.namespace, archived, expiredPurged, deletedPurged)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/configs"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/chatcache"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/diagnostics"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/dispatcher"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/errorreport"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/eventbus"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/featureflag"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/graphql"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/grpc/server"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/grpc/service"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/logging"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/middleware"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/prometheus"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/ratelimit"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/readmodel"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/requestid"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/streambuffer"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/tracing"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/web"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/webhook"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/apikey"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/auditlog"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/batchcompletion"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/deletechat"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/draft"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/importchat"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/listchats"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/listmessages"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/projectchats"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/purgeuserdata"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/relayoutbox"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/renamechat"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/searchchats"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/searchmessages"
+	usages "github.com/alecanutto/fclx/chat-service/internal/usecase/usage"
+	webhooks "github.com/alecanutto/fclx/chat-service/internal/usecase/webhook"
+	openai "github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// serveCommand serves the gRPC API on GRPC_SERVER_PORT, the HTTP API on
+// HTTP_SERVER_PORT, the metrics on METRICS_PORT and the admin endpoints on
+// ADMIN_PORT when set, until interrupted. It then drains the completions in
+// flight before the stores are closed.
+func serveCommand(args []string) int {
+	cfg, err := configs.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	logger := newLogger(cfg)
+	if cfg.OpenAIAPIKey == "" {
+		logger.Error("OPENAI_API_KEY is not set")
+		return 1
+	}
+	if cfg.DatabaseURL == "" {
+		logger.Error("DATABASE_URL is not set")
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	clk := cfg.NewClock()
+	store, err := openChatStore(ctx, cfg, clk)
+	if err != nil {
+		logger.Error("error opening the chat store", logging.Err(err))
+		return 1
+	}
+	defer store.close()
+	if err := store.prepare(namespace.NewContext(ctx, cfg.Namespace)); err != nil {
+		logger.Error("error preparing the chat store", logging.Err(err))
+		return 1
+	}
+	fileStorage, err := newFileStorage(cfg)
+	if err != nil {
+		logger.Error("error opening the file storage", logging.Err(err))
+		return 1
+	}
+
+	tracer, err := newTracer(ctx, cfg, logger)
+	if err != nil {
+		logger.Error("error creating the tracer", logging.Err(err))
+		return 1
+	}
+	defer shutdownTracer(tracer, logger)
+	reporter, err := newErrorReporter(cfg, clk, logger)
+	if err != nil {
+		logger.Error("error setting up the error reporting", logging.Err(err))
+		return 1
+	}
+	if reporter != nil {
+		go reporter.Run(ctx, errorReportInterval)
+		defer flushErrors(reporter, logger)
+	}
+	// the chats publish their events on the bus for the projector, which
+	// keeps the read model the chats are listed from up to date
+	bus := eventbus.New()
+	chats := eventbus.NewChatGateway(store.chats, bus, store.unitOfWork, clk)
+	chatSummaries := namespace.NewChatReadModel(store.chatSummaries, cfg.Namespace)
+	projector := readmodel.NewProjector(projectchats.NewProjectChatsUseCase(store.chats, store.chatSummaries, clk), func(err error) {
+		logger.Warn("error projecting chats", logging.Err(err))
+	})
+	projector.Subscribe(bus)
+	go projector.Run(ctx)
+	go rebuildReadModel(ctx, projectchats.NewRebuildReadModelUseCase(namespace.NewChatGateway(store.chats, cfg.Namespace), chatSummaries, clk), logger)
+	// the erasures of user data publish the deletion of the chats purged
+	userData := eventbus.NewUserDataGateway(store.userData, bus, clk)
+	var limits ratelimit.Store = ratelimit.NewMemoryStore(clk)
+	if cfg.RedisURL != "" {
+		client, err := chatcache.NewRedisClient(cfg.RedisURL)
+		if err != nil {
+			logger.Error("error connecting to redis", logging.Err(err))
+			return 1
+		}
+		defer client.Close()
+		chats = chatcache.NewChatGateway(chats, chatcache.NewRedisStore(client), clk, cfg.ChatCacheTTL, func(err error) {
+			logger.Warn("chat cache failed", logging.Err(err))
+		}, store.cacheOptions()...)
+		userData = chatcache.NewUserDataGateway(userData, chatcache.NewRedisStore(client), func(err error) {
+			logger.Warn("chat cache failed", logging.Err(err))
+		})
+		limits = ratelimit.NewRedisStore(client, cfg.Namespace)
+	}
+	// the cache keys need the namespace, it wraps the chats before they are scoped
+	userData = namespace.NewUserDataGateway(userData, cfg.Namespace)
+	chats = tracing.NewChatGateway(namespace.NewChatGateway(chats, cfg.Namespace), tracer)
+	flags := featureflag.New(featureflag.NewStaticProvider(cfg.FeatureFlags))
+	flags.OnError = func(flag string, err error) {
+		logger.Warn("feature flag failed", logging.String("flag", flag), logging.Err(err))
+	}
+	// the provider is sent the request ID and the trace context of every
+	// completion
+	openAIConfig := openai.DefaultConfig(cfg.OpenAIAPIKey)
+	providerClient := &http.Client{Transport: otelhttp.NewTransport(requestid.NewTransport(nil))}
+	defer providerClient.CloseIdleConnections()
+	openAIConfig.HTTPClient = providerClient
+	hooks := namespace.NewWebhookGateway(store.webhooks, cfg.Namespace)
+	usageRecords := namespace.NewUsageGateway(store.usage, cfg.Namespace)
+	drafts := namespace.NewDraftGateway(store.drafts, cfg.Namespace)
+	// the turns publish their chunks by chat for the clients watching it, and
+	// keep them for the clients resuming them
+	metrics := prometheus.NewRegistry()
+	completionMetrics := chatcompletionstream.NewMetrics()
+	metrics.Register(prometheus.Completions(completionMetrics))
+	streams := streambuffer.New[chatcompletionstream.ChatCompletionOutputDTO](clk, cfg.StreamBufferTTL, cfg.StreamBufferIdleTTL, cfg.StreamBufferEvents)
+	opts := []chatcompletionstream.Option{
+		chatcompletionstream.WithStreamBuffer(streams),
+		chatcompletionstream.WithClock(clk),
+		chatcompletionstream.WithFeatureFlags(flags),
+		chatcompletionstream.WithWebhookGateway(hooks),
+		chatcompletionstream.WithIdempotencyKeyGateway(namespace.NewIdempotencyKeyGateway(store.idempotencyKeys, cfg.Namespace)),
+		chatcompletionstream.WithUsageGateway(usageRecords),
+		chatcompletionstream.WithUnitOfWork(store.unitOfWork),
+		chatcompletionstream.WithDraftGateway(drafts),
+		chatcompletionstream.WithSlowConsumerPolicy(cfg.StreamCapacity, chatcompletionstream.SlowConsumerPolicy(cfg.SlowConsumer), cfg.SlowConsumerTimeout),
+		chatcompletionstream.WithTimeouts(cfg.FirstTokenTimeout, cfg.GenerationTimeout),
+		chatcompletionstream.WithSlowGenerationWatchdog(chatcompletionstream.SlowGenerationWatchdog{
+			FirstToken: cfg.SlowFirstToken,
+			Total:      cfg.SlowGeneration,
+			Provider:   providerName(openAIConfig.BaseURL),
+		}),
+		chatcompletionstream.WithHeartbeat(cfg.HeartbeatInterval),
+		chatcompletionstream.WithTracer(tracer),
+		chatcompletionstream.WithMetrics(completionMetrics),
+		chatcompletionstream.WithErrorReporter(reporter),
+	}
+	var auditLog gateway.AuditLogGateway
+	if cfg.AuditLog {
+		auditLog = namespace.NewAuditLogGateway(store.auditLog, cfg.Namespace)
+		opts = append(opts, chatcompletionstream.WithAuditLog(auditLog, cfg.AuditPolicy))
+	}
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimitPerMinute > 0 {
+		limiter = ratelimit.NewLimiter(limits, ratelimit.Bucket{
+			PerSecond: cfg.RateLimitPerMinute / 60,
+			Burst:     cfg.RateLimitBurst,
+		}, clk)
+		limiter.MaxWait = cfg.RateLimitMaxWait
+		limiter.OnError = func(err error) {
+			logger.Warn("rate limiter failed", logging.Err(err))
+		}
+		opts = append(opts, chatcompletionstream.WithRateLimiter(limiter))
+	}
+	var slots *dispatcher.Dispatcher
+	if cfg.MaxConcurrentCompletions > 0 || cfg.MaxCompletionsPerUser > 0 {
+		slots = dispatcher.New(dispatcher.Config{
+			MaxConcurrent: cfg.MaxConcurrentCompletions,
+			MaxPerUser:    cfg.MaxCompletionsPerUser,
+			UserLimit:     dispatcher.UserLimitPolicy(cfg.UserCompletionLimit),
+			MaxBatch:      cfg.MaxBatchCompletions,
+			MaxQueued:     cfg.CompletionQueueDepth,
+			QueueTimeout:  cfg.CompletionQueueTimeout,
+			Weights:       cfg.CompletionTierWeights,
+			MaxWait:       cfg.CompletionMaxWait,
+			Clock:         clk,
+		})
+		metrics.Register(prometheus.Dispatcher(slots))
+		opts = append(opts, chatcompletionstream.WithDispatcher(slots))
+	}
+	openAIClient := openai.NewClientWithConfig(openAIConfig)
+	uc := chatcompletionstream.NewChatCompletionUseCase(chats, openAIClient, nil, opts...)
+	completionConfig := chatcompletionstream.ChatCompletionConfigInputDTO{
+		Model:                cfg.Model,
+		ModelMaxToken:        cfg.ModelMaxTokens,
+		Temperature:          1,
+		TopP:                 1,
+		N:                    1,
+		MaxTokens:            300,
+		InitialSystemMessage: cfg.InitialSystemMessage,
+	}
+	chatService := service.NewChatService(
+		uc,
+		listchats.NewListChatsByUserUseCase(chats),
+		renamechat.NewRenameChatUseCase(chats),
+		deletechat.NewDeleteChatUseCase(chats, clk),
+		chatcompletionstream.NewRegenerateUseCase(uc),
+		chatcompletionstream.NewStopGenerationUseCase(uc),
+		completionConfig,
+	)
+	chatService.WatchChatUseCase = chatcompletionstream.NewWatchChatUseCase(streams)
+	chatService.ResumeStreamUseCase = chatcompletionstream.NewResumeStreamUseCase(streams, nil)
+	chatService.GetUsageUseCase = usages.NewGetUsageUseCase(usageRecords, clk)
+	chatService.SaveDraftUseCase = draft.NewSaveDraftUseCase(chats, drafts, clk)
+	chatService.GetDraftUseCase = draft.NewGetDraftUseCase(chats, drafts)
+	chatService.DiscardDraftUseCase = draft.NewDiscardDraftUseCase(chats, drafts)
+	if auditLog != nil {
+		chatService.ListAuditEntriesUseCase = auditlog.NewListAuditEntriesUseCase(auditLog)
+	}
+	grpcServer := server.NewGRPCServer(chatService, cfg.GRPCServerPort)
+	grpcServer.Transport = server.TransportConfig{
+		KeepaliveTime:    cfg.GRPCKeepaliveTime,
+		KeepaliveTimeout: cfg.GRPCKeepaliveTimeout,
+		KeepaliveMinTime: cfg.GRPCKeepaliveMinTime,
+		MaxRecvMsgSize:   cfg.GRPCMaxRecvMsgSize,
+		MaxSendMsgSize:   cfg.GRPCMaxSendMsgSize,
+		WindowSize:       int32(cfg.GRPCWindowSize),
+		ConnWindowSize:   int32(cfg.GRPCConnWindowSize),
+	}
+	if cfg.GRPCTLSCert != "" {
+		grpcServer.TLS = &server.TLSConfig{
+			CertFile:     cfg.GRPCTLSCert,
+			KeyFile:      cfg.GRPCTLSKey,
+			ClientCAFile: cfg.GRPCTLSClientCA,
+			SPIFFEIDs:    cfg.GRPCTLSSPIFFEIDs,
+			OnReloadError: func(err error) {
+				logger.Error("error reloading the TLS certificate", logging.Err(err))
+			},
+		}
+	}
+	callMetrics := middleware.NewMetrics()
+	metrics.Register(prometheus.Calls(callMetrics))
+	apiKeys := namespace.NewAPIKeyGateway(store.apiKeys, cfg.Namespace)
+	calls := middlewares(cfg, clk, apiKeys, callMetrics, logger, reporter)
+	grpcServer.Use(calls...)
+
+	// the HTTP API serves the same usecases as the gRPC one, behind the same
+	// middleware
+	versionMetrics := web.NewVersionMetrics()
+	metrics.Register(prometheus.APIVersions(versionMetrics))
+	listChats := listchats.NewListChatsByUserUseCase(chats)
+	router := web.NewAPIRouter(clk, versionMetrics,
+		web.NewDraftHandler(chatService.SaveDraftUseCase, chatService.GetDraftUseCase, chatService.DiscardDraftUseCase),
+		web.NewChatsHandler(searchchats.NewSearchChatsUseCase(chatSummaries)),
+		web.NewChatHandler(chatService.RenameChatUseCase, chatService.DeleteChatUseCase),
+		web.NewMessagesHandler(listmessages.NewListMessagesUseCase(chats)),
+		web.NewSearchHandler(searchmessages.NewSearchMessagesUseCase(namespace.NewSearchGateway(store.search, cfg.Namespace))),
+		web.NewImportHandler(importchat.NewImportChatsUseCase(chats, importchat.ImportConfigInputDTO{
+			Model:                cfg.Model,
+			ModelMaxToken:        cfg.ModelMaxTokens,
+			InitialSystemMessage: cfg.InitialSystemMessage,
+		}, clk)),
+		web.NewUserChatsHandler(listChats),
+		web.NewCompletionsHandler(uc, completionConfig),
+		web.NewRegenerateHandler(chatService.RegenerateUseCase),
+		web.NewStopHandler(chatService.StopGenerationUseCase),
+		web.NewWebSocketHandler(uc, chatService.ResumeStreamUseCase, completionConfig),
+		web.NewAPIKeysHandler(apikey.NewIssueAPIKeyUseCase(apiKeys, clk), apikey.NewListAPIKeysUseCase(apiKeys)),
+		web.NewAPIKeyHandler(apikey.NewRevokeAPIKeyUseCase(apiKeys, clk)),
+		web.NewWebhooksHandler(webhooks.NewRegisterWebhookUseCase(hooks, clk), webhooks.NewListWebhooksUseCase(hooks)),
+		web.NewWebhookHandler(webhooks.NewDeleteWebhookUseCase(hooks)),
+		web.NewDeadLettersHandler(webhooks.NewListDeadLettersUseCase(hooks)),
+		web.NewBatchCompletionsHandler(batchcompletion.NewBatchCompletionUseCase(openAIClient, limiter), completionConfig),
+		web.NewEventsHandler(chatcompletionstream.NewPollEventsUseCase(streams, clk)),
+		web.NewWatchHandler(chatService.WatchChatUseCase),
+		web.NewResumeHandler(chatService.ResumeStreamUseCase),
+	)
+	router.Handle("/graphql", graphql.NewHandler(graphql.NewResolver(listChats, listmessages.NewListMessagesUseCase(chats), uc, completionConfig)))
+	router.Use(calls...)
+	// otelhttp starts the span of every call in the trace of its caller, the
+	// middleware tags it
+	httpServer := newHTTPServer(cfg.HTTPServerPort, otelhttp.NewHandler(router, "http"))
+
+	deliver := webhooks.NewDeliverWebhooksUseCase(hooks, webhook.NewHTTPSender(nil, clk), clk)
+	go deliver.Run(ctx, cfg.WebhookInterval, webhooks.DeliverWebhooksInputDTO{}, func(err error) {
+		logger.Error("error delivering webhooks", logging.Err(err))
+	})
+	if cfg.EventsWebhookURL != "" {
+		// the events of the outbox carry the namespace of the context
+		broker := webhook.NewBroker(cfg.EventsWebhookURL, cfg.EventsWebhookSecret, webhook.NewHTTPSender(nil, clk))
+		relay := relayoutbox.NewRelayOutboxUseCase(store.outbox, broker, clk)
+		go relay.Run(namespace.NewContext(ctx, cfg.Namespace), cfg.OutboxInterval, relayoutbox.RelayOutboxInputDTO{MaxAttempts: cfg.OutboxMaxAttempts}, func(err error) {
+			logger.Error("error relaying the outbox", logging.Err(err))
+		})
+	}
+
+	errs := make(chan error, 4)
+	go func() {
+		errs <- grpcServer.Start()
+	}()
+	logger.Info("serving gRPC", logging.String("port", cfg.GRPCServerPort))
+	go func() {
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("error serving the HTTP API: %s", err.Error())
+		}
+	}()
+	logger.Info("serving HTTP", logging.String("port", cfg.HTTPServerPort))
+	var sections []diagnostics.Section
+	if cfg.Diagnostics {
+		sections = diagnosticSections(uc.Generations, streams, slots, reporter)
+	}
+	if cfg.MetricsPort != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", prometheus.Handler(metrics))
+		if cfg.Diagnostics && cfg.AdminPort == "" {
+			diagnostics.Register(mux, sections...)
+		}
+		metricsServer := newHTTPServer(cfg.MetricsPort, mux)
+		go func() {
+			if err := metricsServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("error serving metrics: %s", err.Error())
+			}
+		}()
+		defer metricsServer.Close()
+		logger.Info("serving metrics", logging.String("port", cfg.MetricsPort))
+	}
+	if cfg.AdminPort != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/flags", web.NewFeatureFlagsHandler(flags))
+		mux.Handle("/admin/erasures", web.NewErasureHandler(purgeuserdata.NewPurgeUserDataUseCase(userData, fileStorage, clk)))
+		if sandbox, ok := clk.(*clock.Sandbox); ok {
+			mux.Handle("/debug/clock", web.NewClockHandler(sandbox))
+		}
+		if cfg.Diagnostics {
+			diagnostics.Register(mux, sections...)
+		}
+		adminServer := newHTTPServer(cfg.AdminPort, mux)
+		go func() {
+			if err := adminServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("error serving the admin endpoints: %s", err.Error())
+			}
+		}()
+		defer adminServer.Close()
+		logger.Info("serving the admin endpoints", logging.String("port", cfg.AdminPort))
+	}
+	select {
+	case err := <-errs:
+		logger.Error("server failed", logging.Err(err))
+		return 1
+	case <-ctx.Done():
+		shutdown(cfg, uc.Generations, grpcServer, httpServer, logger)
+		return 0
+	}
+}
+
+// newHTTPServer serves handler on port, the HTTP API, the metrics or the
+// diagnostics. The streams of the API run as long as their answers, the
+// server has no write timeout.
+func newHTTPServer(port string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + port,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// diagnosticSections are the stats of the diagnostics beside those of the
+// runtime: the generations in flight, the buffered streams and the backlog
+// of their watchers, the completion queue when slots bound the completions,
+// and the errors waiting to be sent.
+func diagnosticSections(generations *chatcompletionstream.Generations, streams *streambuffer.Buffer[chatcompletionstream.ChatCompletionOutputDTO], slots *dispatcher.Dispatcher, reporter *errorreport.Reporter) []diagnostics.Section {
+	sections := []diagnostics.Section{
+		{Name: "generations", Stats: func() interface{} { return generations.Stats() }},
+		{Name: "streams", Stats: func() interface{} { return streams.Stats() }},
+		{Name: "exports", Stats: func() interface{} {
+			return map[string]int{"errors_queued": reporter.Queued()}
+		}},
+	}
+	if slots != nil {
+		sections = append(sections, diagnostics.Section{Name: "completion_queue", Stats: func() interface{} {
+			stats := slots.Stats()
+			queued := 0
+			for _, tiers := range stats.Queued {
+				for _, n := range tiers {
+					queued += n
+				}
+			}
+			return map[string]interface{}{
+				"in_flight":       stats.InFlight,
+				"batch_in_flight": stats.BatchInFlight,
+				"queued":          queued,
+				"by_priority":     stats.Queued,
+			}
+		}})
+	}
+	return sections
+}
+
+// traceFlushTimeout is how long exporting the last spans may take on
+// shutdown.
+const traceFlushTimeout = 5 * time.Second
+
+// providerName is the host of the provider API at baseURL, which tells
+// OpenAI from a proxy or another compatible provider in the slow generations
+// flagged.
+func providerName(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return baseURL
+	}
+	return u.Host
+}
+
+// newLogger logs at the level and in the format of cfg, on stderr.
+func newLogger(cfg *configs.Config) *logging.Logger {
+	level, _ := logging.ParseLevel(cfg.LogLevel)
+	return logging.New(os.Stderr, level, logging.Format(cfg.LogFormat), nil)
+}
+
+// newTracer traces the calls to the collector of cfg, it is nil without one.
+// It is registered as the tracer provider of otelgrpc and otelhttp, with the
+// W3C trace context and baggage as their propagators.
+func newTracer(ctx context.Context, cfg *configs.Config, logger *logging.Logger) (*tracing.Tracer, error) {
+	if cfg.OTLPEndpoint == "" {
+		return nil, nil
+	}
+	tracer, err := tracing.NewTracer(ctx, cfg.OTLPEndpoint, cfg.TraceService, cfg.OTLPHeaders)
+	if err != nil {
+		return nil, err
+	}
+	otel.SetTracerProvider(tracer.Provider())
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("error exporting traces", logging.Err(err))
+	}))
+	return tracer, nil
+}
+
+// shutdownTracer exports the spans left once the server stopped.
+func shutdownTracer(tracer *tracing.Tracer, logger *logging.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+	defer cancel()
+	if err := tracer.Shutdown(ctx); err != nil {
+		logger.Warn("error exporting traces", logging.Err(err))
+	}
+}
+
+// errorReportInterval is how often the errors captured are sent,
+// errorFlushTimeout how long sending the last ones may take on shutdown.
+const (
+	errorReportInterval = 5 * time.Second
+	errorFlushTimeout   = 5 * time.Second
+)
+
+// newErrorReporter reports the errors to the Sentry project of cfg, it is
+// nil without one.
+func newErrorReporter(cfg *configs.Config, clk clock.Clock, logger *logging.Logger) (*errorreport.Reporter, error) {
+	if cfg.SentryDSN == "" {
+		return nil, nil
+	}
+	sender, err := errorreport.NewSentrySender(cfg.SentryDSN, &http.Client{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("SENTRY_DSN: %s", err.Error())
+	}
+	reporter := errorreport.NewReporter(sender, cfg.Release, cfg.SentryEnvironment, clk)
+	reporter.OnError = func(err error) {
+		logger.Warn("error reporting errors", logging.Err(err))
+	}
+	return reporter, nil
+}
+
+// flushErrors sends the errors left once the server stopped.
+func flushErrors(reporter *errorreport.Reporter, logger *logging.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), errorFlushTimeout)
+	defer cancel()
+	if err := reporter.Flush(ctx); err != nil {
+		logger.Warn("error reporting errors", logging.Err(err))
+	}
+}
+
+// shutdownSaveTimeout is how long the completions stopped by the shutdown
+// have to save their partial answers.
+const shutdownSaveTimeout = 10 * time.Second
+
+// shutdown stops taking calls and completions on both APIs, lets the
+// completions in flight finish within cfg.ShutdownTimeout and stops the
+// others, which save what they streamed. The streams left, like idle chat
+// sessions, are closed then.
+func shutdown(cfg *configs.Config, generations *chatcompletionstream.Generations, grpcServer *server.GRPCServer, httpServer *http.Server, logger *logging.Logger) {
+	logger.Info("shutting down, draining the completions in flight")
+	closeCtx, closeStreams := context.WithCancel(context.Background())
+	stopped := make(chan struct{}, 2)
+	go func() {
+		grpcServer.Stop(closeCtx)
+		stopped <- struct{}{}
+	}()
+	go func() {
+		// the streams still open once closeCtx is done are cut, hijacked
+		// connections like the WebSockets are left to their handlers
+		if err := httpServer.Shutdown(closeCtx); err != nil {
+			httpServer.Close()
+		}
+		stopped <- struct{}{}
+	}()
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := generations.Drain(drainCtx); err != nil {
+		logger.Warn("completions left stopped", logging.Err(err))
+		saveCtx, cancel := context.WithTimeout(context.Background(), shutdownSaveTimeout)
+		defer cancel()
+		if err := generations.Wait(saveCtx); err != nil {
+			logger.Error("error saving the stopped completions", logging.Err(err))
+		}
+	}
+	closeStreams()
+	<-stopped
+	<-stopped
+}
+
+// rebuildReadModel projects the chats of the namespace again on start, for
+// the changes the projector missed while the service was down and the purges
+// of the retention, which publish no events.
+func rebuildReadModel(ctx context.Context, rebuild *projectchats.RebuildReadModelUseCase, logger *logging.Logger) {
+	output, err := rebuild.Execute(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Warn("error rebuilding the read model", logging.Err(err))
+		}
+		return
+	}
+	logger.Info("read model rebuilt", logging.Int("projected", output.Projected), logging.Int("purged", int(output.Purged)))
+}
@@ -0,0 +1,777 @@
+package configs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/dispatcher"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/encryption"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/featureflag"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/dynamodb"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/storage"
+)
+
+type Config struct {
+	// Namespace isolates the data of this environment (dev, staging, prod...)
+	// when several of them share the same database.
+	Namespace string
+	// Sandbox runs the service on a clock running in real time that the admin
+	// endpoint moves forward, to see the TTLs expire and the quotas reset.
+	Sandbox bool
+	// FeatureFlags are the rules of the in-config flag provider, by flag name.
+	FeatureFlags map[string]featureflag.Rule
+	// DBDriver selects the chat repository (postgres, mongodb, sqlite or
+	// dynamodb), DatabaseURL is its DSN, or file path for sqlite. MongoDatabase
+	// is the database used on MongoDB. On dynamodb the chats and their outbox
+	// go to the DynamoDB table, the other gateways to the SQLite database of
+	// DatabaseURL.
+	DBDriver      string
+	DatabaseURL   string
+	MongoDatabase string
+	DynamoDB      dynamodb.Config
+	// RedisURL enables the chat cache when set, cached chats live ChatCacheTTL.
+	RedisURL     string
+	ChatCacheTTL time.Duration
+	OpenAIAPIKey string
+	// Model is the default completion model.
+	Model string
+	// Storage is where attachments go: local (default) under StorageDir, or s3.
+	Storage    string
+	StorageDir string
+	S3         storage.S3Config
+	// Encryption encrypts message content at rest, and in the chat cache,
+	// with a key per tenant: none (default), local with the base64 256 bits
+	// EncryptionKey as master key, or kms.
+	Encryption    string
+	EncryptionKey string
+	KMS           encryption.KMSConfig
+	// Retention is how long a chat may stay idle before the retention job
+	// purges it, by tenant, the window keyed "*" applying to the other
+	// tenants and to the chats opened outside of one. The job also
+	// purges the soft-deleted chats after DeletedChatRetention and the
+	// expired ephemeral ones after ExpiredChatRetention. RetentionDryRun only
+	// reports what it would purge, every RetentionInterval.
+	Retention            map[string]time.Duration
+	DeletedChatRetention time.Duration
+	ExpiredChatRetention time.Duration
+	RetentionDryRun      bool
+	RetentionInterval    time.Duration
+	// BackupStorage is where backups go: s3 (default), with the S3_* settings
+	// but BACKUP_S3_BUCKET and BACKUP_S3_PREFIX ("backups/"), or local under
+	// BackupDir. The backup job runs every BackupInterval and keeps the last
+	// BackupKeep backups of each namespace.
+	BackupStorage  string
+	BackupDir      string
+	BackupS3       storage.S3Config
+	BackupInterval time.Duration
+	BackupKeep     int
+	// AuditLog keeps the audit trail of the answered turns, their prompt and
+	// response redacted as AuditPolicy tells for each tenant: hashed, the
+	// default, stripped of personal data or in full.
+	AuditLog    bool
+	AuditPolicy entity.AuditPolicy
+	// GRPCServerPort is the port of the gRPC API, AuthToken the token its
+	// callers must send in the authorization metadata, none when empty.
+	GRPCServerPort string
+	AuthToken      string
+	// HTTPServerPort is the port of the HTTP API, which authenticates its
+	// callers like the gRPC one.
+	HTTPServerPort string
+	// MetricsPort, when set, serves the metrics of the service on /metrics
+	// of this port, for Prometheus to scrape.
+	MetricsPort string
+	// AdminPort, when set, serves the state of the feature flags for a
+	// tenant and user on /debug/flags, the erasures of user data on
+	// /admin/erasures, and the sandbox clock on /debug/clock in sandbox mode. Diagnostics serves the pprof profiles
+	// on /debug/pprof/ and the runtime stats, goroutines, streams and queues,
+	// on /debug/runtime, of AdminPort when set, of MetricsPort otherwise.
+	// Neither port should be reachable from outside.
+	Diagnostics bool
+	AdminPort   string
+	// LogLevel is the least severe level logged, debug, info, the default,
+	// warn or error. LogFormat is text, the default, or json.
+	LogLevel  string
+	LogFormat string
+	// GRPCTLSCert and GRPCTLSKey, when set, serve the gRPC API over TLS, mutual
+	// when GRPCTLSClientCA is set too. The client certificates must then have
+	// one of GRPCTLSSPIFFEIDs, when set. The files are reloaded once changed.
+	GRPCTLSCert      string
+	GRPCTLSKey       string
+	GRPCTLSClientCA  string
+	GRPCTLSSPIFFEIDs []string
+	// GRPCKeepaliveTime, GRPCKeepaliveTimeout and GRPCKeepaliveMinTime tune
+	// the keepalive pings of the gRPC connections, GRPCMaxRecvMsgSize and
+	// GRPCMaxSendMsgSize their message sizes and GRPCWindowSize and
+	// GRPCConnWindowSize their flow-control windows, in bytes. Unset, they
+	// keep the defaults of the server.
+	GRPCKeepaliveTime    time.Duration
+	GRPCKeepaliveTimeout time.Duration
+	GRPCKeepaliveMinTime time.Duration
+	GRPCMaxRecvMsgSize   int
+	GRPCMaxSendMsgSize   int
+	GRPCWindowSize       int
+	GRPCConnWindowSize   int
+	// OIDCIssuer, when set, authenticates the JWT bearer tokens of the callers
+	// with the keys the issuer publishes, cached OIDCKeysTTL. The tokens must
+	// be for OIDCAudience and carry OIDCScope, when set. Their subject is the
+	// user of the calls, their OIDCOrgClaim claim the tenant and their
+	// OIDCTierClaim claim the completion tier. Without an AuthToken, the
+	// calls must then carry a token or an API key.
+	OIDCIssuer    string
+	OIDCAudience  string
+	OIDCScope     string
+	OIDCOrgClaim  string
+	OIDCTierClaim string
+	OIDCKeysTTL   time.Duration
+	// ModelMaxTokens is the context window of Model and InitialSystemMessage
+	// the system message of the chats the API starts.
+	ModelMaxTokens       int
+	InitialSystemMessage string
+	// RateLimitPerMinute, when set, limits the completions of each tenant or
+	// user, in bursts of up to RateLimitBurst. Completions a token frees up
+	// for within RateLimitMaxWait wait for it instead of being rejected. The
+	// limit is shared through Redis when RedisURL is set.
+	RateLimitPerMinute float64
+	RateLimitBurst     int
+	RateLimitMaxWait   time.Duration
+	// WebhookInterval is how often the webhook deliveries due are posted.
+	WebhookInterval time.Duration
+	// EventsWebhookURL, when set, is posted the chat events of the outbox
+	// every OutboxInterval for the consumers downstream, signed with
+	// EventsWebhookSecret like the webhook deliveries. An event failing
+	// OutboxMaxAttempts times is dead-lettered.
+	EventsWebhookURL    string
+	EventsWebhookSecret string
+	OutboxInterval      time.Duration
+	OutboxMaxAttempts   int
+	// MaxConcurrentCompletions and MaxCompletionsPerUser, when set, bound the
+	// completions running at once, overall and per user. The others wait in
+	// a queue of up to CompletionQueueDepth requests for at most
+	// CompletionQueueTimeout, both unbounded when zero. MaxBatchCompletions
+	// bounds the slots the batch completions, summaries and titles among
+	// them, may take. UserCompletionLimit is queue, the default, or reject
+	// for the completions of a user past MaxCompletionsPerUser.
+	MaxConcurrentCompletions int
+	MaxCompletionsPerUser    int
+	UserCompletionLimit      string
+	MaxBatchCompletions      int
+	CompletionQueueDepth     int
+	CompletionQueueTimeout   time.Duration
+	// CompletionTierWeights is the share of the freed slots each tier gets
+	// while completions are queued, paid 3 and free 1 by default, and
+	// CompletionMaxWait promotes the completions queued longer than it
+	// whatever their tier, never when zero. TenantTiers is the tier of the
+	// calls of each tenant without one in their token, free for the others.
+	CompletionTierWeights map[string]int
+	CompletionMaxWait     time.Duration
+	TenantTiers           map[string]string
+	// StreamCapacity is how many chunks a stream consumer may lag behind,
+	// SlowConsumer what a turn does past it: wait, drop content chunks or
+	// fail after SlowConsumerTimeout.
+	StreamCapacity      int
+	SlowConsumer        string
+	SlowConsumerTimeout time.Duration
+	// StreamBufferTTL is how long the chunks of a finished answer stay
+	// buffered for the clients to resume or watch it, StreamBufferEvents how
+	// many of the last chunks of an answer are. The stream of an answer
+	// without a chunk for StreamBufferIdleTTL is dropped unfinished.
+	StreamBufferTTL     time.Duration
+	StreamBufferIdleTTL time.Duration
+	StreamBufferEvents  int
+	// FirstTokenTimeout and GenerationTimeout bound the wait for the first
+	// token of an answer and its whole generation.
+	FirstTokenTimeout time.Duration
+	GenerationTimeout time.Duration
+	// SlowFirstToken and SlowGeneration, when set, flag the answers whose
+	// first token or whole generation take longer, logged and counted in the
+	// metrics, well before the timeouts fail them.
+	SlowFirstToken time.Duration
+	SlowGeneration time.Duration
+	// HeartbeatInterval is how long a streamed answer may go silent before a
+	// heartbeat is sent, so proxies keep the connection open.
+	HeartbeatInterval time.Duration
+	// ShutdownTimeout is how long the completions in flight may run once the
+	// server is asked to stop, those left are stopped and their partial
+	// answers saved.
+	ShutdownTimeout time.Duration
+	// OTLPEndpoint, when set, exports the traces of the calls to the
+	// OpenTelemetry collector at this base URL, as TraceService with
+	// OTLPHeaders sent along.
+	OTLPEndpoint string
+	OTLPHeaders  map[string]string
+	TraceService string
+	// SentryDSN, when set, reports the panics of the calls and the turns
+	// failing unexpectedly to the Sentry project of this DSN, or of a
+	// compatible tracker, tagged with Release and SentryEnvironment.
+	SentryDSN         string
+	SentryEnvironment string
+	Release           string
+}
+
+func Load() (*Config, error) {
+	cfg := &Config{
+		Namespace:     os.Getenv("APP_NAMESPACE"),
+		DBDriver:      getenv("DB_DRIVER", "postgres"),
+		DatabaseURL:   os.Getenv("DATABASE_URL"),
+		MongoDatabase: getenv("MONGO_DATABASE", "chat_service"),
+		RedisURL:      os.Getenv("REDIS_URL"),
+		ChatCacheTTL:  10 * time.Minute,
+		OpenAIAPIKey:  os.Getenv("OPENAI_API_KEY"),
+		Model:         getenv("APP_MODEL", "gpt-3.5-turbo"),
+		Storage:       getenv("APP_STORAGE", "local"),
+		StorageDir:    getenv("APP_STORAGE_DIR", "data/attachments"),
+		DynamoDB: dynamodb.Config{
+			Table:           os.Getenv("DYNAMODB_TABLE"),
+			Endpoint:        os.Getenv("DYNAMODB_ENDPOINT"),
+			Region:          os.Getenv("DYNAMODB_REGION"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		S3: storage.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			Prefix:          os.Getenv("S3_PREFIX"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		Encryption:    getenv("APP_ENCRYPTION", "none"),
+		EncryptionKey: os.Getenv("APP_ENCRYPTION_KEY"),
+		KMS: encryption.KMSConfig{
+			KeyID:           os.Getenv("KMS_KEY_ID"),
+			Endpoint:        os.Getenv("KMS_ENDPOINT"),
+			Region:          os.Getenv("KMS_REGION"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		DeletedChatRetention: 30 * 24 * time.Hour,
+		ExpiredChatRetention: 7 * 24 * time.Hour,
+		RetentionInterval:    time.Hour,
+		BackupStorage:        getenv("BACKUP_STORAGE", "s3"),
+		BackupDir:            getenv("BACKUP_DIR", "data/backups"),
+		BackupInterval:       24 * time.Hour,
+		BackupKeep:           7,
+		GRPCServerPort:       getenv("GRPC_SERVER_PORT", "50052"),
+		AuthToken:            os.Getenv("AUTH_TOKEN"),
+		HTTPServerPort:       getenv("HTTP_SERVER_PORT", "8080"),
+		MetricsPort:          os.Getenv("METRICS_PORT"),
+		AdminPort:            os.Getenv("ADMIN_PORT"),
+		GRPCTLSCert:          os.Getenv("GRPC_TLS_CERT"),
+		GRPCTLSKey:           os.Getenv("GRPC_TLS_KEY"),
+		GRPCTLSClientCA:      os.Getenv("GRPC_TLS_CLIENT_CA"),
+		OIDCIssuer:           os.Getenv("OIDC_ISSUER"),
+		OIDCAudience:         os.Getenv("OIDC_AUDIENCE"),
+		OIDCScope:            os.Getenv("OIDC_SCOPE"),
+		OIDCOrgClaim:         os.Getenv("OIDC_ORG_CLAIM"),
+		OIDCTierClaim:        os.Getenv("OIDC_TIER_CLAIM"),
+		OIDCKeysTTL:          time.Hour,
+		ModelMaxTokens:       4096,
+		InitialSystemMessage: getenv("APP_INITIAL_SYSTEM_MESSAGE", "You are a helpful assistant."),
+		WebhookInterval:      10 * time.Second,
+		EventsWebhookURL:     os.Getenv("EVENTS_WEBHOOK_URL"),
+		EventsWebhookSecret:  os.Getenv("EVENTS_WEBHOOK_SECRET"),
+		OutboxInterval:       5 * time.Second,
+		OutboxMaxAttempts:    entity.OutboxMaxAttempts,
+		ShutdownTimeout:      30 * time.Second,
+		FirstTokenTimeout:    30 * time.Second,
+		GenerationTimeout:    5 * time.Minute,
+		HeartbeatInterval:    15 * time.Second,
+		StreamBufferTTL:      5 * time.Minute,
+		StreamBufferIdleTTL:  10 * time.Minute,
+		StreamBufferEvents:   1024,
+		OTLPEndpoint:         os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		TraceService:         getenv("OTEL_SERVICE_NAME", "chat-service"),
+		SentryDSN:            os.Getenv("SENTRY_DSN"),
+		SentryEnvironment:    getenv("SENTRY_ENVIRONMENT", "production"),
+		Release:              os.Getenv("SENTRY_RELEASE"),
+	}
+	cfg.BackupS3 = cfg.S3
+	cfg.BackupS3.Bucket = getenv("BACKUP_S3_BUCKET", cfg.S3.Bucket)
+	cfg.BackupS3.Prefix = getenv("BACKUP_S3_PREFIX", "backups/")
+	if err := namespace.Validate(cfg.Namespace); err != nil {
+		return nil, fmt.Errorf("APP_NAMESPACE: %s", err.Error())
+	}
+	if v := os.Getenv("APP_SANDBOX"); v != "" {
+		sandbox, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("APP_SANDBOX: %s", err.Error())
+		}
+		cfg.Sandbox = sandbox
+	}
+	if v := os.Getenv("APP_FEATURE_FLAGS"); v != "" {
+		if err := json.Unmarshal([]byte(v), &cfg.FeatureFlags); err != nil {
+			return nil, fmt.Errorf("APP_FEATURE_FLAGS: %s", err.Error())
+		}
+	}
+	if v := os.Getenv("CHAT_CACHE_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("CHAT_CACHE_TTL: %s", err.Error())
+		}
+		cfg.ChatCacheTTL = ttl
+	}
+	if v := os.Getenv("APP_RETENTION"); v != "" {
+		retention, err := parseRetention(v)
+		if err != nil {
+			return nil, fmt.Errorf("APP_RETENTION: %s", err.Error())
+		}
+		cfg.Retention = retention
+	}
+	if v := os.Getenv("APP_DELETED_CHAT_RETENTION"); v != "" {
+		d, err := parseWindow(v)
+		if err != nil {
+			return nil, fmt.Errorf("APP_DELETED_CHAT_RETENTION: %s", err.Error())
+		}
+		cfg.DeletedChatRetention = d
+	}
+	if v := os.Getenv("APP_EXPIRED_CHAT_RETENTION"); v != "" {
+		d, err := parseWindow(v)
+		if err != nil {
+			return nil, fmt.Errorf("APP_EXPIRED_CHAT_RETENTION: %s", err.Error())
+		}
+		cfg.ExpiredChatRetention = d
+	}
+	if v := os.Getenv("APP_RETENTION_DRY_RUN"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("APP_RETENTION_DRY_RUN: %s", err.Error())
+		}
+		cfg.RetentionDryRun = dryRun
+	}
+	if v := os.Getenv("APP_RETENTION_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("APP_RETENTION_INTERVAL: %s", err.Error())
+		}
+		cfg.RetentionInterval = interval
+	}
+	if v := os.Getenv("BACKUP_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("BACKUP_INTERVAL: %s", err.Error())
+		}
+		cfg.BackupInterval = interval
+	}
+	if v := os.Getenv("BACKUP_KEEP"); v != "" {
+		keep, err := strconv.Atoi(v)
+		if err != nil || keep < 0 {
+			return nil, fmt.Errorf("BACKUP_KEEP: must be a positive number, or 0 to keep every backup")
+		}
+		cfg.BackupKeep = keep
+	}
+	if v := os.Getenv("APP_AUDIT_LOG"); v != "" {
+		auditLog, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("APP_AUDIT_LOG: %s", err.Error())
+		}
+		cfg.AuditLog = auditLog
+	}
+	if v := os.Getenv("APP_DIAGNOSTICS"); v != "" {
+		diagnostics, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("APP_DIAGNOSTICS: %s", err.Error())
+		}
+		cfg.Diagnostics = diagnostics
+	}
+	if cfg.Diagnostics && cfg.AdminPort == "" && cfg.MetricsPort == "" {
+		return nil, fmt.Errorf("APP_DIAGNOSTICS: requires ADMIN_PORT or METRICS_PORT")
+	}
+	policy, err := parseAuditPolicy(getenv("APP_AUDIT_REDACTION", "hash"), os.Getenv("APP_AUDIT_TENANT_REDACTION"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.AuditPolicy = policy
+	if v := os.Getenv("APP_MODEL_MAX_TOKENS"); v != "" {
+		maxTokens, err := strconv.Atoi(v)
+		if err != nil || maxTokens <= 0 {
+			return nil, fmt.Errorf("APP_MODEL_MAX_TOKENS: must be a positive number")
+		}
+		cfg.ModelMaxTokens = maxTokens
+	}
+	if v := os.Getenv("APP_RATE_LIMIT_PER_MINUTE"); v != "" {
+		perMinute, err := strconv.ParseFloat(v, 64)
+		if err != nil || perMinute <= 0 {
+			return nil, fmt.Errorf("APP_RATE_LIMIT_PER_MINUTE: must be a positive number")
+		}
+		cfg.RateLimitPerMinute = perMinute
+		cfg.RateLimitBurst = int(math.Ceil(perMinute))
+	}
+	if v := os.Getenv("APP_RATE_LIMIT_BURST"); v != "" {
+		burst, err := strconv.Atoi(v)
+		if err != nil || burst <= 0 {
+			return nil, fmt.Errorf("APP_RATE_LIMIT_BURST: must be a positive number")
+		}
+		cfg.RateLimitBurst = burst
+	}
+	if v := os.Getenv("APP_RATE_LIMIT_MAX_WAIT"); v != "" {
+		maxWait, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("APP_RATE_LIMIT_MAX_WAIT: %s", err.Error())
+		}
+		cfg.RateLimitMaxWait = maxWait
+	}
+	for _, setting := range []struct {
+		env string
+		dst *int
+	}{
+		{"APP_MAX_CONCURRENT_COMPLETIONS", &cfg.MaxConcurrentCompletions},
+		{"APP_MAX_COMPLETIONS_PER_USER", &cfg.MaxCompletionsPerUser},
+		{"APP_MAX_BATCH_COMPLETIONS", &cfg.MaxBatchCompletions},
+		{"APP_COMPLETION_QUEUE_DEPTH", &cfg.CompletionQueueDepth},
+		{"APP_STREAM_CAPACITY", &cfg.StreamCapacity},
+		{"APP_STREAM_BUFFER_EVENTS", &cfg.StreamBufferEvents},
+	} {
+		if v := os.Getenv(setting.env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("%s: must be a positive number", setting.env)
+			}
+			*setting.dst = n
+		}
+	}
+	if v := os.Getenv("APP_COMPLETION_QUEUE_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("APP_COMPLETION_QUEUE_TIMEOUT: must be a positive duration")
+		}
+		cfg.CompletionQueueTimeout = timeout
+	}
+	if v := os.Getenv("APP_COMPLETION_MAX_WAIT"); v != "" {
+		maxWait, err := time.ParseDuration(v)
+		if err != nil || maxWait <= 0 {
+			return nil, fmt.Errorf("APP_COMPLETION_MAX_WAIT: must be a positive duration")
+		}
+		cfg.CompletionMaxWait = maxWait
+	}
+	if v := os.Getenv("APP_COMPLETION_TIER_WEIGHTS"); v != "" {
+		weights, err := parseTierWeights(v)
+		if err != nil {
+			return nil, fmt.Errorf("APP_COMPLETION_TIER_WEIGHTS: %s", err.Error())
+		}
+		cfg.CompletionTierWeights = weights
+	}
+	if v := os.Getenv("APP_TENANT_TIERS"); v != "" {
+		tiers, err := parseTenantTiers(v, cfg.CompletionTierWeights)
+		if err != nil {
+			return nil, fmt.Errorf("APP_TENANT_TIERS: %s", err.Error())
+		}
+		cfg.TenantTiers = tiers
+	}
+	cfg.UserCompletionLimit = getenv("APP_USER_COMPLETION_LIMIT", "queue")
+	switch cfg.UserCompletionLimit {
+	case "queue", "reject":
+	default:
+		return nil, fmt.Errorf("APP_USER_COMPLETION_LIMIT: must be queue or reject")
+	}
+	cfg.LogLevel = getenv("LOG_LEVEL", "info")
+	switch cfg.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return nil, fmt.Errorf("LOG_LEVEL: must be debug, info, warn or error")
+	}
+	cfg.LogFormat = getenv("LOG_FORMAT", "text")
+	switch cfg.LogFormat {
+	case "text", "json":
+	default:
+		return nil, fmt.Errorf("LOG_FORMAT: must be text or json")
+	}
+	cfg.SlowConsumer = getenv("APP_SLOW_CONSUMER", "wait")
+	switch cfg.SlowConsumer {
+	case "wait", "drop", "fail":
+	default:
+		return nil, fmt.Errorf("APP_SLOW_CONSUMER: must be wait, drop or fail")
+	}
+	if v := os.Getenv("APP_SLOW_CONSUMER_TIMEOUT"); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("APP_SLOW_CONSUMER_TIMEOUT: must be a positive duration")
+		}
+		cfg.SlowConsumerTimeout = timeout
+	}
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"); v != "" {
+		cfg.OTLPHeaders = map[string]string{}
+		for _, pair := range strings.Split(v, ",") {
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_HEADERS: %q is not a key=value pair", pair)
+			}
+			cfg.OTLPHeaders[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	if v := os.Getenv("GRPC_TLS_SPIFFE_IDS"); v != "" {
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); !strings.HasPrefix(id, "spiffe://") {
+				return nil, fmt.Errorf("GRPC_TLS_SPIFFE_IDS: %q is not a SPIFFE ID", id)
+			}
+			cfg.GRPCTLSSPIFFEIDs = append(cfg.GRPCTLSSPIFFEIDs, id)
+		}
+	}
+	for _, setting := range []struct {
+		env string
+		dst *time.Duration
+	}{
+		{"GRPC_KEEPALIVE_TIME", &cfg.GRPCKeepaliveTime},
+		{"GRPC_KEEPALIVE_TIMEOUT", &cfg.GRPCKeepaliveTimeout},
+		{"GRPC_KEEPALIVE_MIN_TIME", &cfg.GRPCKeepaliveMinTime},
+		{"APP_SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout},
+		{"APP_STREAM_BUFFER_TTL", &cfg.StreamBufferTTL},
+		{"APP_STREAM_BUFFER_IDLE_TTL", &cfg.StreamBufferIdleTTL},
+		{"APP_FIRST_TOKEN_TIMEOUT", &cfg.FirstTokenTimeout},
+		{"APP_GENERATION_TIMEOUT", &cfg.GenerationTimeout},
+		{"APP_SLOW_FIRST_TOKEN", &cfg.SlowFirstToken},
+		{"APP_SLOW_GENERATION", &cfg.SlowGeneration},
+		{"APP_HEARTBEAT_INTERVAL", &cfg.HeartbeatInterval},
+	} {
+		if v := os.Getenv(setting.env); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("%s: must be a positive duration", setting.env)
+			}
+			*setting.dst = d
+		}
+	}
+	for _, setting := range []struct {
+		env string
+		dst *int
+	}{
+		{"GRPC_MAX_RECV_MSG_SIZE", &cfg.GRPCMaxRecvMsgSize},
+		{"GRPC_MAX_SEND_MSG_SIZE", &cfg.GRPCMaxSendMsgSize},
+		{"GRPC_WINDOW_SIZE", &cfg.GRPCWindowSize},
+		{"GRPC_CONN_WINDOW_SIZE", &cfg.GRPCConnWindowSize},
+	} {
+		if v := os.Getenv(setting.env); v != "" {
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("%s: must be a positive number of bytes", setting.env)
+			}
+			*setting.dst = int(n)
+		}
+	}
+	// gRPC ignores windows smaller than its default of 64KiB
+	if (cfg.GRPCWindowSize > 0 && cfg.GRPCWindowSize < 64<<10) || (cfg.GRPCConnWindowSize > 0 && cfg.GRPCConnWindowSize < 64<<10) {
+		return nil, fmt.Errorf("GRPC_WINDOW_SIZE and GRPC_CONN_WINDOW_SIZE: must be at least 65536")
+	}
+	if (cfg.GRPCTLSCert == "") != (cfg.GRPCTLSKey == "") {
+		return nil, fmt.Errorf("GRPC_TLS_CERT and GRPC_TLS_KEY: must be set together")
+	}
+	if cfg.GRPCTLSClientCA != "" && cfg.GRPCTLSCert == "" {
+		return nil, fmt.Errorf("GRPC_TLS_CLIENT_CA: requires GRPC_TLS_CERT")
+	}
+	if len(cfg.GRPCTLSSPIFFEIDs) > 0 && cfg.GRPCTLSClientCA == "" {
+		return nil, fmt.Errorf("GRPC_TLS_SPIFFE_IDS: requires GRPC_TLS_CLIENT_CA")
+	}
+	if v := os.Getenv("OIDC_KEYS_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("OIDC_KEYS_TTL: must be a positive duration")
+		}
+		cfg.OIDCKeysTTL = ttl
+	}
+	if v := os.Getenv("APP_WEBHOOK_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("APP_WEBHOOK_INTERVAL: must be a positive duration")
+		}
+		cfg.WebhookInterval = interval
+	}
+	if cfg.EventsWebhookURL != "" {
+		u, err := url.Parse(cfg.EventsWebhookURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("EVENTS_WEBHOOK_URL: must be an absolute http or https url")
+		}
+		if cfg.EventsWebhookSecret == "" {
+			return nil, fmt.Errorf("EVENTS_WEBHOOK_SECRET: must be set with EVENTS_WEBHOOK_URL")
+		}
+	}
+	if v := os.Getenv("APP_OUTBOX_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("APP_OUTBOX_INTERVAL: must be a positive duration")
+		}
+		cfg.OutboxInterval = interval
+	}
+	if v := os.Getenv("APP_OUTBOX_MAX_ATTEMPTS"); v != "" {
+		attempts, err := strconv.Atoi(v)
+		if err != nil || attempts <= 0 {
+			return nil, fmt.Errorf("APP_OUTBOX_MAX_ATTEMPTS: must be a positive number")
+		}
+		cfg.OutboxMaxAttempts = attempts
+	}
+	if cfg.BackupStorage != "local" && cfg.BackupStorage != "s3" {
+		return nil, fmt.Errorf("BACKUP_STORAGE: unknown storage %q", cfg.BackupStorage)
+	}
+	switch cfg.DBDriver {
+	case "postgres", "mongodb":
+	case "sqlite":
+		if cfg.DatabaseURL == "" {
+			cfg.DatabaseURL = "data/chat.db"
+		}
+	case "dynamodb":
+		if cfg.DynamoDB.Table == "" {
+			return nil, fmt.Errorf("DYNAMODB_TABLE: required by the dynamodb driver")
+		}
+		if cfg.DynamoDB.Region == "" {
+			return nil, fmt.Errorf("DYNAMODB_REGION: required by the dynamodb driver")
+		}
+		if cfg.DatabaseURL == "" {
+			cfg.DatabaseURL = "data/chat.db"
+		}
+	default:
+		return nil, fmt.Errorf("DB_DRIVER: unknown driver %q", cfg.DBDriver)
+	}
+	if cfg.Storage != "local" && cfg.Storage != "s3" {
+		return nil, fmt.Errorf("APP_STORAGE: unknown storage %q", cfg.Storage)
+	}
+	switch cfg.Encryption {
+	case "none", "kms":
+	case "local":
+		if cfg.EncryptionKey == "" {
+			return nil, fmt.Errorf("APP_ENCRYPTION_KEY: required by local encryption")
+		}
+	default:
+		return nil, fmt.Errorf("APP_ENCRYPTION: unknown encryption %q", cfg.Encryption)
+	}
+	return cfg, nil
+}
+
+// parseTierWeights reads the weights of the completion tiers, comma
+// separated tier=weight pairs: paid=3,free=1. The free tier, the one of the
+// calls of no known tier, must have one.
+func parseTierWeights(v string) (map[string]int, error) {
+	weights := map[string]int{}
+	for _, pair := range strings.Split(v, ",") {
+		tier, weight, ok := strings.Cut(pair, "=")
+		tier = strings.TrimSpace(tier)
+		if !ok || tier == "" {
+			return nil, fmt.Errorf("%q is not a tier=weight pair", pair)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(weight))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("weight of tier %s must be a positive number", tier)
+		}
+		weights[tier] = n
+	}
+	if _, ok := weights[dispatcher.TierFree]; !ok {
+		return nil, fmt.Errorf("tier %s needs a weight", dispatcher.TierFree)
+	}
+	return weights, nil
+}
+
+// parseTenantTiers reads the completion tier of the tenants, comma separated
+// tenant=tier pairs: acme=paid. The tiers must be those of weights, of the
+// default weights when nil.
+func parseTenantTiers(v string, weights map[string]int) (map[string]string, error) {
+	if weights == nil {
+		weights = dispatcher.DefaultWeights()
+	}
+	tiers := map[string]string{}
+	for _, pair := range strings.Split(v, ",") {
+		tenant, tier, ok := strings.Cut(pair, "=")
+		tenant, tier = strings.TrimSpace(tenant), strings.TrimSpace(tier)
+		if !ok || tenant == "" {
+			return nil, fmt.Errorf("%q is not a tenant=tier pair", pair)
+		}
+		if _, ok := weights[tier]; !ok {
+			return nil, fmt.Errorf("unknown tier %q of tenant %s", tier, tenant)
+		}
+		tiers[tenant] = tier
+	}
+	return tiers, nil
+}
+
+// parseRetention reads a JSON object of retention windows by tenant, in days
+// or as durations, "*" for the other tenants: {"acme": "30d", "*": "2160h"}.
+func parseRetention(v string) (map[string]time.Duration, error) {
+	var windows map[string]string
+	if err := json.Unmarshal([]byte(v), &windows); err != nil {
+		return nil, err
+	}
+	retention := make(map[string]time.Duration, len(windows))
+	for orgID, window := range windows {
+		if orgID == "" {
+			return nil, errors.New("tenant must not be empty")
+		}
+		d, err := parseWindow(window)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", orgID, err.Error())
+		}
+		retention[orgID] = d
+	}
+	return retention, nil
+}
+
+// parseWindow reads a positive retention window, in days (30d) or as a
+// duration.
+func parseWindow(window string) (time.Duration, error) {
+	var d time.Duration
+	if days := strings.TrimSuffix(window, "d"); days != window {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid days %q", window)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(window); err != nil {
+			return 0, err
+		}
+	}
+	if d <= 0 {
+		return 0, errors.New("retention must be positive")
+	}
+	return d, nil
+}
+
+// parseAuditPolicy reads the default redaction of the audit log and the
+// JSON object of the redactions by tenant: {"acme": "full", "globex": "pii"}.
+func parseAuditPolicy(redaction, tenants string) (entity.AuditPolicy, error) {
+	var policy entity.AuditPolicy
+	var err error
+	if policy.Default, err = entity.ParseAuditRedaction(redaction); err != nil {
+		return policy, fmt.Errorf("APP_AUDIT_REDACTION: %s", err.Error())
+	}
+	if tenants == "" {
+		return policy, nil
+	}
+	var redactions map[string]string
+	if err := json.Unmarshal([]byte(tenants), &redactions); err != nil {
+		return policy, fmt.Errorf("APP_AUDIT_TENANT_REDACTION: %s", err.Error())
+	}
+	policy.Tenants = make(map[string]entity.AuditRedaction, len(redactions))
+	for orgID, r := range redactions {
+		if policy.Tenants[orgID], err = entity.ParseAuditRedaction(r); err != nil {
+			return policy, fmt.Errorf("APP_AUDIT_TENANT_REDACTION: %s: %s", orgID, err.Error())
+		}
+	}
+	return policy, nil
+}
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// NewClock returns the clock to inject in use cases and jobs, to be built
+// once per process and shared: a sandbox one in sandbox mode, the real one
+// otherwise. The requests signed for AWS stay on the real clock.
+func (c *Config) NewClock() clock.Clock {
+	if c.Sandbox {
+		return clock.NewSandbox()
+	}
+	return clock.Real()
+}
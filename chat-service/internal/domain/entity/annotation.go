@@ -0,0 +1,74 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AnnotationHighlight  = "highlight"
+	AnnotationComment    = "comment"
+	AnnotationCorrection = "correction"
+)
+
+// Annotation is a highlight, comment or correction a user anchored to the
+// [Start, End) character range of an assistant message.
+type Annotation struct {
+	ID        string
+	MessageID string
+	UserID    string
+	Kind      string
+	Start     int
+	End       int
+	Text      string
+	CreatedAt time.Time
+}
+
+func NewAnnotation(message *Message, userID, kind string, start, end int, text string, now time.Time) (*Annotation, error) {
+	annotation := &Annotation{
+		ID:        uuid.New().String(),
+		MessageID: message.ID,
+		UserID:    userID,
+		Kind:      kind,
+		Start:     start,
+		End:       end,
+		Text:      strings.TrimSpace(text),
+		CreatedAt: now,
+	}
+	if message.Role != RoleAssistant {
+		return nil, errors.New("only assistant messages can be annotated")
+	}
+	if err := annotation.Validate(message); err != nil {
+		return nil, err
+	}
+	return annotation, nil
+}
+
+func (a *Annotation) Validate(message *Message) error {
+	if a.UserID == "" {
+		return errors.New("user id is empty")
+	}
+	if a.Kind != AnnotationHighlight && a.Kind != AnnotationComment && a.Kind != AnnotationCorrection {
+		return errors.New("invalid annotation kind")
+	}
+	if a.Start < 0 || a.End <= a.Start || a.End > utf8.RuneCountInString(message.Content) {
+		return errors.New("annotation range is out of the message content")
+	}
+	if a.Kind != AnnotationHighlight && a.Text == "" {
+		return errors.New("annotation text is empty")
+	}
+	return nil
+}
+
+// Quote returns the part of the message content the annotation is anchored to.
+func (a *Annotation) Quote(message *Message) string {
+	runes := []rune(message.Content)
+	if a.End > len(runes) {
+		return ""
+	}
+	return string(runes[a.Start:a.End])
+}
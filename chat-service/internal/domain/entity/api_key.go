@@ -0,0 +1,123 @@
+package entity
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every API key, so leaked keys are easy to scan for.
+const APIKeyPrefix = "fclx_"
+
+// Scopes of an API key.
+const (
+	ScopeChatsRead   = "chats:read"
+	ScopeChatsWrite  = "chats:write"
+	ScopeCompletions = "completions"
+	ScopeUsageRead   = "usage:read"
+	ScopeAuditRead   = "audit:read"
+)
+
+var apiKeyScopes = map[string]bool{
+	ScopeChatsRead:   true,
+	ScopeChatsWrite:  true,
+	ScopeCompletions: true,
+	ScopeUsageRead:   true,
+	ScopeAuditRead:   true,
+}
+
+// APIKey lets a program act as its user, within its scopes, until revoked.
+// Only the hash of the key is kept, the key itself is shown once when
+// issued.
+type APIKey struct {
+	ID     string
+	UserID string
+	// OrgID is the tenant the calls of the key are made for, if any.
+	OrgID string
+	Name  string
+	Hash  string
+	// Hint is the end of the key, to tell the keys of a user apart.
+	Hint      string
+	Scopes    []string
+	CreatedAt time.Time
+	RevokedAt time.Time
+}
+
+// NewAPIKey issues a key, it returns the key along with it.
+func NewAPIKey(userID, orgID, name string, scopes []string, now time.Time) (*APIKey, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("error generating api key: %s", err.Error())
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	apiKey := &APIKey{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		OrgID:     orgID,
+		Name:      strings.TrimSpace(name),
+		Hash:      HashAPIKey(key),
+		Hint:      key[len(key)-4:],
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	if err := apiKey.Validate(); err != nil {
+		return nil, "", err
+	}
+	return apiKey, key, nil
+}
+
+// HashAPIKey is the hash keys are stored and looked up by. The keys are
+// random, a fast hash is enough.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (k *APIKey) Validate() error {
+	if k.UserID == "" {
+		return errors.New("api key needs a user")
+	}
+	if k.Name == "" {
+		return errors.New("api key name is empty")
+	}
+	if len(k.Name) > 100 {
+		return errors.New("api key name is longer than 100 characters")
+	}
+	if len(k.Scopes) == 0 {
+		return errors.New("api key needs a scope")
+	}
+	for _, scope := range k.Scopes {
+		if !apiKeyScopes[scope] {
+			return fmt.Errorf("unknown api key scope %q", scope)
+		}
+	}
+	return nil
+}
+
+func (k *APIKey) Revoke(now time.Time) error {
+	if k.IsRevoked() {
+		return errors.New("api key is already revoked")
+	}
+	k.RevokedAt = now
+	return nil
+}
+
+func (k *APIKey) IsRevoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+func (k *APIKey) Allows(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
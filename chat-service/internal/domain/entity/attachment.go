@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxAttachmentSize is the largest file that can be attached to a message.
+const MaxAttachmentSize = 25 << 20
+
+// Attachment is a file uploaded with, or generated for, a message. Its content
+// lives in the FileStorage under StorageKey.
+type Attachment struct {
+	ID         string
+	MessageID  string
+	Name       string
+	MimeType   string
+	Size       int64
+	StorageKey string
+	CreatedAt  time.Time
+}
+
+func NewAttachment(chatID string, message *Message, name, mimeType string, size int64, now time.Time) (*Attachment, error) {
+	id := uuid.New().String()
+	attachment := &Attachment{
+		ID:         id,
+		MessageID:  message.ID,
+		Name:       strings.TrimSpace(name),
+		MimeType:   strings.ToLower(strings.TrimSpace(mimeType)),
+		Size:       size,
+		StorageKey: fmt.Sprintf("chats/%s/%s", chatID, id),
+		CreatedAt:  now,
+	}
+	if err := attachment.Validate(); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (a *Attachment) Validate() error {
+	if a.Name == "" {
+		return errors.New("attachment name is empty")
+	}
+	if strings.ContainsAny(a.Name, "/\\") {
+		return errors.New("invalid attachment name")
+	}
+	if !strings.Contains(a.MimeType, "/") {
+		return errors.New("invalid attachment mime type")
+	}
+	if a.Size <= 0 || a.Size > MaxAttachmentSize {
+		return fmt.Errorf("attachment size must be between 1 and %d bytes", MaxAttachmentSize)
+	}
+	if a.StorageKey == "" {
+		return errors.New("attachment storage key is empty")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package entity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditRedaction is how the prompt and response of a turn are kept in the
+// audit log.
+type AuditRedaction string
+
+const (
+	// AuditRedactionHash keeps the SHA-256 of the content only, enough to
+	// prove what was sent without keeping it.
+	AuditRedactionHash AuditRedaction = "hash"
+	// AuditRedactionPII keeps the content with the personal data stripped.
+	AuditRedactionPII AuditRedaction = "pii"
+	// AuditRedactionFull keeps the content as it was sent.
+	AuditRedactionFull AuditRedaction = "full"
+)
+
+func ParseAuditRedaction(s string) (AuditRedaction, error) {
+	switch r := AuditRedaction(s); r {
+	case AuditRedactionHash, AuditRedactionPII, AuditRedactionFull:
+		return r, nil
+	}
+	return "", fmt.Errorf("unknown audit redaction %q, must be hash, pii or full", s)
+}
+
+// AuditPolicy is the redaction of the audit entries of each tenant, Default
+// for the tenants it has none for and the users of no tenant.
+type AuditPolicy struct {
+	Default AuditRedaction
+	Tenants map[string]AuditRedaction
+}
+
+// Redaction is the redaction of the entries of orgID, hash when none is
+// configured.
+func (p AuditPolicy) Redaction(orgID string) AuditRedaction {
+	if r, ok := p.Tenants[orgID]; ok && orgID != "" {
+		return r
+	}
+	if p.Default != "" {
+		return p.Default
+	}
+	return AuditRedactionHash
+}
+
+// AuditEntry records a turn: who sent what to which model and what came
+// back. The entries are only ever appended, Prompt and Response are kept as
+// Redaction tells.
+type AuditEntry struct {
+	ID        string
+	CreatedAt time.Time
+	OrgID     string
+	UserID    string
+	ChatID    string
+	// MessageID is the answer, RequestID the request it was generated for.
+	MessageID        string
+	RequestID        string
+	Model            string
+	Redaction        AuditRedaction
+	Prompt           string
+	Response         string
+	PromptTokens     int
+	CompletionTokens int
+	FinishReason     string
+}
+
+func NewAuditEntry(now time.Time, orgID, userID, chatID, messageID, model string) (*AuditEntry, error) {
+	e := &AuditEntry{
+		ID:        uuid.New().String(),
+		CreatedAt: now,
+		OrgID:     orgID,
+		UserID:    userID,
+		ChatID:    chatID,
+		MessageID: messageID,
+		Model:     model,
+		Redaction: AuditRedactionHash,
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *AuditEntry) Validate() error {
+	if e.UserID == "" {
+		return errors.New("audit entry needs a user")
+	}
+	if e.ChatID == "" {
+		return errors.New("audit entry needs a chat")
+	}
+	if e.Model == "" {
+		return errors.New("audit entry needs a model")
+	}
+	return nil
+}
+
+// HashAuditContent is the content kept by AuditRedactionHash.
+func HashAuditContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -2,10 +2,26 @@ package entity
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
 
+const (
+	ChatStatusActive   = "active"
+	ChatStatusEnded    = "ended"
+	ChatStatusArchived = "archived"
+)
+
+// chatTransitions lists the statuses a chat can move to from each status.
+var chatTransitions = map[string][]string{
+	ChatStatusActive: {ChatStatusEnded},
+	ChatStatusEnded:  {ChatStatusArchived},
+}
+
 type ChatConfig struct {
 	Model            *Model
 	Temperature      float32
@@ -15,29 +31,66 @@ type ChatConfig struct {
 	MaxTokens        int
 	PresencePenalty  float32
 	FrequencyPenalty float32
+	// AnswerMode is "direct" (default) or "outline_first", where the assistant
+	// streams an outline and waits for the user to confirm it before answering.
+	AnswerMode string
+	// Deterministic chats run with temperature 0 against the model snapshot
+	// served on their first turn.
+	Deterministic bool
+	// MaxMessages caps the messages kept in the context window, zero means no
+	// cap. EvictionPolicy tells what happens when a message exceeds it.
+	MaxMessages    int
+	EvictionPolicy string
 }
 
 type Chat struct {
 	ID                   string
 	UserID               string
+	Title                string
+	ParentChatID         string
+	ForkedFromMessageID  string
+	PersonaID            string
 	InitialSystemMessage *Message
 	Messages             []*Message
 	ErasedMessages       []*Message
 	Status               string
+	AnswerStage          string
+	SystemFingerprint    string
+	SummaryMessageID     string
 	TokenUsage           int
 	Config               *ChatConfig
+	Tags                 []string
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	DeletedAt            time.Time
+	// ExpiresAt is set on ephemeral chats only.
+	ExpiresAt time.Time
+	// OrgID is the tenant the chat was opened in, empty outside of one.
+	OrgID string
+	// SourceID is the ID the chat had in the file it was imported from, empty
+	// for the chats started here.
+	SourceID string
+	// Version is bumped by the gateway on every write, SaveChat fails with
+	// gateway.ErrChatConflict when the chat was written since it was loaded.
+	Version        int
+	events         []DomainEvent
+	savedEvents    int
+	pendingSummary []*Message
 }
 
-func NewChat(userID string, initialSystemMessage *Message, chatConfig *ChatConfig) (*Chat, error) {
+func NewChat(userID string, initialSystemMessage *Message, chatConfig *ChatConfig, now time.Time) (*Chat, error) {
 	chat := &Chat{
 		ID:                   uuid.New().String(),
 		UserID:               userID,
 		InitialSystemMessage: initialSystemMessage,
-		Status:               "active",
+		Status:               ChatStatusActive,
 		Config:               chatConfig,
 		TokenUsage:           0,
+		CreatedAt:            now,
+		UpdatedAt:            now,
 	}
-	chat.AddMessage(initialSystemMessage)
+	chat.record(ChatCreated{ChatID: chat.ID, UserID: userID, OccurredAt: now})
+	chat.AddMessage(initialSystemMessage, now)
 	if err := chat.Validate(); err != nil {
 		return nil, err
 	}
@@ -48,7 +101,7 @@ func (c *Chat) Validate() error {
 	if c.UserID == "" {
 		return errors.New("user id is empty")
 	}
-	if c.Status != "active" && c.Status != "ended" {
+	if c.Status != ChatStatusActive && c.Status != ChatStatusEnded && c.Status != ChatStatusArchived {
 		return errors.New("invalid status")
 	}
 	if c.Config.Temperature < 0 || c.Config.Temperature > 2 {
@@ -57,18 +110,42 @@ func (c *Chat) Validate() error {
 	if c.Config.Temperature < 0 || c.Config.Temperature > 2 {
 		return errors.New("invalid temperature")
 	}
+	if c.Config.AnswerMode != "" && c.Config.AnswerMode != "direct" && c.Config.AnswerMode != "outline_first" {
+		return errors.New("invalid answer mode")
+	}
+	if c.Config.MaxMessages < 0 {
+		return errors.New("invalid max messages")
+	}
+	if c.Config.EvictionPolicy != "" && c.Config.EvictionPolicy != EvictionReject &&
+		c.Config.EvictionPolicy != EvictionOldest && c.Config.EvictionPolicy != EvictionSummarize {
+		return errors.New("invalid eviction policy")
+	}
+	if c.AnswerStage != "" && c.AnswerStage != "awaiting_confirmation" {
+		return errors.New("invalid answer stage")
+	}
 	return nil
 }
 
-func (c *Chat) AddMessage(m *Message) error {
-	if c.Status == "ended" {
+func (c *Chat) AddMessage(m *Message, now time.Time) error {
+	if c.Status != ChatStatusActive {
 		return errors.New("chat ins ended. no more messages allowed")
 	}
+	if c.IsExpired(now) {
+		return errors.New("chat is expired. no more messages allowed")
+	}
+	if err := c.enforceMessageCap(); err != nil {
+		return err
+	}
+	if maxTokens := c.Config.Model.GetModelMaxTokens(); m.GetQtdTokens() > maxTokens {
+		return fmt.Errorf("%w: %d tokens, the model takes %d", ErrTokenLimitExceeded, m.GetQtdTokens(), maxTokens)
+	}
 
 	for {
 		if c.Config.Model.GetModelMaxTokens() >= m.GetQtdTokens()+c.TokenUsage {
 			c.Messages = append(c.Messages, m)
 			c.RefreshTokenUsage()
+			c.UpdatedAt = now
+			c.record(MessageAdded{ChatID: c.ID, MessageID: m.ID, Role: m.Role, Tokens: m.GetQtdTokens(), OccurredAt: now})
 			break
 		}
 		c.ErasedMessages = append(c.ErasedMessages, c.Messages[0])
@@ -79,6 +156,97 @@ func (c *Chat) AddMessage(m *Message) error {
 	return nil
 }
 
+// ReplaceMessage swaps the user message identified by messageID for m and drops
+// every message that came after it.
+func (c *Chat) ReplaceMessage(messageID string, m *Message, now time.Time) error {
+	if c.Status != ChatStatusActive {
+		return errors.New("chat ins ended. no more messages allowed")
+	}
+	for i, msg := range c.Messages {
+		if msg.ID != messageID {
+			continue
+		}
+		if msg.Role != RoleUser {
+			return errors.New("only user messages can be replaced")
+		}
+		c.Messages = c.Messages[:i]
+		c.RefreshTokenUsage()
+		return c.AddMessage(m, now)
+	}
+	return ErrMessageNotFound
+}
+
+// DiscardLastAnswer drops the assistant message that ends the chat, so it can
+// be generated again, and returns it. An outline awaiting confirmation goes
+// with it.
+func (c *Chat) DiscardLastAnswer(now time.Time) (*Message, error) {
+	if c.Status != ChatStatusActive {
+		return nil, errors.New("chat ins ended. no more messages allowed")
+	}
+	if len(c.Messages) == 0 || c.Messages[len(c.Messages)-1].Role != RoleAssistant {
+		return nil, errors.New("chat has no answer to regenerate")
+	}
+	last := c.Messages[len(c.Messages)-1]
+	c.Messages = c.Messages[:len(c.Messages)-1]
+	c.AnswerStage = ""
+	c.RefreshTokenUsage()
+	c.UpdatedAt = now
+	return last, nil
+}
+
+// Fork creates a new chat with the history of c up to and including messageID.
+// Messages are shared with c, only the slices are copied, so appending to either
+// chat doesn't affect the other.
+func (c *Chat) Fork(messageID string, now time.Time) (*Chat, error) {
+	for i, msg := range c.Messages {
+		if msg.ID != messageID {
+			continue
+		}
+		config := *c.Config
+		fork := &Chat{
+			ID:                   uuid.New().String(),
+			UserID:               c.UserID,
+			Title:                c.Title,
+			ParentChatID:         c.ID,
+			ForkedFromMessageID:  messageID,
+			PersonaID:            c.PersonaID,
+			InitialSystemMessage: c.InitialSystemMessage,
+			Messages:             append([]*Message(nil), c.Messages[:i+1]...),
+			ErasedMessages:       append([]*Message(nil), c.ErasedMessages...),
+			Status:               ChatStatusActive,
+			Config:               &config,
+			ExpiresAt:            c.ExpiresAt,
+			OrgID:                c.OrgID,
+			Tags:                 append([]string(nil), c.Tags...),
+			CreatedAt:            now,
+			UpdatedAt:            now,
+		}
+		fork.RefreshTokenUsage()
+		fork.record(ChatCreated{ChatID: fork.ID, UserID: fork.UserID, ParentChatID: c.ID, OccurredAt: now})
+		if err := fork.Validate(); err != nil {
+			return nil, err
+		}
+		return fork, nil
+	}
+	return nil, ErrMessageNotFound
+}
+
+// FindMessage looks a message up in the chat history, including the messages
+// erased from the context window.
+func (c *Chat) FindMessage(messageID string) (*Message, error) {
+	for _, msg := range c.Messages {
+		if msg.ID == messageID {
+			return msg, nil
+		}
+	}
+	for _, msg := range c.ErasedMessages {
+		if msg.ID == messageID {
+			return msg, nil
+		}
+	}
+	return nil, ErrMessageNotFound
+}
+
 func (c *Chat) GetMessages() []*Message {
 	return c.Messages
 }
@@ -87,8 +255,182 @@ func (c *Chat) CountMessages() int {
 	return len(c.Messages)
 }
 
-func (c *Chat) EndChat() {
-	c.Status = "ended"
+func (c *Chat) EndChat(now time.Time) error {
+	if err := c.transitionTo(ChatStatusEnded); err != nil {
+		return err
+	}
+	c.record(ChatEnded{ChatID: c.ID, OccurredAt: now})
+	return nil
+}
+
+// MaxTitleLength is the most runes of a chat title.
+const MaxTitleLength = 200
+
+func (c *Chat) SetTitle(title string) error {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return errors.New("title is empty")
+	}
+	if utf8.RuneCountInString(title) > MaxTitleLength {
+		return fmt.Errorf("title is longer than %d characters", MaxTitleLength)
+	}
+	c.Title = title
+	return nil
+}
+
+func (c *ChatConfig) ApplyDeterministicProfile() {
+	c.Deterministic = true
+	c.Temperature = 0
+	c.TopP = 1
+	c.N = 1
+}
+
+// RequestModel is the model name to send to the provider: the pinned snapshot
+// for deterministic chats that already had a turn, the configured model otherwise.
+func (c *Chat) RequestModel() string {
+	if c.Config.Deterministic && c.SystemFingerprint != "" {
+		return c.SystemFingerprint
+	}
+	return c.Config.Model.GetModelName()
+}
+
+// RecordFingerprint stores the fingerprint reported by the provider for the last
+// turn and tells whether it differs from the one of the previous turn.
+func (c *Chat) RecordFingerprint(fingerprint string) (changed bool, previous string) {
+	previous = c.SystemFingerprint
+	if fingerprint == "" || fingerprint == previous {
+		return false, previous
+	}
+	c.SystemFingerprint = fingerprint
+	return previous != "", previous
+}
+
+// MakeEphemeral makes the chat expire after ttl: it is archived then purged,
+// and kept out of exports and long-term memory.
+func (c *Chat) MakeEphemeral(now time.Time, ttl time.Duration) error {
+	if ttl <= 0 {
+		return errors.New("invalid ephemeral ttl")
+	}
+	c.ExpiresAt = now.Add(ttl)
+	return nil
+}
+
+func (c *Chat) IsEphemeral() bool {
+	return !c.ExpiresAt.IsZero()
+}
+
+func (c *Chat) IsExpired(now time.Time) bool {
+	return c.IsEphemeral() && !now.Before(c.ExpiresAt)
+}
+
+func (c *Chat) AllowsExport() bool {
+	return !c.IsEphemeral()
+}
+
+func (c *Chat) AllowsMemoryExtraction() bool {
+	return !c.IsEphemeral()
+}
+
+func (c *Chat) IsOutlineFirst() bool {
+	return c.Config.AnswerMode == "outline_first"
+}
+
+func (c *Chat) AwaitConfirmation() {
+	c.AnswerStage = "awaiting_confirmation"
+}
+
+func (c *Chat) IsAwaitingConfirmation() bool {
+	return c.AnswerStage == "awaiting_confirmation"
+}
+
+func (c *Chat) ConfirmOutline() error {
+	if !c.IsAwaitingConfirmation() {
+		return errors.New("chat is not awaiting an outline confirmation")
+	}
+	c.AnswerStage = ""
+	return nil
+}
+
+// Archive archives the chat, ending it first if it is still active.
+func (c *Chat) Archive(now time.Time) error {
+	if c.Status == ChatStatusActive {
+		if err := c.EndChat(now); err != nil {
+			return err
+		}
+	}
+	if err := c.transitionTo(ChatStatusArchived); err != nil {
+		return err
+	}
+	c.record(ChatArchived{ChatID: c.ID, OccurredAt: now})
+	return nil
+}
+
+func (c *Chat) transitionTo(status string) error {
+	for _, allowed := range chatTransitions[c.Status] {
+		if allowed == status {
+			c.Status = status
+			return nil
+		}
+	}
+	return fmt.Errorf("chat can't go from %s to %s", c.Status, status)
+}
+
+func (c *Chat) Delete(now time.Time) error {
+	if c.IsDeleted() {
+		return errors.New("chat is already deleted")
+	}
+	c.DeletedAt = now
+	return nil
+}
+
+func (c *Chat) IsDeleted() bool {
+	return !c.DeletedAt.IsZero()
+}
+
+// NormalizeTag returns the canonical form tags are stored and matched with.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", errors.New("tag is empty")
+	}
+	if len(tag) > 50 {
+		return "", errors.New("tag is longer than 50 characters")
+	}
+	return tag, nil
+}
+
+func (c *Chat) HasTag(tag string) bool {
+	for _, t := range c.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Chat) AddTag(tag string) (string, error) {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return "", err
+	}
+	if !c.HasTag(tag) {
+		c.Tags = append(c.Tags, tag)
+	}
+	return tag, nil
+}
+
+func (c *Chat) RemoveTag(tag string) (string, error) {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return "", err
+	}
+	for i, t := range c.Tags {
+		if t == tag {
+			c.Tags = append(c.Tags[:i:i], c.Tags[i+1:]...)
+			return tag, nil
+		}
+	}
+	return "", ErrTagNotFound
 }
 
 func (c *Chat) RefreshTokenUsage() {
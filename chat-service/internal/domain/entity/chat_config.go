@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const maxStopSequences = 4
+
+type ConfigChange struct {
+	Field    string
+	OldValue string
+	NewValue string
+}
+
+// ConfigAuditEntry records who changed a chat config and how.
+type ConfigAuditEntry struct {
+	ID        string
+	ChatID    string
+	ActorID   string
+	Changes   []ConfigChange
+	CreatedAt time.Time
+}
+
+func NewConfigAuditEntry(chatID, actorID string, changes []ConfigChange, now time.Time) *ConfigAuditEntry {
+	return &ConfigAuditEntry{
+		ID:        uuid.New().String(),
+		ChatID:    chatID,
+		ActorID:   actorID,
+		Changes:   changes,
+		CreatedAt: now,
+	}
+}
+
+// ChatConfigUpdate holds the settings to change, nil fields are kept.
+type ChatConfigUpdate struct {
+	Temperature *float32
+	MaxTokens   *int
+	Stop        *[]string
+}
+
+// UpdateConfig applies update after validating it against the chat model and
+// returns what actually changed.
+func (c *Chat) UpdateConfig(update ChatConfigUpdate) ([]ConfigChange, error) {
+	config := *c.Config
+	var changes []ConfigChange
+	if update.Temperature != nil && *update.Temperature != config.Temperature {
+		if config.Deterministic {
+			return nil, errors.New("temperature of a deterministic chat can't be changed")
+		}
+		changes = append(changes, ConfigChange{"temperature", fmt.Sprint(config.Temperature), fmt.Sprint(*update.Temperature)})
+		config.Temperature = *update.Temperature
+	}
+	if update.MaxTokens != nil && *update.MaxTokens != config.MaxTokens {
+		if *update.MaxTokens < 0 || *update.MaxTokens > config.Model.GetModelMaxTokens() {
+			return nil, fmt.Errorf("max tokens must be between 0 and %d", config.Model.GetModelMaxTokens())
+		}
+		changes = append(changes, ConfigChange{"max_tokens", fmt.Sprint(config.MaxTokens), fmt.Sprint(*update.MaxTokens)})
+		config.MaxTokens = *update.MaxTokens
+	}
+	if update.Stop != nil && strings.Join(*update.Stop, "\x00") != strings.Join(config.Stop, "\x00") {
+		if len(*update.Stop) > maxStopSequences {
+			return nil, fmt.Errorf("at most %d stop sequences are allowed", maxStopSequences)
+		}
+		changes = append(changes, ConfigChange{"stop", fmt.Sprintf("%q", config.Stop), fmt.Sprintf("%q", *update.Stop)})
+		config.Stop = append([]string(nil), *update.Stop...)
+	}
+	previous := c.Config
+	c.Config = &config
+	if err := c.Validate(); err != nil {
+		c.Config = previous
+		return nil, err
+	}
+	return changes, nil
+}
+
+// ChangeSystemMessage replaces the initial system message, in the context window
+// too when it is still there.
+func (c *Chat) ChangeSystemMessage(m *Message) (*ConfigChange, error) {
+	if m.Role != RoleSystem {
+		return nil, errors.New("system message must have the system role")
+	}
+	previous := c.InitialSystemMessage
+	if previous != nil && previous.Content == m.Content {
+		return nil, nil
+	}
+	if len(c.Messages) > 0 && previous != nil && c.Messages[0].ID == previous.ID {
+		if c.TokenUsage-previous.GetQtdTokens()+m.GetQtdTokens() > c.Config.Model.GetModelMaxTokens() {
+			return nil, errors.New("system message doesn't fit in the model context")
+		}
+		c.Messages[0] = m
+		c.RefreshTokenUsage()
+	}
+	c.InitialSystemMessage = m
+	change := &ConfigChange{Field: "system_message", NewValue: m.Content}
+	if previous != nil {
+		change.OldValue = previous.Content
+	}
+	return change, nil
+}
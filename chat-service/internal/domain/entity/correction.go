@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CorrectionScopeChat = "chat"
+	CorrectionScopeUser = "user"
+)
+
+// Correction is a statement of an assistant message the user marked as wrong,
+// with what the right answer is. It is fed back into later completions of the
+// chat, or of every chat of the user when Scope is "user".
+type Correction struct {
+	ID         string
+	ChatID     string
+	MessageID  string
+	UserID     string
+	Statement  string
+	Correction string
+	Scope      string
+	CreatedAt  time.Time
+}
+
+func NewCorrection(chat *Chat, message *Message, statement, correction, scope string, now time.Time) (*Correction, error) {
+	c := &Correction{
+		ID:         uuid.New().String(),
+		ChatID:     chat.ID,
+		MessageID:  message.ID,
+		UserID:     chat.UserID,
+		Statement:  strings.TrimSpace(statement),
+		Correction: strings.TrimSpace(correction),
+		Scope:      scope,
+		CreatedAt:  now,
+	}
+	if c.Scope == "" {
+		c.Scope = CorrectionScopeChat
+	}
+	if message.Role != RoleAssistant {
+		return nil, errors.New("only assistant messages can be corrected")
+	}
+	if !strings.Contains(message.Content, c.Statement) {
+		return nil, errors.New("statement is not part of the message")
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Correction) Validate() error {
+	if c.Statement == "" {
+		return errors.New("statement is empty")
+	}
+	if c.Correction == "" {
+		return errors.New("correction is empty")
+	}
+	if c.Scope != CorrectionScopeChat && c.Scope != CorrectionScopeUser {
+		return errors.New("invalid correction scope")
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxDraftLength is the longest draft kept, in characters.
+const MaxDraftLength = 32000
+
+// Draft is the message a user is typing in a chat and hasn't sent yet.
+type Draft struct {
+	ChatID    string
+	UserID    string
+	Content   string
+	UpdatedAt time.Time
+}
+
+func NewDraft(chatID, userID, content string, now time.Time) (*Draft, error) {
+	draft := &Draft{
+		ChatID:    chatID,
+		UserID:    userID,
+		Content:   content,
+		UpdatedAt: now,
+	}
+	if err := draft.Validate(); err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+func (d *Draft) Validate() error {
+	if d.ChatID == "" || d.UserID == "" {
+		return errors.New("draft needs a chat and a user")
+	}
+	if utf8.RuneCountInString(d.Content) > MaxDraftLength {
+		return fmt.Errorf("draft is longer than %d characters", MaxDraftLength)
+	}
+	return nil
+}
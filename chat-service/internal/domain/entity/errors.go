@@ -0,0 +1,47 @@
+package entity
+
+import "errors"
+
+// Errors of the domain, for the transports to map to their status codes with
+// errors.Is whatever wraps them.
+var (
+	// ErrTokenLimitExceeded rejects a message longer than the context window
+	// of the model of its chat, no eviction could make room for it.
+	ErrTokenLimitExceeded = errors.New("message exceeds the token limit of the model")
+	// ErrInvalidRole rejects a role no message can have.
+	ErrInvalidRole = errors.New("invalid role")
+	// ErrProviderUnavailable is a provider call that failed, or a stream it
+	// broke, worth retrying.
+	ErrProviderUnavailable = errors.New("provider unavailable")
+	// ErrChatForbidden rejects a call on a chat, or a snapshot of one, of
+	// another user than the caller.
+	ErrChatForbidden = errors.New("chat belongs to another user")
+	// ErrAPIKeyForbidden and ErrWebhookForbidden reject a call on an API key
+	// of another user, a webhook of another tenant.
+	ErrAPIKeyForbidden  = errors.New("api key belongs to another user")
+	ErrWebhookForbidden = errors.New("webhook belongs to another tenant")
+	// ErrMessageNotFound, ErrTagNotFound and ErrAnnotationNotFound are the
+	// messages, tags and annotations a chat doesn't have.
+	ErrMessageNotFound    = errors.New("message not found")
+	ErrTagNotFound        = errors.New("tag not found")
+	ErrAnnotationNotFound = errors.New("annotation not found")
+)
+
+// ProviderError is a provider call of Op that failed with Err, it is an
+// ErrProviderUnavailable and unwraps to the error of the provider.
+type ProviderError struct {
+	Op  string
+	Err error
+}
+
+func (e *ProviderError) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ProviderError) Is(target error) bool {
+	return target == ErrProviderUnavailable
+}
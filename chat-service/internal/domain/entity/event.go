@@ -0,0 +1,80 @@
+package entity
+
+import "time"
+
+type DomainEvent interface {
+	EventName() string
+}
+
+type ChatCreated struct {
+	ChatID       string
+	UserID       string
+	ParentChatID string
+	OccurredAt   time.Time
+}
+
+func (ChatCreated) EventName() string { return "chat.created" }
+
+type MessageAdded struct {
+	ChatID     string
+	MessageID  string
+	Role       Role
+	Tokens     int
+	OccurredAt time.Time
+}
+
+func (MessageAdded) EventName() string { return "chat.message_added" }
+
+type ChatEnded struct {
+	ChatID     string
+	OccurredAt time.Time
+}
+
+func (ChatEnded) EventName() string { return "chat.ended" }
+
+type ChatArchived struct {
+	ChatID     string
+	OccurredAt time.Time
+}
+
+func (ChatArchived) EventName() string { return "chat.archived" }
+
+// ChatUpdated and ChatDeleted are published by the gateways on the partial
+// updates that don't go through SaveChat: title, tags, message flags and
+// soft deletes. They are in process only, they don't go to the outbox.
+type ChatUpdated struct {
+	ChatID     string
+	OccurredAt time.Time
+}
+
+func (ChatUpdated) EventName() string { return "chat.updated" }
+
+type ChatDeleted struct {
+	ChatID     string
+	OccurredAt time.Time
+}
+
+func (ChatDeleted) EventName() string { return "chat.deleted" }
+
+func (c *Chat) record(event DomainEvent) {
+	c.events = append(c.events, event)
+}
+
+// PendingEvents returns the events not written to the outbox yet, so a
+// repository can store them in the same transaction as the chat and then call
+// MarkEventsSaved. They stay available to PullEvents.
+func (c *Chat) PendingEvents() []DomainEvent {
+	return append([]DomainEvent(nil), c.events[c.savedEvents:]...)
+}
+
+func (c *Chat) MarkEventsSaved() {
+	c.savedEvents = len(c.events)
+}
+
+// PullEvents returns the events recorded since the last call and clears them.
+func (c *Chat) PullEvents() []DomainEvent {
+	events := c.events
+	c.events = nil
+	c.savedEvents = 0
+	return events
+}
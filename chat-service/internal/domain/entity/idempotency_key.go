@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// MaxIdempotencyKeyLength is the longest idempotency key accepted.
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyKey is a key a client sent a message with, so a retry of the
+// request gets the answer of the first one instead of a new generation.
+// MessageID is the answer, once it is saved.
+type IdempotencyKey struct {
+	Key    string
+	UserID string
+	// RequestHash tells a retry from another request reusing the key.
+	RequestHash string
+	ChatID      string
+	MessageID   string
+	CreatedAt   time.Time
+}
+
+func NewIdempotencyKey(key, userID, chatID, userMessage string, now time.Time) (*IdempotencyKey, error) {
+	k := &IdempotencyKey{
+		Key:         key,
+		UserID:      userID,
+		RequestHash: HashIdempotentRequest(chatID, userMessage),
+		ChatID:      chatID,
+		CreatedAt:   now,
+	}
+	if err := k.Validate(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// HashIdempotentRequest is the RequestHash of the message userMessage sent
+// to chatID.
+func HashIdempotentRequest(chatID, userMessage string) string {
+	sum := sha256.Sum256([]byte(chatID + "\x00" + userMessage))
+	return hex.EncodeToString(sum[:])
+}
+
+func (k *IdempotencyKey) Validate() error {
+	if k.Key == "" {
+		return errors.New("idempotency key is empty")
+	}
+	if len(k.Key) > MaxIdempotencyKeyLength {
+		return errors.New("idempotency key is longer than 255 characters")
+	}
+	if k.UserID == "" {
+		return errors.New("idempotency key needs a user")
+	}
+	return nil
+}
+
+// Complete records the answer of the request, in chatID.
+func (k *IdempotencyKey) Complete(chatID, messageID string) {
+	k.ChatID = chatID
+	k.MessageID = messageID
+}
+
+func (k *IdempotencyKey) IsCompleted() bool {
+	return k.MessageID != ""
+}
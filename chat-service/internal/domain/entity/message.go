@@ -10,14 +10,25 @@ import (
 
 type Message struct {
 	ID        string
-	Role      string
+	Role      Role
 	Content   string
 	Tokens    int
 	Model     *Model
 	CreatedAt time.Time
+	// generation metadata, only set on assistant messages
+	PromptTokens      int
+	TimeToFirstToken  time.Duration
+	GenerationLatency time.Duration
+	Annotations       []*Annotation
+	Attachments       []*Attachment
+	Pinned            bool
+	Starred           bool
+	// IncompleteReason is why the generation of an assistant message failed
+	// before the end of the answer, empty when it is complete.
+	IncompleteReason string
 }
 
-func NewMessage(role, content string, model *Model) (*Message, error) {
+func NewMessage(role Role, content string, model *Model, now time.Time) (*Message, error) {
 	totalTokens := tiktoken_go.CountTokens(model.GetModelName(), content)
 	msg := &Message{
 		ID:        uuid.New().String(),
@@ -25,7 +36,7 @@ func NewMessage(role, content string, model *Model) (*Message, error) {
 		Content:   content,
 		Tokens:    totalTokens,
 		Model:     model,
-		CreatedAt: time.Now(),
+		CreatedAt: now,
 	}
 	if err := msg.Validate(); err != nil {
 		return nil, err
@@ -34,8 +45,8 @@ func NewMessage(role, content string, model *Model) (*Message, error) {
 }
 
 func (m *Message) Validate() error {
-	if m.Role != "user" && m.Role != "system" && m.Role != "assistent" {
-		return errors.New("invalid role")
+	if !m.Role.IsValid() {
+		return ErrInvalidRole
 	}
 	if m.Content == "" {
 		return errors.New("content is empty")
@@ -46,6 +57,52 @@ func (m *Message) Validate() error {
 	return nil
 }
 
+func (m *Message) SetGenerationMetadata(promptTokens int, timeToFirstToken, generationLatency time.Duration) {
+	m.PromptTokens = promptTokens
+	m.TimeToFirstToken = timeToFirstToken
+	m.GenerationLatency = generationLatency
+}
+
+// MarkIncomplete keeps the message the provider failed to finish, with the
+// reason of the failure.
+func (m *Message) MarkIncomplete(reason string) {
+	m.IncompleteReason = reason
+}
+
+func (m *Message) IsIncomplete() bool {
+	return m.IncompleteReason != ""
+}
+
+func (m *Message) SetPinned(pinned bool) {
+	m.Pinned = pinned
+}
+
+func (m *Message) SetStarred(starred bool) {
+	m.Starred = starred
+}
+
+func (m *Message) AddAnnotation(a *Annotation) {
+	m.Annotations = append(m.Annotations, a)
+}
+
+func (m *Message) AddAttachment(a *Attachment) {
+	m.Attachments = append(m.Attachments, a)
+}
+
+func (m *Message) RemoveAnnotation(annotationID string) error {
+	for i, a := range m.Annotations {
+		if a.ID == annotationID {
+			m.Annotations = append(m.Annotations[:i:i], m.Annotations[i+1:]...)
+			return nil
+		}
+	}
+	return ErrAnnotationNotFound
+}
+
+func (m *Message) GetTotalTokens() int {
+	return m.PromptTokens + m.Tokens
+}
+
 func (m *Message) GetQtdTokens() int {
 	return m.Tokens
 }
@@ -0,0 +1,110 @@
+package entity
+
+import "errors"
+
+const (
+	// EvictionReject refuses new messages once the cap is reached (default).
+	EvictionReject = "reject"
+	// EvictionOldest erases the oldest messages to make room.
+	EvictionOldest = "evict_oldest"
+	// EvictionSummarize erases the oldest half of the messages and leaves them
+	// pending so the caller can replace them with a summary through ApplySummary.
+	EvictionSummarize = "summarize"
+)
+
+func (c *Chat) enforceMessageCap() error {
+	max := c.Config.MaxMessages
+	if max <= 0 || len(c.Messages) < max {
+		return nil
+	}
+	switch c.Config.EvictionPolicy {
+	case EvictionOldest:
+		for len(c.Messages) >= max {
+			if _, err := c.evictOldest(); err != nil {
+				return err
+			}
+		}
+	case EvictionSummarize:
+		// erase half of the window at once so that a summary isn't needed on every message
+		for n := (len(c.Messages) + 1) / 2; n > 0; n-- {
+			m, err := c.evictOldest()
+			if err != nil {
+				break
+			}
+			c.pendingSummary = append(c.pendingSummary, m)
+		}
+		if len(c.Messages) >= max {
+			return errors.New("chat reached its message limit")
+		}
+	default:
+		return errors.New("chat reached its message limit")
+	}
+	return nil
+}
+
+// evictOldest moves the oldest message to ErasedMessages, keeping the initial
+// system message and the summary in the context window.
+func (c *Chat) evictOldest() (*Message, error) {
+	for i, m := range c.Messages {
+		if c.isPinnedToContext(m) {
+			continue
+		}
+		c.ErasedMessages = append(c.ErasedMessages, m)
+		c.Messages = append(c.Messages[:i:i], c.Messages[i+1:]...)
+		c.RefreshTokenUsage()
+		return m, nil
+	}
+	return nil, errors.New("no message can be evicted")
+}
+
+func (c *Chat) isPinnedToContext(m *Message) bool {
+	return (c.InitialSystemMessage != nil && m.ID == c.InitialSystemMessage.ID) || m.ID == c.SummaryMessageID
+}
+
+// PendingSummary returns the messages evicted by the summarize policy that
+// still have to be folded into the chat summary, along with the current summary.
+func (c *Chat) PendingSummary() (previous *Message, evicted []*Message) {
+	if len(c.pendingSummary) == 0 {
+		return nil, nil
+	}
+	for _, m := range c.Messages {
+		if m.ID == c.SummaryMessageID {
+			previous = m
+		}
+	}
+	return previous, c.pendingSummary
+}
+
+// ApplySummary replaces the current summary, if any, with summary in place.
+// A first summary goes right after the initial system message, or first
+// without one.
+func (c *Chat) ApplySummary(summary *Message) error {
+	if summary.Role != RoleSystem {
+		return errors.New("summary must have the system role")
+	}
+	messages := make([]*Message, 0, len(c.Messages)+1)
+	replaced := false
+	for _, m := range c.Messages {
+		if c.SummaryMessageID != "" && m.ID == c.SummaryMessageID {
+			messages = append(messages, summary)
+			replaced = true
+			continue
+		}
+		messages = append(messages, m)
+	}
+	if !replaced {
+		at := 0
+		for i, m := range messages {
+			if c.InitialSystemMessage != nil && m.ID == c.InitialSystemMessage.ID {
+				at = i + 1
+				break
+			}
+		}
+		messages = append(messages[:at], append([]*Message{summary}, messages[at:]...)...)
+	}
+	c.Messages = messages
+	c.SummaryMessageID = summary.ID
+	c.pendingSummary = nil
+	c.RefreshTokenUsage()
+	return nil
+}
@@ -1,5 +1,7 @@
 package entity
 
+import "strings"
+
 type Model struct {
 	Name     string
 	MaxToken int
@@ -19,3 +21,64 @@ func (m *Model) GetModelMaxTokens() int {
 func (m *Model) GetModelName() string {
 	return m.Name
 }
+
+// ModelPrice is what a model costs, in US dollars per million tokens.
+type ModelPrice struct {
+	Prompt     float64
+	Completion float64
+}
+
+// modelPrices are the list prices of the known models, by name prefix so
+// dated snapshots share the price of their model.
+var modelPrices = map[string]ModelPrice{
+	"gpt-3.5-turbo": {Prompt: 0.5, Completion: 1.5},
+	"gpt-4":         {Prompt: 30, Completion: 60},
+	"gpt-4-32k":     {Prompt: 60, Completion: 120},
+	"gpt-4-turbo":   {Prompt: 10, Completion: 30},
+	"gpt-4o":        {Prompt: 2.5, Completion: 10},
+	"gpt-4o-mini":   {Prompt: 0.15, Completion: 0.6},
+}
+
+// Price returns the price of the model, matched on the longest known prefix
+// of its name, false for unknown models.
+func (m *Model) Price() (ModelPrice, bool) {
+	var price ModelPrice
+	matched := ""
+	for prefix, p := range modelPrices {
+		if strings.HasPrefix(m.Name, prefix) && len(prefix) > len(matched) {
+			price, matched = p, prefix
+		}
+	}
+	return price, matched != ""
+}
+
+// Cost is what the tokens of a turn cost in US dollars at the list price of
+// its model. Priced is false for unknown models, their costs are 0.
+type Cost struct {
+	Prompt     float64
+	Completion float64
+	Priced     bool
+}
+
+func (c Cost) Total() float64 {
+	return c.Prompt + c.Completion
+}
+
+// Cost is the cost of the tokens at the list price of the model.
+func (m *Model) Cost(promptTokens, completionTokens int) Cost {
+	price, ok := m.Price()
+	if !ok {
+		return Cost{}
+	}
+	return Cost{
+		Prompt:     float64(promptTokens) * price.Prompt / 1e6,
+		Completion: float64(completionTokens) * price.Completion / 1e6,
+		Priced:     true,
+	}
+}
+
+// EstimateCost is the cost in US dollars of the tokens at the list price of
+// the model, 0 for unknown models.
+func (m *Model) EstimateCost(promptTokens, completionTokens int) float64 {
+	return m.Cost(promptTokens, completionTokens).Total()
+}
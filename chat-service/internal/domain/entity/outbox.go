@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxEntry is a domain event waiting to be relayed to the broker. Entries are
+// written with the chat they come from and published at least once, so
+// consumers should deduplicate on ID.
+type OutboxEntry struct {
+	ID          string
+	ChatID      string
+	EventName   string
+	Payload     []byte
+	OccurredAt  time.Time
+	PublishedAt time.Time
+	Attempts    int
+	LastError   string
+	// DeadLetteredAt is when the entry ran out of attempts, it is no longer
+	// relayed.
+	DeadLetteredAt time.Time
+}
+
+// OutboxMaxAttempts bounds the attempts to publish an entry, it is then
+// dead-lettered so the next events of its chat can go.
+const OutboxMaxAttempts = 10
+
+func NewOutboxEntries(chatID string, events []DomainEvent, now time.Time) ([]*OutboxEntry, error) {
+	entries := make([]*OutboxEntry, 0, len(events))
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding %s event: %s", event.EventName(), err.Error())
+		}
+		entries = append(entries, &OutboxEntry{
+			ID:         uuid.New().String(),
+			ChatID:     chatID,
+			EventName:  event.EventName(),
+			Payload:    payload,
+			OccurredAt: now,
+		})
+	}
+	return entries, nil
+}
+
+func (e *OutboxEntry) IsPublished() bool {
+	return !e.PublishedAt.IsZero()
+}
@@ -0,0 +1,76 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Persona is a reusable assistant profile: the system prompt, model config and
+// tools a chat starts with when it references the persona.
+type Persona struct {
+	ID           string
+	Name         string
+	SystemPrompt string
+	Config       ChatConfig
+	Tools        []string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func NewPersona(name, systemPrompt string, config ChatConfig, tools []string, now time.Time) (*Persona, error) {
+	persona := &Persona{
+		ID:           uuid.New().String(),
+		Name:         strings.TrimSpace(name),
+		SystemPrompt: systemPrompt,
+		Config:       config,
+		Tools:        tools,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := persona.Validate(); err != nil {
+		return nil, err
+	}
+	return persona, nil
+}
+
+func (p *Persona) Validate() error {
+	if p.Name == "" {
+		return errors.New("persona name is empty")
+	}
+	if strings.TrimSpace(p.SystemPrompt) == "" {
+		return errors.New("persona system prompt is empty")
+	}
+	if p.Config.Model == nil || p.Config.Model.GetModelName() == "" {
+		return errors.New("persona model is empty")
+	}
+	if p.Config.Temperature < 0 || p.Config.Temperature > 2 {
+		return errors.New("invalid temperature")
+	}
+	return nil
+}
+
+func (p *Persona) Update(name, systemPrompt string, config ChatConfig, tools []string, now time.Time) error {
+	updated := *p
+	updated.Name = strings.TrimSpace(name)
+	updated.SystemPrompt = systemPrompt
+	updated.Config = config
+	updated.Tools = tools
+	if err := updated.Validate(); err != nil {
+		return err
+	}
+	updated.UpdatedAt = now
+	*p = updated
+	return nil
+}
+
+// NewChatConfig returns a copy of the persona config for a new chat.
+func (p *Persona) NewChatConfig() *ChatConfig {
+	config := p.Config
+	model := *p.Config.Model
+	config.Model = &model
+	config.Stop = append([]string(nil), p.Config.Stop...)
+	return &config
+}
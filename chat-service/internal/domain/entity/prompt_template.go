@@ -0,0 +1,75 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var placeholderPattern = regexp.MustCompile(`{{\s*([a-zA-Z0-9_]+)\s*}}`)
+
+type PromptTemplate struct {
+	ID        string
+	Name      string
+	Content   string
+	CreatedAt time.Time
+}
+
+func NewPromptTemplate(name, content string, now time.Time) (*PromptTemplate, error) {
+	template := &PromptTemplate{
+		ID:        uuid.New().String(),
+		Name:      strings.TrimSpace(name),
+		Content:   content,
+		CreatedAt: now,
+	}
+	if err := template.Validate(); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (t *PromptTemplate) Validate() error {
+	if t.Name == "" {
+		return errors.New("template name is empty")
+	}
+	if strings.TrimSpace(t.Content) == "" {
+		return errors.New("template content is empty")
+	}
+	return nil
+}
+
+// Variables returns the placeholder names used in the template, in order of appearance.
+func (t *PromptTemplate) Variables() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, match := range placeholderPattern.FindAllStringSubmatch(t.Content, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// Render replaces every {{name}} placeholder with its value. "today" defaults to
+// the date of now, any other placeholder without a value is an error.
+func (t *PromptTemplate) Render(vars map[string]string, now time.Time) (string, error) {
+	values := map[string]string{
+		"today": now.Format("2006-01-02"),
+	}
+	for k, v := range vars {
+		values[k] = v
+	}
+	for _, name := range t.Variables() {
+		if _, ok := values[name]; !ok {
+			return "", fmt.Errorf("missing value for template variable %s", name)
+		}
+	}
+	return placeholderPattern.ReplaceAllStringFunc(t.Content, func(placeholder string) string {
+		return values[placeholderPattern.FindStringSubmatch(placeholder)[1]]
+	}), nil
+}
@@ -0,0 +1,41 @@
+package entity
+
+import "fmt"
+
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	RoleTool      Role = "tool"
+	RoleFunction  Role = "function"
+)
+
+// legacyRoleAssistant is the misspelled role older rows were stored with.
+const legacyRoleAssistant = "assistent"
+
+// ParseRole converts a stored or received role into a Role. Gateways must read
+// roles through it so rows written with the legacy "assistent" spelling keep working.
+func ParseRole(s string) (Role, error) {
+	if s == legacyRoleAssistant {
+		return RoleAssistant, nil
+	}
+	role := Role(s)
+	if !role.IsValid() {
+		return "", fmt.Errorf("%w %q", ErrInvalidRole, s)
+	}
+	return role, nil
+}
+
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleSystem, RoleUser, RoleAssistant, RoleTool, RoleFunction:
+		return true
+	}
+	return false
+}
+
+func (r Role) String() string {
+	return string(r)
+}
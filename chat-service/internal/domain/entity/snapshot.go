@@ -0,0 +1,153 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Snapshot is a named restore point of a chat. It keeps its own copy of the
+// history, so later edits of the chat don't change what it restores.
+type Snapshot struct {
+	ID                   string
+	ChatID               string
+	UserID               string
+	Name                 string
+	Title                string
+	PersonaID            string
+	Config               ChatConfig
+	InitialSystemMessage *Message
+	Messages             []*Message
+	ErasedMessages       []*Message
+	TokenUsage           int
+	// ExpiresAt and OrgID carry over to the restored chat, an ephemeral chat
+	// restores ephemeral.
+	ExpiresAt time.Time
+	OrgID     string
+	CreatedAt time.Time
+}
+
+func (c *Chat) Snapshot(name string, now time.Time) (*Snapshot, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("snapshot name is empty")
+	}
+	if len(c.Messages) == 0 {
+		return nil, errors.New("chat has no messages")
+	}
+	messages, initial := copyHistory(c.Messages, c.InitialSystemMessage)
+	return &Snapshot{
+		ID:                   uuid.New().String(),
+		ChatID:               c.ID,
+		UserID:               c.UserID,
+		Name:                 name,
+		Title:                c.Title,
+		PersonaID:            c.PersonaID,
+		Config:               c.Config.copy(),
+		InitialSystemMessage: initial,
+		Messages:             messages,
+		ErasedMessages:       copyMessages(c.ErasedMessages),
+		TokenUsage:           c.TokenUsage,
+		ExpiresAt:            c.ExpiresAt,
+		OrgID:                c.OrgID,
+		CreatedAt:            now,
+	}, nil
+}
+
+func (s *Snapshot) LastMessageID() string {
+	return s.Messages[len(s.Messages)-1].ID
+}
+
+// Restore creates a new chat branched off the snapshotted chat with the history
+// the snapshot was taken with.
+func (s *Snapshot) Restore(now time.Time) (*Chat, error) {
+	config := s.Config.copy()
+	messages, initial := copyHistory(s.Messages, s.InitialSystemMessage)
+	chat := &Chat{
+		ID:                   uuid.New().String(),
+		UserID:               s.UserID,
+		Title:                s.Title,
+		ParentChatID:         s.ChatID,
+		ForkedFromMessageID:  s.LastMessageID(),
+		PersonaID:            s.PersonaID,
+		InitialSystemMessage: initial,
+		Messages:             messages,
+		ErasedMessages:       copyMessages(s.ErasedMessages),
+		Status:               ChatStatusActive,
+		Config:               &config,
+		ExpiresAt:            s.ExpiresAt,
+		OrgID:                s.OrgID,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+	}
+	chat.RefreshTokenUsage()
+	if err := chat.Validate(); err != nil {
+		return nil, err
+	}
+	chat.record(ChatCreated{ChatID: chat.ID, UserID: chat.UserID, ParentChatID: s.ChatID, OccurredAt: now})
+	return chat, nil
+}
+
+// copy returns a copy of the config that shares nothing with it.
+func (c ChatConfig) copy() ChatConfig {
+	if c.Model != nil {
+		model := *c.Model
+		c.Model = &model
+	}
+	c.Stop = append([]string(nil), c.Stop...)
+	return c
+}
+
+// copyHistory copies messages along with the initial system message among
+// them, which keeps pointing into the copy.
+func copyHistory(messages []*Message, initial *Message) ([]*Message, *Message) {
+	copied := copyMessages(messages)
+	var copiedInitial *Message
+	for i, m := range messages {
+		if m == initial {
+			copiedInitial = copied[i]
+			break
+		}
+	}
+	if copiedInitial == nil && initial != nil {
+		copiedInitial = copyMessage(initial)
+	}
+	return copied, copiedInitial
+}
+
+func copyMessages(messages []*Message) []*Message {
+	if messages == nil {
+		return nil
+	}
+	copied := make([]*Message, len(messages))
+	for i, m := range messages {
+		copied[i] = copyMessage(m)
+	}
+	return copied
+}
+
+// copyMessage copies m, its model, annotations and attachments.
+func copyMessage(m *Message) *Message {
+	copied := *m
+	if m.Model != nil {
+		model := *m.Model
+		copied.Model = &model
+	}
+	if m.Annotations != nil {
+		copied.Annotations = make([]*Annotation, len(m.Annotations))
+		for i, a := range m.Annotations {
+			annotation := *a
+			copied.Annotations[i] = &annotation
+		}
+	}
+	if m.Attachments != nil {
+		copied.Attachments = make([]*Attachment, len(m.Attachments))
+		for i, a := range m.Attachments {
+			attachment := *a
+			copied.Attachments[i] = &attachment
+		}
+	}
+	return &copied
+}
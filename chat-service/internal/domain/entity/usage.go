@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+// UsageRecord is what a user of a tenant consumed of a model during a day,
+// the unit usage is metered and billed in. OrgID is empty for the users of
+// no tenant.
+type UsageRecord struct {
+	// Day is the UTC midnight starting the day.
+	Day              time.Time
+	OrgID            string
+	UserID           string
+	Model            string
+	Turns            int64
+	PromptTokens     int64
+	CompletionTokens int64
+	// Cost is in US dollars at the list price of the model, 0 for unknown
+	// models. UnpricedTurns are the turns of a model of no known price, not
+	// counted in Cost.
+	Cost          float64
+	UnpricedTurns int64
+}
+
+// NewTurnUsage is the usage of one turn of userID answered by model at,
+// counted in the record of its day.
+func NewTurnUsage(at time.Time, orgID, userID string, model *Model, promptTokens, completionTokens int) (*UsageRecord, error) {
+	cost := model.Cost(promptTokens, completionTokens)
+	u := &UsageRecord{
+		Day:              UsageDay(at),
+		OrgID:            orgID,
+		UserID:           userID,
+		Model:            model.GetModelName(),
+		Turns:            1,
+		PromptTokens:     int64(promptTokens),
+		CompletionTokens: int64(completionTokens),
+		Cost:             cost.Total(),
+	}
+	if !cost.Priced {
+		u.UnpricedTurns = 1
+	}
+	if err := u.Validate(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// UsageDay is the UTC midnight starting the day of t.
+func UsageDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func (u *UsageRecord) Validate() error {
+	if u.UserID == "" {
+		return errors.New("usage needs a user")
+	}
+	if u.Model == "" {
+		return errors.New("usage needs a model")
+	}
+	if u.PromptTokens < 0 || u.CompletionTokens < 0 {
+		return errors.New("usage tokens are negative")
+	}
+	return nil
+}
+
+func (u *UsageRecord) TotalTokens() int64 {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Add counts other in u.
+func (u *UsageRecord) Add(other *UsageRecord) {
+	u.Turns += other.Turns
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.Cost += other.Cost
+	u.UnpricedTurns += other.UnpricedTurns
+}
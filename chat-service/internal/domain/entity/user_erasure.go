@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserErasure records that the data of a user was erased, and how much of it.
+// It is the proof of the erasure, so it keeps the user ID and nothing else
+// about the user.
+type UserErasure struct {
+	ID          string
+	UserID      string
+	RequestedBy string
+	Chats       int64
+	Messages    int64
+	Annotations int64
+	Attachments int64
+	Embeddings  int64
+	CreatedAt   time.Time
+}
+
+func NewUserErasure(userID, requestedBy string, now time.Time) (*UserErasure, error) {
+	if userID == "" {
+		return nil, errors.New("user id is empty")
+	}
+	if requestedBy == "" {
+		return nil, errors.New("requester is empty")
+	}
+	return &UserErasure{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		RequestedBy: requestedBy,
+		CreatedAt:   now,
+	}, nil
+}
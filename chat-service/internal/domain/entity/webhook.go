@@ -0,0 +1,170 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Events a webhook may subscribe to.
+const (
+	WebhookChatCreated      = "chat.created"
+	WebhookMessageCompleted = "message.completed"
+	WebhookGenerationFailed = "generation.failed"
+)
+
+var webhookEvents = map[string]bool{
+	WebhookChatCreated:      true,
+	WebhookMessageCompleted: true,
+	WebhookGenerationFailed: true,
+}
+
+// Webhook is a URL of a tenant the events it subscribes to are posted to,
+// signed with Secret.
+type Webhook struct {
+	ID        string
+	OrgID     string
+	URL       string
+	Events    []string
+	Secret    string
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+func NewWebhook(orgID, userID, rawURL string, events []string, now time.Time) (*Webhook, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("error generating webhook secret: %s", err.Error())
+	}
+	webhook := &Webhook{
+		ID:        uuid.New().String(),
+		OrgID:     orgID,
+		URL:       rawURL,
+		Events:    events,
+		Secret:    "whsec_" + base64.RawURLEncoding.EncodeToString(secret),
+		CreatedBy: userID,
+		CreatedAt: now,
+	}
+	if err := webhook.Validate(); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (w *Webhook) Validate() error {
+	if w.OrgID == "" {
+		return errors.New("webhook needs a tenant")
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New("webhook url must be an absolute http or https url")
+	}
+	if len(w.Events) == 0 {
+		return errors.New("webhook needs an event")
+	}
+	for _, event := range w.Events {
+		if !webhookEvents[event] {
+			return fmt.Errorf("unknown webhook event %q", event)
+		}
+	}
+	return nil
+}
+
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookMaxAttempts is how many times a delivery is tried before it is
+// dead-lettered.
+const WebhookMaxAttempts = 8
+
+// WebhookDelivery is an event on its way to a webhook. It is tried until
+// delivered or out of attempts, backing off between the attempts.
+type WebhookDelivery struct {
+	ID        string
+	WebhookID string
+	OrgID     string
+	Event     string
+	// Payload is the JSON body posted, the ID of the delivery for receivers
+	// to deduplicate on, the event and its data.
+	Payload       []byte
+	Attempts      int
+	NextAttemptAt time.Time
+	DeliveredAt   time.Time
+	// FailedAt is when the delivery ran out of attempts.
+	FailedAt  time.Time
+	LastError string
+	CreatedAt time.Time
+}
+
+type webhookPayload struct {
+	ID         string      `json:"id"`
+	Event      string      `json:"event"`
+	OrgID      string      `json:"org_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+func NewWebhookDelivery(webhook *Webhook, event string, data interface{}, now time.Time) (*WebhookDelivery, error) {
+	id := uuid.New().String()
+	payload, err := json.Marshal(webhookPayload{
+		ID:         id,
+		Event:      event,
+		OrgID:      webhook.OrgID,
+		OccurredAt: now.UTC(),
+		Data:       data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding %s webhook payload: %s", event, err.Error())
+	}
+	return &WebhookDelivery{
+		ID:            id,
+		WebhookID:     webhook.ID,
+		OrgID:         webhook.OrgID,
+		Event:         event,
+		Payload:       payload,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}, nil
+}
+
+func (d *WebhookDelivery) RecordDelivered(now time.Time) {
+	d.Attempts++
+	d.DeliveredAt = now
+	d.LastError = ""
+}
+
+// RecordFailed schedules the next attempt, doubling the wait from 30 seconds
+// up to 6 hours, or dead-letters the delivery on its last attempt.
+func (d *WebhookDelivery) RecordFailed(reason string, now time.Time) {
+	d.Attempts++
+	d.LastError = reason
+	if d.Attempts >= WebhookMaxAttempts {
+		d.FailedAt = now
+		return
+	}
+	backoff := 30 * time.Second << (d.Attempts - 1)
+	if backoff > 6*time.Hour {
+		backoff = 6 * time.Hour
+	}
+	d.NextAttemptAt = now.Add(backoff)
+}
+
+func (d *WebhookDelivery) IsPending() bool {
+	return d.DeliveredAt.IsZero() && d.FailedAt.IsZero()
+}
+
+func (d *WebhookDelivery) IsDead() bool {
+	return !d.FailedAt.IsZero()
+}
@@ -0,0 +1,14 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+// AnnotationGateway stores message annotations. ChatGateway implementations
+// return them on the messages of the chats they load.
+type AnnotationGateway interface {
+	CreateAnnotation(ctx context.Context, annotation *entity.Annotation) error
+	DeleteAnnotation(ctx context.Context, annotationID string) error
+}
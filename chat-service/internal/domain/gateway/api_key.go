@@ -0,0 +1,22 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type APIKeyGateway interface {
+	CreateAPIKey(ctx context.Context, key *entity.APIKey) error
+	// FindAPIKeyByID and FindAPIKeyByHash return ErrAPIKeyNotFound when
+	// there is no such key.
+	FindAPIKeyByID(ctx context.Context, id string) (*entity.APIKey, error)
+	FindAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error)
+	// ListAPIKeysByUser returns the keys of userID newest first, revoked
+	// ones included.
+	ListAPIKeysByUser(ctx context.Context, userID string) ([]*entity.APIKey, error)
+	RevokeAPIKey(ctx context.Context, key *entity.APIKey) error
+}
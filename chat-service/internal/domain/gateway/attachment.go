@@ -0,0 +1,26 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+// AttachmentGateway stores attachment metadata. ChatGateway implementations
+// return attachments on the messages of the chats they load.
+type AttachmentGateway interface {
+	CreateAttachment(ctx context.Context, attachment *entity.Attachment) error
+	DeleteAttachment(ctx context.Context, attachmentID string) error
+}
+
+var ErrFileNotFound = errors.New("file not found")
+
+// FileStorage keeps the content of attachments by storage key.
+type FileStorage interface {
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	// Get returns ErrFileNotFound when nothing is stored under key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,28 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+// AuditFilter selects the audit entries created from From, included, to To,
+// excluded, up to Limit of them. The empty fields match every tenant, user
+// or chat.
+type AuditFilter struct {
+	OrgID  string
+	UserID string
+	ChatID string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+// AuditLogGateway keeps the audit trail of the turns, append only: an entry
+// is never updated nor deleted but with its namespace.
+type AuditLogGateway interface {
+	AppendAuditEntry(ctx context.Context, entry *entity.AuditEntry) error
+	// ListAuditEntries returns the entries matching filter, newest first.
+	ListAuditEntries(ctx context.Context, filter AuditFilter) ([]*entity.AuditEntry, error)
+}
@@ -2,12 +2,88 @@ package gateway
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
 )
 
+// ChatFilter narrows ListChats down, zero fields don't filter.
+type ChatFilter struct {
+	UserID        string
+	Tag           string
+	Status        string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// SourceID finds the chats imported from a chat of that ID.
+	SourceID string
+	Limit    int
+	Offset   int
+}
+
+// ChatPage is a page of ListChatsByUser, Total counts the chats of every page.
+type ChatPage struct {
+	Chats []*entity.Chat
+	Total int
+}
+
+// MessageCursor pages through the active messages of a chat from the newest
+// to the oldest. Before is the ID of the oldest message already fetched, empty
+// for the first page; ListMessages fails with ErrInvalidCursor when it isn't
+// a message of the chat.
+type MessageCursor struct {
+	Before string
+	Limit  int
+}
+
+// MessagePage holds messages oldest first. Next is nil on the last page.
+type MessagePage struct {
+	// ChatUserID is the owner of the chat, for use cases to check access.
+	ChatUserID string
+	Messages   []*entity.Message
+	Next       *MessageCursor
+}
+
+// ErrChatConflict is returned by SaveChat when the chat was written by someone
+// else since it was loaded, the caller should reload it and apply its changes again.
+var ErrChatConflict = errors.New("chat was updated concurrently")
+
+// ErrInvalidCursor is returned by ListMessages for a cursor that isn't one of
+// the chat.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrChatNotFound is returned for the chats that don't exist, or were deleted
+// for the calls that skip deleted chats.
+var ErrChatNotFound = errors.New("chat not found")
+
 type ChatGateway interface {
+	// CreateChat and SaveChat also write chat.PendingEvents() to the outbox in
+	// the same transaction, then call chat.MarkEventsSaved (see OutboxGateway).
+	// Both bump chat.Version, like every other write to the chat.
 	CreateChat(ctx context.Context, chat *entity.Chat) error
 	FindChatByID(ctx context.Context, chatID string) (*entity.Chat, error)
+	FindChatsByParentID(ctx context.Context, parentChatID string) ([]*entity.Chat, error)
+	// ListChats returns the chats matching filter, most recently updated first,
+	// without their messages.
+	ListChats(ctx context.Context, filter ChatFilter) ([]*entity.Chat, error)
+	// ListChatsByUser returns the page-th page (from 1) of size chats of the
+	// user, most recently updated first, without their messages.
+	ListChatsByUser(ctx context.Context, userID string, page int, size int) (*ChatPage, error)
+	// ListMessages returns ErrChatNotFound for missing and deleted chats.
+	ListMessages(ctx context.Context, chatID string, cursor MessageCursor) (*MessagePage, error)
 	SaveChat(ctx context.Context, chat *entity.Chat) error
+	UpdateChatTitle(ctx context.Context, chatID string, title string) error
+	AddChatTag(ctx context.Context, chatID string, tag string) error
+	RemoveChatTag(ctx context.Context, chatID string, tag string) error
+	SetMessageFlags(ctx context.Context, chatID string, messageID string, pinned bool, starred bool) error
+	// DeleteChat soft-deletes a chat: it is kept with its deleted_at set and
+	// FindChatByID and listings must treat it as not found.
+	DeleteChat(ctx context.Context, chatID string, deletedAt time.Time) error
+	// PurgeDeletedChats hard-deletes the chats soft-deleted before deletedBefore.
+	PurgeDeletedChats(ctx context.Context, deletedBefore time.Time) (int64, error)
+	// FindExpiredChats returns ephemeral chats that expired before expiredBefore
+	// and are not archived yet.
+	FindExpiredChats(ctx context.Context, expiredBefore time.Time, limit int) ([]*entity.Chat, error)
+	// PurgeExpiredChats hard-deletes the ephemeral chats that expired before expiredBefore.
+	PurgeExpiredChats(ctx context.Context, expiredBefore time.Time) (int64, error)
 }
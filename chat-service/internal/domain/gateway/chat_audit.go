@@ -0,0 +1,13 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+type ChatAuditGateway interface {
+	CreateConfigAuditEntry(ctx context.Context, entry *entity.ConfigAuditEntry) error
+	// ListConfigAuditEntries returns the config changes of a chat, newest first.
+	ListConfigAuditEntries(ctx context.Context, chatID string) ([]*entity.ConfigAuditEntry, error)
+}
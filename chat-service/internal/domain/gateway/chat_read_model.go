@@ -0,0 +1,56 @@
+package gateway
+
+import (
+	"context"
+	"time"
+)
+
+// ChatSummary is the denormalized row of a chat in the read model, what
+// listings and searches show. It holds no message content, which may be
+// encrypted at rest in the write store.
+type ChatSummary struct {
+	ChatID        string
+	UserID        string
+	ParentChatID  string
+	Title         string
+	Status        string
+	Tags          []string
+	Model         string
+	MessageCount  int
+	TokenUsage    int
+	LastMessageAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	// Version is the version of the chat the summary was projected from.
+	Version     int
+	ProjectedAt time.Time
+}
+
+// ChatSummaryFilter narrows the summaries down, zero fields don't filter.
+// Query matches the titles containing it, ignoring case.
+type ChatSummaryFilter struct {
+	UserID        string
+	Tag           string
+	Status        string
+	Query         string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Limit         int
+	Offset        int
+}
+
+// ChatReadModel is the store of the chat summaries, kept apart from the chats
+// so listing traffic doesn't contend with the writes of the completions. It
+// is eventually consistent, the projection updates it after the writes.
+type ChatReadModel interface {
+	// UpsertChatSummary keeps the stored summary when its Version is higher,
+	// so a late projection of an older copy of the chat can't overwrite it.
+	UpsertChatSummary(ctx context.Context, summary *ChatSummary) error
+	DeleteChatSummary(ctx context.Context, chatID string) error
+	// ListChatSummaries returns the matching summaries, most recently updated first.
+	ListChatSummaries(ctx context.Context, filter ChatSummaryFilter) ([]*ChatSummary, error)
+	CountChatSummaries(ctx context.Context, filter ChatSummaryFilter) (int, error)
+	// PurgeStaleChatSummaries deletes the summaries projected before
+	// projectedBefore, the ones a rebuild started then didn't reach.
+	PurgeStaleChatSummaries(ctx context.Context, projectedBefore time.Time) (int64, error)
+}
@@ -0,0 +1,15 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+type CorrectionGateway interface {
+	CreateCorrection(ctx context.Context, correction *entity.Correction) error
+	// FindCorrectionsByChatID returns the corrections made in a chat, newest first.
+	FindCorrectionsByChatID(ctx context.Context, chatID string, limit int) ([]*entity.Correction, error)
+	// FindUserCorrections returns the user-scoped corrections of a user, newest first.
+	FindUserCorrections(ctx context.Context, userID string, limit int) ([]*entity.Correction, error)
+}
@@ -0,0 +1,18 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+var ErrDraftNotFound = errors.New("draft not found")
+
+// DraftGateway keeps one draft per chat and user, saving replaces it.
+type DraftGateway interface {
+	SaveDraft(ctx context.Context, draft *entity.Draft) error
+	// FindDraft returns ErrDraftNotFound when there is no draft.
+	FindDraft(ctx context.Context, chatID string, userID string) (*entity.Draft, error)
+	DeleteDraft(ctx context.Context, chatID string, userID string) error
+}
@@ -0,0 +1,61 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	EmbeddingSourceMessage  = "message"
+	EmbeddingSourceDocument = "document"
+)
+
+var ErrEmbeddingDimensions = errors.New("embedding has the wrong number of dimensions")
+
+// Embedding is the vector Model computed for the text Content of a source, a
+// message or a document. A source has one embedding per model. ChatID is
+// empty for documents that belong to no chat.
+type Embedding struct {
+	ID         string
+	SourceType string
+	SourceID   string
+	ChatID     string
+	UserID     string
+	Model      string
+	Content    string
+	Vector     []float32
+	Metadata   map[string]string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// EmbeddingQuery looks for the embeddings of Model closest to Vector. The
+// other fields filter the results when set, Metadata matches the embeddings
+// having all its pairs.
+type EmbeddingQuery struct {
+	Vector     []float32
+	Model      string
+	UserID     string
+	ChatID     string
+	SourceType string
+	Metadata   map[string]string
+	// MinScore drops the matches less similar than it, scores go from -1 to 1.
+	MinScore float64
+	Limit    int
+}
+
+type EmbeddingMatch struct {
+	Embedding *Embedding
+	// Score is the cosine similarity to the query vector.
+	Score float64
+}
+
+type EmbeddingGateway interface {
+	// UpsertEmbeddings stores the embeddings, replacing the one of the same
+	// source and model.
+	UpsertEmbeddings(ctx context.Context, embeddings []*Embedding) error
+	// SearchEmbeddings returns the matches most similar first.
+	SearchEmbeddings(ctx context.Context, query EmbeddingQuery) ([]*EmbeddingMatch, error)
+	DeleteEmbeddingsBySource(ctx context.Context, sourceType string, sourceID string) error
+}
@@ -0,0 +1,26 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+var (
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+	ErrIdempotencyKeyExists   = errors.New("idempotency key already exists")
+)
+
+// IdempotencyKeyGateway keeps the idempotency keys of each user.
+type IdempotencyKeyGateway interface {
+	// CreateIdempotencyKey returns ErrIdempotencyKeyExists when the user
+	// already has the key, so concurrent requests with it can't both create it.
+	CreateIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error
+	// FindIdempotencyKey returns ErrIdempotencyKeyNotFound when there is no
+	// such key.
+	FindIdempotencyKey(ctx context.Context, userID, key string) (*entity.IdempotencyKey, error)
+	// CompleteIdempotencyKey saves the chat and answer of the key.
+	CompleteIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error
+	DeleteIdempotencyKey(ctx context.Context, userID, key string) error
+}
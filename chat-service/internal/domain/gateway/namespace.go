@@ -0,0 +1,8 @@
+package gateway
+
+import "context"
+
+type NamespaceGateway interface {
+	// PurgeNamespace hard-deletes every row stored under namespace.
+	PurgeNamespace(ctx context.Context, namespace string) (int64, error)
+}
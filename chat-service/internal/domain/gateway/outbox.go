@@ -0,0 +1,27 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+// OutboxGateway reads the outbox ChatGateway implementations fill from
+// CreateChat and SaveChat.
+type OutboxGateway interface {
+	// FindUnpublishedOutboxEntries returns the oldest unpublished entries
+	// first, not the dead-lettered ones.
+	FindUnpublishedOutboxEntries(ctx context.Context, limit int) ([]*entity.OutboxEntry, error)
+	MarkOutboxEntryPublished(ctx context.Context, entryID string, publishedAt time.Time) error
+	MarkOutboxEntryFailed(ctx context.Context, entryID string, reason string) error
+	// MarkOutboxEntryDeadLettered records the last failure of the entry and
+	// stops relaying it.
+	MarkOutboxEntryDeadLettered(ctx context.Context, entryID string, reason string, deadLetteredAt time.Time) error
+}
+
+// EventBroker publishes outbox entries to the message broker downstream
+// consumers read from.
+type EventBroker interface {
+	Publish(ctx context.Context, entry *entity.OutboxEntry) error
+}
@@ -0,0 +1,15 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+type PersonaGateway interface {
+	CreatePersona(ctx context.Context, persona *entity.Persona) error
+	FindPersonaByID(ctx context.Context, personaID string) (*entity.Persona, error)
+	ListPersonas(ctx context.Context) ([]*entity.Persona, error)
+	SavePersona(ctx context.Context, persona *entity.Persona) error
+	DeletePersona(ctx context.Context, personaID string) error
+}
@@ -0,0 +1,14 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+type PromptTemplateGateway interface {
+	CreatePromptTemplate(ctx context.Context, template *entity.PromptTemplate) error
+	FindPromptTemplateByName(ctx context.Context, name string) (*entity.PromptTemplate, error)
+	ListPromptTemplates(ctx context.Context) ([]*entity.PromptTemplate, error)
+	DeletePromptTemplate(ctx context.Context, name string) error
+}
@@ -0,0 +1,34 @@
+package gateway
+
+import (
+	"context"
+	"time"
+)
+
+// RetentionPurge is what a purge of idle chats leaves to the caller, like
+// UserDataPurge: the chats to evict from caches and the attachment files to
+// delete.
+type RetentionPurge struct {
+	ChatIDs     []string
+	StorageKeys []string
+}
+
+// RetentionScope picks the chats of a retention policy by tenant: those
+// opened in OrgID or, with Others, those opened outside of a tenant or in
+// any tenant not in Except, the tenants with a policy of their own.
+type RetentionScope struct {
+	OrgID  string
+	Others bool
+	Except []string
+}
+
+// RetentionGateway enforces the retention windows, in the namespace of the
+// context. A chat is idle when it was last updated before idleBefore, the
+// soft-deleted and ephemeral ones included.
+type RetentionGateway interface {
+	CountIdleChats(ctx context.Context, scope RetentionScope, idleBefore time.Time) (int64, error)
+	// PurgeIdleChats hard-deletes up to limit idle chats of scope, the least
+	// recently updated first, with everything stored with them and their
+	// events still in the outbox.
+	PurgeIdleChats(ctx context.Context, scope RetentionScope, idleBefore time.Time, limit int) (*RetentionPurge, error)
+}
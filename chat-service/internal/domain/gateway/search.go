@@ -0,0 +1,39 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+// MessageSearchQuery matches Text, plain words, against the user and
+// assistant messages of the chats of UserID that are not deleted.
+type MessageSearchQuery struct {
+	UserID string
+	Text   string
+	Limit  int
+	Offset int
+}
+
+// TextRange is a [Start, End) character range, like annotation ranges.
+type TextRange struct {
+	Start int
+	End   int
+}
+
+type MessageSearchHit struct {
+	ChatID        string
+	ChatTitle     string
+	ChatUpdatedAt time.Time
+	Message       *entity.Message
+	// Snippet is the part of the message content around the matches,
+	// Highlights are the matches in it.
+	Snippet    string
+	Highlights []TextRange
+}
+
+type SearchGateway interface {
+	// SearchMessages returns the hits best match first.
+	SearchMessages(ctx context.Context, query MessageSearchQuery) ([]*MessageSearchHit, error)
+}
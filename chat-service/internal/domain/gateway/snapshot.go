@@ -0,0 +1,14 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+type SnapshotGateway interface {
+	CreateSnapshot(ctx context.Context, snapshot *entity.Snapshot) error
+	FindSnapshotByID(ctx context.Context, snapshotID string) (*entity.Snapshot, error)
+	// ListSnapshotsByChatID returns the snapshots of a chat, newest first, without their messages.
+	ListSnapshotsByChatID(ctx context.Context, chatID string) ([]*entity.Snapshot, error)
+}
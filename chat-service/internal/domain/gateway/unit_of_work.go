@@ -0,0 +1,13 @@
+package gateway
+
+import "context"
+
+// UnitOfWork runs fn in a transaction: the gateway calls made with the ctx fn
+// gets are committed together when fn returns nil, and rolled back otherwise.
+// Units of work started inside fn join the outer one.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+	// AfterCommit runs fn once the unit of work ctx is in is committed, not
+	// when it is rolled back, and right away outside of one.
+	AfterCommit(ctx context.Context, fn func())
+}
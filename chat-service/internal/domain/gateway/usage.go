@@ -0,0 +1,28 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+// UsageFilter selects the usage records of the days from From, included, to
+// To, excluded. The empty fields match every tenant, user or model.
+type UsageFilter struct {
+	OrgID  string
+	UserID string
+	Model  string
+	From   time.Time
+	To     time.Time
+}
+
+// UsageGateway keeps the usage of each user per tenant, model and day.
+type UsageGateway interface {
+	// RecordUsage adds the turns, tokens and cost of usage to the record of
+	// its day, tenant, user and model, creating it for the first turn.
+	RecordUsage(ctx context.Context, usage *entity.UsageRecord) error
+	// ListUsage returns the records matching filter by day, then tenant, user
+	// and model.
+	ListUsage(ctx context.Context, filter UsageFilter) ([]*entity.UsageRecord, error)
+}
@@ -0,0 +1,24 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+// UserDataPurge is what a purge leaves to the caller: the chats to evict from
+// caches and the attachment files to delete.
+type UserDataPurge struct {
+	ChatIDs     []string
+	StorageKeys []string
+}
+
+type UserDataGateway interface {
+	// PurgeUserData hard-deletes the chats of erasure.UserID, the deleted ones
+	// too, with everything stored with them, the annotations the user wrote on
+	// other chats, the embeddings of the user and the rest of what names the
+	// user: summaries, drafts, idempotency keys, usage, audit log and webhook
+	// deliveries. It fills the counts of erasure and stores it in the same
+	// transaction.
+	PurgeUserData(ctx context.Context, erasure *entity.UserErasure) (*UserDataPurge, error)
+}
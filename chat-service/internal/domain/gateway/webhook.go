@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+type WebhookGateway interface {
+	CreateWebhook(ctx context.Context, webhook *entity.Webhook) error
+	// FindWebhookByID returns ErrWebhookNotFound when there is no such webhook.
+	FindWebhookByID(ctx context.Context, id string) (*entity.Webhook, error)
+	// ListWebhooksByOrg returns the webhooks of orgID oldest first.
+	ListWebhooksByOrg(ctx context.Context, orgID string) ([]*entity.Webhook, error)
+	// DeleteWebhook deletes the webhook with its deliveries.
+	DeleteWebhook(ctx context.Context, id string) error
+	CreateWebhookDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error
+	// FindDueWebhookDeliveries returns the pending deliveries due by now,
+	// the earliest due first.
+	FindDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error)
+	SaveWebhookDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error
+	// ListDeadWebhookDeliveries returns the deliveries of webhookID that ran
+	// out of attempts, the latest first.
+	ListDeadWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]*entity.WebhookDelivery, error)
+}
+
+// WebhookSender posts a delivery to its webhook, it fails unless the
+// receiver acknowledged it.
+type WebhookSender interface {
+	Send(ctx context.Context, webhook *entity.Webhook, delivery *entity.WebhookDelivery) error
+}
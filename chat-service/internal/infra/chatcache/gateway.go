@@ -0,0 +1,254 @@
+package chatcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+// Store keeps the cached chats. Get reports found false on a miss.
+type Store interface {
+	Get(ctx context.Context, key string) (data []byte, found bool, err error)
+	Set(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Cipher seals the cached chats, see package encryption.
+type Cipher interface {
+	Encrypt(ctx context.Context, orgID string, plaintext string) (string, error)
+	Decrypt(ctx context.Context, stored string) (string, error)
+}
+
+type chatGateway struct {
+	next    gateway.ChatGateway
+	store   Store
+	clock   clock.Clock
+	ttl     time.Duration
+	cipher  Cipher
+	onError func(err error)
+}
+
+type ChatGatewayOption func(g *chatGateway)
+
+// WithCipher caches the chats encrypted with cipher, with the key of their
+// tenant, so their content is no more readable from store than from the
+// database. Set it whenever the repository encrypts the content.
+func WithCipher(cipher Cipher) ChatGatewayOption {
+	return func(g *chatGateway) {
+		g.cipher = cipher
+	}
+}
+
+// NewChatGateway serves FindChatByID from store, loading and caching the chat
+// from next on a miss. Every write to a chat through the gateway drops its
+// entry, and entries live ttl at most, so a chat cached by a read racing with
+// a write is stale for ttl at worst. Annotations and attachments are written
+// through their own gateways and also show up once the entry goes.
+//
+// The cache is best effort: store errors are passed to onError and the call
+// goes to next. The keys are per namespace, next must not be scoped to one.
+func NewChatGateway(next gateway.ChatGateway, store Store, clk clock.Clock, ttl time.Duration, onError func(err error), opts ...ChatGatewayOption) gateway.ChatGateway {
+	if onError == nil {
+		onError = func(err error) {}
+	}
+	g := &chatGateway{
+		next:    next,
+		store:   store,
+		clock:   clk,
+		ttl:     ttl,
+		onError: onError,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+func (g *chatGateway) FindChatByID(ctx context.Context, chatID string) (*entity.Chat, error) {
+	key, err := chatKey(ctx, chatID)
+	if err != nil {
+		return g.next.FindChatByID(ctx, chatID)
+	}
+	data, found, err := g.store.Get(ctx, key)
+	if err != nil {
+		g.onError(fmt.Errorf("error reading cached chat %s: %s", chatID, err.Error()))
+	}
+	if found {
+		chat, err := g.decode(ctx, data)
+		if err == nil {
+			return chat, nil
+		}
+		g.onError(fmt.Errorf("error decoding cached chat %s: %s", chatID, err.Error()))
+	}
+	chat, err := g.next.FindChatByID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	g.cache(ctx, key, chat)
+	return chat, nil
+}
+
+func (g *chatGateway) cache(ctx context.Context, key string, chat *entity.Chat) {
+	ttl := g.ttl
+	if !chat.ExpiresAt.IsZero() {
+		// don't outlive the chat, expired ones are purged without going through FindChatByID
+		if left := chat.ExpiresAt.Sub(g.clock.Now()); left < ttl {
+			ttl = left
+		}
+	}
+	if ttl <= 0 {
+		return
+	}
+	data, err := g.encode(ctx, chat)
+	if err != nil {
+		g.onError(fmt.Errorf("error encoding chat %s: %s", chat.ID, err.Error()))
+		return
+	}
+	if err := g.store.Set(ctx, key, data, ttl); err != nil {
+		g.onError(fmt.Errorf("error caching chat %s: %s", chat.ID, err.Error()))
+	}
+}
+
+func (g *chatGateway) invalidate(ctx context.Context, chatID string) {
+	key, err := chatKey(ctx, chatID)
+	if err != nil {
+		return
+	}
+	if err := g.store.Delete(ctx, key); err != nil {
+		g.onError(fmt.Errorf("error invalidating cached chat %s: %s", chatID, err.Error()))
+	}
+}
+
+func (g *chatGateway) CreateChat(ctx context.Context, chat *entity.Chat) error {
+	return g.next.CreateChat(ctx, chat)
+}
+
+func (g *chatGateway) FindChatsByParentID(ctx context.Context, parentChatID string) ([]*entity.Chat, error) {
+	return g.next.FindChatsByParentID(ctx, parentChatID)
+}
+
+func (g *chatGateway) ListChats(ctx context.Context, filter gateway.ChatFilter) ([]*entity.Chat, error) {
+	return g.next.ListChats(ctx, filter)
+}
+
+func (g *chatGateway) ListChatsByUser(ctx context.Context, userID string, page int, size int) (*gateway.ChatPage, error) {
+	return g.next.ListChatsByUser(ctx, userID, page, size)
+}
+
+func (g *chatGateway) ListMessages(ctx context.Context, chatID string, cursor gateway.MessageCursor) (*gateway.MessagePage, error) {
+	return g.next.ListMessages(ctx, chatID, cursor)
+}
+
+func (g *chatGateway) SaveChat(ctx context.Context, chat *entity.Chat) error {
+	err := g.next.SaveChat(ctx, chat)
+	if err == nil || errors.Is(err, gateway.ErrChatConflict) {
+		// on a conflict the caller reloads the chat, which must not come from a stale entry
+		g.invalidate(ctx, chat.ID)
+	}
+	return err
+}
+
+func (g *chatGateway) UpdateChatTitle(ctx context.Context, chatID string, title string) error {
+	if err := g.next.UpdateChatTitle(ctx, chatID, title); err != nil {
+		return err
+	}
+	g.invalidate(ctx, chatID)
+	return nil
+}
+
+func (g *chatGateway) AddChatTag(ctx context.Context, chatID string, tag string) error {
+	if err := g.next.AddChatTag(ctx, chatID, tag); err != nil {
+		return err
+	}
+	g.invalidate(ctx, chatID)
+	return nil
+}
+
+func (g *chatGateway) RemoveChatTag(ctx context.Context, chatID string, tag string) error {
+	if err := g.next.RemoveChatTag(ctx, chatID, tag); err != nil {
+		return err
+	}
+	g.invalidate(ctx, chatID)
+	return nil
+}
+
+func (g *chatGateway) SetMessageFlags(ctx context.Context, chatID string, messageID string, pinned bool, starred bool) error {
+	if err := g.next.SetMessageFlags(ctx, chatID, messageID, pinned, starred); err != nil {
+		return err
+	}
+	g.invalidate(ctx, chatID)
+	return nil
+}
+
+func (g *chatGateway) DeleteChat(ctx context.Context, chatID string, deletedAt time.Time) error {
+	if err := g.next.DeleteChat(ctx, chatID, deletedAt); err != nil {
+		return err
+	}
+	g.invalidate(ctx, chatID)
+	return nil
+}
+
+// PurgeDeletedChats needs no invalidation, DeleteChat already dropped the entries.
+func (g *chatGateway) PurgeDeletedChats(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return g.next.PurgeDeletedChats(ctx, deletedBefore)
+}
+
+func (g *chatGateway) FindExpiredChats(ctx context.Context, expiredBefore time.Time, limit int) ([]*entity.Chat, error) {
+	return g.next.FindExpiredChats(ctx, expiredBefore, limit)
+}
+
+// PurgeExpiredChats needs no invalidation, entries never outlive ExpiresAt.
+func (g *chatGateway) PurgeExpiredChats(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	return g.next.PurgeExpiredChats(ctx, expiredBefore)
+}
+
+func chatKey(ctx context.Context, chatID string) (string, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "chat:" + ns + ":" + chatID, nil
+}
+
+func (g *chatGateway) encode(ctx context.Context, chat *entity.Chat) ([]byte, error) {
+	data, err := json.Marshal(chat)
+	if err != nil || g.cipher == nil {
+		return data, err
+	}
+	sealed, err := g.cipher.Encrypt(ctx, chat.OrgID, string(data))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(sealed), nil
+}
+
+func (g *chatGateway) decode(ctx context.Context, data []byte) (*entity.Chat, error) {
+	if g.cipher != nil {
+		opened, err := g.cipher.Decrypt(ctx, string(data))
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(opened)
+	}
+	chat := &entity.Chat{}
+	if err := json.Unmarshal(data, chat); err != nil {
+		return nil, err
+	}
+	// the initial system message is one of the chat messages, keep it that way
+	if chat.InitialSystemMessage != nil {
+		for _, m := range chat.Messages {
+			if m.ID == chat.InitialSystemMessage.ID {
+				chat.InitialSystemMessage = m
+				break
+			}
+		}
+	}
+	return chat, nil
+}
@@ -0,0 +1,49 @@
+package chatcache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type retentionGateway struct {
+	next    gateway.RetentionGateway
+	store   Store
+	onError func(err error)
+}
+
+// NewRetentionGateway drops the cached chats next purges, like
+// NewUserDataGateway does for erasures.
+func NewRetentionGateway(next gateway.RetentionGateway, store Store, onError func(err error)) gateway.RetentionGateway {
+	if onError == nil {
+		onError = func(err error) {}
+	}
+	return &retentionGateway{
+		next:    next,
+		store:   store,
+		onError: onError,
+	}
+}
+
+func (g *retentionGateway) CountIdleChats(ctx context.Context, scope gateway.RetentionScope, idleBefore time.Time) (int64, error) {
+	return g.next.CountIdleChats(ctx, scope, idleBefore)
+}
+
+func (g *retentionGateway) PurgeIdleChats(ctx context.Context, scope gateway.RetentionScope, idleBefore time.Time, limit int) (*gateway.RetentionPurge, error) {
+	purge, err := g.next.PurgeIdleChats(ctx, scope, idleBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, chatID := range purge.ChatIDs {
+		key, err := chatKey(ctx, chatID)
+		if err != nil {
+			return purge, nil
+		}
+		if err := g.store.Delete(ctx, key); err != nil {
+			g.onError(fmt.Errorf("error invalidating cached chat %s: %s", chatID, err.Error()))
+		}
+	}
+	return purge, nil
+}
@@ -0,0 +1,45 @@
+package chatcache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type userDataGateway struct {
+	next    gateway.UserDataGateway
+	store   Store
+	onError func(err error)
+}
+
+// NewUserDataGateway drops the cached chats of the users whose data next
+// purges, an erased chat must not be served from the cache until its TTL.
+func NewUserDataGateway(next gateway.UserDataGateway, store Store, onError func(err error)) gateway.UserDataGateway {
+	if onError == nil {
+		onError = func(err error) {}
+	}
+	return &userDataGateway{
+		next:    next,
+		store:   store,
+		onError: onError,
+	}
+}
+
+func (g *userDataGateway) PurgeUserData(ctx context.Context, erasure *entity.UserErasure) (*gateway.UserDataPurge, error) {
+	purge, err := g.next.PurgeUserData(ctx, erasure)
+	if err != nil {
+		return nil, err
+	}
+	for _, chatID := range purge.ChatIDs {
+		key, err := chatKey(ctx, chatID)
+		if err != nil {
+			return purge, nil
+		}
+		if err := g.store.Delete(ctx, key); err != nil {
+			g.onError(fmt.Errorf("error invalidating cached chat %s: %s", chatID, err.Error()))
+		}
+	}
+	return purge, nil
+}
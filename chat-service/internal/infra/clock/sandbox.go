@@ -0,0 +1,149 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Sandbox is a Clock running in real time from an offset that Advance moves
+// forward, so the sandbox mode can skip to when TTLs expire and quotas reset
+// while the service keeps running. Timers and tickers fire at their deadline
+// in the time of the clock, right away for those Advance moved past.
+type Sandbox struct {
+	mu      sync.Mutex
+	offset  time.Duration
+	waiters map[*sandboxWaiter]struct{}
+}
+
+type sandboxWaiter struct {
+	clock    *Sandbox
+	deadline time.Time
+	period   time.Duration
+	ch       chan time.Time
+	timer    *time.Timer
+}
+
+func NewSandbox() *Sandbox {
+	return &Sandbox{waiters: make(map[*sandboxWaiter]struct{})}
+}
+
+func (s *Sandbox) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now()
+}
+
+func (s *Sandbox) now() time.Time {
+	return time.Now().Add(s.offset)
+}
+
+func (s *Sandbox) Since(t time.Time) time.Duration {
+	return s.Now().Sub(t)
+}
+
+func (s *Sandbox) NewTimer(d time.Duration) Timer {
+	return s.add(d, 0)
+}
+
+func (s *Sandbox) NewTicker(d time.Duration) Ticker {
+	return sandboxTicker{s.add(d, d)}
+}
+
+// Offset is how far Advance moved the clock ahead of the wall clock.
+func (s *Sandbox) Offset() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offset
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers due on
+// the way. A ticker fires once however many periods d skips.
+func (s *Sandbox) Advance(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset += d
+	for w := range s.waiters {
+		w.schedule()
+	}
+}
+
+func (s *Sandbox) add(d, period time.Duration) *sandboxWaiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := &sandboxWaiter{
+		clock:    s,
+		deadline: s.now().Add(d),
+		period:   period,
+		ch:       make(chan time.Time, 1),
+	}
+	s.waiters[w] = struct{}{}
+	w.schedule()
+	return w
+}
+
+// schedule arms the wall clock timer of w for its deadline, with the lock of
+// its clock held.
+func (w *sandboxWaiter) schedule() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	left := w.deadline.Sub(w.clock.now())
+	if left < 0 {
+		left = 0
+	}
+	w.timer = time.AfterFunc(left, w.fire)
+}
+
+func (w *sandboxWaiter) fire() {
+	s := w.clock
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.waiters[w]; !ok {
+		return
+	}
+	now := s.now()
+	if now.Before(w.deadline) {
+		// a timer Advance replaced, or the wall clock being a little early
+		w.schedule()
+		return
+	}
+	select {
+	case w.ch <- now:
+	default:
+		// like time.Ticker, ticks are dropped for slow receivers
+	}
+	if w.period <= 0 {
+		delete(s.waiters, w)
+		return
+	}
+	for !w.deadline.After(now) {
+		w.deadline = w.deadline.Add(w.period)
+	}
+	w.schedule()
+}
+
+func (w *sandboxWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+func (w *sandboxWaiter) Stop() bool {
+	s := w.clock
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.waiters[w]; !ok {
+		return false
+	}
+	delete(s.waiters, w)
+	w.timer.Stop()
+	return true
+}
+
+type sandboxTicker struct {
+	w *sandboxWaiter
+}
+
+func (t sandboxTicker) C() <-chan time.Time { return t.w.C() }
+func (t sandboxTicker) Stop()               { t.w.Stop() }
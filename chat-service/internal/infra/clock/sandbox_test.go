@@ -0,0 +1,70 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSandboxAdvanceMovesNow(t *testing.T) {
+	clk := NewSandbox()
+	before := clk.Now()
+	clk.Advance(time.Hour)
+	if got := clk.Now().Sub(before); got < time.Hour {
+		t.Fatalf("Now moved %s after advancing an hour", got)
+	}
+	if got := clk.Offset(); got != time.Hour {
+		t.Fatalf("Offset() = %s, want 1h", got)
+	}
+}
+
+func TestSandboxAdvanceFiresTimersDue(t *testing.T) {
+	clk := NewSandbox()
+	due := clk.NewTimer(time.Hour)
+	later := clk.NewTimer(3 * time.Hour)
+	defer later.Stop()
+	clk.Advance(2 * time.Hour)
+	select {
+	case <-due.C():
+	case <-time.After(time.Second):
+		t.Fatal("the timer advanced past didn't fire")
+	}
+	select {
+	case <-later.C():
+		t.Fatal("the timer not due yet fired")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSandboxTickerFiresOncePerAdvance(t *testing.T) {
+	clk := NewSandbox()
+	ticker := clk.NewTicker(time.Minute)
+	defer ticker.Stop()
+	clk.Advance(10 * time.Minute)
+	select {
+	case <-ticker.C():
+	case <-time.After(time.Second):
+		t.Fatal("the ticker didn't fire")
+	}
+	select {
+	case <-ticker.C():
+		t.Fatal("the ticker fired for every period skipped")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSandboxStoppedTimerDoesNotFire(t *testing.T) {
+	clk := NewSandbox()
+	timer := clk.NewTimer(time.Minute)
+	if !timer.Stop() {
+		t.Fatal("Stop() = false for a pending timer")
+	}
+	clk.Advance(time.Hour)
+	select {
+	case <-timer.C():
+		t.Fatal("the stopped timer fired")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if timer.Stop() {
+		t.Fatal("Stop() = true for a stopped timer")
+	}
+}
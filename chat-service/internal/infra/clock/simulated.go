@@ -0,0 +1,110 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Simulated is a Clock that only moves when told to. Timers and tickers fire,
+// in order, as Advance moves past their deadlines.
+type Simulated struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	clock    *Simulated
+	deadline time.Time
+	period   time.Duration
+	ch       chan time.Time
+	stopped  bool
+}
+
+func NewSimulated(start time.Time) *Simulated {
+	return &Simulated{now: start}
+}
+
+func (s *Simulated) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+func (s *Simulated) Since(t time.Time) time.Duration {
+	return s.Now().Sub(t)
+}
+
+func (s *Simulated) NewTimer(d time.Duration) Timer {
+	return s.add(d, 0)
+}
+
+func (s *Simulated) NewTicker(d time.Duration) Ticker {
+	return simulatedTicker{s.add(d, d)}
+}
+
+// Advance moves the clock forward by d, firing every timer and ticker due on the way.
+func (s *Simulated) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	target := s.now.Add(d)
+	for {
+		sort.Slice(s.waiters, func(i, j int) bool {
+			return s.waiters[i].deadline.Before(s.waiters[j].deadline)
+		})
+		if len(s.waiters) == 0 || s.waiters[0].deadline.After(target) {
+			break
+		}
+		w := s.waiters[0]
+		s.now = w.deadline
+		select {
+		case w.ch <- s.now:
+		default:
+			// like time.Ticker, ticks are dropped for slow receivers
+		}
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			s.waiters = s.waiters[1:]
+		}
+	}
+	s.now = target
+}
+
+func (s *Simulated) add(d, period time.Duration) *waiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := &waiter{
+		clock:    s,
+		deadline: s.now.Add(d),
+		period:   period,
+		ch:       make(chan time.Time, 1),
+	}
+	s.waiters = append(s.waiters, w)
+	return w
+}
+
+func (w *waiter) C() <-chan time.Time {
+	return w.ch
+}
+
+func (w *waiter) Stop() bool {
+	s := w.clock
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, other := range s.waiters {
+		if other == w {
+			s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type simulatedTicker struct {
+	w *waiter
+}
+
+func (t simulatedTicker) C() <-chan time.Time { return t.w.C() }
+func (t simulatedTicker) Stop()               { t.w.Stop() }
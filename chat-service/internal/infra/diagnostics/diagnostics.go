@@ -0,0 +1,80 @@
+// Package diagnostics serves the profiles of the Go runtime and live stats
+// of the service, its goroutines and memory, streams and queues, so leaks
+// around the streaming can be diagnosed on a running instance.
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+// Section is a named part of the runtime stats, the streams or the
+// completion queue for one, Stats being read on every request.
+type Section struct {
+	Name  string
+	Stats func() interface{}
+}
+
+// Runtime are the stats of the Go runtime.
+type Runtime struct {
+	Goroutines  int    `json:"goroutines"`
+	GOMAXPROCS  int    `json:"gomaxprocs"`
+	HeapAlloc   uint64 `json:"heap_alloc_bytes"`
+	HeapInuse   uint64 `json:"heap_inuse_bytes"`
+	HeapObjects uint64 `json:"heap_objects"`
+	StackInuse  uint64 `json:"stack_inuse_bytes"`
+	Sys         uint64 `json:"sys_bytes"`
+	NumGC       uint32 `json:"gc_cycles"`
+	// LastGCPause is how long the last collection stopped the world.
+	LastGCPause time.Duration `json:"last_gc_pause_ns"`
+}
+
+// ReadRuntime reads the stats of the runtime, which stops the world for a
+// moment.
+func ReadRuntime() Runtime {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	stats := Runtime{
+		Goroutines:  runtime.NumGoroutine(),
+		GOMAXPROCS:  runtime.GOMAXPROCS(0),
+		HeapAlloc:   mem.HeapAlloc,
+		HeapInuse:   mem.HeapInuse,
+		HeapObjects: mem.HeapObjects,
+		StackInuse:  mem.StackInuse,
+		Sys:         mem.Sys,
+		NumGC:       mem.NumGC,
+	}
+	if mem.NumGC > 0 {
+		stats.LastGCPause = time.Duration(mem.PauseNs[(mem.NumGC+255)%256])
+	}
+	return stats
+}
+
+// Register serves the profiles of net/http/pprof on /debug/pprof/ of mux,
+// and the runtime stats and those of sections on /debug/runtime.
+func Register(mux *http.ServeMux, sections ...Section) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/runtime", Handler(sections...))
+}
+
+// Handler answers the runtime stats, under runtime, and those of every
+// section under its name, in JSON.
+func Handler(sections ...Section) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		stats := map[string]interface{}{"runtime": ReadRuntime()}
+		for _, section := range sections {
+			stats[section.Name] = section.Stats()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(stats)
+	})
+}
@@ -0,0 +1,481 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+const (
+	TierPaid = "paid"
+	TierFree = "free"
+)
+
+// Priority orders the queued requests: the interactive ones, a user waits
+// for, are always served before the batch ones, background work like
+// summaries and titles.
+type Priority string
+
+const (
+	PriorityInteractive Priority = "interactive"
+	PriorityBatch       Priority = "batch"
+)
+
+// ParsePriority is the priority named p, interactive when empty.
+func ParsePriority(p string) (Priority, bool) {
+	switch Priority(p) {
+	case "", PriorityInteractive:
+		return PriorityInteractive, true
+	case PriorityBatch:
+		return PriorityBatch, true
+	}
+	return "", false
+}
+
+// priorities are the priorities in the order they are served.
+var priorities = []Priority{PriorityInteractive, PriorityBatch}
+
+var (
+	ErrQueueFull    = errors.New("completion queue is full")
+	ErrQueueTimeout = errors.New("timed out waiting in the completion queue")
+	// ErrUserLimit rejects the requests of a user already at MaxPerUser,
+	// under UserLimitReject.
+	ErrUserLimit = errors.New("too many completions in flight for the user")
+)
+
+// UserLimitPolicy is what happens to the requests of a user past
+// MaxPerUser.
+type UserLimitPolicy string
+
+const (
+	// UserLimitQueue has them wait while the requests of other users are
+	// served.
+	UserLimitQueue UserLimitPolicy = "queue"
+	// UserLimitReject fails them with ErrUserLimit, counting the requests of
+	// the user already queued.
+	UserLimitReject UserLimitPolicy = "reject"
+)
+
+// DefaultWeights are the weights of the tiers of a Config without any: paid
+// requests get 3 of every 4 slots freed while both tiers are queued.
+func DefaultWeights() map[string]int {
+	return map[string]int{TierPaid: 3, TierFree: 1}
+}
+
+type Config struct {
+	// MaxConcurrent is the number of completions allowed to run at the same time,
+	// zero means unlimited.
+	MaxConcurrent int
+	// MaxPerUser is the number of completions of a user allowed to run at the
+	// same time, UserLimit tells what happens to the next ones, queued by
+	// default. Zero means unlimited.
+	MaxPerUser int
+	UserLimit  UserLimitPolicy
+	// MaxBatch is the number of slots batch requests may hold at the same
+	// time, so the others stay free for interactive ones. Zero means
+	// unlimited.
+	MaxBatch int
+	// MaxQueued is the number of requests allowed to wait for a slot, the
+	// next ones fail with ErrQueueFull. Zero means unlimited.
+	MaxQueued int
+	// QueueTimeout fails the requests that waited for it with
+	// ErrQueueTimeout, zero means they wait as long as their context.
+	QueueTimeout time.Duration
+	// Weights is the share of freed slots each tier gets while requests are queued.
+	Weights map[string]int
+	// MaxWait promotes any request queued for longer than it, whatever its
+	// tier. Batch requests still yield to the interactive ones.
+	MaxWait time.Duration
+	// DefaultTier is used for requests without a known tier.
+	DefaultTier string
+	// Clock defaults to the real clock.
+	Clock clock.Clock
+}
+
+type waiter struct {
+	user       string
+	tier       string
+	priority   Priority
+	enqueuedAt time.Time
+	ready      chan struct{}
+	positions  chan int
+	position   int
+}
+
+// Dispatcher bounds the number of in-flight completions, overall and per
+// user. When every slot is taken, requests wait in per-tier queues and freed
+// slots go to the interactive requests first, handed out by smooth weighted
+// round robin between the tiers, then to the batch ones the same way.
+type Dispatcher struct {
+	mu          sync.Mutex
+	config      Config
+	inFlight    int
+	batchFlight int
+	userFlight  map[string]int
+	queues      map[string][]*waiter
+	current     map[string]int
+}
+
+func New(config Config) *Dispatcher {
+	if config.DefaultTier == "" {
+		config.DefaultTier = TierFree
+	}
+	if config.Weights == nil {
+		config.Weights = DefaultWeights()
+	}
+	if config.Clock == nil {
+		config.Clock = clock.Real()
+	}
+	return &Dispatcher{
+		config:     config,
+		userFlight: make(map[string]int),
+		queues:     make(map[string][]*waiter),
+		current:    make(map[string]int),
+	}
+}
+
+// Acquire is AcquireForUser for a request of no user in particular.
+func (d *Dispatcher) Acquire(ctx context.Context, tier string, onPosition func(int)) (release func(), err error) {
+	return d.AcquireForUser(ctx, "", tier, onPosition)
+}
+
+// AcquireForUser is AcquireWithPriority for an interactive request.
+func (d *Dispatcher) AcquireForUser(ctx context.Context, user, tier string, onPosition func(int)) (release func(), err error) {
+	return d.AcquireWithPriority(ctx, user, tier, PriorityInteractive, onPosition)
+}
+
+// AcquireWithPriority blocks until a slot is free for the tier, priority and
+// user, ctx is done or the request waited for QueueTimeout. While queued,
+// onPosition is called with the 1-based position every time it changes. The
+// returned release func must be called once the completion finishes.
+func (d *Dispatcher) AcquireWithPriority(ctx context.Context, user, tier string, priority Priority, onPosition func(int)) (release func(), err error) {
+	d.mu.Lock()
+	if _, ok := d.config.Weights[tier]; !ok {
+		tier = d.config.DefaultTier
+	}
+	if priority != PriorityBatch {
+		priority = PriorityInteractive
+	}
+	w := &waiter{
+		user:     user,
+		tier:     tier,
+		priority: priority,
+	}
+	if d.config.UserLimit == UserLimitReject && !d.userMayQueue(user) {
+		d.mu.Unlock()
+		return nil, ErrUserLimit
+	}
+	if d.hasFreeSlot() && d.eligible(w) && d.queued() == 0 {
+		d.grant(w)
+		d.mu.Unlock()
+		return d.releaseFunc(w), nil
+	}
+	if d.config.MaxQueued > 0 && d.queued() >= d.config.MaxQueued {
+		d.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+	w.enqueuedAt = d.config.Clock.Now()
+	w.ready = make(chan struct{})
+	w.positions = make(chan int, 1)
+	d.queues[tier] = append(d.queues[tier], w)
+	// the requests queued may all be held back by their user's limit
+	d.dispatch()
+	d.notifyPositions()
+	d.mu.Unlock()
+
+	var timer, timeout <-chan time.Time
+	if d.config.MaxWait > 0 {
+		// wake the queue up when this request becomes starved so its position is refreshed
+		t := d.config.Clock.NewTimer(d.config.MaxWait)
+		defer t.Stop()
+		timer = t.C()
+	}
+	if d.config.QueueTimeout > 0 {
+		t := d.config.Clock.NewTimer(d.config.QueueTimeout)
+		defer t.Stop()
+		timeout = t.C()
+	}
+	for {
+		select {
+		case <-w.ready:
+			return d.releaseFunc(w), nil
+		case position := <-w.positions:
+			if onPosition != nil {
+				onPosition(position)
+			}
+		case <-timer:
+			d.mu.Lock()
+			d.notifyPositions()
+			d.mu.Unlock()
+		case <-timeout:
+			if d.leave(w) {
+				return d.releaseFunc(w), nil
+			}
+			return nil, ErrQueueTimeout
+		case <-ctx.Done():
+			if d.leave(w) {
+				// the slot was granted while ctx was being cancelled
+				d.releaseFunc(w)()
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// leave takes w out of its queue, it returns true when w was granted a slot
+// meanwhile instead.
+func (d *Dispatcher) leave(w *waiter) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	select {
+	case <-w.ready:
+		return true
+	default:
+	}
+	d.remove(w)
+	d.notifyPositions()
+	return false
+}
+
+func (d *Dispatcher) grant(w *waiter) {
+	d.inFlight++
+	if w.priority == PriorityBatch {
+		d.batchFlight++
+	}
+	if w.user != "" {
+		d.userFlight[w.user]++
+	}
+}
+
+func (d *Dispatcher) releaseFunc(w *waiter) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.inFlight--
+			if w.priority == PriorityBatch {
+				d.batchFlight--
+			}
+			if w.user != "" {
+				if d.userFlight[w.user]--; d.userFlight[w.user] <= 0 {
+					delete(d.userFlight, w.user)
+				}
+			}
+			d.dispatch()
+			d.notifyPositions()
+		})
+	}
+}
+
+func (d *Dispatcher) dispatch() {
+	for d.hasFreeSlot() {
+		w := d.next(d.current, d.queues, d.config.Clock.Now(), d.eligible)
+		if w == nil {
+			return
+		}
+		d.remove(w)
+		d.grant(w)
+		close(w.ready)
+	}
+}
+
+// next picks the waiter to serve among the eligible ones of the first
+// priority that has some. It returns nil when no waiter is eligible.
+func (d *Dispatcher) next(current map[string]int, queues map[string][]*waiter, now time.Time, eligible func(w *waiter) bool) *waiter {
+	for _, priority := range priorities {
+		priority := priority
+		w := d.nextOf(current, queues, now, func(w *waiter) bool {
+			return w.priority == priority && eligible(w)
+		})
+		if w != nil {
+			return w
+		}
+	}
+	return nil
+}
+
+// nextOf picks the waiter to serve among the eligible ones: the oldest
+// starved one if any, else the first of the tier chosen by smooth weighted
+// round robin. current is updated in place. It returns nil when no waiter is
+// eligible.
+func (d *Dispatcher) nextOf(current map[string]int, queues map[string][]*waiter, now time.Time, eligible func(w *waiter) bool) *waiter {
+	first := func(q []*waiter) *waiter {
+		for _, w := range q {
+			if eligible(w) {
+				return w
+			}
+		}
+		return nil
+	}
+	var oldest *waiter
+	for _, q := range queues {
+		if w := first(q); w != nil && (oldest == nil || w.enqueuedAt.Before(oldest.enqueuedAt)) {
+			oldest = w
+		}
+	}
+	if oldest == nil {
+		return nil
+	}
+	if d.config.MaxWait > 0 && now.Sub(oldest.enqueuedAt) >= d.config.MaxWait {
+		return oldest
+	}
+	total := 0
+	best := ""
+	for _, tier := range d.tiers() {
+		if first(queues[tier]) == nil {
+			continue
+		}
+		current[tier] += d.config.Weights[tier]
+		total += d.config.Weights[tier]
+		if best == "" || current[tier] > current[best] {
+			best = tier
+		}
+	}
+	current[best] -= total
+	return first(queues[best])
+}
+
+// notifyPositions simulates the upcoming picks to tell every waiter where it
+// stands, ignoring the limits of the users and of the batch requests.
+func (d *Dispatcher) notifyPositions() {
+	current := make(map[string]int, len(d.current))
+	for tier, cw := range d.current {
+		current[tier] = cw
+	}
+	queues := make(map[string][]*waiter, len(d.queues))
+	for tier, q := range d.queues {
+		queues[tier] = append([]*waiter(nil), q...)
+	}
+	now := d.config.Clock.Now()
+	for position := 1; ; position++ {
+		empty := true
+		for _, q := range queues {
+			if len(q) > 0 {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			return
+		}
+		w := d.next(current, queues, now, anyWaiter)
+		// w is not always the head of its tier, a batch one may be ahead of it
+		removeWaiter(queues, w)
+		if w.position != position {
+			w.position = position
+			// keep only the latest position, the waiter may not have read the previous one yet
+			select {
+			case <-w.positions:
+			default:
+			}
+			w.positions <- position
+		}
+	}
+}
+
+func (d *Dispatcher) remove(w *waiter) {
+	removeWaiter(d.queues, w)
+}
+
+// removeWaiter takes w out of the queue of its tier in queues, leaving the
+// slice it was in untouched.
+func removeWaiter(queues map[string][]*waiter, w *waiter) {
+	q := queues[w.tier]
+	for i := range q {
+		if q[i] == w {
+			queues[w.tier] = append(q[:i:i], q[i+1:]...)
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) hasFreeSlot() bool {
+	return d.config.MaxConcurrent <= 0 || d.inFlight < d.config.MaxConcurrent
+}
+
+func (d *Dispatcher) userHasSlot(user string) bool {
+	return user == "" || d.config.MaxPerUser <= 0 || d.userFlight[user] < d.config.MaxPerUser
+}
+
+// eligible tells w can take a free slot under the limits of its user and
+// of the batch requests.
+func (d *Dispatcher) eligible(w *waiter) bool {
+	if w.priority == PriorityBatch && d.config.MaxBatch > 0 && d.batchFlight >= d.config.MaxBatch {
+		return false
+	}
+	return d.userHasSlot(w.user)
+}
+
+// userMayQueue tells the user has fewer than MaxPerUser requests in flight
+// and queued.
+func (d *Dispatcher) userMayQueue(user string) bool {
+	if user == "" || d.config.MaxPerUser <= 0 {
+		return true
+	}
+	n := d.userFlight[user]
+	for _, q := range d.queues {
+		for _, w := range q {
+			if w.user == user {
+				n++
+			}
+		}
+	}
+	return n < d.config.MaxPerUser
+}
+
+// Stats are the slots taken and the requests queued by a Dispatcher.
+type Stats struct {
+	InFlight      int
+	BatchInFlight int
+	// Queued counts the requests waiting for a slot by priority then tier.
+	Queued map[Priority]map[string]int
+}
+
+// Stats returns the slots taken and the requests queued right now.
+func (d *Dispatcher) Stats() Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats := Stats{
+		InFlight:      d.inFlight,
+		BatchInFlight: d.batchFlight,
+		Queued:        make(map[Priority]map[string]int, len(priorities)),
+	}
+	for _, priority := range priorities {
+		stats.Queued[priority] = make(map[string]int, len(d.queues))
+		for _, tier := range d.tiers() {
+			stats.Queued[priority][tier] = 0
+		}
+	}
+	for tier, q := range d.queues {
+		for _, w := range q {
+			stats.Queued[w.priority][tier]++
+		}
+	}
+	return stats
+}
+
+func anyWaiter(*waiter) bool {
+	return true
+}
+
+func (d *Dispatcher) queued() int {
+	n := 0
+	for _, q := range d.queues {
+		n += len(q)
+	}
+	return n
+}
+
+func (d *Dispatcher) tiers() []string {
+	tiers := make([]string, 0, len(d.config.Weights))
+	for tier := range d.config.Weights {
+		tiers = append(tiers, tier)
+	}
+	sort.Strings(tiers)
+	return tiers
+}
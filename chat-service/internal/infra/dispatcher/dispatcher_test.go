@@ -0,0 +1,189 @@
+package dispatcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type queued struct {
+	tier     string
+	priority Priority
+}
+
+// enqueue queues a waiter of each of requests in order, a second apart.
+func enqueue(d *Dispatcher, requests []queued) []*waiter {
+	waiters := make([]*waiter, len(requests))
+	for i, r := range requests {
+		w := &waiter{
+			tier:       r.tier,
+			priority:   r.priority,
+			enqueuedAt: start.Add(time.Duration(i) * time.Second),
+			ready:      make(chan struct{}),
+			positions:  make(chan int, 1),
+		}
+		d.queues[r.tier] = append(d.queues[r.tier], w)
+		waiters[i] = w
+	}
+	return waiters
+}
+
+func TestNotifyPositions(t *testing.T) {
+	tests := []struct {
+		name   string
+		queued []queued
+		want   []int
+	}{
+		{
+			name:   "interactive behind a batch one of its tier",
+			queued: []queued{{TierFree, PriorityBatch}, {TierFree, PriorityInteractive}},
+			want:   []int{2, 1},
+		},
+		{
+			name:   "batch behind an interactive one of its tier",
+			queued: []queued{{TierFree, PriorityInteractive}, {TierFree, PriorityBatch}},
+			want:   []int{1, 2},
+		},
+		{
+			name: "interactive ones of both tiers ahead of the batch ones",
+			queued: []queued{
+				{TierPaid, PriorityBatch},
+				{TierFree, PriorityBatch},
+				{TierFree, PriorityInteractive},
+				{TierPaid, PriorityInteractive},
+			},
+			want: []int{4, 3, 2, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(Config{MaxConcurrent: 1, Clock: clock.NewSimulated(start)})
+			waiters := enqueue(d, tt.queued)
+			d.notifyPositions()
+			for i, w := range waiters {
+				if w.position != tt.want[i] {
+					t.Errorf("position of request %d = %d, want %d", i, w.position, tt.want[i])
+				}
+				select {
+				case position := <-w.positions:
+					if position != tt.want[i] {
+						t.Errorf("position notified to request %d = %d, want %d", i, position, tt.want[i])
+					}
+				default:
+					t.Errorf("request %d was not notified of its position", i)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatchOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights map[string]int
+		maxWait time.Duration
+		// elapsed is the time since the first request was queued when the
+		// slots are freed
+		elapsed time.Duration
+		queued  []queued
+		// want are the requests of queued in the order they are served
+		want []int
+	}{
+		{
+			name: "paid requests get 3 of every 4 slots",
+			queued: []queued{
+				{TierPaid, PriorityInteractive}, {TierPaid, PriorityInteractive},
+				{TierPaid, PriorityInteractive}, {TierPaid, PriorityInteractive},
+				{TierFree, PriorityInteractive}, {TierFree, PriorityInteractive},
+				{TierFree, PriorityInteractive}, {TierFree, PriorityInteractive},
+			},
+			want: []int{0, 4, 1, 2, 3, 5, 6, 7},
+		},
+		{
+			name:    "tiers weighted the same take turns",
+			weights: map[string]int{TierPaid: 1, TierFree: 1},
+			queued: []queued{
+				{TierPaid, PriorityInteractive}, {TierPaid, PriorityInteractive},
+				{TierFree, PriorityInteractive}, {TierFree, PriorityInteractive},
+			},
+			want: []int{2, 0, 3, 1},
+		},
+		{
+			name:    "free requests waiting less than MaxWait",
+			maxWait: 20 * time.Second,
+			elapsed: 10500 * time.Millisecond,
+			queued: []queued{
+				{TierFree, PriorityInteractive}, {TierFree, PriorityInteractive},
+				{TierPaid, PriorityInteractive}, {TierPaid, PriorityInteractive}, {TierPaid, PriorityInteractive},
+			},
+			want: []int{2, 0, 3, 4, 1},
+		},
+		{
+			name:    "free request starved past MaxWait",
+			maxWait: 10 * time.Second,
+			elapsed: 10500 * time.Millisecond,
+			queued: []queued{
+				{TierFree, PriorityInteractive}, {TierFree, PriorityInteractive},
+				{TierPaid, PriorityInteractive}, {TierPaid, PriorityInteractive}, {TierPaid, PriorityInteractive},
+			},
+			want: []int{0, 2, 1, 3, 4},
+		},
+		{
+			name:   "interactive requests ahead of the batch ones of any tier",
+			queued: []queued{{TierPaid, PriorityBatch}, {TierFree, PriorityBatch}, {TierFree, PriorityInteractive}},
+			want:   []int{2, 0, 1},
+		},
+		{
+			name:    "starved batch requests still behind the interactive ones",
+			maxWait: time.Second,
+			elapsed: 10 * time.Second,
+			queued:  []queued{{TierFree, PriorityBatch}, {TierPaid, PriorityBatch}, {TierFree, PriorityInteractive}},
+			want:    []int{2, 0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(Config{
+				MaxConcurrent: 1,
+				Weights:       tt.weights,
+				MaxWait:       tt.maxWait,
+				Clock:         clock.NewSimulated(start.Add(tt.elapsed)),
+			})
+			// the only slot is taken by a running request
+			running := &waiter{}
+			d.grant(running)
+			waiters := enqueue(d, tt.queued)
+
+			d.notifyPositions()
+			for position, i := range tt.want {
+				if waiters[i].position != position+1 {
+					t.Errorf("position of request %d = %d, want %d", i, waiters[i].position, position+1)
+				}
+			}
+
+			release := d.releaseFunc(running)
+			served := make(map[*waiter]bool)
+			for _, i := range tt.want {
+				release()
+				var next *waiter
+				for _, w := range waiters {
+					select {
+					case <-w.ready:
+						if !served[w] {
+							next = w
+						}
+					default:
+					}
+				}
+				if next != waiters[i] {
+					t.Fatalf("slot freed went to %+v, want request %d", next, i)
+				}
+				served[next] = true
+				release = d.releaseFunc(next)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	tiktoken_go "github.com/j178/tiktoken-go"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type PingerFunc func(ctx context.Context) error
+
+func (f PingerFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+// ConnectivityCheck pings a dependency such as the database or the broker.
+func ConnectivityCheck(name string, pinger Pinger, hint string) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) Finding {
+			if pinger == nil {
+				return Skipped("not configured")
+			}
+			if err := pinger.Ping(ctx); err != nil {
+				return Failed(fmt.Sprintf("unreachable: %s", err.Error()), hint)
+			}
+			return OK("reachable")
+		},
+	}
+}
+
+// SchemaVersionCheck compares the migrated schema version to the one the
+// binary expects.
+func SchemaVersionCheck(current func(ctx context.Context) (int, error), expected int) Check {
+	return Check{
+		Name: "schema version",
+		Run: func(ctx context.Context) Finding {
+			if current == nil {
+				return Skipped("not configured")
+			}
+			version, err := current(ctx)
+			if err != nil {
+				return Failed(fmt.Sprintf("can't read schema version: %s", err.Error()), "check the database credentials and that migrations ran once")
+			}
+			switch {
+			case version < expected:
+				return Failed(fmt.Sprintf("schema is at version %d, %d expected", version, expected), "run chatservice migrate")
+			case version > expected:
+				return Warning(fmt.Sprintf("schema is at version %d, ahead of the expected %d", version, expected), "this binary is older than the database, upgrade it")
+			}
+			return OK(fmt.Sprintf("version %d", version))
+		},
+	}
+}
+
+// unauthorized tells whether the OpenAI API rejected the key of the call.
+func unauthorized(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusUnauthorized
+	}
+	var reqErr *openai.RequestError
+	return errors.As(err, &reqErr) && reqErr.StatusCode == http.StatusUnauthorized
+}
+
+// OpenAICheck validates the API key by listing the models it can use, and
+// that the default model is among them.
+func OpenAICheck(client *openai.Client, model string) Check {
+	return Check{
+		Name: "openai",
+		Run: func(ctx context.Context) Finding {
+			if client == nil {
+				return Failed("no API key", "set OPENAI_API_KEY")
+			}
+			models, err := client.ListModels(ctx)
+			if err != nil {
+				hint := "check the network access to the OpenAI API"
+				if unauthorized(err) {
+					hint = "OPENAI_API_KEY is invalid or revoked, generate a new one"
+				}
+				return Failed(fmt.Sprintf("can't list models: %s", err.Error()), hint)
+			}
+			if model == "" {
+				return OK(fmt.Sprintf("key valid, %d models available", len(models.Models)))
+			}
+			for _, m := range models.Models {
+				if m.ID == model {
+					return OK(fmt.Sprintf("key valid, %s available", model))
+				}
+			}
+			return Warning(fmt.Sprintf("key valid but %s is not available to it", model), "pick a model the key's organization has access to")
+		},
+	}
+}
+
+// TokenizerCheck makes sure the encoding of model can be loaded. It may be
+// downloaded on first use, so this fails on hosts without network access.
+func TokenizerCheck(model string) Check {
+	return Check{
+		Name: "tokenizer",
+		Run: func(ctx context.Context) Finding {
+			if n := tiktoken_go.CountTokens(model, "doctor"); n <= 0 {
+				return Failed(fmt.Sprintf("no encoding for %s", model), "use a model known to tiktoken or warm its cache on the host")
+			}
+			return OK(fmt.Sprintf("encoding for %s loaded", model))
+		},
+	}
+}
+
+// StorageCheck writes, reads back and deletes a probe file to verify the
+// permissions of the attachment storage.
+func StorageCheck(storage gateway.FileStorage) Check {
+	return Check{
+		Name: "file storage",
+		Run: func(ctx context.Context) Finding {
+			if storage == nil {
+				return Skipped("not configured")
+			}
+			const key = "doctor/probe"
+			const content = "doctor probe"
+			hint := "the service needs read, write and delete permissions on the storage"
+			if err := storage.Put(ctx, key, strings.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+				return Failed(fmt.Sprintf("can't write: %s", err.Error()), hint)
+			}
+			r, err := storage.Get(ctx, key)
+			if err != nil {
+				return Failed(fmt.Sprintf("can't read: %s", err.Error()), hint)
+			}
+			read, err := io.ReadAll(r)
+			r.Close()
+			if err != nil || string(read) != content {
+				return Failed("probe file read back differs", hint)
+			}
+			if err := storage.Delete(ctx, key); err != nil {
+				return Failed(fmt.Sprintf("can't delete: %s", err.Error()), hint)
+			}
+			if r, err := storage.Get(ctx, key); err == nil {
+				r.Close()
+				return Warning("probe file still readable after delete", "check the bucket versioning or caching settings")
+			}
+			return OK("read, write and delete allowed")
+		},
+	}
+}
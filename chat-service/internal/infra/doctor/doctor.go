@@ -0,0 +1,86 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	StatusOK      = "ok"
+	StatusWarning = "warning"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Finding is the outcome of a check. Hint tells the operator what to do about
+// anything that is not ok.
+type Finding struct {
+	Check   string
+	Status  string
+	Message string
+	Hint    string
+}
+
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) Finding
+}
+
+// Run runs every check with its own timeout, in order.
+func Run(ctx context.Context, timeout time.Duration, checks ...Check) []Finding {
+	findings := make([]Finding, 0, len(checks))
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		finding := run(checkCtx, check)
+		cancel()
+		finding.Check = check.Name
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+func run(ctx context.Context, check Check) (finding Finding) {
+	defer func() {
+		if r := recover(); r != nil {
+			finding = Failed(fmt.Sprintf("check panicked: %v", r), "")
+		}
+	}()
+	return check.Run(ctx)
+}
+
+// Healthy reports whether no finding failed.
+func Healthy(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Status == StatusFailed {
+			return false
+		}
+	}
+	return true
+}
+
+func Print(w io.Writer, findings []Finding) {
+	for _, f := range findings {
+		fmt.Fprintf(w, "[%-7s] %s: %s\n", f.Status, f.Check, f.Message)
+		if f.Hint != "" && f.Status != StatusOK {
+			fmt.Fprintf(w, "          -> %s\n", f.Hint)
+		}
+	}
+}
+
+func OK(message string) Finding {
+	return Finding{Status: StatusOK, Message: message}
+}
+
+func Warning(message, hint string) Finding {
+	return Finding{Status: StatusWarning, Message: message, Hint: hint}
+}
+
+func Failed(message, hint string) Finding {
+	return Finding{Status: StatusFailed, Message: message, Hint: hint}
+}
+
+func Skipped(message string) Finding {
+	return Finding{Status: StatusSkipped, Message: message}
+}
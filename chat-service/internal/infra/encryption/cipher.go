@@ -0,0 +1,242 @@
+// Package encryption encrypts message content at rest with envelope
+// encryption: each namespace has its own data key, stored wrapped by a master
+// key that never leaves the MasterKey (a local key or AWS KMS), and each
+// tenant of a namespace its own key derived from it. Nothing is stored for a
+// tenant, so its first write doesn't need a data key created inside the
+// transaction it runs in.
+//
+// Full-text search indexes the stored content, it finds nothing in encrypted
+// messages.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+// prefix marks the values encrypted with the key of the namespace, values
+// without it or tenantPrefix are read as they are so content written before
+// encryption was enabled stays readable.
+const prefix = "enc:v1:"
+
+// tenantPrefix marks the values encrypted with the key of a tenant, the
+// tenant follows base64 encoded, then a colon and the sealed value.
+const tenantPrefix = "enc:v2:"
+
+// tenantKeyLabel separates the tenant keys from any other key derived from
+// the data keys.
+const tenantKeyLabel = "chat-service tenant key\x00"
+
+const dataKeySize = 32
+
+var ErrNoDataKey = errors.New("no data key for the namespace")
+
+// MasterKey wraps and unwraps the data keys.
+type MasterKey interface {
+	WrapKey(ctx context.Context, dataKey []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// KeyStore keeps the wrapped data key of each namespace.
+type KeyStore interface {
+	// FindDataKey reports found false when ns has no data key yet.
+	FindDataKey(ctx context.Context, ns string) (wrapped []byte, found bool, err error)
+	// CreateDataKey stores wrapped as the data key of ns unless it already
+	// has one, and returns the data key of ns.
+	CreateDataKey(ctx context.Context, ns string, wrapped []byte) ([]byte, error)
+}
+
+// Cipher encrypts and decrypts the content of the namespace of the context,
+// with the key of the tenant of the content. Unwrapped data keys are kept in
+// memory, a namespace's key is created the first time it encrypts something.
+type Cipher struct {
+	master MasterKey
+	keys   KeyStore
+
+	mu       sync.Mutex
+	dataKeys map[string][]byte
+	aeads    map[tenantKey]cipher.AEAD
+}
+
+type tenantKey struct {
+	namespace string
+	orgID     string
+}
+
+func NewCipher(master MasterKey, keys KeyStore) *Cipher {
+	return &Cipher{
+		master:   master,
+		keys:     keys,
+		dataKeys: make(map[string][]byte),
+		aeads:    make(map[tenantKey]cipher.AEAD),
+	}
+}
+
+// Encrypt seals plaintext with the key of the tenant orgID, the data key of
+// the namespace for content outside of a tenant. The key also authenticates
+// the namespace and the tenant, so content can't be moved to another one.
+func (c *Cipher) Encrypt(ctx context.Context, orgID string, plaintext string) (string, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	aead, err := c.aead(ctx, tenantKey{namespace: ns, orgID: orgID}, true)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), additionalData(ns, orgID))
+	if orgID == "" {
+		return prefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+	}
+	return tenantPrefix + base64.RawURLEncoding.EncodeToString([]byte(orgID)) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value Encrypt sealed, with the key of the tenant it names.
+func (c *Cipher) Decrypt(ctx context.Context, stored string) (string, error) {
+	var orgID, encoded string
+	switch {
+	case strings.HasPrefix(stored, prefix):
+		encoded = strings.TrimPrefix(stored, prefix)
+	case strings.HasPrefix(stored, tenantPrefix):
+		tenant, rest, ok := strings.Cut(strings.TrimPrefix(stored, tenantPrefix), ":")
+		decoded, err := base64.RawURLEncoding.DecodeString(tenant)
+		if !ok || err != nil || len(decoded) == 0 {
+			return "", errors.New("error decoding encrypted content: invalid tenant")
+		}
+		orgID, encoded = string(decoded), rest
+	default:
+		return stored, nil
+	}
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("error decoding encrypted content: %s", err.Error())
+	}
+	aead, err := c.aead(ctx, tenantKey{namespace: ns, orgID: orgID}, false)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("encrypted content is truncated")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData(ns, orgID))
+	if err != nil {
+		return "", fmt.Errorf("error decrypting content: %s", err.Error())
+	}
+	return string(plaintext), nil
+}
+
+// Prepare loads the data key of the namespace of the context, creating it if
+// needed, so the first write doesn't have to. The keys of its tenants need
+// no preparing.
+func (c *Cipher) Prepare(ctx context.Context) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = c.aead(ctx, tenantKey{namespace: ns}, true)
+	return err
+}
+
+// additionalData is what a sealed value is bound to: its namespace, and its
+// tenant when it has one. The content of no tenant keeps the namespace alone,
+// as it was sealed before the tenant keys.
+func additionalData(ns, orgID string) []byte {
+	if orgID == "" {
+		return []byte(ns)
+	}
+	return []byte(ns + "\x00" + orgID)
+}
+
+func (c *Cipher) aead(ctx context.Context, key tenantKey, create bool) (cipher.AEAD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if aead, ok := c.aeads[key]; ok {
+		return aead, nil
+	}
+	dataKey, err := c.dataKey(ctx, key.namespace, create)
+	if err != nil {
+		return nil, err
+	}
+	if key.orgID != "" {
+		// HKDF-Expand with a single block: the data key is already uniformly random
+		mac := hmac.New(sha256.New, dataKey)
+		mac.Write([]byte(tenantKeyLabel + key.orgID + "\x01"))
+		dataKey = mac.Sum(nil)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	c.aeads[key] = aead
+	return aead, nil
+}
+
+// dataKey is the unwrapped data key of ns, with the lock held.
+func (c *Cipher) dataKey(ctx context.Context, ns string, create bool) ([]byte, error) {
+	if dataKey, ok := c.dataKeys[ns]; ok {
+		return dataKey, nil
+	}
+	wrapped, found, err := c.keys.FindDataKey(ctx, ns)
+	if err != nil {
+		return nil, fmt.Errorf("error loading data key: %s", err.Error())
+	}
+	if !found {
+		if !create {
+			return nil, ErrNoDataKey
+		}
+		if wrapped, err = c.createDataKey(ctx, ns); err != nil {
+			return nil, err
+		}
+	}
+	dataKey, err := c.master.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("error unwrapping data key: %s", err.Error())
+	}
+	c.dataKeys[ns] = dataKey
+	return dataKey, nil
+}
+
+func (c *Cipher) createDataKey(ctx context.Context, ns string) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	wrapped, err := c.master.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("error wrapping data key: %s", err.Error())
+	}
+	// another instance may have created one meanwhile, the stored one wins
+	wrapped, err = c.keys.CreateDataKey(ctx, ns, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("error storing data key: %s", err.Error())
+	}
+	return wrapped, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,101 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/awssig"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type KMSConfig struct {
+	// KeyID is the id, ARN or alias of the KMS key wrapping the data keys.
+	KeyID string
+	// Endpoint defaults to https://kms.<region>.amazonaws.com.
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// KMSMasterKey wraps the data keys with the Encrypt and Decrypt calls of AWS
+// KMS, the master key never leaves KMS.
+type KMSMasterKey struct {
+	config KMSConfig
+	client *http.Client
+	clock  clock.Clock
+}
+
+func NewKMSMasterKey(config KMSConfig, client *http.Client, clk clock.Clock) (*KMSMasterKey, error) {
+	if config.KeyID == "" || config.Region == "" {
+		return nil, fmt.Errorf("kms master key needs a key id and a region")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("kms master key needs credentials")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://kms." + config.Region + ".amazonaws.com"
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &KMSMasterKey{
+		config: config,
+		client: client,
+		clock:  clk,
+	}, nil
+}
+
+func (k *KMSMasterKey) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var output struct {
+		CiphertextBlob []byte
+	}
+	err := k.call(ctx, "Encrypt", map[string]interface{}{"KeyId": k.config.KeyID, "Plaintext": dataKey}, &output)
+	return output.CiphertextBlob, err
+}
+
+func (k *KMSMasterKey) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	var output struct {
+		Plaintext []byte
+	}
+	err := k.call(ctx, "Decrypt", map[string]interface{}{"KeyId": k.config.KeyID, "CiphertextBlob": wrapped}, &output)
+	return output.Plaintext, err
+}
+
+// call runs a KMS JSON API action, []byte fields are base64 both ways as KMS
+// expects.
+func (k *KMSMasterKey) call(ctx context.Context, action string, input, output interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.config.Endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	creds := awssig.Credentials{
+		AccessKeyID:     k.config.AccessKeyID,
+		SecretAccessKey: k.config.SecretAccessKey,
+		SessionToken:    k.config.SessionToken,
+	}
+	awssig.Sign(req, "/", creds, k.config.Region, "kms", awssig.PayloadHash(body), k.clock.Now())
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("kms %s: %s: %s", action, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(output)
+}
@@ -0,0 +1,50 @@
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// LocalMasterKey wraps the data keys with AES-256-GCM under a key from the
+// configuration.
+type LocalMasterKey struct {
+	key []byte
+}
+
+// NewLocalMasterKey takes the base64 encoding of a 32 bytes key.
+func NewLocalMasterKey(encoded string) (*LocalMasterKey, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding master key: %s", err.Error())
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("master key has %d bytes, 32 expected", len(key))
+	}
+	return &LocalMasterKey{key: key}, nil
+}
+
+func (k *LocalMasterKey) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	aead, err := newAEAD(k.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, dataKey, nil), nil
+}
+
+func (k *LocalMasterKey) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	aead, err := newAEAD(k.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped key is truncated")
+	}
+	return aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], nil)
+}
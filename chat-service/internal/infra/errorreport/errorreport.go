@@ -0,0 +1,254 @@
+// Package errorreport reports the panics and unexpected errors of the
+// service to an error tracker, Sentry or a compatible one, with the context
+// they happened in and the release that raised them, so they can be triaged
+// without digging through the logs.
+package errorreport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/requestid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Levels of the events, a recovered panic is fatal to its call.
+const (
+	LevelError = "error"
+	LevelFatal = "fatal"
+)
+
+// maxQueued bounds the events waiting to be sent, the next ones are dropped
+// while the tracker is down.
+const maxQueued = 256
+
+// maxFrames bounds the frames of the stacks captured.
+const maxFrames = 64
+
+// Frame is a frame of the stack an event was captured on.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// Event is a panic or an error captured, as sent to the tracker.
+type Event struct {
+	// ID is 32 hex digits, as the trackers expect.
+	ID    string
+	Time  time.Time
+	Level string
+	// Type is the type of the error, or of the value of the panic, and
+	// Message its text.
+	Type    string
+	Message string
+	// Panic tells a recovered panic from an error returned.
+	Panic bool
+	// Stack is the stack the event was captured on, innermost frame first.
+	Stack       []Frame
+	Release     string
+	Environment string
+	// Tags are the context of the event: request and trace IDs, and the chat,
+	// model or method it happened in.
+	Tags map[string]string
+}
+
+// Sender sends the events captured to an error tracker.
+type Sender interface {
+	SendEvent(ctx context.Context, event Event) error
+}
+
+// Reporter captures the panics and errors of the service and sends them in
+// the background with Run, tagged with Release and Environment. A nil
+// Reporter reports nothing.
+type Reporter struct {
+	Sender      Sender
+	Clock       clock.Clock
+	Release     string
+	Environment string
+	// OnError is told of the events that failed to be sent.
+	OnError func(err error)
+
+	mu      sync.Mutex
+	queued  []Event
+	dropped int
+}
+
+func NewReporter(sender Sender, release, environment string, clk clock.Clock) *Reporter {
+	if clk == nil {
+		clk = clock.Real()
+	}
+	return &Reporter{
+		Sender:      sender,
+		Clock:       clk,
+		Release:     release,
+		Environment: environment,
+	}
+}
+
+// CaptureError reports err with tags, along with the request and trace IDs
+// of ctx. Nil errors are ignored.
+func (r *Reporter) CaptureError(ctx context.Context, err error, tags map[string]string) {
+	if r == nil || err == nil {
+		return
+	}
+	event := r.event(ctx, LevelError, tags)
+	event.Type = fmt.Sprintf("%T", rootCause(err))
+	event.Message = err.Error()
+	event.Stack = stack(3)
+	r.queue(event)
+}
+
+// CapturePanic reports the value recovered from a panic with tags, along
+// with the request and trace IDs of ctx. Called from the deferred function
+// that recovered it, the stack is the one that panicked.
+func (r *Reporter) CapturePanic(ctx context.Context, recovered interface{}, tags map[string]string) {
+	if r == nil || recovered == nil {
+		return
+	}
+	event := r.event(ctx, LevelFatal, tags)
+	event.Type = fmt.Sprintf("%T", recovered)
+	if err, ok := recovered.(error); ok {
+		event.Type = fmt.Sprintf("%T", rootCause(err))
+	}
+	event.Message = fmt.Sprint(recovered)
+	event.Panic = true
+	event.Stack = stack(3)
+	r.queue(event)
+}
+
+func (r *Reporter) event(ctx context.Context, level string, tags map[string]string) Event {
+	var id [16]byte
+	rand.Read(id[:])
+	event := Event{
+		ID:          hex.EncodeToString(id[:]),
+		Time:        r.Clock.Now(),
+		Level:       level,
+		Release:     r.Release,
+		Environment: r.Environment,
+		Tags:        make(map[string]string, len(tags)+2),
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		event.Tags["request_id"] = id
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		event.Tags["trace_id"] = sc.TraceID().String()
+	}
+	for k, v := range tags {
+		if v != "" {
+			event.Tags[k] = v
+		}
+	}
+	return event
+}
+
+func (r *Reporter) queue(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.queued) >= maxQueued {
+		r.dropped++
+		return
+	}
+	r.queued = append(r.queued, event)
+}
+
+// Queued is how many events captured wait to be sent.
+func (r *Reporter) Queued() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.queued)
+}
+
+// Flush sends the events captured so far, those failing to be sent are
+// dropped. It returns the first failure.
+func (r *Reporter) Flush(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	events, dropped := r.queued, r.dropped
+	r.queued, r.dropped = nil, 0
+	r.mu.Unlock()
+	var first error
+	for _, event := range events {
+		if err := r.Sender.SendEvent(ctx, event); err != nil && first == nil {
+			first = err
+		}
+	}
+	if first != nil {
+		return first
+	}
+	if dropped > 0 && r.OnError != nil {
+		r.OnError(&DroppedError{Events: dropped})
+	}
+	return nil
+}
+
+// Run flushes the events every interval until ctx is done. The events
+// captured after are left for a last Flush.
+func (r *Reporter) Run(ctx context.Context, interval time.Duration) {
+	if r == nil {
+		return
+	}
+	ticker := r.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if err := r.Flush(ctx); err != nil && r.OnError != nil {
+				r.OnError(err)
+			}
+		}
+	}
+}
+
+// DroppedError reports the events dropped while the queue was full.
+type DroppedError struct {
+	Events int
+}
+
+func (e *DroppedError) Error() string {
+	return fmt.Sprintf("%d error events dropped, the report queue was full", e.Events)
+}
+
+// rootCause is the innermost error err wraps, whose type names the failure
+// better than those of the wrappers, the request ID one for instance.
+func rootCause(err error) error {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+}
+
+// stack is the stack of the caller, skip frames up.
+func stack(skip int) []Frame {
+	pcs := make([]uintptr, maxFrames)
+	n := runtime.Callers(skip, pcs)
+	if n == 0 {
+		return nil
+	}
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []Frame
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		if !more {
+			return stack
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package errorreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sentryClient names the sender to the tracker.
+const sentryClient = "chat-service/1.0"
+
+// SentrySender sends the events to the store API of a Sentry project, or of
+// a tracker compatible with it like GlitchTip.
+type SentrySender struct {
+	// Endpoint is the store URL of the project and Key its public key, both
+	// taken from the DSN of the project.
+	Endpoint string
+	Key      string
+	Client   *http.Client
+}
+
+// NewSentrySender sends the events to the project of dsn,
+// https://<key>@<host>/<project> for one.
+func NewSentrySender(dsn string, client *http.Client) (*SentrySender, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN: %s", err.Error())
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid DSN: not an http or https URL")
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("invalid DSN: the public key is missing")
+	}
+	path := strings.Trim(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	prefix, project := path[:i+1], path[i+1:]
+	if project == "" {
+		return nil, fmt.Errorf("invalid DSN: the project is missing")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &SentrySender{
+		Endpoint: u.Scheme + "://" + u.Host + "/" + prefix + "api/" + project + "/store/",
+		Key:      u.User.Username(),
+		Client:   client,
+	}, nil
+}
+
+func (s *SentrySender) SendEvent(ctx context.Context, event Event) error {
+	body, err := json.Marshal(sentryPayload(event))
+	if err != nil {
+		return fmt.Errorf("error encoding error event: %s", err.Error())
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating error event: %s", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClient, s.Key))
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending error event: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("error sending error event: tracker answered %s", resp.Status)
+	}
+	return nil
+}
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Exception   sentryExceptions  `json:"exception"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string          `json:"type"`
+	Value      string          `json:"value"`
+	Mechanism  sentryMechanism `json:"mechanism"`
+	Stacktrace sentryStack     `json:"stacktrace"`
+}
+
+// sentryMechanism tells a recovered panic, unhandled, from an error returned.
+type sentryMechanism struct {
+	Type    string `json:"type"`
+	Handled bool   `json:"handled"`
+}
+
+type sentryStack struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+}
+
+func sentryPayload(event Event) sentryEvent {
+	mechanism := sentryMechanism{Type: "generic", Handled: true}
+	if event.Panic {
+		mechanism = sentryMechanism{Type: "panic", Handled: false}
+	}
+	// the frames go outermost first
+	frames := make([]sentryFrame, 0, len(event.Stack))
+	for i := len(event.Stack) - 1; i >= 0; i-- {
+		frame := event.Stack[i]
+		frames = append(frames, sentryFrame{Function: frame.Function, Filename: frame.File, Lineno: frame.Line})
+	}
+	return sentryEvent{
+		EventID:     event.ID,
+		Timestamp:   event.Time.UTC().Format(time.RFC3339Nano),
+		Level:       event.Level,
+		Platform:    "go",
+		Release:     event.Release,
+		Environment: event.Environment,
+		Tags:        event.Tags,
+		Exception: sentryExceptions{Values: []sentryException{{
+			Type:       event.Type,
+			Value:      event.Message,
+			Mechanism:  mechanism,
+			Stacktrace: sentryStack{Frames: frames},
+		}}},
+	}
+}
@@ -0,0 +1,49 @@
+package eventbus
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+type Handler func(ctx context.Context, event entity.DomainEvent)
+
+// Bus is an in-process, synchronous event bus. Handlers run in the publisher's
+// goroutine, in subscription order, so a slow handler should hand work off.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	all      []Handler
+}
+
+func New() *Bus {
+	return &Bus{
+		handlers: make(map[string][]Handler),
+	}
+}
+
+func (b *Bus) Subscribe(eventName string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventName] = append(b.handlers[eventName], handler)
+}
+
+func (b *Bus) SubscribeAll(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.all = append(b.all, handler)
+}
+
+func (b *Bus) Publish(ctx context.Context, events ...entity.DomainEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, event := range events {
+		for _, handler := range b.handlers[event.EventName()] {
+			handler(ctx, event)
+		}
+		for _, handler := range b.all {
+			handler(ctx, event)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package eventbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type chatGateway struct {
+	gateway.ChatGateway
+	bus        *Bus
+	unitOfWork gateway.UnitOfWork
+	clock      clock.Clock
+}
+
+// NewChatGateway wraps next so the events recorded on a chat are published on
+// bus once CreateChat or SaveChat persisted it. The partial updates publish
+// ChatUpdated or ChatDeleted. With unitOfWork, the calls made in one are
+// published once it commits. The events of the partial updates occur at the
+// time of clk.
+func NewChatGateway(next gateway.ChatGateway, bus *Bus, unitOfWork gateway.UnitOfWork, clk clock.Clock) gateway.ChatGateway {
+	return &chatGateway{
+		ChatGateway: next,
+		bus:         bus,
+		unitOfWork:  unitOfWork,
+		clock:       clk,
+	}
+}
+
+// publish holds the events of the calls made in a unit of work until it
+// commits, the subscribers must not see the changes it rolls back.
+func (g *chatGateway) publish(ctx context.Context, events ...entity.DomainEvent) {
+	if g.unitOfWork == nil {
+		g.bus.Publish(ctx, events...)
+		return
+	}
+	g.unitOfWork.AfterCommit(ctx, func() {
+		g.bus.Publish(ctx, events...)
+	})
+}
+
+func (g *chatGateway) CreateChat(ctx context.Context, chat *entity.Chat) error {
+	if err := g.ChatGateway.CreateChat(ctx, chat); err != nil {
+		return err
+	}
+	g.publish(ctx, chat.PullEvents()...)
+	return nil
+}
+
+func (g *chatGateway) SaveChat(ctx context.Context, chat *entity.Chat) error {
+	if err := g.ChatGateway.SaveChat(ctx, chat); err != nil {
+		return err
+	}
+	g.publish(ctx, chat.PullEvents()...)
+	return nil
+}
+
+func (g *chatGateway) UpdateChatTitle(ctx context.Context, chatID string, title string) error {
+	if err := g.ChatGateway.UpdateChatTitle(ctx, chatID, title); err != nil {
+		return err
+	}
+	g.publish(ctx, entity.ChatUpdated{ChatID: chatID, OccurredAt: g.clock.Now()})
+	return nil
+}
+
+func (g *chatGateway) AddChatTag(ctx context.Context, chatID string, tag string) error {
+	if err := g.ChatGateway.AddChatTag(ctx, chatID, tag); err != nil {
+		return err
+	}
+	g.publish(ctx, entity.ChatUpdated{ChatID: chatID, OccurredAt: g.clock.Now()})
+	return nil
+}
+
+func (g *chatGateway) RemoveChatTag(ctx context.Context, chatID string, tag string) error {
+	if err := g.ChatGateway.RemoveChatTag(ctx, chatID, tag); err != nil {
+		return err
+	}
+	g.publish(ctx, entity.ChatUpdated{ChatID: chatID, OccurredAt: g.clock.Now()})
+	return nil
+}
+
+func (g *chatGateway) SetMessageFlags(ctx context.Context, chatID string, messageID string, pinned bool, starred bool) error {
+	if err := g.ChatGateway.SetMessageFlags(ctx, chatID, messageID, pinned, starred); err != nil {
+		return err
+	}
+	g.publish(ctx, entity.ChatUpdated{ChatID: chatID, OccurredAt: g.clock.Now()})
+	return nil
+}
+
+func (g *chatGateway) DeleteChat(ctx context.Context, chatID string, deletedAt time.Time) error {
+	if err := g.ChatGateway.DeleteChat(ctx, chatID, deletedAt); err != nil {
+		return err
+	}
+	g.publish(ctx, entity.ChatDeleted{ChatID: chatID, OccurredAt: deletedAt})
+	return nil
+}
@@ -0,0 +1,39 @@
+package eventbus
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type userDataGateway struct {
+	next  gateway.UserDataGateway
+	bus   *Bus
+	clock clock.Clock
+}
+
+// NewUserDataGateway wraps next so the chats of the user data it purges
+// publish ChatDeleted on bus, like the chats deleted one by one.
+func NewUserDataGateway(next gateway.UserDataGateway, bus *Bus, clk clock.Clock) gateway.UserDataGateway {
+	return &userDataGateway{
+		next:  next,
+		bus:   bus,
+		clock: clk,
+	}
+}
+
+func (g *userDataGateway) PurgeUserData(ctx context.Context, erasure *entity.UserErasure) (*gateway.UserDataPurge, error) {
+	purge, err := g.next.PurgeUserData(ctx, erasure)
+	if err != nil {
+		return nil, err
+	}
+	now := g.clock.Now()
+	events := make([]entity.DomainEvent, 0, len(purge.ChatIDs))
+	for _, chatID := range purge.ChatIDs {
+		events = append(events, entity.ChatDeleted{ChatID: chatID, OccurredAt: now})
+	}
+	g.bus.Publish(ctx, events...)
+	return purge, nil
+}
@@ -0,0 +1,65 @@
+package featureflag
+
+import (
+	"context"
+	"sort"
+)
+
+const (
+	FlagTools           = "tools"
+	FlagRAG             = "rag"
+	FlagOutlineFirst    = "outline_first"
+	FlagSummaryEviction = "summary_eviction"
+)
+
+// Target is who a flag is evaluated for.
+type Target struct {
+	OrgID  string
+	UserID string
+}
+
+// Provider evaluates flags. StaticProvider reads them from the config; clients
+// of LaunchDarkly, Unleash and the like can be plugged in behind it.
+type Provider interface {
+	Evaluate(ctx context.Context, flag string, target Target) (bool, error)
+}
+
+// Flags is what use cases consult. A flag the provider fails to evaluate is
+// considered off, and the error is reported through OnError when set.
+type Flags struct {
+	provider Provider
+	known    []string
+	OnError  func(flag string, err error)
+}
+
+func New(provider Provider, known ...string) *Flags {
+	if len(known) == 0 {
+		known = []string{FlagTools, FlagRAG, FlagOutlineFirst, FlagSummaryEviction}
+	}
+	known = append([]string(nil), known...)
+	sort.Strings(known)
+	return &Flags{
+		provider: provider,
+		known:    known,
+	}
+}
+
+func (f *Flags) IsEnabled(ctx context.Context, flag string, target Target) bool {
+	enabled, err := f.provider.Evaluate(ctx, flag, target)
+	if err != nil {
+		if f.OnError != nil {
+			f.OnError(flag, err)
+		}
+		return false
+	}
+	return enabled
+}
+
+// State evaluates every known flag for target, for debugging.
+func (f *Flags) State(ctx context.Context, target Target) map[string]bool {
+	state := make(map[string]bool, len(f.known))
+	for _, flag := range f.known {
+		state[flag] = f.IsEnabled(ctx, flag, target)
+	}
+	return state
+}
@@ -0,0 +1,53 @@
+package featureflag
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// Rule enables a flag for everyone, for the listed orgs and users, or for a
+// percentage of users. Users keep the same bucket as the percentage grows.
+type Rule struct {
+	Enabled    bool     `json:"enabled"`
+	Orgs       []string `json:"orgs"`
+	Users      []string `json:"users"`
+	Percentage int      `json:"percentage"`
+}
+
+type StaticProvider struct {
+	rules map[string]Rule
+}
+
+func NewStaticProvider(rules map[string]Rule) *StaticProvider {
+	return &StaticProvider{rules: rules}
+}
+
+func (p *StaticProvider) Evaluate(ctx context.Context, flag string, target Target) (bool, error) {
+	rule, ok := p.rules[flag]
+	if !ok {
+		return false, nil
+	}
+	if rule.Enabled {
+		return true, nil
+	}
+	for _, org := range rule.Orgs {
+		if target.OrgID != "" && org == target.OrgID {
+			return true, nil
+		}
+	}
+	for _, user := range rule.Users {
+		if target.UserID != "" && user == target.UserID {
+			return true, nil
+		}
+	}
+	if rule.Percentage > 0 && target.UserID != "" {
+		return bucket(flag, target.UserID) < rule.Percentage, nil
+	}
+	return false, nil
+}
+
+func bucket(flag, userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(flag + ":" + userID))
+	return int(h.Sum32() % 100)
+}
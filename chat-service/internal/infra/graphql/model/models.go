@@ -0,0 +1,68 @@
+// Package model holds the types of schema.graphqls, gqlgen binds them
+// instead of generating its own.
+package model
+
+import "time"
+
+type Chat struct {
+	ID                 string    `json:"id"`
+	Title              string    `json:"title"`
+	Status             string    `json:"status"`
+	Tags               []string  `json:"tags"`
+	LastMessagePreview *string   `json:"lastMessagePreview"`
+	CreatedAt          time.Time `json:"createdAt"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
+
+type ChatPage struct {
+	Chats      []*Chat `json:"chats"`
+	Page       int     `json:"page"`
+	Size       int     `json:"size"`
+	Total      int     `json:"total"`
+	TotalPages int     `json:"totalPages"`
+}
+
+type Message struct {
+	ID               string    `json:"id"`
+	Role             string    `json:"role"`
+	Content          string    `json:"content"`
+	Tokens           int       `json:"tokens"`
+	Pinned           bool      `json:"pinned"`
+	Starred          bool      `json:"starred"`
+	IncompleteReason string    `json:"incompleteReason"`
+	AnnotationCount  int       `json:"annotationCount"`
+	AttachmentCount  int       `json:"attachmentCount"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+type MessagePage struct {
+	Messages   []*Message `json:"messages"`
+	NextCursor *string    `json:"nextCursor"`
+}
+
+type CompletionEvent struct {
+	Event                string  `json:"event"`
+	ChatID               string  `json:"chatId"`
+	TurnID               *string `json:"turnId"`
+	Seq                  int     `json:"seq"`
+	Delta                *string `json:"delta"`
+	Content              *string `json:"content"`
+	ResumeToken          *string `json:"resumeToken"`
+	QueuePosition        *int    `json:"queuePosition"`
+	AwaitingConfirmation bool    `json:"awaitingConfirmation"`
+	Stopped              bool    `json:"stopped"`
+	IncompleteReason     *string `json:"incompleteReason"`
+	FinishReason         *string `json:"finishReason"`
+	Error                *string `json:"error"`
+	Usage                *Usage  `json:"usage"`
+}
+
+type Usage struct {
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens"`
+	EstimatedCost    float64 `json:"estimatedCost"`
+	PromptCost       float64 `json:"promptCost"`
+	CompletionCost   float64 `json:"completionCost"`
+	Priced           bool    `json:"priced"`
+}
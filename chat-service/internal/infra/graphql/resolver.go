@@ -0,0 +1,100 @@
+// Package graphql implements the resolvers of schema.graphqls on the
+// usecases. The executable schema is generated by gqlgen into generated/,
+// regenerate it after changing the schema with:
+//
+//	go generate ./internal/infra/graphql
+//
+// gqlgen keeps the resolvers of schema.resolvers.go and adds those of the
+// new fields.
+package graphql
+
+//go:generate go run github.com/99designs/gqlgen generate --config gqlgen.yml
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/graphql/generated"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/graphql/model"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/middleware"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/listchats"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/listmessages"
+)
+
+var (
+	errMissingUser = errors.New("missing user")
+	errScope       = errors.New("API key lacks the " + entity.ScopeCompletions + " scope")
+)
+
+// Resolver is the root resolver. Config is the completion config of the
+// chats the subscriptions start.
+type Resolver struct {
+	ListChatsByUser *listchats.ListChatsByUserUseCase
+	ListMessages    *listmessages.ListMessagesUseCase
+	ChatCompletion  *chatcompletionstream.ChatCompletionUseCase
+	Config          chatcompletionstream.ChatCompletionConfigInputDTO
+}
+
+func NewResolver(listChatsByUser *listchats.ListChatsByUserUseCase, listMessages *listmessages.ListMessagesUseCase, chatCompletion *chatcompletionstream.ChatCompletionUseCase, config chatcompletionstream.ChatCompletionConfigInputDTO) *Resolver {
+	return &Resolver{
+		ListChatsByUser: listChatsByUser,
+		ListMessages:    listMessages,
+		ChatCompletion:  chatCompletion,
+		Config:          config,
+	}
+}
+
+// NewHandler serves the schema on resolver over POST and GET, and the
+// subscriptions over WebSocket (graphql-ws and graphql-transport-ws) or
+// Server-Sent Events. It must be mounted behind the auth middleware, the
+// resolvers act for the principal of the call.
+func NewHandler(resolver *Resolver) http.Handler {
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{Resolvers: resolver}))
+	srv.AddTransport(transport.Websocket{KeepAlivePingInterval: 10 * time.Second})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	// before POST, which takes the subscriptions asking for an event stream too
+	srv.AddTransport(transport.SSE{})
+	srv.AddTransport(transport.POST{})
+	srv.SetQueryCache(lru.New(1000))
+	srv.Use(extension.Introspection{})
+	return srv
+}
+
+// principal is the principal the call was authenticated as, the API key or
+// bearer token of the request, or of the upgrade request of a WebSocket.
+func principal(ctx context.Context) (*middleware.Principal, error) {
+	p := middleware.PrincipalFromContext(ctx)
+	if p == nil || p.UserID == "" {
+		return nil, errMissingUser
+	}
+	return p, nil
+}
+
+// optional is nil for an empty s, the null of the optional fields.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func newUsage(output chatcompletionstream.ChatCompletionOutputDTO) *model.Usage {
+	return &model.Usage{
+		PromptTokens:     output.PromptTokens,
+		CompletionTokens: output.CompletionTokens,
+		TotalTokens:      output.TotalTokens,
+		EstimatedCost:    output.EstimatedCost,
+		PromptCost:       output.PromptCost,
+		CompletionCost:   output.CompletionCost,
+		Priced:           output.Priced,
+	}
+}
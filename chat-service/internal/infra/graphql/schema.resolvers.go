@@ -0,0 +1,178 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.31
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/graphql/generated"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/graphql/model"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/listchats"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/listmessages"
+)
+
+// Chats is the resolver for the chats field.
+func (r *queryResolver) Chats(ctx context.Context, page *int, size *int) (*model.ChatPage, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	input := listchats.ListChatsByUserInputDTO{UserID: p.UserID}
+	if page != nil {
+		input.Page = *page
+	}
+	if size != nil {
+		input.Size = *size
+	}
+	output, err := r.ListChatsByUser.Execute(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	result := &model.ChatPage{
+		Chats:      make([]*model.Chat, 0, len(output.Chats)),
+		Page:       output.Page,
+		Size:       output.Size,
+		Total:      output.Total,
+		TotalPages: output.TotalPages,
+	}
+	for _, chat := range output.Chats {
+		result.Chats = append(result.Chats, &model.Chat{
+			ID:                 chat.ChatID,
+			Title:              chat.Title,
+			Status:             chat.Status,
+			Tags:               chat.Tags,
+			LastMessagePreview: optional(chat.LastMessagePreview),
+			CreatedAt:          chat.CreatedAt,
+			UpdatedAt:          chat.UpdatedAt,
+		})
+	}
+	return result, nil
+}
+
+// Messages is the resolver for the messages field.
+func (r *queryResolver) Messages(ctx context.Context, chatID string, cursor *string, limit *int) (*model.MessagePage, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	input := listmessages.ListMessagesInputDTO{
+		ChatID: chatID,
+		UserID: p.UserID,
+	}
+	if cursor != nil {
+		input.Cursor = *cursor
+	}
+	if limit != nil {
+		input.Limit = *limit
+	}
+	output, err := r.ListMessages.Execute(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	result := &model.MessagePage{
+		Messages:   make([]*model.Message, 0, len(output.Messages)),
+		NextCursor: optional(output.NextCursor),
+	}
+	for _, m := range output.Messages {
+		message := model.Message(m)
+		result.Messages = append(result.Messages, &message)
+	}
+	return result, nil
+}
+
+// Completion is the resolver for the completion field.
+func (r *subscriptionResolver) Completion(ctx context.Context, chatID *string, message string) (<-chan *model.CompletionEvent, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the call only needed chats:read to get here
+	if p.KeyID != "" && !p.Allows(entity.ScopeCompletions) {
+		return nil, errScope
+	}
+	input := chatcompletionstream.ChatCompletionInputDTO{
+		UserID:      p.UserID,
+		OrgID:       p.OrgID,
+		Tier:        p.Tier,
+		UserMessage: message,
+		Config:      r.Config,
+	}
+	if strings.TrimSpace(message) == "" {
+		return nil, errors.New("message is required")
+	}
+	if chatID != nil {
+		input.ChatID = *chatID
+	}
+	events := make(chan *model.CompletionEvent)
+	go func() {
+		defer close(events)
+		send := func(event *model.CompletionEvent) error {
+			select {
+			case events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		output, err := r.ChatCompletion.ExecuteStreaming(ctx, input, func(chunk chatcompletionstream.ChatCompletionOutputDTO) error {
+			event := &model.CompletionEvent{
+				Event:                chunk.Event,
+				ChatID:               chunk.ChatID,
+				TurnID:               optional(chunk.TurnID),
+				Seq:                  chunk.Seq,
+				ResumeToken:          optional(chunk.ResumeToken),
+				AwaitingConfirmation: chunk.AwaitingConfirmation,
+				IncompleteReason:     optional(chunk.IncompleteReason),
+				FinishReason:         optional(chunk.FinishReason),
+				Error:                optional(chunk.Error),
+			}
+			if chunk.QueuePosition > 0 {
+				event.QueuePosition = &chunk.QueuePosition
+			}
+			if chunk.Event == chatcompletionstream.EventUsage {
+				event.Usage = newUsage(chunk)
+			}
+			if chunk.Event == chatcompletionstream.EventContent {
+				event.Event = "delta"
+				event.Delta = optional(chunk.Delta)
+			} else {
+				event.Content = optional(chunk.Content)
+			}
+			return send(event)
+		})
+		if err != nil {
+			// with an output the turn completed and the subscriber is gone
+			if output == nil {
+				send(&model.CompletionEvent{Event: "error", ChatID: input.ChatID, Error: optional(err.Error())})
+			}
+			return
+		}
+		send(&model.CompletionEvent{
+			Event:                "done",
+			ChatID:               output.ChatID,
+			TurnID:               optional(output.TurnID),
+			Content:              optional(output.Content),
+			AwaitingConfirmation: output.AwaitingConfirmation,
+			Stopped:              output.Stopped,
+			IncompleteReason:     optional(output.IncompleteReason),
+			FinishReason:         optional(output.FinishReason),
+			Usage:                newUsage(*output),
+		})
+	}()
+	return events, nil
+}
+
+// Query returns generated.QueryResolver implementation.
+func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
+
+// Subscription returns generated.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
+
+type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
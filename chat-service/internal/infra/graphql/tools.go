@@ -0,0 +1,6 @@
+//go:build tools
+
+package graphql
+
+// gqlgen stays in go.mod for go generate.
+import _ "github.com/99designs/gqlgen"
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/middleware"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ReflectionPrefix is the method prefix of the reflection service, it only
+// describes the API so tools can be let through without a token.
+const ReflectionPrefix = "/grpc.reflection."
+
+func (g *GRPCServer) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if g.middleware == nil {
+		return handler(ctx, req)
+	}
+	call := newCall(ctx, info.FullMethod, func(md metadata.MD) {
+		grpc.SetHeader(ctx, md)
+	})
+	var resp interface{}
+	err := g.middleware(func(ctx context.Context, call *middleware.Call) error {
+		var err error
+		resp, err = handler(ctx, req)
+		call.Status = status.Code(err).String()
+		return err
+	})(ctx, call)
+	return resp, middlewareError(err)
+}
+
+func (g *GRPCServer) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if g.middleware == nil {
+		return handler(srv, ss)
+	}
+	call := newCall(ss.Context(), info.FullMethod, func(md metadata.MD) {
+		ss.SetHeader(md)
+	})
+	err := g.middleware(func(ctx context.Context, call *middleware.Call) error {
+		err := handler(srv, contextStream{ServerStream: ss, ctx: ctx})
+		call.Status = status.Code(err).String()
+		return err
+	})(ss.Context(), call)
+	return middlewareError(err)
+}
+
+func newCall(ctx context.Context, method string, setHeader func(md metadata.MD)) *middleware.Call {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return &middleware.Call{
+		Transport: middleware.GRPC,
+		Method:    method,
+		Header: func(key string) string {
+			if values := md.Get(key); len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		},
+		SetHeader: func(key, value string) {
+			setHeader(metadata.Pairs(key, value))
+		},
+	}
+}
+
+// contextStream is ss with the context the middleware handed down.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// middlewareError is the status of a call a middleware rejected, the errors
+// of the handlers are statuses already.
+func middlewareError(err error) error {
+	var rejected *middleware.Error
+	if !errors.As(err, &rejected) {
+		return err
+	}
+	code := codes.Internal
+	switch rejected.Code {
+	case middleware.Unauthenticated:
+		code = codes.Unauthenticated
+	case middleware.PermissionDenied:
+		code = codes.PermissionDenied
+	case middleware.ResourceExhausted:
+		code = codes.ResourceExhausted
+	}
+	st := status.New(code, rejected.Message)
+	if rejected.RetryAfter > 0 {
+		if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(rejected.RetryAfter)}); err == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
@@ -0,0 +1,103 @@
+// Package server runs the gRPC server of the chat service.
+package server
+
+//go:generate protoc --proto_path=../../../../proto --go_out=../pb --go_opt=paths=source_relative --go-grpc_out=../pb --go-grpc_opt=paths=source_relative chat/v1/chat.proto chat/v2/chat.proto
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+
+	chatv1 "github.com/alecanutto/fclx/chat-service/internal/infra/grpc/pb/chat/v1"
+	chatv2 "github.com/alecanutto/fclx/chat-service/internal/infra/grpc/pb/chat/v2"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/grpc/service"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
+)
+
+type GRPCServer struct {
+	ChatService *service.ChatService
+	Port        string
+	// TLS, when set, serves over TLS, mutual with its ClientCAFile.
+	TLS       *TLSConfig
+	Transport TransportConfig
+
+	middleware middleware.Middleware
+	mu         sync.Mutex
+	server     *grpc.Server
+}
+
+func NewGRPCServer(chatService *service.ChatService, port string) *GRPCServer {
+	return &GRPCServer{
+		ChatService: chatService,
+		Port:        port,
+	}
+}
+
+// Use runs mws on every call, the first one outermost. It must be called
+// before Start.
+func (g *GRPCServer) Use(mws ...middleware.Middleware) {
+	if g.middleware != nil {
+		mws = append([]middleware.Middleware{g.middleware}, mws...)
+	}
+	g.middleware = middleware.Chain(mws...)
+}
+
+// Start serves until Stop is called, it returns nil then.
+func (g *GRPCServer) Start() error {
+	lis, err := net.Listen("tcp", ":"+g.Port)
+	if err != nil {
+		return fmt.Errorf("error listening on port %s: %s", g.Port, err.Error())
+	}
+	// otelgrpc starts the span of every call in the trace of its caller, the
+	// middleware tags it
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(otelgrpc.UnaryServerInterceptor(), g.unaryInterceptor),
+		grpc.ChainStreamInterceptor(otelgrpc.StreamServerInterceptor(), g.streamInterceptor),
+	}
+	opts = append(opts, g.Transport.serverOptions()...)
+	if g.TLS != nil {
+		certs, err := newCertReloader(*g.TLS)
+		if err != nil {
+			lis.Close()
+			return err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(certs.tlsConfig())))
+	}
+	server := grpc.NewServer(opts...)
+	chatv2.RegisterChatServiceServer(server, g.ChatService)
+	chatv1.RegisterChatServiceServer(server, service.NewChatServiceV1(g.ChatService))
+	// lets grpcurl and other tools list and call the services without the
+	// protos, see ReflectionPrefix
+	reflection.Register(server)
+	g.mu.Lock()
+	g.server = server
+	g.mu.Unlock()
+	return server.Serve(lis)
+}
+
+// Stop stops taking new calls and waits for the streams in flight to end, the
+// ones left are closed when ctx ends.
+func (g *GRPCServer) Stop(ctx context.Context) {
+	g.mu.Lock()
+	server := g.server
+	g.mu.Unlock()
+	if server == nil {
+		return
+	}
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+		<-stopped
+	}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TLSConfig serves the API over TLS with the certificate of CertFile and
+// KeyFile. ClientCAFile, when set, makes it mutual: the clients must present
+// a certificate of one of its CAs, and one of SPIFFEIDs when set. A SPIFFE
+// ID ending with a slash, spiffe://example.org/, allows the whole trust
+// domain. The files are reloaded when they change, for certificates rotated
+// in place.
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	SPIFFEIDs    []string
+	// OnReloadError is told of the files failing to reload, the certificate
+	// in use is kept then.
+	OnReloadError func(err error)
+}
+
+// reloadEvery is how often the handshakes check the files for changes.
+const reloadEvery = 30 * time.Second
+
+// certReloader holds the certificate and client CAs of a TLSConfig, reloaded
+// by the handshakes once the files changed.
+type certReloader struct {
+	cfg TLSConfig
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  []time.Time
+	checkedAt time.Time
+}
+
+func newCertReloader(cfg TLSConfig) (*certReloader, error) {
+	r := &certReloader{cfg: cfg}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// tlsConfig is the config of the listener, asking every handshake for the
+// current certificate and CAs.
+func (r *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, clientCAs := r.current()
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				NextProtos:   []string{"h2"},
+			}
+			if clientCAs != nil {
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+				cfg.ClientCAs = clientCAs
+				cfg.VerifyConnection = r.verifySPIFFEID
+			}
+			return cfg, nil
+		},
+	}
+}
+
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checkedAt) >= reloadEvery {
+		r.checkedAt = time.Now()
+		if r.changed() {
+			if err := r.loadLocked(); err != nil && r.cfg.OnReloadError != nil {
+				r.cfg.OnReloadError(err)
+			}
+		}
+	}
+	return r.cert, r.clientCAs
+}
+
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+func (r *certReloader) changed() bool {
+	for i, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Equal(r.modTimes[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *certReloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkedAt = time.Now()
+	return r.loadLocked()
+}
+
+func (r *certReloader) loadLocked() error {
+	var modTimes []time.Time
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("error loading the TLS certificate: %s", err.Error())
+	}
+	var clientCAs *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("error reading the client CAs: %s", err.Error())
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate in the client CAs of %s", r.cfg.ClientCAFile)
+		}
+	}
+	r.cert, r.clientCAs, r.modTimes = &cert, clientCAs, modTimes
+	return nil
+}
+
+// verifySPIFFEID requires a SPIFFEIDs URI SAN in the client certificate,
+// once its chain was verified.
+func (r *certReloader) verifySPIFFEID(state tls.ConnectionState) error {
+	if len(r.cfg.SPIFFEIDs) == 0 {
+		return nil
+	}
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("no client certificate")
+	}
+	for _, uri := range state.PeerCertificates[0].URIs {
+		if uri.Scheme != "spiffe" {
+			continue
+		}
+		id := uri.String()
+		for _, allowed := range r.cfg.SPIFFEIDs {
+			if id == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(id, allowed)) {
+				return nil
+			}
+		}
+		return fmt.Errorf("SPIFFE ID %s is not allowed", id)
+	}
+	return errors.New("client certificate has no SPIFFE ID")
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Defaults of TransportConfig. The server pings connections idle for
+// defaultKeepaliveTime so the NATs and proxies between it and mobile clients
+// don't drop them in the silences of long generations.
+const (
+	defaultKeepaliveTime    = 30 * time.Second
+	defaultKeepaliveTimeout = 10 * time.Second
+	defaultKeepaliveMinTime = 10 * time.Second
+	defaultMaxRecvMsgSize   = 4 << 20
+	defaultMaxSendMsgSize   = 16 << 20
+)
+
+// TransportConfig tunes the connections of the server, its zero fields keep
+// their default.
+type TransportConfig struct {
+	// KeepaliveTime is how long a connection may stay idle before the
+	// server pings it, KeepaliveTimeout how long it waits for the ack
+	// before closing it.
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+	// KeepaliveMinTime is how often clients may ping, streams in flight or
+	// not, before the server closes their connection for pinging too much.
+	KeepaliveMinTime time.Duration
+	MaxRecvMsgSize   int
+	MaxSendMsgSize   int
+	// WindowSize and ConnWindowSize are the flow-control windows of each
+	// stream and of each connection, in bytes. When unset, gRPC sizes them
+	// from the bandwidth-delay product it measures instead.
+	WindowSize     int32
+	ConnWindowSize int32
+}
+
+func (c TransportConfig) serverOptions() []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    durationOr(c.KeepaliveTime, defaultKeepaliveTime),
+			Timeout: durationOr(c.KeepaliveTimeout, defaultKeepaliveTimeout),
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             durationOr(c.KeepaliveMinTime, defaultKeepaliveMinTime),
+			PermitWithoutStream: true,
+		}),
+		grpc.MaxRecvMsgSize(intOr(c.MaxRecvMsgSize, defaultMaxRecvMsgSize)),
+		grpc.MaxSendMsgSize(intOr(c.MaxSendMsgSize, defaultMaxSendMsgSize)),
+	}
+	if c.WindowSize > 0 {
+		opts = append(opts, grpc.InitialWindowSize(c.WindowSize))
+	}
+	if c.ConnWindowSize > 0 {
+		opts = append(opts, grpc.InitialConnWindowSize(c.ConnWindowSize))
+	}
+	return opts
+}
+
+func durationOr(d, fallback time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return fallback
+}
+
+func intOr(n, fallback int) int {
+	if n > 0 {
+		return n
+	}
+	return fallback
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+
+	chatv2 "github.com/alecanutto/fclx/chat-service/internal/infra/grpc/pb/chat/v2"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/auditlog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *ChatService) ListAuditEntries(ctx context.Context, req *chatv2.ListAuditEntriesRequest) (*chatv2.ListAuditEntriesResponse, error) {
+	if s.ListAuditEntriesUseCase == nil {
+		return nil, status.Error(codes.Unimplemented, "the audit log is not enabled")
+	}
+	input := auditlog.ListAuditEntriesInputDTO{
+		OrgID:  req.GetOrgId(),
+		UserID: req.GetUserId(),
+		ChatID: req.GetChatId(),
+		Limit:  int(req.GetLimit()),
+	}
+	if err := tenantScope(ctx, &input.OrgID, &input.UserID); err != nil {
+		return nil, err
+	}
+	if req.GetFrom() != nil {
+		input.From = req.GetFrom().AsTime()
+	}
+	if req.GetTo() != nil {
+		input.To = req.GetTo().AsTime()
+	}
+	output, err := s.ListAuditEntriesUseCase.Execute(ctx, input)
+	if err != nil {
+		return nil, usecaseError(err)
+	}
+	resp := &chatv2.ListAuditEntriesResponse{
+		Entries: make([]*chatv2.AuditEntry, 0, len(output.Entries)),
+	}
+	for _, entry := range output.Entries {
+		resp.Entries = append(resp.Entries, &chatv2.AuditEntry{
+			Id:               entry.ID,
+			CreatedAt:        timestamppb.New(entry.CreatedAt),
+			OrgId:            entry.OrgID,
+			UserId:           entry.UserID,
+			ChatId:           entry.ChatID,
+			MessageId:        entry.MessageID,
+			RequestId:        entry.RequestID,
+			Model:            entry.Model,
+			Redaction:        entry.Redaction,
+			Prompt:           entry.Prompt,
+			Response:         entry.Response,
+			PromptTokens:     int32(entry.PromptTokens),
+			CompletionTokens: int32(entry.CompletionTokens),
+			FinishReason:     entry.FinishReason,
+		})
+	}
+	return resp, nil
+}
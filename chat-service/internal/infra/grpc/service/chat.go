@@ -0,0 +1,134 @@
+// Package service implements the gRPC services of proto/ on the usecases:
+// ChatService serves chat.v2 and ChatServiceV1 serves v1 on top of it.
+package service
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/dispatcher"
+	chatv2 "github.com/alecanutto/fclx/chat-service/internal/infra/grpc/pb/chat/v2"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/auditlog"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/deletechat"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/draft"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/listchats"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/renamechat"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/usage"
+)
+
+// ChatService serves the ChatService RPCs with the usecases. Config is the
+// completion config of the chats the requests start.
+type ChatService struct {
+	chatv2.UnimplementedChatServiceServer
+	ChatCompletionStreamUseCase *chatcompletionstream.ChatCompletionUseCase
+	ListChatsByUserUseCase      *listchats.ListChatsByUserUseCase
+	RenameChatUseCase           *renamechat.RenameChatUseCase
+	DeleteChatUseCase           *deletechat.DeleteChatUseCase
+	RegenerateUseCase           *chatcompletionstream.RegenerateUseCase
+	StopGenerationUseCase       *chatcompletionstream.StopGenerationUseCase
+	// WatchChatUseCase and ResumeStreamUseCase, when set, serve WatchChat
+	// and ResumeStream.
+	WatchChatUseCase    *chatcompletionstream.WatchChatUseCase
+	ResumeStreamUseCase *chatcompletionstream.ResumeStreamUseCase
+	// GetUsageUseCase, when set, serves GetUsage.
+	GetUsageUseCase *usage.GetUsageUseCase
+	// ListAuditEntriesUseCase, when set, serves ListAuditEntries.
+	ListAuditEntriesUseCase *auditlog.ListAuditEntriesUseCase
+	// SaveDraftUseCase, GetDraftUseCase and DiscardDraftUseCase, when set,
+	// serve the draft RPCs.
+	SaveDraftUseCase    *draft.SaveDraftUseCase
+	GetDraftUseCase     *draft.GetDraftUseCase
+	DiscardDraftUseCase *draft.DiscardDraftUseCase
+	Config              chatcompletionstream.ChatCompletionConfigInputDTO
+}
+
+func NewChatService(chatCompletionStreamUseCase *chatcompletionstream.ChatCompletionUseCase, listChatsByUserUseCase *listchats.ListChatsByUserUseCase, renameChatUseCase *renamechat.RenameChatUseCase, deleteChatUseCase *deletechat.DeleteChatUseCase, regenerateUseCase *chatcompletionstream.RegenerateUseCase, stopGenerationUseCase *chatcompletionstream.StopGenerationUseCase, config chatcompletionstream.ChatCompletionConfigInputDTO) *ChatService {
+	return &ChatService{
+		ChatCompletionStreamUseCase: chatCompletionStreamUseCase,
+		ListChatsByUserUseCase:      listChatsByUserUseCase,
+		RenameChatUseCase:           renameChatUseCase,
+		DeleteChatUseCase:           deleteChatUseCase,
+		RegenerateUseCase:           regenerateUseCase,
+		StopGenerationUseCase:       stopGenerationUseCase,
+		Config:                      config,
+	}
+}
+
+func (s *ChatService) ChatStream(req *chatv2.ChatRequest, stream chatv2.ChatService_ChatStreamServer) error {
+	userID, err := requestUser(stream.Context(), req.GetUserId())
+	if err != nil {
+		return err
+	}
+	if req.GetUserMessage() == "" {
+		return fieldError("user_message", "is required")
+	}
+	if _, ok := dispatcher.ParsePriority(req.GetPriority()); !ok {
+		return fieldError("priority", "must be interactive or batch")
+	}
+	_, err = s.complete(stream.Context(), req, req.GetChatId(), userID, stream.Send)
+	return err
+}
+
+// complete runs the turn of req in chatID for userID and hands its chunks to
+// send as they arrive, it returns the output of the turn.
+func (s *ChatService) complete(ctx context.Context, req *chatv2.ChatRequest, chatID, userID string, send func(*chatv2.ChatResponse) error) (*chatcompletionstream.ChatCompletionOutputDTO, error) {
+	input := chatcompletionstream.ChatCompletionInputDTO{
+		ChatID:         chatID,
+		UserID:         userID,
+		OrgID:          requestOrg(ctx),
+		Tier:           requestTier(ctx),
+		UserMessage:    req.GetUserMessage(),
+		Config:         s.Config,
+		IdempotencyKey: req.GetIdempotencyKey(),
+		Priority:       req.GetPriority(),
+	}
+	return streamTurn(ctx, send, req.GetDeltaOnly(), func(send func(chatcompletionstream.ChatCompletionOutputDTO) error) (*chatcompletionstream.ChatCompletionOutputDTO, error) {
+		return s.ChatCompletionStreamUseCase.ExecuteStreaming(ctx, input, send)
+	})
+}
+
+// streamTurn hands the chunks of the turn run executes to send as responses
+// carrying their delta, without the content so far when deltaOnly, and
+// returns the output of the turn.
+func streamTurn(ctx context.Context, send func(*chatv2.ChatResponse) error, deltaOnly bool, run func(send func(chatcompletionstream.ChatCompletionOutputDTO) error) (*chatcompletionstream.ChatCompletionOutputDTO, error)) (*chatcompletionstream.ChatCompletionOutputDTO, error) {
+	output, err := run(chunkSender(send, deltaOnly))
+	if output == nil {
+		// the usecase failed, a failed send comes with the output
+		return nil, completionError(ctx, err)
+	}
+	return output, err
+}
+
+func newChatResponse(chunk chatcompletionstream.ChatCompletionOutputDTO) *chatv2.ChatResponse {
+	return &chatv2.ChatResponse{
+		ChatId:               chunk.ChatID,
+		UserId:               chunk.UserID,
+		Content:              chunk.Content,
+		Event:                chunk.Event,
+		Seq:                  int64(chunk.Seq),
+		TurnId:               chunk.TurnID,
+		ResumeToken:          chunk.ResumeToken,
+		QueuePosition:        int32(chunk.QueuePosition),
+		AwaitingConfirmation: chunk.AwaitingConfirmation,
+		RequestId:            chunk.RequestID,
+		Usage:                newUsage(chunk),
+		IncompleteReason:     chunk.IncompleteReason,
+		FinishReason:         chunk.FinishReason,
+		Error:                chunk.Error,
+	}
+}
+
+func newUsage(chunk chatcompletionstream.ChatCompletionOutputDTO) *chatv2.Usage {
+	if chunk.Event != chatcompletionstream.EventUsage {
+		return nil
+	}
+	return &chatv2.Usage{
+		PromptTokens:     int32(chunk.PromptTokens),
+		CompletionTokens: int32(chunk.CompletionTokens),
+		TotalTokens:      int32(chunk.TotalTokens),
+		EstimatedCost:    chunk.EstimatedCost,
+		PromptCost:       chunk.PromptCost,
+		CompletionCost:   chunk.CompletionCost,
+		Priced:           chunk.Priced,
+	}
+}
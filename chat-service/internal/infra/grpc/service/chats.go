@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+
+	chatv2 "github.com/alecanutto/fclx/chat-service/internal/infra/grpc/pb/chat/v2"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/deletechat"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/listchats"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/renamechat"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *ChatService) ListChats(ctx context.Context, req *chatv2.ListChatsRequest) (*chatv2.ListChatsResponse, error) {
+	userID, err := requestUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	output, err := s.ListChatsByUserUseCase.Execute(ctx, listchats.ListChatsByUserInputDTO{
+		UserID: userID,
+		Page:   int(req.GetPage()),
+		Size:   int(req.GetSize()),
+	})
+	if err != nil {
+		return nil, usecaseError(err)
+	}
+	resp := &chatv2.ListChatsResponse{
+		Chats:      make([]*chatv2.ChatSummary, 0, len(output.Chats)),
+		Page:       int32(output.Page),
+		Size:       int32(output.Size),
+		Total:      int32(output.Total),
+		TotalPages: int32(output.TotalPages),
+	}
+	for _, chat := range output.Chats {
+		resp.Chats = append(resp.Chats, &chatv2.ChatSummary{
+			ChatId:             chat.ChatID,
+			Title:              chat.Title,
+			Status:             chat.Status,
+			Tags:               chat.Tags,
+			LastMessagePreview: chat.LastMessagePreview,
+			CreatedAt:          timestamppb.New(chat.CreatedAt),
+			UpdatedAt:          timestamppb.New(chat.UpdatedAt),
+		})
+	}
+	return resp, nil
+}
+
+func (s *ChatService) RenameChat(ctx context.Context, req *chatv2.RenameChatRequest) (*chatv2.RenameChatResponse, error) {
+	userID, err := requestUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	output, err := s.RenameChatUseCase.Execute(ctx, renamechat.RenameChatInputDTO{
+		ChatID: req.GetChatId(),
+		UserID: userID,
+		Title:  req.GetTitle(),
+	})
+	if err != nil {
+		return nil, usecaseError(err)
+	}
+	return &chatv2.RenameChatResponse{
+		ChatId: output.ChatID,
+		Title:  output.Title,
+	}, nil
+}
+
+func (s *ChatService) DeleteChat(ctx context.Context, req *chatv2.DeleteChatRequest) (*chatv2.DeleteChatResponse, error) {
+	userID, err := requestUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	err = s.DeleteChatUseCase.Execute(ctx, deletechat.DeleteChatInputDTO{
+		ChatID: req.GetChatId(),
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, usecaseError(err)
+	}
+	return &chatv2.DeleteChatResponse{}, nil
+}
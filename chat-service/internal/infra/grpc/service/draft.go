@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+
+	chatv2 "github.com/alecanutto/fclx/chat-service/internal/infra/grpc/pb/chat/v2"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/draft"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *ChatService) GetDraft(ctx context.Context, req *chatv2.GetDraftRequest) (*chatv2.Draft, error) {
+	if s.GetDraftUseCase == nil {
+		return nil, status.Error(codes.Unimplemented, "drafts are not enabled")
+	}
+	userID, err := requestUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	output, err := s.GetDraftUseCase.Execute(ctx, draft.DraftInputDTO{
+		ChatID: req.GetChatId(),
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, usecaseError(err)
+	}
+	return draftResponse(output), nil
+}
+
+func (s *ChatService) SaveDraft(ctx context.Context, req *chatv2.SaveDraftRequest) (*chatv2.Draft, error) {
+	if s.SaveDraftUseCase == nil {
+		return nil, status.Error(codes.Unimplemented, "drafts are not enabled")
+	}
+	userID, err := requestUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	output, err := s.SaveDraftUseCase.Execute(ctx, draft.SaveDraftInputDTO{
+		ChatID:  req.GetChatId(),
+		UserID:  userID,
+		Content: req.GetContent(),
+	})
+	if err != nil {
+		return nil, usecaseError(err)
+	}
+	return draftResponse(output), nil
+}
+
+func (s *ChatService) DiscardDraft(ctx context.Context, req *chatv2.DiscardDraftRequest) (*chatv2.DiscardDraftResponse, error) {
+	if s.DiscardDraftUseCase == nil {
+		return nil, status.Error(codes.Unimplemented, "drafts are not enabled")
+	}
+	userID, err := requestUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	err = s.DiscardDraftUseCase.Execute(ctx, draft.DraftInputDTO{
+		ChatID: req.GetChatId(),
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, usecaseError(err)
+	}
+	return &chatv2.DiscardDraftResponse{}, nil
+}
+
+func draftResponse(output *draft.DraftOutputDTO) *chatv2.Draft {
+	resp := &chatv2.Draft{
+		ChatId:  output.ChatID,
+		Content: output.Content,
+	}
+	if !output.UpdatedAt.IsZero() {
+		resp.UpdatedAt = timestamppb.New(output.UpdatedAt)
+	}
+	return resp
+}
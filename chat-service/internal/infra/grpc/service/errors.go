@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/dispatcher"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/ratelimit"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/requestid"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/streambuffer"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain is the domain of the ErrorInfo details of the statuses.
+const errorDomain = "chat-service"
+
+// Reasons of the ErrorInfo details, for clients to handle errors without
+// parsing messages.
+const (
+	reasonInvalidArgument     = "INVALID_ARGUMENT"
+	reasonTokenLimit          = "TOKEN_LIMIT_EXCEEDED"
+	reasonNotFound            = "NOT_FOUND"
+	reasonPermissionDenied    = "PERMISSION_DENIED"
+	reasonProviderUnavailable = "PROVIDER_UNAVAILABLE"
+	reasonConflict            = "CONCURRENT_UPDATE"
+	reasonIdempotencyKey      = "IDEMPOTENCY_KEY_CONFLICT"
+	reasonRateLimited         = "RATE_LIMITED"
+	reasonOverloaded          = "OVERLOADED"
+	reasonUserLimit           = "TOO_MANY_GENERATIONS"
+	reasonConsumerTooSlow     = "CONSUMER_TOO_SLOW"
+	reasonShuttingDown        = "SHUTTING_DOWN"
+	reasonSequenceExpired     = "SEQUENCE_EXPIRED"
+	reasonGenerationTimeout   = "GENERATION_TIMEOUT"
+	reasonInternal            = "INTERNAL"
+)
+
+// retryDelay is the RetryInfo of the errors worth retrying.
+const retryDelay = time.Second
+
+// fieldError is the InvalidArgument status of a request field, with a
+// BadRequest field violation.
+func fieldError(field, description string) error {
+	msg := field + " " + description
+	st, err := status.New(codes.InvalidArgument, msg).WithDetails(
+		&errdetails.ErrorInfo{Reason: reasonInvalidArgument, Domain: errorDomain},
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: field, Description: msg},
+		}},
+	)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, msg)
+	}
+	return st.Err()
+}
+
+// reasonError is the status of err with code and an ErrorInfo of reason,
+// carrying the request ID of err, and a RetryInfo of retry when set.
+func reasonError(code codes.Code, reason string, cause error, retry time.Duration) error {
+	st := status.New(code, cause.Error())
+	var (
+		detailed *status.Status
+		err      error
+	)
+	info := &errdetails.ErrorInfo{Reason: reason, Domain: errorDomain}
+	var named *requestid.Error
+	if errors.As(cause, &named) {
+		info.Metadata = map[string]string{"request_id": named.ID}
+	}
+	if retry > 0 {
+		detailed, err = st.WithDetails(info, &errdetails.RetryInfo{RetryDelay: durationpb.New(retry)})
+	} else {
+		detailed, err = st.WithDetails(info)
+	}
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// notFoundErrors are the errors of the resources a call names that don't
+// exist, forbiddenErrors of those of another user or tenant.
+var (
+	notFoundErrors = []error{
+		gateway.ErrChatNotFound,
+		gateway.ErrDraftNotFound,
+		gateway.ErrAPIKeyNotFound,
+		gateway.ErrFileNotFound,
+		gateway.ErrWebhookNotFound,
+		entity.ErrMessageNotFound,
+		entity.ErrTagNotFound,
+		entity.ErrAnnotationNotFound,
+		streambuffer.ErrStreamNotFound,
+		chatcompletionstream.ErrGenerationNotFound,
+	}
+	forbiddenErrors = []error{
+		entity.ErrChatForbidden,
+		entity.ErrAPIKeyForbidden,
+		entity.ErrWebhookForbidden,
+		streambuffer.ErrStreamForbidden,
+	}
+)
+
+// isAny tells whether err is one of targets, whatever wraps it.
+func isAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// domainError is the status of the typed errors of the domain and the
+// usecases, nil for the others.
+func domainError(err error) error {
+	switch {
+	case isAny(err, notFoundErrors):
+		return reasonError(codes.NotFound, reasonNotFound, err, 0)
+	case isAny(err, forbiddenErrors):
+		return reasonError(codes.PermissionDenied, reasonPermissionDenied, err, 0)
+	case errors.Is(err, gateway.ErrChatConflict):
+		return reasonError(codes.Aborted, reasonConflict, err, retryDelay)
+	case errors.Is(err, entity.ErrTokenLimitExceeded):
+		return reasonError(codes.InvalidArgument, reasonTokenLimit, err, 0)
+	case errors.Is(err, entity.ErrInvalidRole):
+		return reasonError(codes.InvalidArgument, reasonInvalidArgument, err, 0)
+	case errors.Is(err, entity.ErrProviderUnavailable):
+		return reasonError(codes.Unavailable, reasonProviderUnavailable, err, retryDelay)
+	case errors.Is(err, dispatcher.ErrUserLimit):
+		return reasonError(codes.ResourceExhausted, reasonUserLimit, err, retryDelay)
+	case errors.Is(err, dispatcher.ErrQueueFull), errors.Is(err, dispatcher.ErrQueueTimeout):
+		return reasonError(codes.ResourceExhausted, reasonOverloaded, err, retryDelay)
+	case errors.Is(err, streambuffer.ErrSequenceExpired):
+		return reasonError(codes.OutOfRange, reasonSequenceExpired, err, 0)
+	case errors.Is(err, chatcompletionstream.ErrShuttingDown):
+		return reasonError(codes.Unavailable, reasonShuttingDown, err, retryDelay)
+	case errors.Is(err, chatcompletionstream.ErrIdempotencyKeyInProgress):
+		return reasonError(codes.Aborted, reasonIdempotencyKey, err, retryDelay)
+	case errors.Is(err, chatcompletionstream.ErrIdempotencyKeyReused):
+		return reasonError(codes.FailedPrecondition, reasonIdempotencyKey, err, 0)
+	}
+	return nil
+}
+
+// completionError is the status of a failed completion, the context error
+// when the call was canceled or timed out. The provider failing, concurrent
+// updates of the chat, a full completion queue, a generation timing out and
+// an instance shutting down are worth retrying, rate limited turns once the
+// limit lets them through and turns over the limit of the user once one of
+// theirs is done.
+func completionError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	var limited *ratelimit.Error
+	if errors.As(err, &limited) {
+		return reasonError(codes.ResourceExhausted, reasonRateLimited, err, limited.RetryAfter)
+	}
+	var slow *chatcompletionstream.ConsumerTooSlowError
+	if errors.As(err, &slow) {
+		return reasonError(codes.ResourceExhausted, reasonConsumerTooSlow, err, 0)
+	}
+	var lagged *chatcompletionstream.StreamLaggedError
+	if errors.As(err, &lagged) {
+		return reasonError(codes.ResourceExhausted, reasonConsumerTooSlow, err, 0)
+	}
+	var timedOut *chatcompletionstream.TimeoutError
+	if errors.As(err, &timedOut) {
+		return reasonError(codes.DeadlineExceeded, reasonGenerationTimeout, err, retryDelay)
+	}
+	if st := domainError(err); st != nil {
+		return st
+	}
+	return reasonError(codes.Internal, reasonInternal, err, 0)
+}
+
+// usecaseError maps the usecase errors to a status, like the HTTP API maps
+// them to a status code.
+func usecaseError(err error) error {
+	if st := domainError(err); st != nil {
+		return st
+	}
+	return reasonError(codes.InvalidArgument, reasonInvalidArgument, err, 0)
+}
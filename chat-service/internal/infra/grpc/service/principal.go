@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/middleware"
+	"google.golang.org/grpc/codes"
+)
+
+// requestUser is the user a request is made as: the user of its API key, or
+// its user_id otherwise. An API key can't make requests for another user.
+func requestUser(ctx context.Context, userID string) (string, error) {
+	p := middleware.PrincipalFromContext(ctx)
+	if p == nil {
+		if userID == "" {
+			return "", fieldError("user_id", "is required")
+		}
+		return userID, nil
+	}
+	if userID != "" && userID != p.UserID {
+		return "", reasonError(codes.PermissionDenied, reasonPermissionDenied, errors.New("user_id is not the user of the api key"), 0)
+	}
+	return p.UserID, nil
+}
+
+// requestOrg is the tenant of the API key of a request, if any.
+func requestOrg(ctx context.Context) string {
+	if p := middleware.PrincipalFromContext(ctx); p != nil {
+		return p.OrgID
+	}
+	return ""
+}
+
+// requestTier is the completion tier of the caller of a request, empty for
+// the default one.
+func requestTier(ctx context.Context) string {
+	if p := middleware.PrincipalFromContext(ctx); p != nil {
+		return p.Tier
+	}
+	return ""
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	chatv2 "github.com/alecanutto/fclx/chat-service/internal/infra/grpc/pb/chat/v2"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+)
+
+func (s *ChatService) Regenerate(req *chatv2.RegenerateRequest, stream chatv2.ChatService_RegenerateServer) error {
+	userID, err := requestUser(stream.Context(), req.GetUserId())
+	if err != nil {
+		return err
+	}
+	if req.GetChatId() == "" {
+		return fieldError("chat_id", "is required")
+	}
+	input := chatcompletionstream.RegenerateInputDTO{
+		ChatID: req.GetChatId(),
+		UserID: userID,
+		OrgID:  requestOrg(stream.Context()),
+		Tier:   requestTier(stream.Context()),
+	}
+	if req.GetTemperature() != nil {
+		temperature := req.GetTemperature().GetValue()
+		input.Temperature = &temperature
+	}
+	_, err = streamTurn(stream.Context(), stream.Send, req.GetDeltaOnly(), func(send func(chatcompletionstream.ChatCompletionOutputDTO) error) (*chatcompletionstream.ChatCompletionOutputDTO, error) {
+		return s.RegenerateUseCase.ExecuteStreaming(stream.Context(), input, send)
+	})
+	return err
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"io"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/dispatcher"
+	chatv2 "github.com/alecanutto/fclx/chat-service/internal/infra/grpc/pb/chat/v2"
+)
+
+// ChatSession answers the requests of the stream one turn after the other,
+// all in the chat of the first one. The stream ends when the client closes
+// its side, after the turn in progress.
+func (s *ChatService) ChatSession(stream chatv2.ChatService_ChatSessionServer) error {
+	ctx := stream.Context()
+	var chatID, userID string
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if userID == "" {
+			if userID, err = requestUser(ctx, req.GetUserId()); err != nil {
+				return err
+			}
+			chatID = req.GetChatId()
+		}
+		if req.GetUserId() != "" && req.GetUserId() != userID {
+			return fieldError("user_id", "can't change during a session")
+		}
+		if req.GetChatId() != "" && chatID != "" && req.GetChatId() != chatID {
+			return fieldError("chat_id", "can't change during a session")
+		}
+		if req.GetUserMessage() == "" {
+			return fieldError("user_message", "is required")
+		}
+		if _, ok := dispatcher.ParsePriority(req.GetPriority()); !ok {
+			return fieldError("priority", "must be interactive or batch")
+		}
+		output, err := s.complete(ctx, req, chatID, userID, stream.Send)
+		if err != nil {
+			return err
+		}
+		if output != nil {
+			chatID = output.ChatID
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+
+	chatv2 "github.com/alecanutto/fclx/chat-service/internal/infra/grpc/pb/chat/v2"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+)
+
+func (s *ChatService) StopGeneration(ctx context.Context, req *chatv2.StopGenerationRequest) (*chatv2.StopGenerationResponse, error) {
+	userID, err := requestUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	err = s.StopGenerationUseCase.Execute(ctx, chatcompletionstream.StopGenerationInputDTO{
+		ChatID: req.GetChatId(),
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, usecaseError(err)
+	}
+	return &chatv2.StopGenerationResponse{}, nil
+}
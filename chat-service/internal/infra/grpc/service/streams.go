@@ -0,0 +1,63 @@
+package service
+
+import (
+	chatv2 "github.com/alecanutto/fclx/chat-service/internal/infra/grpc/pb/chat/v2"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *ChatService) WatchChat(req *chatv2.WatchChatRequest, stream chatv2.ChatService_WatchChatServer) error {
+	if s.WatchChatUseCase == nil {
+		return status.Error(codes.Unimplemented, "watching chats is not enabled")
+	}
+	userID, err := requestUser(stream.Context(), req.GetUserId())
+	if err != nil {
+		return err
+	}
+	if req.GetChatId() == "" {
+		return fieldError("chat_id", "is required")
+	}
+	err = s.WatchChatUseCase.Execute(stream.Context(), chatcompletionstream.WatchChatInputDTO{
+		ChatID: req.GetChatId(),
+		UserID: userID,
+	}, chunkSender(stream.Send, req.GetDeltaOnly()))
+	return completionError(stream.Context(), err)
+}
+
+func (s *ChatService) ResumeStream(req *chatv2.ResumeStreamRequest, stream chatv2.ChatService_ResumeStreamServer) error {
+	if s.ResumeStreamUseCase == nil {
+		return status.Error(codes.Unimplemented, "resuming streams is not enabled")
+	}
+	userID, err := requestUser(stream.Context(), req.GetUserId())
+	if err != nil {
+		return err
+	}
+	if req.GetResumeToken() == "" {
+		return fieldError("resume_token", "is required")
+	}
+	if req.GetLastSeq() < 0 {
+		return fieldError("last_seq", "must not be negative")
+	}
+	err = s.ResumeStreamUseCase.ExecuteStreaming(stream.Context(), chatcompletionstream.ResumeStreamInputDTO{
+		ResumeToken: req.GetResumeToken(),
+		UserID:      userID,
+		LastSeq:     int(req.GetLastSeq()),
+	}, chunkSender(stream.Send, req.GetDeltaOnly()))
+	return completionError(stream.Context(), err)
+}
+
+// chunkSender hands the chunks of a stream to send as responses carrying
+// their delta, without the content so far when deltaOnly.
+func chunkSender(send func(*chatv2.ChatResponse) error, deltaOnly bool) func(chatcompletionstream.ChatCompletionOutputDTO) error {
+	return func(chunk chatcompletionstream.ChatCompletionOutputDTO) error {
+		resp := newChatResponse(chunk)
+		if chunk.Event == chatcompletionstream.EventContent {
+			resp.Delta = chunk.Delta
+			if deltaOnly {
+				resp.Content = ""
+			}
+		}
+		return send(resp)
+	}
+}
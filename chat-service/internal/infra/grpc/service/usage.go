@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	chatv2 "github.com/alecanutto/fclx/chat-service/internal/infra/grpc/pb/chat/v2"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/middleware"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/usage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// usageDayLayout is the layout of the days of the usage RPCs.
+const usageDayLayout = "2006-01-02"
+
+func (s *ChatService) GetUsage(ctx context.Context, req *chatv2.GetUsageRequest) (*chatv2.GetUsageResponse, error) {
+	if s.GetUsageUseCase == nil {
+		return nil, status.Error(codes.Unimplemented, "usage accounting is not enabled")
+	}
+	input := usage.GetUsageInputDTO{
+		OrgID:  req.GetOrgId(),
+		UserID: req.GetUserId(),
+		Model:  req.GetModel(),
+	}
+	if err := tenantScope(ctx, &input.OrgID, &input.UserID); err != nil {
+		return nil, err
+	}
+	var err error
+	if input.From, err = usageDay("from", req.GetFrom()); err != nil {
+		return nil, err
+	}
+	if input.To, err = usageDay("to", req.GetTo()); err != nil {
+		return nil, err
+	}
+	output, err := s.GetUsageUseCase.Execute(ctx, input)
+	if err != nil {
+		return nil, usecaseError(err)
+	}
+	resp := &chatv2.GetUsageResponse{
+		From:    output.From.Format(usageDayLayout),
+		To:      output.To.Format(usageDayLayout),
+		Records: make([]*chatv2.UsageRecord, 0, len(output.Records)),
+		Total:   usageRecord(output.Total),
+	}
+	for _, record := range output.Records {
+		resp.Records = append(resp.Records, usageRecord(record))
+	}
+	return resp, nil
+}
+
+// tenantScope narrows the orgID and userID of a report to the tenant of the
+// API key of the request, or to its user outside of one. The requests
+// without a key are the operator's, they see every tenant.
+func tenantScope(ctx context.Context, orgID, userID *string) error {
+	p := middleware.PrincipalFromContext(ctx)
+	if p == nil {
+		return nil
+	}
+	if p.OrgID == "" {
+		id, err := requestUser(ctx, *userID)
+		if err != nil {
+			return err
+		}
+		*userID = id
+		return nil
+	}
+	if *orgID != "" && *orgID != p.OrgID {
+		return reasonError(codes.PermissionDenied, reasonPermissionDenied, errors.New("org_id is not the tenant of the api key"), 0)
+	}
+	*orgID = p.OrgID
+	return nil
+}
+
+// usageDay parses the day of field, zero when it is empty.
+func usageDay(field, day string) (time.Time, error) {
+	if day == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(usageDayLayout, day)
+	if err != nil {
+		return time.Time{}, fieldError(field, "must be a day in YYYY-MM-DD")
+	}
+	return t, nil
+}
+
+func usageRecord(record usage.UsageRecordOutputDTO) *chatv2.UsageRecord {
+	r := &chatv2.UsageRecord{
+		OrgId:            record.OrgID,
+		UserId:           record.UserID,
+		Model:            record.Model,
+		Turns:            record.Turns,
+		PromptTokens:     record.PromptTokens,
+		CompletionTokens: record.CompletionTokens,
+		TotalTokens:      record.TotalTokens,
+		Cost:             record.Cost,
+		UnpricedTurns:    record.UnpricedTurns,
+	}
+	if !record.Day.IsZero() {
+		r.Day = record.Day.Format(usageDayLayout)
+	}
+	return r
+}
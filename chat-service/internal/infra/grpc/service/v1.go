@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+
+	chatv1 "github.com/alecanutto/fclx/chat-service/internal/infra/grpc/pb/chat/v1"
+	chatv2 "github.com/alecanutto/fclx/chat-service/internal/infra/grpc/pb/chat/v2"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+)
+
+// ChatServiceV1 serves the frozen v1 API on ChatService: requests are
+// translated to v2 and responses back, so v2 can change without breaking the
+// v1 clients. The translations are where v1 behavior is kept once they
+// differ.
+type ChatServiceV1 struct {
+	chatv1.UnimplementedChatServiceServer
+	ChatService *ChatService
+}
+
+func NewChatServiceV1(chatService *ChatService) *ChatServiceV1 {
+	return &ChatServiceV1{ChatService: chatService}
+}
+
+func (s *ChatServiceV1) ChatStream(req *chatv1.ChatRequest, stream chatv1.ChatService_ChatStreamServer) error {
+	return s.ChatService.ChatStream(chatRequestToV2(req), v1ChatStream{stream})
+}
+
+func (s *ChatServiceV1) ChatSession(stream chatv1.ChatService_ChatSessionServer) error {
+	return s.ChatService.ChatSession(v1ChatSession{stream})
+}
+
+func (s *ChatServiceV1) ListChats(ctx context.Context, req *chatv1.ListChatsRequest) (*chatv1.ListChatsResponse, error) {
+	resp, err := s.ChatService.ListChats(ctx, &chatv2.ListChatsRequest{
+		UserId: req.GetUserId(),
+		Page:   req.GetPage(),
+		Size:   req.GetSize(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	chats := make([]*chatv1.ChatSummary, 0, len(resp.GetChats()))
+	for _, chat := range resp.GetChats() {
+		chats = append(chats, &chatv1.ChatSummary{
+			ChatId:             chat.GetChatId(),
+			Title:              chat.GetTitle(),
+			Status:             chat.GetStatus(),
+			Tags:               chat.GetTags(),
+			LastMessagePreview: chat.GetLastMessagePreview(),
+			CreatedAt:          chat.GetCreatedAt(),
+			UpdatedAt:          chat.GetUpdatedAt(),
+		})
+	}
+	return &chatv1.ListChatsResponse{
+		Chats:      chats,
+		Page:       resp.GetPage(),
+		Size:       resp.GetSize(),
+		Total:      resp.GetTotal(),
+		TotalPages: resp.GetTotalPages(),
+	}, nil
+}
+
+func (s *ChatServiceV1) RenameChat(ctx context.Context, req *chatv1.RenameChatRequest) (*chatv1.RenameChatResponse, error) {
+	resp, err := s.ChatService.RenameChat(ctx, &chatv2.RenameChatRequest{
+		ChatId: req.GetChatId(),
+		UserId: req.GetUserId(),
+		Title:  req.GetTitle(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &chatv1.RenameChatResponse{
+		ChatId: resp.GetChatId(),
+		Title:  resp.GetTitle(),
+	}, nil
+}
+
+func (s *ChatServiceV1) DeleteChat(ctx context.Context, req *chatv1.DeleteChatRequest) (*chatv1.DeleteChatResponse, error) {
+	_, err := s.ChatService.DeleteChat(ctx, &chatv2.DeleteChatRequest{
+		ChatId: req.GetChatId(),
+		UserId: req.GetUserId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &chatv1.DeleteChatResponse{}, nil
+}
+
+func (s *ChatServiceV1) Regenerate(req *chatv1.RegenerateRequest, stream chatv1.ChatService_RegenerateServer) error {
+	return s.ChatService.Regenerate(&chatv2.RegenerateRequest{
+		ChatId:      req.GetChatId(),
+		UserId:      req.GetUserId(),
+		Temperature: req.GetTemperature(),
+	}, v1RegenerateStream{stream})
+}
+
+func (s *ChatServiceV1) StopGeneration(ctx context.Context, req *chatv1.StopGenerationRequest) (*chatv1.StopGenerationResponse, error) {
+	_, err := s.ChatService.StopGeneration(ctx, &chatv2.StopGenerationRequest{
+		ChatId: req.GetChatId(),
+		UserId: req.GetUserId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &chatv1.StopGenerationResponse{}, nil
+}
+
+func chatRequestToV2(req *chatv1.ChatRequest) *chatv2.ChatRequest {
+	return &chatv2.ChatRequest{
+		ChatId:      req.GetChatId(),
+		UserId:      req.GetUserId(),
+		UserMessage: req.GetUserMessage(),
+	}
+}
+
+// skipV1 tells the responses v1 has no event for, they aren't sent to v1
+// clients.
+func skipV1(resp *chatv2.ChatResponse) bool {
+	switch resp.GetEvent() {
+	case chatcompletionstream.EventUsage, chatcompletionstream.EventGenerationEnded, chatcompletionstream.EventHeartbeat:
+		return true
+	}
+	return false
+}
+
+func chatResponseToV1(resp *chatv2.ChatResponse) *chatv1.ChatResponse {
+	return &chatv1.ChatResponse{
+		ChatId:               resp.GetChatId(),
+		UserId:               resp.GetUserId(),
+		Content:              resp.GetContent(),
+		Event:                resp.GetEvent(),
+		Seq:                  resp.GetSeq(),
+		ResumeToken:          resp.GetResumeToken(),
+		QueuePosition:        resp.GetQueuePosition(),
+		AwaitingConfirmation: resp.GetAwaitingConfirmation(),
+		Delta:                resp.GetDelta(),
+	}
+}
+
+// v1ChatStream is a v1 stream seen as a v2 one by ChatService, like
+// v1ChatSession and v1RegenerateStream.
+type v1ChatStream struct {
+	chatv1.ChatService_ChatStreamServer
+}
+
+func (s v1ChatStream) Send(resp *chatv2.ChatResponse) error {
+	if skipV1(resp) {
+		return nil
+	}
+	return s.ChatService_ChatStreamServer.Send(chatResponseToV1(resp))
+}
+
+type v1ChatSession struct {
+	chatv1.ChatService_ChatSessionServer
+}
+
+func (s v1ChatSession) Send(resp *chatv2.ChatResponse) error {
+	if skipV1(resp) {
+		return nil
+	}
+	return s.ChatService_ChatSessionServer.Send(chatResponseToV1(resp))
+}
+
+func (s v1ChatSession) Recv() (*chatv2.ChatRequest, error) {
+	req, err := s.ChatService_ChatSessionServer.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return chatRequestToV2(req), nil
+}
+
+type v1RegenerateStream struct {
+	chatv1.ChatService_RegenerateServer
+}
+
+func (s v1RegenerateStream) Send(resp *chatv2.ChatResponse) error {
+	if skipV1(resp) {
+		return nil
+	}
+	return s.ChatService_RegenerateServer.Send(chatResponseToV1(resp))
+}
@@ -0,0 +1,103 @@
+package highlight
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+// Markers delimit the matches in the snippets built by databases, control
+// characters never show up in chat messages.
+const (
+	StartMarker = "\x02"
+	StopMarker  = "\x03"
+)
+
+// Parse strips the markers of a snippet and returns the character ranges
+// they delimited.
+func Parse(marked string) (string, []gateway.TextRange) {
+	var b strings.Builder
+	var ranges []gateway.TextRange
+	pos, start := 0, -1
+	for _, r := range marked {
+		switch string(r) {
+		case StartMarker:
+			start = pos
+		case StopMarker:
+			if start >= 0 && pos > start {
+				ranges = append(ranges, gateway.TextRange{Start: start, End: pos})
+			}
+			start = -1
+		default:
+			b.WriteRune(r)
+			pos++
+		}
+	}
+	return b.String(), ranges
+}
+
+// Terms splits a search query into lowercase words.
+func Terms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Snippet finds the words of content starting with one of terms and returns
+// an excerpt of about width characters around the first one, with the ranges of
+// the matching words in it. It reports false when no word matches.
+func Snippet(content string, terms []string, width int) (string, []gateway.TextRange, bool) {
+	runes := []rune(content)
+	type word struct{ start, end int }
+	var matches []word
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) {
+			j++
+		}
+		w := strings.ToLower(string(runes[i:j]))
+		for _, t := range terms {
+			if strings.HasPrefix(w, t) {
+				matches = append(matches, word{i, j})
+				break
+			}
+		}
+		i = j
+	}
+	if len(matches) == 0 {
+		return "", nil, false
+	}
+	from := matches[0].start - width/4
+	if from < 0 {
+		from = 0
+	}
+	to := from + width
+	if to > len(runes) {
+		to = len(runes)
+	}
+	prefix, suffix := "", ""
+	if from > 0 {
+		prefix = "…"
+	}
+	if to < len(runes) {
+		suffix = "…"
+	}
+	offset := utf8.RuneCountInString(prefix) - from
+	var ranges []gateway.TextRange
+	for _, m := range matches {
+		if m.start >= from && m.end <= to {
+			ranges = append(ranges, gateway.TextRange{Start: m.start + offset, End: m.end + offset})
+		}
+	}
+	return prefix + string(runes[from:to]) + suffix, ranges, true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
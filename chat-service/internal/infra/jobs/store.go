@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/google/uuid"
+)
+
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+type Job[T any] struct {
+	ID         string
+	Owner      string
+	Status     string
+	Total      int
+	Done       int
+	Result     T
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Store runs background jobs and keeps their progress and result in memory
+// for ttl after they finish.
+type Store[T any] struct {
+	mu    sync.Mutex
+	clock clock.Clock
+	ttl   time.Duration
+	jobs  map[string]*Job[T]
+}
+
+func NewStore[T any](clk clock.Clock, ttl time.Duration) *Store[T] {
+	return &Store[T]{
+		clock: clk,
+		ttl:   ttl,
+		jobs:  make(map[string]*Job[T]),
+	}
+}
+
+// Start runs fn in its own goroutine and returns the job ID right away. fn reports
+// progress through the given func; ctx is not tied to the caller's request.
+func (s *Store[T]) Start(owner string, total int, fn func(ctx context.Context, progress func(done int)) (T, error)) string {
+	job := &Job[T]{
+		ID:        uuid.New().String(),
+		Owner:     owner,
+		Status:    StatusRunning,
+		Total:     total,
+		StartedAt: s.clock.Now(),
+	}
+	s.mu.Lock()
+	s.evictExpired()
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+	go func() {
+		result, err := fn(context.Background(), func(done int) {
+			s.mu.Lock()
+			job.Done = done
+			s.mu.Unlock()
+		})
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		job.Result = result
+		job.Status = StatusSucceeded
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		}
+		job.FinishedAt = s.clock.Now()
+	}()
+	return job.ID
+}
+
+// Get returns a copy of the job, only to its owner.
+func (s *Store[T]) Get(jobID, owner string) (Job[T], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictExpired()
+	job, ok := s.jobs[jobID]
+	if !ok || job.Owner != owner {
+		return Job[T]{}, ErrJobNotFound
+	}
+	return *job, nil
+}
+
+func (s *Store[T]) evictExpired() {
+	now := s.clock.Now()
+	for id, job := range s.jobs {
+		if job.Status != StatusRunning && now.Sub(job.FinishedAt) > s.ttl {
+			delete(s.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package logging writes the logs of the service as structured records, in
+// logfmt or JSON for log aggregation. The logger of a call travels in its
+// context, carrying the fields that identify it, the request ID, chat and
+// user, so every stage logs them.
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+// Level is the severity of a record, the records below the level of a
+// Logger are dropped.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	}
+	return "error"
+}
+
+// ParseLevel is the level named s, info when empty.
+func ParseLevel(s string) (Level, bool) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, true
+	case "", "info":
+		return LevelInfo, true
+	case "warn", "warning":
+		return LevelWarn, true
+	case "error":
+		return LevelError, true
+	}
+	return 0, false
+}
+
+// Format is how the records are written.
+type Format string
+
+const (
+	// FormatText writes time, level and message then the fields in logfmt.
+	FormatText Format = "text"
+	// FormatJSON writes a JSON object per line.
+	FormatJSON Format = "json"
+)
+
+// Field is a key and a value of a record.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func String(key, value string) Field    { return Field{Key: key, Value: value} }
+func Int(key string, value int) Field   { return Field{Key: key, Value: value} }
+func Bool(key string, value bool) Field { return Field{Key: key, Value: value} }
+
+// Duration is d in milliseconds, under key suffixed with _ms.
+func Duration(key string, d time.Duration) Field {
+	return Field{Key: key + "_ms", Value: d.Milliseconds()}
+}
+
+// Err is the message of err under error, nil gives an empty one.
+func Err(err error) Field {
+	if err == nil {
+		return Field{Key: "error", Value: ""}
+	}
+	return Field{Key: "error", Value: err.Error()}
+}
+
+// output is shared by a Logger and those derived from it with With, so
+// their records don't interleave.
+type output struct {
+	mu     sync.Mutex
+	w      io.Writer
+	level  Level
+	format Format
+	clock  clock.Clock
+}
+
+// Logger writes records with its fields. The methods of a nil Logger do
+// nothing, it is what a context without one gives.
+type Logger struct {
+	out    *output
+	fields []Field
+}
+
+func New(w io.Writer, level Level, format Format, clk clock.Clock) *Logger {
+	if clk == nil {
+		clk = clock.Real()
+	}
+	if format != FormatJSON {
+		format = FormatText
+	}
+	return &Logger{out: &output{w: w, level: level, format: format, clock: clk}}
+}
+
+// With returns a logger writing fields with every record, after those of l.
+func (l *Logger) With(fields ...Field) *Logger {
+	if l == nil {
+		return nil
+	}
+	return &Logger{
+		out:    l.out,
+		fields: append(append([]Field(nil), l.fields...), fields...),
+	}
+}
+
+// Enabled tells the records of level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return l != nil && level >= l.out.level
+}
+
+func (l *Logger) Debug(msg string, fields ...Field) { l.log(LevelDebug, msg, fields) }
+func (l *Logger) Info(msg string, fields ...Field)  { l.log(LevelInfo, msg, fields) }
+func (l *Logger) Warn(msg string, fields ...Field)  { l.log(LevelWarn, msg, fields) }
+func (l *Logger) Error(msg string, fields ...Field) { l.log(LevelError, msg, fields) }
+
+func (l *Logger) log(level Level, msg string, fields []Field) {
+	if !l.Enabled(level) {
+		return
+	}
+	out := l.out
+	at := out.clock.Now().UTC().Format(time.RFC3339Nano)
+	all := append(append([]Field(nil), l.fields...), fields...)
+	var line []byte
+	if out.format == FormatJSON {
+		line = jsonRecord(at, level, msg, all)
+	} else {
+		line = textRecord(at, level, msg, all)
+	}
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	out.w.Write(line)
+}
+
+func jsonRecord(at string, level Level, msg string, fields []Field) []byte {
+	var b strings.Builder
+	b.WriteString(`{"time":` + strconv.Quote(at) + `,"level":"` + level.String() + `","msg":`)
+	writeJSON(&b, msg)
+	for _, f := range fields {
+		b.WriteByte(',')
+		writeJSON(&b, f.Key)
+		b.WriteByte(':')
+		writeJSON(&b, f.Value)
+	}
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+func writeJSON(b *strings.Builder, v interface{}) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		encoded, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(encoded)
+}
+
+func textRecord(at string, level Level, msg string, fields []Field) []byte {
+	var b strings.Builder
+	b.WriteString("time=" + at + " level=" + level.String() + " msg=" + textValue(msg))
+	for _, f := range fields {
+		b.WriteString(" " + f.Key + "=" + textValue(fmt.Sprint(f.Value)))
+	}
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// textValue quotes s when it has spaces, quotes or an equal sign.
+func textValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+type loggerKey struct{}
+
+// NewContext returns ctx with l as its logger.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger of ctx, nil when it has none.
+func FromContext(ctx context.Context) *Logger {
+	l, _ := ctx.Value(loggerKey{}).(*Logger)
+	return l
+}
+
+// WithContext returns ctx with its logger writing fields too.
+func WithContext(ctx context.Context, fields ...Field) context.Context {
+	l := FromContext(ctx)
+	if l == nil {
+		return ctx
+	}
+	return NewContext(ctx, l.With(fields...))
+}
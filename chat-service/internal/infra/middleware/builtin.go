@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"sync"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/logging"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/requestid"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestID keeps the request ID the client sent, or gives the call a new
+// one, in the context of the call and the headers of its response.
+func RequestID() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			id := requestid.Incoming(call.Header(requestid.Header))
+			call.SetHeader(requestid.Header, id)
+			return next(requestid.NewContext(ctx, id), call)
+		}
+	}
+}
+
+// Tracing names the server span otelgrpc or otelhttp started for the call
+// after its method, tags it with the transport, request ID and status of the
+// call and fails it with its error, then sends the trace context back in the
+// headers of the response. It lets calls through untraced when no tracer
+// provider is registered.
+func Tracing() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			span := trace.SpanFromContext(ctx)
+			if !span.IsRecording() {
+				return next(ctx, call)
+			}
+			span.SetName(call.name())
+			span.SetAttributes(
+				attribute.String("transport", call.Transport),
+				attribute.String("request.id", requestid.FromContext(ctx)))
+			otel.GetTextMapPropagator().Inject(ctx, headerCarrier{call})
+			err := next(ctx, call)
+			span.SetAttributes(attribute.String("status", call.Status))
+			tracing.RecordError(span, err)
+			return err
+		}
+	}
+}
+
+// headerCarrier injects the trace context in the headers of the response of
+// a call.
+type headerCarrier struct {
+	call *Call
+}
+
+func (c headerCarrier) Get(key string) string        { return c.call.Header(key) }
+func (c headerCarrier) Set(key string, value string) { c.call.SetHeader(key, value) }
+func (c headerCarrier) Keys() []string               { return nil }
+
+// Recovery turns a panic of the rest of the chain into an Internal error,
+// so one bad call doesn't take the server down. onPanic, when set, is told
+// of it with the context of the call, from the deferred function that
+// recovered it so the stack is still the one that panicked.
+func Recovery(onPanic func(ctx context.Context, method string, recovered interface{})) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) (err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				if onPanic != nil {
+					onPanic(ctx, call.Method, r)
+				}
+				err = &Error{Code: Internal, Message: "internal error"}
+			}()
+			return next(ctx, call)
+		}
+	}
+}
+
+// Auth requires token in the authorization header of every call but those
+// authenticated as a Principal already, it lets every call through when token
+// is empty.
+func Auth(token string) Middleware {
+	return func(next Handler) Handler {
+		if token == "" {
+			return next
+		}
+		return func(ctx context.Context, call *Call) error {
+			if PrincipalFromContext(ctx) != nil {
+				return next(ctx, call)
+			}
+			got := call.Header("authorization")
+			if got == "" {
+				return &Error{Code: Unauthenticated, Message: "authorization token is missing"}
+			}
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				return &Error{Code: Unauthenticated, Message: "authorization token is invalid"}
+			}
+			return next(ctx, call)
+		}
+	}
+}
+
+// corsHeaders are the request headers browsers may send cross-origin.
+const corsHeaders = "Authorization, Content-Type, X-User-ID, X-Request-ID, Idempotency-Key, " + APIKeyHeader
+
+// CORS lets the browsers of origins call the HTTP API, "*" allowing any
+// origin. It answers preflight requests itself and leaves gRPC calls alone.
+func CORS(origins ...string) Middleware {
+	allowed := map[string]bool{}
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			origin := call.Header("Origin")
+			if call.Transport != HTTP || origin == "" || !(allowed[origin] || allowed["*"]) {
+				return next(ctx, call)
+			}
+			call.SetHeader("Access-Control-Allow-Origin", origin)
+			call.SetHeader("Vary", "Origin")
+			call.SetHeader("Access-Control-Expose-Headers", requestid.Header)
+			if call.HTTPMethod != "OPTIONS" || call.Header("Access-Control-Request-Method") == "" {
+				return next(ctx, call)
+			}
+			call.SetHeader("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
+			call.SetHeader("Access-Control-Allow-Headers", corsHeaders)
+			call.SetHeader("Access-Control-Max-Age", "600")
+			return nil
+		}
+	}
+}
+
+// Logging gives every call a logger of logger with its request ID,
+// transport and method, and trace ID when traced, in its context, and logs
+// the call once it returned with its status or error and duration. The calls
+// failed are logged as errors, the others at info.
+func Logging(logger *logging.Logger) Middleware {
+	return func(next Handler) Handler {
+		if logger == nil {
+			return next
+		}
+		return func(ctx context.Context, call *Call) error {
+			startedAt := time.Now()
+			fields := []logging.Field{
+				logging.String("request_id", requestid.FromContext(ctx)),
+				logging.String("transport", call.Transport),
+				logging.String("method", call.name()),
+			}
+			if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+				fields = append(fields, logging.String("trace_id", sc.TraceID().String()))
+			}
+			l := logger.With(fields...)
+			err := next(logging.NewContext(ctx, l), call)
+			status := call.Status
+			if status == "" && err == nil {
+				status = "answered by middleware"
+			}
+			done := []logging.Field{logging.String("status", status), logging.Duration("duration", time.Since(startedAt))}
+			if err != nil {
+				l.Error("call failed", append(done, logging.Err(err))...)
+			} else {
+				l.Info("call served", done...)
+			}
+			return err
+		}
+	}
+}
+
+// Metrics counts calls by transport and method then status, the calls a
+// middleware rejected under the code of their Error.
+type Metrics struct {
+	mu     sync.Mutex
+	counts map[string]map[string]int64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		counts: make(map[string]map[string]int64),
+	}
+}
+
+func (m *Metrics) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			err := next(ctx, call)
+			result := call.Status
+			if rejected, ok := err.(*Error); ok {
+				result = rejected.Code.String()
+			}
+			m.observe(call.Transport+" "+call.name(), result)
+			return err
+		}
+	}
+}
+
+func (m *Metrics) observe(method, result string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counts[method] == nil {
+		m.counts[method] = make(map[string]int64)
+	}
+	m.counts[method][result]++
+}
+
+// Snapshot returns the counts by method then status.
+func (m *Metrics) Snapshot() map[string]map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make(map[string]map[string]int64, len(m.counts))
+	for method, results := range m.counts {
+		snapshot[method] = make(map[string]int64, len(results))
+		for result, n := range results {
+			snapshot[method][result] = n
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,109 @@
+// Package middleware holds the cross-cutting concerns of the HTTP and gRPC
+// APIs, written once against Call and adapted by each transport: the web
+// Router takes them with Use, the gRPC server with its Use.
+package middleware
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Transports of a Call.
+const (
+	HTTP = "http"
+	GRPC = "grpc"
+)
+
+// Call is what a middleware sees of a call of either transport.
+type Call struct {
+	Transport string
+	// Method is the route pattern for HTTP, /v2/chats/, or the full gRPC
+	// method, /chat.v2.ChatService/ChatStream.
+	Method string
+	// HTTPMethod is the method of an HTTP request, empty for gRPC.
+	HTTPMethod string
+	// Path is the path of an HTTP request, empty for gRPC.
+	Path string
+	// Header returns a header of the request, or the first value of a gRPC
+	// metadata key.
+	Header func(key string) string
+	// SetHeader sets a header of the response, or gRPC header metadata.
+	SetHeader func(key, value string)
+	// Status is set by the transport once the handler returned: the HTTP
+	// status code, or the gRPC code.
+	Status string
+}
+
+// name is Method, after the HTTP method for HTTP.
+func (c *Call) name() string {
+	if c.HTTPMethod == "" {
+		return c.Method
+	}
+	return c.HTTPMethod + " " + c.Method
+}
+
+// Handler serves a call, the transports end every chain with their handler.
+type Handler func(ctx context.Context, call *Call) error
+
+type Middleware func(next Handler) Handler
+
+// Chain composes mws into one middleware, the first one running outermost.
+func Chain(mws ...Middleware) Middleware {
+	return func(next Handler) Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
+
+// Except runs mw on every call but those of the methods starting with
+// prefix.
+func Except(prefix string, mw Middleware) Middleware {
+	return func(next Handler) Handler {
+		wrapped := mw(next)
+		return func(ctx context.Context, call *Call) error {
+			if strings.HasPrefix(call.Method, prefix) {
+				return next(ctx, call)
+			}
+			return wrapped(ctx, call)
+		}
+	}
+}
+
+// Code is the kind of an Error, the transports map it to their status.
+type Code int
+
+const (
+	Unauthenticated Code = iota + 1
+	PermissionDenied
+	ResourceExhausted
+	Internal
+)
+
+func (c Code) String() string {
+	switch c {
+	case Unauthenticated:
+		return "unauthenticated"
+	case PermissionDenied:
+		return "permission_denied"
+	case ResourceExhausted:
+		return "resource_exhausted"
+	case Internal:
+		return "internal"
+	}
+	return "unknown"
+}
+
+// Error is how a middleware rejects a call.
+type Error struct {
+	Code    Code
+	Message string
+	// RetryAfter, when set, is when the call may be retried.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Principal is who an authenticated call is made as. The transports take the
+// user and tenant of the call from it rather than from the request.
+type Principal struct {
+	// KeyID is the API key the call was authenticated with, empty for bearer
+	// tokens.
+	KeyID  string
+	UserID string
+	OrgID  string
+	Scopes []string
+	// Tier is the dispatcher tier of the completions of the call, empty for
+	// the default one.
+	Tier string
+}
+
+func (p *Principal) Allows(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+func NewPrincipalContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal of the call of ctx, nil when the
+// call wasn't authenticated as a user.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// APIKeyHeader carries the API key of a call, which may also come as a
+// bearer token.
+const APIKeyHeader = "X-API-Key"
+
+// APIKey authenticates the calls carrying an API key starting with prefix:
+// authenticate resolves the key to its principal, nil when the key is unknown
+// or revoked. scope returns the scope a call needs, empty for the calls no
+// API key may make. The calls without a key are let through, for Auth.
+func APIKey(prefix string, authenticate func(ctx context.Context, key string) (*Principal, error), scope func(call *Call) string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			key := call.Header(APIKeyHeader)
+			if bearer := strings.TrimPrefix(call.Header("authorization"), "Bearer "); key == "" && strings.HasPrefix(bearer, prefix) {
+				key = bearer
+			}
+			if key == "" {
+				return next(ctx, call)
+			}
+			principal, err := authenticate(ctx, key)
+			if err != nil {
+				return &Error{Code: Internal, Message: "error authenticating api key"}
+			}
+			if principal == nil {
+				return &Error{Code: Unauthenticated, Message: "api key is invalid"}
+			}
+			needed := scope(call)
+			if needed == "" {
+				return &Error{Code: PermissionDenied, Message: "api keys can't call " + call.name()}
+			}
+			if !principal.Allows(needed) {
+				return &Error{Code: PermissionDenied, Message: "api key lacks the " + needed + " scope"}
+			}
+			return next(NewPrincipalContext(ctx, principal), call)
+		}
+	}
+}
+
+// BearerToken authenticates the calls carrying a JWT bearer token, and not
+// an API key already: verify resolves the token to its principal, or fails
+// with the Error to reject the call with. The calls without a token are let
+// through, for Auth or RequirePrincipal.
+func BearerToken(verify func(ctx context.Context, token string) (*Principal, error)) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			token := strings.TrimPrefix(call.Header("authorization"), "Bearer ")
+			if PrincipalFromContext(ctx) != nil || strings.Count(token, ".") != 2 {
+				return next(ctx, call)
+			}
+			principal, err := verify(ctx, token)
+			if err != nil {
+				var rejected *Error
+				if errors.As(err, &rejected) {
+					return rejected
+				}
+				return &Error{Code: Internal, Message: "error verifying bearer token"}
+			}
+			return next(NewPrincipalContext(ctx, principal), call)
+		}
+	}
+}
+
+// RequirePrincipal rejects the calls no middleware authenticated as a user.
+func RequirePrincipal() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, call *Call) error {
+			if PrincipalFromContext(ctx) == nil {
+				return &Error{Code: Unauthenticated, Message: "a bearer token or an api key is required"}
+			}
+			return next(ctx, call)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package namespace
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type apiKeyGateway struct {
+	next      gateway.APIKeyGateway
+	namespace string
+}
+
+// NewAPIKeyGateway scopes every call to next to the configured namespace.
+func NewAPIKeyGateway(next gateway.APIKeyGateway, namespace string) gateway.APIKeyGateway {
+	return &apiKeyGateway{
+		next:      next,
+		namespace: namespace,
+	}
+}
+
+func (g *apiKeyGateway) CreateAPIKey(ctx context.Context, key *entity.APIKey) error {
+	return g.next.CreateAPIKey(NewContext(ctx, g.namespace), key)
+}
+
+func (g *apiKeyGateway) FindAPIKeyByID(ctx context.Context, id string) (*entity.APIKey, error) {
+	return g.next.FindAPIKeyByID(NewContext(ctx, g.namespace), id)
+}
+
+func (g *apiKeyGateway) FindAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	return g.next.FindAPIKeyByHash(NewContext(ctx, g.namespace), hash)
+}
+
+func (g *apiKeyGateway) ListAPIKeysByUser(ctx context.Context, userID string) ([]*entity.APIKey, error) {
+	return g.next.ListAPIKeysByUser(NewContext(ctx, g.namespace), userID)
+}
+
+func (g *apiKeyGateway) RevokeAPIKey(ctx context.Context, key *entity.APIKey) error {
+	return g.next.RevokeAPIKey(NewContext(ctx, g.namespace), key)
+}
@@ -0,0 +1,29 @@
+package namespace
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type auditLogGateway struct {
+	next      gateway.AuditLogGateway
+	namespace string
+}
+
+// NewAuditLogGateway scopes every call to next to the configured namespace.
+func NewAuditLogGateway(next gateway.AuditLogGateway, namespace string) gateway.AuditLogGateway {
+	return &auditLogGateway{
+		next:      next,
+		namespace: namespace,
+	}
+}
+
+func (g *auditLogGateway) AppendAuditEntry(ctx context.Context, entry *entity.AuditEntry) error {
+	return g.next.AppendAuditEntry(NewContext(ctx, g.namespace), entry)
+}
+
+func (g *auditLogGateway) ListAuditEntries(ctx context.Context, filter gateway.AuditFilter) ([]*entity.AuditEntry, error) {
+	return g.next.ListAuditEntries(NewContext(ctx, g.namespace), filter)
+}
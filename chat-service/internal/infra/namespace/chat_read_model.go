@@ -0,0 +1,41 @@
+package namespace
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type chatReadModel struct {
+	next      gateway.ChatReadModel
+	namespace string
+}
+
+// NewChatReadModel scopes every call to next to the configured namespace.
+func NewChatReadModel(next gateway.ChatReadModel, namespace string) gateway.ChatReadModel {
+	return &chatReadModel{
+		next:      next,
+		namespace: namespace,
+	}
+}
+
+func (g *chatReadModel) UpsertChatSummary(ctx context.Context, summary *gateway.ChatSummary) error {
+	return g.next.UpsertChatSummary(NewContext(ctx, g.namespace), summary)
+}
+
+func (g *chatReadModel) DeleteChatSummary(ctx context.Context, chatID string) error {
+	return g.next.DeleteChatSummary(NewContext(ctx, g.namespace), chatID)
+}
+
+func (g *chatReadModel) ListChatSummaries(ctx context.Context, filter gateway.ChatSummaryFilter) ([]*gateway.ChatSummary, error) {
+	return g.next.ListChatSummaries(NewContext(ctx, g.namespace), filter)
+}
+
+func (g *chatReadModel) CountChatSummaries(ctx context.Context, filter gateway.ChatSummaryFilter) (int, error) {
+	return g.next.CountChatSummaries(NewContext(ctx, g.namespace), filter)
+}
+
+func (g *chatReadModel) PurgeStaleChatSummaries(ctx context.Context, projectedBefore time.Time) (int64, error) {
+	return g.next.PurgeStaleChatSummaries(NewContext(ctx, g.namespace), projectedBefore)
+}
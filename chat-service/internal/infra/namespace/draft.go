@@ -0,0 +1,33 @@
+package namespace
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type draftGateway struct {
+	next      gateway.DraftGateway
+	namespace string
+}
+
+// NewDraftGateway scopes every call to next to the configured namespace.
+func NewDraftGateway(next gateway.DraftGateway, namespace string) gateway.DraftGateway {
+	return &draftGateway{
+		next:      next,
+		namespace: namespace,
+	}
+}
+
+func (g *draftGateway) SaveDraft(ctx context.Context, draft *entity.Draft) error {
+	return g.next.SaveDraft(NewContext(ctx, g.namespace), draft)
+}
+
+func (g *draftGateway) FindDraft(ctx context.Context, chatID, userID string) (*entity.Draft, error) {
+	return g.next.FindDraft(NewContext(ctx, g.namespace), chatID, userID)
+}
+
+func (g *draftGateway) DeleteDraft(ctx context.Context, chatID, userID string) error {
+	return g.next.DeleteDraft(NewContext(ctx, g.namespace), chatID, userID)
+}
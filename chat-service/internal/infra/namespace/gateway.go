@@ -0,0 +1,83 @@
+package namespace
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type chatGateway struct {
+	next      gateway.ChatGateway
+	namespace string
+}
+
+// NewChatGateway scopes every call to next to the configured namespace,
+// replacing whatever namespace the caller's context carried.
+func NewChatGateway(next gateway.ChatGateway, namespace string) gateway.ChatGateway {
+	return &chatGateway{
+		next:      next,
+		namespace: namespace,
+	}
+}
+
+func (g *chatGateway) CreateChat(ctx context.Context, chat *entity.Chat) error {
+	return g.next.CreateChat(NewContext(ctx, g.namespace), chat)
+}
+
+func (g *chatGateway) FindChatByID(ctx context.Context, chatID string) (*entity.Chat, error) {
+	return g.next.FindChatByID(NewContext(ctx, g.namespace), chatID)
+}
+
+func (g *chatGateway) FindChatsByParentID(ctx context.Context, parentChatID string) ([]*entity.Chat, error) {
+	return g.next.FindChatsByParentID(NewContext(ctx, g.namespace), parentChatID)
+}
+
+func (g *chatGateway) ListChats(ctx context.Context, filter gateway.ChatFilter) ([]*entity.Chat, error) {
+	return g.next.ListChats(NewContext(ctx, g.namespace), filter)
+}
+
+func (g *chatGateway) ListChatsByUser(ctx context.Context, userID string, page int, size int) (*gateway.ChatPage, error) {
+	return g.next.ListChatsByUser(NewContext(ctx, g.namespace), userID, page, size)
+}
+
+func (g *chatGateway) ListMessages(ctx context.Context, chatID string, cursor gateway.MessageCursor) (*gateway.MessagePage, error) {
+	return g.next.ListMessages(NewContext(ctx, g.namespace), chatID, cursor)
+}
+
+func (g *chatGateway) SaveChat(ctx context.Context, chat *entity.Chat) error {
+	return g.next.SaveChat(NewContext(ctx, g.namespace), chat)
+}
+
+func (g *chatGateway) UpdateChatTitle(ctx context.Context, chatID string, title string) error {
+	return g.next.UpdateChatTitle(NewContext(ctx, g.namespace), chatID, title)
+}
+
+func (g *chatGateway) AddChatTag(ctx context.Context, chatID string, tag string) error {
+	return g.next.AddChatTag(NewContext(ctx, g.namespace), chatID, tag)
+}
+
+func (g *chatGateway) RemoveChatTag(ctx context.Context, chatID string, tag string) error {
+	return g.next.RemoveChatTag(NewContext(ctx, g.namespace), chatID, tag)
+}
+
+func (g *chatGateway) SetMessageFlags(ctx context.Context, chatID string, messageID string, pinned bool, starred bool) error {
+	return g.next.SetMessageFlags(NewContext(ctx, g.namespace), chatID, messageID, pinned, starred)
+}
+
+func (g *chatGateway) DeleteChat(ctx context.Context, chatID string, deletedAt time.Time) error {
+	return g.next.DeleteChat(NewContext(ctx, g.namespace), chatID, deletedAt)
+}
+
+func (g *chatGateway) PurgeDeletedChats(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return g.next.PurgeDeletedChats(NewContext(ctx, g.namespace), deletedBefore)
+}
+
+func (g *chatGateway) FindExpiredChats(ctx context.Context, expiredBefore time.Time, limit int) ([]*entity.Chat, error) {
+	return g.next.FindExpiredChats(NewContext(ctx, g.namespace), expiredBefore, limit)
+}
+
+func (g *chatGateway) PurgeExpiredChats(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	return g.next.PurgeExpiredChats(NewContext(ctx, g.namespace), expiredBefore)
+}
@@ -0,0 +1,37 @@
+package namespace
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type idempotencyKeyGateway struct {
+	next      gateway.IdempotencyKeyGateway
+	namespace string
+}
+
+// NewIdempotencyKeyGateway scopes every call to next to the configured namespace.
+func NewIdempotencyKeyGateway(next gateway.IdempotencyKeyGateway, namespace string) gateway.IdempotencyKeyGateway {
+	return &idempotencyKeyGateway{
+		next:      next,
+		namespace: namespace,
+	}
+}
+
+func (g *idempotencyKeyGateway) CreateIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error {
+	return g.next.CreateIdempotencyKey(NewContext(ctx, g.namespace), key)
+}
+
+func (g *idempotencyKeyGateway) FindIdempotencyKey(ctx context.Context, userID, key string) (*entity.IdempotencyKey, error) {
+	return g.next.FindIdempotencyKey(NewContext(ctx, g.namespace), userID, key)
+}
+
+func (g *idempotencyKeyGateway) CompleteIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error {
+	return g.next.CompleteIdempotencyKey(NewContext(ctx, g.namespace), key)
+}
+
+func (g *idempotencyKeyGateway) DeleteIdempotencyKey(ctx context.Context, userID, key string) error {
+	return g.next.DeleteIdempotencyKey(NewContext(ctx, g.namespace), userID, key)
+}
@@ -0,0 +1,31 @@
+package namespace
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+type contextKey struct{}
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+func Validate(namespace string) error {
+	if !validName.MatchString(namespace) {
+		return errors.New("invalid namespace: use up to 63 lowercase letters, digits, '-' or '_'")
+	}
+	return nil
+}
+
+func NewContext(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, contextKey{}, namespace)
+}
+
+// FromContext returns the namespace every gateway query must be scoped to.
+func FromContext(ctx context.Context) (string, error) {
+	namespace, ok := ctx.Value(contextKey{}).(string)
+	if !ok || namespace == "" {
+		return "", errors.New("namespace missing from context")
+	}
+	return namespace, nil
+}
@@ -0,0 +1,24 @@
+package namespace
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type searchGateway struct {
+	next      gateway.SearchGateway
+	namespace string
+}
+
+// NewSearchGateway scopes every call to next to the configured namespace.
+func NewSearchGateway(next gateway.SearchGateway, namespace string) gateway.SearchGateway {
+	return &searchGateway{
+		next:      next,
+		namespace: namespace,
+	}
+}
+
+func (g *searchGateway) SearchMessages(ctx context.Context, query gateway.MessageSearchQuery) ([]*gateway.MessageSearchHit, error) {
+	return g.next.SearchMessages(NewContext(ctx, g.namespace), query)
+}
@@ -0,0 +1,29 @@
+package namespace
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type usageGateway struct {
+	next      gateway.UsageGateway
+	namespace string
+}
+
+// NewUsageGateway scopes every call to next to the configured namespace.
+func NewUsageGateway(next gateway.UsageGateway, namespace string) gateway.UsageGateway {
+	return &usageGateway{
+		next:      next,
+		namespace: namespace,
+	}
+}
+
+func (g *usageGateway) RecordUsage(ctx context.Context, usage *entity.UsageRecord) error {
+	return g.next.RecordUsage(NewContext(ctx, g.namespace), usage)
+}
+
+func (g *usageGateway) ListUsage(ctx context.Context, filter gateway.UsageFilter) ([]*entity.UsageRecord, error) {
+	return g.next.ListUsage(NewContext(ctx, g.namespace), filter)
+}
@@ -0,0 +1,25 @@
+package namespace
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type userDataGateway struct {
+	next      gateway.UserDataGateway
+	namespace string
+}
+
+// NewUserDataGateway scopes every call to next to the configured namespace.
+func NewUserDataGateway(next gateway.UserDataGateway, namespace string) gateway.UserDataGateway {
+	return &userDataGateway{
+		next:      next,
+		namespace: namespace,
+	}
+}
+
+func (g *userDataGateway) PurgeUserData(ctx context.Context, erasure *entity.UserErasure) (*gateway.UserDataPurge, error) {
+	return g.next.PurgeUserData(NewContext(ctx, g.namespace), erasure)
+}
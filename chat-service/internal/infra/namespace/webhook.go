@@ -0,0 +1,54 @@
+package namespace
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type webhookGateway struct {
+	next      gateway.WebhookGateway
+	namespace string
+}
+
+// NewWebhookGateway scopes every call to next to the configured namespace.
+func NewWebhookGateway(next gateway.WebhookGateway, namespace string) gateway.WebhookGateway {
+	return &webhookGateway{
+		next:      next,
+		namespace: namespace,
+	}
+}
+
+func (g *webhookGateway) CreateWebhook(ctx context.Context, webhook *entity.Webhook) error {
+	return g.next.CreateWebhook(NewContext(ctx, g.namespace), webhook)
+}
+
+func (g *webhookGateway) FindWebhookByID(ctx context.Context, id string) (*entity.Webhook, error) {
+	return g.next.FindWebhookByID(NewContext(ctx, g.namespace), id)
+}
+
+func (g *webhookGateway) ListWebhooksByOrg(ctx context.Context, orgID string) ([]*entity.Webhook, error) {
+	return g.next.ListWebhooksByOrg(NewContext(ctx, g.namespace), orgID)
+}
+
+func (g *webhookGateway) DeleteWebhook(ctx context.Context, id string) error {
+	return g.next.DeleteWebhook(NewContext(ctx, g.namespace), id)
+}
+
+func (g *webhookGateway) CreateWebhookDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	return g.next.CreateWebhookDeliveries(NewContext(ctx, g.namespace), deliveries)
+}
+
+func (g *webhookGateway) FindDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error) {
+	return g.next.FindDueWebhookDeliveries(NewContext(ctx, g.namespace), now, limit)
+}
+
+func (g *webhookGateway) SaveWebhookDelivery(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	return g.next.SaveWebhookDelivery(NewContext(ctx, g.namespace), delivery)
+}
+
+func (g *webhookGateway) ListDeadWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]*entity.WebhookDelivery, error) {
+	return g.next.ListDeadWebhookDeliveries(NewContext(ctx, g.namespace), webhookID, limit)
+}
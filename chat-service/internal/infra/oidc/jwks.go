@@ -0,0 +1,228 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+// KeySet is the signing keys the issuer publishes at the jwks_uri of its
+// discovery document, cached TTL. A key it doesn't know refreshes them, at
+// most once every MinRefresh, for the keys the issuer rotated in. The keys
+// are fetched outside of the lock, the callers asking in the meantime wait
+// for the same fetch.
+type KeySet struct {
+	Issuer     string
+	Client     *http.Client
+	Clock      clock.Clock
+	TTL        time.Duration
+	MinRefresh time.Duration
+
+	mu         sync.Mutex
+	jwksURI    string
+	keys       map[string]crypto.PublicKey
+	fetchedAt  time.Time
+	refreshing *refresh
+}
+
+// refresh is a fetch of the keys in flight, err is set once done is closed.
+type refresh struct {
+	done chan struct{}
+	err  error
+}
+
+func NewKeySet(issuer string, client *http.Client, clk clock.Clock) *KeySet {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &KeySet{
+		Issuer:     issuer,
+		Client:     client,
+		Clock:      clk,
+		TTL:        time.Hour,
+		MinRefresh: time.Minute,
+	}
+}
+
+// Key returns the key of kid, the only key of the set when kid is empty.
+func (s *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	age := s.Clock.Since(s.fetchedAt)
+	key, known := s.find(kid)
+	if s.keys != nil && age < s.TTL && (known || age < s.MinRefresh) {
+		s.mu.Unlock()
+		if !known {
+			return nil, invalid("unknown signing key " + kid)
+		}
+		return key, nil
+	}
+	r := s.refreshing
+	if r == nil {
+		r = &refresh{done: make(chan struct{})}
+		s.refreshing = r
+		jwksURI := s.jwksURI
+		s.mu.Unlock()
+		keys, jwksURI, err := s.fetch(ctx, jwksURI)
+		s.mu.Lock()
+		s.refreshing = nil
+		if err == nil {
+			s.jwksURI, s.keys, s.fetchedAt = jwksURI, keys, s.Clock.Now()
+		} else if s.keys != nil {
+			// the keys fetched last keep verifying while the issuer is down,
+			// it is tried again in MinRefresh
+			s.fetchedAt = s.Clock.Now().Add(s.MinRefresh - s.TTL)
+		}
+		r.err = err
+		close(r.done)
+	} else {
+		s.mu.Unlock()
+		if known {
+			// the key outlived the TTL, it verifies until the fetch is done
+			return key, nil
+		}
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		s.mu.Lock()
+	}
+	key, known = s.find(kid)
+	s.mu.Unlock()
+	if known {
+		return key, nil
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return nil, invalid("unknown signing key " + kid)
+}
+
+func (s *KeySet) find(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+type discovery struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetch fetches the keys published at jwksURI, found in the discovery
+// document when empty, and returns them with it.
+func (s *KeySet) fetch(ctx context.Context, jwksURI string) (map[string]crypto.PublicKey, string, error) {
+	if jwksURI == "" {
+		var doc discovery
+		if err := s.get(ctx, s.Issuer+"/.well-known/openid-configuration", &doc); err != nil {
+			return nil, "", fmt.Errorf("error fetching the discovery document of %s: %s", s.Issuer, err.Error())
+		}
+		if doc.JWKSURI == "" {
+			return nil, "", fmt.Errorf("discovery document of %s has no jwks_uri", s.Issuer)
+		}
+		jwksURI = doc.JWKSURI
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := s.get(ctx, jwksURI, &set); err != nil {
+		return nil, "", fmt.Errorf("error fetching the keys of %s: %s", s.Issuer, err.Error())
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			// keys of other types don't keep the others from verifying
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, jwksURI, nil
+}
+
+func (s *KeySet) get(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
@@ -0,0 +1,237 @@
+// Package oidc verifies the JWT bearer tokens of an OpenID Connect issuer,
+// with the signing keys the issuer publishes.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+// Claims are what a verified token says of its caller.
+type Claims struct {
+	Subject   string
+	OrgID     string
+	Tier      string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+// TokenError is the error of a token that isn't valid, as opposed to the
+// errors of fetching the keys of the issuer.
+type TokenError struct {
+	Reason string
+}
+
+func (e *TokenError) Error() string {
+	return "invalid token: " + e.Reason
+}
+
+func invalid(reason string) error {
+	return &TokenError{Reason: reason}
+}
+
+type Verifier struct {
+	Issuer string
+	// Audience, when set, must be one of the audiences of the tokens.
+	Audience string
+	// RequiredScope, when set, must be one of the scopes of the tokens.
+	RequiredScope string
+	// OrgClaim is the claim of the tenant of the caller, TierClaim of the
+	// tier of its plan, if any.
+	OrgClaim  string
+	TierClaim string
+	// Leeway is the clock skew allowed with the issuer.
+	Leeway time.Duration
+	Clock  clock.Clock
+	Keys   *KeySet
+}
+
+func NewVerifier(issuer, audience string, client *http.Client, clk clock.Clock) *Verifier {
+	issuer = strings.TrimSuffix(issuer, "/")
+	return &Verifier{
+		Issuer:   issuer,
+		Audience: audience,
+		Leeway:   time.Minute,
+		Clock:    clk,
+		Keys:     NewKeySet(issuer, client, clk),
+	}
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type payload struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	// Scope is the space separated scopes of the OAuth 2 access tokens.
+	// Some issuers send them as an scp array instead.
+	Scope string   `json:"scope"`
+	Scp   []string `json:"scp"`
+}
+
+// audience is the aud claim, a string or an array of them.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Verify checks the signature of token with the keys of the issuer, then its
+// issuer, audience, lifetime and scope. A token that isn't valid fails with
+// a TokenError.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalid("malformed token")
+	}
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, invalid("malformed header")
+	}
+	hash, ok := algHashes[h.Alg]
+	if !ok {
+		return nil, invalid("unsupported algorithm " + h.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalid("malformed signature")
+	}
+	key, err := v.Keys.Key(ctx, h.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if !verifySignature(h.Alg, hash, key, parts[0]+"."+parts[1], signature) {
+		return nil, invalid("bad signature")
+	}
+	var p payload
+	if err := decodeSegment(parts[1], &p); err != nil {
+		return nil, invalid("malformed payload")
+	}
+	if err := v.check(p); err != nil {
+		return nil, err
+	}
+	claims := &Claims{
+		Subject:   p.Subject,
+		Scopes:    p.Scp,
+		ExpiresAt: unixTime(*p.ExpiresAt),
+	}
+	if p.Scope != "" {
+		claims.Scopes = strings.Fields(p.Scope)
+	}
+	if v.OrgClaim != "" || v.TierClaim != "" {
+		var all map[string]interface{}
+		if err := decodeSegment(parts[1], &all); err != nil {
+			return nil, invalid("malformed payload")
+		}
+		claims.OrgID, _ = all[v.OrgClaim].(string)
+		claims.Tier, _ = all[v.TierClaim].(string)
+	}
+	if v.RequiredScope != "" && !contains(claims.Scopes, v.RequiredScope) {
+		return nil, invalid("missing scope " + v.RequiredScope)
+	}
+	return claims, nil
+}
+
+func (v *Verifier) check(p payload) error {
+	now := v.Clock.Now()
+	switch {
+	case strings.TrimSuffix(p.Issuer, "/") != v.Issuer:
+		return invalid("unexpected issuer")
+	case v.Audience != "" && !contains(p.Audience, v.Audience):
+		return invalid("unexpected audience")
+	case p.Subject == "":
+		return invalid("missing subject")
+	case p.ExpiresAt == nil:
+		return invalid("missing expiry")
+	case now.After(unixTime(*p.ExpiresAt).Add(v.Leeway)):
+		return invalid("token is expired")
+	case p.NotBefore != nil && now.Add(v.Leeway).Before(unixTime(*p.NotBefore)):
+		return invalid("token is not valid yet")
+	}
+	return nil
+}
+
+// algHashes are the hashes of the algorithms accepted, asymmetric ones only:
+// the keys of the issuer are public.
+var algHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"PS256": crypto.SHA256,
+	"PS384": crypto.SHA384,
+	"PS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+func verifySignature(alg string, hash crypto.Hash, key crypto.PublicKey, signed string, signature []byte) bool {
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil
+		case "PS":
+			return rsa.VerifyPSS(key, hash, digest, signature, nil) == nil
+		}
+	case *ecdsa.PublicKey:
+		// the signature is r and s, each the size of the curve
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if alg[:2] != "ES" || len(signature) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(key, digest, r, s)
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package prometheus
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/dispatcher"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/middleware"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/web"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/enforceretention"
+)
+
+// Completions collects the metrics of the generations, by model and tenant.
+func Completions(metrics *chatcompletionstream.Metrics) Collector {
+	return CollectorFunc(func() []Family {
+		snapshot := metrics.Snapshot()
+		keys := make([]chatcompletionstream.GenerationLabels, 0, len(snapshot.Generations))
+		for key := range snapshot.Generations {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].Model != keys[j].Model {
+				return keys[i].Model < keys[j].Model
+			}
+			return keys[i].Tenant < keys[j].Tenant
+		})
+		started := Family{Name: "chat_completions_started_total", Help: "Generations started.", Type: Counter}
+		succeeded := Family{Name: "chat_completions_succeeded_total", Help: "Generations answered.", Type: Counter}
+		failed := Family{Name: "chat_completions_failed_total", Help: "Generations failed.", Type: Counter}
+		promptTokens := Family{Name: "chat_completion_prompt_tokens_total", Help: "Prompt tokens of the answered generations.", Type: Counter}
+		completionTokens := Family{Name: "chat_completion_completion_tokens_total", Help: "Completion tokens of the answered generations.", Type: Counter}
+		firstToken := Family{Name: "chat_completion_time_to_first_token_seconds", Help: "Time from the provider call to the first token.", Type: Histogram}
+		duration := Family{Name: "chat_completion_stream_duration_seconds", Help: "Time from the provider call to the end of its stream.", Type: Histogram}
+		providerErrors := Family{Name: "chat_completion_provider_errors_total", Help: "Failed provider calls, by provider error code.", Type: Counter}
+		slow := Family{Name: "chat_completion_slow_generations_total", Help: "Generations flagged slow by the watchdog, by phase.", Type: Counter}
+		for _, key := range keys {
+			g := snapshot.Generations[key]
+			labels := []Label{{Name: "model", Value: key.Model}, {Name: "tenant", Value: key.Tenant}}
+			started.Samples = append(started.Samples, Sample{Labels: labels, Value: float64(g.Started)})
+			succeeded.Samples = append(succeeded.Samples, Sample{Labels: labels, Value: float64(g.Succeeded)})
+			failed.Samples = append(failed.Samples, Sample{Labels: labels, Value: float64(g.Failed)})
+			promptTokens.Samples = append(promptTokens.Samples, Sample{Labels: labels, Value: float64(g.PromptTokens)})
+			completionTokens.Samples = append(completionTokens.Samples, Sample{Labels: labels, Value: float64(g.CompletionTokens)})
+			h := g.TimeToFirstToken
+			firstToken.Samples = append(firstToken.Samples, HistogramSamples(labels, h.Bounds, h.Counts, h.Sum, h.Count)...)
+			h = g.StreamDuration
+			duration.Samples = append(duration.Samples, HistogramSamples(labels, h.Bounds, h.Counts, h.Sum, h.Count)...)
+			for _, code := range sortedKeys(g.ProviderErrors) {
+				providerErrors.Samples = append(providerErrors.Samples, Sample{
+					Labels: withLabel(labels, "code", code),
+					Value:  float64(g.ProviderErrors[code]),
+				})
+			}
+			for _, phase := range sortedPhases(g.SlowGenerations) {
+				slow.Samples = append(slow.Samples, Sample{
+					Labels: withLabel(labels, "phase", string(phase)),
+					Value:  float64(g.SlowGenerations[phase]),
+				})
+			}
+		}
+		timeouts := Family{Name: "chat_completion_timeouts_total", Help: "Generations timed out, by phase.", Type: Counter}
+		for _, phase := range sortedPhases(snapshot.Timeouts) {
+			timeouts.Samples = append(timeouts.Samples, Sample{
+				Labels: []Label{{Name: "phase", Value: string(phase)}},
+				Value:  float64(snapshot.Timeouts[phase]),
+			})
+		}
+		return []Family{started, succeeded, failed, promptTokens, completionTokens, firstToken, duration, providerErrors, slow, timeouts}
+	})
+}
+
+// Dispatcher collects the slots taken and the depth of the queues of d.
+func Dispatcher(d *dispatcher.Dispatcher) Collector {
+	return CollectorFunc(func() []Family {
+		stats := d.Stats()
+		depth := Family{Name: "chat_completion_queue_depth", Help: "Requests waiting for a completion slot, by priority and tier.", Type: Gauge}
+		for _, priority := range []dispatcher.Priority{dispatcher.PriorityInteractive, dispatcher.PriorityBatch} {
+			for _, tier := range sortedKeys(stats.Queued[priority]) {
+				depth.Samples = append(depth.Samples, Sample{
+					Labels: []Label{{Name: "priority", Value: string(priority)}, {Name: "tier", Value: tier}},
+					Value:  float64(stats.Queued[priority][tier]),
+				})
+			}
+		}
+		return []Family{
+			depth,
+			{Name: "chat_completion_slots_in_flight", Help: "Completion slots taken.", Type: Gauge, Samples: []Sample{{Value: float64(stats.InFlight)}}},
+			{Name: "chat_completion_batch_slots_in_flight", Help: "Completion slots taken by batch requests.", Type: Gauge, Samples: []Sample{{Value: float64(stats.BatchInFlight)}}},
+		}
+	})
+}
+
+// Calls collects the calls counted by the metrics middleware, by transport,
+// method and status.
+func Calls(metrics *middleware.Metrics) Collector {
+	return CollectorFunc(func() []Family {
+		snapshot := metrics.Snapshot()
+		calls := Family{Name: "chat_calls_total", Help: "Calls served, by transport, method and status.", Type: Counter}
+		for _, key := range sortedKeys(snapshot) {
+			// the key is the transport then the method
+			transport, method, _ := strings.Cut(key, " ")
+			for _, status := range sortedKeys(snapshot[key]) {
+				calls.Samples = append(calls.Samples, Sample{
+					Labels: []Label{{Name: "transport", Value: transport}, {Name: "method", Value: method}, {Name: "status", Value: status}},
+					Value:  float64(snapshot[key][status]),
+				})
+			}
+		}
+		return []Family{calls}
+	})
+}
+
+// APIVersions collects the responses of the HTTP API by version and status
+// class, to see who still calls a deprecated version.
+func APIVersions(metrics *web.VersionMetrics) Collector {
+	return CollectorFunc(func() []Family {
+		snapshot := metrics.Snapshot()
+		responses := Family{Name: "chat_http_responses_total", Help: "HTTP API responses, by version and status class.", Type: Counter}
+		for _, version := range sortedKeys(snapshot) {
+			for _, class := range sortedKeys(snapshot[version]) {
+				responses.Samples = append(responses.Samples, Sample{
+					Labels: []Label{{Name: "version", Value: version}, {Name: "class", Value: class}},
+					Value:  float64(snapshot[version][class]),
+				})
+			}
+		}
+		return []Family{responses}
+	})
+}
+
+// Retention collects what the retention job purged, by tenant, and what its
+// sweeps of the expired and soft-deleted chats did.
+func Retention(metrics *enforceretention.Metrics) Collector {
+	return CollectorFunc(func() []Family {
+		snapshot := metrics.Snapshot()
+		runs := Family{Name: "chat_retention_runs_total", Help: "Runs of the retention policies, by tenant.", Type: Counter}
+		failures := Family{Name: "chat_retention_failures_total", Help: "Failed runs of the retention policies, by tenant.", Type: Counter}
+		matched := Family{Name: "chat_retention_chats_matched_total", Help: "Idle chats found, dry runs included, by tenant.", Type: Counter}
+		purged := Family{Name: "chat_retention_chats_purged_total", Help: "Idle chats purged, by tenant.", Type: Counter}
+		filesDeleted := Family{Name: "chat_retention_files_deleted_total", Help: "Attachment files deleted with the idle chats, by tenant.", Type: Counter}
+		filesFailed := Family{Name: "chat_retention_files_failed_total", Help: "Attachment files that could not be deleted, by tenant.", Type: Counter}
+		idle := Family{Name: "chat_retention_idle_chats", Help: "Idle chats found by the last run, by tenant.", Type: Gauge}
+		for _, orgID := range sortedKeys(snapshot.Tenants) {
+			n := snapshot.Tenants[orgID]
+			labels := []Label{{Name: "tenant", Value: orgID}}
+			runs.Samples = append(runs.Samples, Sample{Labels: labels, Value: float64(n.Runs)})
+			failures.Samples = append(failures.Samples, Sample{Labels: labels, Value: float64(n.Failures)})
+			matched.Samples = append(matched.Samples, Sample{Labels: labels, Value: float64(n.Matched)})
+			purged.Samples = append(purged.Samples, Sample{Labels: labels, Value: float64(n.Purged)})
+			filesDeleted.Samples = append(filesDeleted.Samples, Sample{Labels: labels, Value: float64(n.FilesDeleted)})
+			filesFailed.Samples = append(filesFailed.Samples, Sample{Labels: labels, Value: float64(n.FailedFiles)})
+			idle.Samples = append(idle.Samples, Sample{Labels: labels, Value: float64(n.LastMatched)})
+		}
+		sweeps := snapshot.Sweeps
+		return []Family{
+			runs, failures, matched, purged, filesDeleted, filesFailed, idle,
+			{Name: "chat_retention_sweeps_total", Help: "Sweeps of the expired and soft-deleted chats.", Type: Counter, Samples: []Sample{{Value: float64(sweeps.Runs)}}},
+			{Name: "chat_retention_sweep_failures_total", Help: "Failed sweeps of the expired and soft-deleted chats.", Type: Counter, Samples: []Sample{{Value: float64(sweeps.Failures)}}},
+			{Name: "chat_retention_expired_chats_archived_total", Help: "Ephemeral chats archived once expired.", Type: Counter, Samples: []Sample{{Value: float64(sweeps.ExpiredArchived)}}},
+			{Name: "chat_retention_expired_chats_purged_total", Help: "Expired ephemeral chats purged.", Type: Counter, Samples: []Sample{{Value: float64(sweeps.ExpiredPurged)}}},
+			{Name: "chat_retention_deleted_chats_purged_total", Help: "Soft-deleted chats purged.", Type: Counter, Samples: []Sample{{Value: float64(sweeps.DeletedPurged)}}},
+		}
+	})
+}
+
+func sortedPhases(m map[chatcompletionstream.TimeoutPhase]int64) []chatcompletionstream.TimeoutPhase {
+	phases := make([]chatcompletionstream.TimeoutPhase, 0, len(m))
+	for phase := range m {
+		phases = append(phases, phase)
+	}
+	sort.Slice(phases, func(i, j int) bool { return phases[i] < phases[j] })
+	return phases
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,157 @@
+// Package prometheus serves the metrics of the service in the Prometheus
+// text exposition format, for a Prometheus server to scrape.
+package prometheus
+
+import (
+	"bufio"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Type is the Prometheus type of a metric family.
+type Type string
+
+const (
+	Counter   Type = "counter"
+	Gauge     Type = "gauge"
+	Histogram Type = "histogram"
+)
+
+// Label is a label of a sample.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a value of a metric family, Suffix is appended to the name of
+// the family, _bucket, _sum or _count for a histogram.
+type Sample struct {
+	Suffix string
+	Labels []Label
+	Value  float64
+}
+
+// Family is a metric and its samples, one per combination of labels.
+type Family struct {
+	Name    string
+	Help    string
+	Type    Type
+	Samples []Sample
+}
+
+// HistogramSamples are the samples of a histogram with labels: counts are
+// the observations per bucket up to each of bounds, not cumulated, count all
+// of them.
+func HistogramSamples(labels []Label, bounds []float64, counts []int64, sum float64, count int64) []Sample {
+	samples := make([]Sample, 0, len(bounds)+3)
+	var cumulated int64
+	for i, bound := range bounds {
+		cumulated += counts[i]
+		samples = append(samples, Sample{
+			Suffix: "_bucket",
+			Labels: withLabel(labels, "le", formatFloat(bound)),
+			Value:  float64(cumulated),
+		})
+	}
+	samples = append(samples,
+		Sample{Suffix: "_bucket", Labels: withLabel(labels, "le", "+Inf"), Value: float64(count)},
+		Sample{Suffix: "_sum", Labels: labels, Value: sum},
+		Sample{Suffix: "_count", Labels: labels, Value: float64(count)},
+	)
+	return samples
+}
+
+func withLabel(labels []Label, name, value string) []Label {
+	return append(append([]Label(nil), labels...), Label{Name: name, Value: value})
+}
+
+// Collector collects metric families when they are scraped.
+type Collector interface {
+	Collect() []Family
+}
+
+// CollectorFunc adapts a func to a Collector.
+type CollectorFunc func() []Family
+
+func (f CollectorFunc) Collect() []Family {
+	return f()
+}
+
+// Registry holds the collectors served.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Register(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// Gather collects the families of every collector, sorted by name.
+func (r *Registry) Gather() []Family {
+	r.mu.Lock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+	var families []Family
+	for _, c := range collectors {
+		families = append(families, c.Collect()...)
+	}
+	sort.SliceStable(families, func(i, j int) bool {
+		return families[i].Name < families[j].Name
+	})
+	return families
+}
+
+// Handler serves the families of r in the text exposition format.
+func Handler(r *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		buf := bufio.NewWriter(w)
+		for _, family := range r.Gather() {
+			writeFamily(buf, family)
+		}
+		buf.Flush()
+	})
+}
+
+func writeFamily(w *bufio.Writer, family Family) {
+	if family.Help != "" {
+		w.WriteString("# HELP " + family.Name + " " + escapeHelp(family.Help) + "\n")
+	}
+	w.WriteString("# TYPE " + family.Name + " " + string(family.Type) + "\n")
+	for _, sample := range family.Samples {
+		w.WriteString(family.Name + sample.Suffix)
+		if len(sample.Labels) > 0 {
+			w.WriteByte('{')
+			for i, label := range sample.Labels {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				w.WriteString(label.Name + `="` + escapeLabel(label.Value) + `"`)
+			}
+			w.WriteByte('}')
+		}
+		w.WriteString(" " + formatFloat(sample.Value) + "\n")
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
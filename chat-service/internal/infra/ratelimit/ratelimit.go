@@ -0,0 +1,143 @@
+// Package ratelimit limits the completions of each user or tenant with token
+// buckets, kept in memory for a single instance or in Redis when several
+// share the limit.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+// Bucket lets Burst requests through at once, then PerSecond on average.
+type Bucket struct {
+	PerSecond float64
+	Burst     int
+}
+
+// refill is how long an empty bucket takes to be full again.
+func (b Bucket) refill() time.Duration {
+	return time.Duration(float64(b.Burst) / b.PerSecond * float64(time.Second))
+}
+
+// Store keeps the buckets by key. Take takes a token from the bucket of key,
+// or returns how long until one is available without taking any.
+type Store interface {
+	Take(ctx context.Context, key string, bucket Bucket) (time.Duration, error)
+}
+
+// Error is the error of a rate limited request.
+type Error struct {
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rate limited, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+type Limiter struct {
+	Store  Store
+	Bucket Bucket
+	// MaxWait queues the requests a token is available for within it,
+	// instead of rejecting them.
+	MaxWait time.Duration
+	Clock   clock.Clock
+	// OnError is told of the errors of Store, the requests are let through
+	// then rather than failing with the limiter.
+	OnError func(err error)
+}
+
+func NewLimiter(store Store, bucket Bucket, clk clock.Clock) *Limiter {
+	return &Limiter{
+		Store:  store,
+		Bucket: bucket,
+		Clock:  clk,
+	}
+}
+
+// Take takes a token of key, waiting for it up to MaxWait. It returns an
+// Error when the request is rate limited. A nil Limiter limits nothing.
+func (l *Limiter) Take(ctx context.Context, key string) error {
+	if l == nil {
+		return nil
+	}
+	var waited time.Duration
+	for {
+		wait, err := l.Store.Take(ctx, key, l.Bucket)
+		if err != nil {
+			if l.OnError != nil {
+				l.OnError(fmt.Errorf("error taking a token of %s: %s", key, err.Error()))
+			}
+			return nil
+		}
+		if wait <= 0 {
+			return nil
+		}
+		if waited+wait > l.MaxWait {
+			return &Error{RetryAfter: wait}
+		}
+		timer := l.Clock.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+		// another request may have taken the token meanwhile
+		waited += wait
+	}
+}
+
+// MemoryStore keeps the buckets of a single instance.
+type MemoryStore struct {
+	clock   clock.Clock
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	takes   int
+}
+
+type memoryBucket struct {
+	tokens float64
+	at     time.Time
+}
+
+func NewMemoryStore(clk clock.Clock) *MemoryStore {
+	return &MemoryStore{
+		clock:   clk,
+		buckets: map[string]*memoryBucket{},
+	}
+}
+
+// sweepEvery is how many takes pass between sweeps of the full buckets.
+const sweepEvery = 1024
+
+func (s *MemoryStore) Take(ctx context.Context, key string, bucket Bucket) (time.Duration, error) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.takes++
+	if s.takes%sweepEvery == 0 {
+		// a bucket idle long enough to be full is the same as none
+		for k, b := range s.buckets {
+			if now.Sub(b.at) >= bucket.refill() {
+				delete(s.buckets, k)
+			}
+		}
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(bucket.Burst), at: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(bucket.Burst), b.tokens+now.Sub(b.at).Seconds()*bucket.PerSecond)
+	b.at = now
+	if b.tokens < 1 {
+		return time.Duration(math.Ceil((1 - b.tokens) / bucket.PerSecond * float64(time.Second))), nil
+	}
+	b.tokens--
+	return 0, nil
+}
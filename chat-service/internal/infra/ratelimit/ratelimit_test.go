@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+func TestLimiterResetsOnceTheBucketRefills(t *testing.T) {
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	// 3 requests a minute
+	bucket := Bucket{PerSecond: 3.0 / 60, Burst: 3}
+	limiter := NewLimiter(NewMemoryStore(clk), bucket, clk)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := limiter.Take(ctx, "org"); err != nil {
+			t.Fatalf("Take() %d within the burst: %v", i, err)
+		}
+	}
+	var limited *Error
+	if err := limiter.Take(ctx, "org"); !errors.As(err, &limited) {
+		t.Fatalf("Take() past the burst = %v, want a rate limit error", err)
+	}
+	if limited.RetryAfter != 20*time.Second {
+		t.Fatalf("RetryAfter = %s, want 20s", limited.RetryAfter)
+	}
+	if err := limiter.Take(ctx, "other-org"); err != nil {
+		t.Fatalf("Take() of another key: %v", err)
+	}
+
+	clk.Advance(time.Minute)
+	for i := 0; i < 3; i++ {
+		if err := limiter.Take(ctx, "org"); err != nil {
+			t.Fatalf("Take() %d once refilled: %v", i, err)
+		}
+	}
+}
+
+func TestLimiterWaitsForATokenWithinMaxWait(t *testing.T) {
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter := NewLimiter(NewMemoryStore(clk), Bucket{PerSecond: 1, Burst: 1}, clk)
+	limiter.MaxWait = 5 * time.Second
+	ctx := context.Background()
+	if err := limiter.Take(ctx, "user"); err != nil {
+		t.Fatalf("Take(): %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- limiter.Take(ctx, "user")
+	}()
+	// the second take waits on a timer of the clock for the next token
+	deadline := time.After(time.Second)
+	for {
+		clk.Advance(100 * time.Millisecond)
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Take() waiting for a token: %v", err)
+			}
+			return
+		case <-deadline:
+			t.Fatal("Take() didn't return once the token was available")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript refills and takes from the bucket of KEYS[1] atomically, on the
+// clock of Redis so every instance agrees. It returns the milliseconds until
+// a token is available, 0 when one was taken.
+var takeScript = redis.NewScript(`
+local per_second = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(state[1]) or burst
+local at = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + (now - at) * per_second / 1000)
+local wait = 0
+if tokens < 1 then
+  wait = math.ceil((1 - tokens) * 1000 / per_second)
+else
+  tokens = tokens - 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'at', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst * 1000 / per_second))
+return wait
+`)
+
+// RedisStore keeps the buckets in Redis, shared by the instances of a
+// namespace.
+type RedisStore struct {
+	client    *redis.Client
+	namespace string
+}
+
+func NewRedisStore(client *redis.Client, namespace string) *RedisStore {
+	return &RedisStore{client: client, namespace: namespace}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, bucket Bucket) (time.Duration, error) {
+	wait, err := takeScript.Run(ctx, s.client, []string{"ratelimit:" + s.namespace + ":" + key}, bucket.PerSecond, bucket.Burst).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
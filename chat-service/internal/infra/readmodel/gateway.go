@@ -0,0 +1,81 @@
+package readmodel
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type chatGateway struct {
+	gateway.ChatGateway
+	readModel gateway.ChatReadModel
+}
+
+// NewChatGateway serves ListChats and ListChatsByUser from readModel and the
+// rest from next. The listings are eventually consistent: a chat shows up, or
+// changes, once the projector got to it.
+func NewChatGateway(next gateway.ChatGateway, readModel gateway.ChatReadModel) gateway.ChatGateway {
+	return &chatGateway{
+		ChatGateway: next,
+		readModel:   readModel,
+	}
+}
+
+func (g *chatGateway) ListChats(ctx context.Context, filter gateway.ChatFilter) ([]*entity.Chat, error) {
+	if filter.SourceID != "" {
+		// the summaries don't keep where a chat was imported from
+		return g.ChatGateway.ListChats(ctx, filter)
+	}
+	summaries, err := g.readModel.ListChatSummaries(ctx, gateway.ChatSummaryFilter{
+		UserID:        filter.UserID,
+		Tag:           filter.Tag,
+		Status:        filter.Status,
+		CreatedAfter:  filter.CreatedAfter,
+		CreatedBefore: filter.CreatedBefore,
+		Limit:         filter.Limit,
+		Offset:        filter.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toChats(summaries), nil
+}
+
+func (g *chatGateway) ListChatsByUser(ctx context.Context, userID string, page int, size int) (*gateway.ChatPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	filter := gateway.ChatSummaryFilter{UserID: userID}
+	total, err := g.readModel.CountChatSummaries(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	filter.Limit, filter.Offset = size, (page-1)*size
+	summaries, err := g.readModel.ListChatSummaries(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return &gateway.ChatPage{Chats: toChats(summaries), Total: total}, nil
+}
+
+// toChats returns the chats of summaries without their messages, like the
+// listings of the write store.
+func toChats(summaries []*gateway.ChatSummary) []*entity.Chat {
+	chats := make([]*entity.Chat, 0, len(summaries))
+	for _, s := range summaries {
+		chats = append(chats, &entity.Chat{
+			ID:           s.ChatID,
+			UserID:       s.UserID,
+			Title:        s.Title,
+			ParentChatID: s.ParentChatID,
+			Status:       s.Status,
+			TokenUsage:   s.TokenUsage,
+			Tags:         s.Tags,
+			CreatedAt:    s.CreatedAt,
+			UpdatedAt:    s.UpdatedAt,
+			Version:      s.Version,
+		})
+	}
+	return chats
+}
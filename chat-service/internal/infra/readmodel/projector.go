@@ -0,0 +1,117 @@
+package readmodel
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/eventbus"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/projectchats"
+)
+
+type chatRef struct {
+	namespace string
+	chatID    string
+}
+
+// Projector keeps the read model up to date from the chat events of the bus.
+// Handlers only queue the chat, Run projects it in the background so the
+// write path never waits on the read store. A chat changed several times
+// before Run gets to it is projected once. The eventbus gateway must be
+// wrapped by the namespace one, handlers take the namespace from the context.
+type Projector struct {
+	project *projectchats.ProjectChatsUseCase
+	onError func(err error)
+	mu      sync.Mutex
+	pending map[chatRef]bool
+	wake    chan struct{}
+}
+
+func NewProjector(project *projectchats.ProjectChatsUseCase, onError func(err error)) *Projector {
+	if onError == nil {
+		onError = func(err error) {}
+	}
+	return &Projector{
+		project: project,
+		onError: onError,
+		pending: make(map[chatRef]bool),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Subscribe queues the chats of every event published on bus.
+func (p *Projector) Subscribe(bus *eventbus.Bus) {
+	bus.SubscribeAll(p.handle)
+}
+
+func (p *Projector) handle(ctx context.Context, event entity.DomainEvent) {
+	chatID := chatIDOf(event)
+	if chatID == "" {
+		return
+	}
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		p.onError(errors.New("error queueing projection of chat " + chatID + ": " + err.Error()))
+		return
+	}
+	p.mu.Lock()
+	p.pending[chatRef{namespace: ns, chatID: chatID}] = true
+	p.mu.Unlock()
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func chatIDOf(event entity.DomainEvent) string {
+	switch e := event.(type) {
+	case entity.ChatCreated:
+		return e.ChatID
+	case entity.MessageAdded:
+		return e.ChatID
+	case entity.ChatEnded:
+		return e.ChatID
+	case entity.ChatArchived:
+		return e.ChatID
+	case entity.ChatUpdated:
+		return e.ChatID
+	case entity.ChatDeleted:
+		return e.ChatID
+	}
+	return ""
+}
+
+// Run projects the queued chats until ctx is done. A failed projection is
+// reported through onError and queued again for the next event, the rebuild
+// repairs what stays behind.
+func (p *Projector) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		}
+		p.mu.Lock()
+		batch := make(map[string][]string)
+		for ref := range p.pending {
+			batch[ref.namespace] = append(batch[ref.namespace], ref.chatID)
+		}
+		p.pending = make(map[chatRef]bool)
+		p.mu.Unlock()
+		for ns, chatIDs := range batch {
+			projected, err := p.project.Execute(namespace.NewContext(ctx, ns), projectchats.ProjectChatsInputDTO{ChatIDs: chatIDs})
+			if err == nil {
+				continue
+			}
+			p.onError(err)
+			done := projected.Projected + projected.Removed
+			p.mu.Lock()
+			for _, chatID := range chatIDs[done:] {
+				p.pending[chatRef{namespace: ns, chatID: chatID}] = true
+			}
+			p.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package redact
+
+import (
+	"regexp"
+	"sort"
+)
+
+var piiRules = []rule{
+	{"email", regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{"ssn", regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{"iban", regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`)},
+	{"card number", regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)},
+	{"ip address", regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)},
+	{"phone number", regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?\(?\b\d{2,4}\)?[ .-]?\d{3,5}[ .-]?\d{4}\b`)},
+}
+
+// PII replaces personal data in text, emails, card, social security and
+// bank account numbers, phone numbers and IP addresses, with a placeholder
+// and returns the kinds found, sorted and without duplicates. The rules run
+// in order, so bank account numbers aren't taken for card numbers, nor card
+// numbers and IP addresses for phone numbers.
+func PII(text string) (string, []string) {
+	found := map[string]bool{}
+	for _, r := range piiRules {
+		text = r.pattern.ReplaceAllStringFunc(text, func(string) string {
+			found[r.kind] = true
+			return "[REDACTED " + r.kind + "]"
+		})
+	}
+	kinds := make([]string, 0, len(found))
+	for kind := range found {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return text, kinds
+}
@@ -0,0 +1,40 @@
+package redact
+
+import (
+	"regexp"
+	"sort"
+)
+
+type rule struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+var secretRules = []rule{
+	{"private key", regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{10,}`)},
+	{"openai api key", regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{20,}`)},
+	{"aws access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"google api key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"stripe key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}\b`)},
+}
+
+// Secrets replaces well known credential formats in text with a placeholder and
+// returns the kinds found, sorted and without duplicates.
+func Secrets(text string) (string, []string) {
+	found := map[string]bool{}
+	for _, r := range secretRules {
+		text = r.pattern.ReplaceAllStringFunc(text, func(string) string {
+			found[r.kind] = true
+			return "[REDACTED " + r.kind + "]"
+		})
+	}
+	kinds := make([]string, 0, len(found))
+	for kind := range found {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return text, kinds
+}
@@ -0,0 +1,94 @@
+package dynamodb
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// attributeValue is a DynamoDB attribute in the JSON API format, only the
+// types the repositories store are there.
+type attributeValue struct {
+	S  *string                   `json:"S,omitempty"`
+	N  *string                   `json:"N,omitempty"`
+	SS []string                  `json:"SS,omitempty"`
+	M  map[string]attributeValue `json:"M,omitempty"`
+}
+
+type item map[string]attributeValue
+
+func str(s string) attributeValue {
+	return attributeValue{S: &s}
+}
+
+func num(n int) attributeValue {
+	s := strconv.Itoa(n)
+	return attributeValue{N: &s}
+}
+
+// strSet is a string set, DynamoDB has no empty sets so values must not be
+// empty.
+func strSet(values ...string) attributeValue {
+	return attributeValue{SS: values}
+}
+
+func strMap(values map[string]string) attributeValue {
+	m := make(map[string]attributeValue, len(values))
+	for k, v := range values {
+		m[k] = str(v)
+	}
+	return attributeValue{M: m}
+}
+
+// timeLayout is fixed width so timestamps sort as strings, the index sort
+// keys rely on it.
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+func timeAttr(t time.Time) attributeValue {
+	return str(formatTime(t))
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func (it item) str(name string) string {
+	if v, ok := it[name]; ok && v.S != nil {
+		return *v.S
+	}
+	return ""
+}
+
+func (it item) num(name string) int {
+	if v, ok := it[name]; ok && v.N != nil {
+		n, _ := strconv.Atoi(*v.N)
+		return n
+	}
+	return 0
+}
+
+// strings returns a string set sorted, sets have no order.
+func (it item) strings(name string) []string {
+	values := append([]string{}, it[name].SS...)
+	sort.Strings(values)
+	return values
+}
+
+func (it item) strMap(name string) map[string]string {
+	values := make(map[string]string, len(it[name].M))
+	for k, v := range it[name].M {
+		if v.S != nil {
+			values[k] = *v.S
+		}
+	}
+	return values
+}
+
+// time returns the zero time for missing and invalid attributes.
+func (it item) time(name string) time.Time {
+	t, err := time.Parse(timeLayout, it.str(name))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
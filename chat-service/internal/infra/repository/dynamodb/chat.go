@@ -0,0 +1,798 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+// maxFlagAttempts bounds the retries of SetMessageFlags when the message is
+// written concurrently.
+const maxFlagAttempts = 3
+
+// ChatRepository is the DynamoDB ChatGateway. Every write of a chat is a
+// transaction conditioned on the version of the chat item, which also carries
+// a digest of each message: SaveChat only rewrites the message items whose
+// digest changed, so a turn writes a handful of items however long the chat.
+//
+// DynamoDB has no transactions spanning calls, the UnitOfWork of the other
+// databases has no DynamoDB counterpart.
+type ChatRepository struct {
+	client *Client
+	cipher ContentCipher
+	clock  clock.Clock
+}
+
+// ContentCipher encrypts message content before it is stored and decrypts it
+// when read, see package encryption.
+type ContentCipher interface {
+	Encrypt(ctx context.Context, orgID string, plaintext string) (string, error)
+	Decrypt(ctx context.Context, stored string) (string, error)
+}
+
+type ChatRepositoryOption func(r *ChatRepository)
+
+// WithContentCipher stores message content encrypted with cipher.
+func WithContentCipher(cipher ContentCipher) ChatRepositoryOption {
+	return func(r *ChatRepository) {
+		r.cipher = cipher
+	}
+}
+
+// WithClock stamps the outbox entries and the partial updates with clk
+// instead of the real clock.
+func WithClock(clk clock.Clock) ChatRepositoryOption {
+	return func(r *ChatRepository) {
+		r.clock = clk
+	}
+}
+
+func NewChatRepository(client *Client, opts ...ChatRepositoryOption) *ChatRepository {
+	r := &ChatRepository{client: client, clock: clock.Real()}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *ChatRepository) CreateChat(ctx context.Context, chat *entity.Chat) error {
+	return r.saveChat(ctx, chat, true)
+}
+
+func (r *ChatRepository) SaveChat(ctx context.Context, chat *entity.Chat) error {
+	return r.saveChat(ctx, chat, false)
+}
+
+// saveChat writes the chat item, the changed message items and the outbox
+// entries in one transaction. New messages that don't fit in it are written
+// first: no chat item references them until the transaction commits, so they
+// stay invisible, and left behind if it fails. The messages the chat dropped
+// are deleted once it committed.
+func (r *ChatRepository) saveChat(ctx context.Context, chat *entity.Chat, create bool) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	entries, err := entity.NewOutboxEntries(chat.ID, chat.PendingEvents(), r.clock.Now())
+	if err != nil {
+		return err
+	}
+	record, messages := newChatRecord(chat)
+	digests := make(map[string]string, len(messages))
+	for id, m := range messages {
+		digests[id] = m.digest()
+	}
+	condition := "attribute_not_exists(#pk)"
+	names := map[string]string{"#pk": "pk"}
+	var values item
+	var stored map[string]string
+	if !create {
+		current, err := r.client.getItem(ctx, chatKey(ns, chat.ID))
+		if err != nil {
+			return err
+		}
+		if len(current) > 0 {
+			if current.num("version") != chat.Version {
+				return gateway.ErrChatConflict
+			}
+			stored = current.strMap("digests")
+			condition = "#version = :version"
+			names = map[string]string{"#version": "version"}
+			values = item{":version": num(chat.Version)}
+		}
+	}
+	chatItem, err := newChatItem(ns, chat, record, digests)
+	if err != nil {
+		return err
+	}
+	put := map[string]interface{}{
+		"TableName":                r.client.config.Table,
+		"Item":                     chatItem,
+		"ConditionExpression":      condition,
+		"ExpressionAttributeNames": names,
+	}
+	if values != nil {
+		put["ExpressionAttributeValues"] = values
+	}
+	transaction := []map[string]interface{}{{"Put": put}}
+	var added []item
+	for _, id := range sortedKeys(digests) {
+		old, ok := stored[id]
+		if ok && old == digests[id] {
+			continue
+		}
+		it, err := r.newMessageItem(ctx, ns, chat, messages[id])
+		if err != nil {
+			return err
+		}
+		if ok {
+			transaction = append(transaction, r.putItem(it))
+		} else {
+			added = append(added, it)
+		}
+	}
+	for _, e := range entries {
+		transaction = append(transaction, r.putItem(newOutboxItem(ns, e)))
+	}
+	room := maxTransactItems - len(transaction)
+	if room < 0 {
+		return fmt.Errorf("chat %s changes %d items, more than the %d of a DynamoDB transaction", chat.ID, len(transaction), maxTransactItems)
+	}
+	if len(added) > room {
+		var requests []map[string]interface{}
+		for _, it := range added[room:] {
+			requests = append(requests, map[string]interface{}{"PutRequest": map[string]interface{}{"Item": it}})
+		}
+		if err := r.client.batchWrite(ctx, requests); err != nil {
+			return fmt.Errorf("error writing messages: %s", err.Error())
+		}
+		added = added[:room]
+	}
+	for _, it := range added {
+		transaction = append(transaction, r.putItem(it))
+	}
+	if err := r.client.transactWrite(ctx, transaction); err != nil {
+		if conditionFailed(err, 0) {
+			if create {
+				return errors.New("chat already exists")
+			}
+			return gateway.ErrChatConflict
+		}
+		return err
+	}
+	chat.Version++
+	chat.MarkEventsSaved()
+	var removed []map[string]interface{}
+	for id := range stored {
+		if _, ok := digests[id]; !ok {
+			removed = append(removed, map[string]interface{}{"DeleteRequest": map[string]interface{}{"Key": messageKey(ns, chat.ID, id)}})
+		}
+	}
+	// dropped message items are no longer read, failing to delete them only
+	// leaves garbage behind
+	_ = r.client.batchWrite(ctx, removed)
+	return nil
+}
+
+func (r *ChatRepository) putItem(it item) map[string]interface{} {
+	return map[string]interface{}{"Put": map[string]interface{}{"TableName": r.client.config.Table, "Item": it}}
+}
+
+// newChatItem sets the index keys of the chat: deleted chats are only in the
+// deleted partition of by_state, waiting to be purged.
+func newChatItem(ns string, chat *entity.Chat, record chatRecord, digests map[string]string) (item, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding chat %s: %s", chat.ID, err.Error())
+	}
+	it := chatKey(ns, chat.ID)
+	it["id"] = str(chat.ID)
+	it["user_id"] = str(chat.UserID)
+	it["title"] = str(chat.Title)
+	it["status"] = str(chat.Status)
+	it["parent_chat_id"] = str(chat.ParentChatID)
+	if chat.SourceID != "" {
+		it["source_id"] = str(chat.SourceID)
+	}
+	it["created_at"] = timeAttr(chat.CreatedAt)
+	it["updated_at"] = timeAttr(chat.UpdatedAt)
+	it["version"] = num(chat.Version + 1)
+	if len(digests) > 0 {
+		it["digests"] = strMap(digests)
+	}
+	it["data"] = str(string(data))
+	if len(chat.Tags) > 0 {
+		it["tags"] = strSet(chat.Tags...)
+	}
+	if !chat.ExpiresAt.IsZero() {
+		it["expires_at"] = timeAttr(chat.ExpiresAt)
+	}
+	if !chat.DeletedAt.IsZero() {
+		it["deleted_at"] = timeAttr(chat.DeletedAt)
+		it["state_pk"] = str(ns + stateDeleted)
+		it["state_sk"] = str(sortKey(formatTime(chat.DeletedAt), chat.ID))
+		return it, nil
+	}
+	updated := str(sortKey(formatTime(chat.UpdatedAt), chat.ID))
+	it["user_pk"] = str(ns + "#" + chat.UserID)
+	it["user_sk"] = updated
+	it["ns_pk"] = str(ns)
+	it["ns_sk"] = updated
+	if chat.ParentChatID != "" {
+		it["parent_pk"] = str(ns + "#" + chat.ParentChatID)
+		it["parent_sk"] = str(sortKey(formatTime(chat.CreatedAt), chat.ID))
+	}
+	if !chat.ExpiresAt.IsZero() {
+		it["state_pk"] = str(ns + stateExpires)
+		it["state_sk"] = str(sortKey(formatTime(chat.ExpiresAt), chat.ID))
+	}
+	return it, nil
+}
+
+func (r *ChatRepository) newMessageItem(ctx context.Context, ns string, chat *entity.Chat, record messageRecord) (item, error) {
+	if r.cipher != nil {
+		content, err := r.cipher.Encrypt(ctx, chat.OrgID, record.Content)
+		if err != nil {
+			return nil, fmt.Errorf("error encrypting message content: %s", err.Error())
+		}
+		record.Content = content
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding message %s: %s", record.ID, err.Error())
+	}
+	it := messageKey(ns, chat.ID, record.ID)
+	it["message_id"] = str(record.ID)
+	it["data"] = str(string(data))
+	return it, nil
+}
+
+func (r *ChatRepository) decodeMessage(ctx context.Context, it item) (messageRecord, error) {
+	var record messageRecord
+	if err := json.Unmarshal([]byte(it.str("data")), &record); err != nil {
+		return record, fmt.Errorf("error decoding message %s: %s", it.str("message_id"), err.Error())
+	}
+	if r.cipher != nil {
+		content, err := r.cipher.Decrypt(ctx, record.Content)
+		if err != nil {
+			return record, err
+		}
+		record.Content = content
+	}
+	return record, nil
+}
+
+func (r *ChatRepository) FindChatByID(ctx context.Context, chatID string) (*entity.Chat, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.loadChat(ctx, ns, chatID)
+}
+
+// loadChat queries the partition of the chat, its item and the ones of its
+// messages.
+func (r *ChatRepository) loadChat(ctx context.Context, ns, chatID string) (*entity.Chat, error) {
+	var chatItem item
+	messages := make(map[string]messageRecord)
+	var decodeErr error
+	err := r.client.query(ctx, map[string]interface{}{
+		"KeyConditionExpression":    "#pk = :pk",
+		"ExpressionAttributeNames":  map[string]string{"#pk": "pk"},
+		"ExpressionAttributeValues": item{":pk": str(chatPK(ns, chatID))},
+		"ConsistentRead":            true,
+	}, func(items []item) bool {
+		for _, it := range items {
+			if it.str("sk") == skChat {
+				chatItem = it
+				continue
+			}
+			m, err := r.decodeMessage(ctx, it)
+			if err != nil {
+				decodeErr = err
+				return false
+			}
+			messages[m.ID] = m
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	if chatItem == nil || chatItem.str("deleted_at") != "" {
+		return nil, gateway.ErrChatNotFound
+	}
+	return chatFromItem(chatItem, messages)
+}
+
+// indexQuery reads an index of the namespace of ctx. key is the key condition
+// on pk and sk, the names (#pk, #sk) and values (:pk, :sk) of the condition
+// and of filters are merged in.
+type indexQuery struct {
+	index   string
+	key     string
+	names   map[string]string
+	values  item
+	filters []string
+	forward bool
+}
+
+func (r *ChatRepository) queryIndex(ctx context.Context, q indexQuery, fn func(it item) (bool, error)) error {
+	input := map[string]interface{}{
+		"IndexName":                 q.index,
+		"KeyConditionExpression":    q.key,
+		"ExpressionAttributeNames":  q.names,
+		"ExpressionAttributeValues": q.values,
+		"ScanIndexForward":          q.forward,
+	}
+	if len(q.filters) > 0 {
+		filter := q.filters[0]
+		for _, f := range q.filters[1:] {
+			filter += " AND " + f
+		}
+		input["FilterExpression"] = filter
+	}
+	var fnErr error
+	err := r.client.query(ctx, input, func(items []item) bool {
+		for _, it := range items {
+			more, err := fn(it)
+			if err != nil {
+				fnErr = err
+				return false
+			}
+			if !more {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return fnErr
+}
+
+// loadChats loads the chats of the index items, skipping the ones deleted
+// since the index was read.
+func (r *ChatRepository) loadChats(ctx context.Context, ns string, items []item) ([]*entity.Chat, error) {
+	chats := make([]*entity.Chat, 0, len(items))
+	for _, it := range items {
+		chat, err := r.loadChat(ctx, ns, it.str("id"))
+		if errors.Is(err, gateway.ErrChatNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		chats = append(chats, chat)
+	}
+	return chats, nil
+}
+
+func (r *ChatRepository) FindChatsByParentID(ctx context.Context, parentChatID string) ([]*entity.Chat, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var items []item
+	err = r.queryIndex(ctx, indexQuery{
+		index:   indexParent,
+		key:     "#pk = :pk",
+		names:   map[string]string{"#pk": "parent_pk"},
+		values:  item{":pk": str(ns + "#" + parentChatID)},
+		forward: true,
+	}, func(it item) (bool, error) {
+		items = append(items, it)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.loadChats(ctx, ns, items)
+}
+
+// ListChats reads the user partition of by_user when filtering by user, the
+// namespace partition of by_namespace otherwise. The other filters are
+// applied by DynamoDB after reading, and the offset is skipped here.
+func (r *ChatRepository) ListChats(ctx context.Context, filter gateway.ChatFilter) ([]*entity.Chat, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q := indexQuery{
+		index:  indexNamespace,
+		key:    "#pk = :pk",
+		names:  map[string]string{"#pk": "ns_pk"},
+		values: item{":pk": str(ns)},
+	}
+	if filter.UserID != "" {
+		q.index = indexUser
+		q.names["#pk"] = "user_pk"
+		q.values[":pk"] = str(ns + "#" + filter.UserID)
+	}
+	if filter.Status != "" {
+		q.filters = append(q.filters, "#status = :status")
+		q.names["#status"] = "status"
+		q.values[":status"] = str(filter.Status)
+	}
+	if filter.SourceID != "" {
+		q.filters = append(q.filters, "#source_id = :source_id")
+		q.names["#source_id"] = "source_id"
+		q.values[":source_id"] = str(filter.SourceID)
+	}
+	if filter.Tag != "" {
+		q.filters = append(q.filters, "contains(#tags, :tag)")
+		q.names["#tags"] = "tags"
+		q.values[":tag"] = str(filter.Tag)
+	}
+	if !filter.CreatedAfter.IsZero() || !filter.CreatedBefore.IsZero() {
+		q.names["#created_at"] = "created_at"
+	}
+	if !filter.CreatedAfter.IsZero() {
+		q.filters = append(q.filters, "#created_at > :after")
+		q.values[":after"] = timeAttr(filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		q.filters = append(q.filters, "#created_at < :before")
+		q.values[":before"] = timeAttr(filter.CreatedBefore)
+	}
+	return r.listChats(ctx, q, filter.Offset, filter.Limit)
+}
+
+func (r *ChatRepository) listChats(ctx context.Context, q indexQuery, offset, limit int) ([]*entity.Chat, error) {
+	chats := []*entity.Chat{}
+	err := r.queryIndex(ctx, q, func(it item) (bool, error) {
+		if offset > 0 {
+			offset--
+			return true, nil
+		}
+		chat, err := chatFromItem(it, nil)
+		if err != nil {
+			return false, err
+		}
+		chats = append(chats, chat)
+		return limit <= 0 || len(chats) < limit, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chats, nil
+}
+
+func (r *ChatRepository) ListChatsByUser(ctx context.Context, userID string, page int, size int) (*gateway.ChatPage, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	q := indexQuery{
+		index:  indexUser,
+		key:    "#pk = :pk",
+		names:  map[string]string{"#pk": "user_pk"},
+		values: item{":pk": str(ns + "#" + userID)},
+	}
+	chats, err := r.listChats(ctx, q, (page-1)*size, size)
+	if err != nil {
+		return nil, err
+	}
+	total, err := r.client.count(ctx, map[string]interface{}{
+		"IndexName":                 q.index,
+		"KeyConditionExpression":    q.key,
+		"ExpressionAttributeNames":  q.names,
+		"ExpressionAttributeValues": q.values,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &gateway.ChatPage{Chats: chats, Total: total}, nil
+}
+
+// ListMessages reads the chat item for the message IDs, then only the items
+// of the page.
+func (r *ChatRepository) ListMessages(ctx context.Context, chatID string, cursor gateway.MessageCursor) (*gateway.MessagePage, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatItem, err := r.client.getItem(ctx, chatKey(ns, chatID))
+	if err != nil {
+		return nil, err
+	}
+	if len(chatItem) == 0 || chatItem.str("deleted_at") != "" {
+		return nil, gateway.ErrChatNotFound
+	}
+	var record chatRecord
+	if err := json.Unmarshal([]byte(chatItem.str("data")), &record); err != nil {
+		return nil, fmt.Errorf("error decoding chat %s: %s", chatID, err.Error())
+	}
+	ids := record.Messages
+	end := len(ids)
+	if cursor.Before != "" {
+		end = -1
+		for i, id := range ids {
+			if id == cursor.Before {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return nil, gateway.ErrInvalidCursor
+		}
+	}
+	page := &gateway.MessagePage{ChatUserID: chatItem.str("user_id")}
+	start := 0
+	if cursor.Limit > 0 && end > cursor.Limit {
+		start = end - cursor.Limit
+		page.Next = &gateway.MessageCursor{Before: ids[start], Limit: cursor.Limit}
+	}
+	keys := make([]item, 0, end-start)
+	for _, id := range ids[start:end] {
+		keys = append(keys, messageKey(ns, chatID, id))
+	}
+	items, err := r.client.batchGet(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	messages := make(map[string]*entity.Message, len(items))
+	for _, it := range items {
+		record, err := r.decodeMessage(ctx, it)
+		if err != nil {
+			return nil, err
+		}
+		if messages[record.ID], err = record.toEntity(); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range ids[start:end] {
+		m, ok := messages[id]
+		if !ok {
+			return nil, fmt.Errorf("chat %s: message %s is missing", chatID, id)
+		}
+		page.Messages = append(page.Messages, m)
+	}
+	return page, nil
+}
+
+func (r *ChatRepository) FindExpiredChats(ctx context.Context, expiredBefore time.Time, limit int) ([]*entity.Chat, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var items []item
+	err = r.queryIndex(ctx, indexQuery{
+		index:   indexState,
+		key:     "#pk = :pk AND #sk < :sk",
+		names:   map[string]string{"#pk": "state_pk", "#sk": "state_sk", "#status": "status"},
+		values:  item{":pk": str(ns + stateExpires), ":sk": timeAttr(expiredBefore), ":archived": str(entity.ChatStatusArchived)},
+		filters: []string{"#status <> :archived"},
+		forward: true,
+	}, func(it item) (bool, error) {
+		items = append(items, it)
+		return limit <= 0 || len(items) < limit, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.loadChats(ctx, ns, items)
+}
+
+// UpdateChatTitle moves the chat in by_user and by_namespace, they sort by
+// updated_at.
+func (r *ChatRepository) UpdateChatTitle(ctx context.Context, chatID string, title string) error {
+	now := r.clock.Now()
+	return r.updateChat(ctx, chatID, []string{"#title = :title", "#updated_at = :updated_at", "#user_sk = :sk", "#ns_sk = :sk"}, "",
+		map[string]string{"#title": "title", "#updated_at": "updated_at", "#user_sk": "user_sk", "#ns_sk": "ns_sk"},
+		item{":title": str(title), ":updated_at": timeAttr(now), ":sk": str(sortKey(formatTime(now), chatID))})
+}
+
+func (r *ChatRepository) AddChatTag(ctx context.Context, chatID string, tag string) error {
+	return r.updateChat(ctx, chatID, nil, "ADD #tags :tags",
+		map[string]string{"#tags": "tags"}, item{":tags": strSet(tag)})
+}
+
+func (r *ChatRepository) RemoveChatTag(ctx context.Context, chatID string, tag string) error {
+	return r.updateChat(ctx, chatID, nil, "DELETE #tags :tags",
+		map[string]string{"#tags": "tags"}, item{":tags": strSet(tag)})
+}
+
+// DeleteChat takes the chat out of every index but the deleted partition of
+// by_state.
+func (r *ChatRepository) DeleteChat(ctx context.Context, chatID string, deletedAt time.Time) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return r.updateChat(ctx, chatID,
+		[]string{"#deleted_at = :deleted_at", "#state_pk = :state_pk", "#state_sk = :state_sk"},
+		"REMOVE #user_pk, #user_sk, #ns_pk, #ns_sk, #parent_pk, #parent_sk",
+		map[string]string{
+			"#state_pk": "state_pk", "#state_sk": "state_sk",
+			"#user_pk": "user_pk", "#user_sk": "user_sk", "#ns_pk": "ns_pk", "#ns_sk": "ns_sk",
+			"#parent_pk": "parent_pk", "#parent_sk": "parent_sk",
+		},
+		item{
+			":deleted_at": timeAttr(deletedAt),
+			":state_pk":   str(ns + stateDeleted),
+			":state_sk":   str(sortKey(formatTime(deletedAt), chatID)),
+		})
+}
+
+// updateChat runs the set actions, plus the bump of the version, and the
+// other clauses of the update on the chat item, if it is not deleted.
+func (r *ChatRepository) updateChat(ctx context.Context, chatID string, set []string, other string, names map[string]string, values item) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	names["#pk"] = "pk"
+	names["#deleted_at"] = "deleted_at"
+	names["#version"] = "version"
+	values[":one"] = num(1)
+	update := "SET " + strings.Join(append(set, "#version = #version + :one"), ", ")
+	if other != "" {
+		update += " " + other
+	}
+	err = r.client.call(ctx, "UpdateItem", map[string]interface{}{
+		"Key":                       chatKey(ns, chatID),
+		"UpdateExpression":          update,
+		"ConditionExpression":       "attribute_exists(#pk) AND attribute_not_exists(#deleted_at)",
+		"ExpressionAttributeNames":  names,
+		"ExpressionAttributeValues": values,
+	}, nil)
+	if conditionFailed(err, 0) {
+		return gateway.ErrChatNotFound
+	}
+	return err
+}
+
+// SetMessageFlags rewrites the message item and its digest in the chat item.
+// The write is conditioned on the item read, and retried when a SaveChat got
+// in between.
+func (r *ChatRepository) SetMessageFlags(ctx context.Context, chatID string, messageID string, pinned bool, starred bool) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	key := messageKey(ns, chatID, messageID)
+	for attempt := 1; ; attempt++ {
+		current, err := r.client.getItem(ctx, key)
+		if err != nil {
+			return err
+		}
+		if len(current) == 0 {
+			return entity.ErrMessageNotFound
+		}
+		// the content stays as stored, the digest leaves it out
+		var record messageRecord
+		if err := json.Unmarshal([]byte(current.str("data")), &record); err != nil {
+			return fmt.Errorf("error decoding message %s: %s", messageID, err.Error())
+		}
+		record.Pinned = pinned
+		record.Starred = starred
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		updated := messageKey(ns, chatID, messageID)
+		updated["message_id"] = str(messageID)
+		updated["data"] = str(string(data))
+		err = r.client.transactWrite(ctx, []map[string]interface{}{
+			{"Put": map[string]interface{}{
+				"TableName":                 r.client.config.Table,
+				"Item":                      updated,
+				"ConditionExpression":       "#data = :data",
+				"ExpressionAttributeNames":  map[string]string{"#data": "data"},
+				"ExpressionAttributeValues": item{":data": current["data"]},
+			}},
+			{"Update": map[string]interface{}{
+				"TableName":                 r.client.config.Table,
+				"Key":                       chatKey(ns, chatID),
+				"UpdateExpression":          "SET #digests.#message = :digest, #version = #version + :one",
+				"ConditionExpression":       "attribute_exists(#pk)",
+				"ExpressionAttributeNames":  map[string]string{"#digests": "digests", "#message": messageID, "#version": "version", "#pk": "pk"},
+				"ExpressionAttributeValues": item{":digest": str(record.digest()), ":one": num(1)},
+			}},
+		})
+		if conditionFailed(err, 0) && attempt < maxFlagAttempts {
+			continue
+		}
+		if conditionFailed(err, 0) {
+			return gateway.ErrChatConflict
+		}
+		if conditionFailed(err, 1) {
+			return gateway.ErrChatNotFound
+		}
+		return err
+	}
+}
+
+func (r *ChatRepository) PurgeDeletedChats(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return r.purge(ctx, stateDeleted, deletedBefore, false)
+}
+
+func (r *ChatRepository) PurgeExpiredChats(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	return r.purge(ctx, stateExpires, expiredBefore, true)
+}
+
+// purge deletes the chats of a by_state partition before t, only the archived
+// ones when archivedOnly is set. The message items go first, a purge stopped
+// halfway leaves the chat item in the index for the next one.
+func (r *ChatRepository) purge(ctx context.Context, state string, t time.Time, archivedOnly bool) (int64, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	q := indexQuery{
+		index:   indexState,
+		key:     "#pk = :pk AND #sk < :sk",
+		names:   map[string]string{"#pk": "state_pk", "#sk": "state_sk"},
+		values:  item{":pk": str(ns + state), ":sk": timeAttr(t)},
+		forward: true,
+	}
+	if archivedOnly {
+		q.filters = []string{"#status = :archived"}
+		q.names["#status"] = "status"
+		q.values[":archived"] = str(entity.ChatStatusArchived)
+	}
+	var chatIDs []string
+	err = r.queryIndex(ctx, q, func(it item) (bool, error) {
+		chatIDs = append(chatIDs, it.str("id"))
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	var purged int64
+	for _, chatID := range chatIDs {
+		if err := r.deleteChatItems(ctx, ns, chatID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (r *ChatRepository) deleteChatItems(ctx context.Context, ns, chatID string) error {
+	var requests []map[string]interface{}
+	err := r.client.query(ctx, map[string]interface{}{
+		"KeyConditionExpression":    "#pk = :pk AND begins_with(#sk, :msg)",
+		"ProjectionExpression":      "#pk, #sk",
+		"ExpressionAttributeNames":  map[string]string{"#pk": "pk", "#sk": "sk"},
+		"ExpressionAttributeValues": item{":pk": str(chatPK(ns, chatID)), ":msg": str(prefixMsg)},
+	}, func(items []item) bool {
+		for _, it := range items {
+			requests = append(requests, map[string]interface{}{"DeleteRequest": map[string]interface{}{"Key": it}})
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if err := r.client.batchWrite(ctx, requests); err != nil {
+		return err
+	}
+	return r.client.call(ctx, "DeleteItem", map[string]interface{}{"Key": chatKey(ns, chatID)}, nil)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
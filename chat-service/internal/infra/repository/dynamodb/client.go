@@ -0,0 +1,287 @@
+// Package dynamodb implements the chat gateways on a single DynamoDB table,
+// through the DynamoDB JSON API signed with awssig. See table.go for the
+// layout of the items.
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/awssig"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type Config struct {
+	Table string
+	// Endpoint defaults to https://dynamodb.<region>.amazonaws.com, set it to
+	// run against DynamoDB Local.
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// Client runs the DynamoDB actions the repositories need on the table of its
+// config.
+type Client struct {
+	config Config
+	http   *http.Client
+	clock  clock.Clock
+}
+
+func NewClient(config Config, httpClient *http.Client, clk clock.Clock) (*Client, error) {
+	if config.Table == "" || config.Region == "" {
+		return nil, fmt.Errorf("dynamodb client needs a table and a region")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("dynamodb client needs credentials")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://dynamodb." + config.Region + ".amazonaws.com"
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		config: config,
+		http:   httpClient,
+		clock:  clk,
+	}, nil
+}
+
+// apiError is the body of the DynamoDB error responses. Type is the exception
+// name without its namespace, CancellationReasons are set on canceled
+// transactions, one per item in order.
+type apiError struct {
+	Type                string `json:"__type"`
+	Message             string `json:"message"`
+	CancellationReasons []struct {
+		Code string
+	}
+}
+
+func (e *apiError) Error() string {
+	return "dynamodb: " + e.Type + ": " + e.Message
+}
+
+const (
+	errConditionFailed       = "ConditionalCheckFailedException"
+	errTransactionCanceled   = "TransactionCanceledException"
+	errResourceInUse         = "ResourceInUseException"
+	reasonConditionalFailure = "ConditionalCheckFailed"
+)
+
+func isAPIError(err error, errType string) bool {
+	apiErr, ok := err.(*apiError)
+	return ok && apiErr.Type == errType
+}
+
+// conditionFailed tells whether err is a failed condition, on its own or on
+// the item at index of a transaction.
+func conditionFailed(err error, index int) bool {
+	apiErr, ok := err.(*apiError)
+	if !ok {
+		return false
+	}
+	if apiErr.Type == errConditionFailed {
+		return true
+	}
+	return apiErr.Type == errTransactionCanceled && index < len(apiErr.CancellationReasons) &&
+		apiErr.CancellationReasons[index].Code == reasonConditionalFailure
+}
+
+// call runs a DynamoDB JSON API action on the table of the config, the batch
+// and transaction actions name it in their items.
+func (c *Client) call(ctx context.Context, action string, input map[string]interface{}, output interface{}) error {
+	if _, ok := input["TableName"]; !ok && action != "TransactWriteItems" && action != "BatchWriteItem" && action != "BatchGetItem" {
+		input["TableName"] = c.config.Table
+	}
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.Endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "DynamoDB_20120810."+action)
+	creds := awssig.Credentials{
+		AccessKeyID:     c.config.AccessKeyID,
+		SecretAccessKey: c.config.SecretAccessKey,
+		SessionToken:    c.config.SessionToken,
+	}
+	awssig.Sign(req, "/", creds, c.config.Region, "dynamodb", awssig.PayloadHash(body), c.clock.Now())
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		apiErr := &apiError{}
+		if json.Unmarshal(msg, apiErr) != nil || apiErr.Type == "" {
+			return fmt.Errorf("dynamodb %s: %s: %s", action, resp.Status, strings.TrimSpace(string(msg)))
+		}
+		if i := strings.LastIndexByte(apiErr.Type, '#'); i >= 0 {
+			apiErr.Type = apiErr.Type[i+1:]
+		}
+		return apiErr
+	}
+	if output == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(output)
+}
+
+// maxTransactItems and maxBatchWrite are the most items TransactWriteItems
+// and BatchWriteItem take, maxBatchGet the most keys of BatchGetItem.
+const (
+	maxTransactItems = 100
+	maxBatchWrite    = 25
+	maxBatchGet      = 100
+)
+
+// maxBatchAttempts bounds the retries of the unprocessed items of batches,
+// which DynamoDB leaves out when throttling.
+const maxBatchAttempts = 8
+
+func (c *Client) transactWrite(ctx context.Context, items []map[string]interface{}) error {
+	return c.call(ctx, "TransactWriteItems", map[string]interface{}{"TransactItems": items}, nil)
+}
+
+// batchWrite runs the put and delete requests in batches, retrying the
+// unprocessed ones. It is not atomic.
+func (c *Client) batchWrite(ctx context.Context, requests []map[string]interface{}) error {
+	for len(requests) > 0 {
+		n := len(requests)
+		if n > maxBatchWrite {
+			n = maxBatchWrite
+		}
+		pending := requests[:n]
+		requests = requests[n:]
+		for attempt := 1; len(pending) > 0; attempt++ {
+			if attempt > maxBatchAttempts {
+				return fmt.Errorf("dynamodb BatchWriteItem: %d items left unprocessed", len(pending))
+			}
+			var output struct {
+				UnprocessedItems map[string][]map[string]interface{}
+			}
+			if err := c.call(ctx, "BatchWriteItem", map[string]interface{}{
+				"RequestItems": map[string]interface{}{c.config.Table: pending},
+			}, &output); err != nil {
+				return err
+			}
+			pending = output.UnprocessedItems[c.config.Table]
+			if err := c.backoff(ctx, attempt, len(pending)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// batchGet returns the items of keys that exist, in no particular order.
+func (c *Client) batchGet(ctx context.Context, keys []item) ([]item, error) {
+	var items []item
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > maxBatchGet {
+			n = maxBatchGet
+		}
+		pending := keys[:n]
+		keys = keys[n:]
+		for attempt := 1; len(pending) > 0; attempt++ {
+			if attempt > maxBatchAttempts {
+				return nil, fmt.Errorf("dynamodb BatchGetItem: %d keys left unprocessed", len(pending))
+			}
+			var output struct {
+				Responses       map[string][]item
+				UnprocessedKeys map[string]struct {
+					Keys []item
+				}
+			}
+			if err := c.call(ctx, "BatchGetItem", map[string]interface{}{
+				"RequestItems": map[string]interface{}{c.config.Table: map[string]interface{}{
+					"Keys":           pending,
+					"ConsistentRead": true,
+				}},
+			}, &output); err != nil {
+				return nil, err
+			}
+			items = append(items, output.Responses[c.config.Table]...)
+			pending = output.UnprocessedKeys[c.config.Table].Keys
+			if err := c.backoff(ctx, attempt, len(pending)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return items, nil
+}
+
+// backoff waits before retrying the unprocessed items of a batch.
+func (c *Client) backoff(ctx context.Context, attempt int, pending int) error {
+	if pending == 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Duration(attempt*attempt) * 25 * time.Millisecond):
+		return nil
+	}
+}
+
+// query runs input until fn returns false or the results run out, handing it
+// one page of items at a time.
+func (c *Client) query(ctx context.Context, input map[string]interface{}, fn func(items []item) bool) error {
+	for {
+		var output struct {
+			Items            []item
+			LastEvaluatedKey item
+		}
+		if err := c.call(ctx, "Query", input, &output); err != nil {
+			return err
+		}
+		if !fn(output.Items) || len(output.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		input["ExclusiveStartKey"] = output.LastEvaluatedKey
+	}
+}
+
+func (c *Client) getItem(ctx context.Context, key item) (item, error) {
+	var output struct {
+		Item item
+	}
+	err := c.call(ctx, "GetItem", map[string]interface{}{"Key": key, "ConsistentRead": true}, &output)
+	return output.Item, err
+}
+
+// count runs input with Select COUNT, across every page.
+func (c *Client) count(ctx context.Context, input map[string]interface{}) (int, error) {
+	input["Select"] = "COUNT"
+	total := 0
+	for {
+		var output struct {
+			Count            int
+			LastEvaluatedKey item
+		}
+		if err := c.call(ctx, "Query", input, &output); err != nil {
+			return 0, err
+		}
+		total += output.Count
+		if len(output.LastEvaluatedKey) == 0 {
+			return total, nil
+		}
+		input["ExclusiveStartKey"] = output.LastEvaluatedKey
+	}
+}
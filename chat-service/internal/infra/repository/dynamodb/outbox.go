@@ -0,0 +1,111 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+// newOutboxItem puts the entry in the outbox partition of by_state until it
+// is published.
+func newOutboxItem(ns string, e *entity.OutboxEntry) item {
+	it := outboxKey(ns, e.ID)
+	it["id"] = str(e.ID)
+	it["chat_id"] = str(e.ChatID)
+	it["event_name"] = str(e.EventName)
+	it["payload"] = str(string(e.Payload))
+	it["occurred_at"] = timeAttr(e.OccurredAt)
+	it["attempts"] = num(0)
+	it["state_pk"] = str(ns + stateOutbox)
+	it["state_sk"] = str(sortKey(formatTime(e.OccurredAt), e.ID))
+	return it
+}
+
+// OutboxRepository reads the outbox entries ChatRepository writes with the
+// chats.
+type OutboxRepository struct {
+	client *Client
+}
+
+func NewOutboxRepository(client *Client) *OutboxRepository {
+	return &OutboxRepository{client: client}
+}
+
+func (r *OutboxRepository) FindUnpublishedOutboxEntries(ctx context.Context, limit int) ([]*entity.OutboxEntry, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+	var entries []*entity.OutboxEntry
+	err = r.client.query(ctx, map[string]interface{}{
+		"IndexName":                 indexState,
+		"KeyConditionExpression":    "#pk = :pk",
+		"ExpressionAttributeNames":  map[string]string{"#pk": "state_pk"},
+		"ExpressionAttributeValues": item{":pk": str(ns + stateOutbox)},
+		"ScanIndexForward":          true,
+		"Limit":                     limit,
+	}, func(items []item) bool {
+		for _, it := range items {
+			entries = append(entries, &entity.OutboxEntry{
+				ID:         it.str("id"),
+				ChatID:     it.str("chat_id"),
+				EventName:  it.str("event_name"),
+				Payload:    []byte(it.str("payload")),
+				OccurredAt: it.time("occurred_at"),
+				Attempts:   it.num("attempts"),
+				LastError:  it.str("last_error"),
+			})
+		}
+		return len(entries) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// MarkOutboxEntryPublished takes the entry out of by_state.
+func (r *OutboxRepository) MarkOutboxEntryPublished(ctx context.Context, entryID string, publishedAt time.Time) error {
+	return r.update(ctx, entryID, "SET #published_at = :published_at, #attempts = #attempts + :one REMOVE #state_pk, #state_sk",
+		map[string]string{"#published_at": "published_at", "#attempts": "attempts", "#state_pk": "state_pk", "#state_sk": "state_sk"},
+		item{":published_at": timeAttr(publishedAt), ":one": num(1)})
+}
+
+func (r *OutboxRepository) MarkOutboxEntryFailed(ctx context.Context, entryID string, reason string) error {
+	return r.update(ctx, entryID, "SET #last_error = :reason, #attempts = #attempts + :one",
+		map[string]string{"#last_error": "last_error", "#attempts": "attempts"},
+		item{":reason": str(reason), ":one": num(1)})
+}
+
+// MarkOutboxEntryDeadLettered takes the entry out of by_state, like a
+// published one.
+func (r *OutboxRepository) MarkOutboxEntryDeadLettered(ctx context.Context, entryID string, reason string, deadLetteredAt time.Time) error {
+	return r.update(ctx, entryID, "SET #last_error = :reason, #dead_lettered_at = :dead_lettered_at, #attempts = #attempts + :one REMOVE #state_pk, #state_sk",
+		map[string]string{"#last_error": "last_error", "#dead_lettered_at": "dead_lettered_at", "#attempts": "attempts", "#state_pk": "state_pk", "#state_sk": "state_sk"},
+		item{":reason": str(reason), ":dead_lettered_at": timeAttr(deadLetteredAt), ":one": num(1)})
+}
+
+func (r *OutboxRepository) update(ctx context.Context, entryID string, update string, names map[string]string, values item) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	names["#pk"] = "pk"
+	err = r.client.call(ctx, "UpdateItem", map[string]interface{}{
+		"Key":                       outboxKey(ns, entryID),
+		"UpdateExpression":          update,
+		"ConditionExpression":       "attribute_exists(#pk)",
+		"ExpressionAttributeNames":  names,
+		"ExpressionAttributeValues": values,
+	}, nil)
+	if conditionFailed(err, 0) {
+		// like the other databases, updating a missing entry is no error
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,316 @@
+package dynamodb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+// chatRecord is the data attribute of a chat item, the fields no condition,
+// update or index needs. Messages lists the IDs of the active messages in
+// order, the initial system message is in one of the lists or on its own.
+type chatRecord struct {
+	ForkedFromMessageID string       `json:"forked_from_message_id,omitempty"`
+	PersonaID           string       `json:"persona_id,omitempty"`
+	OrgID               string       `json:"org_id,omitempty"`
+	InitialMessageID    string       `json:"initial_message_id,omitempty"`
+	AnswerStage         string       `json:"answer_stage,omitempty"`
+	SystemFingerprint   string       `json:"system_fingerprint,omitempty"`
+	SummaryMessageID    string       `json:"summary_message_id,omitempty"`
+	TokenUsage          int          `json:"token_usage"`
+	Config              configRecord `json:"config"`
+	Messages            []string     `json:"messages"`
+	ErasedMessages      []string     `json:"erased_messages,omitempty"`
+}
+
+type configRecord struct {
+	ModelName        string   `json:"model_name"`
+	ModelMaxTokens   int      `json:"model_max_tokens"`
+	Temperature      float32  `json:"temperature"`
+	TopP             float32  `json:"top_p"`
+	N                int      `json:"n"`
+	Stop             []string `json:"stop,omitempty"`
+	MaxTokens        int      `json:"max_tokens"`
+	PresencePenalty  float32  `json:"presence_penalty"`
+	FrequencyPenalty float32  `json:"frequency_penalty"`
+	AnswerMode       string   `json:"answer_mode,omitempty"`
+	Deterministic    bool     `json:"deterministic,omitempty"`
+	MaxMessages      int      `json:"max_messages,omitempty"`
+	EvictionPolicy   string   `json:"eviction_policy,omitempty"`
+}
+
+// messageRecord is the data attribute of a message item.
+type messageRecord struct {
+	ID                  string             `json:"id"`
+	Role                string             `json:"role"`
+	Content             string             `json:"content"`
+	Tokens              int                `json:"tokens"`
+	ModelName           string             `json:"model_name"`
+	ModelMaxTokens      int                `json:"model_max_tokens"`
+	PromptTokens        int                `json:"prompt_tokens,omitempty"`
+	TimeToFirstTokenNS  int64              `json:"time_to_first_token_ns,omitempty"`
+	GenerationLatencyNS int64              `json:"generation_latency_ns,omitempty"`
+	Pinned              bool               `json:"pinned"`
+	Starred             bool               `json:"starred"`
+	IncompleteReason    string             `json:"incomplete_reason,omitempty"`
+	CreatedAt           time.Time          `json:"created_at"`
+	Annotations         []annotationRecord `json:"annotations,omitempty"`
+	Attachments         []attachmentRecord `json:"attachments,omitempty"`
+}
+
+type annotationRecord struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Kind      string    `json:"kind"`
+	Start     int       `json:"start"`
+	End       int       `json:"end"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type attachmentRecord struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	MimeType   string    `json:"mime_type"`
+	Size       int64     `json:"size"`
+	StorageKey string    `json:"storage_key"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// newChatRecord also returns every message of chat once, by ID.
+func newChatRecord(chat *entity.Chat) (chatRecord, map[string]messageRecord) {
+	c := chat.Config
+	record := chatRecord{
+		ForkedFromMessageID: chat.ForkedFromMessageID,
+		PersonaID:           chat.PersonaID,
+		OrgID:               chat.OrgID,
+		AnswerStage:         chat.AnswerStage,
+		SystemFingerprint:   chat.SystemFingerprint,
+		SummaryMessageID:    chat.SummaryMessageID,
+		TokenUsage:          chat.TokenUsage,
+		Config: configRecord{
+			Temperature:      c.Temperature,
+			TopP:             c.TopP,
+			N:                c.N,
+			Stop:             c.Stop,
+			MaxTokens:        c.MaxTokens,
+			PresencePenalty:  c.PresencePenalty,
+			FrequencyPenalty: c.FrequencyPenalty,
+			AnswerMode:       c.AnswerMode,
+			Deterministic:    c.Deterministic,
+			MaxMessages:      c.MaxMessages,
+			EvictionPolicy:   c.EvictionPolicy,
+		},
+		Messages: []string{},
+	}
+	if c.Model != nil {
+		record.Config.ModelName = c.Model.Name
+		record.Config.ModelMaxTokens = c.Model.MaxToken
+	}
+	messages := make(map[string]messageRecord)
+	add := func(m *entity.Message) {
+		if _, ok := messages[m.ID]; !ok {
+			messages[m.ID] = newMessageRecord(m)
+		}
+	}
+	for _, m := range chat.Messages {
+		record.Messages = append(record.Messages, m.ID)
+		add(m)
+	}
+	for _, m := range chat.ErasedMessages {
+		record.ErasedMessages = append(record.ErasedMessages, m.ID)
+		add(m)
+	}
+	if chat.InitialSystemMessage != nil {
+		record.InitialMessageID = chat.InitialSystemMessage.ID
+		add(chat.InitialSystemMessage)
+	}
+	return record, messages
+}
+
+func newMessageRecord(m *entity.Message) messageRecord {
+	record := messageRecord{
+		ID:                  m.ID,
+		Role:                m.Role.String(),
+		Content:             m.Content,
+		Tokens:              m.Tokens,
+		PromptTokens:        m.PromptTokens,
+		TimeToFirstTokenNS:  int64(m.TimeToFirstToken),
+		GenerationLatencyNS: int64(m.GenerationLatency),
+		Pinned:              m.Pinned,
+		Starred:             m.Starred,
+		IncompleteReason:    m.IncompleteReason,
+		CreatedAt:           m.CreatedAt,
+	}
+	if m.Model != nil {
+		record.ModelName = m.Model.Name
+		record.ModelMaxTokens = m.Model.MaxToken
+	}
+	for _, a := range m.Annotations {
+		record.Annotations = append(record.Annotations, annotationRecord{
+			ID:        a.ID,
+			UserID:    a.UserID,
+			Kind:      a.Kind,
+			Start:     a.Start,
+			End:       a.End,
+			Text:      a.Text,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+	for _, a := range m.Attachments {
+		record.Attachments = append(record.Attachments, attachmentRecord{
+			ID:         a.ID,
+			Name:       a.Name,
+			MimeType:   a.MimeType,
+			Size:       a.Size,
+			StorageKey: a.StorageKey,
+			CreatedAt:  a.CreatedAt,
+		})
+	}
+	return record
+}
+
+// digest tells SaveChat which message items changed. Content is left out:
+// it never changes in place, a new content comes with a new message, and a
+// hash of it would leak what encryption hides.
+func (m messageRecord) digest() string {
+	m.Content = ""
+	data, _ := json.Marshal(m)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+func (m messageRecord) toEntity() (*entity.Message, error) {
+	role, err := entity.ParseRole(m.Role)
+	if err != nil {
+		return nil, fmt.Errorf("message %s: %s", m.ID, err.Error())
+	}
+	message := &entity.Message{
+		ID:                m.ID,
+		Role:              role,
+		Content:           m.Content,
+		Tokens:            m.Tokens,
+		Model:             entity.NewModel(m.ModelName, m.ModelMaxTokens),
+		CreatedAt:         m.CreatedAt,
+		PromptTokens:      m.PromptTokens,
+		TimeToFirstToken:  time.Duration(m.TimeToFirstTokenNS),
+		GenerationLatency: time.Duration(m.GenerationLatencyNS),
+		Pinned:            m.Pinned,
+		Starred:           m.Starred,
+		IncompleteReason:  m.IncompleteReason,
+	}
+	for _, a := range m.Annotations {
+		message.AddAnnotation(&entity.Annotation{
+			ID:        a.ID,
+			MessageID: m.ID,
+			UserID:    a.UserID,
+			Kind:      a.Kind,
+			Start:     a.Start,
+			End:       a.End,
+			Text:      a.Text,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+	for _, a := range m.Attachments {
+		message.AddAttachment(&entity.Attachment{
+			ID:         a.ID,
+			MessageID:  m.ID,
+			Name:       a.Name,
+			MimeType:   a.MimeType,
+			Size:       a.Size,
+			StorageKey: a.StorageKey,
+			CreatedAt:  a.CreatedAt,
+		})
+	}
+	return message, nil
+}
+
+// chatFromItem builds the chat of a chat item. messages holds the message
+// items of the chat by ID, nil to leave the messages out like listings do.
+func chatFromItem(it item, messages map[string]messageRecord) (*entity.Chat, error) {
+	var record chatRecord
+	if err := json.Unmarshal([]byte(it.str("data")), &record); err != nil {
+		return nil, fmt.Errorf("error decoding chat %s: %s", it.str("id"), err.Error())
+	}
+	c := record.Config
+	chat := &entity.Chat{
+		ID:                  it.str("id"),
+		UserID:              it.str("user_id"),
+		Title:               it.str("title"),
+		ParentChatID:        it.str("parent_chat_id"),
+		SourceID:            it.str("source_id"),
+		ForkedFromMessageID: record.ForkedFromMessageID,
+		PersonaID:           record.PersonaID,
+		OrgID:               record.OrgID,
+		Status:              it.str("status"),
+		AnswerStage:         record.AnswerStage,
+		SystemFingerprint:   record.SystemFingerprint,
+		SummaryMessageID:    record.SummaryMessageID,
+		TokenUsage:          record.TokenUsage,
+		Config: &entity.ChatConfig{
+			Model:            entity.NewModel(c.ModelName, c.ModelMaxTokens),
+			Temperature:      c.Temperature,
+			TopP:             c.TopP,
+			N:                c.N,
+			Stop:             c.Stop,
+			MaxTokens:        c.MaxTokens,
+			PresencePenalty:  c.PresencePenalty,
+			FrequencyPenalty: c.FrequencyPenalty,
+			AnswerMode:       c.AnswerMode,
+			Deterministic:    c.Deterministic,
+			MaxMessages:      c.MaxMessages,
+			EvictionPolicy:   c.EvictionPolicy,
+		},
+		Tags:      it.strings("tags"),
+		CreatedAt: it.time("created_at"),
+		UpdatedAt: it.time("updated_at"),
+		DeletedAt: it.time("deleted_at"),
+		ExpiresAt: it.time("expires_at"),
+		Version:   it.num("version"),
+	}
+	if messages == nil {
+		return chat, nil
+	}
+	loaded := make(map[string]*entity.Message)
+	load := func(id string) (*entity.Message, error) {
+		if m, ok := loaded[id]; ok {
+			return m, nil
+		}
+		mr, ok := messages[id]
+		if !ok {
+			return nil, fmt.Errorf("chat %s: message %s is missing", chat.ID, id)
+		}
+		m, err := mr.toEntity()
+		if err != nil {
+			return nil, err
+		}
+		loaded[id] = m
+		return m, nil
+	}
+	for _, id := range record.Messages {
+		m, err := load(id)
+		if err != nil {
+			return nil, err
+		}
+		chat.Messages = append(chat.Messages, m)
+	}
+	for _, id := range record.ErasedMessages {
+		m, err := load(id)
+		if err != nil {
+			return nil, err
+		}
+		chat.ErasedMessages = append(chat.ErasedMessages, m)
+	}
+	if record.InitialMessageID != "" {
+		m, err := load(record.InitialMessageID)
+		if err != nil {
+			return nil, err
+		}
+		chat.InitialSystemMessage = m
+	}
+	return chat, nil
+}
@@ -0,0 +1,94 @@
+package dynamodb
+
+import "context"
+
+// The table holds three kinds of items, all keyed by pk and sk:
+//
+//   - chat:    pk CHAT#<namespace>#<chat id>, sk CHAT, the chat fields and the
+//     IDs of its messages by state
+//   - message: pk CHAT#<namespace>#<chat id>, sk MSG#<message id>, one message
+//     with its annotations and attachments
+//   - outbox:  pk OUTBOX#<namespace>#<entry id>, sk OUTBOX
+//
+// A chat and its messages share a partition, so one Query loads the whole
+// chat. The indexes are sparse, an item is only in the ones whose keys it has:
+//
+//   - by_user:      chats not deleted by user, most recently updated last
+//   - by_namespace: chats not deleted of the namespace, same order
+//   - by_parent:    forks not deleted by parent chat, oldest first
+//   - by_state:     ephemeral chats by expiry, deleted chats by deletion and
+//     unpublished outbox entries by occurrence, each in a partition of its own
+const (
+	skChat       = "CHAT"
+	skOutbox     = "OUTBOX"
+	prefixChat   = "CHAT#"
+	prefixMsg    = "MSG#"
+	prefixOutbox = "OUTBOX#"
+
+	indexUser      = "by_user"
+	indexNamespace = "by_namespace"
+	indexParent    = "by_parent"
+	indexState     = "by_state"
+
+	stateExpires = "#EXPIRES"
+	stateDeleted = "#DELETED"
+	stateOutbox  = "#OUTBOX"
+)
+
+func chatPK(ns, chatID string) string {
+	return prefixChat + ns + "#" + chatID
+}
+
+func chatKey(ns, chatID string) item {
+	return item{"pk": str(chatPK(ns, chatID)), "sk": str(skChat)}
+}
+
+func messageKey(ns, chatID, messageID string) item {
+	return item{"pk": str(chatPK(ns, chatID)), "sk": str(prefixMsg + messageID)}
+}
+
+func outboxKey(ns, entryID string) item {
+	return item{"pk": str(prefixOutbox + ns + "#" + entryID), "sk": str(skOutbox)}
+}
+
+// sortKey orders index entries by t, then by id for equal times.
+func sortKey(t, id string) string {
+	return t + "#" + id
+}
+
+// EnsureTable creates the table with its indexes, on demand capacity. It is
+// safe to run on every start, an existing table is left as it is.
+func (c *Client) EnsureTable(ctx context.Context) error {
+	var attributes []map[string]string
+	for _, name := range []string{"pk", "sk", "user_pk", "user_sk", "ns_pk", "ns_sk", "parent_pk", "parent_sk", "state_pk", "state_sk"} {
+		attributes = append(attributes, map[string]string{"AttributeName": name, "AttributeType": "S"})
+	}
+	index := func(name, pk, sk string) map[string]interface{} {
+		return map[string]interface{}{
+			"IndexName": name,
+			"KeySchema": []map[string]string{
+				{"AttributeName": pk, "KeyType": "HASH"},
+				{"AttributeName": sk, "KeyType": "RANGE"},
+			},
+			"Projection": map[string]string{"ProjectionType": "ALL"},
+		}
+	}
+	err := c.call(ctx, "CreateTable", map[string]interface{}{
+		"AttributeDefinitions": attributes,
+		"KeySchema": []map[string]string{
+			{"AttributeName": "pk", "KeyType": "HASH"},
+			{"AttributeName": "sk", "KeyType": "RANGE"},
+		},
+		"GlobalSecondaryIndexes": []map[string]interface{}{
+			index(indexUser, "user_pk", "user_sk"),
+			index(indexNamespace, "ns_pk", "ns_sk"),
+			index(indexParent, "parent_pk", "parent_sk"),
+			index(indexState, "state_pk", "state_sk"),
+		},
+		"BillingMode": "PAY_PER_REQUEST",
+	}, nil)
+	if isAPIError(err, errResourceInUse) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AnnotationRepository and AttachmentRepository push into the message they
+// belong to, inside its chat document. Both bump the chat version, SaveChat
+// replaces the whole document.
+type AnnotationRepository struct {
+	chats *mongo.Collection
+}
+
+func NewAnnotationRepository(db *mongo.Database) *AnnotationRepository {
+	return &AnnotationRepository{chats: db.Collection(chatsCollection)}
+}
+
+func (r *AnnotationRepository) CreateAnnotation(ctx context.Context, annotation *entity.Annotation) error {
+	return pushToMessage(ctx, r.chats, annotation.MessageID, "annotations", newAnnotationDocument(annotation))
+}
+
+func (r *AnnotationRepository) DeleteAnnotation(ctx context.Context, annotationID string) error {
+	return pullFromMessages(ctx, r.chats, "annotations", annotationID)
+}
+
+type AttachmentRepository struct {
+	chats *mongo.Collection
+}
+
+func NewAttachmentRepository(db *mongo.Database) *AttachmentRepository {
+	return &AttachmentRepository{chats: db.Collection(chatsCollection)}
+}
+
+func (r *AttachmentRepository) CreateAttachment(ctx context.Context, attachment *entity.Attachment) error {
+	return pushToMessage(ctx, r.chats, attachment.MessageID, "attachments", newAttachmentDocument(attachment))
+}
+
+func (r *AttachmentRepository) DeleteAttachment(ctx context.Context, attachmentID string) error {
+	return pullFromMessages(ctx, r.chats, "attachments", attachmentID)
+}
+
+func pushToMessage(ctx context.Context, chats *mongo.Collection, messageID, field string, doc interface{}) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := chats.UpdateOne(ctx,
+		bson.M{"namespace": ns, "messages.id": messageID},
+		bson.M{"$push": bson.M{"messages.$." + field: doc}, "$inc": bson.M{"version": 1}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return entity.ErrMessageNotFound
+	}
+	return nil
+}
+
+func pullFromMessages(ctx context.Context, chats *mongo.Collection, field, id string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = chats.UpdateOne(ctx,
+		bson.M{"namespace": ns, "messages." + field + ".id": id},
+		bson.M{"$pull": bson.M{"messages.$[]." + field: bson.M{"id": id}}, "$inc": bson.M{"version": 1}})
+	return err
+}
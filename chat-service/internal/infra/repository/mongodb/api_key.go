@@ -0,0 +1,134 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type apiKeyDocument struct {
+	Namespace string     `bson:"namespace"`
+	ID        string     `bson:"id"`
+	UserID    string     `bson:"user_id"`
+	OrgID     string     `bson:"org_id,omitempty"`
+	Name      string     `bson:"name"`
+	Hash      string     `bson:"hash"`
+	Hint      string     `bson:"hint"`
+	Scopes    []string   `bson:"scopes"`
+	CreatedAt time.Time  `bson:"created_at"`
+	RevokedAt *time.Time `bson:"revoked_at,omitempty"`
+}
+
+// APIKeyRepository is the APIKeyGateway on the api_keys collection.
+type APIKeyRepository struct {
+	keys *mongo.Collection
+}
+
+func NewAPIKeyRepository(db *mongo.Database) *APIKeyRepository {
+	return &APIKeyRepository{keys: db.Collection(apiKeysCollection)}
+}
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *entity.APIKey) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.keys.InsertOne(ctx, apiKeyDocument{
+		Namespace: ns,
+		ID:        key.ID,
+		UserID:    key.UserID,
+		OrgID:     key.OrgID,
+		Name:      key.Name,
+		Hash:      key.Hash,
+		Hint:      key.Hint,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: timePtr(key.RevokedAt),
+	})
+	return err
+}
+
+func (r *APIKeyRepository) FindAPIKeyByID(ctx context.Context, id string) (*entity.APIKey, error) {
+	return r.findAPIKey(ctx, "id", id)
+}
+
+func (r *APIKeyRepository) FindAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	return r.findAPIKey(ctx, "hash", hash)
+}
+
+func (r *APIKeyRepository) findAPIKey(ctx context.Context, field, value string) (*entity.APIKey, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var doc apiKeyDocument
+	err = r.keys.FindOne(ctx, bson.M{"namespace": ns, field: value}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, gateway.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.apiKey(), nil
+}
+
+func (r *APIKeyRepository) ListAPIKeysByUser(ctx context.Context, userID string) ([]*entity.APIKey, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "id", Value: 1}})
+	cursor, err := r.keys.Find(ctx, bson.M{"namespace": ns, "user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []apiKeyDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	keys := make([]*entity.APIKey, 0, len(docs))
+	for _, doc := range docs {
+		keys = append(keys, doc.apiKey())
+	}
+	return keys, nil
+}
+
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, key *entity.APIKey) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := r.keys.UpdateOne(ctx, bson.M{"namespace": ns, "id": key.ID},
+		bson.M{"$set": bson.M{"revoked_at": timePtr(key.RevokedAt)}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return gateway.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (doc apiKeyDocument) apiKey() *entity.APIKey {
+	key := &entity.APIKey{
+		ID:        doc.ID,
+		UserID:    doc.UserID,
+		OrgID:     doc.OrgID,
+		Name:      doc.Name,
+		Hash:      doc.Hash,
+		Hint:      doc.Hint,
+		Scopes:    doc.Scopes,
+		CreatedAt: doc.CreatedAt,
+	}
+	if doc.RevokedAt != nil {
+		key.RevokedAt = *doc.RevokedAt
+	}
+	return key
+}
@@ -0,0 +1,146 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type auditEntryDocument struct {
+	Namespace        string    `bson:"namespace"`
+	ID               string    `bson:"id"`
+	CreatedAt        time.Time `bson:"created_at"`
+	OrgID            string    `bson:"org_id"`
+	UserID           string    `bson:"user_id"`
+	ChatID           string    `bson:"chat_id"`
+	MessageID        string    `bson:"message_id"`
+	RequestID        string    `bson:"request_id"`
+	Model            string    `bson:"model"`
+	Redaction        string    `bson:"redaction"`
+	Prompt           string    `bson:"prompt"`
+	Response         string    `bson:"response"`
+	PromptTokens     int       `bson:"prompt_tokens"`
+	CompletionTokens int       `bson:"completion_tokens"`
+	FinishReason     string    `bson:"finish_reason"`
+}
+
+// AuditLogRepository is the AuditLogGateway on the audit_log collection, it
+// only inserts documents. The prompts and responses are encrypted with
+// cipher, when set, like the content of the messages.
+type AuditLogRepository struct {
+	auditLog *mongo.Collection
+	cipher   ContentCipher
+}
+
+func NewAuditLogRepository(db *mongo.Database, cipher ContentCipher) *AuditLogRepository {
+	return &AuditLogRepository{auditLog: db.Collection(auditLogCollection), cipher: cipher}
+}
+
+func (r *AuditLogRepository) AppendAuditEntry(ctx context.Context, entry *entity.AuditEntry) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	doc := auditEntryDocument{
+		Namespace:        ns,
+		ID:               entry.ID,
+		CreatedAt:        entry.CreatedAt,
+		OrgID:            entry.OrgID,
+		UserID:           entry.UserID,
+		ChatID:           entry.ChatID,
+		MessageID:        entry.MessageID,
+		RequestID:        entry.RequestID,
+		Model:            entry.Model,
+		Redaction:        string(entry.Redaction),
+		Prompt:           entry.Prompt,
+		Response:         entry.Response,
+		PromptTokens:     entry.PromptTokens,
+		CompletionTokens: entry.CompletionTokens,
+		FinishReason:     entry.FinishReason,
+	}
+	if r.cipher != nil && entry.Redaction != entity.AuditRedactionHash {
+		if doc.Prompt, err = r.cipher.Encrypt(ctx, entry.OrgID, doc.Prompt); err != nil {
+			return fmt.Errorf("error encrypting audit prompt: %s", err.Error())
+		}
+		if doc.Response, err = r.cipher.Encrypt(ctx, entry.OrgID, doc.Response); err != nil {
+			return fmt.Errorf("error encrypting audit response: %s", err.Error())
+		}
+	}
+	_, err = r.auditLog.InsertOne(ctx, doc)
+	return err
+}
+
+func (r *AuditLogRepository) ListAuditEntries(ctx context.Context, filter gateway.AuditFilter) ([]*entity.AuditEntry, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := bson.M{"namespace": ns}
+	if filter.OrgID != "" {
+		query["org_id"] = filter.OrgID
+	}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.ChatID != "" {
+		query["chat_id"] = filter.ChatID
+	}
+	createdAt := bson.M{}
+	if !filter.From.IsZero() {
+		createdAt["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		createdAt["$lt"] = filter.To
+	}
+	if len(createdAt) > 0 {
+		query["created_at"] = createdAt
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "id", Value: -1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	cursor, err := r.auditLog.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []auditEntryDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	entries := make([]*entity.AuditEntry, 0, len(docs))
+	for _, doc := range docs {
+		e := &entity.AuditEntry{
+			ID:               doc.ID,
+			CreatedAt:        doc.CreatedAt.UTC(),
+			OrgID:            doc.OrgID,
+			UserID:           doc.UserID,
+			ChatID:           doc.ChatID,
+			MessageID:        doc.MessageID,
+			RequestID:        doc.RequestID,
+			Model:            doc.Model,
+			Redaction:        entity.AuditRedaction(doc.Redaction),
+			Prompt:           doc.Prompt,
+			Response:         doc.Response,
+			PromptTokens:     doc.PromptTokens,
+			CompletionTokens: doc.CompletionTokens,
+			FinishReason:     doc.FinishReason,
+		}
+		if r.cipher != nil {
+			if e.Prompt, err = r.cipher.Decrypt(ctx, e.Prompt); err != nil {
+				return nil, err
+			}
+			if e.Response, err = r.cipher.Decrypt(ctx, e.Response); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,345 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ChatRepository is the MongoDB ChatGateway, one document per chat. Writing
+// the outbox with the chat needs transactions, so a replica set.
+type ChatRepository struct {
+	client *mongo.Client
+	chats  *mongo.Collection
+	outbox *mongo.Collection
+	cipher ContentCipher
+	clock  clock.Clock
+}
+
+// ContentCipher encrypts message content before it is stored and decrypts it
+// when read, see package encryption.
+type ContentCipher interface {
+	Encrypt(ctx context.Context, orgID string, plaintext string) (string, error)
+	Decrypt(ctx context.Context, stored string) (string, error)
+}
+
+type ChatRepositoryOption func(r *ChatRepository)
+
+// WithContentCipher stores message content encrypted with cipher.
+func WithContentCipher(cipher ContentCipher) ChatRepositoryOption {
+	return func(r *ChatRepository) {
+		r.cipher = cipher
+	}
+}
+
+// WithClock stamps the outbox entries and the partial updates with clk
+// instead of the real clock.
+func WithClock(clk clock.Clock) ChatRepositoryOption {
+	return func(r *ChatRepository) {
+		r.clock = clk
+	}
+}
+
+func NewChatRepository(client *mongo.Client, db *mongo.Database, opts ...ChatRepositoryOption) *ChatRepository {
+	r := &ChatRepository{
+		client: client,
+		chats:  db.Collection(chatsCollection),
+		outbox: db.Collection(outboxCollection),
+		clock:  clock.Real(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *ChatRepository) CreateChat(ctx context.Context, chat *entity.Chat) error {
+	return r.saveChat(ctx, chat, func(ctx context.Context, doc chatDocument) error {
+		_, err := r.chats.InsertOne(ctx, doc)
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.New("chat already exists")
+		}
+		return err
+	})
+}
+
+func (r *ChatRepository) SaveChat(ctx context.Context, chat *entity.Chat) error {
+	return r.saveChat(ctx, chat, func(ctx context.Context, doc chatDocument) error {
+		var previous interface{} = chat.Version
+		if chat.Version == 0 {
+			// documents written before versioning have no version field
+			previous = bson.M{"$in": bson.A{0, nil}}
+		}
+		// a stale version matches nothing, and the upsert then collides with
+		// the unique (namespace, id) index
+		_, err := r.chats.ReplaceOne(ctx, bson.M{"namespace": doc.Namespace, "id": doc.ID, "version": previous},
+			doc, options.Replace().SetUpsert(true))
+		if mongo.IsDuplicateKeyError(err) {
+			return gateway.ErrChatConflict
+		}
+		return err
+	})
+}
+
+func (r *ChatRepository) saveChat(ctx context.Context, chat *entity.Chat, write func(ctx context.Context, doc chatDocument) error) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	entries, err := entity.NewOutboxEntries(chat.ID, chat.PendingEvents(), r.clock.Now())
+	if err != nil {
+		return err
+	}
+	doc := newChatDocument(ns, chat)
+	doc.Version = chat.Version + 1
+	if r.cipher != nil {
+		for i := range doc.Messages {
+			if doc.Messages[i].Content, err = r.cipher.Encrypt(ctx, chat.OrgID, doc.Messages[i].Content); err != nil {
+				return fmt.Errorf("error encrypting message content: %s", err.Error())
+			}
+		}
+	}
+	return inTx(ctx, r.client, func(ctx context.Context) error {
+		if err := write(ctx, doc); err != nil {
+			return err
+		}
+		return insertOutboxEntries(ctx, r.outbox, ns, entries)
+	}, func() {
+		chat.Version = doc.Version
+		chat.MarkEventsSaved()
+	})
+}
+
+func (r *ChatRepository) FindChatByID(ctx context.Context, chatID string) (*entity.Chat, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var doc chatDocument
+	err = r.chats.FindOne(ctx, bson.M{"namespace": ns, "id": chatID, "deleted_at": nil}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, gateway.ErrChatNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.toEntity(ctx, doc)
+}
+
+func (r *ChatRepository) FindChatsByParentID(ctx context.Context, parentChatID string) ([]*entity.Chat, error) {
+	return r.findChats(ctx, bson.M{"parent_chat_id": parentChatID, "deleted_at": nil},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+}
+
+func (r *ChatRepository) ListChats(ctx context.Context, filter gateway.ChatFilter) ([]*entity.Chat, error) {
+	query := bson.M{"deleted_at": nil}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.SourceID != "" {
+		query["source_id"] = filter.SourceID
+	}
+	if filter.Tag != "" {
+		query["tags"] = filter.Tag
+	}
+	created := bson.M{}
+	if !filter.CreatedAfter.IsZero() {
+		created["$gt"] = filter.CreatedAfter
+	}
+	if !filter.CreatedBefore.IsZero() {
+		created["$lt"] = filter.CreatedBefore
+	}
+	if len(created) > 0 {
+		query["created_at"] = created
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}, {Key: "id", Value: 1}}).
+		SetProjection(bson.M{"messages": 0})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		opts.SetSkip(int64(filter.Offset))
+	}
+	return r.findChats(ctx, query, opts)
+}
+
+func (r *ChatRepository) ListChatsByUser(ctx context.Context, userID string, page int, size int) (*gateway.ChatPage, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	chats, err := r.findChats(ctx, bson.M{"user_id": userID, "deleted_at": nil}, options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}, {Key: "id", Value: 1}}).
+		SetProjection(bson.M{"messages": 0}).
+		SetSkip(int64((page-1)*size)).
+		SetLimit(int64(size)))
+	if err != nil {
+		return nil, err
+	}
+	total, err := r.chats.CountDocuments(ctx, bson.M{"namespace": ns, "user_id": userID, "deleted_at": nil})
+	if err != nil {
+		return nil, err
+	}
+	return &gateway.ChatPage{Chats: chats, Total: int(total)}, nil
+}
+
+// ListMessages cuts the page from the chat document, messages are embedded.
+func (r *ChatRepository) ListMessages(ctx context.Context, chatID string, cursor gateway.MessageCursor) (*gateway.MessagePage, error) {
+	chat, err := r.FindChatByID(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	end := len(chat.Messages)
+	if cursor.Before != "" {
+		end = -1
+		for i, m := range chat.Messages {
+			if m.ID == cursor.Before {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return nil, gateway.ErrInvalidCursor
+		}
+	}
+	page := &gateway.MessagePage{ChatUserID: chat.UserID}
+	start := 0
+	if cursor.Limit > 0 && end > cursor.Limit {
+		start = end - cursor.Limit
+		page.Next = &gateway.MessageCursor{Before: chat.Messages[start].ID, Limit: cursor.Limit}
+	}
+	page.Messages = chat.Messages[start:end]
+	return page, nil
+}
+
+func (r *ChatRepository) FindExpiredChats(ctx context.Context, expiredBefore time.Time, limit int) ([]*entity.Chat, error) {
+	return r.findChats(ctx, bson.M{
+		"expires_at": bson.M{"$lt": expiredBefore},
+		"status":     bson.M{"$ne": entity.ChatStatusArchived},
+		"deleted_at": nil,
+	}, options.Find().SetSort(bson.D{{Key: "expires_at", Value: 1}}).SetLimit(int64(limit)))
+}
+
+func (r *ChatRepository) findChats(ctx context.Context, query bson.M, opts *options.FindOptions) ([]*entity.Chat, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query["namespace"] = ns
+	cursor, err := r.chats.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []chatDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	chats := make([]*entity.Chat, 0, len(docs))
+	for _, doc := range docs {
+		chat, err := r.toEntity(ctx, doc)
+		if err != nil {
+			return nil, err
+		}
+		chats = append(chats, chat)
+	}
+	return chats, nil
+}
+
+func (r *ChatRepository) toEntity(ctx context.Context, doc chatDocument) (*entity.Chat, error) {
+	if r.cipher != nil {
+		for i := range doc.Messages {
+			content, err := r.cipher.Decrypt(ctx, doc.Messages[i].Content)
+			if err != nil {
+				return nil, err
+			}
+			doc.Messages[i].Content = content
+		}
+	}
+	return doc.toEntity()
+}
+
+func (r *ChatRepository) UpdateChatTitle(ctx context.Context, chatID string, title string) error {
+	return r.updateChat(ctx, chatID, bson.M{"$set": bson.M{"title": title, "updated_at": r.clock.Now()}})
+}
+
+func (r *ChatRepository) AddChatTag(ctx context.Context, chatID string, tag string) error {
+	return r.updateChat(ctx, chatID, bson.M{"$addToSet": bson.M{"tags": tag}})
+}
+
+func (r *ChatRepository) RemoveChatTag(ctx context.Context, chatID string, tag string) error {
+	return r.updateChat(ctx, chatID, bson.M{"$pull": bson.M{"tags": tag}})
+}
+
+func (r *ChatRepository) DeleteChat(ctx context.Context, chatID string, deletedAt time.Time) error {
+	return r.updateChat(ctx, chatID, bson.M{"$set": bson.M{"deleted_at": deletedAt}})
+}
+
+func (r *ChatRepository) SetMessageFlags(ctx context.Context, chatID string, messageID string, pinned bool, starred bool) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := r.chats.UpdateOne(ctx,
+		bson.M{"namespace": ns, "id": chatID, "messages.id": messageID},
+		bson.M{"$set": bson.M{"messages.$.pinned": pinned, "messages.$.starred": starred}, "$inc": bson.M{"version": 1}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return entity.ErrMessageNotFound
+	}
+	return nil
+}
+
+func (r *ChatRepository) updateChat(ctx context.Context, chatID string, update bson.M) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	update["$inc"] = bson.M{"version": 1}
+	result, err := r.chats.UpdateOne(ctx, bson.M{"namespace": ns, "id": chatID, "deleted_at": nil}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return gateway.ErrChatNotFound
+	}
+	return nil
+}
+
+func (r *ChatRepository) PurgeDeletedChats(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return r.purge(ctx, bson.M{"deleted_at": bson.M{"$lt": deletedBefore}})
+}
+
+func (r *ChatRepository) PurgeExpiredChats(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	return r.purge(ctx, bson.M{"expires_at": bson.M{"$lt": expiredBefore}, "status": entity.ChatStatusArchived})
+}
+
+func (r *ChatRepository) purge(ctx context.Context, query bson.M) (int64, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	query["namespace"] = ns
+	result, err := r.chats.DeleteMany(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
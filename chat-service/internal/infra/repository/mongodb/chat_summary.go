@@ -0,0 +1,182 @@
+package mongodb
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type chatSummaryDocument struct {
+	Namespace     string     `bson:"namespace"`
+	ChatID        string     `bson:"chat_id"`
+	UserID        string     `bson:"user_id"`
+	ParentChatID  string     `bson:"parent_chat_id,omitempty"`
+	Title         string     `bson:"title"`
+	Status        string     `bson:"status"`
+	Tags          []string   `bson:"tags"`
+	Model         string     `bson:"model,omitempty"`
+	MessageCount  int        `bson:"message_count"`
+	TokenUsage    int        `bson:"token_usage"`
+	LastMessageAt *time.Time `bson:"last_message_at,omitempty"`
+	CreatedAt     time.Time  `bson:"created_at"`
+	UpdatedAt     time.Time  `bson:"updated_at"`
+	Version       int        `bson:"version"`
+	ProjectedAt   time.Time  `bson:"projected_at"`
+}
+
+// ChatSummaryRepository is the ChatReadModel on the chat_summaries
+// collection, db may be another database than the one of the chats.
+type ChatSummaryRepository struct {
+	summaries *mongo.Collection
+}
+
+func NewChatSummaryRepository(db *mongo.Database) *ChatSummaryRepository {
+	return &ChatSummaryRepository{summaries: db.Collection(chatSummariesCollection)}
+}
+
+func (r *ChatSummaryRepository) UpsertChatSummary(ctx context.Context, s *gateway.ChatSummary) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	doc := chatSummaryDocument{
+		Namespace:     ns,
+		ChatID:        s.ChatID,
+		UserID:        s.UserID,
+		ParentChatID:  s.ParentChatID,
+		Title:         s.Title,
+		Status:        s.Status,
+		Tags:          s.Tags,
+		Model:         s.Model,
+		MessageCount:  s.MessageCount,
+		TokenUsage:    s.TokenUsage,
+		LastMessageAt: timePtr(s.LastMessageAt),
+		CreatedAt:     s.CreatedAt,
+		UpdatedAt:     s.UpdatedAt,
+		Version:       s.Version,
+		ProjectedAt:   s.ProjectedAt,
+	}
+	if doc.Tags == nil {
+		doc.Tags = []string{}
+	}
+	_, err = r.summaries.ReplaceOne(ctx,
+		bson.M{"namespace": ns, "chat_id": s.ChatID, "version": bson.M{"$lte": s.Version}},
+		doc, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// the stored summary is of a newer version, the filter missed it
+		return nil
+	}
+	return err
+}
+
+func (r *ChatSummaryRepository) DeleteChatSummary(ctx context.Context, chatID string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.summaries.DeleteOne(ctx, bson.M{"namespace": ns, "chat_id": chatID})
+	return err
+}
+
+func (r *ChatSummaryRepository) ListChatSummaries(ctx context.Context, filter gateway.ChatSummaryFilter) ([]*gateway.ChatSummary, error) {
+	query, err := summaryQuery(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}, {Key: "chat_id", Value: 1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	if filter.Offset > 0 {
+		opts.SetSkip(int64(filter.Offset))
+	}
+	cursor, err := r.summaries.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []chatSummaryDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	summaries := make([]*gateway.ChatSummary, 0, len(docs))
+	for _, doc := range docs {
+		s := &gateway.ChatSummary{
+			ChatID:       doc.ChatID,
+			UserID:       doc.UserID,
+			ParentChatID: doc.ParentChatID,
+			Title:        doc.Title,
+			Status:       doc.Status,
+			Tags:         doc.Tags,
+			Model:        doc.Model,
+			MessageCount: doc.MessageCount,
+			TokenUsage:   doc.TokenUsage,
+			CreatedAt:    doc.CreatedAt,
+			UpdatedAt:    doc.UpdatedAt,
+			Version:      doc.Version,
+			ProjectedAt:  doc.ProjectedAt,
+		}
+		if doc.LastMessageAt != nil {
+			s.LastMessageAt = *doc.LastMessageAt
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, nil
+}
+
+func (r *ChatSummaryRepository) CountChatSummaries(ctx context.Context, filter gateway.ChatSummaryFilter) (int, error) {
+	query, err := summaryQuery(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	n, err := r.summaries.CountDocuments(ctx, query)
+	return int(n), err
+}
+
+func (r *ChatSummaryRepository) PurgeStaleChatSummaries(ctx context.Context, projectedBefore time.Time) (int64, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	result, err := r.summaries.DeleteMany(ctx, bson.M{"namespace": ns, "projected_at": bson.M{"$lt": projectedBefore}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func summaryQuery(ctx context.Context, filter gateway.ChatSummaryFilter) (bson.M, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := bson.M{"namespace": ns}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Tag != "" {
+		query["tags"] = filter.Tag
+	}
+	if filter.Query != "" {
+		query["title"] = bson.M{"$regex": regexp.QuoteMeta(filter.Query), "$options": "i"}
+	}
+	created := bson.M{}
+	if !filter.CreatedAfter.IsZero() {
+		created["$gt"] = filter.CreatedAfter
+	}
+	if !filter.CreatedBefore.IsZero() {
+		created["$lt"] = filter.CreatedBefore
+	}
+	if len(created) > 0 {
+		query["created_at"] = created
+	}
+	return query, nil
+}
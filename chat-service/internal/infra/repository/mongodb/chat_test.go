@@ -0,0 +1,30 @@
+package mongodb
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/repositorytest"
+)
+
+// TestChatRepositoryOnMongoDB runs on the chat_service_test database of the
+// server at MONGO_URL, a replica set for the transactions, in namespaces of
+// its own. It is skipped without one.
+func TestChatRepositoryOnMongoDB(t *testing.T) {
+	url := os.Getenv("MONGO_URL")
+	if url == "" {
+		t.Skip("MONGO_URL is not set")
+	}
+	ctx := context.Background()
+	client, err := Connect(ctx, url)
+	if err != nil {
+		t.Fatalf("Connect(): %v", err)
+	}
+	defer client.Disconnect(ctx)
+	db := client.Database("chat_service_test")
+	if err := EnsureIndexes(ctx, db); err != nil {
+		t.Fatalf("EnsureIndexes(): %v", err)
+	}
+	repositorytest.TestChatGateway(t, NewChatRepository(client, db), NewNamespaceRepository(db))
+}
@@ -0,0 +1,58 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type dataKeyDocument struct {
+	Namespace  string    `bson:"namespace"`
+	WrappedKey []byte    `bson:"wrapped_key"`
+	CreatedAt  time.Time `bson:"created_at"`
+}
+
+// DataKeyRepository is the encryption.KeyStore on the data_keys collection.
+// It runs outside of any unit of work so a data key is never rolled back with
+// content it already encrypted.
+type DataKeyRepository struct {
+	keys *mongo.Collection
+}
+
+func NewDataKeyRepository(db *mongo.Database) *DataKeyRepository {
+	return &DataKeyRepository{keys: db.Collection(dataKeysCollection)}
+}
+
+func (r *DataKeyRepository) FindDataKey(ctx context.Context, ns string) ([]byte, bool, error) {
+	var doc dataKeyDocument
+	err := r.keys.FindOne(withoutSession{ctx}, bson.M{"namespace": ns}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return doc.WrappedKey, true, nil
+}
+
+func (r *DataKeyRepository) CreateDataKey(ctx context.Context, ns string, wrapped []byte) ([]byte, error) {
+	_, err := r.keys.InsertOne(withoutSession{ctx}, dataKeyDocument{Namespace: ns, WrappedKey: wrapped, CreatedAt: time.Now()})
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+	stored, _, err := r.FindDataKey(ctx, ns)
+	return stored, err
+}
+
+// withoutSession keeps the deadline and cancellation of a context but none of
+// its values, so the session of a unit of work the context is in.
+type withoutSession struct {
+	context.Context
+}
+
+func (withoutSession) Value(key interface{}) interface{} {
+	return nil
+}
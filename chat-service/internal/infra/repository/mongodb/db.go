@@ -0,0 +1,134 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	chatsCollection             = "chats"
+	outboxCollection            = "outbox"
+	dataKeysCollection          = "data_keys"
+	userErasuresCollection      = "user_erasures"
+	chatSummariesCollection     = "chat_summaries"
+	apiKeysCollection           = "api_keys"
+	webhooksCollection          = "webhooks"
+	webhookDeliveriesCollection = "webhook_deliveries"
+	idempotencyKeysCollection   = "idempotency_keys"
+	usageCollection             = "usage_records"
+	auditLogCollection          = "audit_log"
+	draftsCollection            = "drafts"
+)
+
+func Connect(ctx context.Context, uri string) (*mongo.Client, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to mongodb: %s", err.Error())
+	}
+	return client, nil
+}
+
+// EnsureIndexes creates the indexes the repositories query with, it is safe
+// to run on every start.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(chatsCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "user_id", Value: 1}, {Key: "updated_at", Value: -1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "parent_chat_id", Value: 1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "tags", Value: 1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "messages.id", Value: 1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "messages.annotations.user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "deleted_at", Value: 1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "expires_at", Value: 1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "updated_at", Value: 1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "org_id", Value: 1}, {Key: "updated_at", Value: 1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "user_id", Value: 1}, {Key: "source_id", Value: 1}},
+			Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "user_id", Value: 1}, {Key: "messages.content", Value: "text"}},
+			Options: options.Index().SetName("messages_text")},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating chat indexes: %s", err.Error())
+	}
+	_, err = db.Collection(outboxCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "published_at", Value: 1}, {Key: "dead_lettered_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "chat_id", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating outbox indexes: %s", err.Error())
+	}
+	_, err = db.Collection(dataKeysCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "namespace", Value: 1}}, Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("error creating data key indexes: %s", err.Error())
+	}
+	_, err = db.Collection(chatSummariesCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "chat_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "user_id", Value: 1}, {Key: "updated_at", Value: -1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "projected_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating chat summary indexes: %s", err.Error())
+	}
+	_, err = db.Collection(apiKeysCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating api key indexes: %s", err.Error())
+	}
+	_, err = db.Collection(webhooksCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "org_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating webhook indexes: %s", err.Error())
+	}
+	_, err = db.Collection(webhookDeliveriesCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "webhook_id", Value: 1}, {Key: "failed_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating webhook delivery indexes: %s", err.Error())
+	}
+	_, err = db.Collection(idempotencyKeysCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "namespace", Value: 1}, {Key: "user_id", Value: 1}, {Key: "key", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("error creating idempotency key indexes: %s", err.Error())
+	}
+	_, err = db.Collection(usageCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "day", Value: 1}, {Key: "org_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "model", Value: 1}},
+			Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "org_id", Value: 1}, {Key: "day", Value: 1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "user_id", Value: 1}, {Key: "day", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating usage indexes: %s", err.Error())
+	}
+	_, err = db.Collection(auditLogCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "org_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "namespace", Value: 1}, {Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating audit log indexes: %s", err.Error())
+	}
+	_, err = db.Collection(draftsCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "namespace", Value: 1}, {Key: "chat_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("error creating draft indexes: %s", err.Error())
+	}
+	return nil
+}
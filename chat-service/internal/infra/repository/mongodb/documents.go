@@ -0,0 +1,316 @@
+package mongodb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+const (
+	messageActive  = "active"
+	messageErased  = "erased"
+	messageInitial = "initial"
+)
+
+// chatDocument is a chat with all its messages embedded. Messages of every
+// state share the array so annotations and attachments can be updated with
+// the positional operators.
+type chatDocument struct {
+	Namespace           string            `bson:"namespace"`
+	ID                  string            `bson:"id"`
+	UserID              string            `bson:"user_id"`
+	Title               string            `bson:"title"`
+	ParentChatID        string            `bson:"parent_chat_id"`
+	ForkedFromMessageID string            `bson:"forked_from_message_id"`
+	PersonaID           string            `bson:"persona_id"`
+	OrgID               string            `bson:"org_id"`
+	SourceID            string            `bson:"source_id,omitempty"`
+	InitialMessageID    string            `bson:"initial_message_id"`
+	Status              string            `bson:"status"`
+	AnswerStage         string            `bson:"answer_stage"`
+	SystemFingerprint   string            `bson:"system_fingerprint"`
+	SummaryMessageID    string            `bson:"summary_message_id"`
+	TokenUsage          int               `bson:"token_usage"`
+	Config              configDocument    `bson:"config"`
+	Tags                []string          `bson:"tags"`
+	Messages            []messageDocument `bson:"messages,omitempty"`
+	CreatedAt           time.Time         `bson:"created_at"`
+	UpdatedAt           time.Time         `bson:"updated_at"`
+	DeletedAt           *time.Time        `bson:"deleted_at,omitempty"`
+	ExpiresAt           *time.Time        `bson:"expires_at,omitempty"`
+	Version             int               `bson:"version"`
+}
+
+type configDocument struct {
+	ModelName        string   `bson:"model_name"`
+	ModelMaxTokens   int      `bson:"model_max_tokens"`
+	Temperature      float32  `bson:"temperature"`
+	TopP             float32  `bson:"top_p"`
+	N                int      `bson:"n"`
+	Stop             []string `bson:"stop,omitempty"`
+	MaxTokens        int      `bson:"max_tokens"`
+	PresencePenalty  float32  `bson:"presence_penalty"`
+	FrequencyPenalty float32  `bson:"frequency_penalty"`
+	AnswerMode       string   `bson:"answer_mode,omitempty"`
+	Deterministic    bool     `bson:"deterministic,omitempty"`
+	MaxMessages      int      `bson:"max_messages,omitempty"`
+	EvictionPolicy   string   `bson:"eviction_policy,omitempty"`
+}
+
+type messageDocument struct {
+	ID                  string               `bson:"id"`
+	State               string               `bson:"state"`
+	Role                string               `bson:"role"`
+	Content             string               `bson:"content"`
+	Tokens              int                  `bson:"tokens"`
+	ModelName           string               `bson:"model_name"`
+	ModelMaxTokens      int                  `bson:"model_max_tokens"`
+	PromptTokens        int                  `bson:"prompt_tokens,omitempty"`
+	TimeToFirstTokenNS  int64                `bson:"time_to_first_token_ns,omitempty"`
+	GenerationLatencyNS int64                `bson:"generation_latency_ns,omitempty"`
+	Pinned              bool                 `bson:"pinned"`
+	Starred             bool                 `bson:"starred"`
+	IncompleteReason    string               `bson:"incomplete_reason,omitempty"`
+	CreatedAt           time.Time            `bson:"created_at"`
+	Annotations         []annotationDocument `bson:"annotations,omitempty"`
+	Attachments         []attachmentDocument `bson:"attachments,omitempty"`
+}
+
+type annotationDocument struct {
+	ID        string    `bson:"id"`
+	UserID    string    `bson:"user_id"`
+	Kind      string    `bson:"kind"`
+	Start     int       `bson:"start"`
+	End       int       `bson:"end"`
+	Text      string    `bson:"text"`
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+type attachmentDocument struct {
+	ID         string    `bson:"id"`
+	Name       string    `bson:"name"`
+	MimeType   string    `bson:"mime_type"`
+	Size       int64     `bson:"size"`
+	StorageKey string    `bson:"storage_key"`
+	CreatedAt  time.Time `bson:"created_at"`
+}
+
+func newChatDocument(ns string, chat *entity.Chat) chatDocument {
+	doc := chatDocument{
+		Namespace:           ns,
+		ID:                  chat.ID,
+		UserID:              chat.UserID,
+		Title:               chat.Title,
+		ParentChatID:        chat.ParentChatID,
+		ForkedFromMessageID: chat.ForkedFromMessageID,
+		PersonaID:           chat.PersonaID,
+		OrgID:               chat.OrgID,
+		SourceID:            chat.SourceID,
+		Status:              chat.Status,
+		AnswerStage:         chat.AnswerStage,
+		SystemFingerprint:   chat.SystemFingerprint,
+		SummaryMessageID:    chat.SummaryMessageID,
+		TokenUsage:          chat.TokenUsage,
+		Config:              newConfigDocument(chat.Config),
+		Tags:                append([]string{}, chat.Tags...),
+		CreatedAt:           chat.CreatedAt,
+		UpdatedAt:           chat.UpdatedAt,
+		DeletedAt:           timePtr(chat.DeletedAt),
+		ExpiresAt:           timePtr(chat.ExpiresAt),
+	}
+	seen := make(map[string]bool)
+	add := func(m *entity.Message, state string) {
+		if seen[m.ID] {
+			return
+		}
+		seen[m.ID] = true
+		doc.Messages = append(doc.Messages, newMessageDocument(m, state))
+	}
+	for _, m := range chat.Messages {
+		add(m, messageActive)
+	}
+	for _, m := range chat.ErasedMessages {
+		add(m, messageErased)
+	}
+	if chat.InitialSystemMessage != nil {
+		doc.InitialMessageID = chat.InitialSystemMessage.ID
+		add(chat.InitialSystemMessage, messageInitial)
+	}
+	return doc
+}
+
+func newConfigDocument(c *entity.ChatConfig) configDocument {
+	doc := configDocument{
+		Temperature:      c.Temperature,
+		TopP:             c.TopP,
+		N:                c.N,
+		Stop:             c.Stop,
+		MaxTokens:        c.MaxTokens,
+		PresencePenalty:  c.PresencePenalty,
+		FrequencyPenalty: c.FrequencyPenalty,
+		AnswerMode:       c.AnswerMode,
+		Deterministic:    c.Deterministic,
+		MaxMessages:      c.MaxMessages,
+		EvictionPolicy:   c.EvictionPolicy,
+	}
+	if c.Model != nil {
+		doc.ModelName = c.Model.Name
+		doc.ModelMaxTokens = c.Model.MaxToken
+	}
+	return doc
+}
+
+func newMessageDocument(m *entity.Message, state string) messageDocument {
+	doc := messageDocument{
+		ID:                  m.ID,
+		State:               state,
+		Role:                m.Role.String(),
+		Content:             m.Content,
+		Tokens:              m.Tokens,
+		PromptTokens:        m.PromptTokens,
+		TimeToFirstTokenNS:  int64(m.TimeToFirstToken),
+		GenerationLatencyNS: int64(m.GenerationLatency),
+		Pinned:              m.Pinned,
+		Starred:             m.Starred,
+		IncompleteReason:    m.IncompleteReason,
+		CreatedAt:           m.CreatedAt,
+	}
+	if m.Model != nil {
+		doc.ModelName = m.Model.Name
+		doc.ModelMaxTokens = m.Model.MaxToken
+	}
+	for _, a := range m.Annotations {
+		doc.Annotations = append(doc.Annotations, newAnnotationDocument(a))
+	}
+	for _, a := range m.Attachments {
+		doc.Attachments = append(doc.Attachments, newAttachmentDocument(a))
+	}
+	return doc
+}
+
+func newAnnotationDocument(a *entity.Annotation) annotationDocument {
+	return annotationDocument{
+		ID:        a.ID,
+		UserID:    a.UserID,
+		Kind:      a.Kind,
+		Start:     a.Start,
+		End:       a.End,
+		Text:      a.Text,
+		CreatedAt: a.CreatedAt,
+	}
+}
+
+func newAttachmentDocument(a *entity.Attachment) attachmentDocument {
+	return attachmentDocument{
+		ID:         a.ID,
+		Name:       a.Name,
+		MimeType:   a.MimeType,
+		Size:       a.Size,
+		StorageKey: a.StorageKey,
+		CreatedAt:  a.CreatedAt,
+	}
+}
+
+func (doc chatDocument) toEntity() (*entity.Chat, error) {
+	c := doc.Config
+	chat := &entity.Chat{
+		ID:                  doc.ID,
+		UserID:              doc.UserID,
+		Title:               doc.Title,
+		ParentChatID:        doc.ParentChatID,
+		ForkedFromMessageID: doc.ForkedFromMessageID,
+		PersonaID:           doc.PersonaID,
+		OrgID:               doc.OrgID,
+		SourceID:            doc.SourceID,
+		Status:              doc.Status,
+		AnswerStage:         doc.AnswerStage,
+		SystemFingerprint:   doc.SystemFingerprint,
+		SummaryMessageID:    doc.SummaryMessageID,
+		TokenUsage:          doc.TokenUsage,
+		Config: &entity.ChatConfig{
+			Model:            entity.NewModel(c.ModelName, c.ModelMaxTokens),
+			Temperature:      c.Temperature,
+			TopP:             c.TopP,
+			N:                c.N,
+			Stop:             c.Stop,
+			MaxTokens:        c.MaxTokens,
+			PresencePenalty:  c.PresencePenalty,
+			FrequencyPenalty: c.FrequencyPenalty,
+			AnswerMode:       c.AnswerMode,
+			Deterministic:    c.Deterministic,
+			MaxMessages:      c.MaxMessages,
+			EvictionPolicy:   c.EvictionPolicy,
+		},
+		Tags:      doc.Tags,
+		CreatedAt: doc.CreatedAt,
+		UpdatedAt: doc.UpdatedAt,
+		Version:   doc.Version,
+	}
+	if doc.DeletedAt != nil {
+		chat.DeletedAt = *doc.DeletedAt
+	}
+	if doc.ExpiresAt != nil {
+		chat.ExpiresAt = *doc.ExpiresAt
+	}
+	for _, md := range doc.Messages {
+		role, err := entity.ParseRole(md.Role)
+		if err != nil {
+			return nil, fmt.Errorf("message %s: %s", md.ID, err.Error())
+		}
+		m := &entity.Message{
+			ID:                md.ID,
+			Role:              role,
+			Content:           md.Content,
+			Tokens:            md.Tokens,
+			Model:             entity.NewModel(md.ModelName, md.ModelMaxTokens),
+			CreatedAt:         md.CreatedAt,
+			PromptTokens:      md.PromptTokens,
+			TimeToFirstToken:  time.Duration(md.TimeToFirstTokenNS),
+			GenerationLatency: time.Duration(md.GenerationLatencyNS),
+			Pinned:            md.Pinned,
+			Starred:           md.Starred,
+			IncompleteReason:  md.IncompleteReason,
+		}
+		for _, a := range md.Annotations {
+			m.AddAnnotation(&entity.Annotation{
+				ID:        a.ID,
+				MessageID: m.ID,
+				UserID:    a.UserID,
+				Kind:      a.Kind,
+				Start:     a.Start,
+				End:       a.End,
+				Text:      a.Text,
+				CreatedAt: a.CreatedAt,
+			})
+		}
+		for _, a := range md.Attachments {
+			m.AddAttachment(&entity.Attachment{
+				ID:         a.ID,
+				MessageID:  m.ID,
+				Name:       a.Name,
+				MimeType:   a.MimeType,
+				Size:       a.Size,
+				StorageKey: a.StorageKey,
+				CreatedAt:  a.CreatedAt,
+			})
+		}
+		switch md.State {
+		case messageActive:
+			chat.Messages = append(chat.Messages, m)
+		case messageErased:
+			chat.ErasedMessages = append(chat.ErasedMessages, m)
+		}
+		if m.ID == doc.InitialMessageID {
+			chat.InitialSystemMessage = m
+		}
+	}
+	return chat, nil
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,77 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type draftDocument struct {
+	Namespace string    `bson:"namespace"`
+	ChatID    string    `bson:"chat_id"`
+	UserID    string    `bson:"user_id"`
+	Content   string    `bson:"content"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// DraftRepository is the DraftGateway on the drafts collection.
+type DraftRepository struct {
+	drafts *mongo.Collection
+}
+
+func NewDraftRepository(db *mongo.Database) *DraftRepository {
+	return &DraftRepository{drafts: db.Collection(draftsCollection)}
+}
+
+func (r *DraftRepository) SaveDraft(ctx context.Context, draft *entity.Draft) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.drafts.ReplaceOne(ctx, bson.M{"namespace": ns, "chat_id": draft.ChatID, "user_id": draft.UserID},
+		draftDocument{
+			Namespace: ns,
+			ChatID:    draft.ChatID,
+			UserID:    draft.UserID,
+			Content:   draft.Content,
+			UpdatedAt: draft.UpdatedAt,
+		}, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (r *DraftRepository) FindDraft(ctx context.Context, chatID, userID string) (*entity.Draft, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var doc draftDocument
+	err = r.drafts.FindOne(ctx, bson.M{"namespace": ns, "chat_id": chatID, "user_id": userID}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, gateway.ErrDraftNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entity.Draft{
+		ChatID:    doc.ChatID,
+		UserID:    doc.UserID,
+		Content:   doc.Content,
+		UpdatedAt: doc.UpdatedAt,
+	}, nil
+}
+
+func (r *DraftRepository) DeleteDraft(ctx context.Context, chatID, userID string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.drafts.DeleteOne(ctx, bson.M{"namespace": ns, "chat_id": chatID, "user_id": userID})
+	return err
+}
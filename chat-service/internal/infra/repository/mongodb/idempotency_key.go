@@ -0,0 +1,101 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type idempotencyKeyDocument struct {
+	Namespace   string    `bson:"namespace"`
+	UserID      string    `bson:"user_id"`
+	Key         string    `bson:"key"`
+	RequestHash string    `bson:"request_hash"`
+	ChatID      string    `bson:"chat_id,omitempty"`
+	MessageID   string    `bson:"message_id,omitempty"`
+	CreatedAt   time.Time `bson:"created_at"`
+}
+
+// IdempotencyKeyRepository is the IdempotencyKeyGateway on the
+// idempotency_keys collection.
+type IdempotencyKeyRepository struct {
+	keys *mongo.Collection
+}
+
+func NewIdempotencyKeyRepository(db *mongo.Database) *IdempotencyKeyRepository {
+	return &IdempotencyKeyRepository{keys: db.Collection(idempotencyKeysCollection)}
+}
+
+func (r *IdempotencyKeyRepository) CreateIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.keys.InsertOne(ctx, idempotencyKeyDocument{
+		Namespace:   ns,
+		UserID:      key.UserID,
+		Key:         key.Key,
+		RequestHash: key.RequestHash,
+		ChatID:      key.ChatID,
+		MessageID:   key.MessageID,
+		CreatedAt:   key.CreatedAt,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return gateway.ErrIdempotencyKeyExists
+	}
+	return err
+}
+
+func (r *IdempotencyKeyRepository) FindIdempotencyKey(ctx context.Context, userID, key string) (*entity.IdempotencyKey, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var doc idempotencyKeyDocument
+	err = r.keys.FindOne(ctx, bson.M{"namespace": ns, "user_id": userID, "key": key}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, gateway.ErrIdempotencyKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entity.IdempotencyKey{
+		Key:         doc.Key,
+		UserID:      doc.UserID,
+		RequestHash: doc.RequestHash,
+		ChatID:      doc.ChatID,
+		MessageID:   doc.MessageID,
+		CreatedAt:   doc.CreatedAt,
+	}, nil
+}
+
+func (r *IdempotencyKeyRepository) CompleteIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := r.keys.UpdateOne(ctx, bson.M{"namespace": ns, "user_id": key.UserID, "key": key.Key},
+		bson.M{"$set": bson.M{"chat_id": key.ChatID, "message_id": key.MessageID}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return gateway.ErrIdempotencyKeyNotFound
+	}
+	return nil
+}
+
+func (r *IdempotencyKeyRepository) DeleteIdempotencyKey(ctx context.Context, userID, key string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.keys.DeleteOne(ctx, bson.M{"namespace": ns, "user_id": userID, "key": key})
+	return err
+}
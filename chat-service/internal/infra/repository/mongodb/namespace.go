@@ -0,0 +1,32 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type NamespaceRepository struct {
+	db *mongo.Database
+}
+
+func NewNamespaceRepository(db *mongo.Database) *NamespaceRepository {
+	return &NamespaceRepository{db: db}
+}
+
+// PurgeNamespace returns the number of chats, outbox entries, API keys,
+// webhooks, webhook deliveries, idempotency keys, usage records, audit
+// entries and drafts removed.
+func (r *NamespaceRepository) PurgeNamespace(ctx context.Context, namespace string) (int64, error) {
+	var purged int64
+	for _, collection := range []string{chatsCollection, outboxCollection, apiKeysCollection,
+		webhooksCollection, webhookDeliveriesCollection, idempotencyKeysCollection, usageCollection, auditLogCollection, draftsCollection} {
+		result, err := r.db.Collection(collection).DeleteMany(ctx, bson.M{"namespace": namespace})
+		if err != nil {
+			return purged, err
+		}
+		purged += result.DeletedCount
+	}
+	return purged, nil
+}
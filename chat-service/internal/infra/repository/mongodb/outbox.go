@@ -0,0 +1,104 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type outboxDocument struct {
+	Namespace   string     `bson:"namespace"`
+	ID          string     `bson:"id"`
+	ChatID      string     `bson:"chat_id"`
+	EventName   string     `bson:"event_name"`
+	Payload     string     `bson:"payload"`
+	OccurredAt  time.Time  `bson:"occurred_at"`
+	PublishedAt *time.Time `bson:"published_at,omitempty"`
+	Attempts    int        `bson:"attempts"`
+	LastError   string     `bson:"last_error"`
+	// DeadLetteredAt is set once the entry ran out of attempts.
+	DeadLetteredAt *time.Time `bson:"dead_lettered_at,omitempty"`
+}
+
+type OutboxRepository struct {
+	outbox *mongo.Collection
+}
+
+func NewOutboxRepository(db *mongo.Database) *OutboxRepository {
+	return &OutboxRepository{outbox: db.Collection(outboxCollection)}
+}
+
+func insertOutboxEntries(ctx context.Context, outbox *mongo.Collection, ns string, entries []*entity.OutboxEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(entries))
+	for _, e := range entries {
+		docs = append(docs, outboxDocument{
+			Namespace:  ns,
+			ID:         e.ID,
+			ChatID:     e.ChatID,
+			EventName:  e.EventName,
+			Payload:    string(e.Payload),
+			OccurredAt: e.OccurredAt,
+		})
+	}
+	_, err := outbox.InsertMany(ctx, docs)
+	return err
+}
+
+func (r *OutboxRepository) FindUnpublishedOutboxEntries(ctx context.Context, limit int) ([]*entity.OutboxEntry, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// _id is an ObjectID, which grows with insertion time
+	cursor, err := r.outbox.Find(ctx, bson.M{"namespace": ns, "published_at": nil, "dead_lettered_at": nil},
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	var docs []outboxDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	entries := make([]*entity.OutboxEntry, 0, len(docs))
+	for _, doc := range docs {
+		entries = append(entries, &entity.OutboxEntry{
+			ID:         doc.ID,
+			ChatID:     doc.ChatID,
+			EventName:  doc.EventName,
+			Payload:    []byte(doc.Payload),
+			OccurredAt: doc.OccurredAt,
+			Attempts:   doc.Attempts,
+			LastError:  doc.LastError,
+		})
+	}
+	return entries, nil
+}
+
+func (r *OutboxRepository) MarkOutboxEntryPublished(ctx context.Context, entryID string, publishedAt time.Time) error {
+	return r.update(ctx, entryID, bson.M{"$set": bson.M{"published_at": publishedAt}, "$inc": bson.M{"attempts": 1}})
+}
+
+func (r *OutboxRepository) MarkOutboxEntryFailed(ctx context.Context, entryID string, reason string) error {
+	return r.update(ctx, entryID, bson.M{"$set": bson.M{"last_error": reason}, "$inc": bson.M{"attempts": 1}})
+}
+
+func (r *OutboxRepository) MarkOutboxEntryDeadLettered(ctx context.Context, entryID string, reason string, deadLetteredAt time.Time) error {
+	return r.update(ctx, entryID, bson.M{"$set": bson.M{"last_error": reason, "dead_lettered_at": deadLetteredAt}, "$inc": bson.M{"attempts": 1}})
+}
+
+func (r *OutboxRepository) update(ctx context.Context, entryID string, update bson.M) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.outbox.UpdateOne(ctx, bson.M{"namespace": ns, "id": entryID}, update)
+	return err
+}
@@ -0,0 +1,95 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RetentionRepository is the MongoDB RetentionGateway.
+type RetentionRepository struct {
+	client *mongo.Client
+	chats  *mongo.Collection
+	outbox *mongo.Collection
+	drafts *mongo.Collection
+}
+
+func NewRetentionRepository(client *mongo.Client, db *mongo.Database) *RetentionRepository {
+	return &RetentionRepository{
+		client: client,
+		chats:  db.Collection(chatsCollection),
+		outbox: db.Collection(outboxCollection),
+		drafts: db.Collection(draftsCollection),
+	}
+}
+
+func (r *RetentionRepository) CountIdleChats(ctx context.Context, scope gateway.RetentionScope, idleBefore time.Time) (int64, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return r.chats.CountDocuments(ctx, idleFilter(ns, scope, idleBefore))
+}
+
+func (r *RetentionRepository) PurgeIdleChats(ctx context.Context, scope gateway.RetentionScope, idleBefore time.Time, limit int) (*gateway.RetentionPurge, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	purge := &gateway.RetentionPurge{}
+	err = inTx(ctx, r.client, func(ctx context.Context) error {
+		// WithTransaction may run this more than once
+		*purge = gateway.RetentionPurge{}
+		cursor, err := r.chats.Find(ctx, idleFilter(ns, scope, idleBefore),
+			options.Find().SetSort(bson.D{{Key: "updated_at", Value: 1}}).SetLimit(int64(limit)).
+				SetProjection(bson.M{"id": 1, "messages.attachments": 1}))
+		if err != nil {
+			return err
+		}
+		var docs []chatDocument
+		if err := cursor.All(ctx, &docs); err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+		for _, doc := range docs {
+			purge.ChatIDs = append(purge.ChatIDs, doc.ID)
+			for _, m := range doc.Messages {
+				for _, a := range m.Attachments {
+					purge.StorageKeys = append(purge.StorageKeys, a.StorageKey)
+				}
+			}
+		}
+		if _, err := r.outbox.DeleteMany(ctx, bson.M{"namespace": ns, "chat_id": bson.M{"$in": purge.ChatIDs}}); err != nil {
+			return err
+		}
+		if _, err := r.drafts.DeleteMany(ctx, bson.M{"namespace": ns, "chat_id": bson.M{"$in": purge.ChatIDs}}); err != nil {
+			return err
+		}
+		_, err = r.chats.DeleteMany(ctx, bson.M{"namespace": ns, "id": bson.M{"$in": purge.ChatIDs}})
+		return err
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return purge, nil
+}
+
+// idleFilter matches the chats of scope idle since before idleBefore. The
+// chats stored before they had a tenant have no org_id, $nin matches them.
+func idleFilter(ns string, scope gateway.RetentionScope, idleBefore time.Time) bson.M {
+	filter := bson.M{"namespace": ns, "updated_at": bson.M{"$lt": idleBefore}}
+	switch {
+	case !scope.Others:
+		filter["org_id"] = scope.OrgID
+	case len(scope.Except) > 0:
+		filter["org_id"] = bson.M{"$nin": scope.Except}
+	}
+	return filter
+}
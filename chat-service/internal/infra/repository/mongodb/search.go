@@ -0,0 +1,91 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/highlight"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// maxSearchedChats bounds the chats whose messages are scanned per search
+	maxSearchedChats = 50
+	snippetWidth     = 160
+)
+
+// SearchRepository runs on the text index of the chat documents. The index
+// finds the chats, the matching messages and their highlights are picked out
+// of them here.
+type SearchRepository struct {
+	chats *mongo.Collection
+}
+
+func NewSearchRepository(db *mongo.Database) *SearchRepository {
+	return &SearchRepository{chats: db.Collection(chatsCollection)}
+}
+
+func (r *SearchRepository) SearchMessages(ctx context.Context, query gateway.MessageSearchQuery) ([]*gateway.MessageSearchHit, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	terms := highlight.Terms(query.Text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	score := bson.M{"$meta": "textScore"}
+	cursor, err := r.chats.Find(ctx, bson.M{
+		"namespace":  ns,
+		"user_id":    query.UserID,
+		"deleted_at": nil,
+		"$text":      bson.M{"$search": query.Text},
+	}, options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(maxSearchedChats))
+	if err != nil {
+		return nil, err
+	}
+	var docs []chatDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	var hits []*gateway.MessageSearchHit
+	for _, doc := range docs {
+		chat, err := doc.toEntity()
+		if err != nil {
+			return nil, err
+		}
+		for i := len(chat.Messages) - 1; i >= 0; i-- {
+			m := chat.Messages[i]
+			if m.Role != entity.RoleUser && m.Role != entity.RoleAssistant {
+				continue
+			}
+			snippet, highlights, ok := highlight.Snippet(m.Content, terms, snippetWidth)
+			if !ok {
+				continue
+			}
+			hits = append(hits, &gateway.MessageSearchHit{
+				ChatID:        chat.ID,
+				ChatTitle:     chat.Title,
+				ChatUpdatedAt: chat.UpdatedAt,
+				Message:       m,
+				Snippet:       snippet,
+				Highlights:    highlights,
+			})
+		}
+	}
+	if query.Offset >= len(hits) {
+		return nil, nil
+	}
+	hits = hits[query.Offset:]
+	if query.Limit > 0 && len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, nil
+}
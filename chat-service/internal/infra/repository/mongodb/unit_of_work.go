@@ -0,0 +1,89 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type txKey struct{}
+
+type txState struct {
+	afterCommit []func()
+}
+
+// UnitOfWork is the gateway.UnitOfWork of the MongoDB repositories, it runs
+// on a session transaction so it needs a replica set too.
+type UnitOfWork struct {
+	client *mongo.Client
+}
+
+func NewUnitOfWork(client *mongo.Client) *UnitOfWork {
+	return &UnitOfWork{client: client}
+}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	session, err := u.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	var state *txState
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		// WithTransaction retries fn on transient errors, only the last run counts
+		state = &txState{}
+		return nil, fn(context.WithValue(sc, txKey{}, state))
+	})
+	if err != nil {
+		return err
+	}
+	for _, f := range state.afterCommit {
+		f()
+	}
+	return nil
+}
+
+func (u *UnitOfWork) AfterCommit(ctx context.Context, fn func()) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// inTx runs fn in the transaction of the unit of work ctx is in, or in its own
+// one, and afterCommit once that transaction is committed.
+func inTx(ctx context.Context, client *mongo.Client, fn func(ctx context.Context) error, afterCommit func()) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		if afterCommit == nil {
+			return nil
+		}
+		if state, ok := ctx.Value(txKey{}).(*txState); ok {
+			state.afterCommit = append(state.afterCommit, afterCommit)
+		} else {
+			afterCommit()
+		}
+		return nil
+	}
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	if err != nil {
+		return err
+	}
+	if afterCommit != nil {
+		afterCommit()
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type usageDocument struct {
+	Namespace        string    `bson:"namespace"`
+	Day              time.Time `bson:"day"`
+	OrgID            string    `bson:"org_id"`
+	UserID           string    `bson:"user_id"`
+	Model            string    `bson:"model"`
+	Turns            int64     `bson:"turns"`
+	PromptTokens     int64     `bson:"prompt_tokens"`
+	CompletionTokens int64     `bson:"completion_tokens"`
+	Cost             float64   `bson:"cost"`
+	UnpricedTurns    int64     `bson:"unpriced_turns"`
+}
+
+// UsageRepository is the UsageGateway on the usage_records collection.
+type UsageRepository struct {
+	usage *mongo.Collection
+}
+
+func NewUsageRepository(db *mongo.Database) *UsageRepository {
+	return &UsageRepository{usage: db.Collection(usageCollection)}
+}
+
+func (r *UsageRepository) RecordUsage(ctx context.Context, usage *entity.UsageRecord) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.usage.UpdateOne(ctx,
+		bson.M{
+			"namespace": ns,
+			"day":       entity.UsageDay(usage.Day),
+			"org_id":    usage.OrgID,
+			"user_id":   usage.UserID,
+			"model":     usage.Model,
+		},
+		bson.M{"$inc": bson.M{
+			"turns":             usage.Turns,
+			"prompt_tokens":     usage.PromptTokens,
+			"completion_tokens": usage.CompletionTokens,
+			"cost":              usage.Cost,
+			"unpriced_turns":    usage.UnpricedTurns,
+		}},
+		options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *UsageRepository) ListUsage(ctx context.Context, filter gateway.UsageFilter) ([]*entity.UsageRecord, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := bson.M{"namespace": ns}
+	if filter.OrgID != "" {
+		query["org_id"] = filter.OrgID
+	}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Model != "" {
+		query["model"] = filter.Model
+	}
+	day := bson.M{}
+	if !filter.From.IsZero() {
+		day["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		day["$lt"] = filter.To
+	}
+	if len(day) > 0 {
+		query["day"] = day
+	}
+	cursor, err := r.usage.Find(ctx, query, options.Find().SetSort(bson.D{
+		{Key: "day", Value: 1}, {Key: "org_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "model", Value: 1},
+	}))
+	if err != nil {
+		return nil, err
+	}
+	var docs []usageDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	records := make([]*entity.UsageRecord, 0, len(docs))
+	for _, doc := range docs {
+		records = append(records, &entity.UsageRecord{
+			Day:              doc.Day.UTC(),
+			OrgID:            doc.OrgID,
+			UserID:           doc.UserID,
+			Model:            doc.Model,
+			Turns:            doc.Turns,
+			PromptTokens:     doc.PromptTokens,
+			CompletionTokens: doc.CompletionTokens,
+			Cost:             doc.Cost,
+			UnpricedTurns:    doc.UnpricedTurns,
+		})
+	}
+	return records, nil
+}
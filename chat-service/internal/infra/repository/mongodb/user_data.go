@@ -0,0 +1,178 @@
+package mongodb
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type userErasureDocument struct {
+	Namespace   string    `bson:"namespace"`
+	ID          string    `bson:"id"`
+	UserID      string    `bson:"user_id"`
+	RequestedBy string    `bson:"requested_by"`
+	Chats       int64     `bson:"chats"`
+	Messages    int64     `bson:"messages"`
+	Annotations int64     `bson:"annotations"`
+	Attachments int64     `bson:"attachments"`
+	Embeddings  int64     `bson:"embeddings"`
+	CreatedAt   time.Time `bson:"created_at"`
+}
+
+// UserDataRepository is the MongoDB UserDataGateway. Embeddings are not
+// stored on MongoDB, the Embeddings count stays 0.
+type UserDataRepository struct {
+	client     *mongo.Client
+	chats      *mongo.Collection
+	outbox     *mongo.Collection
+	erasures   *mongo.Collection
+	deliveries *mongo.Collection
+	// byUser are the other collections keeping documents of the user, under
+	// their user_id
+	byUser []*mongo.Collection
+}
+
+func NewUserDataRepository(client *mongo.Client, db *mongo.Database) *UserDataRepository {
+	return &UserDataRepository{
+		client:     client,
+		chats:      db.Collection(chatsCollection),
+		outbox:     db.Collection(outboxCollection),
+		erasures:   db.Collection(userErasuresCollection),
+		deliveries: db.Collection(webhookDeliveriesCollection),
+		byUser: []*mongo.Collection{
+			db.Collection(chatSummariesCollection),
+			db.Collection(draftsCollection),
+			db.Collection(idempotencyKeysCollection),
+			db.Collection(usageCollection),
+			db.Collection(auditLogCollection),
+		},
+	}
+}
+
+func (r *UserDataRepository) PurgeUserData(ctx context.Context, erasure *entity.UserErasure) (*gateway.UserDataPurge, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	purge := &gateway.UserDataPurge{}
+	err = inTx(ctx, r.client, func(ctx context.Context) error {
+		// WithTransaction may run this more than once
+		*purge = gateway.UserDataPurge{}
+		erasure.Messages, erasure.Annotations, erasure.Attachments = 0, 0, 0
+		owned, err := r.find(ctx, bson.M{"namespace": ns, "user_id": erasure.UserID})
+		if err != nil {
+			return err
+		}
+		for _, doc := range owned {
+			purge.ChatIDs = append(purge.ChatIDs, doc.ID)
+			erasure.Messages += int64(len(doc.Messages))
+			for _, m := range doc.Messages {
+				erasure.Annotations += int64(len(m.Annotations))
+				for _, a := range m.Attachments {
+					purge.StorageKeys = append(purge.StorageKeys, a.StorageKey)
+				}
+			}
+		}
+		erasure.Attachments = int64(len(purge.StorageKeys))
+
+		// the annotations the user wrote on the chats of others
+		others := bson.M{"namespace": ns, "user_id": bson.M{"$ne": erasure.UserID}, "messages.annotations.user_id": erasure.UserID}
+		annotated, err := r.find(ctx, others)
+		if err != nil {
+			return err
+		}
+		for _, doc := range annotated {
+			for _, m := range doc.Messages {
+				for _, a := range m.Annotations {
+					if a.UserID == erasure.UserID {
+						erasure.Annotations++
+					}
+				}
+			}
+		}
+		if len(annotated) > 0 {
+			_, err = r.chats.UpdateMany(ctx, others, bson.M{
+				"$pull": bson.M{"messages.$[].annotations": bson.M{"user_id": erasure.UserID}},
+				"$inc":  bson.M{"version": 1},
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(purge.ChatIDs) > 0 {
+			// the events of the chats carry the user ID
+			if _, err := r.outbox.DeleteMany(ctx, bson.M{"namespace": ns, "chat_id": bson.M{"$in": purge.ChatIDs}}); err != nil {
+				return err
+			}
+		}
+		result, err := r.chats.DeleteMany(ctx, bson.M{"namespace": ns, "user_id": erasure.UserID})
+		if err != nil {
+			return err
+		}
+		erasure.Chats = result.DeletedCount
+		// the rest of what the service keeps on the user: the read model, the
+		// drafts, the replays of the idempotent requests, the usage, the audit
+		// trail of the prompts and the webhook payloads naming the user
+		for _, collection := range r.byUser {
+			if _, err := collection.DeleteMany(ctx, bson.M{"namespace": ns, "user_id": erasure.UserID}); err != nil {
+				return err
+			}
+		}
+		if _, err := r.deliveries.DeleteMany(ctx, bson.M{"namespace": ns, "payload": bson.M{"$regex": payloadUserPattern(erasure.UserID)}}); err != nil {
+			return err
+		}
+		_, err = r.erasures.InsertOne(ctx, userErasureDocument{
+			Namespace:   ns,
+			ID:          erasure.ID,
+			UserID:      erasure.UserID,
+			RequestedBy: erasure.RequestedBy,
+			Chats:       erasure.Chats,
+			Messages:    erasure.Messages,
+			Annotations: erasure.Annotations,
+			Attachments: erasure.Attachments,
+			Embeddings:  erasure.Embeddings,
+			CreatedAt:   erasure.CreatedAt,
+		})
+		return err
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return purge, nil
+}
+
+// find loads the ids, annotations and attachments of the messages of the
+// matching chats, not their content.
+func (r *UserDataRepository) find(ctx context.Context, query bson.M) ([]chatDocument, error) {
+	cursor, err := r.chats.Find(ctx, query, options.Find().SetProjection(bson.M{
+		"id":                   1,
+		"user_id":              1,
+		"messages.id":          1,
+		"messages.annotations": 1,
+		"messages.attachments": 1,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	var docs []chatDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// payloadUserPattern matches the webhook payloads whose data names userID,
+// the payloads are stored as the JSON posted.
+func payloadUserPattern(userID string) string {
+	id, _ := json.Marshal(userID)
+	return `"data":\{[^{}]*"user_id":` + regexp.QuoteMeta(string(id))
+}
@@ -0,0 +1,248 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type webhookDocument struct {
+	Namespace string    `bson:"namespace"`
+	ID        string    `bson:"id"`
+	OrgID     string    `bson:"org_id"`
+	URL       string    `bson:"url"`
+	Events    []string  `bson:"events"`
+	Secret    string    `bson:"secret"`
+	CreatedBy string    `bson:"created_by,omitempty"`
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+type webhookDeliveryDocument struct {
+	Namespace     string     `bson:"namespace"`
+	ID            string     `bson:"id"`
+	WebhookID     string     `bson:"webhook_id"`
+	OrgID         string     `bson:"org_id"`
+	Event         string     `bson:"event"`
+	Payload       string     `bson:"payload"`
+	Attempts      int        `bson:"attempts"`
+	NextAttemptAt time.Time  `bson:"next_attempt_at"`
+	DeliveredAt   *time.Time `bson:"delivered_at,omitempty"`
+	FailedAt      *time.Time `bson:"failed_at,omitempty"`
+	LastError     string     `bson:"last_error,omitempty"`
+	CreatedAt     time.Time  `bson:"created_at"`
+}
+
+// WebhookRepository is the WebhookGateway on the webhooks and
+// webhook_deliveries collections.
+type WebhookRepository struct {
+	webhooks   *mongo.Collection
+	deliveries *mongo.Collection
+}
+
+func NewWebhookRepository(db *mongo.Database) *WebhookRepository {
+	return &WebhookRepository{
+		webhooks:   db.Collection(webhooksCollection),
+		deliveries: db.Collection(webhookDeliveriesCollection),
+	}
+}
+
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, webhook *entity.Webhook) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.webhooks.InsertOne(ctx, webhookDocument{
+		Namespace: ns,
+		ID:        webhook.ID,
+		OrgID:     webhook.OrgID,
+		URL:       webhook.URL,
+		Events:    webhook.Events,
+		Secret:    webhook.Secret,
+		CreatedBy: webhook.CreatedBy,
+		CreatedAt: webhook.CreatedAt,
+	})
+	return err
+}
+
+func (r *WebhookRepository) FindWebhookByID(ctx context.Context, id string) (*entity.Webhook, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var doc webhookDocument
+	err = r.webhooks.FindOne(ctx, bson.M{"namespace": ns, "id": id}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, gateway.ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.webhook(), nil
+}
+
+func (r *WebhookRepository) ListWebhooksByOrg(ctx context.Context, orgID string) ([]*entity.Webhook, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}})
+	cursor, err := r.webhooks.Find(ctx, bson.M{"namespace": ns, "org_id": orgID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []webhookDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	webhooks := make([]*entity.Webhook, 0, len(docs))
+	for _, doc := range docs {
+		webhooks = append(webhooks, doc.webhook())
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes the deliveries first, a webhook is never left with
+// deliveries no one can list.
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := r.deliveries.DeleteMany(ctx, bson.M{"namespace": ns, "webhook_id": id}); err != nil {
+		return err
+	}
+	result, err := r.webhooks.DeleteOne(ctx, bson.M{"namespace": ns, "id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return gateway.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(deliveries))
+	for _, d := range deliveries {
+		docs = append(docs, webhookDeliveryDocument{
+			Namespace:     ns,
+			ID:            d.ID,
+			WebhookID:     d.WebhookID,
+			OrgID:         d.OrgID,
+			Event:         d.Event,
+			Payload:       string(d.Payload),
+			Attempts:      d.Attempts,
+			NextAttemptAt: d.NextAttemptAt,
+			DeliveredAt:   timePtr(d.DeliveredAt),
+			FailedAt:      timePtr(d.FailedAt),
+			LastError:     d.LastError,
+			CreatedAt:     d.CreatedAt,
+		})
+	}
+	_, err = r.deliveries.InsertMany(ctx, docs)
+	return err
+}
+
+func (r *WebhookRepository) FindDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{
+		"namespace":       ns,
+		"delivered_at":    bson.M{"$exists": false},
+		"failed_at":       bson.M{"$exists": false},
+		"next_attempt_at": bson.M{"$lte": now},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "id", Value: 1}}).SetLimit(int64(limit))
+	return r.findDeliveries(ctx, filter, opts)
+}
+
+func (r *WebhookRepository) SaveWebhookDelivery(ctx context.Context, d *entity.WebhookDelivery) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	set := bson.M{
+		"attempts":        d.Attempts,
+		"next_attempt_at": d.NextAttemptAt,
+		"last_error":      d.LastError,
+	}
+	if !d.DeliveredAt.IsZero() {
+		set["delivered_at"] = d.DeliveredAt
+	}
+	if !d.FailedAt.IsZero() {
+		set["failed_at"] = d.FailedAt
+	}
+	_, err = r.deliveries.UpdateOne(ctx, bson.M{"namespace": ns, "id": d.ID}, bson.M{"$set": set})
+	return err
+}
+
+func (r *WebhookRepository) ListDeadWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]*entity.WebhookDelivery, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"namespace": ns, "webhook_id": webhookID, "failed_at": bson.M{"$exists": true}}
+	opts := options.Find().SetSort(bson.D{{Key: "failed_at", Value: -1}, {Key: "id", Value: 1}}).SetLimit(int64(limit))
+	return r.findDeliveries(ctx, filter, opts)
+}
+
+func (r *WebhookRepository) findDeliveries(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*entity.WebhookDelivery, error) {
+	cursor, err := r.deliveries.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []webhookDeliveryDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	deliveries := make([]*entity.WebhookDelivery, 0, len(docs))
+	for _, doc := range docs {
+		d := &entity.WebhookDelivery{
+			ID:            doc.ID,
+			WebhookID:     doc.WebhookID,
+			OrgID:         doc.OrgID,
+			Event:         doc.Event,
+			Payload:       []byte(doc.Payload),
+			Attempts:      doc.Attempts,
+			NextAttemptAt: doc.NextAttemptAt,
+			LastError:     doc.LastError,
+			CreatedAt:     doc.CreatedAt,
+		}
+		if doc.DeliveredAt != nil {
+			d.DeliveredAt = *doc.DeliveredAt
+		}
+		if doc.FailedAt != nil {
+			d.FailedAt = *doc.FailedAt
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+func (doc webhookDocument) webhook() *entity.Webhook {
+	return &entity.Webhook{
+		ID:        doc.ID,
+		OrgID:     doc.OrgID,
+		URL:       doc.URL,
+		Events:    doc.Events,
+		Secret:    doc.Secret,
+		CreatedBy: doc.CreatedBy,
+		CreatedAt: doc.CreatedAt,
+	}
+}
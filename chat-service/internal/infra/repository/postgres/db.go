@@ -0,0 +1,23 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	// registers the "pgx" database/sql driver
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// Open connects to Postgres, the repositories of the sqlrepo package run on
+// the returned handle.
+func Open(url string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, fmt.Errorf("error opening postgres: %s", err.Error())
+	}
+	db.SetMaxOpenConns(20)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+	return db, nil
+}
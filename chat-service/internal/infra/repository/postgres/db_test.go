@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/repositorytest"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/sqlrepo"
+)
+
+// TestChatRepositoryOnPostgres runs on the database of POSTGRES_URL, in
+// namespaces of its own, and is skipped without one.
+func TestChatRepositoryOnPostgres(t *testing.T) {
+	url := os.Getenv("POSTGRES_URL")
+	if url == "" {
+		t.Skip("POSTGRES_URL is not set")
+	}
+	ctx := context.Background()
+	db, err := Open(url)
+	if err != nil {
+		t.Fatalf("Open(): %v", err)
+	}
+	// closing the migrator closes db
+	m, err := NewMigrator(ctx, db)
+	if err != nil {
+		db.Close()
+		t.Fatalf("NewMigrator(): %v", err)
+	}
+	defer m.Close()
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up(): %v", err)
+	}
+	repositorytest.TestChatGateway(t, sqlrepo.NewChatRepository(db), sqlrepo.NewNamespaceRepository(db))
+}
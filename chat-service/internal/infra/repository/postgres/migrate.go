@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/sqlrepo"
+	migratepgx "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// NewMigrator returns the Migrator of the embedded migrations on db.
+// Instances migrating together wait for each other on the lock of
+// golang-migrate, the later ones find nothing left to run. Closing the
+// Migrator closes db.
+func NewMigrator(ctx context.Context, db *sql.DB) (*sqlrepo.Migrator, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT to_regclass('schema_version') IS NOT NULL`).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("error reading postgres schema: %s", err.Error())
+	}
+	legacy := 0
+	if exists {
+		if legacy, err = sqlrepo.CurrentSchemaVersion(ctx, db); err != nil {
+			return nil, fmt.Errorf("error reading postgres schema version: %s", err.Error())
+		}
+	}
+	driver, err := migratepgx.WithInstance(db, &migratepgx.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("error opening postgres migrations: %s", err.Error())
+	}
+	scripts, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return sqlrepo.NewMigrator(scripts, "postgres", driver, legacy)
+}
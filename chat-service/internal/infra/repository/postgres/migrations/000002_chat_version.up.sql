@@ -0,0 +1,3 @@
+ALTER TABLE chats ADD COLUMN version BIGINT NOT NULL DEFAULT 0;
+
+INSERT INTO schema_version (version) VALUES (2);
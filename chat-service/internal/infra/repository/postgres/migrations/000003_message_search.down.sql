@@ -0,0 +1,4 @@
+DELETE FROM schema_version WHERE version = 3;
+
+DROP INDEX messages_search_idx;
+ALTER TABLE messages DROP COLUMN search;
@@ -0,0 +1,7 @@
+-- english stemming, so "goroutine" finds "goroutines"
+ALTER TABLE messages ADD COLUMN search tsvector
+    GENERATED ALWAYS AS (to_tsvector('english', content)) STORED;
+
+CREATE INDEX messages_search_idx ON messages USING GIN (search);
+
+INSERT INTO schema_version (version) VALUES (3);
@@ -0,0 +1,3 @@
+DELETE FROM schema_version WHERE version = 4;
+
+DROP TABLE embeddings;
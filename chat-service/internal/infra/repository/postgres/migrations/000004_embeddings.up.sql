@@ -0,0 +1,29 @@
+-- needs pgvector 0.5.0 or later for the hnsw index
+CREATE EXTENSION IF NOT EXISTS vector;
+
+-- chat_id is NULL for documents outside of any chat, the embeddings of a
+-- message go with its chat. vector(1536) is the size of the OpenAI
+-- text-embedding-ada-002 embeddings.
+CREATE TABLE embeddings (
+    namespace   TEXT         NOT NULL,
+    id          TEXT         NOT NULL,
+    source_type TEXT         NOT NULL,
+    source_id   TEXT         NOT NULL,
+    chat_id     TEXT,
+    user_id     TEXT         NOT NULL DEFAULT '',
+    model       TEXT         NOT NULL,
+    content     TEXT         NOT NULL DEFAULT '',
+    metadata    JSONB        NOT NULL DEFAULT '{}',
+    embedding   vector(1536) NOT NULL,
+    created_at  TIMESTAMPTZ  NOT NULL,
+    updated_at  TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (namespace, id),
+    UNIQUE (namespace, source_type, source_id, model),
+    FOREIGN KEY (namespace, chat_id) REFERENCES chats (namespace, id) ON DELETE CASCADE
+);
+
+CREATE INDEX embeddings_vector_idx ON embeddings USING hnsw (embedding vector_cosine_ops);
+CREATE INDEX embeddings_user_idx ON embeddings (namespace, user_id);
+CREATE INDEX embeddings_chat_idx ON embeddings (namespace, chat_id) WHERE chat_id IS NOT NULL;
+
+INSERT INTO schema_version (version) VALUES (4);
@@ -0,0 +1,8 @@
+-- the data key of each namespace, wrapped by the master key
+CREATE TABLE data_keys (
+    namespace   TEXT        NOT NULL PRIMARY KEY,
+    wrapped_key BYTEA       NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+INSERT INTO schema_version (version) VALUES (5);
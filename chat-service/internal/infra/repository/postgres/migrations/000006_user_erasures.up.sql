@@ -0,0 +1,19 @@
+-- proof of the erasures of user data, kept when everything else is gone
+CREATE TABLE user_erasures (
+    namespace    TEXT        NOT NULL,
+    id           TEXT        NOT NULL,
+    user_id      TEXT        NOT NULL,
+    requested_by TEXT        NOT NULL,
+    chats        BIGINT      NOT NULL,
+    messages     BIGINT      NOT NULL,
+    annotations  BIGINT      NOT NULL,
+    attachments  BIGINT      NOT NULL,
+    embeddings   BIGINT      NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (namespace, id)
+);
+
+CREATE INDEX annotations_user_idx ON annotations (namespace, user_id);
+CREATE INDEX outbox_chat_idx ON outbox (namespace, chat_id);
+
+INSERT INTO schema_version (version) VALUES (6);
@@ -0,0 +1,4 @@
+-- the retention purge looks idle chats up by last update
+CREATE INDEX chats_updated_idx ON chats (namespace, updated_at);
+
+INSERT INTO schema_version (version) VALUES (7);
@@ -0,0 +1,26 @@
+-- the read model of the chat listings, filled by the projection from the
+-- chat events. It may live in another database than the chats, so it has no
+-- foreign key to them. tags is a JSON array.
+CREATE TABLE chat_summaries (
+    namespace       TEXT        NOT NULL,
+    chat_id         TEXT        NOT NULL,
+    user_id         TEXT        NOT NULL,
+    parent_chat_id  TEXT        NOT NULL DEFAULT '',
+    title           TEXT        NOT NULL DEFAULT '',
+    status          TEXT        NOT NULL,
+    tags            TEXT        NOT NULL DEFAULT '[]',
+    model           TEXT        NOT NULL DEFAULT '',
+    message_count   INTEGER     NOT NULL DEFAULT 0,
+    token_usage     INTEGER     NOT NULL DEFAULT 0,
+    last_message_at TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL,
+    version         INTEGER     NOT NULL,
+    projected_at    TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (namespace, chat_id)
+);
+
+CREATE INDEX chat_summaries_user_updated_idx ON chat_summaries (namespace, user_id, updated_at DESC);
+CREATE INDEX chat_summaries_projected_idx ON chat_summaries (namespace, projected_at);
+
+INSERT INTO schema_version (version) VALUES (8);
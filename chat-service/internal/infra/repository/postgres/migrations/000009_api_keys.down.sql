@@ -0,0 +1,3 @@
+DELETE FROM schema_version WHERE version = 9;
+
+DROP TABLE api_keys;
@@ -0,0 +1,4 @@
+DELETE FROM schema_version WHERE version = 10;
+
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
@@ -0,0 +1,14 @@
+-- the idempotency keys users sent messages with, message_id is the answer
+-- once it is saved
+CREATE TABLE idempotency_keys (
+    namespace       TEXT        NOT NULL,
+    user_id         TEXT        NOT NULL,
+    idempotency_key TEXT        NOT NULL,
+    request_hash    TEXT        NOT NULL,
+    chat_id         TEXT        NOT NULL DEFAULT '',
+    message_id      TEXT        NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (namespace, user_id, idempotency_key)
+);
+
+INSERT INTO schema_version (version) VALUES (11);
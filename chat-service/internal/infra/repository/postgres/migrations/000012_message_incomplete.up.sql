@@ -0,0 +1,4 @@
+-- the reason the generation of a partial answer failed, empty when complete
+ALTER TABLE messages ADD COLUMN incomplete_reason TEXT NOT NULL DEFAULT '';
+
+INSERT INTO schema_version (version) VALUES (12);
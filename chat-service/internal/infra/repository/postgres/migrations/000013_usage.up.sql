@@ -0,0 +1,19 @@
+-- the turns, tokens and cost of each user per tenant, model and day, day
+-- being the UTC midnight starting it
+CREATE TABLE usage_records (
+    namespace         TEXT             NOT NULL,
+    day               TIMESTAMPTZ      NOT NULL,
+    org_id            TEXT             NOT NULL,
+    user_id           TEXT             NOT NULL,
+    model             TEXT             NOT NULL,
+    turns             BIGINT           NOT NULL DEFAULT 0,
+    prompt_tokens     BIGINT           NOT NULL DEFAULT 0,
+    completion_tokens BIGINT           NOT NULL DEFAULT 0,
+    cost              DOUBLE PRECISION NOT NULL DEFAULT 0,
+    PRIMARY KEY (namespace, day, org_id, user_id, model)
+);
+
+CREATE INDEX usage_records_org_idx ON usage_records (namespace, org_id, day);
+CREATE INDEX usage_records_user_idx ON usage_records (namespace, user_id, day);
+
+INSERT INTO schema_version (version) VALUES (13);
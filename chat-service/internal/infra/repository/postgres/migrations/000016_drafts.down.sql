@@ -0,0 +1,3 @@
+DELETE FROM schema_version WHERE version = 16;
+
+DROP TABLE drafts;
@@ -0,0 +1,6 @@
+-- the tenant a chat was opened in, for the retention policies by tenant
+ALTER TABLE chats ADD COLUMN org_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX chats_org_updated_idx ON chats (namespace, org_id, updated_at);
+
+INSERT INTO schema_version (version) VALUES (18);
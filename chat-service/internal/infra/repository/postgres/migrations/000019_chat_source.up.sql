@@ -0,0 +1,7 @@
+-- the ID an imported chat had in its file, the imports skip the chats of the
+-- user imported from the same one
+ALTER TABLE chats ADD COLUMN source_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX chats_source_idx ON chats (namespace, user_id, source_id) WHERE source_id <> '';
+
+INSERT INTO schema_version (version) VALUES (19);
@@ -0,0 +1,5 @@
+-- the messages of a chat are paged by creation time, positions are rewritten
+-- as the history changes
+CREATE INDEX messages_chat_created_idx ON messages (namespace, chat_id, state, created_at, id);
+
+INSERT INTO schema_version (version) VALUES (20);
@@ -0,0 +1,471 @@
+// Package repositorytest is the contract the repositories of the gateways
+// pass, whatever their database: the tests of each repository run it against
+// a database of theirs so they behave the same to the use cases.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"github.com/google/uuid"
+)
+
+// TestChatGateway runs the contract of gateway.ChatGateway against chats.
+// Every subtest writes in a namespace of its own, purged with namespaces
+// once it ends, so the database can be shared with other runs.
+func TestChatGateway(t *testing.T, chats gateway.ChatGateway, namespaces gateway.NamespaceGateway) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ctx context.Context, chats gateway.ChatGateway)
+	}{
+		{"CreatedChatIsFoundAsSaved", testCreatedChatIsFoundAsSaved},
+		{"MissingChatIsNotFound", testMissingChatIsNotFound},
+		{"SaveChatAddsMessagesAndBumpsVersion", testSaveChatAddsMessagesAndBumpsVersion},
+		{"StaleSaveChatConflicts", testStaleSaveChatConflicts},
+		{"ChatsAreScopedToTheirNamespace", testChatsAreScopedToTheirNamespace},
+		{"ListChatsByUserPagesNewestFirst", testListChatsByUserPagesNewestFirst},
+		{"ListChatsFiltersByTag", testListChatsFiltersByTag},
+		{"ListMessagesPagesFromTheNewest", testListMessagesPagesFromTheNewest},
+		{"ListMessagesFailsOnAnUnknownCursor", testListMessagesFailsOnAnUnknownCursor},
+		{"UpdatesOfAMissingChatAreNotFound", testUpdatesOfAMissingChatAreNotFound},
+		{"MessageFlagsAreSaved", testMessageFlagsAreSaved},
+		{"DeletedChatIsHiddenThenPurged", testDeletedChatIsHiddenThenPurged},
+		{"ExpiredChatsAreFoundThenPurgedOnceArchived", testExpiredChatsAreFoundThenPurgedOnceArchived},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newNamespace(t, namespaces), chats)
+		})
+	}
+}
+
+// newNamespace returns a context in a new namespace, purged once t ends.
+func newNamespace(t *testing.T, namespaces gateway.NamespaceGateway) context.Context {
+	ns := "contract-" + uuid.New().String()[:8]
+	t.Cleanup(func() {
+		if _, err := namespaces.PurgeNamespace(context.Background(), ns); err != nil {
+			t.Errorf("PurgeNamespace(%s): %v", ns, err)
+		}
+	})
+	return namespace.NewContext(context.Background(), ns)
+}
+
+// at is a time of the fixtures, whole seconds so every database keeps it as
+// it is.
+func at(minutes int) time.Time {
+	return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).Add(time.Duration(minutes) * time.Minute)
+}
+
+var model = entity.NewModel("gpt-3.5-turbo", 4096)
+
+func newMessage(t *testing.T, role entity.Role, content string, createdAt time.Time) *entity.Message {
+	t.Helper()
+	m, err := entity.NewMessage(role, content, model, createdAt)
+	if err != nil {
+		t.Fatalf("NewMessage(): %v", err)
+	}
+	return m
+}
+
+// newChat returns a chat of userID updated at updatedAt, with its system
+// message and a message of the user for each of contents, a minute apart.
+func newChat(t *testing.T, userID string, updatedAt time.Time, contents ...string) *entity.Chat {
+	t.Helper()
+	system := newMessage(t, entity.RoleSystem, "You are a helpful assistant.", updatedAt)
+	chat, err := entity.NewChat(userID, system, &entity.ChatConfig{Model: model, Temperature: 0.5, N: 1, MaxTokens: 256}, updatedAt)
+	if err != nil {
+		t.Fatalf("NewChat(): %v", err)
+	}
+	for i, content := range contents {
+		createdAt := updatedAt.Add(time.Duration(i+1) * time.Minute)
+		if err := chat.AddMessage(newMessage(t, entity.RoleUser, content, createdAt), createdAt); err != nil {
+			t.Fatalf("AddMessage(): %v", err)
+		}
+	}
+	chat.CreatedAt, chat.UpdatedAt = updatedAt, updatedAt
+	return chat
+}
+
+func createChat(t *testing.T, ctx context.Context, chats gateway.ChatGateway, chat *entity.Chat) {
+	t.Helper()
+	if err := chats.CreateChat(ctx, chat); err != nil {
+		t.Fatalf("CreateChat(): %v", err)
+	}
+}
+
+func findChat(t *testing.T, ctx context.Context, chats gateway.ChatGateway, chatID string) *entity.Chat {
+	t.Helper()
+	chat, err := chats.FindChatByID(ctx, chatID)
+	if err != nil {
+		t.Fatalf("FindChatByID(): %v", err)
+	}
+	return chat
+}
+
+func contents(messages []*entity.Message) []string {
+	var contents []string
+	for _, m := range messages {
+		contents = append(contents, m.Content)
+	}
+	return contents
+}
+
+func ids(chats []*entity.Chat) []string {
+	var ids []string
+	for _, chat := range chats {
+		ids = append(ids, chat.ID)
+	}
+	return ids
+}
+
+func sameStrings(got, want []string) bool {
+	return fmt.Sprint(got) == fmt.Sprint(want)
+}
+
+func testCreatedChatIsFoundAsSaved(t *testing.T, ctx context.Context, chats gateway.ChatGateway) {
+	chat := newChat(t, "user-1", at(0), "first", "second")
+	chat.Title = "A title"
+	chat.OrgID = "org-1"
+	chat.Tags = []string{"work"}
+	createChat(t, ctx, chats, chat)
+	if chat.Version != 1 {
+		t.Fatalf("Version after CreateChat = %d, want 1", chat.Version)
+	}
+
+	got := findChat(t, ctx, chats, chat.ID)
+	if got.UserID != "user-1" || got.Title != "A title" || got.OrgID != "org-1" || got.Status != entity.ChatStatusActive {
+		t.Fatalf("FindChatByID() = %+v, want the chat created", got)
+	}
+	if got.Version != 1 {
+		t.Fatalf("Version = %d, want 1", got.Version)
+	}
+	if !got.CreatedAt.Equal(at(0)) || !got.UpdatedAt.Equal(at(0)) {
+		t.Fatalf("CreatedAt, UpdatedAt = %s, %s, want %s", got.CreatedAt, got.UpdatedAt, at(0))
+	}
+	if want := []string{"You are a helpful assistant.", "first", "second"}; !sameStrings(contents(got.Messages), want) {
+		t.Fatalf("messages = %q, want %q", contents(got.Messages), want)
+	}
+	if got.InitialSystemMessage == nil || got.InitialSystemMessage.ID != chat.InitialSystemMessage.ID {
+		t.Fatalf("InitialSystemMessage = %+v, want %s", got.InitialSystemMessage, chat.InitialSystemMessage.ID)
+	}
+	if m := got.Messages[1]; m.ID != chat.Messages[1].ID || m.Role != entity.RoleUser || m.Tokens != chat.Messages[1].Tokens || !m.CreatedAt.Equal(at(1)) {
+		t.Fatalf("message = %+v, want %+v", m, chat.Messages[1])
+	}
+	if got.Config == nil || got.Config.Model.GetModelName() != "gpt-3.5-turbo" || got.Config.MaxTokens != 256 || got.Config.Temperature != 0.5 {
+		t.Fatalf("Config = %+v, want the config of the chat", got.Config)
+	}
+	if !sameStrings(got.Tags, []string{"work"}) {
+		t.Fatalf("Tags = %q, want [work]", got.Tags)
+	}
+}
+
+func testMissingChatIsNotFound(t *testing.T, ctx context.Context, chats gateway.ChatGateway) {
+	if _, err := chats.FindChatByID(ctx, uuid.New().String()); !errors.Is(err, gateway.ErrChatNotFound) {
+		t.Fatalf("FindChatByID() of a missing chat = %v, want ErrChatNotFound", err)
+	}
+	if _, err := chats.ListMessages(ctx, uuid.New().String(), gateway.MessageCursor{}); !errors.Is(err, gateway.ErrChatNotFound) {
+		t.Fatalf("ListMessages() of a missing chat = %v, want ErrChatNotFound", err)
+	}
+}
+
+func testSaveChatAddsMessagesAndBumpsVersion(t *testing.T, ctx context.Context, chats gateway.ChatGateway) {
+	chat := newChat(t, "user-1", at(0), "first")
+	createChat(t, ctx, chats, chat)
+
+	loaded := findChat(t, ctx, chats, chat.ID)
+	if err := loaded.AddMessage(newMessage(t, entity.RoleAssistant, "an answer", at(5)), at(5)); err != nil {
+		t.Fatalf("AddMessage(): %v", err)
+	}
+	if err := chats.SaveChat(ctx, loaded); err != nil {
+		t.Fatalf("SaveChat(): %v", err)
+	}
+	if loaded.Version != 2 {
+		t.Fatalf("Version after SaveChat = %d, want 2", loaded.Version)
+	}
+
+	got := findChat(t, ctx, chats, chat.ID)
+	if want := []string{"You are a helpful assistant.", "first", "an answer"}; !sameStrings(contents(got.Messages), want) {
+		t.Fatalf("messages = %q, want %q", contents(got.Messages), want)
+	}
+	if got.Version != 2 || !got.UpdatedAt.Equal(at(5)) {
+		t.Fatalf("Version, UpdatedAt = %d, %s, want 2, %s", got.Version, got.UpdatedAt, at(5))
+	}
+}
+
+func testStaleSaveChatConflicts(t *testing.T, ctx context.Context, chats gateway.ChatGateway) {
+	chat := newChat(t, "user-1", at(0))
+	createChat(t, ctx, chats, chat)
+	first := findChat(t, ctx, chats, chat.ID)
+	second := findChat(t, ctx, chats, chat.ID)
+
+	first.Title = "first"
+	if err := chats.SaveChat(ctx, first); err != nil {
+		t.Fatalf("SaveChat(): %v", err)
+	}
+	second.Title = "second"
+	if err := chats.SaveChat(ctx, second); !errors.Is(err, gateway.ErrChatConflict) {
+		t.Fatalf("SaveChat() of a stale chat = %v, want ErrChatConflict", err)
+	}
+	if got := findChat(t, ctx, chats, chat.ID); got.Title != "first" {
+		t.Fatalf("Title = %q, want the one saved first", got.Title)
+	}
+}
+
+func testChatsAreScopedToTheirNamespace(t *testing.T, ctx context.Context, chats gateway.ChatGateway) {
+	chat := newChat(t, "user-1", at(0))
+	createChat(t, ctx, chats, chat)
+
+	other := namespace.NewContext(context.Background(), "contract-"+uuid.New().String()[:8])
+	if _, err := chats.FindChatByID(other, chat.ID); !errors.Is(err, gateway.ErrChatNotFound) {
+		t.Fatalf("FindChatByID() in another namespace = %v, want ErrChatNotFound", err)
+	}
+	page, err := chats.ListChatsByUser(other, "user-1", 1, 10)
+	if err != nil {
+		t.Fatalf("ListChatsByUser(): %v", err)
+	}
+	if page.Total != 0 || len(page.Chats) != 0 {
+		t.Fatalf("ListChatsByUser() in another namespace = %d chats, want none", page.Total)
+	}
+}
+
+func testListChatsByUserPagesNewestFirst(t *testing.T, ctx context.Context, chats gateway.ChatGateway) {
+	var created []*entity.Chat
+	for i := 0; i < 3; i++ {
+		chat := newChat(t, "user-1", at(i), "hello")
+		createChat(t, ctx, chats, chat)
+		created = append(created, chat)
+	}
+	createChat(t, ctx, chats, newChat(t, "user-2", at(10)))
+	deleted := newChat(t, "user-1", at(20))
+	createChat(t, ctx, chats, deleted)
+	if err := chats.DeleteChat(ctx, deleted.ID, at(21)); err != nil {
+		t.Fatalf("DeleteChat(): %v", err)
+	}
+
+	first, err := chats.ListChatsByUser(ctx, "user-1", 1, 2)
+	if err != nil {
+		t.Fatalf("ListChatsByUser(): %v", err)
+	}
+	if first.Total != 3 {
+		t.Fatalf("Total = %d, want 3 without the deleted chat", first.Total)
+	}
+	if want := []string{created[2].ID, created[1].ID}; !sameStrings(ids(first.Chats), want) {
+		t.Fatalf("first page = %v, want %v", ids(first.Chats), want)
+	}
+	second, err := chats.ListChatsByUser(ctx, "user-1", 2, 2)
+	if err != nil {
+		t.Fatalf("ListChatsByUser(): %v", err)
+	}
+	if want := []string{created[0].ID}; !sameStrings(ids(second.Chats), want) {
+		t.Fatalf("second page = %v, want %v", ids(second.Chats), want)
+	}
+	if len(second.Chats[0].Messages) != 0 {
+		t.Fatalf("ListChatsByUser() loaded %d messages, want none", len(second.Chats[0].Messages))
+	}
+}
+
+func testListChatsFiltersByTag(t *testing.T, ctx context.Context, chats gateway.ChatGateway) {
+	tagged := newChat(t, "user-1", at(0))
+	untagged := newChat(t, "user-1", at(1))
+	createChat(t, ctx, chats, tagged)
+	createChat(t, ctx, chats, untagged)
+	if err := chats.AddChatTag(ctx, tagged.ID, "work"); err != nil {
+		t.Fatalf("AddChatTag(): %v", err)
+	}
+
+	got, err := chats.ListChats(ctx, gateway.ChatFilter{UserID: "user-1", Tag: "work"})
+	if err != nil {
+		t.Fatalf("ListChats(): %v", err)
+	}
+	if want := []string{tagged.ID}; !sameStrings(ids(got), want) {
+		t.Fatalf("ListChats() by tag = %v, want %v", ids(got), want)
+	}
+	if chat := findChat(t, ctx, chats, tagged.ID); !sameStrings(chat.Tags, []string{"work"}) || chat.Version != 2 {
+		t.Fatalf("Tags, Version = %q, %d, want [work], 2", chat.Tags, chat.Version)
+	}
+
+	if err := chats.RemoveChatTag(ctx, tagged.ID, "work"); err != nil {
+		t.Fatalf("RemoveChatTag(): %v", err)
+	}
+	got, err = chats.ListChats(ctx, gateway.ChatFilter{UserID: "user-1", Tag: "work"})
+	if err != nil {
+		t.Fatalf("ListChats(): %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("ListChats() by a removed tag = %v, want none", ids(got))
+	}
+}
+
+func testListMessagesPagesFromTheNewest(t *testing.T, ctx context.Context, chats gateway.ChatGateway) {
+	chat := newChat(t, "user-1", at(0), "one", "two", "three", "four")
+	createChat(t, ctx, chats, chat)
+
+	var pages [][]string
+	cursor := gateway.MessageCursor{Limit: 2}
+	for {
+		page, err := chats.ListMessages(ctx, chat.ID, cursor)
+		if err != nil {
+			t.Fatalf("ListMessages(): %v", err)
+		}
+		if page.ChatUserID != "user-1" {
+			t.Fatalf("ChatUserID = %q, want user-1", page.ChatUserID)
+		}
+		pages = append(pages, contents(page.Messages))
+		if page.Next == nil {
+			break
+		}
+		if len(pages) > 3 {
+			t.Fatalf("ListMessages() pages past the oldest message: %q", pages)
+		}
+		cursor = *page.Next
+	}
+	want := [][]string{{"three", "four"}, {"one", "two"}, {"You are a helpful assistant."}}
+	if fmt.Sprint(pages) != fmt.Sprint(want) {
+		t.Fatalf("pages = %q, want %q", pages, want)
+	}
+
+	all, err := chats.ListMessages(ctx, chat.ID, gateway.MessageCursor{})
+	if err != nil {
+		t.Fatalf("ListMessages(): %v", err)
+	}
+	if len(all.Messages) != 5 || all.Next != nil {
+		t.Fatalf("ListMessages() without a limit = %d messages, next %v, want 5 and no next page", len(all.Messages), all.Next)
+	}
+}
+
+func testListMessagesFailsOnAnUnknownCursor(t *testing.T, ctx context.Context, chats gateway.ChatGateway) {
+	chat := newChat(t, "user-1", at(0), "one")
+	other := newChat(t, "user-1", at(1), "two")
+	createChat(t, ctx, chats, chat)
+	createChat(t, ctx, chats, other)
+
+	for _, before := range []string{uuid.New().String(), other.Messages[1].ID} {
+		_, err := chats.ListMessages(ctx, chat.ID, gateway.MessageCursor{Before: before, Limit: 10})
+		if !errors.Is(err, gateway.ErrInvalidCursor) {
+			t.Fatalf("ListMessages() before %s = %v, want ErrInvalidCursor", before, err)
+		}
+	}
+}
+
+func testUpdatesOfAMissingChatAreNotFound(t *testing.T, ctx context.Context, chats gateway.ChatGateway) {
+	chatID := uuid.New().String()
+	if err := chats.UpdateChatTitle(ctx, chatID, "title"); !errors.Is(err, gateway.ErrChatNotFound) {
+		t.Fatalf("UpdateChatTitle() of a missing chat = %v, want ErrChatNotFound", err)
+	}
+	if err := chats.DeleteChat(ctx, chatID, at(0)); !errors.Is(err, gateway.ErrChatNotFound) {
+		t.Fatalf("DeleteChat() of a missing chat = %v, want ErrChatNotFound", err)
+	}
+
+	chat := newChat(t, "user-1", at(0))
+	createChat(t, ctx, chats, chat)
+	if err := chats.UpdateChatTitle(ctx, chat.ID, "renamed"); err != nil {
+		t.Fatalf("UpdateChatTitle(): %v", err)
+	}
+	if got := findChat(t, ctx, chats, chat.ID); got.Title != "renamed" || got.Version != 2 {
+		t.Fatalf("Title, Version = %q, %d, want renamed, 2", got.Title, got.Version)
+	}
+}
+
+func testMessageFlagsAreSaved(t *testing.T, ctx context.Context, chats gateway.ChatGateway) {
+	chat := newChat(t, "user-1", at(0), "pin me")
+	createChat(t, ctx, chats, chat)
+	messageID := chat.Messages[1].ID
+
+	if err := chats.SetMessageFlags(ctx, chat.ID, messageID, true, true); err != nil {
+		t.Fatalf("SetMessageFlags(): %v", err)
+	}
+	got := findChat(t, ctx, chats, chat.ID)
+	if m := got.Messages[1]; !m.Pinned || !m.Starred {
+		t.Fatalf("Pinned, Starred = %t, %t, want both set", m.Pinned, m.Starred)
+	}
+	if err := chats.SetMessageFlags(ctx, chat.ID, messageID, false, true); err != nil {
+		t.Fatalf("SetMessageFlags(): %v", err)
+	}
+	got = findChat(t, ctx, chats, chat.ID)
+	if m := got.Messages[1]; m.Pinned || !m.Starred {
+		t.Fatalf("Pinned, Starred = %t, %t, want only starred", m.Pinned, m.Starred)
+	}
+}
+
+func testDeletedChatIsHiddenThenPurged(t *testing.T, ctx context.Context, chats gateway.ChatGateway) {
+	chat := newChat(t, "user-1", at(0), "hello")
+	kept := newChat(t, "user-1", at(1))
+	createChat(t, ctx, chats, chat)
+	createChat(t, ctx, chats, kept)
+	if err := chats.DeleteChat(ctx, chat.ID, at(10)); err != nil {
+		t.Fatalf("DeleteChat(): %v", err)
+	}
+
+	if _, err := chats.FindChatByID(ctx, chat.ID); !errors.Is(err, gateway.ErrChatNotFound) {
+		t.Fatalf("FindChatByID() of a deleted chat = %v, want ErrChatNotFound", err)
+	}
+	if _, err := chats.ListMessages(ctx, chat.ID, gateway.MessageCursor{}); !errors.Is(err, gateway.ErrChatNotFound) {
+		t.Fatalf("ListMessages() of a deleted chat = %v, want ErrChatNotFound", err)
+	}
+	listed, err := chats.ListChats(ctx, gateway.ChatFilter{UserID: "user-1"})
+	if err != nil {
+		t.Fatalf("ListChats(): %v", err)
+	}
+	if want := []string{kept.ID}; !sameStrings(ids(listed), want) {
+		t.Fatalf("ListChats() = %v, want %v without the deleted chat", ids(listed), want)
+	}
+
+	if purged, err := chats.PurgeDeletedChats(ctx, at(10)); err != nil || purged != 0 {
+		t.Fatalf("PurgeDeletedChats() at the deletion = %d, %v, want nothing purged", purged, err)
+	}
+	if purged, err := chats.PurgeDeletedChats(ctx, at(11)); err != nil || purged != 1 {
+		t.Fatalf("PurgeDeletedChats() after the deletion = %d, %v, want 1 purged", purged, err)
+	}
+	findChat(t, ctx, chats, kept.ID)
+}
+
+func testExpiredChatsAreFoundThenPurgedOnceArchived(t *testing.T, ctx context.Context, chats gateway.ChatGateway) {
+	expired := newChat(t, "user-1", at(0))
+	if err := expired.MakeEphemeral(at(0), 10*time.Minute); err != nil {
+		t.Fatalf("MakeEphemeral(): %v", err)
+	}
+	live := newChat(t, "user-1", at(0))
+	if err := live.MakeEphemeral(at(0), time.Hour); err != nil {
+		t.Fatalf("MakeEphemeral(): %v", err)
+	}
+	createChat(t, ctx, chats, expired)
+	createChat(t, ctx, chats, live)
+	createChat(t, ctx, chats, newChat(t, "user-1", at(0)))
+
+	found, err := chats.FindExpiredChats(ctx, at(30), 10)
+	if err != nil {
+		t.Fatalf("FindExpiredChats(): %v", err)
+	}
+	if want := []string{expired.ID}; !sameStrings(ids(found), want) {
+		t.Fatalf("FindExpiredChats() = %v, want %v", ids(found), want)
+	}
+	if !found[0].ExpiresAt.Equal(at(10)) {
+		t.Fatalf("ExpiresAt = %s, want %s", found[0].ExpiresAt, at(10))
+	}
+	if purged, err := chats.PurgeExpiredChats(ctx, at(30)); err != nil || purged != 0 {
+		t.Fatalf("PurgeExpiredChats() before the archive = %d, %v, want nothing purged", purged, err)
+	}
+
+	chat := found[0]
+	if err := chat.EndChat(at(20)); err != nil {
+		t.Fatalf("EndChat(): %v", err)
+	}
+	if err := chat.Archive(at(20)); err != nil {
+		t.Fatalf("Archive(): %v", err)
+	}
+	if err := chats.SaveChat(ctx, chat); err != nil {
+		t.Fatalf("SaveChat(): %v", err)
+	}
+	if found, err := chats.FindExpiredChats(ctx, at(30), 10); err != nil || len(found) != 0 {
+		t.Fatalf("FindExpiredChats() once archived = %v, %v, want none", ids(found), err)
+	}
+	if purged, err := chats.PurgeExpiredChats(ctx, at(30)); err != nil || purged != 1 {
+		t.Fatalf("PurgeExpiredChats() once archived = %d, %v, want 1 purged", purged, err)
+	}
+}
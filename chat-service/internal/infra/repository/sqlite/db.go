@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/sqlrepo"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+
+	// registers the pure Go "sqlite" database/sql driver
+	_ "modernc.org/sqlite"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Open opens the SQLite database at path, creating it and migrating its
+// schema to the latest version, so the service runs without any database server for demos and
+// tests. The repositories of the sqlrepo package run on the returned handle.
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	db, err := Connect(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Connect opens the SQLite database at path, creating it, without migrating
+// its schema.
+func Connect(path string) (*sql.DB, error) {
+	dsn := path
+	if !strings.HasPrefix(path, "file:") {
+		if path != ":memory:" {
+			if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+				return nil, fmt.Errorf("error creating sqlite directory: %s", err.Error())
+			}
+		}
+		dsn = "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("error opening sqlite: %s", err.Error())
+	}
+	// SQLite allows a single writer, serialize the connections instead of
+	// failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// Migrate runs the embedded migrations newer than the schema version and
+// returns the versions it ran.
+func Migrate(ctx context.Context, db *sql.DB) ([]int, error) {
+	m, err := NewMigrator(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	return m.Up(ctx)
+}
+
+// NewMigrator returns the Migrator of the embedded migrations on db. Unlike
+// its Close, which closes db, dropping the Migrator leaves db open.
+func NewMigrator(ctx context.Context, db *sql.DB) (*sqlrepo.Migrator, error) {
+	var tables int
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'`).Scan(&tables)
+	if err != nil {
+		return nil, fmt.Errorf("error reading sqlite schema: %s", err.Error())
+	}
+	legacy := 0
+	if tables > 0 {
+		if legacy, err = sqlrepo.CurrentSchemaVersion(ctx, db); err != nil {
+			return nil, fmt.Errorf("error reading sqlite schema version: %s", err.Error())
+		}
+	}
+	driver, err := migratesqlite.WithInstance(db, &migratesqlite.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("error opening sqlite migrations: %s", err.Error())
+	}
+	scripts, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return sqlrepo.NewMigrator(scripts, "sqlite", driver, legacy)
+}
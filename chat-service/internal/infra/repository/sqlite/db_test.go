@@ -0,0 +1,19 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/repositorytest"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/repository/sqlrepo"
+)
+
+func TestChatRepositoryOnSQLite(t *testing.T) {
+	db, err := Open(context.Background(), filepath.Join(t.TempDir(), "chat.db"))
+	if err != nil {
+		t.Fatalf("Open(): %v", err)
+	}
+	defer db.Close()
+	repositorytest.TestChatGateway(t, sqlrepo.NewChatRepository(db), sqlrepo.NewNamespaceRepository(db))
+}
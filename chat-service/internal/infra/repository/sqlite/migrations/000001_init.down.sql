@@ -0,0 +1,7 @@
+DROP TABLE schema_version;
+DROP TABLE outbox;
+DROP TABLE attachments;
+DROP TABLE annotations;
+DROP TABLE chat_tags;
+DROP TABLE messages;
+DROP TABLE chats;
@@ -0,0 +1,117 @@
+CREATE TABLE chats (
+    namespace              TEXT        NOT NULL,
+    id                     TEXT        NOT NULL,
+    user_id                TEXT        NOT NULL,
+    title                  TEXT        NOT NULL DEFAULT '',
+    parent_chat_id         TEXT        NOT NULL DEFAULT '',
+    forked_from_message_id TEXT        NOT NULL DEFAULT '',
+    persona_id             TEXT        NOT NULL DEFAULT '',
+    initial_message_id     TEXT        NOT NULL,
+    status                 TEXT        NOT NULL,
+    answer_stage           TEXT        NOT NULL DEFAULT '',
+    system_fingerprint     TEXT        NOT NULL DEFAULT '',
+    summary_message_id     TEXT        NOT NULL DEFAULT '',
+    token_usage            INTEGER     NOT NULL DEFAULT 0,
+    config                 TEXT        NOT NULL,
+    created_at             DATETIME    NOT NULL,
+    updated_at             DATETIME    NOT NULL,
+    deleted_at             DATETIME,
+    expires_at             DATETIME,
+    PRIMARY KEY (namespace, id)
+);
+
+CREATE INDEX chats_user_updated_idx ON chats (namespace, user_id, updated_at DESC) WHERE deleted_at IS NULL;
+CREATE INDEX chats_parent_idx ON chats (namespace, parent_chat_id) WHERE parent_chat_id <> '';
+CREATE INDEX chats_deleted_idx ON chats (namespace, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX chats_expires_idx ON chats (namespace, expires_at) WHERE expires_at IS NOT NULL;
+
+-- state is active, erased (evicted from the context window) or initial (the
+-- initial system message once it is no longer in either list)
+CREATE TABLE messages (
+    namespace              TEXT        NOT NULL,
+    id                     TEXT        NOT NULL,
+    chat_id                TEXT        NOT NULL,
+    state                  TEXT        NOT NULL,
+    position               INTEGER     NOT NULL,
+    role                   TEXT        NOT NULL,
+    content                TEXT        NOT NULL,
+    tokens                 INTEGER     NOT NULL,
+    model_name             TEXT        NOT NULL,
+    model_max_tokens       INTEGER     NOT NULL,
+    prompt_tokens          INTEGER     NOT NULL DEFAULT 0,
+    time_to_first_token_ns INTEGER     NOT NULL DEFAULT 0,
+    generation_latency_ns  INTEGER     NOT NULL DEFAULT 0,
+    pinned                 BOOLEAN     NOT NULL DEFAULT FALSE,
+    starred                BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at             DATETIME    NOT NULL,
+    PRIMARY KEY (namespace, id),
+    FOREIGN KEY (namespace, chat_id) REFERENCES chats (namespace, id) ON DELETE CASCADE
+);
+
+CREATE INDEX messages_chat_idx ON messages (namespace, chat_id, state, position);
+
+CREATE TABLE chat_tags (
+    namespace TEXT NOT NULL,
+    chat_id   TEXT NOT NULL,
+    tag       TEXT NOT NULL,
+    PRIMARY KEY (namespace, chat_id, tag),
+    FOREIGN KEY (namespace, chat_id) REFERENCES chats (namespace, id) ON DELETE CASCADE
+);
+
+CREATE INDEX chat_tags_tag_idx ON chat_tags (namespace, tag);
+
+CREATE TABLE annotations (
+    namespace    TEXT        NOT NULL,
+    id           TEXT        NOT NULL,
+    chat_id      TEXT        NOT NULL,
+    message_id   TEXT        NOT NULL,
+    user_id      TEXT        NOT NULL,
+    kind         TEXT        NOT NULL,
+    start_offset INTEGER     NOT NULL,
+    end_offset   INTEGER     NOT NULL,
+    text         TEXT        NOT NULL DEFAULT '',
+    created_at   DATETIME    NOT NULL,
+    PRIMARY KEY (namespace, id),
+    FOREIGN KEY (namespace, chat_id) REFERENCES chats (namespace, id) ON DELETE CASCADE
+);
+
+CREATE INDEX annotations_chat_idx ON annotations (namespace, chat_id);
+
+CREATE TABLE attachments (
+    namespace   TEXT        NOT NULL,
+    id          TEXT        NOT NULL,
+    chat_id     TEXT        NOT NULL,
+    message_id  TEXT        NOT NULL,
+    name        TEXT        NOT NULL,
+    mime_type   TEXT        NOT NULL,
+    size        INTEGER     NOT NULL,
+    storage_key TEXT        NOT NULL,
+    created_at  DATETIME    NOT NULL,
+    PRIMARY KEY (namespace, id),
+    FOREIGN KEY (namespace, chat_id) REFERENCES chats (namespace, id) ON DELETE CASCADE
+);
+
+CREATE INDEX attachments_chat_idx ON attachments (namespace, chat_id);
+
+-- outbox entries outlive their chat, consumers may still need the events
+CREATE TABLE outbox (
+    seq          INTEGER     PRIMARY KEY AUTOINCREMENT,
+    namespace    TEXT        NOT NULL,
+    id           TEXT        NOT NULL,
+    chat_id      TEXT        NOT NULL,
+    event_name   TEXT        NOT NULL,
+    payload      TEXT        NOT NULL,
+    occurred_at  DATETIME    NOT NULL,
+    published_at DATETIME,
+    attempts     INTEGER     NOT NULL DEFAULT 0,
+    last_error   TEXT        NOT NULL DEFAULT '',
+    UNIQUE (namespace, id)
+);
+
+CREATE INDEX outbox_unpublished_idx ON outbox (namespace, seq) WHERE published_at IS NULL;
+
+CREATE TABLE schema_version (
+    version INTEGER NOT NULL
+);
+
+INSERT INTO schema_version (version) VALUES (1);
@@ -0,0 +1,3 @@
+DELETE FROM schema_version WHERE version = 2;
+
+ALTER TABLE chats DROP COLUMN version;
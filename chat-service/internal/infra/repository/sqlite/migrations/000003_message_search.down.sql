@@ -0,0 +1,6 @@
+DELETE FROM schema_version WHERE version = 3;
+
+DROP TRIGGER messages_fts_update;
+DROP TRIGGER messages_fts_delete;
+DROP TRIGGER messages_fts_insert;
+DROP TABLE messages_fts;
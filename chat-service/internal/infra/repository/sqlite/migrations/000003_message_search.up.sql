@@ -0,0 +1,25 @@
+-- messages has no INTEGER PRIMARY KEY, its rowids are not stable so the index
+-- keeps its own copy of the content, keyed by the message columns
+CREATE VIRTUAL TABLE messages_fts USING fts5(
+    namespace UNINDEXED,
+    message_id UNINDEXED,
+    content,
+    tokenize = 'porter unicode61'
+);
+
+INSERT INTO messages_fts (namespace, message_id, content)
+    SELECT namespace, id, content FROM messages;
+
+CREATE TRIGGER messages_fts_insert AFTER INSERT ON messages BEGIN
+    INSERT INTO messages_fts (namespace, message_id, content) VALUES (new.namespace, new.id, new.content);
+END;
+
+CREATE TRIGGER messages_fts_delete AFTER DELETE ON messages BEGIN
+    DELETE FROM messages_fts WHERE namespace = old.namespace AND message_id = old.id;
+END;
+
+CREATE TRIGGER messages_fts_update AFTER UPDATE OF content ON messages BEGIN
+    UPDATE messages_fts SET content = new.content WHERE namespace = old.namespace AND message_id = old.id;
+END;
+
+INSERT INTO schema_version (version) VALUES (3);
@@ -0,0 +1,3 @@
+-- embeddings are stored in Postgres only, SQLite has no vector type. The
+-- version is recorded so both schemas stay at the same version.
+INSERT INTO schema_version (version) VALUES (4);
@@ -0,0 +1,3 @@
+DELETE FROM schema_version WHERE version = 5;
+
+DROP TABLE data_keys;
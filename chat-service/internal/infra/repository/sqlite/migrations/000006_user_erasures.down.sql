@@ -0,0 +1,5 @@
+DELETE FROM schema_version WHERE version = 6;
+
+DROP INDEX outbox_chat_idx;
+DROP INDEX annotations_user_idx;
+DROP TABLE user_erasures;
@@ -0,0 +1,3 @@
+DELETE FROM schema_version WHERE version = 7;
+
+DROP INDEX chats_updated_idx;
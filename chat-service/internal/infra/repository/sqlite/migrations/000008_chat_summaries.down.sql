@@ -0,0 +1,3 @@
+DELETE FROM schema_version WHERE version = 8;
+
+DROP TABLE chat_summaries;
@@ -0,0 +1,20 @@
+-- the API keys users issue to their programs. Only the hash of a key is
+-- stored, scopes is a JSON array.
+CREATE TABLE api_keys (
+    namespace  TEXT        NOT NULL,
+    id         TEXT        NOT NULL,
+    user_id    TEXT        NOT NULL,
+    org_id     TEXT        NOT NULL DEFAULT '',
+    name       TEXT        NOT NULL,
+    hash       TEXT        NOT NULL,
+    hint       TEXT        NOT NULL,
+    scopes     TEXT        NOT NULL DEFAULT '[]',
+    created_at DATETIME    NOT NULL,
+    revoked_at DATETIME,
+    PRIMARY KEY (namespace, id),
+    UNIQUE (namespace, hash)
+);
+
+CREATE INDEX api_keys_user_idx ON api_keys (namespace, user_id, created_at DESC);
+
+INSERT INTO schema_version (version) VALUES (9);
@@ -0,0 +1,40 @@
+-- the webhooks tenants register, events is a JSON array
+CREATE TABLE webhooks (
+    namespace  TEXT        NOT NULL,
+    id         TEXT        NOT NULL,
+    org_id     TEXT        NOT NULL,
+    url        TEXT        NOT NULL,
+    events     TEXT        NOT NULL DEFAULT '[]',
+    secret     TEXT        NOT NULL,
+    created_by TEXT        NOT NULL DEFAULT '',
+    created_at DATETIME    NOT NULL,
+    PRIMARY KEY (namespace, id)
+);
+
+CREATE INDEX webhooks_org_idx ON webhooks (namespace, org_id, created_at);
+
+-- a delivery is pending until delivered_at or failed_at is set, failed_at
+-- being set once it ran out of attempts
+CREATE TABLE webhook_deliveries (
+    namespace       TEXT        NOT NULL,
+    id              TEXT        NOT NULL,
+    webhook_id      TEXT        NOT NULL,
+    org_id          TEXT        NOT NULL,
+    event           TEXT        NOT NULL,
+    payload         TEXT        NOT NULL,
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME    NOT NULL,
+    delivered_at    DATETIME   ,
+    failed_at       DATETIME   ,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    created_at      DATETIME    NOT NULL,
+    PRIMARY KEY (namespace, id),
+    FOREIGN KEY (namespace, webhook_id) REFERENCES webhooks (namespace, id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (namespace, next_attempt_at)
+    WHERE delivered_at IS NULL AND failed_at IS NULL;
+CREATE INDEX webhook_deliveries_dead_idx ON webhook_deliveries (namespace, webhook_id, failed_at DESC)
+    WHERE failed_at IS NOT NULL;
+
+INSERT INTO schema_version (version) VALUES (10);
@@ -0,0 +1,3 @@
+DELETE FROM schema_version WHERE version = 11;
+
+DROP TABLE idempotency_keys;
@@ -0,0 +1,3 @@
+DELETE FROM schema_version WHERE version = 12;
+
+ALTER TABLE messages DROP COLUMN incomplete_reason;
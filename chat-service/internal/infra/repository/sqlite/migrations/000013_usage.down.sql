@@ -0,0 +1,3 @@
+DELETE FROM schema_version WHERE version = 13;
+
+DROP TABLE usage_records;
@@ -0,0 +1,3 @@
+DELETE FROM schema_version WHERE version = 14;
+
+ALTER TABLE usage_records DROP COLUMN unpriced_turns;
@@ -0,0 +1,4 @@
+-- the turns of a model of no known price, whose tokens the cost misses
+ALTER TABLE usage_records ADD COLUMN unpriced_turns INTEGER NOT NULL DEFAULT 0;
+
+INSERT INTO schema_version (version) VALUES (14);
@@ -0,0 +1,3 @@
+DELETE FROM schema_version WHERE version = 15;
+
+DROP TABLE audit_log;
@@ -0,0 +1,26 @@
+-- the audit trail of the turns, append only, prompt and response being kept
+-- as redaction tells: hashed, stripped of personal data or in full
+CREATE TABLE audit_log (
+    namespace         TEXT        NOT NULL,
+    id                TEXT        NOT NULL,
+    created_at        DATETIME    NOT NULL,
+    org_id            TEXT        NOT NULL,
+    user_id           TEXT        NOT NULL,
+    chat_id           TEXT        NOT NULL,
+    message_id        TEXT        NOT NULL,
+    request_id        TEXT        NOT NULL,
+    model             TEXT        NOT NULL,
+    redaction         TEXT        NOT NULL,
+    prompt            TEXT        NOT NULL,
+    response          TEXT        NOT NULL,
+    prompt_tokens     INTEGER     NOT NULL,
+    completion_tokens INTEGER     NOT NULL,
+    finish_reason     TEXT        NOT NULL,
+    PRIMARY KEY (namespace, id)
+);
+
+CREATE INDEX audit_log_created_idx ON audit_log (namespace, created_at);
+CREATE INDEX audit_log_org_idx ON audit_log (namespace, org_id, created_at);
+CREATE INDEX audit_log_user_idx ON audit_log (namespace, user_id, created_at);
+
+INSERT INTO schema_version (version) VALUES (15);
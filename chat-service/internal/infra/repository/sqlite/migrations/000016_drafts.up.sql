@@ -0,0 +1,13 @@
+-- the message each user is typing in a chat, one per chat and user, gone
+-- with the chat
+CREATE TABLE drafts (
+    namespace  TEXT        NOT NULL,
+    chat_id    TEXT        NOT NULL,
+    user_id    TEXT        NOT NULL,
+    content    TEXT        NOT NULL,
+    updated_at DATETIME    NOT NULL,
+    PRIMARY KEY (namespace, chat_id, user_id),
+    FOREIGN KEY (namespace, chat_id) REFERENCES chats (namespace, id) ON DELETE CASCADE
+);
+
+INSERT INTO schema_version (version) VALUES (16);
@@ -0,0 +1,6 @@
+DELETE FROM schema_version WHERE version = 17;
+
+DROP INDEX outbox_unpublished_idx;
+CREATE INDEX outbox_unpublished_idx ON outbox (namespace, seq) WHERE published_at IS NULL;
+
+ALTER TABLE outbox DROP COLUMN dead_lettered_at;
@@ -0,0 +1,7 @@
+-- the entries out of attempts are dead-lettered, no longer relayed
+ALTER TABLE outbox ADD COLUMN dead_lettered_at DATETIME;
+
+DROP INDEX outbox_unpublished_idx;
+CREATE INDEX outbox_unpublished_idx ON outbox (namespace, seq) WHERE published_at IS NULL AND dead_lettered_at IS NULL;
+
+INSERT INTO schema_version (version) VALUES (17);
@@ -0,0 +1,5 @@
+DELETE FROM schema_version WHERE version = 18;
+
+DROP INDEX chats_org_updated_idx;
+
+ALTER TABLE chats DROP COLUMN org_id;
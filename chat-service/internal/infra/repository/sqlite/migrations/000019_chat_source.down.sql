@@ -0,0 +1,5 @@
+DELETE FROM schema_version WHERE version = 19;
+
+DROP INDEX chats_source_idx;
+
+ALTER TABLE chats DROP COLUMN source_id;
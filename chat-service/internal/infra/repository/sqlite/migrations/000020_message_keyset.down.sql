@@ -0,0 +1,3 @@
+DELETE FROM schema_version WHERE version = 20;
+
+DROP INDEX messages_chat_created_idx;
@@ -0,0 +1,58 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+type AnnotationRepository struct {
+	db *sql.DB
+}
+
+func NewAnnotationRepository(db *sql.DB) *AnnotationRepository {
+	return &AnnotationRepository{db: db}
+}
+
+func (r *AnnotationRepository) CreateAnnotation(ctx context.Context, annotation *entity.Annotation) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `INSERT INTO annotations (namespace, id, chat_id, message_id, user_id,
+		kind, start_offset, end_offset, text, created_at)
+		SELECT $1, $2, chat_id, $3, $4, $5, $6, $7, $8, $9 FROM messages WHERE namespace = $1 AND id = $3`,
+		ns, annotation.ID, annotation.MessageID, annotation.UserID, annotation.Kind, annotation.Start,
+		annotation.End, annotation.Text, utc(annotation.CreatedAt))
+	return err
+}
+
+func (r *AnnotationRepository) DeleteAnnotation(ctx context.Context, annotationID string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM annotations WHERE namespace = $1 AND id = $2`, ns, annotationID)
+	return err
+}
+
+func loadAnnotations(ctx context.Context, q querier, ns, chatID string, messages map[string]*entity.Message) error {
+	rows, err := q.QueryContext(ctx, `SELECT id, message_id, user_id, kind, start_offset, end_offset, text, created_at
+		FROM annotations WHERE namespace = $1 AND chat_id = $2 ORDER BY created_at`, ns, chatID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a := &entity.Annotation{}
+		if err := rows.Scan(&a.ID, &a.MessageID, &a.UserID, &a.Kind, &a.Start, &a.End, &a.Text, &a.CreatedAt); err != nil {
+			return err
+		}
+		if m, ok := messages[a.MessageID]; ok {
+			m.AddAnnotation(a)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,117 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, user_id, org_id, name, hash, hint, scopes, created_at, revoked_at`
+
+func (r *APIKeyRepository) CreateAPIKey(ctx context.Context, key *entity.APIKey) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `INSERT INTO api_keys (namespace, `+apiKeyColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		ns, key.ID, key.UserID, key.OrgID, key.Name, key.Hash, key.Hint, string(scopes),
+		utc(key.CreatedAt), nullTime(key.RevokedAt))
+	return err
+}
+
+func (r *APIKeyRepository) FindAPIKeyByID(ctx context.Context, id string) (*entity.APIKey, error) {
+	return r.findAPIKey(ctx, "id", id)
+}
+
+func (r *APIKeyRepository) FindAPIKeyByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	return r.findAPIKey(ctx, "hash", hash)
+}
+
+func (r *APIKeyRepository) findAPIKey(ctx context.Context, column, value string) (*entity.APIKey, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	row := conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys
+		WHERE namespace = $1 AND `+column+` = $2`, ns, value)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, gateway.ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+func (r *APIKeyRepository) ListAPIKeysByUser(ctx context.Context, userID string) ([]*entity.APIKey, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys
+		WHERE namespace = $1 AND user_id = $2 ORDER BY created_at DESC, id`, ns, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []*entity.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *APIKeyRepository) RevokeAPIKey(ctx context.Context, key *entity.APIKey) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE api_keys SET revoked_at = $3
+		WHERE namespace = $1 AND id = $2`, ns, key.ID, nullTime(key.RevokedAt))
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return gateway.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func scanAPIKey(s scanner) (*entity.APIKey, error) {
+	key := &entity.APIKey{}
+	var scopes string
+	var revokedAt sql.NullTime
+	if err := s.Scan(&key.ID, &key.UserID, &key.OrgID, &key.Name, &key.Hash, &key.Hint, &scopes,
+		&key.CreatedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, err
+	}
+	key.RevokedAt = timeOf(revokedAt)
+	return key, nil
+}
@@ -0,0 +1,58 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+type AttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewAttachmentRepository(db *sql.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+func (r *AttachmentRepository) CreateAttachment(ctx context.Context, attachment *entity.Attachment) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `INSERT INTO attachments (namespace, id, chat_id, message_id, name,
+		mime_type, size, storage_key, created_at)
+		SELECT $1, $2, chat_id, $3, $4, $5, $6, $7, $8 FROM messages WHERE namespace = $1 AND id = $3`,
+		ns, attachment.ID, attachment.MessageID, attachment.Name, attachment.MimeType, attachment.Size,
+		attachment.StorageKey, utc(attachment.CreatedAt))
+	return err
+}
+
+func (r *AttachmentRepository) DeleteAttachment(ctx context.Context, attachmentID string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM attachments WHERE namespace = $1 AND id = $2`, ns, attachmentID)
+	return err
+}
+
+func loadAttachments(ctx context.Context, q querier, ns, chatID string, messages map[string]*entity.Message) error {
+	rows, err := q.QueryContext(ctx, `SELECT id, message_id, name, mime_type, size, storage_key, created_at
+		FROM attachments WHERE namespace = $1 AND chat_id = $2 ORDER BY created_at`, ns, chatID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		a := &entity.Attachment{}
+		if err := rows.Scan(&a.ID, &a.MessageID, &a.Name, &a.MimeType, &a.Size, &a.StorageKey, &a.CreatedAt); err != nil {
+			return err
+		}
+		if m, ok := messages[a.MessageID]; ok {
+			m.AddAttachment(a)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,108 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+// AuditLogRepository is the AuditLogGateway on the audit_log table, it only
+// inserts rows. The prompts and responses are encrypted with cipher, when
+// set, like the content of the messages.
+type AuditLogRepository struct {
+	db     *sql.DB
+	cipher ContentCipher
+}
+
+func NewAuditLogRepository(db *sql.DB, cipher ContentCipher) *AuditLogRepository {
+	return &AuditLogRepository{db: db, cipher: cipher}
+}
+
+func (r *AuditLogRepository) AppendAuditEntry(ctx context.Context, entry *entity.AuditEntry) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	prompt, response := entry.Prompt, entry.Response
+	if r.cipher != nil && entry.Redaction != entity.AuditRedactionHash {
+		if prompt, err = r.cipher.Encrypt(ctx, entry.OrgID, prompt); err != nil {
+			return fmt.Errorf("error encrypting audit prompt: %s", err.Error())
+		}
+		if response, err = r.cipher.Encrypt(ctx, entry.OrgID, response); err != nil {
+			return fmt.Errorf("error encrypting audit response: %s", err.Error())
+		}
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `INSERT INTO audit_log
+		(namespace, id, created_at, org_id, user_id, chat_id, message_id, request_id, model,
+			redaction, prompt, response, prompt_tokens, completion_tokens, finish_reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+		ns, entry.ID, utc(entry.CreatedAt), entry.OrgID, entry.UserID, entry.ChatID, entry.MessageID, entry.RequestID, entry.Model,
+		string(entry.Redaction), prompt, response, entry.PromptTokens, entry.CompletionTokens, entry.FinishReason)
+	return err
+}
+
+func (r *AuditLogRepository) ListAuditEntries(ctx context.Context, filter gateway.AuditFilter) ([]*entity.AuditEntry, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	where := []string{"namespace = $1"}
+	args := []interface{}{ns}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if filter.OrgID != "" {
+		where = append(where, "org_id = "+arg(filter.OrgID))
+	}
+	if filter.UserID != "" {
+		where = append(where, "user_id = "+arg(filter.UserID))
+	}
+	if filter.ChatID != "" {
+		where = append(where, "chat_id = "+arg(filter.ChatID))
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "created_at >= "+arg(utc(filter.From)))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "created_at < "+arg(utc(filter.To)))
+	}
+	query := `SELECT id, created_at, org_id, user_id, chat_id, message_id, request_id, model,
+		redaction, prompt, response, prompt_tokens, completion_tokens, finish_reason
+		FROM audit_log WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY created_at DESC, id DESC`
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []*entity.AuditEntry
+	for rows.Next() {
+		e := &entity.AuditEntry{}
+		var redaction string
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.OrgID, &e.UserID, &e.ChatID, &e.MessageID, &e.RequestID, &e.Model,
+			&redaction, &e.Prompt, &e.Response, &e.PromptTokens, &e.CompletionTokens, &e.FinishReason); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = e.CreatedAt.UTC()
+		e.Redaction = entity.AuditRedaction(redaction)
+		if r.cipher != nil {
+			if e.Prompt, err = r.cipher.Decrypt(ctx, e.Prompt); err != nil {
+				return nil, err
+			}
+			if e.Response, err = r.cipher.Decrypt(ctx, e.Response); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,620 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+const chatColumns = `id, user_id, title, parent_chat_id, forked_from_message_id, persona_id,
+	initial_message_id, status, answer_stage, system_fingerprint, summary_message_id,
+	token_usage, config, created_at, updated_at, deleted_at, expires_at, version, org_id, source_id`
+
+// ChatRepository is the ChatGateway on SQL databases, Postgres and SQLite.
+// Every query is scoped to the namespace of the context.
+type ChatRepository struct {
+	db     *sql.DB
+	cipher ContentCipher
+	clock  clock.Clock
+}
+
+// ContentCipher encrypts message content before it is stored and decrypts it
+// when read, see package encryption.
+type ContentCipher interface {
+	Encrypt(ctx context.Context, orgID string, plaintext string) (string, error)
+	Decrypt(ctx context.Context, stored string) (string, error)
+}
+
+type ChatRepositoryOption func(r *ChatRepository)
+
+// WithContentCipher stores message content encrypted with cipher.
+func WithContentCipher(cipher ContentCipher) ChatRepositoryOption {
+	return func(r *ChatRepository) {
+		r.cipher = cipher
+	}
+}
+
+// WithClock stamps the outbox entries and the partial updates with clk
+// instead of the real clock.
+func WithClock(clk clock.Clock) ChatRepositoryOption {
+	return func(r *ChatRepository) {
+		r.clock = clk
+	}
+}
+
+func NewChatRepository(db *sql.DB, opts ...ChatRepositoryOption) *ChatRepository {
+	r := &ChatRepository{db: db, clock: clock.Real()}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *ChatRepository) CreateChat(ctx context.Context, chat *entity.Chat) error {
+	return r.saveChat(ctx, chat, true)
+}
+
+func (r *ChatRepository) SaveChat(ctx context.Context, chat *entity.Chat) error {
+	return r.saveChat(ctx, chat, false)
+}
+
+func (r *ChatRepository) saveChat(ctx context.Context, chat *entity.Chat, create bool) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	config, err := encodeConfig(chat.Config)
+	if err != nil {
+		return fmt.Errorf("error encoding chat config: %s", err.Error())
+	}
+	events, err := entity.NewOutboxEntries(chat.ID, chat.PendingEvents(), r.clock.Now())
+	if err != nil {
+		return err
+	}
+	version := chat.Version + 1
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		query := `INSERT INTO chats (namespace, ` + chatColumns + `)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)`
+		if !create {
+			query += ` ON CONFLICT (namespace, id) DO UPDATE SET
+				title = EXCLUDED.title,
+				persona_id = EXCLUDED.persona_id,
+				initial_message_id = EXCLUDED.initial_message_id,
+				status = EXCLUDED.status,
+				answer_stage = EXCLUDED.answer_stage,
+				system_fingerprint = EXCLUDED.system_fingerprint,
+				summary_message_id = EXCLUDED.summary_message_id,
+				token_usage = EXCLUDED.token_usage,
+				config = EXCLUDED.config,
+				updated_at = EXCLUDED.updated_at,
+				deleted_at = EXCLUDED.deleted_at,
+				expires_at = EXCLUDED.expires_at,
+				version = EXCLUDED.version
+				WHERE chats.version = EXCLUDED.version - 1`
+		}
+		initialMessageID := ""
+		if chat.InitialSystemMessage != nil {
+			initialMessageID = chat.InitialSystemMessage.ID
+		}
+		result, err := tx.ExecContext(ctx, query, ns, chat.ID, chat.UserID, chat.Title, chat.ParentChatID,
+			chat.ForkedFromMessageID, chat.PersonaID, initialMessageID, chat.Status, chat.AnswerStage,
+			chat.SystemFingerprint, chat.SummaryMessageID, chat.TokenUsage, config, utc(chat.CreatedAt),
+			utc(chat.UpdatedAt), nullTime(chat.DeletedAt), nullTime(chat.ExpiresAt), version, chat.OrgID, chat.SourceID)
+		if err != nil {
+			return err
+		}
+		if err := expectRows(result, gateway.ErrChatConflict); err != nil {
+			return err
+		}
+		if err := r.saveMessages(ctx, tx, ns, chat); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM chat_tags WHERE namespace = $1 AND chat_id = $2`, ns, chat.ID); err != nil {
+			return err
+		}
+		for _, tag := range chat.Tags {
+			_, err := tx.ExecContext(ctx, `INSERT INTO chat_tags (namespace, chat_id, tag) VALUES ($1, $2, $3)
+				ON CONFLICT DO NOTHING`, ns, chat.ID, tag)
+			if err != nil {
+				return err
+			}
+		}
+		return insertOutboxEntries(ctx, tx, ns, events)
+	}, func() {
+		chat.Version = version
+		chat.MarkEventsSaved()
+	})
+}
+
+// saveMessages rewrites the messages of the chat, annotations and attachments
+// reference them by ID and are kept.
+func (r *ChatRepository) saveMessages(ctx context.Context, tx *sql.Tx, ns string, chat *entity.Chat) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE namespace = $1 AND chat_id = $2`, ns, chat.ID); err != nil {
+		return err
+	}
+	saved := make(map[string]bool)
+	insert := func(m *entity.Message, state string, position int) error {
+		if saved[m.ID] {
+			return nil
+		}
+		saved[m.ID] = true
+		modelName, modelMaxTokens := "", 0
+		if m.Model != nil {
+			modelName, modelMaxTokens = m.Model.Name, m.Model.MaxToken
+		}
+		content, err := r.encrypt(ctx, chat.OrgID, m.Content)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO messages (namespace, id, chat_id, state, position, role,
+			content, tokens, model_name, model_max_tokens, prompt_tokens, time_to_first_token_ns,
+			generation_latency_ns, pinned, starred, created_at, incomplete_reason)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`,
+			ns, m.ID, chat.ID, state, position, m.Role.String(), content, m.Tokens, modelName,
+			modelMaxTokens, m.PromptTokens, int64(m.TimeToFirstToken), int64(m.GenerationLatency),
+			m.Pinned, m.Starred, utc(m.CreatedAt), m.IncompleteReason)
+		return err
+	}
+	for i, m := range chat.Messages {
+		if err := insert(m, messageActive, i); err != nil {
+			return err
+		}
+	}
+	for i, m := range chat.ErasedMessages {
+		if err := insert(m, messageErased, i); err != nil {
+			return err
+		}
+	}
+	if chat.InitialSystemMessage != nil {
+		return insert(chat.InitialSystemMessage, messageInitial, 0)
+	}
+	return nil
+}
+
+func (r *ChatRepository) encrypt(ctx context.Context, orgID string, content string) (string, error) {
+	if r.cipher == nil {
+		return content, nil
+	}
+	encrypted, err := r.cipher.Encrypt(ctx, orgID, content)
+	if err != nil {
+		return "", fmt.Errorf("error encrypting message content: %s", err.Error())
+	}
+	return encrypted, nil
+}
+
+func (r *ChatRepository) decrypt(ctx context.Context, content string) (string, error) {
+	if r.cipher == nil {
+		return content, nil
+	}
+	return r.cipher.Decrypt(ctx, content)
+}
+
+func (r *ChatRepository) FindChatByID(ctx context.Context, chatID string) (*entity.Chat, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	row := conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+chatColumns+` FROM chats
+		WHERE namespace = $1 AND id = $2 AND deleted_at IS NULL`, ns, chatID)
+	chat, err := scanChat(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, gateway.ErrChatNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadChat(ctx, ns, chat); err != nil {
+		return nil, err
+	}
+	return chat, nil
+}
+
+func (r *ChatRepository) FindChatsByParentID(ctx context.Context, parentChatID string) ([]*entity.Chat, error) {
+	return r.findChats(ctx, true, `parent_chat_id = $2 AND deleted_at IS NULL ORDER BY created_at`, parentChatID)
+}
+
+func (r *ChatRepository) FindExpiredChats(ctx context.Context, expiredBefore time.Time, limit int) ([]*entity.Chat, error) {
+	return r.findChats(ctx, true, `expires_at < $2 AND status <> $3 AND deleted_at IS NULL
+		ORDER BY expires_at LIMIT $4`, utc(expiredBefore), entity.ChatStatusArchived, limit)
+}
+
+func (r *ChatRepository) ListChats(ctx context.Context, filter gateway.ChatFilter) ([]*entity.Chat, error) {
+	where := []string{"deleted_at IS NULL"}
+	args := []interface{}{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args)+1)
+	}
+	if filter.UserID != "" {
+		where = append(where, "user_id = "+arg(filter.UserID))
+	}
+	if filter.Status != "" {
+		where = append(where, "status = "+arg(filter.Status))
+	}
+	if filter.SourceID != "" {
+		where = append(where, "source_id = "+arg(filter.SourceID))
+	}
+	if filter.Tag != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM chat_tags t WHERE t.namespace = chats.namespace AND t.chat_id = chats.id AND t.tag = "+arg(filter.Tag)+")")
+	}
+	if !filter.CreatedAfter.IsZero() {
+		where = append(where, "created_at > "+arg(utc(filter.CreatedAfter)))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		where = append(where, "created_at < "+arg(utc(filter.CreatedBefore)))
+	}
+	query := strings.Join(where, " AND ") + " ORDER BY updated_at DESC, id"
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	} else if filter.Offset > 0 {
+		// SQLite has no OFFSET without LIMIT
+		query += " LIMIT " + arg(int64(math.MaxInt64))
+	}
+	if filter.Offset > 0 {
+		query += " OFFSET " + arg(filter.Offset)
+	}
+	return r.findChats(ctx, false, query, args...)
+}
+
+func (r *ChatRepository) ListChatsByUser(ctx context.Context, userID string, page int, size int) (*gateway.ChatPage, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	chats, err := r.findChats(ctx, false, `user_id = $2 AND deleted_at IS NULL ORDER BY updated_at DESC, id
+		LIMIT $3 OFFSET $4`, userID, size, (page-1)*size)
+	if err != nil {
+		return nil, err
+	}
+	result := &gateway.ChatPage{Chats: chats}
+	err = conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM chats
+		WHERE namespace = $1 AND user_id = $2 AND deleted_at IS NULL`, ns, userID).Scan(&result.Total)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *ChatRepository) ListMessages(ctx context.Context, chatID string, cursor gateway.MessageCursor) (*gateway.MessagePage, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q := conn(ctx, r.db)
+	page := &gateway.MessagePage{}
+	err = q.QueryRowContext(ctx, `SELECT user_id FROM chats WHERE namespace = $1 AND id = $2 AND deleted_at IS NULL`,
+		ns, chatID).Scan(&page.ChatUserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, gateway.ErrChatNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + messageColumns + ` FROM messages WHERE namespace = $1 AND chat_id = $2 AND state = $3`
+	args := []interface{}{ns, chatID, messageActive}
+	if cursor.Before != "" {
+		// positions are rewritten as the history changes, creation times
+		// aren't, and the message stays in any state
+		var before time.Time
+		err := q.QueryRowContext(ctx, `SELECT created_at FROM messages WHERE namespace = $1 AND chat_id = $2 AND id = $3`,
+			ns, chatID, cursor.Before).Scan(&before)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, gateway.ErrInvalidCursor
+		}
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, before, cursor.Before)
+		query += ` AND (created_at < $4 OR (created_at = $4 AND id < $5))`
+	}
+	query += ` ORDER BY created_at DESC, id DESC`
+	if cursor.Limit > 0 {
+		// one more tells whether there is a next page
+		args = append(args, cursor.Limit+1)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	messages := make(map[string]*entity.Message)
+	for rows.Next() {
+		m, _, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		if m.Content, err = r.decrypt(ctx, m.Content); err != nil {
+			return nil, err
+		}
+		if cursor.Limit > 0 && len(page.Messages) == cursor.Limit {
+			page.Next = &gateway.MessageCursor{Before: page.Messages[len(page.Messages)-1].ID, Limit: cursor.Limit}
+			break
+		}
+		page.Messages = append(page.Messages, m)
+		messages[m.ID] = m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	for i, j := 0, len(page.Messages)-1; i < j; i, j = i+1, j-1 {
+		page.Messages[i], page.Messages[j] = page.Messages[j], page.Messages[i]
+	}
+	if err := loadAnnotations(ctx, q, ns, chatID, messages); err != nil {
+		return nil, err
+	}
+	if err := loadAttachments(ctx, q, ns, chatID, messages); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// findChats runs a query on chats with $1 bound to the namespace. Messages are
+// only loaded when withMessages is set, tags always are.
+func (r *ChatRepository) findChats(ctx context.Context, withMessages bool, where string, args ...interface{}) ([]*entity.Chat, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT `+chatColumns+` FROM chats WHERE namespace = $1 AND `+where,
+		append([]interface{}{ns}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var chats []*entity.Chat
+	for rows.Next() {
+		chat, err := scanChat(rows)
+		if err != nil {
+			return nil, err
+		}
+		chats = append(chats, chat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	for _, chat := range chats {
+		if withMessages {
+			err = r.loadChat(ctx, ns, chat)
+		} else {
+			chat.Tags, err = loadTags(ctx, conn(ctx, r.db), ns, chat.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return chats, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanChat(s scanner) (*entity.Chat, error) {
+	chat := &entity.Chat{}
+	var initialMessageID string
+	var config []byte
+	var deletedAt, expiresAt sql.NullTime
+	err := s.Scan(&chat.ID, &chat.UserID, &chat.Title, &chat.ParentChatID, &chat.ForkedFromMessageID,
+		&chat.PersonaID, &initialMessageID, &chat.Status, &chat.AnswerStage, &chat.SystemFingerprint,
+		&chat.SummaryMessageID, &chat.TokenUsage, &config, &chat.CreatedAt, &chat.UpdatedAt,
+		&deletedAt, &expiresAt, &chat.Version, &chat.OrgID, &chat.SourceID)
+	if err != nil {
+		return nil, err
+	}
+	chat.Config, err = decodeConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding config of chat %s: %s", chat.ID, err.Error())
+	}
+	chat.DeletedAt = timeOf(deletedAt)
+	chat.ExpiresAt = timeOf(expiresAt)
+	// resolved by loadChat
+	chat.InitialSystemMessage = &entity.Message{ID: initialMessageID}
+	return chat, nil
+}
+
+// loadChat fills the messages, with their annotations and attachments, and
+// the tags of chat.
+func (r *ChatRepository) loadChat(ctx context.Context, ns string, chat *entity.Chat) error {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT `+messageColumns+`
+		FROM messages WHERE namespace = $1 AND chat_id = $2 ORDER BY state, position`, ns, chat.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	messages := make(map[string]*entity.Message)
+	initialMessageID := chat.InitialSystemMessage.ID
+	chat.InitialSystemMessage = nil
+	for rows.Next() {
+		m, state, err := scanMessage(rows)
+		if err != nil {
+			return err
+		}
+		if m.Content, err = r.decrypt(ctx, m.Content); err != nil {
+			return err
+		}
+		messages[m.ID] = m
+		switch state {
+		case messageActive:
+			chat.Messages = append(chat.Messages, m)
+		case messageErased:
+			chat.ErasedMessages = append(chat.ErasedMessages, m)
+		}
+		if m.ID == initialMessageID {
+			chat.InitialSystemMessage = m
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	if err := loadAnnotations(ctx, conn(ctx, r.db), ns, chat.ID, messages); err != nil {
+		return err
+	}
+	if err := loadAttachments(ctx, conn(ctx, r.db), ns, chat.ID, messages); err != nil {
+		return err
+	}
+	chat.Tags, err = loadTags(ctx, conn(ctx, r.db), ns, chat.ID)
+	return err
+}
+
+const messageColumns = `id, state, role, content, tokens, model_name, model_max_tokens, prompt_tokens,
+	time_to_first_token_ns, generation_latency_ns, pinned, starred, created_at, incomplete_reason`
+
+// scanMessage scans the messageColumns, then the extra columns into extra.
+func scanMessage(s scanner, extra ...interface{}) (*entity.Message, string, error) {
+	m := &entity.Message{Model: &entity.Model{}}
+	var state, role string
+	var timeToFirstToken, generationLatency int64
+	dest := []interface{}{&m.ID, &state, &role, &m.Content, &m.Tokens, &m.Model.Name, &m.Model.MaxToken,
+		&m.PromptTokens, &timeToFirstToken, &generationLatency, &m.Pinned, &m.Starred, &m.CreatedAt, &m.IncompleteReason}
+	err := s.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, "", err
+	}
+	if m.Role, err = entity.ParseRole(role); err != nil {
+		return nil, "", fmt.Errorf("message %s: %s", m.ID, err.Error())
+	}
+	m.TimeToFirstToken = time.Duration(timeToFirstToken)
+	m.GenerationLatency = time.Duration(generationLatency)
+	return m, state, nil
+}
+
+func loadTags(ctx context.Context, q querier, ns, chatID string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT tag FROM chat_tags WHERE namespace = $1 AND chat_id = $2 ORDER BY tag`, ns, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (r *ChatRepository) UpdateChatTitle(ctx context.Context, chatID string, title string) error {
+	return r.updateChat(ctx, `title = $3, updated_at = $4`, chatID, title, utc(r.clock.Now()))
+}
+
+func (r *ChatRepository) DeleteChat(ctx context.Context, chatID string, deletedAt time.Time) error {
+	return r.updateChat(ctx, `deleted_at = $3`, chatID, utc(deletedAt))
+}
+
+func (r *ChatRepository) updateChat(ctx context.Context, set string, chatID string, args ...interface{}) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE chats SET `+set+`, version = version + 1
+		WHERE namespace = $1 AND id = $2 AND deleted_at IS NULL`, append([]interface{}{ns, chatID}, args...)...)
+	if err != nil {
+		return err
+	}
+	return expectRows(result, gateway.ErrChatNotFound)
+}
+
+func (r *ChatRepository) AddChatTag(ctx context.Context, chatID string, tag string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO chat_tags (namespace, chat_id, tag) VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING`, ns, chatID, tag)
+		if err != nil {
+			return err
+		}
+		return bumpVersion(ctx, tx, ns, chatID)
+	}, nil)
+}
+
+func (r *ChatRepository) RemoveChatTag(ctx context.Context, chatID string, tag string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM chat_tags WHERE namespace = $1 AND chat_id = $2 AND tag = $3`, ns, chatID, tag)
+		if err != nil {
+			return err
+		}
+		return bumpVersion(ctx, tx, ns, chatID)
+	}, nil)
+}
+
+func (r *ChatRepository) SetMessageFlags(ctx context.Context, chatID string, messageID string, pinned bool, starred bool) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `UPDATE messages SET pinned = $4, starred = $5
+			WHERE namespace = $1 AND chat_id = $2 AND id = $3`, ns, chatID, messageID, pinned, starred)
+		if err != nil {
+			return err
+		}
+		if err := expectRows(result, entity.ErrMessageNotFound); err != nil {
+			return err
+		}
+		return bumpVersion(ctx, tx, ns, chatID)
+	}, nil)
+}
+
+// bumpVersion makes a SaveChat of a copy loaded before a partial update fail
+// instead of overwriting it.
+func bumpVersion(ctx context.Context, tx *sql.Tx, ns, chatID string) error {
+	_, err := tx.ExecContext(ctx, `UPDATE chats SET version = version + 1 WHERE namespace = $1 AND id = $2`, ns, chatID)
+	return err
+}
+
+func (r *ChatRepository) PurgeDeletedChats(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return r.purge(ctx, `deleted_at < $2`, utc(deletedBefore))
+}
+
+func (r *ChatRepository) PurgeExpiredChats(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	return r.purge(ctx, `expires_at < $2 AND status = $3`, utc(expiredBefore), entity.ChatStatusArchived)
+}
+
+// purge hard-deletes chats, their messages, tags, annotations and attachments
+// go with them by cascade.
+func (r *ChatRepository) purge(ctx context.Context, where string, args ...interface{}) (int64, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM chats WHERE namespace = $1 AND `+where, append([]interface{}{ns}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func expectRows(result sql.Result, notFound error) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+	return nil
+}
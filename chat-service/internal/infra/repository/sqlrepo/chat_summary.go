@@ -0,0 +1,167 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+const chatSummaryColumns = `chat_id, user_id, parent_chat_id, title, status, tags, model, message_count,
+	token_usage, last_message_at, created_at, updated_at, version, projected_at`
+
+// upsertChatSummary leaves a summary of a newer version of the chat alone.
+const upsertChatSummary = `INSERT INTO chat_summaries (namespace, ` + chatSummaryColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	ON CONFLICT (namespace, chat_id) DO UPDATE SET
+	user_id = EXCLUDED.user_id, parent_chat_id = EXCLUDED.parent_chat_id, title = EXCLUDED.title,
+	status = EXCLUDED.status, tags = EXCLUDED.tags, model = EXCLUDED.model,
+	message_count = EXCLUDED.message_count, token_usage = EXCLUDED.token_usage,
+	last_message_at = EXCLUDED.last_message_at, created_at = EXCLUDED.created_at,
+	updated_at = EXCLUDED.updated_at, version = EXCLUDED.version, projected_at = EXCLUDED.projected_at
+	WHERE chat_summaries.version <= EXCLUDED.version`
+
+// ChatSummaryRepository is the ChatReadModel on the chat_summaries table, on
+// Postgres or SQLite. db may be another database than the one of the chats.
+type ChatSummaryRepository struct {
+	db *sql.DB
+}
+
+func NewChatSummaryRepository(db *sql.DB) *ChatSummaryRepository {
+	return &ChatSummaryRepository{db: db}
+}
+
+func (r *ChatSummaryRepository) UpsertChatSummary(ctx context.Context, s *gateway.ChatSummary) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	tags, err := json.Marshal(s.Tags)
+	if err != nil {
+		return err
+	}
+	if s.Tags == nil {
+		tags = []byte("[]")
+	}
+	_, err = r.db.ExecContext(ctx, upsertChatSummary,
+		ns, s.ChatID, s.UserID, s.ParentChatID, s.Title, s.Status, string(tags), s.Model, s.MessageCount,
+		s.TokenUsage, nullTime(s.LastMessageAt), utc(s.CreatedAt), utc(s.UpdatedAt), s.Version, utc(s.ProjectedAt))
+	return err
+}
+
+func (r *ChatSummaryRepository) DeleteChatSummary(ctx context.Context, chatID string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `DELETE FROM chat_summaries WHERE namespace = $1 AND chat_id = $2`, ns, chatID)
+	return err
+}
+
+func (r *ChatSummaryRepository) ListChatSummaries(ctx context.Context, filter gateway.ChatSummaryFilter) ([]*gateway.ChatSummary, error) {
+	where, args, err := summaryFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT ` + chatSummaryColumns + ` FROM chat_summaries WHERE ` + where + ` ORDER BY updated_at DESC, chat_id`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	} else if filter.Offset > 0 {
+		// SQLite has no OFFSET without LIMIT
+		args = append(args, int64(math.MaxInt64))
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var summaries []*gateway.ChatSummary
+	for rows.Next() {
+		s := &gateway.ChatSummary{}
+		var tags string
+		var lastMessageAt sql.NullTime
+		err := rows.Scan(&s.ChatID, &s.UserID, &s.ParentChatID, &s.Title, &s.Status, &tags, &s.Model, &s.MessageCount,
+			&s.TokenUsage, &lastMessageAt, &s.CreatedAt, &s.UpdatedAt, &s.Version, &s.ProjectedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(tags), &s.Tags); err != nil {
+			return nil, fmt.Errorf("error decoding tags of chat summary %s: %s", s.ChatID, err.Error())
+		}
+		s.LastMessageAt = timeOf(lastMessageAt)
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+func (r *ChatSummaryRepository) CountChatSummaries(ctx context.Context, filter gateway.ChatSummaryFilter) (int, error) {
+	where, args, err := summaryFilter(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM chat_summaries WHERE `+where, args...).Scan(&n)
+	return n, err
+}
+
+func (r *ChatSummaryRepository) PurgeStaleChatSummaries(ctx context.Context, projectedBefore time.Time) (int64, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return exec(ctx, r.db, `DELETE FROM chat_summaries WHERE namespace = $1 AND projected_at < $2`, ns, utc(projectedBefore))
+}
+
+// summaryFilter returns the WHERE clause of filter, $1 is the namespace.
+func summaryFilter(ctx context.Context, filter gateway.ChatSummaryFilter) (string, []interface{}, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	where := []string{"namespace = $1"}
+	args := []interface{}{ns}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if filter.UserID != "" {
+		where = append(where, "user_id = "+arg(filter.UserID))
+	}
+	if filter.Status != "" {
+		where = append(where, "status = "+arg(filter.Status))
+	}
+	if filter.Tag != "" {
+		// tags is a JSON array, the quoted tag only matches a whole element
+		tag, err := json.Marshal(filter.Tag)
+		if err != nil {
+			return "", nil, err
+		}
+		where = append(where, "tags LIKE "+arg("%"+escapeLike(string(tag))+"%")+" ESCAPE '!'")
+	}
+	if filter.Query != "" {
+		where = append(where, "LOWER(title) LIKE "+arg("%"+escapeLike(strings.ToLower(filter.Query))+"%")+" ESCAPE '!'")
+	}
+	if !filter.CreatedAfter.IsZero() {
+		where = append(where, "created_at > "+arg(utc(filter.CreatedAfter)))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		where = append(where, "created_at < "+arg(utc(filter.CreatedBefore)))
+	}
+	return strings.Join(where, " AND "), args, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
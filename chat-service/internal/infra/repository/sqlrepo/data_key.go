@@ -0,0 +1,43 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// DataKeyRepository is the encryption.KeyStore on the data_keys table. It
+// runs outside of any unit of work so a data key is never rolled back with
+// content it already encrypted. The SQLite handle has a single connection,
+// which a unit of work holds: prepare the keys before serving there.
+type DataKeyRepository struct {
+	db *sql.DB
+}
+
+func NewDataKeyRepository(db *sql.DB) *DataKeyRepository {
+	return &DataKeyRepository{db: db}
+}
+
+func (r *DataKeyRepository) FindDataKey(ctx context.Context, ns string) ([]byte, bool, error) {
+	var wrapped []byte
+	err := r.db.QueryRowContext(ctx, `SELECT wrapped_key FROM data_keys WHERE namespace = $1`, ns).Scan(&wrapped)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return wrapped, true, nil
+}
+
+func (r *DataKeyRepository) CreateDataKey(ctx context.Context, ns string, wrapped []byte) ([]byte, error) {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO data_keys (namespace, wrapped_key, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (namespace) DO NOTHING`, ns, wrapped, utc(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	var stored []byte
+	err = r.db.QueryRowContext(ctx, `SELECT wrapped_key FROM data_keys WHERE namespace = $1`, ns).Scan(&stored)
+	return stored, err
+}
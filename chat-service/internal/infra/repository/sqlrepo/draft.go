@@ -0,0 +1,60 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+type DraftRepository struct {
+	db *sql.DB
+}
+
+func NewDraftRepository(db *sql.DB) *DraftRepository {
+	return &DraftRepository{db: db}
+}
+
+func (r *DraftRepository) SaveDraft(ctx context.Context, draft *entity.Draft) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `INSERT INTO drafts (namespace, chat_id, user_id, content, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (namespace, chat_id, user_id) DO UPDATE SET
+		content = EXCLUDED.content, updated_at = EXCLUDED.updated_at`,
+		ns, draft.ChatID, draft.UserID, draft.Content, utc(draft.UpdatedAt))
+	return err
+}
+
+func (r *DraftRepository) FindDraft(ctx context.Context, chatID, userID string) (*entity.Draft, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	draft := &entity.Draft{}
+	err = conn(ctx, r.db).QueryRowContext(ctx, `SELECT chat_id, user_id, content, updated_at
+		FROM drafts WHERE namespace = $1 AND chat_id = $2 AND user_id = $3`, ns, chatID, userID).
+		Scan(&draft.ChatID, &draft.UserID, &draft.Content, &draft.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, gateway.ErrDraftNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+func (r *DraftRepository) DeleteDraft(ctx context.Context, chatID, userID string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM drafts
+		WHERE namespace = $1 AND chat_id = $2 AND user_id = $3`, ns, chatID, userID)
+	return err
+}
@@ -0,0 +1,195 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"github.com/google/uuid"
+)
+
+// EmbeddingDimensions is the size of the vector column of the embeddings
+// migration.
+const EmbeddingDimensions = 1536
+
+const embeddingColumns = `id, source_type, source_id, COALESCE(chat_id, ''), user_id, model, content, metadata, embedding::text, created_at, updated_at`
+
+// upsertEmbedding keeps the id and created_at of the embedding it replaces.
+const upsertEmbedding = `INSERT INTO embeddings (namespace, id, source_type, source_id, chat_id, user_id, model, content, metadata, embedding, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10::vector, $11, $11)
+	ON CONFLICT (namespace, source_type, source_id, model) DO UPDATE SET
+	chat_id = EXCLUDED.chat_id, user_id = EXCLUDED.user_id, content = EXCLUDED.content,
+	metadata = EXCLUDED.metadata, embedding = EXCLUDED.embedding, updated_at = EXCLUDED.updated_at
+	RETURNING id, created_at`
+
+// EmbeddingRepository is the EmbeddingGateway on the pgvector embeddings
+// table, Postgres only.
+type EmbeddingRepository struct {
+	db *sql.DB
+}
+
+func NewEmbeddingRepository(db *sql.DB) *EmbeddingRepository {
+	return &EmbeddingRepository{db: db}
+}
+
+func (r *EmbeddingRepository) UpsertEmbeddings(ctx context.Context, embeddings []*gateway.Embedding) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, e := range embeddings {
+		if len(e.Vector) != EmbeddingDimensions {
+			return fmt.Errorf("%w: %s %s has %d, expected %d", gateway.ErrEmbeddingDimensions, e.SourceType, e.SourceID, len(e.Vector), EmbeddingDimensions)
+		}
+	}
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		for _, e := range embeddings {
+			if e.ID == "" {
+				e.ID = uuid.New().String()
+			}
+			if e.UpdatedAt.IsZero() {
+				e.UpdatedAt = time.Now()
+			}
+			metadata, err := encodeMetadata(e.Metadata)
+			if err != nil {
+				return err
+			}
+			err = tx.QueryRowContext(ctx, upsertEmbedding,
+				ns, e.ID, e.SourceType, e.SourceID, e.ChatID, e.UserID, e.Model, e.Content,
+				metadata, encodeVector(e.Vector), utc(e.UpdatedAt),
+			).Scan(&e.ID, &e.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("error upserting embedding of %s %s: %s", e.SourceType, e.SourceID, err.Error())
+			}
+		}
+		return nil
+	}, nil)
+}
+
+func (r *EmbeddingRepository) SearchEmbeddings(ctx context.Context, query gateway.EmbeddingQuery) ([]*gateway.EmbeddingMatch, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(query.Vector) != EmbeddingDimensions {
+		return nil, fmt.Errorf("%w: the query has %d, expected %d", gateway.ErrEmbeddingDimensions, len(query.Vector), EmbeddingDimensions)
+	}
+	// <=> is the cosine distance, 1 - similarity, ordering by it uses the hnsw index
+	args := []interface{}{ns, encodeVector(query.Vector), query.Model}
+	where := []string{"namespace = $1", "model = $3"}
+	filter := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if query.UserID != "" {
+		filter("user_id = $%d", query.UserID)
+	}
+	if query.ChatID != "" {
+		filter("chat_id = $%d", query.ChatID)
+	}
+	if query.SourceType != "" {
+		filter("source_type = $%d", query.SourceType)
+	}
+	if len(query.Metadata) > 0 {
+		metadata, err := encodeMetadata(query.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		filter("metadata @> $%d::jsonb", metadata)
+	}
+	if query.MinScore != 0 {
+		filter("embedding <=> $2::vector <= 1 - $%d::float8", query.MinScore)
+	}
+	args = append(args, query.Limit)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, fmt.Sprintf(
+		`SELECT %s, 1 - (embedding <=> $2::vector) FROM embeddings WHERE %s ORDER BY embedding <=> $2::vector LIMIT $%d`,
+		embeddingColumns, strings.Join(where, " AND "), len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var matches []*gateway.EmbeddingMatch
+	for rows.Next() {
+		match := &gateway.EmbeddingMatch{}
+		match.Embedding, err = scanEmbedding(rows, &match.Score)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+	return matches, rows.Err()
+}
+
+func (r *EmbeddingRepository) DeleteEmbeddingsBySource(ctx context.Context, sourceType string, sourceID string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM embeddings WHERE namespace = $1 AND source_type = $2 AND source_id = $3`,
+		ns, sourceType, sourceID)
+	return err
+}
+
+func scanEmbedding(s scanner, extra ...interface{}) (*gateway.Embedding, error) {
+	e := &gateway.Embedding{}
+	var metadata []byte
+	var vector string
+	dest := []interface{}{&e.ID, &e.SourceType, &e.SourceID, &e.ChatID, &e.UserID, &e.Model, &e.Content,
+		&metadata, &vector, &e.CreatedAt, &e.UpdatedAt}
+	if err := s.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(metadata, &e.Metadata); err != nil {
+		return nil, fmt.Errorf("error decoding metadata of embedding %s: %s", e.ID, err.Error())
+	}
+	var err error
+	if e.Vector, err = decodeVector(vector); err != nil {
+		return nil, fmt.Errorf("error decoding embedding %s: %s", e.ID, err.Error())
+	}
+	return e, nil
+}
+
+func encodeMetadata(metadata map[string]string) (string, error) {
+	if metadata == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(metadata)
+	return string(b), err
+}
+
+// encodeVector writes v in the pgvector text format, [1,2,3].
+func encodeVector(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func decodeVector(s string) ([]float32, error) {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	v := make([]float32, len(parts))
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(p, 32)
+		if err != nil {
+			return nil, err
+		}
+		v[i] = float32(f)
+	}
+	return v, nil
+}
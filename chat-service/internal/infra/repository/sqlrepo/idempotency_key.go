@@ -0,0 +1,90 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+type IdempotencyKeyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyKeyRepository(db *sql.DB) *IdempotencyKeyRepository {
+	return &IdempotencyKeyRepository{db: db}
+}
+
+func (r *IdempotencyKeyRepository) CreateIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO idempotency_keys
+		(namespace, user_id, idempotency_key, request_hash, chat_id, message_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT DO NOTHING`,
+		ns, key.UserID, key.Key, key.RequestHash, key.ChatID, key.MessageID, utc(key.CreatedAt))
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return gateway.ErrIdempotencyKeyExists
+	}
+	return nil
+}
+
+func (r *IdempotencyKeyRepository) FindIdempotencyKey(ctx context.Context, userID, key string) (*entity.IdempotencyKey, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	k := &entity.IdempotencyKey{}
+	err = conn(ctx, r.db).QueryRowContext(ctx, `SELECT user_id, idempotency_key, request_hash, chat_id, message_id, created_at
+		FROM idempotency_keys WHERE namespace = $1 AND user_id = $2 AND idempotency_key = $3`, ns, userID, key).
+		Scan(&k.UserID, &k.Key, &k.RequestHash, &k.ChatID, &k.MessageID, &k.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, gateway.ErrIdempotencyKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (r *IdempotencyKeyRepository) CompleteIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE idempotency_keys SET chat_id = $4, message_id = $5
+		WHERE namespace = $1 AND user_id = $2 AND idempotency_key = $3`, ns, key.UserID, key.Key, key.ChatID, key.MessageID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return gateway.ErrIdempotencyKeyNotFound
+	}
+	return nil
+}
+
+func (r *IdempotencyKeyRepository) DeleteIdempotencyKey(ctx context.Context, userID, key string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `DELETE FROM idempotency_keys
+		WHERE namespace = $1 AND user_id = $2 AND idempotency_key = $3`, ns, userID, key)
+	return err
+}
@@ -0,0 +1,150 @@
+package sqlrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Migrator runs the NNNNNN_name.up.sql and NNNNNN_name.down.sql scripts at
+// the root of its migrations with golang-migrate, which records the version
+// of the schema in schema_migrations and locks it while migrating.
+type Migrator struct {
+	m          *migrate.Migrate
+	migrations fs.FS
+}
+
+// NewMigrator returns the Migrator of the database of driver. legacy is the
+// version schema_version records, adopted by a database migrated before
+// golang-migrate ran them so its scripts are not run twice.
+func NewMigrator(migrations fs.FS, name string, driver database.Driver, legacy int) (*Migrator, error) {
+	source, err := iofs.New(migrations, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %s", err.Error())
+	}
+	m, err := migrate.NewWithInstance("iofs", source, name, driver)
+	if err != nil {
+		return nil, fmt.Errorf("error opening migrations: %s", err.Error())
+	}
+	if _, _, err := m.Version(); errors.Is(err, migrate.ErrNilVersion) && legacy > 0 {
+		if err := m.Force(legacy); err != nil {
+			return nil, fmt.Errorf("error adopting schema version %d: %s", legacy, err.Error())
+		}
+	}
+	return &Migrator{m: m, migrations: migrations}, nil
+}
+
+// Version returns the version of the schema, 0 before any migration ran, and
+// whether a migration failed halfway through it.
+func (m *Migrator) Version() (int, bool, error) {
+	version, dirty, err := m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("error reading schema version: %s", err.Error())
+	}
+	return int(version), dirty, nil
+}
+
+// Up runs the migrations newer than the schema version, in order, and
+// returns the versions it ran.
+func (m *Migrator) Up(ctx context.Context) ([]int, error) {
+	from, _, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	err = m.run(ctx, m.m.Up)
+	to, _, verr := m.Version()
+	if verr != nil {
+		return nil, verr
+	}
+	applied, lerr := m.versions(from, to)
+	if lerr != nil {
+		return nil, lerr
+	}
+	if err != nil {
+		return applied, fmt.Errorf("error running migrations: %s", err.Error())
+	}
+	return applied, nil
+}
+
+// Down reverts the last steps migrations and returns the versions it
+// reverted, latest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]int, error) {
+	from, _, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	err = m.run(ctx, func() error { return m.m.Steps(-steps) })
+	to, _, verr := m.Version()
+	if verr != nil {
+		return nil, verr
+	}
+	reverted, lerr := m.versions(to, from)
+	if lerr != nil {
+		return nil, lerr
+	}
+	for i, j := 0, len(reverted)-1; i < j; i, j = i+1, j-1 {
+		reverted[i], reverted[j] = reverted[j], reverted[i]
+	}
+	if err != nil {
+		return reverted, fmt.Errorf("error reverting migrations: %s", err.Error())
+	}
+	return reverted, nil
+}
+
+// Close closes the migrations and the database of the driver.
+func (m *Migrator) Close() error {
+	_, err := m.m.Close()
+	return err
+}
+
+// run runs fn, stopping after the migration in progress when ctx is done.
+func (m *Migrator) run(ctx context.Context, fn func() error) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			m.m.GracefulStop <- true
+		case <-done:
+		}
+	}()
+	err := fn()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	if err == nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// versions returns the versions of the migrations after from up to to.
+func (m *Migrator) versions(from, to int) ([]int, error) {
+	files, err := fs.Glob(m.migrations, "*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var versions []int
+	for _, name := range files {
+		version, err := strconv.Atoi(strings.SplitN(name, "_", 2)[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration name %s", name)
+		}
+		if version > from && version <= to {
+			versions = append(versions, version)
+		}
+	}
+	return versions, nil
+}
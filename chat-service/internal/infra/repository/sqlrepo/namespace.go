@@ -0,0 +1,40 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+)
+
+type NamespaceRepository struct {
+	db *sql.DB
+}
+
+func NewNamespaceRepository(db *sql.DB) *NamespaceRepository {
+	return &NamespaceRepository{db: db}
+}
+
+// PurgeNamespace deletes the chats and webhooks, which cascade to their rows,
+// the outbox, the API keys, the idempotency keys, the usage records and the
+// audit log of namespace. It returns the number of chats, outbox entries, API
+// keys, webhooks, idempotency keys, usage records and audit entries removed.
+func (r *NamespaceRepository) PurgeNamespace(ctx context.Context, namespace string) (int64, error) {
+	var purged int64
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		for _, table := range []string{"chats", "outbox", "api_keys", "webhooks", "idempotency_keys", "usage_records", "audit_log"} {
+			result, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE namespace = $1`, namespace)
+			if err != nil {
+				return err
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			purged += n
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
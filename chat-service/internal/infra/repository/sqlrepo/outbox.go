@@ -0,0 +1,81 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+func insertOutboxEntries(ctx context.Context, tx *sql.Tx, ns string, entries []*entity.OutboxEntry) error {
+	for _, e := range entries {
+		_, err := tx.ExecContext(ctx, `INSERT INTO outbox (namespace, id, chat_id, event_name, payload, occurred_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`, ns, e.ID, e.ChatID, e.EventName, string(e.Payload), utc(e.OccurredAt))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *OutboxRepository) FindUnpublishedOutboxEntries(ctx context.Context, limit int) ([]*entity.OutboxEntry, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT id, chat_id, event_name, payload, occurred_at, attempts, last_error
+		FROM outbox WHERE namespace = $1 AND published_at IS NULL AND dead_lettered_at IS NULL ORDER BY seq LIMIT $2`, ns, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []*entity.OutboxEntry
+	for rows.Next() {
+		e := &entity.OutboxEntry{}
+		if err := rows.Scan(&e.ID, &e.ChatID, &e.EventName, &e.Payload, &e.OccurredAt, &e.Attempts, &e.LastError); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *OutboxRepository) MarkOutboxEntryPublished(ctx context.Context, entryID string, publishedAt time.Time) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `UPDATE outbox SET published_at = $3, attempts = attempts + 1
+		WHERE namespace = $1 AND id = $2`, ns, entryID, utc(publishedAt))
+	return err
+}
+
+func (r *OutboxRepository) MarkOutboxEntryFailed(ctx context.Context, entryID string, reason string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `UPDATE outbox SET attempts = attempts + 1, last_error = $3
+		WHERE namespace = $1 AND id = $2`, ns, entryID, reason)
+	return err
+}
+
+func (r *OutboxRepository) MarkOutboxEntryDeadLettered(ctx context.Context, entryID string, reason string, deadLetteredAt time.Time) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `UPDATE outbox SET attempts = attempts + 1, last_error = $3, dead_lettered_at = $4
+		WHERE namespace = $1 AND id = $2`, ns, entryID, reason, utc(deadLetteredAt))
+	return err
+}
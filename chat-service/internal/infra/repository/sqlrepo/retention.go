@@ -0,0 +1,92 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+// RetentionRepository is the RetentionGateway on SQL databases. Messages,
+// tags, annotations, attachments and embeddings go with the chats by cascade.
+type RetentionRepository struct {
+	db *sql.DB
+}
+
+func NewRetentionRepository(db *sql.DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+func (r *RetentionRepository) CountIdleChats(ctx context.Context, scope gateway.RetentionScope, idleBefore time.Time) (int64, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cond, args := scopeCondition(scope, 3)
+	var n int64
+	err = conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM chats WHERE namespace = $1 AND updated_at < $2`+cond,
+		append([]interface{}{ns, utc(idleBefore)}, args...)...).Scan(&n)
+	return n, err
+}
+
+func (r *RetentionRepository) PurgeIdleChats(ctx context.Context, scope gateway.RetentionScope, idleBefore time.Time, limit int) (*gateway.RetentionPurge, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	purge := &gateway.RetentionPurge{}
+	err = inTx(ctx, r.db, func(tx *sql.Tx) error {
+		cond, args := scopeCondition(scope, 3)
+		args = append([]interface{}{ns, utc(idleBefore)}, append(args, limit)...)
+		purge.ChatIDs, err = queryStrings(ctx, tx, `SELECT id FROM chats WHERE namespace = $1 AND updated_at < $2`+cond+`
+			ORDER BY updated_at LIMIT $`+strconv.Itoa(len(args)), args...)
+		if err != nil || len(purge.ChatIDs) == 0 {
+			return err
+		}
+		// the ids are bound from $2 on, after the namespace
+		in, args := inList(2, purge.ChatIDs)
+		args = append([]interface{}{ns}, args...)
+		if purge.StorageKeys, err = queryStrings(ctx, tx, `SELECT storage_key FROM attachments
+			WHERE namespace = $1 AND chat_id IN (`+in+`)`, args...); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM outbox WHERE namespace = $1 AND chat_id IN (`+in+`)`, args...); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM chats WHERE namespace = $1 AND id IN (`+in+`)`, args...)
+		return err
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return purge, nil
+}
+
+// scopeCondition is the condition on the tenant of the chats of scope, its
+// arguments numbered from first.
+func scopeCondition(scope gateway.RetentionScope, first int) (string, []interface{}) {
+	if !scope.Others {
+		return ` AND org_id = $` + strconv.Itoa(first), []interface{}{scope.OrgID}
+	}
+	if len(scope.Except) == 0 {
+		return "", nil
+	}
+	in, args := inList(first, scope.Except)
+	return ` AND org_id NOT IN (` + in + `)`, args
+}
+
+// inList returns the placeholders of values numbered from first, for an IN
+// clause, and values as arguments.
+func inList(first int, values []string) (string, []interface{}) {
+	placeholders := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		placeholders[i] = "$" + strconv.Itoa(first+i)
+		args[i] = v
+	}
+	return strings.Join(placeholders, ", "), args
+}
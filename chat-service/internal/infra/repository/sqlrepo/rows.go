@@ -0,0 +1,101 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+const (
+	messageActive  = "active"
+	messageErased  = "erased"
+	messageInitial = "initial"
+)
+
+// chatConfig is how entity.ChatConfig is stored in the config JSONB column.
+type chatConfig struct {
+	ModelName        string   `json:"model_name"`
+	ModelMaxTokens   int      `json:"model_max_tokens"`
+	Temperature      float32  `json:"temperature"`
+	TopP             float32  `json:"top_p"`
+	N                int      `json:"n"`
+	Stop             []string `json:"stop,omitempty"`
+	MaxTokens        int      `json:"max_tokens"`
+	PresencePenalty  float32  `json:"presence_penalty"`
+	FrequencyPenalty float32  `json:"frequency_penalty"`
+	AnswerMode       string   `json:"answer_mode,omitempty"`
+	Deterministic    bool     `json:"deterministic,omitempty"`
+	MaxMessages      int      `json:"max_messages,omitempty"`
+	EvictionPolicy   string   `json:"eviction_policy,omitempty"`
+}
+
+func encodeConfig(c *entity.ChatConfig) (string, error) {
+	row := chatConfig{
+		Temperature:      c.Temperature,
+		TopP:             c.TopP,
+		N:                c.N,
+		Stop:             c.Stop,
+		MaxTokens:        c.MaxTokens,
+		PresencePenalty:  c.PresencePenalty,
+		FrequencyPenalty: c.FrequencyPenalty,
+		AnswerMode:       c.AnswerMode,
+		Deterministic:    c.Deterministic,
+		MaxMessages:      c.MaxMessages,
+		EvictionPolicy:   c.EvictionPolicy,
+	}
+	if c.Model != nil {
+		row.ModelName = c.Model.Name
+		row.ModelMaxTokens = c.Model.MaxToken
+	}
+	b, err := json.Marshal(row)
+	return string(b), err
+}
+
+func decodeConfig(b []byte) (*entity.ChatConfig, error) {
+	var row chatConfig
+	if err := json.Unmarshal(b, &row); err != nil {
+		return nil, err
+	}
+	return &entity.ChatConfig{
+		Model:            entity.NewModel(row.ModelName, row.ModelMaxTokens),
+		Temperature:      row.Temperature,
+		TopP:             row.TopP,
+		N:                row.N,
+		Stop:             row.Stop,
+		MaxTokens:        row.MaxTokens,
+		PresencePenalty:  row.PresencePenalty,
+		FrequencyPenalty: row.FrequencyPenalty,
+		AnswerMode:       row.AnswerMode,
+		Deterministic:    row.Deterministic,
+		MaxMessages:      row.MaxMessages,
+		EvictionPolicy:   row.EvictionPolicy,
+	}, nil
+}
+
+// Times are always stored in UTC: SQLite compares them as text.
+func utc(t time.Time) time.Time {
+	return t.UTC()
+}
+
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
+
+func timeOf(t sql.NullTime) time.Time {
+	if !t.Valid {
+		return time.Time{}
+	}
+	return t.Time
+}
@@ -0,0 +1,15 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SchemaVersion is the version of the migrations this code expects.
+const SchemaVersion = 20
+
+func CurrentSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
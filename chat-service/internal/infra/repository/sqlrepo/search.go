@@ -0,0 +1,119 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/highlight"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+// searchedRoles leaves system and tool messages out of the results, the
+// legacy assistant spelling is still stored on old rows.
+const searchedRoles = `m.role IN ('user', 'assistant', 'assistent')`
+
+// postgresSearch runs on the generated search column of messages. $3 is the
+// query in websearch syntax and $4 the ts_headline options.
+const postgresSearch = `SELECT %s, c.id, c.title, c.updated_at, ts_headline('english', m.content, q, $4)
+	FROM messages m
+	JOIN chats c ON c.namespace = m.namespace AND c.id = m.chat_id,
+	websearch_to_tsquery('english', $3) q
+	WHERE m.namespace = $1 AND c.user_id = $2 AND c.deleted_at IS NULL AND m.state = 'active'
+	AND ` + searchedRoles + ` AND m.search @@ q
+	ORDER BY ts_rank(m.search, q) DESC, m.created_at DESC
+	LIMIT $5 OFFSET $6`
+
+// sqliteSearch runs on the messages_fts table, $3 is an FTS5 query.
+const sqliteSearch = `SELECT %s, c.id, c.title, c.updated_at,
+	snippet(messages_fts, 2, char(2), char(3), ' … ', 24)
+	FROM messages_fts
+	JOIN messages m ON m.namespace = messages_fts.namespace AND m.id = messages_fts.message_id
+	JOIN chats c ON c.namespace = m.namespace AND c.id = m.chat_id
+	WHERE messages_fts MATCH $3 AND messages_fts.namespace = $1 AND c.user_id = $2
+	AND c.deleted_at IS NULL AND m.state = 'active' AND ` + searchedRoles + `
+	ORDER BY bm25(messages_fts), m.created_at DESC
+	LIMIT $4 OFFSET $5`
+
+const headlineOptions = `StartSel="` + highlight.StartMarker + `", StopSel="` + highlight.StopMarker +
+	`", MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "`
+
+// SearchRepository is the SearchGateway on the full-text index of the database,
+// built by the message_search migration.
+type SearchRepository struct {
+	db       *sql.DB
+	postgres bool
+}
+
+func NewPostgresSearchRepository(db *sql.DB) *SearchRepository {
+	return &SearchRepository{db: db, postgres: true}
+}
+
+func NewSQLiteSearchRepository(db *sql.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+func (r *SearchRepository) SearchMessages(ctx context.Context, query gateway.MessageSearchQuery) ([]*gateway.MessageSearchHit, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	columns := qualify(messageColumns, "m")
+	var rows *sql.Rows
+	if r.postgres {
+		rows, err = conn(ctx, r.db).QueryContext(ctx, strings.Replace(postgresSearch, "%s", columns, 1),
+			ns, query.UserID, query.Text, headlineOptions, query.Limit, query.Offset)
+	} else {
+		terms := highlight.Terms(query.Text)
+		if len(terms) == 0 {
+			return nil, nil
+		}
+		rows, err = conn(ctx, r.db).QueryContext(ctx, strings.Replace(sqliteSearch, "%s", columns, 1),
+			ns, query.UserID, ftsQuery(terms), query.Limit, query.Offset)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hits []*gateway.MessageSearchHit
+	for rows.Next() {
+		hit, err := scanSearchHit(rows)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+func scanSearchHit(rows *sql.Rows) (*gateway.MessageSearchHit, error) {
+	hit := &gateway.MessageSearchHit{}
+	var marked string
+	m, _, err := scanMessage(rows, &hit.ChatID, &hit.ChatTitle, &hit.ChatUpdatedAt, &marked)
+	if err != nil {
+		return nil, err
+	}
+	hit.Message = m
+	hit.Snippet, hit.Highlights = highlight.Parse(marked)
+	return hit, nil
+}
+
+// ftsQuery matches every term as a prefix, quoted so FTS5 operators in the
+// user input are searched for literally.
+func ftsQuery(terms []string) string {
+	quoted := make([]string, 0, len(terms))
+	for _, t := range terms {
+		quoted = append(quoted, `"`+strings.ReplaceAll(t, `"`, `""`)+`"*`)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// qualify prefixes the columns of a column list with alias.
+func qualify(columns, alias string) string {
+	parts := strings.Split(columns, ",")
+	for i, p := range parts {
+		parts[i] = alias + "." + strings.TrimSpace(p)
+	}
+	return strings.Join(parts, ", ")
+}
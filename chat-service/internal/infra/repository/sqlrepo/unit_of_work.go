@@ -0,0 +1,90 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+)
+
+type txKey struct{}
+
+type txState struct {
+	tx          *sql.Tx
+	afterCommit []func()
+}
+
+// UnitOfWork is the gateway.UnitOfWork of the SQL repositories built on the
+// same *sql.DB.
+type UnitOfWork struct {
+	db *sql.DB
+}
+
+func NewUnitOfWork(db *sql.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*txState); ok {
+		return fn(ctx)
+	}
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	state := &txState{tx: tx}
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, f := range state.afterCommit {
+		f()
+	}
+	return nil
+}
+
+func (u *UnitOfWork) AfterCommit(ctx context.Context, fn func()) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// conn returns the transaction of the unit of work ctx is in, or db.
+func conn(ctx context.Context, db *sql.DB) querier {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.tx
+	}
+	return db
+}
+
+// inTx runs fn in the transaction of the unit of work ctx is in, or in its own
+// one, and afterCommit once that transaction is committed.
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error, afterCommit func()) error {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		if err := fn(state.tx); err != nil {
+			return err
+		}
+		if afterCommit != nil {
+			state.afterCommit = append(state.afterCommit, afterCommit)
+		}
+		return nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if afterCommit != nil {
+		afterCommit()
+	}
+	return nil
+}
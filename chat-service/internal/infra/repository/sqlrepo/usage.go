@@ -0,0 +1,84 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+type UsageRepository struct {
+	db *sql.DB
+}
+
+func NewUsageRepository(db *sql.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+func (r *UsageRepository) RecordUsage(ctx context.Context, usage *entity.UsageRecord) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `INSERT INTO usage_records
+		(namespace, day, org_id, user_id, model, turns, prompt_tokens, completion_tokens, cost, unpriced_turns)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (namespace, day, org_id, user_id, model) DO UPDATE SET
+			turns = usage_records.turns + excluded.turns,
+			prompt_tokens = usage_records.prompt_tokens + excluded.prompt_tokens,
+			completion_tokens = usage_records.completion_tokens + excluded.completion_tokens,
+			cost = usage_records.cost + excluded.cost,
+			unpriced_turns = usage_records.unpriced_turns + excluded.unpriced_turns`,
+		ns, utc(entity.UsageDay(usage.Day)), usage.OrgID, usage.UserID, usage.Model,
+		usage.Turns, usage.PromptTokens, usage.CompletionTokens, usage.Cost, usage.UnpricedTurns)
+	return err
+}
+
+func (r *UsageRepository) ListUsage(ctx context.Context, filter gateway.UsageFilter) ([]*entity.UsageRecord, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	where := []string{"namespace = $1"}
+	args := []interface{}{ns}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if filter.OrgID != "" {
+		where = append(where, "org_id = "+arg(filter.OrgID))
+	}
+	if filter.UserID != "" {
+		where = append(where, "user_id = "+arg(filter.UserID))
+	}
+	if filter.Model != "" {
+		where = append(where, "model = "+arg(filter.Model))
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "day >= "+arg(utc(filter.From)))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "day < "+arg(utc(filter.To)))
+	}
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT day, org_id, user_id, model, turns, prompt_tokens, completion_tokens, cost, unpriced_turns
+		FROM usage_records WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY day, org_id, user_id, model`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var records []*entity.UsageRecord
+	for rows.Next() {
+		u := &entity.UsageRecord{}
+		if err := rows.Scan(&u.Day, &u.OrgID, &u.UserID, &u.Model, &u.Turns, &u.PromptTokens, &u.CompletionTokens, &u.Cost, &u.UnpricedTurns); err != nil {
+			return nil, err
+		}
+		u.Day = u.Day.UTC()
+		records = append(records, u)
+	}
+	return records, rows.Err()
+}
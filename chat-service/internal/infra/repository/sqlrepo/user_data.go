@@ -0,0 +1,131 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+// userChats selects the chats of the user, $1 is the namespace and $2 the user.
+const userChats = `SELECT id FROM chats WHERE namespace = $1 AND user_id = $2`
+
+// UserDataRepository is the UserDataGateway on SQL databases. Only Postgres
+// has the embeddings table.
+type UserDataRepository struct {
+	db       *sql.DB
+	postgres bool
+}
+
+func NewPostgresUserDataRepository(db *sql.DB) *UserDataRepository {
+	return &UserDataRepository{db: db, postgres: true}
+}
+
+func NewSQLiteUserDataRepository(db *sql.DB) *UserDataRepository {
+	return &UserDataRepository{db: db}
+}
+
+func (r *UserDataRepository) PurgeUserData(ctx context.Context, erasure *entity.UserErasure) (*gateway.UserDataPurge, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	purge := &gateway.UserDataPurge{}
+	err = inTx(ctx, r.db, func(tx *sql.Tx) error {
+		if purge.ChatIDs, err = queryStrings(ctx, tx, userChats, ns, erasure.UserID); err != nil {
+			return err
+		}
+		if purge.StorageKeys, err = queryStrings(ctx, tx, `SELECT storage_key FROM attachments
+			WHERE namespace = $1 AND chat_id IN (`+userChats+`)`, ns, erasure.UserID); err != nil {
+			return err
+		}
+		erasure.Attachments = int64(len(purge.StorageKeys))
+		err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM messages WHERE namespace = $1 AND chat_id IN (`+userChats+`)`,
+			ns, erasure.UserID).Scan(&erasure.Messages)
+		if err != nil {
+			return err
+		}
+		// the annotations of the user's chats go with them, the ones the user
+		// wrote elsewhere are deleted here
+		err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM annotations WHERE namespace = $1 AND chat_id IN (`+userChats+`)`,
+			ns, erasure.UserID).Scan(&erasure.Annotations)
+		if err != nil {
+			return err
+		}
+		n, err := exec(ctx, tx, `DELETE FROM annotations WHERE namespace = $1 AND user_id = $2
+			AND chat_id NOT IN (`+userChats+`)`, ns, erasure.UserID)
+		if err != nil {
+			return err
+		}
+		erasure.Annotations += n
+		if r.postgres {
+			if erasure.Embeddings, err = exec(ctx, tx, `DELETE FROM embeddings WHERE namespace = $1
+				AND (user_id = $2 OR chat_id IN (`+userChats+`))`, ns, erasure.UserID); err != nil {
+				return err
+			}
+		}
+		// the events of the chats carry the user ID
+		if _, err := exec(ctx, tx, `DELETE FROM outbox WHERE namespace = $1 AND chat_id IN (`+userChats+`)`,
+			ns, erasure.UserID); err != nil {
+			return err
+		}
+		if erasure.Chats, err = exec(ctx, tx, `DELETE FROM chats WHERE namespace = $1 AND user_id = $2`,
+			ns, erasure.UserID); err != nil {
+			return err
+		}
+		// the rest of what the service keeps on the user: the read model, the
+		// drafts, the replays of the idempotent requests, the usage, the audit
+		// trail of the prompts and the webhook payloads naming the user
+		for _, table := range []string{"chat_summaries", "drafts", "idempotency_keys", "usage_records", "audit_log"} {
+			if _, err := exec(ctx, tx, `DELETE FROM `+table+` WHERE namespace = $1 AND user_id = $2`,
+				ns, erasure.UserID); err != nil {
+				return err
+			}
+		}
+		deliveryUser := `json_extract(payload, '$.data.user_id')`
+		if r.postgres {
+			deliveryUser = `payload->'data'->>'user_id'`
+		}
+		if _, err := exec(ctx, tx, `DELETE FROM webhook_deliveries WHERE namespace = $1 AND `+deliveryUser+` = $2`,
+			ns, erasure.UserID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO user_erasures (namespace, id, user_id, requested_by, chats,
+			messages, annotations, attachments, embeddings, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			ns, erasure.ID, erasure.UserID, erasure.RequestedBy, erasure.Chats, erasure.Messages,
+			erasure.Annotations, erasure.Attachments, erasure.Embeddings, utc(erasure.CreatedAt))
+		return err
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return purge, nil
+}
+
+func queryStrings(ctx context.Context, q querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+func exec(ctx context.Context, q querier, query string, args ...interface{}) (int64, error) {
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,184 @@
+package sqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const (
+	webhookColumns  = `id, org_id, url, events, secret, created_by, created_at`
+	deliveryColumns = `id, webhook_id, org_id, event, payload, attempts, next_attempt_at, delivered_at, failed_at, last_error, created_at`
+)
+
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, webhook *entity.Webhook) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `INSERT INTO webhooks (namespace, `+webhookColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		ns, webhook.ID, webhook.OrgID, webhook.URL, string(events), webhook.Secret, webhook.CreatedBy, utc(webhook.CreatedAt))
+	return err
+}
+
+func (r *WebhookRepository) FindWebhookByID(ctx context.Context, id string) (*entity.Webhook, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	row := conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks
+		WHERE namespace = $1 AND id = $2`, ns, id)
+	webhook, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, gateway.ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+func (r *WebhookRepository) ListWebhooksByOrg(ctx context.Context, orgID string) ([]*entity.Webhook, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks
+		WHERE namespace = $1 AND org_id = $2 ORDER BY created_at, id`, ns, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var webhooks []*entity.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook deletes the webhook, which cascades to its deliveries.
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM webhooks WHERE namespace = $1 AND id = $2`, ns, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return gateway.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []*entity.WebhookDelivery) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		for _, d := range deliveries {
+			_, err := tx.ExecContext(ctx, `INSERT INTO webhook_deliveries (namespace, `+deliveryColumns+`)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+				ns, d.ID, d.WebhookID, d.OrgID, d.Event, string(d.Payload), d.Attempts, utc(d.NextAttemptAt),
+				nullTime(d.DeliveredAt), nullTime(d.FailedAt), d.LastError, utc(d.CreatedAt))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil)
+}
+
+func (r *WebhookRepository) FindDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]*entity.WebhookDelivery, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.listDeliveries(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE namespace = $1 AND delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= $2
+		ORDER BY next_attempt_at, id LIMIT $3`, ns, utc(now), limit)
+}
+
+func (r *WebhookRepository) SaveWebhookDelivery(ctx context.Context, d *entity.WebhookDelivery) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = conn(ctx, r.db).ExecContext(ctx, `UPDATE webhook_deliveries
+		SET attempts = $3, next_attempt_at = $4, delivered_at = $5, failed_at = $6, last_error = $7
+		WHERE namespace = $1 AND id = $2`,
+		ns, d.ID, d.Attempts, utc(d.NextAttemptAt), nullTime(d.DeliveredAt), nullTime(d.FailedAt), d.LastError)
+	return err
+}
+
+func (r *WebhookRepository) ListDeadWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]*entity.WebhookDelivery, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.listDeliveries(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE namespace = $1 AND webhook_id = $2 AND failed_at IS NOT NULL
+		ORDER BY failed_at DESC, id LIMIT $3`, ns, webhookID, limit)
+}
+
+func (r *WebhookRepository) listDeliveries(ctx context.Context, query string, args ...interface{}) ([]*entity.WebhookDelivery, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var deliveries []*entity.WebhookDelivery
+	for rows.Next() {
+		d := &entity.WebhookDelivery{}
+		var payload string
+		var deliveredAt, failedAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.OrgID, &d.Event, &payload, &d.Attempts, &d.NextAttemptAt,
+			&deliveredAt, &failedAt, &d.LastError, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		d.DeliveredAt = timeOf(deliveredAt)
+		d.FailedAt = timeOf(failedAt)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanWebhook(s scanner) (*entity.Webhook, error) {
+	webhook := &entity.Webhook{}
+	var events string
+	if err := s.Scan(&webhook.ID, &webhook.OrgID, &webhook.URL, &events, &webhook.Secret,
+		&webhook.CreatedBy, &webhook.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(events), &webhook.Events); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
@@ -0,0 +1,111 @@
+// Package requestid threads the ID of a request through its context, so a
+// failed completion can be traced across the service, provider and BFF logs.
+package requestid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+// Header carries the request ID over HTTP, MetadataKey over gRPC.
+const (
+	Header      = "X-Request-ID"
+	MetadataKey = "x-request-id"
+)
+
+type contextKey struct{}
+
+// validID keeps the IDs of clients short and printable, so they can't break
+// the headers and log lines they end up in.
+var validID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+func New() string {
+	return uuid.New().String()
+}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID of ctx, empty when it has none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Ensure returns ctx with a new request ID unless it has one already.
+func Ensure(ctx context.Context) context.Context {
+	if FromContext(ctx) != "" {
+		return ctx
+	}
+	return NewContext(ctx, New())
+}
+
+// Incoming returns the ID a client sent when it is valid, a new one
+// otherwise.
+func Incoming(id string) string {
+	if Valid(id) {
+		return id
+	}
+	return New()
+}
+
+// Error is an error of the request ID, its message names the ID so it can be
+// looked up in the logs of every service the request went through.
+type Error struct {
+	ID  string
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error() + " (request " + e.ID + ")"
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap names the request ID of ctx in err, once. It returns err as is when
+// it is nil or ctx has no request ID.
+func Wrap(ctx context.Context, err error) error {
+	id := FromContext(ctx)
+	if err == nil || id == "" {
+		return err
+	}
+	var named *Error
+	if errors.As(err, &named) {
+		return err
+	}
+	return &Error{ID: id, Err: err}
+}
+
+// Transport sends the request ID of the context of every request in Header,
+// so the provider logs it along with its own.
+type Transport struct {
+	Next http.RoundTripper
+}
+
+func NewTransport(next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{Next: next}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := FromContext(req.Context())
+	if id == "" || req.Header.Get(Header) != "" {
+		return t.Next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(Header, id)
+	return t.Next.RoundTrip(req)
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+// LocalStorage stores files under a directory of the local disk, for
+// development and single node deployments.
+type LocalStorage struct {
+	root string
+}
+
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("error creating storage directory: %s", err.Error())
+	}
+	return &LocalStorage{root: root}, nil
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	// write to a temporary file first so readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	written, err := io.Copy(tmp, io.LimitReader(content, size+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if written != size {
+		return fmt.Errorf("expected %d bytes, got %d", size, written)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, gateway.ErrFileNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || strings.Contains(key, "..") || clean == "/" {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/awssig"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type S3Config struct {
+	// Endpoint defaults to https://s3.<region>.amazonaws.com, set it for
+	// S3 compatible services such as MinIO.
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3Storage stores files in an S3 bucket, signing path-style requests with
+// AWS Signature Version 4.
+type S3Storage struct {
+	config S3Config
+	client *http.Client
+	clock  clock.Clock
+}
+
+func NewS3Storage(config S3Config, client *http.Client, clk clock.Clock) (*S3Storage, error) {
+	if config.Region == "" || config.Bucket == "" {
+		return nil, fmt.Errorf("s3 storage needs a region and a bucket")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("s3 storage needs credentials")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://s3." + config.Region + ".amazonaws.com"
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &S3Storage{
+		config: config,
+		client: client,
+		clock:  clk,
+	}, nil
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	req, err := s.request(ctx, http.MethodPut, key, content)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	req, err := s.request(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) request(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	if key == "" {
+		return nil, fmt.Errorf("invalid storage key %q", key)
+	}
+	path := "/" + s.config.Bucket + "/" + strings.TrimPrefix(s.config.Prefix+key, "/")
+	req, err := http.NewRequestWithContext(ctx, method, s.config.Endpoint+escapePath(path), body)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, escapePath(path))
+	return req, nil
+}
+
+func (s *S3Storage) do(req *http.Request) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, gateway.ErrFileNotFound
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// sign adds the SigV4 authorization headers. The payload is left unsigned so
+// uploads can be streamed, TLS protects its integrity.
+func (s *S3Storage) sign(req *http.Request, canonicalURI string) {
+	creds := awssig.Credentials{
+		AccessKeyID:     s.config.AccessKeyID,
+		SecretAccessKey: s.config.SecretAccessKey,
+		SessionToken:    s.config.SessionToken,
+	}
+	awssig.Sign(req, canonicalURI, creds, s.config.Region, "s3", awssig.UnsignedPayload, s.clock.Now())
+}
+
+// escapePath percent-encodes everything but unreserved characters and slashes,
+// as SigV4 expects for S3.
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,280 @@
+package streambuffer
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+var (
+	ErrStreamNotFound  = errors.New("stream not found")
+	ErrStreamForbidden = errors.New("stream belongs to another user")
+	ErrSequenceExpired = errors.New("requested sequence is no longer buffered")
+)
+
+type Event[T any] struct {
+	Seq   int
+	Value T
+}
+
+type stream[T any] struct {
+	owner       string
+	topic       string
+	events      []Event[T]
+	subscribers map[chan Event[T]]struct{}
+	finished    bool
+	expiresAt   time.Time
+	// activeAt is when the stream opened or last had an event.
+	activeAt time.Time
+	// lagged are the subscribers dropped for falling behind, until they
+	// cancel.
+	lagged map[<-chan Event[T]]struct{}
+}
+
+// Buffer keeps the recent events of every open stream so a client that reconnects
+// with the stream's resume token can replay what it missed and continue live.
+// Finished streams stay available for the TTL, open ones without an event for
+// the idle TTL are taken for abandoned and dropped.
+type Buffer[T any] struct {
+	mu        sync.Mutex
+	clock     clock.Clock
+	ttl       time.Duration
+	idleTTL   time.Duration
+	maxEvents int
+	streams   map[string]*stream[T]
+	topics    map[string]*topic
+	// opened is closed when a stream opens in a topic without one, for the
+	// waiters of the topics nothing was opened in.
+	opened chan struct{}
+}
+
+// topic is the latest stream opened in a topic, opened is closed when
+// another one opens.
+type topic struct {
+	token  string
+	opened chan struct{}
+}
+
+// New returns a Buffer keeping the last maxEvents of each stream. A zero
+// idleTTL keeps the open streams until they finish.
+func New[T any](clk clock.Clock, ttl, idleTTL time.Duration, maxEvents int) *Buffer[T] {
+	return &Buffer[T]{
+		clock:     clk,
+		ttl:       ttl,
+		idleTTL:   idleTTL,
+		maxEvents: maxEvents,
+		streams:   make(map[string]*stream[T]),
+		topics:    make(map[string]*topic),
+		opened:    make(chan struct{}),
+	}
+}
+
+func (b *Buffer[T]) Open(token, owner string) {
+	b.OpenInTopic(token, owner, "")
+}
+
+// OpenInTopic opens a stream that becomes the latest of topic, e.g. the
+// streams of a chat, so the clients of the topic can find it with Latest.
+func (b *Buffer[T]) OpenInTopic(token, owner, topicName string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.evictExpired()
+	b.streams[token] = &stream[T]{
+		owner:       owner,
+		topic:       topicName,
+		subscribers: make(map[chan Event[T]]struct{}),
+		lagged:      make(map[<-chan Event[T]]struct{}),
+		activeAt:    b.clock.Now(),
+	}
+	if topicName == "" {
+		return
+	}
+	t, ok := b.topics[topicName]
+	if !ok {
+		b.topics[topicName] = &topic{token: token, opened: make(chan struct{})}
+		close(b.opened)
+		b.opened = make(chan struct{})
+		return
+	}
+	t.token = token
+	close(t.opened)
+	t.opened = make(chan struct{})
+}
+
+// Latest returns the token of the latest stream of topic still buffered,
+// empty when there is none, and a channel closed once another stream opens
+// in topic. Asking for a topic keeps nothing for it: the channel of a topic
+// without a stream is closed once a stream opens in any such topic.
+func (b *Buffer[T]) Latest(topicName string) (token string, opened <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.evictExpired()
+	t, ok := b.topics[topicName]
+	if !ok {
+		return "", b.opened
+	}
+	return t.token, t.opened
+}
+
+// Finished tells whether the stream of token finished, all its events are
+// then buffered.
+func (b *Buffer[T]) Finished(token string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.streams[token]
+	return ok && s.finished
+}
+
+func (b *Buffer[T]) Publish(token string, seq int, value T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.streams[token]
+	if !ok || s.finished {
+		return
+	}
+	ev := Event[T]{Seq: seq, Value: value}
+	s.events = append(s.events, ev)
+	if b.maxEvents > 0 && len(s.events) > b.maxEvents {
+		s.events = s.events[len(s.events)-b.maxEvents:]
+	}
+	s.activeAt = b.clock.Now()
+	for ch := range s.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// a subscriber that can't keep up is dropped; it can resume again from its last seq
+			delete(s.subscribers, ch)
+			s.lagged[ch] = struct{}{}
+			close(ch)
+		}
+	}
+}
+
+func (b *Buffer[T]) Finish(token string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.streams[token]
+	if !ok || s.finished {
+		return
+	}
+	s.finished = true
+	s.expiresAt = b.clock.Now().Add(b.ttl)
+	for ch := range s.subscribers {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Subscribe returns the buffered events after afterSeq and a channel with the live
+// ones, a negative afterSeq replaying from the oldest event buffered. The channel
+// is closed when the stream finishes or the subscriber falls behind, which Lagged
+// tells apart; cancel must be called once the caller stops reading.
+func (b *Buffer[T]) Subscribe(token, owner string, afterSeq int) (replay []Event[T], live <-chan Event[T], cancel func(), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.evictExpired()
+	s, ok := b.streams[token]
+	if !ok {
+		return nil, nil, nil, ErrStreamNotFound
+	}
+	if s.owner != owner {
+		return nil, nil, nil, ErrStreamForbidden
+	}
+	if afterSeq >= 0 && len(s.events) > 0 && afterSeq < s.events[0].Seq-1 {
+		return nil, nil, nil, ErrSequenceExpired
+	}
+	for _, ev := range s.events {
+		if ev.Seq > afterSeq {
+			replay = append(replay, ev)
+		}
+	}
+	ch := make(chan Event[T], b.maxEvents+1)
+	if s.finished {
+		close(ch)
+		return replay, ch, func() {}, nil
+	}
+	s.subscribers[ch] = struct{}{}
+	cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(s.lagged, ch)
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+	return replay, ch, cancel, nil
+}
+
+// Lagged tells whether live, a channel Subscribe returned for token, was
+// closed because its subscriber fell behind rather than because the stream
+// finished. It is false again once the subscription is canceled.
+func (b *Buffer[T]) Lagged(token string, live <-chan Event[T]) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.streams[token]
+	if !ok {
+		return false
+	}
+	_, lagged := s.lagged[live]
+	return lagged
+}
+
+// Stats are the streams a Buffer holds and the backlog of their subscribers.
+type Stats struct {
+	Open        int `json:"open"`
+	Finished    int `json:"finished"`
+	Subscribers int `json:"subscribers"`
+	// Events are the events buffered over every stream, Backlog those sent
+	// to the subscribers and not read yet, MaxBacklog most any of them has.
+	Events     int `json:"events"`
+	Backlog    int `json:"backlog"`
+	MaxBacklog int `json:"max_backlog"`
+}
+
+// Stats returns the streams held right now, the expired and idle ones aside.
+func (b *Buffer[T]) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.evictExpired()
+	var stats Stats
+	for _, s := range b.streams {
+		if s.finished {
+			stats.Finished++
+		} else {
+			stats.Open++
+		}
+		stats.Events += len(s.events)
+		stats.Subscribers += len(s.subscribers)
+		for ch := range s.subscribers {
+			stats.Backlog += len(ch)
+			if len(ch) > stats.MaxBacklog {
+				stats.MaxBacklog = len(ch)
+			}
+		}
+	}
+	return stats
+}
+
+func (b *Buffer[T]) evictExpired() {
+	now := b.clock.Now()
+	for token, s := range b.streams {
+		expired := s.finished && now.After(s.expiresAt)
+		// a stream nobody publishes to nor finishes lost its generation
+		idle := !s.finished && b.idleTTL > 0 && now.Sub(s.activeAt) > b.idleTTL
+		if expired || idle {
+			for ch := range s.subscribers {
+				delete(s.subscribers, ch)
+				close(ch)
+			}
+			delete(b.streams, token)
+			if t, ok := b.topics[s.topic]; ok && s.topic != "" && t.token == token {
+				// the waiters of the topic wake up to find it empty
+				close(t.opened)
+				delete(b.topics, s.topic)
+			}
+		}
+	}
+}
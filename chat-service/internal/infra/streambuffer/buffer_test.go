@@ -0,0 +1,126 @@
+package streambuffer
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+func TestFinishedStreamExpiresAfterTTL(t *testing.T) {
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	buffer := New[string](clk, time.Minute, 10*time.Minute, 10)
+	buffer.Open("token", "user")
+	buffer.Publish("token", 1, "hello")
+	buffer.Finish("token")
+
+	clk.Advance(59 * time.Second)
+	replay, _, cancel, err := buffer.Subscribe("token", "user", 0)
+	if err != nil {
+		t.Fatalf("Subscribe() before the TTL: %v", err)
+	}
+	cancel()
+	if len(replay) != 1 || replay[0].Value != "hello" {
+		t.Fatalf("replay = %v, want the event published", replay)
+	}
+
+	clk.Advance(2 * time.Second)
+	if _, _, _, err := buffer.Subscribe("token", "user", 0); !errors.Is(err, ErrStreamNotFound) {
+		t.Fatalf("Subscribe() after the TTL = %v, want ErrStreamNotFound", err)
+	}
+	if stats := buffer.Stats(); stats.Finished != 0 {
+		t.Fatalf("Stats().Finished = %d after the TTL, want 0", stats.Finished)
+	}
+}
+
+func TestOpenStreamExpiresOnceIdle(t *testing.T) {
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	buffer := New[string](clk, time.Minute, 10*time.Minute, 10)
+	buffer.OpenInTopic("token", "user", "chat")
+
+	// the events keep it open well past the TTL of the finished streams
+	for i := 1; i <= 3; i++ {
+		clk.Advance(9 * time.Minute)
+		buffer.Publish("token", i, "chunk")
+	}
+	_, live, cancel, err := buffer.Subscribe("token", "user", 3)
+	if err != nil {
+		t.Fatalf("Subscribe() to an open stream: %v", err)
+	}
+	defer cancel()
+
+	clk.Advance(11 * time.Minute)
+	if _, _, _, err := buffer.Subscribe("token", "user", 0); !errors.Is(err, ErrStreamNotFound) {
+		t.Fatalf("Subscribe() to an idle stream = %v, want ErrStreamNotFound", err)
+	}
+	if _, ok := <-live; ok {
+		t.Fatal("the subscriber of an idle stream got an event, want its channel closed")
+	}
+	if token, _ := buffer.Latest("chat"); token != "" {
+		t.Fatalf("Latest() = %q once the stream is idle, want none", token)
+	}
+	if stats := buffer.Stats(); stats.Open != 0 {
+		t.Fatalf("Stats().Open = %d once the stream is idle, want 0", stats.Open)
+	}
+}
+
+func TestLatestKeepsNothingForTopicsWithoutStream(t *testing.T) {
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	buffer := New[string](clk, time.Minute, 10*time.Minute, 10)
+	token, opened := buffer.Latest("chat")
+	if token != "" {
+		t.Fatalf("Latest() = %q before any stream, want none", token)
+	}
+	for i := 0; i < 100; i++ {
+		buffer.Latest(fmt.Sprintf("polled-%d", i))
+	}
+	if len(buffer.topics) != 0 {
+		t.Fatalf("%d topics kept for the topics only asked for, want 0", len(buffer.topics))
+	}
+
+	buffer.OpenInTopic("token", "user", "chat")
+	select {
+	case <-opened:
+	default:
+		t.Fatal("the waiter of the topic was not woken up by the stream opened in it")
+	}
+	if token, _ := buffer.Latest("chat"); token != "token" {
+		t.Fatalf("Latest() = %q, want the stream opened", token)
+	}
+}
+
+func TestSlowSubscriberIsDroppedAsLagged(t *testing.T) {
+	clk := clock.NewSimulated(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	buffer := New[string](clk, time.Minute, 10*time.Minute, 1)
+	buffer.Open("token", "user")
+	_, live, cancel, err := buffer.Subscribe("token", "user", 0)
+	if err != nil {
+		t.Fatalf("Subscribe(): %v", err)
+	}
+	for seq := 1; seq <= 3; seq++ {
+		buffer.Publish("token", seq, "chunk")
+	}
+	for range live {
+	}
+	if !buffer.Lagged("token", live) {
+		t.Fatal("Lagged() = false for a subscriber that fell behind")
+	}
+	cancel()
+	if buffer.Lagged("token", live) {
+		t.Fatal("Lagged() = true after cancel")
+	}
+
+	_, live, cancel, err = buffer.Subscribe("token", "user", 3)
+	if err != nil {
+		t.Fatalf("Subscribe() again: %v", err)
+	}
+	defer cancel()
+	buffer.Finish("token")
+	for range live {
+	}
+	if buffer.Lagged("token", live) {
+		t.Fatal("Lagged() = true for a stream that finished")
+	}
+}
@@ -0,0 +1,153 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type chatGateway struct {
+	next   gateway.ChatGateway
+	tracer *Tracer
+}
+
+// NewChatGateway traces every call to next as a span of the call it is
+// made for, next as it is with a nil tracer.
+func NewChatGateway(next gateway.ChatGateway, tracer *Tracer) gateway.ChatGateway {
+	if tracer == nil {
+		return next
+	}
+	return &chatGateway{next: next, tracer: tracer}
+}
+
+// start starts the span of the call method, for chatID when set.
+func (g *chatGateway) start(ctx context.Context, method, chatID string) (context.Context, trace.Span) {
+	if chatID == "" {
+		return g.tracer.Start(ctx, "ChatGateway."+method)
+	}
+	return g.tracer.Start(ctx, "ChatGateway."+method, attribute.String("chat.id", chatID))
+}
+
+func (g *chatGateway) CreateChat(ctx context.Context, chat *entity.Chat) error {
+	ctx, span := g.start(ctx, "CreateChat", chat.ID)
+	defer span.End()
+	err := g.next.CreateChat(ctx, chat)
+	RecordError(span, err)
+	return err
+}
+
+func (g *chatGateway) FindChatByID(ctx context.Context, chatID string) (*entity.Chat, error) {
+	ctx, span := g.start(ctx, "FindChatByID", chatID)
+	defer span.End()
+	result, err := g.next.FindChatByID(ctx, chatID)
+	RecordError(span, err)
+	return result, err
+}
+
+func (g *chatGateway) FindChatsByParentID(ctx context.Context, parentChatID string) ([]*entity.Chat, error) {
+	ctx, span := g.start(ctx, "FindChatsByParentID", parentChatID)
+	defer span.End()
+	result, err := g.next.FindChatsByParentID(ctx, parentChatID)
+	RecordError(span, err)
+	return result, err
+}
+
+func (g *chatGateway) ListChats(ctx context.Context, filter gateway.ChatFilter) ([]*entity.Chat, error) {
+	ctx, span := g.start(ctx, "ListChats", "")
+	defer span.End()
+	result, err := g.next.ListChats(ctx, filter)
+	RecordError(span, err)
+	return result, err
+}
+
+func (g *chatGateway) ListChatsByUser(ctx context.Context, userID string, page int, size int) (*gateway.ChatPage, error) {
+	ctx, span := g.start(ctx, "ListChatsByUser", "")
+	defer span.End()
+	result, err := g.next.ListChatsByUser(ctx, userID, page, size)
+	RecordError(span, err)
+	return result, err
+}
+
+func (g *chatGateway) ListMessages(ctx context.Context, chatID string, cursor gateway.MessageCursor) (*gateway.MessagePage, error) {
+	ctx, span := g.start(ctx, "ListMessages", chatID)
+	defer span.End()
+	result, err := g.next.ListMessages(ctx, chatID, cursor)
+	RecordError(span, err)
+	return result, err
+}
+
+func (g *chatGateway) SaveChat(ctx context.Context, chat *entity.Chat) error {
+	ctx, span := g.start(ctx, "SaveChat", chat.ID)
+	defer span.End()
+	err := g.next.SaveChat(ctx, chat)
+	RecordError(span, err)
+	return err
+}
+
+func (g *chatGateway) UpdateChatTitle(ctx context.Context, chatID string, title string) error {
+	ctx, span := g.start(ctx, "UpdateChatTitle", chatID)
+	defer span.End()
+	err := g.next.UpdateChatTitle(ctx, chatID, title)
+	RecordError(span, err)
+	return err
+}
+
+func (g *chatGateway) AddChatTag(ctx context.Context, chatID string, tag string) error {
+	ctx, span := g.start(ctx, "AddChatTag", chatID)
+	defer span.End()
+	err := g.next.AddChatTag(ctx, chatID, tag)
+	RecordError(span, err)
+	return err
+}
+
+func (g *chatGateway) RemoveChatTag(ctx context.Context, chatID string, tag string) error {
+	ctx, span := g.start(ctx, "RemoveChatTag", chatID)
+	defer span.End()
+	err := g.next.RemoveChatTag(ctx, chatID, tag)
+	RecordError(span, err)
+	return err
+}
+
+func (g *chatGateway) SetMessageFlags(ctx context.Context, chatID string, messageID string, pinned bool, starred bool) error {
+	ctx, span := g.start(ctx, "SetMessageFlags", chatID)
+	defer span.End()
+	err := g.next.SetMessageFlags(ctx, chatID, messageID, pinned, starred)
+	RecordError(span, err)
+	return err
+}
+
+func (g *chatGateway) DeleteChat(ctx context.Context, chatID string, deletedAt time.Time) error {
+	ctx, span := g.start(ctx, "DeleteChat", chatID)
+	defer span.End()
+	err := g.next.DeleteChat(ctx, chatID, deletedAt)
+	RecordError(span, err)
+	return err
+}
+
+func (g *chatGateway) PurgeDeletedChats(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, span := g.start(ctx, "PurgeDeletedChats", "")
+	defer span.End()
+	result, err := g.next.PurgeDeletedChats(ctx, deletedBefore)
+	RecordError(span, err)
+	return result, err
+}
+
+func (g *chatGateway) FindExpiredChats(ctx context.Context, expiredBefore time.Time, limit int) ([]*entity.Chat, error) {
+	ctx, span := g.start(ctx, "FindExpiredChats", "")
+	defer span.End()
+	result, err := g.next.FindExpiredChats(ctx, expiredBefore, limit)
+	RecordError(span, err)
+	return result, err
+}
+
+func (g *chatGateway) PurgeExpiredChats(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	ctx, span := g.start(ctx, "PurgeExpiredChats", "")
+	defer span.End()
+	result, err := g.next.PurgeExpiredChats(ctx, expiredBefore)
+	RecordError(span, err)
+	return result, err
+}
@@ -0,0 +1,101 @@
+// Package tracing traces the calls of the service with the OpenTelemetry
+// SDK: the W3C trace context of the callers is continued by otelgrpc and
+// otelhttp, passed on to the provider, and the spans are batched and
+// exported over OTLP/HTTP so the latency of a request can be broken down by
+// stage.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation is the scope of the spans the service starts itself.
+const instrumentation = "github.com/alecanutto/fclx/chat-service"
+
+// noopTracer is what a nil Tracer starts its spans with.
+var noopTracer = trace.NewNoopTracerProvider().Tracer(instrumentation)
+
+// Tracer starts the spans of the service and exports them in batches. A nil
+// Tracer traces nothing.
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// NewTracer exports the spans to the OpenTelemetry collector at endpoint,
+// its base URL such as http://localhost:4318, as service with headers sent
+// along. The sampler and the batches are configured by the
+// OTEL_TRACES_SAMPLER and OTEL_BSP_ variables of the SDK.
+func NewTracer(ctx context.Context, endpoint, service string, headers map[string]string) (*Tracer, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q", endpoint)
+	}
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(strings.TrimSuffix(u.Path, "/") + "/v1/traces"),
+		otlptracehttp.WithHeaders(headers),
+	}
+	if u.Scheme == "http" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the span exporter: %s", err.Error())
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(service)))
+	if err != nil {
+		return nil, fmt.Errorf("error creating the trace resource: %s", err.Error())
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	return &Tracer{provider: provider, tracer: provider.Tracer(instrumentation)}, nil
+}
+
+// Provider is the provider of the tracer, for otelgrpc and otelhttp.
+func (t *Tracer) Provider() trace.TracerProvider {
+	if t == nil {
+		return trace.NewNoopTracerProvider()
+	}
+	return t.provider
+}
+
+// Start starts an internal span, child of the span of ctx, and returns ctx
+// with it.
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.StartKind(ctx, name, trace.SpanKindInternal, attrs...)
+}
+
+func (t *Tracer) StartKind(ctx context.Context, name string, kind trace.SpanKind, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if t == nil {
+		return noopTracer.Start(ctx, name)
+	}
+	return t.tracer.Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+}
+
+// Shutdown exports the spans ended so far and stops the tracer.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.provider.Shutdown(ctx)
+}
+
+// RecordError fails span with err, nil leaves it as it is.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,156 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/apikey"
+)
+
+type issueAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type apiKeyResponse struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	OrgID     string     `json:"org_id,omitempty"`
+	Hint      string     `json:"hint"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// Key is only returned when the key is issued.
+	Key string `json:"key,omitempty"`
+}
+
+type apiKeyListResponse struct {
+	APIKeys []apiKeyResponse `json:"api_keys"`
+}
+
+func newAPIKeyResponse(output *apikey.APIKeyOutputDTO) apiKeyResponse {
+	resp := apiKeyResponse{
+		ID:        output.ID,
+		Name:      output.Name,
+		OrgID:     output.OrgID,
+		Hint:      output.Hint,
+		Scopes:    output.Scopes,
+		CreatedAt: output.CreatedAt,
+		Key:       output.Key,
+	}
+	if !output.RevokedAt.IsZero() {
+		resp.RevokedAt = &output.RevokedAt
+	}
+	return resp
+}
+
+// APIKeysHandler serves /api-keys for the keys of the user: GET lists them
+// and POST issues one for the tenant of the request. API keys can't manage
+// keys themselves.
+type APIKeysHandler struct {
+	IssueAPIKey *apikey.IssueAPIKeyUseCase
+	ListAPIKeys *apikey.ListAPIKeysUseCase
+}
+
+func NewAPIKeysHandler(issueAPIKey *apikey.IssueAPIKeyUseCase, listAPIKeys *apikey.ListAPIKeysUseCase) *APIKeysHandler {
+	return &APIKeysHandler{
+		IssueAPIKey: issueAPIKey,
+		ListAPIKeys: listAPIKeys,
+	}
+}
+
+func (h *APIKeysHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user := userID(r)
+	if user == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		outputs, err := h.ListAPIKeys.Execute(r.Context(), user)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		resp := apiKeyListResponse{APIKeys: make([]apiKeyResponse, 0, len(outputs))}
+		for _, output := range outputs {
+			resp.APIKeys = append(resp.APIKeys, newAPIKeyResponse(output))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	case http.MethodPost:
+		var body issueAPIKeyRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid body"})
+			return
+		}
+		output, err := h.IssueAPIKey.Execute(r.Context(), apikey.IssueAPIKeyInputDTO{
+			UserID: user,
+			OrgID:  orgID(r),
+			Name:   body.Name,
+			Scopes: body.Scopes,
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, newAPIKeyResponse(output))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *APIKeysHandler) Operations() []Operation {
+	return []Operation{
+		{
+			Method:    http.MethodGet,
+			Summary:   "List the API keys of the user",
+			Responses: []Response{{Status: http.StatusOK, Description: "The keys, newest first, revoked ones included.", Body: apiKeyListResponse{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Summary:     "Issue an API key",
+			Description: "The key acts as the user, for the tenant of the request, within its scopes: chats:read, chats:write, completions, usage:read and audit:read. It is sent as a bearer token or in the X-API-Key header.",
+			Body:        issueAPIKeyRequest{},
+			Responses:   []Response{{Status: http.StatusCreated, Description: "The key, the only time it is returned.", Body: apiKeyResponse{}}},
+		},
+	}
+}
+
+// APIKeyHandler serves DELETE /api-keys/{id}, revoking a key of the user.
+type APIKeyHandler struct {
+	RevokeAPIKey *apikey.RevokeAPIKeyUseCase
+}
+
+func NewAPIKeyHandler(revokeAPIKey *apikey.RevokeAPIKeyUseCase) *APIKeyHandler {
+	return &APIKeyHandler{RevokeAPIKey: revokeAPIKey}
+}
+
+func (h *APIKeyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	input := apikey.RevokeAPIKeyInputDTO{
+		KeyID:  pathID(r),
+		UserID: userID(r),
+	}
+	if input.UserID == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	output, err := h.RevokeAPIKey.Execute(r.Context(), input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newAPIKeyResponse(output))
+}
+
+func (h *APIKeyHandler) Operations() []Operation {
+	return []Operation{{
+		Method:    http.MethodDelete,
+		Summary:   "Revoke an API key",
+		Responses: []Response{{Status: http.StatusOK, Description: "The revoked key, the calls made with it fail from then on.", Body: apiKeyResponse{}}},
+	}}
+}
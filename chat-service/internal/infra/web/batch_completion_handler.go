@@ -0,0 +1,88 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/batchcompletion"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+)
+
+type batchCompletionRequest struct {
+	Prompts []string `json:"prompts"`
+}
+
+type batchAnswer struct {
+	Status           string `json:"status"`
+	Content          string `json:"content,omitempty"`
+	Error            string `json:"error,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+}
+
+type batchCompletionResponse struct {
+	Answers []batchAnswer `json:"answers"`
+}
+
+// BatchCompletionsHandler serves POST /completions, it answers the prompts of
+// the body without streaming nor storing them, once all are answered.
+type BatchCompletionsHandler struct {
+	BatchCompletion *batchcompletion.BatchCompletionUseCase
+	// Config is the completion config the prompts are answered with.
+	Config chatcompletionstream.ChatCompletionConfigInputDTO
+}
+
+func NewBatchCompletionsHandler(batchCompletion *batchcompletion.BatchCompletionUseCase, config chatcompletionstream.ChatCompletionConfigInputDTO) *BatchCompletionsHandler {
+	return &BatchCompletionsHandler{
+		BatchCompletion: batchCompletion,
+		Config:          config,
+	}
+}
+
+func (h *BatchCompletionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	input := batchcompletion.BatchCompletionInputDTO{
+		UserID: userID(r),
+		OrgID:  orgID(r),
+		Config: h.Config,
+	}
+	if input.UserID == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	var body batchCompletionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid body"})
+		return
+	}
+	input.Prompts = body.Prompts
+	output, err := h.BatchCompletion.Execute(r.Context(), input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := batchCompletionResponse{Answers: make([]batchAnswer, 0, len(output.Answers))}
+	for _, answer := range output.Answers {
+		resp.Answers = append(resp.Answers, batchAnswer{
+			Status:           answer.Status,
+			Content:          answer.Content,
+			Error:            answer.Error,
+			PromptTokens:     answer.PromptTokens,
+			CompletionTokens: answer.CompletionTokens,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *BatchCompletionsHandler) Operations() []Operation {
+	return []Operation{{
+		Method:      http.MethodPost,
+		Summary:     "Answer prompts without streaming",
+		Description: "Each of the up to 50 prompts is answered on its own, outside of any chat, and nothing is stored. A prompt failing, rate limited for one, has an answer of status error instead of failing the others.",
+		Body:        batchCompletionRequest{},
+		Responses:   []Response{{Status: http.StatusOK, Description: "The answers, in the order of the prompts.", Body: batchCompletionResponse{}}},
+	}}
+}
@@ -0,0 +1,86 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/deletechat"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/renamechat"
+)
+
+type renameChatRequest struct {
+	Title string `json:"title"`
+}
+
+type renameChatResponse struct {
+	ChatID string `json:"chat_id"`
+	Title  string `json:"title"`
+}
+
+// ChatHandler serves /chats/{id} for the chats of the user: PATCH sets the
+// title of the body and DELETE soft-deletes the chat.
+type ChatHandler struct {
+	RenameChat *renamechat.RenameChatUseCase
+	DeleteChat *deletechat.DeleteChatUseCase
+}
+
+func NewChatHandler(renameChat *renamechat.RenameChatUseCase, deleteChat *deletechat.DeleteChatUseCase) *ChatHandler {
+	return &ChatHandler{
+		RenameChat: renameChat,
+		DeleteChat: deleteChat,
+	}
+}
+
+func (h *ChatHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user := userID(r)
+	if user == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	switch r.Method {
+	case http.MethodPatch:
+		var body renameChatRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid body"})
+			return
+		}
+		output, err := h.RenameChat.Execute(r.Context(), renamechat.RenameChatInputDTO{
+			ChatID: pathID(r),
+			UserID: user,
+			Title:  body.Title,
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, renameChatResponse(*output))
+	case http.MethodDelete:
+		err := h.DeleteChat.Execute(r.Context(), deletechat.DeleteChatInputDTO{
+			ChatID: pathID(r),
+			UserID: user,
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *ChatHandler) Operations() []Operation {
+	return []Operation{
+		{
+			Method:    http.MethodPatch,
+			Summary:   "Rename a chat",
+			Body:      renameChatRequest{},
+			Responses: []Response{{Status: http.StatusOK, Description: "The new title.", Body: renameChatResponse{}}},
+		},
+		{
+			Method:    http.MethodDelete,
+			Summary:   "Delete a chat",
+			Responses: []Response{{Status: http.StatusNoContent, Description: "The chat is soft-deleted."}},
+		},
+	}
+}
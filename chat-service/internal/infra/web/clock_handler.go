@@ -0,0 +1,43 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+// ClockHandler is the debug endpoint of the sandbox clock: GET returns its
+// time, POST moves it forward by the advance query parameter, a duration
+// like 90m, to expire TTLs and reset quotas without waiting.
+type ClockHandler struct {
+	Clock *clock.Sandbox
+}
+
+func NewClockHandler(clk *clock.Sandbox) *ClockHandler {
+	return &ClockHandler{
+		Clock: clk,
+	}
+}
+
+type clockResponse struct {
+	Now    time.Time `json:"now"`
+	Offset string    `json:"offset"`
+}
+
+func (h *ClockHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		d, err := time.ParseDuration(r.URL.Query().Get("advance"))
+		if err != nil || d <= 0 {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "advance must be a positive duration, like 90m"})
+			return
+		}
+		h.Clock.Advance(d)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, clockResponse{Now: h.Clock.Now(), Offset: h.Clock.Offset().String()})
+}
@@ -0,0 +1,307 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/dispatcher"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+)
+
+type completionRequest struct {
+	Message string `json:"message"`
+	// Priority is interactive, the default, or batch for the messages no
+	// user waits for the answer of.
+	Priority string `json:"priority,omitempty"`
+}
+
+// completionChunk is the data of the delta events and of the events of the
+// other chunks, which are named after their usecase event.
+type completionChunk struct {
+	ChatID               string `json:"chat_id"`
+	TurnID               string `json:"turn_id"`
+	Seq                  int    `json:"seq"`
+	Delta                string `json:"delta,omitempty"`
+	Content              string `json:"content,omitempty"`
+	ResumeToken          string `json:"resume_token,omitempty"`
+	QueuePosition        int    `json:"queue_position,omitempty"`
+	AwaitingConfirmation bool   `json:"awaiting_confirmation,omitempty"`
+	RequestID            string `json:"request_id,omitempty"`
+	// IncompleteReason is set on the generation_incomplete event.
+	IncompleteReason string `json:"incomplete_reason,omitempty"`
+	// FinishReason and Error are set on the generation_ended event, the last
+	// one of every turn before done or error.
+	FinishReason string `json:"finish_reason,omitempty"`
+	Error        string `json:"error,omitempty"`
+	// Usage is set on the usage event.
+	Usage *usageResponse `json:"usage,omitempty"`
+}
+
+type completionDone struct {
+	ChatID               string         `json:"chat_id"`
+	TurnID               string         `json:"turn_id"`
+	Content              string         `json:"content"`
+	AwaitingConfirmation bool           `json:"awaiting_confirmation,omitempty"`
+	Stopped              bool           `json:"stopped,omitempty"`
+	IncompleteReason     string         `json:"incomplete_reason,omitempty"`
+	FinishReason         string         `json:"finish_reason,omitempty"`
+	RequestID            string         `json:"request_id,omitempty"`
+	Usage                *usageResponse `json:"usage,omitempty"`
+}
+
+// usageResponse is the tokens of a turn, with their cost in US dollars at the
+// list price of the model, priced false and 0 when unknown.
+type usageResponse struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	EstimatedCost    float64 `json:"estimated_cost"`
+	PromptCost       float64 `json:"prompt_cost"`
+	CompletionCost   float64 `json:"completion_cost"`
+	Priced           bool    `json:"priced"`
+}
+
+// newUsageResponse is the usage of a usage chunk or of the output of a
+// turn.
+func newUsageResponse(output chatcompletionstream.ChatCompletionOutputDTO) *usageResponse {
+	return &usageResponse{
+		PromptTokens:     output.PromptTokens,
+		CompletionTokens: output.CompletionTokens,
+		TotalTokens:      output.TotalTokens,
+		EstimatedCost:    output.EstimatedCost,
+		PromptCost:       output.PromptCost,
+		CompletionCost:   output.CompletionCost,
+		Priced:           output.Priced,
+	}
+}
+
+// CompletionsHandler serves POST /chats/{id}/completions, it sends the
+// message of the body to the chat and streams the answer as Server-Sent
+// Events: delta events with what each content chunk adds, the other chunks
+// under the name of their event (generation_started, system_notice...),
+// then done with the whole answer, or error. Clients accepting
+// application/x-ndjson get the events as JSON lines instead. A turn failing
+// before its first event, rate limited for one, is answered with the status
+// of its error instead. A chat ID of new starts a chat.
+type CompletionsHandler struct {
+	ChatCompletion *chatcompletionstream.ChatCompletionUseCase
+	// Config is the completion config of the chats the requests start.
+	Config chatcompletionstream.ChatCompletionConfigInputDTO
+}
+
+func NewCompletionsHandler(chatCompletion *chatcompletionstream.ChatCompletionUseCase, config chatcompletionstream.ChatCompletionConfigInputDTO) *CompletionsHandler {
+	return &CompletionsHandler{
+		ChatCompletion: chatCompletion,
+		Config:         config,
+	}
+}
+
+func (h *CompletionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	input := chatcompletionstream.ChatCompletionInputDTO{
+		ChatID: pathID(r),
+		UserID: userID(r),
+		OrgID:  orgID(r),
+		Tier:   tier(r),
+		Config: h.Config,
+	}
+	if input.UserID == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	if input.ChatID == "new" {
+		input.ChatID = ""
+	}
+	var body completionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid body"})
+		return
+	}
+	if strings.TrimSpace(body.Message) == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "message is required"})
+		return
+	}
+	if _, ok := dispatcher.ParsePriority(body.Priority); !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "priority must be interactive or batch"})
+		return
+	}
+	input.UserMessage = body.Message
+	input.Priority = body.Priority
+	input.IdempotencyKey = r.Header.Get(idempotencyKeyHeader)
+	streamTurn(w, r, func(send func(chatcompletionstream.ChatCompletionOutputDTO) error) (*chatcompletionstream.ChatCompletionOutputDTO, error) {
+		return h.ChatCompletion.ExecuteStreaming(r.Context(), input, send)
+	})
+}
+
+func (h *CompletionsHandler) Operations() []Operation {
+	return []Operation{{
+		Method:      http.MethodPost,
+		Summary:     "Send a message to a chat and stream the answer",
+		Description: "A chat ID of new starts a chat, its ID comes with every event. A retry with the Idempotency-Key header of the request gets the answer already generated, as a done event, instead of a new one.",
+		Body:        completionRequest{},
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The answer as Server-Sent Events, or JSON lines of {event, data} when application/x-ndjson is accepted.", Events: completionEvents},
+			{Status: http.StatusForbidden, Description: "The chat belongs to another user.", Body: errorResponse{}},
+			{Status: http.StatusConflict, Description: "The Idempotency-Key is of a request still in progress or of another request.", Body: errorResponse{}},
+			rateLimitedResponse,
+		},
+	}}
+}
+
+// rateLimitedResponse documents the turns rejected by the rate limit, or by
+// the limit of completions in flight of the user.
+var rateLimitedResponse = Response{
+	Status:      http.StatusTooManyRequests,
+	Description: "The user or tenant is rate limited, Retry-After tells when to retry, or the user has too many completions in flight.",
+	Body:        errorResponse{},
+}
+
+// completionEvents are the events of a streamed turn.
+var completionEvents = map[string]interface{}{
+	"delta": completionChunk{},
+	"done":  completionDone{},
+	"error": errorResponse{},
+
+	chatcompletionstream.EventGenerationStarted:    completionChunk{},
+	chatcompletionstream.EventGenerationThinking:   completionChunk{},
+	chatcompletionstream.EventSystemNotice:         completionChunk{},
+	chatcompletionstream.EventWarning:              completionChunk{},
+	chatcompletionstream.EventGenerationStopped:    completionChunk{},
+	chatcompletionstream.EventGenerationIncomplete: completionChunk{},
+	chatcompletionstream.EventUsage:                completionChunk{},
+	chatcompletionstream.EventGenerationEnded:      completionChunk{},
+	chatcompletionstream.EventHeartbeat:            completionChunk{},
+}
+
+// streamTurn streams the turn run executes as the events of CompletionsHandler,
+// in the format r accepts.
+func streamTurn(w http.ResponseWriter, r *http.Request, run func(send func(chatcompletionstream.ChatCompletionOutputDTO) error) (*chatcompletionstream.ChatCompletionOutputDTO, error)) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "streaming is not supported"})
+		return
+	}
+	contentType, write := "text/event-stream", writeEvent
+	if acceptsNDJSON(r) {
+		contentType, write = ndjsonContentType, writeLine
+	}
+
+	// the stream starts with its first event, so a turn rejected before it,
+	// rate limited for one, is answered with its status instead
+	started := false
+	send := func(event string, v interface{}) error {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("X-Accel-Buffering", "no")
+			w.WriteHeader(http.StatusOK)
+		}
+		if err := write(w, event, v); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	output, err := run(func(chunk chatcompletionstream.ChatCompletionOutputDTO) error {
+		event, data := chunkEvent(chunk)
+		return send(event, data)
+	})
+	if output == nil && !started {
+		writeError(w, err)
+		return
+	}
+	if output == nil {
+		send("error", errorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		// the client is gone
+		return
+	}
+	send("done", completionDone{
+		ChatID:               output.ChatID,
+		TurnID:               output.TurnID,
+		Content:              output.Content,
+		AwaitingConfirmation: output.AwaitingConfirmation,
+		Stopped:              output.Stopped,
+		IncompleteReason:     output.IncompleteReason,
+		FinishReason:         output.FinishReason,
+		RequestID:            output.RequestID,
+		Usage:                newUsageResponse(*output),
+	})
+}
+
+// chunkEvent is the event of chunk and its data: delta for content chunks,
+// its usecase event for the others.
+func chunkEvent(chunk chatcompletionstream.ChatCompletionOutputDTO) (string, completionChunk) {
+	data := completionChunk{
+		ChatID:               chunk.ChatID,
+		TurnID:               chunk.TurnID,
+		Seq:                  chunk.Seq,
+		ResumeToken:          chunk.ResumeToken,
+		QueuePosition:        chunk.QueuePosition,
+		AwaitingConfirmation: chunk.AwaitingConfirmation,
+		RequestID:            chunk.RequestID,
+		IncompleteReason:     chunk.IncompleteReason,
+		FinishReason:         chunk.FinishReason,
+		Error:                chunk.Error,
+	}
+	if chunk.Event == chatcompletionstream.EventUsage {
+		data.Usage = newUsageResponse(chunk)
+	}
+	if chunk.Event != chatcompletionstream.EventContent {
+		data.Content = chunk.Content
+		return chunk.Event, data
+	}
+	data.Delta = chunk.Delta
+	return "delta", data
+}
+
+// writeEvent writes an SSE event with v as JSON data, on a single line.
+func writeEvent(w http.ResponseWriter, event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+const ndjsonContentType = "application/x-ndjson"
+
+// ndjsonLine is an event of a stream of JSON lines.
+type ndjsonLine struct {
+	Event string      `json:"event"`
+	Data  interface{} `json:"data"`
+}
+
+// writeLine writes an event as a JSON line.
+func writeLine(w http.ResponseWriter, event string, v interface{}) error {
+	data, err := json.Marshal(ndjsonLine{Event: event, Data: v})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// acceptsNDJSON tells whether r prefers JSON lines to Server-Sent Events,
+// the first of them listed in its Accept header winning.
+func acceptsNDJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(accepted, ";")
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case ndjsonContentType, "application/ndjson":
+			return true
+		case "text/event-stream":
+			return false
+		}
+	}
+	return false
+}
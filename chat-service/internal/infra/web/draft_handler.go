@@ -0,0 +1,104 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/draft"
+)
+
+type draftRequest struct {
+	Content string `json:"content"`
+}
+
+type draftResponse struct {
+	ChatID    string    `json:"chat_id"`
+	Content   string    `json:"content"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DraftHandler serves the draft of the chat_id query parameter: GET loads it,
+// PUT saves it and DELETE discards it.
+type DraftHandler struct {
+	SaveDraft    *draft.SaveDraftUseCase
+	GetDraft     *draft.GetDraftUseCase
+	DiscardDraft *draft.DiscardDraftUseCase
+}
+
+func NewDraftHandler(saveDraft *draft.SaveDraftUseCase, getDraft *draft.GetDraftUseCase, discardDraft *draft.DiscardDraftUseCase) *DraftHandler {
+	return &DraftHandler{
+		SaveDraft:    saveDraft,
+		GetDraft:     getDraft,
+		DiscardDraft: discardDraft,
+	}
+}
+
+func (h *DraftHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	input := draft.DraftInputDTO{
+		ChatID: r.URL.Query().Get("chat_id"),
+		UserID: userID(r),
+	}
+	if input.UserID == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		output, err := h.GetDraft.Execute(r.Context(), input)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, draftResponse(*output))
+	case http.MethodPut:
+		var body draftRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid body"})
+			return
+		}
+		output, err := h.SaveDraft.Execute(r.Context(), draft.SaveDraftInputDTO{
+			ChatID:  input.ChatID,
+			UserID:  input.UserID,
+			Content: body.Content,
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, draftResponse(*output))
+	case http.MethodDelete:
+		if err := h.DiscardDraft.Execute(r.Context(), input); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *DraftHandler) Operations() []Operation {
+	chatID := []QueryParam{{Name: "chat_id", Description: "The chat of the draft."}}
+	return []Operation{
+		{
+			Method:    http.MethodGet,
+			Summary:   "Load the draft of a chat",
+			Query:     chatID,
+			Responses: []Response{{Status: http.StatusOK, Description: "The draft.", Body: draftResponse{}}},
+		},
+		{
+			Method:    http.MethodPut,
+			Summary:   "Save the draft of a chat",
+			Query:     chatID,
+			Body:      draftRequest{},
+			Responses: []Response{{Status: http.StatusOK, Description: "The saved draft.", Body: draftResponse{}}},
+		},
+		{
+			Method:    http.MethodDelete,
+			Summary:   "Discard the draft of a chat",
+			Query:     chatID,
+			Responses: []Response{{Status: http.StatusNoContent, Description: "The draft is discarded."}},
+		},
+	}
+}
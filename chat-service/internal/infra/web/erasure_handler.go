@@ -0,0 +1,65 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/purgeuserdata"
+)
+
+// ErasureHandler is the admin endpoint of the right to erasure requests:
+// POST erases the data of the user of the body, which the confirmation must
+// repeat, and answers the deletion report.
+type ErasureHandler struct {
+	PurgeUserData *purgeuserdata.PurgeUserDataUseCase
+}
+
+func NewErasureHandler(purgeUserData *purgeuserdata.PurgeUserDataUseCase) *ErasureHandler {
+	return &ErasureHandler{
+		PurgeUserData: purgeUserData,
+	}
+}
+
+type erasureRequest struct {
+	UserID       string `json:"user_id"`
+	Confirmation string `json:"confirmation"`
+	RequestedBy  string `json:"requested_by"`
+}
+
+type erasureResponse struct {
+	ErasureID    string   `json:"erasure_id"`
+	UserID       string   `json:"user_id"`
+	Chats        int64    `json:"chats"`
+	Messages     int64    `json:"messages"`
+	Annotations  int64    `json:"annotations"`
+	Attachments  int64    `json:"attachments"`
+	Embeddings   int64    `json:"embeddings"`
+	FilesDeleted int64    `json:"files_deleted"`
+	FailedFiles  []string `json:"failed_files,omitempty"`
+}
+
+func (h *ErasureHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var body erasureRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid body"})
+		return
+	}
+	if body.RequestedBy == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "requested_by is empty"})
+		return
+	}
+	output, err := h.PurgeUserData.Execute(r.Context(), purgeuserdata.PurgeUserDataInputDTO{
+		UserID:       body.UserID,
+		Confirmation: body.Confirmation,
+		RequestedBy:  body.RequestedBy,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, erasureResponse(*output))
+}
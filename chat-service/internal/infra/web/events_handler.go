@@ -0,0 +1,89 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+)
+
+// EventsHandler serves GET /chats/{id}/events, a long poll of the events of
+// the latest answer of the chat for the clients behind proxies that break
+// Server-Sent Events and WebSockets. It returns the events after since
+// right away, or waits up to wait seconds for the next ones.
+type EventsHandler struct {
+	PollEvents *chatcompletionstream.PollEventsUseCase
+}
+
+func NewEventsHandler(pollEvents *chatcompletionstream.PollEventsUseCase) *EventsHandler {
+	return &EventsHandler{
+		PollEvents: pollEvents,
+	}
+}
+
+// eventsResponse holds the events of the turn as the JSON lines of a
+// completion stream, the done event excepted: the turn is over once
+// Finished is set.
+type eventsResponse struct {
+	TurnID    string       `json:"turn_id,omitempty"`
+	Events    []ndjsonLine `json:"events"`
+	NextSince int          `json:"next_since"`
+	Finished  bool         `json:"finished"`
+}
+
+func (h *EventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	user := userID(r)
+	if user == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	since, ok := intParam(w, r, "since")
+	if !ok {
+		return
+	}
+	wait, ok := intParam(w, r, "wait")
+	if !ok {
+		return
+	}
+	output, err := h.PollEvents.Execute(r.Context(), chatcompletionstream.PollEventsInputDTO{
+		ChatID: pathID(r),
+		UserID: user,
+		TurnID: r.URL.Query().Get("turn_id"),
+		Since:  since,
+		Wait:   time.Duration(wait) * time.Second,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := eventsResponse{
+		TurnID:    output.TurnID,
+		Events:    make([]ndjsonLine, 0, len(output.Events)),
+		NextSince: output.NextSince,
+		Finished:  output.Finished,
+	}
+	for _, chunk := range output.Events {
+		event, data := chunkEvent(chunk)
+		resp.Events = append(resp.Events, ndjsonLine{Event: event, Data: data})
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *EventsHandler) Operations() []Operation {
+	return []Operation{{
+		Method:      http.MethodGet,
+		Summary:     "Long poll the events of the latest answer of a chat",
+		Description: "A fallback for the clients that can't keep a stream open. The events after since are returned right away, otherwise the call waits for the next ones: poll again with the turn_id and next_since of the response until finished. A turn_id that is no longer the latest returns the events of the new answer from its first.",
+		Query: []QueryParam{
+			{Name: "turn_id", Description: "The turn_id of the previous response."},
+			{Name: "since", Description: "The next_since of the previous response, 0 by default.", Integer: true},
+			{Name: "wait", Description: "The seconds to wait for an event, at most and by default 25.", Integer: true},
+		},
+		Responses: []Response{{Status: http.StatusOK, Description: "The events of the answer, the data of the events of a completion stream.", Body: eventsResponse{}}},
+	}}
+}
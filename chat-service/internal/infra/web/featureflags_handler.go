@@ -0,0 +1,31 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/featureflag"
+)
+
+// FeatureFlagsHandler is a debug endpoint returning the state of every known
+// flag for the org_id and user_id query parameters.
+type FeatureFlagsHandler struct {
+	Flags *featureflag.Flags
+}
+
+func NewFeatureFlagsHandler(flags *featureflag.Flags) *FeatureFlagsHandler {
+	return &FeatureFlagsHandler{
+		Flags: flags,
+	}
+}
+
+func (h *FeatureFlagsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	target := featureflag.Target{
+		OrgID:  r.URL.Query().Get("org_id"),
+		UserID: r.URL.Query().Get("user_id"),
+	}
+	writeJSON(w, http.StatusOK, h.Flags.State(r.Context(), target))
+}
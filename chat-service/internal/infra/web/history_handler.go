@@ -0,0 +1,262 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/listchats"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/listmessages"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/searchchats"
+)
+
+type chatSummaryResponse struct {
+	ChatID             string    `json:"chat_id"`
+	Title              string    `json:"title"`
+	Status             string    `json:"status"`
+	Tags               []string  `json:"tags"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	LastMessagePreview string    `json:"last_message_preview,omitempty"`
+}
+
+type chatPageResponse struct {
+	Chats      []chatSummaryResponse `json:"chats"`
+	Page       int                   `json:"page"`
+	Size       int                   `json:"size"`
+	Total      int                   `json:"total"`
+	TotalPages int                   `json:"total_pages"`
+}
+
+type messageResponse struct {
+	ID               string    `json:"id"`
+	Role             string    `json:"role"`
+	Content          string    `json:"content"`
+	Tokens           int       `json:"tokens"`
+	Pinned           bool      `json:"pinned"`
+	Starred          bool      `json:"starred"`
+	IncompleteReason string    `json:"incomplete_reason,omitempty"`
+	AnnotationCount  int       `json:"annotation_count"`
+	AttachmentCount  int       `json:"attachment_count"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type messagePageResponse struct {
+	Messages   []messageResponse `json:"messages"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// defaultChatsPageSize is the size of the pages of chats without the size
+// query parameter, the one of the listings.
+const defaultChatsPageSize = 20
+
+// ChatsHandler serves GET with the page and size query parameters, the
+// chats of the user for the history sidebar, from the read model. The q, tag
+// and status query parameters search them by title, tag and status.
+type ChatsHandler struct {
+	SearchChats *searchchats.SearchChatsUseCase
+}
+
+func NewChatsHandler(searchChats *searchchats.SearchChatsUseCase) *ChatsHandler {
+	return &ChatsHandler{SearchChats: searchChats}
+}
+
+func (h *ChatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	input := searchchats.SearchChatsInputDTO{
+		UserID: userID(r),
+		Query:  query.Get("q"),
+		Tag:    query.Get("tag"),
+		Status: query.Get("status"),
+	}
+	if input.UserID == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	page, ok := intParam(w, r, "page")
+	if !ok {
+		return
+	}
+	if page < 1 {
+		page = 1
+	}
+	if input.Limit, ok = intParam(w, r, "size"); !ok {
+		return
+	}
+	if input.Limit <= 0 {
+		input.Limit = defaultChatsPageSize
+	}
+	input.Offset = (page - 1) * input.Limit
+	output, err := h.SearchChats.Execute(r.Context(), input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := chatPageResponse{
+		Chats:      make([]chatSummaryResponse, 0, len(output.Chats)),
+		Page:       page,
+		Size:       output.Limit,
+		Total:      output.Total,
+		TotalPages: (output.Total + output.Limit - 1) / output.Limit,
+	}
+	for _, chat := range output.Chats {
+		response.Chats = append(response.Chats, chatSummaryResponse{
+			ChatID:    chat.ChatID,
+			Title:     chat.Title,
+			Status:    chat.Status,
+			Tags:      chat.Tags,
+			CreatedAt: chat.CreatedAt,
+			UpdatedAt: chat.UpdatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// UserChatsHandler serves GET /users/{id}/chats with the page and size query
+// parameters, the chats of the user with a preview of their last message.
+// Users can only list their own chats.
+type UserChatsHandler struct {
+	ListChatsByUser *listchats.ListChatsByUserUseCase
+}
+
+func NewUserChatsHandler(listChatsByUser *listchats.ListChatsByUserUseCase) *UserChatsHandler {
+	return &UserChatsHandler{ListChatsByUser: listChatsByUser}
+}
+
+func (h *UserChatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	input := listchats.ListChatsByUserInputDTO{UserID: userID(r)}
+	if input.UserID == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	if pathID(r) != input.UserID {
+		writeJSON(w, http.StatusForbidden, errorResponse{Error: "chats belong to another user"})
+		return
+	}
+	var ok bool
+	if input.Page, ok = intParam(w, r, "page"); !ok {
+		return
+	}
+	if input.Size, ok = intParam(w, r, "size"); !ok {
+		return
+	}
+	output, err := h.ListChatsByUser.Execute(r.Context(), input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeChatPage(w, output)
+}
+
+func (h *ChatsHandler) Operations() []Operation {
+	return []Operation{{
+		Method:      http.MethodGet,
+		Summary:     "List the chats of the user",
+		Description: "The chats are listed from the read model, a chat changed shows up once it was projected.",
+		Query:       append(searchParams, pageParams...),
+		Responses:   []Response{{Status: http.StatusOK, Description: "A page of chats, most recent first.", Body: chatPageResponse{}}},
+	}}
+}
+
+func (h *UserChatsHandler) Operations() []Operation {
+	return []Operation{{
+		Method:      http.MethodGet,
+		Summary:     "List the chats of a user with their last message",
+		Description: "Users can only list their own chats, the path ID must be the user of the request.",
+		Query:       pageParams,
+		Responses:   []Response{{Status: http.StatusOK, Description: "A page of chats with a preview of their last message.", Body: chatPageResponse{}}},
+	}}
+}
+
+var searchParams = []QueryParam{
+	{Name: "q", Description: "Matches the titles containing it, ignoring case."},
+	{Name: "tag", Description: "Only the chats with the tag."},
+	{Name: "status", Description: "Only the chats with the status, active, ended or archived."},
+}
+
+var pageParams = []QueryParam{
+	{Name: "page", Description: "The page, from 1.", Integer: true},
+	{Name: "size", Description: "The chats per page.", Integer: true},
+}
+
+func writeChatPage(w http.ResponseWriter, output *listchats.ChatPageOutputDTO) {
+	response := chatPageResponse{
+		Chats:      make([]chatSummaryResponse, 0, len(output.Chats)),
+		Page:       output.Page,
+		Size:       output.Size,
+		Total:      output.Total,
+		TotalPages: output.TotalPages,
+	}
+	for _, chat := range output.Chats {
+		response.Chats = append(response.Chats, chatSummaryResponse(chat))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// MessagesHandler serves GET with the chat_id, cursor and limit query
+// parameters, the messages of the chat newest page first. Mounted under
+// /chats/{id}/messages it takes the chat ID from the path.
+type MessagesHandler struct {
+	ListMessages *listmessages.ListMessagesUseCase
+}
+
+func NewMessagesHandler(listMessages *listmessages.ListMessagesUseCase) *MessagesHandler {
+	return &MessagesHandler{ListMessages: listMessages}
+}
+
+func (h *MessagesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	chatID := pathID(r)
+	if chatID == "" {
+		chatID = r.URL.Query().Get("chat_id")
+	}
+	input := listmessages.ListMessagesInputDTO{
+		ChatID: chatID,
+		UserID: userID(r),
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+	if input.UserID == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	var ok bool
+	if input.Limit, ok = intParam(w, r, "limit"); !ok {
+		return
+	}
+	output, err := h.ListMessages.Execute(r.Context(), input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := messagePageResponse{
+		Messages:   make([]messageResponse, 0, len(output.Messages)),
+		NextCursor: output.NextCursor,
+	}
+	for _, m := range output.Messages {
+		response.Messages = append(response.Messages, messageResponse(m))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *MessagesHandler) Operations() []Operation {
+	return []Operation{{
+		Method:  http.MethodGet,
+		Summary: "List the messages of a chat",
+		Query: []QueryParam{
+			{Name: "chat_id", Description: "The chat, when it is not in the path."},
+			{Name: "cursor", Description: "The next_cursor of the previous page."},
+			{Name: "limit", Description: "The messages per page.", Integer: true},
+		},
+		Responses: []Response{{Status: http.StatusOK, Description: "A page of messages, newest first.", Body: messagePageResponse{}}},
+	}}
+}
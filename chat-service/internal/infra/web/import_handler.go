@@ -0,0 +1,103 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/importchat"
+)
+
+// maxImportSize bounds the body of an import, ChatGPT exports of heavy users
+// run into the tens of megabytes.
+const maxImportSize = 64 << 20
+
+type importedChatResponse struct {
+	SourceID string `json:"source_id"`
+	ChatID   string `json:"chat_id"`
+	Title    string `json:"title"`
+	Messages int    `json:"messages"`
+	Redacted int    `json:"redacted"`
+}
+
+type skippedItemResponse struct {
+	SourceID  string `json:"source_id,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+type importResponse struct {
+	Format   string                 `json:"format"`
+	Imported []importedChatResponse `json:"imported"`
+	Skipped  []skippedItemResponse  `json:"skipped"`
+}
+
+// importFailureResponse reports the chats imported before the import failed.
+type importFailureResponse struct {
+	Error  string         `json:"error"`
+	Report importResponse `json:"report"`
+}
+
+// ImportHandler serves POST with an export file, or a ChatGPT
+// conversations.json, as body and imports its chats for the user.
+type ImportHandler struct {
+	ImportChats *importchat.ImportChatsUseCase
+}
+
+func NewImportHandler(importChats *importchat.ImportChatsUseCase) *ImportHandler {
+	return &ImportHandler{ImportChats: importChats}
+}
+
+func (h *ImportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	input := importchat.ImportChatsInputDTO{UserID: userID(r), OrgID: orgID(r)}
+	if input.UserID == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse{Error: "import is too large"})
+		return
+	}
+	input.Data = data
+	output, err := h.ImportChats.Execute(r.Context(), input)
+	if err != nil && output == nil {
+		writeError(w, err)
+		return
+	}
+	response := importResponse{
+		Format:   output.Format,
+		Imported: make([]importedChatResponse, 0, len(output.Imported)),
+		Skipped:  make([]skippedItemResponse, 0, len(output.Skipped)),
+	}
+	for _, c := range output.Imported {
+		response.Imported = append(response.Imported, importedChatResponse(c))
+	}
+	for _, s := range output.Skipped {
+		response.Skipped = append(response.Skipped, skippedItemResponse(s))
+	}
+	if err != nil {
+		// the chats imported before the failure stay, report them with the error
+		writeJSON(w, http.StatusInternalServerError, importFailureResponse{Error: err.Error(), Report: response})
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *ImportHandler) Operations() []Operation {
+	return []Operation{{
+		Method:      http.MethodPost,
+		Summary:     "Import chats",
+		Description: "The body is an export of the service or a ChatGPT conversations.json, of up to 64MiB.",
+		Body:        json.RawMessage{},
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The imported chats and the skipped items.", Body: importResponse{}},
+			{Status: http.StatusRequestEntityTooLarge, Description: "The import is too large.", Body: errorResponse{}},
+			{Status: http.StatusInternalServerError, Description: "The import failed, the chats imported before stay.", Body: importFailureResponse{}},
+		},
+	}}
+}
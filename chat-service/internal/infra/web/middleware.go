@@ -0,0 +1,59 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/middleware"
+)
+
+// chained runs the middleware of rt around next, mounted at pattern.
+func (rt *Router) chained(pattern string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rt.middleware == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		call := &middleware.Call{
+			Transport:  middleware.HTTP,
+			Method:     pattern,
+			HTTPMethod: r.Method,
+			Path:       r.URL.Path,
+			Header:     r.Header.Get,
+			SetHeader:  w.Header().Set,
+		}
+		err := rt.middleware(func(ctx context.Context, call *middleware.Call) error {
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+			call.Status = strconv.Itoa(recorder.status)
+			return nil
+		})(r.Context(), call)
+		if err != nil && !recorder.wrote {
+			writeMiddlewareError(recorder, err)
+		}
+	})
+}
+
+// writeMiddlewareError answers a call a middleware rejected.
+func writeMiddlewareError(w http.ResponseWriter, err error) {
+	var rejected *middleware.Error
+	if !errors.As(err, &rejected) {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "internal error"})
+		return
+	}
+	status := http.StatusInternalServerError
+	switch rejected.Code {
+	case middleware.Unauthenticated:
+		status = http.StatusUnauthorized
+	case middleware.PermissionDenied:
+		status = http.StatusForbidden
+	case middleware.ResourceExhausted:
+		status = http.StatusTooManyRequests
+	}
+	if rejected.RetryAfter > 0 {
+		w.Header().Set("Retry-After", retryAfter(rejected.RetryAfter))
+	}
+	writeJSON(w, status, errorResponse{Error: rejected.Message})
+}
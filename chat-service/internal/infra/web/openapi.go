@@ -0,0 +1,366 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/middleware"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/requestid"
+)
+
+// Operation documents one method of a route for the OpenAPI document. Body
+// and the Body of the responses are values of the DTOs the handler decodes
+// and encodes, their schemas are derived from the json tags.
+type Operation struct {
+	Method string
+	// Path is relative to where the handler is mounted, with {name} for path
+	// parameters.
+	Path        string
+	Summary     string
+	Description string
+	Query       []QueryParam
+	Body        interface{}
+	// BodyType is the content type of Body, application/json by default.
+	BodyType     string
+	BodyOptional bool
+	Responses    []Response
+}
+
+type QueryParam struct {
+	Name        string
+	Description string
+	Integer     bool
+}
+
+type Response struct {
+	Status      int
+	Description string
+	Body        interface{}
+	// ContentType is the content type of Body, application/json by default.
+	ContentType string
+	// Events are the data of the Server-Sent Events of a text/event-stream
+	// response by event name, or of the lines of its application/x-ndjson
+	// alternative.
+	Events map[string]interface{}
+}
+
+// documented handlers describe their operations for the OpenAPI document.
+type documented interface {
+	Operations() []Operation
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// OpenAPI builds the OpenAPI 3 document of the operations mounted on rt, as
+// served under version. Responses are documented as the newest version
+// produces them.
+func (rt *Router) OpenAPI(version APIVersion) map[string]interface{} {
+	b := &schemaBuilder{components: map[string]interface{}{}}
+	paths := map[string]interface{}{}
+	for _, op := range rt.operations {
+		item, _ := paths[op.Path].(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = b.operation(op)
+	}
+	info := map[string]interface{}{
+		"title":   "Chat Service API",
+		"version": version.Name,
+	}
+	if !version.DeprecatedAt.IsZero() {
+		info["description"] = fmt.Sprintf("Deprecated since %s.", version.DeprecatedAt.UTC().Format("2006-01-02"))
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    info,
+		"servers": []interface{}{map[string]interface{}{"url": "/" + version.Name}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": b.components,
+			"securitySchemes": map[string]interface{}{
+				"user": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        userIDHeader,
+					"description": "The authenticated user, set by the gateway in front of the service.",
+				},
+				"apiKey": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        middleware.APIKeyHeader,
+					"description": "An API key of the user, also accepted as a bearer token. Its calls are made as the user, for the tenant of the key.",
+				},
+				"bearer": map[string]interface{}{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+					"description":  "A token of the OIDC issuer, when one is configured. Its calls are made as its subject.",
+				},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"user": []interface{}{}},
+			map[string]interface{}{"apiKey": []interface{}{}},
+			map[string]interface{}{"bearer": []interface{}{}},
+		},
+	}
+}
+
+type schemaBuilder struct {
+	components map[string]interface{}
+}
+
+func (b *schemaBuilder) operation(op Operation) map[string]interface{} {
+	doc := map[string]interface{}{
+		"operationId": operationID(op),
+		"summary":     op.Summary,
+	}
+	if op.Description != "" {
+		doc["description"] = op.Description
+	}
+	var params []interface{}
+	for _, match := range pathParam.FindAllStringSubmatch(op.Path, -1) {
+		params = append(params, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, q := range op.Query {
+		schema := map[string]interface{}{"type": "string"}
+		if q.Integer {
+			schema["type"] = "integer"
+		}
+		params = append(params, map[string]interface{}{
+			"name":        q.Name,
+			"in":          "query",
+			"description": q.Description,
+			"schema":      schema,
+		})
+	}
+	params = append(params, map[string]interface{}{
+		"name":        requestid.Header,
+		"in":          "header",
+		"description": "The ID to trace the request by, a new one when missing or invalid. It is returned in the same header.",
+		"schema":      map[string]interface{}{"type": "string"},
+	})
+	doc["parameters"] = params
+	if op.Body != nil {
+		doc["requestBody"] = map[string]interface{}{
+			"required": !op.BodyOptional,
+			"content":  b.content(op.BodyType, op.Body),
+		}
+	}
+	responses := map[string]interface{}{}
+	for _, resp := range op.Responses {
+		entry := map[string]interface{}{"description": resp.Description}
+		if resp.Body != nil {
+			entry["content"] = b.content(resp.ContentType, resp.Body)
+		}
+		if len(resp.Events) > 0 {
+			events := map[string]interface{}{}
+			for name, data := range resp.Events {
+				events[name] = b.schema(reflect.TypeOf(data))
+			}
+			entry["content"] = map[string]interface{}{
+				"text/event-stream": map[string]interface{}{
+					"schema":   map[string]interface{}{"type": "string"},
+					"x-events": events,
+				},
+				ndjsonContentType: map[string]interface{}{
+					"schema":   map[string]interface{}{"type": "string"},
+					"x-events": events,
+				},
+			}
+		}
+		responses[strconv.Itoa(resp.Status)] = entry
+	}
+	responses["401"] = map[string]interface{}{
+		"description": "The user header is missing.",
+		"content":     b.content("", errorResponse{}),
+	}
+	responses["default"] = map[string]interface{}{
+		"description": "The request failed: 400 when it is invalid, 403 for a resource of another user, 404 when it doesn't exist.",
+		"content":     b.content("", errorResponse{}),
+	}
+	doc["responses"] = responses
+	return doc
+}
+
+// operationID derives the ID of op from its method and path, GET
+// /chats/{id}/messages is getChatsIdMessages.
+func operationID(op Operation) string {
+	id := strings.ToLower(op.Method)
+	for _, part := range strings.FieldsFunc(op.Path, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+func (b *schemaBuilder) content(contentType string, body interface{}) map[string]interface{} {
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	return map[string]interface{}{
+		contentType: map[string]interface{}{"schema": b.schema(reflect.TypeOf(body))},
+	}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// schema returns the schema of t, named structs are added to the components
+// and referenced.
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := b.schema(t.Elem())
+		if _, ref := schema["$ref"]; ref {
+			return schema
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name := schemaName(t)
+		if _, ok := b.components[name]; !ok {
+			// registered first so recursive types end on the reference
+			b.components[name] = map[string]interface{}{}
+			b.components[name] = b.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (b *schemaBuilder) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// schemaName names the schema of a DTO after its type, chatSummaryResponse
+// is ChatSummaryResponse.
+func schemaName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+// OpenAPIHandler serves GET with the OpenAPI document of its version.
+type OpenAPIHandler struct {
+	Router  *Router
+	Version APIVersion
+}
+
+func (h *OpenAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.Router.OpenAPI(h.Version))
+}
+
+// swaggerUI loads Swagger UI from its CDN with the document next to the page.
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Chat Service API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5.9.0/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5.9.0/swagger-ui-bundle.js"></script>
+<script>
+window.ui = SwaggerUIBundle({url: "openapi.json", dom_id: "#swagger-ui"});
+</script>
+</body>
+</html>
+`
+
+func serveDocs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUI))
+}
+
+// HandleDocs serves the OpenAPI document of every version under
+// /<version>/openapi.json with Swagger UI at /<version>/docs, and redirects
+// /docs to the newest version. Call it once every route is mounted.
+func (rt *Router) HandleDocs() {
+	for _, version := range rt.versions {
+		rt.mux.Handle("/"+version.Name+"/openapi.json", &OpenAPIHandler{Router: rt, Version: version})
+		rt.mux.HandleFunc("/"+version.Name+"/docs", serveDocs)
+	}
+	if len(rt.versions) > 0 {
+		newest := rt.versions[len(rt.versions)-1]
+		rt.mux.Handle("/docs", http.RedirectHandler("/"+newest.Name+"/docs", http.StatusFound))
+	}
+}
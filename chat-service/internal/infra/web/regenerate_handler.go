@@ -0,0 +1,66 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+)
+
+type regenerateRequest struct {
+	Temperature *float32 `json:"temperature"`
+}
+
+// RegenerateHandler serves POST /chats/{id}/regenerate, it discards the last
+// answer of the chat and streams a new one like CompletionsHandler does. The
+// body is optional, its temperature applies to the new answer only.
+type RegenerateHandler struct {
+	Regenerate *chatcompletionstream.RegenerateUseCase
+}
+
+func NewRegenerateHandler(regenerate *chatcompletionstream.RegenerateUseCase) *RegenerateHandler {
+	return &RegenerateHandler{
+		Regenerate: regenerate,
+	}
+}
+
+func (h *RegenerateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	input := chatcompletionstream.RegenerateInputDTO{
+		ChatID: pathID(r),
+		UserID: userID(r),
+		OrgID:  orgID(r),
+		Tier:   tier(r),
+	}
+	if input.UserID == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	var body regenerateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid body"})
+		return
+	}
+	input.Temperature = body.Temperature
+	streamTurn(w, r, func(send func(chatcompletionstream.ChatCompletionOutputDTO) error) (*chatcompletionstream.ChatCompletionOutputDTO, error) {
+		return h.Regenerate.ExecuteStreaming(r.Context(), input, send)
+	})
+}
+
+func (h *RegenerateHandler) Operations() []Operation {
+	return []Operation{{
+		Method:       http.MethodPost,
+		Summary:      "Regenerate the last answer of a chat",
+		Body:         regenerateRequest{},
+		BodyOptional: true,
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The new answer as Server-Sent Events or JSON lines, like a completion.", Events: completionEvents},
+			rateLimitedResponse,
+		},
+	}}
+}
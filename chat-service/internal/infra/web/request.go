@@ -0,0 +1,161 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/dispatcher"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/middleware"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/ratelimit"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/streambuffer"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+)
+
+// userIDHeader, orgIDHeader and tierHeader carry the authenticated user, its
+// tenant and the completion tier of its plan, set by the gateway in front of
+// the service.
+const (
+	userIDHeader = "X-User-ID"
+	orgIDHeader  = "X-Org-ID"
+	tierHeader   = "X-Tier"
+)
+
+// idempotencyKeyHeader lets a retried message get the answer of the first
+// request.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// userID is the user of the API key of the request, or of userIDHeader.
+func userID(r *http.Request) string {
+	if p := middleware.PrincipalFromContext(r.Context()); p != nil {
+		return p.UserID
+	}
+	return strings.TrimSpace(r.Header.Get(userIDHeader))
+}
+
+// orgID is the tenant of the API key of the request, or of orgIDHeader.
+func orgID(r *http.Request) string {
+	if p := middleware.PrincipalFromContext(r.Context()); p != nil {
+		return p.OrgID
+	}
+	return strings.TrimSpace(r.Header.Get(orgIDHeader))
+}
+
+// tier is the completion tier of the principal of the request, or of
+// tierHeader, empty for the default one.
+func tier(r *http.Request) string {
+	if p := middleware.PrincipalFromContext(r.Context()); p != nil {
+		return p.Tier
+	}
+	return strings.TrimSpace(r.Header.Get(tierHeader))
+}
+
+// intParam reads an optional integer query parameter, zero when missing. It
+// writes a bad request and returns false when it is malformed.
+func intParam(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid " + name})
+		return 0, false
+	}
+	return n, true
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// notFoundErrors are the errors of the resources a request names that don't
+// exist, forbiddenErrors of those of another user or tenant.
+var (
+	notFoundErrors = []error{
+		gateway.ErrChatNotFound,
+		gateway.ErrDraftNotFound,
+		gateway.ErrAPIKeyNotFound,
+		gateway.ErrFileNotFound,
+		gateway.ErrWebhookNotFound,
+		entity.ErrMessageNotFound,
+		entity.ErrTagNotFound,
+		entity.ErrAnnotationNotFound,
+		streambuffer.ErrStreamNotFound,
+		chatcompletionstream.ErrGenerationNotFound,
+	}
+	forbiddenErrors = []error{
+		entity.ErrChatForbidden,
+		entity.ErrAPIKeyForbidden,
+		entity.ErrWebhookForbidden,
+		streambuffer.ErrStreamForbidden,
+	}
+)
+
+// isAny tells whether err is one of targets, whatever wraps it.
+func isAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeError maps the use case errors to a status code.
+func writeError(w http.ResponseWriter, err error) {
+	var limited *ratelimit.Error
+	if errors.As(err, &limited) {
+		w.Header().Set("Retry-After", retryAfter(limited.RetryAfter))
+		writeJSON(w, http.StatusTooManyRequests, errorResponse{Error: err.Error()})
+		return
+	}
+	var timedOut *chatcompletionstream.TimeoutError
+	if errors.As(err, &timedOut) {
+		writeJSON(w, http.StatusGatewayTimeout, errorResponse{Error: err.Error()})
+		return
+	}
+	status := http.StatusBadRequest
+	switch {
+	case isAny(err, notFoundErrors):
+		status = http.StatusNotFound
+	case isAny(err, forbiddenErrors):
+		status = http.StatusForbidden
+	case errors.Is(err, gateway.ErrChatConflict),
+		errors.Is(err, chatcompletionstream.ErrIdempotencyKeyInProgress),
+		errors.Is(err, chatcompletionstream.ErrIdempotencyKeyReused):
+		status = http.StatusConflict
+	case errors.Is(err, entity.ErrTokenLimitExceeded):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, entity.ErrInvalidRole):
+		status = http.StatusBadRequest
+	case errors.Is(err, entity.ErrProviderUnavailable):
+		status = http.StatusBadGateway
+	case errors.Is(err, dispatcher.ErrUserLimit):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, streambuffer.ErrSequenceExpired):
+		status = http.StatusGone
+	case errors.Is(err, dispatcher.ErrQueueFull),
+		errors.Is(err, dispatcher.ErrQueueTimeout),
+		errors.Is(err, chatcompletionstream.ErrShuttingDown):
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// retryAfter is the Retry-After header of d, in whole seconds rounded up.
+func retryAfter(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
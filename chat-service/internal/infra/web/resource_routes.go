@@ -0,0 +1,67 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type pathIDKey struct{}
+
+// ResourceRoutes serves /<Resource>/{id}/<action> with the handler of the
+// action, "" for /<Resource>/{id}. Handlers read the ID with pathID.
+type ResourceRoutes struct {
+	Resource string
+	Actions  map[string]http.Handler
+}
+
+func (routes ResourceRoutes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// the path is /<version>/<resource>/{id}[/<action>]
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 || len(parts) > 4 || parts[1] != routes.Resource || parts[2] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	action := ""
+	if len(parts) == 4 {
+		action = parts[3]
+	}
+	handler, ok := routes.Actions[action]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), pathIDKey{}, parts[2])))
+}
+
+// Operations documents the operations of the documented actions under
+// /{id}/<action>.
+func (routes ResourceRoutes) Operations() []Operation {
+	actions := make([]string, 0, len(routes.Actions))
+	for action := range routes.Actions {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	var ops []Operation
+	for _, action := range actions {
+		d, ok := routes.Actions[action].(documented)
+		if !ok {
+			continue
+		}
+		prefix := "/{id}"
+		if action != "" {
+			prefix += "/" + action
+		}
+		for _, op := range d.Operations() {
+			op.Path = prefix + op.Path
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+func pathID(r *http.Request) string {
+	id, _ := r.Context().Value(pathIDKey{}).(string)
+	return id
+}
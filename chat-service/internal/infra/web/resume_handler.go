@@ -0,0 +1,141 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+)
+
+// ResumeHandler serves GET /streams/{resume_token}, the events of an answer
+// after the last one a client received before losing its stream, then the
+// next ones live until the answer is done. The SSE events carry their seq as
+// id, so an EventSource reconnects from where it left with Last-Event-ID.
+type ResumeHandler struct {
+	ResumeStream *chatcompletionstream.ResumeStreamUseCase
+}
+
+func NewResumeHandler(resumeStream *chatcompletionstream.ResumeStreamUseCase) *ResumeHandler {
+	return &ResumeHandler{
+		ResumeStream: resumeStream,
+	}
+}
+
+func (h *ResumeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	user := userID(r)
+	if user == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	lastSeq, ok := intParam(w, r, "last_seq")
+	if !ok {
+		return
+	}
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid Last-Event-ID"})
+			return
+		}
+		lastSeq = n
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "streaming is not supported"})
+		return
+	}
+	sse := !acceptsNDJSON(r)
+	contentType, write := "text/event-stream", writeEvent
+	if !sse {
+		contentType, write = ndjsonContentType, writeLine
+	}
+	// the stream starts with its first event, so a resume token unknown or
+	// expired is answered with its status instead
+	started := false
+	send := func(seq int, event string, v interface{}) error {
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("X-Accel-Buffering", "no")
+			w.WriteHeader(http.StatusOK)
+		}
+		if sse && seq > 0 {
+			if _, err := fmt.Fprintf(w, "id: %d\n", seq); err != nil {
+				return err
+			}
+		}
+		if err := write(w, event, v); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	var last chatcompletionstream.ChatCompletionOutputDTO
+	var usage *usageResponse
+	err := h.ResumeStream.ExecuteStreaming(r.Context(), chatcompletionstream.ResumeStreamInputDTO{
+		ResumeToken: pathID(r),
+		UserID:      user,
+		LastSeq:     lastSeq,
+	}, func(chunk chatcompletionstream.ChatCompletionOutputDTO) error {
+		if chunk.Event == chatcompletionstream.EventUsage {
+			usage = newUsageResponse(chunk)
+		} else {
+			last = chunk
+		}
+		event, data := chunkEvent(chunk)
+		return send(chunk.Seq, event, data)
+	})
+	if err != nil && !started {
+		writeError(w, err)
+		return
+	}
+	if err != nil {
+		if r.Context().Err() == nil {
+			send(0, "error", errorResponse{Error: err.Error()})
+		}
+		return
+	}
+	if failedTurn(last) {
+		send(0, "error", errorResponse{Error: last.Error})
+		return
+	}
+	send(0, "done", completionDone{
+		ChatID:               last.ChatID,
+		TurnID:               last.TurnID,
+		Content:              last.Content,
+		AwaitingConfirmation: last.AwaitingConfirmation,
+		Stopped:              last.Stopped || last.Event == chatcompletionstream.EventGenerationStopped,
+		IncompleteReason:     last.IncompleteReason,
+		FinishReason:         last.FinishReason,
+		RequestID:            last.RequestID,
+		Usage:                usage,
+	})
+}
+
+// failedTurn tells the generation_ended chunk of a turn that failed before
+// any content, a resumed stream ends with its error instead of done.
+func failedTurn(chunk chatcompletionstream.ChatCompletionOutputDTO) bool {
+	return chunk.Event == chatcompletionstream.EventGenerationEnded && chunk.Content == "" && chunk.Error != ""
+}
+
+func (h *ResumeHandler) Operations() []Operation {
+	return []Operation{{
+		Method:      http.MethodGet,
+		Summary:     "Resume the stream of an answer",
+		Description: "Replays the events of the answer of resume_token after last_seq, then streams the next ones live until done. The Last-Event-ID header an EventSource reconnects with takes the place of last_seq. Answers stay resumable for a few minutes after they are done.",
+		Query: []QueryParam{
+			{Name: "last_seq", Description: "The seq of the last event received, 0 to replay the answer from its first.", Integer: true},
+		},
+		Responses: []Response{
+			{Status: http.StatusOK, Description: "The events as Server-Sent Events, or JSON lines of {event, data} when application/x-ndjson is accepted.", Events: completionEvents},
+			{Status: http.StatusNotFound, Description: "The answer isn't buffered, or is no longer.", Body: errorResponse{}},
+			{Status: http.StatusGone, Description: "The events after last_seq are no longer buffered.", Body: errorResponse{}},
+		},
+	}}
+}
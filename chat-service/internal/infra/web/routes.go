@@ -0,0 +1,54 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+// Versions are the API versions served, oldest first. Deprecating one means
+// setting its DeprecatedAt and Sunset. Handlers serve v2, v1 is kept for its
+// clients by the shims of v1Shims.
+func Versions() []APIVersion {
+	return []APIVersion{
+		{Name: "v1", Shims: v1Shims()},
+		{Name: "v2"},
+	}
+}
+
+func NewAPIRouter(clk clock.Clock, metrics *VersionMetrics, drafts *DraftHandler, chats *ChatsHandler, chat *ChatHandler, messages *MessagesHandler, search *SearchHandler, imports *ImportHandler, userChats *UserChatsHandler, completions *CompletionsHandler, regenerate *RegenerateHandler, stop *StopHandler, ws *WebSocketHandler, apiKeys *APIKeysHandler, apiKey *APIKeyHandler, webhooks *WebhooksHandler, webhook *WebhookHandler, deadLetters *DeadLettersHandler, batchCompletions *BatchCompletionsHandler, events *EventsHandler, watch *WatchHandler, resume *ResumeHandler) *Router {
+	router := NewRouter(clk, metrics, Versions()...)
+	router.Handle("/drafts", drafts)
+	router.Handle("/chats", chats)
+	router.Handle("/chats/", ResourceRoutes{Resource: "chats", Actions: map[string]http.Handler{
+		"":            chat,
+		"completions": completions,
+		"events":      events,
+		"messages":    messages,
+		"regenerate":  regenerate,
+		"stop":        stop,
+		"watch":       watch,
+	}})
+	router.Handle("/users/", ResourceRoutes{Resource: "users", Actions: map[string]http.Handler{
+		"chats": userChats,
+	}})
+	router.Handle("/messages", messages)
+	router.Handle("/search", search)
+	router.Handle("/imports", imports)
+	router.Handle("/ws", ws)
+	router.Handle("/streams/", ResourceRoutes{Resource: "streams", Actions: map[string]http.Handler{
+		"": resume,
+	}})
+	router.Handle("/completions", batchCompletions)
+	router.Handle("/api-keys", apiKeys)
+	router.Handle("/api-keys/", ResourceRoutes{Resource: "api-keys", Actions: map[string]http.Handler{
+		"": apiKey,
+	}})
+	router.Handle("/webhooks", webhooks)
+	router.Handle("/webhooks/", ResourceRoutes{Resource: "webhooks", Actions: map[string]http.Handler{
+		"":             webhook,
+		"dead-letters": deadLetters,
+	}})
+	router.HandleDocs()
+	return router
+}
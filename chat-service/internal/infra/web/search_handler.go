@@ -0,0 +1,96 @@
+package web
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/searchmessages"
+)
+
+type highlightResponse struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+type searchHitResponse struct {
+	ChatID        string              `json:"chat_id"`
+	ChatTitle     string              `json:"chat_title"`
+	ChatUpdatedAt time.Time           `json:"chat_updated_at"`
+	MessageID     string              `json:"message_id"`
+	Role          string              `json:"role"`
+	CreatedAt     time.Time           `json:"created_at"`
+	Snippet       string              `json:"snippet"`
+	Highlights    []highlightResponse `json:"highlights"`
+}
+
+type searchResponse struct {
+	Hits []searchHitResponse `json:"hits"`
+}
+
+// SearchHandler serves GET with the q, limit and offset query parameters, the
+// messages of the user matching q.
+type SearchHandler struct {
+	SearchMessages *searchmessages.SearchMessagesUseCase
+}
+
+func NewSearchHandler(searchMessages *searchmessages.SearchMessagesUseCase) *SearchHandler {
+	return &SearchHandler{SearchMessages: searchMessages}
+}
+
+func (h *SearchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	input := searchmessages.SearchMessagesInputDTO{
+		UserID: userID(r),
+		Query:  r.URL.Query().Get("q"),
+	}
+	if input.UserID == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	var ok bool
+	if input.Limit, ok = intParam(w, r, "limit"); !ok {
+		return
+	}
+	if input.Offset, ok = intParam(w, r, "offset"); !ok {
+		return
+	}
+	hits, err := h.SearchMessages.Execute(r.Context(), input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := make([]searchHitResponse, 0, len(hits))
+	for _, hit := range hits {
+		highlights := make([]highlightResponse, 0, len(hit.Highlights))
+		for _, hl := range hit.Highlights {
+			highlights = append(highlights, highlightResponse(hl))
+		}
+		response = append(response, searchHitResponse{
+			ChatID:        hit.ChatID,
+			ChatTitle:     hit.ChatTitle,
+			ChatUpdatedAt: hit.ChatUpdatedAt,
+			MessageID:     hit.MessageID,
+			Role:          hit.Role,
+			CreatedAt:     hit.CreatedAt,
+			Snippet:       hit.Snippet,
+			Highlights:    highlights,
+		})
+	}
+	writeJSON(w, http.StatusOK, searchResponse{Hits: response})
+}
+
+func (h *SearchHandler) Operations() []Operation {
+	return []Operation{{
+		Method:  http.MethodGet,
+		Summary: "Search the messages of the user",
+		Query: []QueryParam{
+			{Name: "q", Description: "The words to search for."},
+			{Name: "limit", Description: "The hits per page.", Integer: true},
+			{Name: "offset", Description: "The hits to skip.", Integer: true},
+		},
+		Responses: []Response{{Status: http.StatusOK, Description: "The matching messages, best first.", Body: searchResponse{}}},
+	}}
+}
@@ -0,0 +1,10 @@
+package web
+
+// v1Shims translate the v2 responses into what the v1 clients expect, by
+// route pattern. v2 has no breaking change yet, a route gets its shim with
+// the change that breaks it. Shimmed routes are buffered, so streamed
+// responses need their v1 form produced by the handler instead, from
+// APIVersionFromContext.
+func v1Shims() map[string]Shim {
+	return map[string]Shim{}
+}
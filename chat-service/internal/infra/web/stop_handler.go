@@ -0,0 +1,50 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+)
+
+// StopHandler serves POST /chats/{id}/stop, it aborts the answer the chat is
+// generating and returns right away. The completion stream of the answer
+// ends with a generation_stopped event and done with the partial content,
+// which is kept in the chat.
+type StopHandler struct {
+	StopGeneration *chatcompletionstream.StopGenerationUseCase
+}
+
+func NewStopHandler(stopGeneration *chatcompletionstream.StopGenerationUseCase) *StopHandler {
+	return &StopHandler{
+		StopGeneration: stopGeneration,
+	}
+}
+
+func (h *StopHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	user := userID(r)
+	if user == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	err := h.StopGeneration.Execute(r.Context(), chatcompletionstream.StopGenerationInputDTO{
+		ChatID: pathID(r),
+		UserID: user,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *StopHandler) Operations() []Operation {
+	return []Operation{{
+		Method:    http.MethodPost,
+		Summary:   "Stop the answer a chat is generating",
+		Responses: []Response{{Status: http.StatusAccepted, Description: "The generation is stopping, its stream ends with the partial answer."}},
+	}}
+}
@@ -0,0 +1,223 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/middleware"
+)
+
+// APIVersion is a version of the API, served under /<Name>/. Handlers always
+// produce the DTOs of the newest version, older ones get them translated by
+// their Shims.
+type APIVersion struct {
+	Name string
+	// DeprecatedAt, when set, adds Deprecation headers to every response.
+	DeprecatedAt time.Time
+	// Sunset is when the version stops being served, it answers 410 afterwards.
+	Sunset time.Time
+	// MigrationURL documents how to move to the next version.
+	MigrationURL string
+	// Shims translate JSON responses by route pattern, see Shim.
+	Shims map[string]Shim
+}
+
+// Shim rewrites in place the decoded JSON body of a response of the newest
+// version into what this version's clients expect.
+type Shim func(body interface{})
+
+type apiVersionKey struct{}
+
+// APIVersionFromContext returns the version the request was made against.
+func APIVersionFromContext(ctx context.Context) string {
+	version, _ := ctx.Value(apiVersionKey{}).(string)
+	return version
+}
+
+// Router mounts every handler under each version.
+type Router struct {
+	mux      *http.ServeMux
+	versions []APIVersion
+	metrics  *VersionMetrics
+	clock    clock.Clock
+	// operations are those of the documented handlers, for HandleDocs
+	operations []Operation
+	middleware middleware.Middleware
+}
+
+// NewRouter takes the versions oldest first.
+func NewRouter(clk clock.Clock, metrics *VersionMetrics, versions ...APIVersion) *Router {
+	return &Router{
+		mux:      http.NewServeMux(),
+		versions: versions,
+		metrics:  metrics,
+		clock:    clk,
+	}
+}
+
+func (rt *Router) Handle(pattern string, handler http.Handler) {
+	if d, ok := handler.(documented); ok {
+		for _, op := range d.Operations() {
+			op.Path = strings.TrimSuffix(pattern, "/") + op.Path
+			rt.operations = append(rt.operations, op)
+		}
+	}
+	for _, version := range rt.versions {
+		rt.mux.Handle("/"+version.Name+pattern, rt.chained("/"+version.Name+pattern, rt.versioned(version, pattern, handler)))
+	}
+}
+
+// Use runs mws on every route, the first one outermost. It must be called
+// before serving.
+func (rt *Router) Use(mws ...middleware.Middleware) {
+	if rt.middleware != nil {
+		mws = append([]middleware.Middleware{rt.middleware}, mws...)
+	}
+	rt.middleware = middleware.Chain(mws...)
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
+
+func (rt *Router) versioned(version APIVersion, pattern string, next http.Handler) http.Handler {
+	shim := version.Shims[pattern]
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			if rt.metrics != nil {
+				rt.metrics.observe(version.Name, recorder.status)
+			}
+		}()
+		if !version.DeprecatedAt.IsZero() {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(version.DeprecatedAt.Unix(), 10))
+			if version.MigrationURL != "" {
+				w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"deprecation\"", version.MigrationURL))
+			}
+		}
+		if !version.Sunset.IsZero() {
+			w.Header().Set("Sunset", version.Sunset.UTC().Format(http.TimeFormat))
+			if !rt.clock.Now().Before(version.Sunset) {
+				writeJSON(recorder, http.StatusGone, errorResponse{Error: fmt.Sprintf("API %s is no longer served", version.Name)})
+				return
+			}
+		}
+		r = r.WithContext(context.WithValue(r.Context(), apiVersionKey{}, version.Name))
+		if shim == nil {
+			next.ServeHTTP(recorder, r)
+			return
+		}
+		buffered := &bufferedWriter{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(buffered, r)
+		body := buffered.body.Bytes()
+		if strings.HasPrefix(buffered.header.Get("Content-Type"), "application/json") && len(body) > 0 {
+			var decoded interface{}
+			if err := json.Unmarshal(body, &decoded); err == nil {
+				shim(decoded)
+				if rewritten, err := json.Marshal(decoded); err == nil {
+					body = append(rewritten, '\n')
+				}
+			}
+		}
+		for k, v := range buffered.header {
+			w.Header()[k] = v
+		}
+		w.Header().Del("Content-Length")
+		recorder.WriteHeader(buffered.status)
+		recorder.Write(body)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.wrote = true
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wrote = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming responses working through the recorder.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket handlers take the connection over.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response can't be hijacked")
+	}
+	r.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// bufferedWriter holds a response until its shim ran, routes with a shim
+// can therefore not stream.
+type bufferedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) Header() http.Header         { return w.header }
+func (w *bufferedWriter) WriteHeader(status int)      { w.status = status }
+func (w *bufferedWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+
+// VersionMetrics counts responses by API version and status class, to see
+// who still calls a deprecated version.
+type VersionMetrics struct {
+	mu     sync.Mutex
+	counts map[string]map[string]int64
+}
+
+func NewVersionMetrics() *VersionMetrics {
+	return &VersionMetrics{
+		counts: make(map[string]map[string]int64),
+	}
+}
+
+func (m *VersionMetrics) observe(version string, status int) {
+	class := strconv.Itoa(status/100) + "xx"
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counts[version] == nil {
+		m.counts[version] = make(map[string]int64)
+	}
+	m.counts[version][class]++
+}
+
+// Snapshot returns the counts by version then status class.
+func (m *VersionMetrics) Snapshot() map[string]map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make(map[string]map[string]int64, len(m.counts))
+	for version, classes := range m.counts {
+		snapshot[version] = make(map[string]int64, len(classes))
+		for class, n := range classes {
+			snapshot[version][class] = n
+		}
+	}
+	return snapshot
+}
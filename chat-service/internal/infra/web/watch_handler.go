@@ -0,0 +1,71 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+)
+
+// WatchHandler serves GET /chats/{id}/watch, the events of the answers of a
+// chat whatever the request generating them, from the one in progress, for
+// as long as the client stays. Any number of clients can watch a chat.
+type WatchHandler struct {
+	WatchChat *chatcompletionstream.WatchChatUseCase
+}
+
+func NewWatchHandler(watchChat *chatcompletionstream.WatchChatUseCase) *WatchHandler {
+	return &WatchHandler{
+		WatchChat: watchChat,
+	}
+}
+
+func (h *WatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	user := userID(r)
+	if user == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "streaming is not supported"})
+		return
+	}
+	contentType, write := "text/event-stream", writeEvent
+	if acceptsNDJSON(r) {
+		contentType, write = ndjsonContentType, writeLine
+	}
+	// the stream starts right away, the chat may have no answer in progress
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	err := h.WatchChat.Execute(r.Context(), chatcompletionstream.WatchChatInputDTO{
+		ChatID: pathID(r),
+		UserID: user,
+	}, func(chunk chatcompletionstream.ChatCompletionOutputDTO) error {
+		event, data := chunkEvent(chunk)
+		if err := write(w, event, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+	if err != nil && r.Context().Err() == nil {
+		write(w, "error", errorResponse{Error: err.Error()})
+		flusher.Flush()
+	}
+}
+
+func (h *WatchHandler) Operations() []Operation {
+	return []Operation{{
+		Method:      http.MethodGet,
+		Summary:     "Watch the answers of a chat",
+		Description: "Streams the events of the answer in progress, from the oldest still buffered, then of every answer started after it, whichever request generates them, until the client disconnects. The events are those of a completion stream but done, turn_id and seq telling the answers and their events apart.",
+		Responses:   []Response{{Status: http.StatusOK, Description: "The events as Server-Sent Events, or JSON lines of {event, data} when application/x-ndjson is accepted.", Events: completionEvents}},
+	}}
+}
@@ -0,0 +1,213 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/webhook"
+)
+
+type registerWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+type webhookResponse struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Secret is only returned when the webhook is registered.
+	Secret string `json:"secret,omitempty"`
+}
+
+type webhookListResponse struct {
+	Webhooks []webhookResponse `json:"webhooks"`
+}
+
+type deadLetterResponse struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error"`
+	CreatedAt time.Time       `json:"created_at"`
+	FailedAt  time.Time       `json:"failed_at"`
+}
+
+type deadLetterListResponse struct {
+	DeadLetters []deadLetterResponse `json:"dead_letters"`
+}
+
+func newWebhookResponse(output *webhook.WebhookOutputDTO) webhookResponse {
+	return webhookResponse{
+		ID:        output.ID,
+		URL:       output.URL,
+		Events:    output.Events,
+		CreatedBy: output.CreatedBy,
+		CreatedAt: output.CreatedAt,
+		Secret:    output.Secret,
+	}
+}
+
+// WebhooksHandler serves /webhooks for the webhooks of the tenant of the
+// request: GET lists them and POST registers one.
+type WebhooksHandler struct {
+	RegisterWebhook *webhook.RegisterWebhookUseCase
+	ListWebhooks    *webhook.ListWebhooksUseCase
+}
+
+func NewWebhooksHandler(registerWebhook *webhook.RegisterWebhookUseCase, listWebhooks *webhook.ListWebhooksUseCase) *WebhooksHandler {
+	return &WebhooksHandler{
+		RegisterWebhook: registerWebhook,
+		ListWebhooks:    listWebhooks,
+	}
+}
+
+func (h *WebhooksHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	org := orgID(r)
+	if org == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "missing tenant"})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		outputs, err := h.ListWebhooks.Execute(r.Context(), org)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		resp := webhookListResponse{Webhooks: make([]webhookResponse, 0, len(outputs))}
+		for _, output := range outputs {
+			resp.Webhooks = append(resp.Webhooks, newWebhookResponse(output))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	case http.MethodPost:
+		var body registerWebhookRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid body"})
+			return
+		}
+		output, err := h.RegisterWebhook.Execute(r.Context(), webhook.RegisterWebhookInputDTO{
+			OrgID:  org,
+			UserID: userID(r),
+			URL:    body.URL,
+			Events: body.Events,
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, newWebhookResponse(output))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *WebhooksHandler) Operations() []Operation {
+	return []Operation{
+		{
+			Method:    http.MethodGet,
+			Summary:   "List the webhooks of the tenant",
+			Responses: []Response{{Status: http.StatusOK, Description: "The webhooks, oldest first.", Body: webhookListResponse{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Summary:     "Register a webhook",
+			Description: "The events subscribed to, among chat.created, message.completed and generation.failed, are posted to the URL as JSON, signed in the Webhook-Signature header: t=<unix seconds>,v1=<hex HMAC-SHA256 with the secret of \"<unix seconds>.<body>\">. Deliveries the receiver doesn't acknowledge with a 2xx are retried with backoff, then kept as dead letters.",
+			Body:        registerWebhookRequest{},
+			Responses:   []Response{{Status: http.StatusCreated, Description: "The webhook, with its secret the only time it is returned.", Body: webhookResponse{}}},
+		},
+	}
+}
+
+// WebhookHandler serves DELETE /webhooks/{id}, deleting a webhook of the
+// tenant with its deliveries.
+type WebhookHandler struct {
+	DeleteWebhook *webhook.DeleteWebhookUseCase
+}
+
+func NewWebhookHandler(deleteWebhook *webhook.DeleteWebhookUseCase) *WebhookHandler {
+	return &WebhookHandler{DeleteWebhook: deleteWebhook}
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	input := webhook.WebhookInputDTO{
+		WebhookID: pathID(r),
+		OrgID:     orgID(r),
+	}
+	if input.OrgID == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "missing tenant"})
+		return
+	}
+	if err := h.DeleteWebhook.Execute(r.Context(), input); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) Operations() []Operation {
+	return []Operation{{
+		Method:    http.MethodDelete,
+		Summary:   "Delete a webhook",
+		Responses: []Response{{Status: http.StatusNoContent, Description: "Deleted, its pending deliveries are dropped."}},
+	}}
+}
+
+// DeadLettersHandler serves GET /webhooks/{id}/dead-letters, the deliveries
+// of a webhook that ran out of attempts.
+type DeadLettersHandler struct {
+	ListDeadLetters *webhook.ListDeadLettersUseCase
+}
+
+func NewDeadLettersHandler(listDeadLetters *webhook.ListDeadLettersUseCase) *DeadLettersHandler {
+	return &DeadLettersHandler{ListDeadLetters: listDeadLetters}
+}
+
+func (h *DeadLettersHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	input := webhook.WebhookInputDTO{
+		WebhookID: pathID(r),
+		OrgID:     orgID(r),
+	}
+	if input.OrgID == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "missing tenant"})
+		return
+	}
+	outputs, err := h.ListDeadLetters.Execute(r.Context(), input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp := deadLetterListResponse{DeadLetters: make([]deadLetterResponse, 0, len(outputs))}
+	for _, output := range outputs {
+		resp.DeadLetters = append(resp.DeadLetters, deadLetterResponse{
+			ID:        output.ID,
+			Event:     output.Event,
+			Payload:   output.Payload,
+			Attempts:  output.Attempts,
+			LastError: output.LastError,
+			CreatedAt: output.CreatedAt,
+			FailedAt:  output.FailedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *DeadLettersHandler) Operations() []Operation {
+	return []Operation{{
+		Method:    http.MethodGet,
+		Summary:   "List the dead letters of a webhook",
+		Responses: []Response{{Status: http.StatusOK, Description: "The deliveries that ran out of attempts, the latest first.", Body: deadLetterListResponse{}}},
+	}}
+}
@@ -0,0 +1,403 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/ratelimit"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/requestid"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/websocket"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+)
+
+const (
+	wsPingInterval = 30 * time.Second
+	// wsPongWait is how long the connection may stay silent, pongs included,
+	// before it is dropped.
+	wsPongWait     = 2 * wsPingInterval
+	wsWriteTimeout = 10 * time.Second
+	// wsMaxPending bounds the messages waiting for the turn in progress.
+	wsMaxPending = 4
+	// wsMaxWatches bounds the chats a connection watches at once.
+	wsMaxWatches = 8
+)
+
+// wsRequest is a frame of the client: a message to a chat, an empty ChatID
+// starting one, the resume of an interrupted answer from the chunk after
+// LastSeq, with the resume token of its frames, or the watch or unwatch of
+// the answers of a chat. RequestID, when valid, is the request ID of the
+// answer to a message instead of a new one.
+type wsRequest struct {
+	Type        string `json:"type"`
+	ChatID      string `json:"chat_id,omitempty"`
+	Message     string `json:"message,omitempty"`
+	ResumeToken string `json:"resume_token,omitempty"`
+	LastSeq     int    `json:"last_seq,omitempty"`
+	RequestID   string `json:"request_id,omitempty"`
+}
+
+// wsFrame is a frame of the server, of type started, delta, done or error,
+// or the usecase event of the other chunks (generation_thinking,
+// system_notice, warning, generation_stopped, generation_incomplete, usage,
+// generation_ended, heartbeat).
+type wsFrame struct {
+	Type                 string `json:"type"`
+	ChatID               string `json:"chat_id,omitempty"`
+	TurnID               string `json:"turn_id,omitempty"`
+	Seq                  int    `json:"seq,omitempty"`
+	Delta                string `json:"delta,omitempty"`
+	Content              string `json:"content,omitempty"`
+	ResumeToken          string `json:"resume_token,omitempty"`
+	QueuePosition        int    `json:"queue_position,omitempty"`
+	AwaitingConfirmation bool   `json:"awaiting_confirmation,omitempty"`
+	Stopped              bool   `json:"stopped,omitempty"`
+	IncompleteReason     string `json:"incomplete_reason,omitempty"`
+	// FinishReason is set on generation_ended and done frames.
+	FinishReason string `json:"finish_reason,omitempty"`
+	RequestID    string `json:"request_id,omitempty"`
+	Error        string `json:"error,omitempty"`
+	// Usage is set on usage and done frames.
+	Usage *usageResponse `json:"usage,omitempty"`
+	// RetryAfter is in seconds, on the error of a rate limited message.
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// WebSocketHandler serves completions over a WebSocket: the client sends
+// message and resume frames, the answers are streamed back as frames one
+// request after the other. The server pings every 30s and drops connections
+// silent for twice as long.
+type WebSocketHandler struct {
+	ChatCompletion *chatcompletionstream.ChatCompletionUseCase
+	// ResumeStream, when set, lets clients that reconnected resume an
+	// answer with its resume token.
+	ResumeStream *chatcompletionstream.ResumeStreamUseCase
+	// WatchChat, when set, lets clients watch the answers of a chat.
+	WatchChat *chatcompletionstream.WatchChatUseCase
+	// Config is the completion config of the chats the requests start.
+	Config chatcompletionstream.ChatCompletionConfigInputDTO
+}
+
+func NewWebSocketHandler(chatCompletion *chatcompletionstream.ChatCompletionUseCase, resumeStream *chatcompletionstream.ResumeStreamUseCase, config chatcompletionstream.ChatCompletionConfigInputDTO) *WebSocketHandler {
+	return &WebSocketHandler{
+		ChatCompletion: chatCompletion,
+		ResumeStream:   resumeStream,
+		Config:         config,
+	}
+}
+
+func (h *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user := userID(r)
+	if user == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing user"})
+		return
+	}
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	// the request context ends with the hijacked connection's handler
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &wsSession{
+		handler: h,
+		conn:    conn,
+		userID:  user,
+		orgID:   orgID(r),
+		tier:    tier(r),
+		watches: map[string]*wsWatch{},
+	}
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.OnPong = func() {
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	}
+	requests := make(chan wsRequest, wsMaxPending)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for req := range requests {
+			session.serve(ctx, req)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		session.keepAlive(ctx)
+	}()
+
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			session.write(wsFrame{Type: "error", Error: "invalid frame"})
+			continue
+		}
+		// watches run beside the turns of the connection
+		switch req.Type {
+		case "watch":
+			session.watch(ctx, req)
+			continue
+		case "unwatch":
+			session.unwatch(req.ChatID)
+			continue
+		}
+		select {
+		case requests <- req:
+		default:
+			session.write(wsFrame{Type: "error", ChatID: req.ChatID, Error: "too many pending messages"})
+		}
+	}
+	cancel()
+	close(requests)
+	wg.Wait()
+	session.watching.Wait()
+	conn.Close(websocket.CloseNormal, "")
+}
+
+func (h *WebSocketHandler) Operations() []Operation {
+	return []Operation{{
+		Method:      http.MethodGet,
+		Summary:     "Open a WebSocket for completions",
+		Description: "The client sends JSON text frames of type message ({chat_id, message}), resume ({resume_token, last_seq}), or watch and unwatch ({chat_id}) to follow the answers of a chat whichever request generates them. The server answers with frames of type started, delta, done or error, or the usecase event of the other chunks, the data of the SSE events of a completion. The frames of a watched answer don't end with done.",
+		Responses:   []Response{{Status: http.StatusSwitchingProtocols, Description: "The connection is upgraded to a WebSocket."}},
+	}}
+}
+
+type wsSession struct {
+	handler  *WebSocketHandler
+	conn     *websocket.Conn
+	userID   string
+	orgID    string
+	tier     string
+	mu       sync.Mutex
+	watches  map[string]*wsWatch
+	watching sync.WaitGroup
+}
+
+// wsWatch is the watch of a chat by a connection.
+type wsWatch struct {
+	cancel context.CancelFunc
+}
+
+func (s *wsSession) write(frame wsFrame) error {
+	s.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return s.conn.WriteJSON(frame)
+}
+
+func (s *wsSession) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.conn.Ping() != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *wsSession) serve(ctx context.Context, req wsRequest) {
+	switch req.Type {
+	case "message":
+		s.complete(ctx, req)
+	case "resume":
+		s.resume(ctx, req)
+	default:
+		s.write(wsFrame{Type: "error", Error: "unknown frame type " + req.Type})
+	}
+}
+
+func (s *wsSession) complete(ctx context.Context, req wsRequest) {
+	if strings.TrimSpace(req.Message) == "" {
+		s.write(wsFrame{Type: "error", ChatID: req.ChatID, Error: "message is required"})
+		return
+	}
+	input := chatcompletionstream.ChatCompletionInputDTO{
+		ChatID:      req.ChatID,
+		UserID:      s.userID,
+		OrgID:       s.orgID,
+		Tier:        s.tier,
+		UserMessage: req.Message,
+		Config:      s.handler.Config,
+	}
+	// the turns of a connection are requests of their own
+	id := requestid.Incoming(req.RequestID)
+	ctx = requestid.NewContext(ctx, id)
+	var content string
+	output, err := s.handler.ChatCompletion.ExecuteStreaming(ctx, input, func(chunk chatcompletionstream.ChatCompletionOutputDTO) error {
+		return s.write(s.chunkFrame(chunk, &content))
+	})
+	if output == nil {
+		frame := wsFrame{Type: "error", ChatID: req.ChatID, RequestID: id, Error: err.Error()}
+		var limited *ratelimit.Error
+		if errors.As(err, &limited) {
+			frame.RetryAfter = int(math.Ceil(limited.RetryAfter.Seconds()))
+		}
+		s.write(frame)
+		return
+	}
+	if err != nil {
+		return
+	}
+	s.write(wsFrame{
+		Type:                 "done",
+		ChatID:               output.ChatID,
+		TurnID:               output.TurnID,
+		Content:              output.Content,
+		AwaitingConfirmation: output.AwaitingConfirmation,
+		Stopped:              output.Stopped,
+		IncompleteReason:     output.IncompleteReason,
+		FinishReason:         output.FinishReason,
+		RequestID:            output.RequestID,
+		Usage:                newUsageResponse(*output),
+	})
+}
+
+func (s *wsSession) resume(ctx context.Context, req wsRequest) {
+	if s.handler.ResumeStream == nil {
+		s.write(wsFrame{Type: "error", Error: "resuming is not enabled"})
+		return
+	}
+	var content string
+	var last chatcompletionstream.ChatCompletionOutputDTO
+	var usage *usageResponse
+	err := s.handler.ResumeStream.ExecuteStreaming(ctx, chatcompletionstream.ResumeStreamInputDTO{
+		ResumeToken: req.ResumeToken,
+		UserID:      s.userID,
+		LastSeq:     req.LastSeq,
+	}, func(chunk chatcompletionstream.ChatCompletionOutputDTO) error {
+		if chunk.Event == chatcompletionstream.EventUsage {
+			usage = newUsageResponse(chunk)
+		} else {
+			last = chunk
+		}
+		return s.write(s.chunkFrame(chunk, &content))
+	})
+	if err != nil {
+		s.write(wsFrame{Type: "error", ResumeToken: req.ResumeToken, Error: err.Error()})
+		return
+	}
+	if failedTurn(last) {
+		s.write(wsFrame{Type: "error", ChatID: last.ChatID, TurnID: last.TurnID, ResumeToken: req.ResumeToken, Error: last.Error})
+		return
+	}
+	s.write(wsFrame{
+		Type:                 "done",
+		ChatID:               last.ChatID,
+		TurnID:               last.TurnID,
+		Content:              last.Content,
+		ResumeToken:          req.ResumeToken,
+		AwaitingConfirmation: last.AwaitingConfirmation,
+		Stopped:              last.Stopped,
+		IncompleteReason:     last.IncompleteReason,
+		FinishReason:         last.FinishReason,
+		RequestID:            last.RequestID,
+		Usage:                usage,
+	})
+}
+
+// watch streams the answers of the chat of req on the connection until it is
+// unwatched or the connection closes.
+func (s *wsSession) watch(ctx context.Context, req wsRequest) {
+	if s.handler.WatchChat == nil {
+		s.write(wsFrame{Type: "error", ChatID: req.ChatID, Error: "watching chats is not enabled"})
+		return
+	}
+	if req.ChatID == "" {
+		s.write(wsFrame{Type: "error", Error: "chat_id is required"})
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.watches[req.ChatID]; ok {
+		return
+	}
+	if len(s.watches) == wsMaxWatches {
+		s.write(wsFrame{Type: "error", ChatID: req.ChatID, Error: "too many watched chats"})
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	w := &wsWatch{cancel: cancel}
+	s.watches[req.ChatID] = w
+	s.watching.Add(1)
+	go func() {
+		defer s.watching.Done()
+		defer s.endWatch(req.ChatID, w)
+		// every answer starts over the content its deltas build on
+		var content, turnID string
+		err := s.handler.WatchChat.Execute(ctx, chatcompletionstream.WatchChatInputDTO{
+			ChatID: req.ChatID,
+			UserID: s.userID,
+		}, func(chunk chatcompletionstream.ChatCompletionOutputDTO) error {
+			if chunk.TurnID != turnID {
+				content, turnID = "", chunk.TurnID
+			}
+			return s.write(s.chunkFrame(chunk, &content))
+		})
+		if err != nil && ctx.Err() == nil {
+			s.write(wsFrame{Type: "error", ChatID: req.ChatID, Error: err.Error()})
+		}
+	}()
+}
+
+func (s *wsSession) unwatch(chatID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w, ok := s.watches[chatID]; ok {
+		w.cancel()
+		delete(s.watches, chatID)
+	}
+}
+
+// endWatch forgets w once it ended, unless the chat is watched anew.
+func (s *wsSession) endWatch(chatID string, w *wsWatch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.cancel()
+	if s.watches[chatID] == w {
+		delete(s.watches, chatID)
+	}
+}
+
+// chunkFrame builds the frame of chunk, content is the content of the
+// previous content chunk of the answer. The first delta of a resumed answer
+// carries the whole content so far.
+func (s *wsSession) chunkFrame(chunk chatcompletionstream.ChatCompletionOutputDTO, content *string) wsFrame {
+	frame := wsFrame{
+		Type:                 chunk.Event,
+		ChatID:               chunk.ChatID,
+		TurnID:               chunk.TurnID,
+		Seq:                  chunk.Seq,
+		ResumeToken:          chunk.ResumeToken,
+		QueuePosition:        chunk.QueuePosition,
+		AwaitingConfirmation: chunk.AwaitingConfirmation,
+		IncompleteReason:     chunk.IncompleteReason,
+		FinishReason:         chunk.FinishReason,
+		Error:                chunk.Error,
+		RequestID:            chunk.RequestID,
+	}
+	switch chunk.Event {
+	case chatcompletionstream.EventGenerationStarted:
+		frame.Type = "started"
+	case chatcompletionstream.EventContent:
+		frame.Type = "delta"
+		frame.Delta = strings.TrimPrefix(chunk.Content, *content)
+		*content = chunk.Content
+	case chatcompletionstream.EventUsage:
+		frame.Usage = newUsageResponse(chunk)
+	default:
+		frame.Content = chunk.Content
+	}
+	return frame
+}
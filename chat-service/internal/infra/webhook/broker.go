@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+// Broker is the EventBroker posting the outbox entries to the webhook of the
+// consumers downstream, signed like the deliveries of the tenant webhooks.
+// The ID header is the one of the entry, for the receiver to deduplicate on.
+type Broker struct {
+	webhook *entity.Webhook
+	sender  *HTTPSender
+}
+
+func NewBroker(url, secret string, sender *HTTPSender) *Broker {
+	return &Broker{
+		webhook: &entity.Webhook{ID: "events", URL: url, Secret: secret},
+		sender:  sender,
+	}
+}
+
+type eventPayload struct {
+	ID         string          `json:"id"`
+	Event      string          `json:"event"`
+	Namespace  string          `json:"namespace"`
+	ChatID     string          `json:"chat_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func (b *Broker) Publish(ctx context.Context, entry *entity.OutboxEntry) error {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(eventPayload{
+		ID:         entry.ID,
+		Event:      entry.EventName,
+		Namespace:  ns,
+		ChatID:     entry.ChatID,
+		OccurredAt: entry.OccurredAt.UTC(),
+		Data:       entry.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding %s event: %s", entry.EventName, err.Error())
+	}
+	return b.sender.Send(ctx, b.webhook, &entity.WebhookDelivery{
+		ID:        entry.ID,
+		WebhookID: b.webhook.ID,
+		Event:     entry.EventName,
+		Payload:   payload,
+		CreatedAt: entry.OccurredAt,
+	})
+}
@@ -0,0 +1,74 @@
+// Package webhook posts webhook deliveries over HTTP, signed so receivers can
+// tell they come from the service.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+// Headers of a delivery. Signature is "t=<unix seconds>,v1=<hex>", the hex
+// being the HMAC-SHA256 with the secret of the webhook of
+// "<unix seconds>.<body>", so a replayed body can be told by its time.
+const (
+	IDHeader        = "Webhook-Id"
+	EventHeader     = "Webhook-Event"
+	SignatureHeader = "Webhook-Signature"
+)
+
+const defaultTimeout = 10 * time.Second
+
+type HTTPSender struct {
+	client *http.Client
+	clock  clock.Clock
+}
+
+// NewHTTPSender posts with client, one timing out after 10s when nil.
+func NewHTTPSender(client *http.Client, clk clock.Clock) *HTTPSender {
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	return &HTTPSender{client: client, clock: clk}
+}
+
+func (s *HTTPSender) Send(ctx context.Context, webhook *entity.Webhook, delivery *entity.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return fmt.Errorf("error building request: %s", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IDHeader, delivery.ID)
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, delivery.Payload, s.clock.Now()))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the signature header of body posted at.
+func Sign(secret string, body []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,267 @@
+// Package websocket implements the server side of RFC 6455, the part the
+// chat service needs: text messages, ping/pong and the closing handshake.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// Close codes of the closing handshake.
+const (
+	CloseNormal        = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	CloseTooBig        = 1009
+)
+
+// MaxMessageSize bounds the messages ReadMessage accepts.
+const MaxMessageSize = 1 << 20
+
+// ErrClosed is returned by ReadMessage once the peer closed the connection.
+var ErrClosed = errors.New("websocket: connection closed")
+
+// Conn is a server connection. Reads must come from one goroutine, writes
+// may come from any.
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	mu   sync.Mutex
+	// OnPong is called by ReadMessage for every pong, e.g. to extend the
+	// read deadline.
+	OnPong func()
+}
+
+// Upgrade answers the opening handshake of r and takes the connection over,
+// it writes a bad request itself when r is not one.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, errors.New("websocket: not an upgrade request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusBadRequest)
+		return nil, errors.New("websocket: unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("websocket: missing key")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket is not supported", http.StatusInternalServerError)
+		return nil, errors.New("websocket: response can't be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: %s", err.Error())
+	}
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("websocket: %s", err.Error())
+	}
+	return &Conn{conn: conn, br: rw.Reader}, nil
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the payload of the next text or binary message. It
+// answers pings and the closing handshake on its way, returning ErrClosed
+// for the latter.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var message []byte
+	started := false
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			if c.OnPong != nil {
+				c.OnPong()
+			}
+			continue
+		case opClose:
+			code := CloseNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.Close(code, "")
+			return nil, ErrClosed
+		case opText, opBinary:
+			if started {
+				return nil, c.fail(CloseProtocolError, "websocket: new message inside a fragmented one")
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, c.fail(CloseProtocolError, "websocket: continuation without a message")
+			}
+		default:
+			return nil, c.fail(CloseProtocolError, fmt.Sprintf("websocket: unknown opcode %d", op))
+		}
+		if len(message)+len(payload) > MaxMessageSize {
+			return nil, c.fail(CloseTooBig, "websocket: message too big")
+		}
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	op = head[0] & 0x0f
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "websocket: reserved bits set")
+	}
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "websocket: unmasked client frame")
+	}
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= opClose && (length > 125 || !fin) {
+		return false, 0, nil, c.fail(CloseProtocolError, "websocket: invalid control frame")
+	}
+	if length > MaxMessageSize {
+		return false, 0, nil, c.fail(CloseTooBig, "websocket: frame too big")
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// fail closes the connection with code and returns the error of reason.
+func (c *Conn) fail(code int, reason string) error {
+	c.Close(code, "")
+	return errors.New(reason)
+}
+
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	header := []byte{0x80 | op}
+	switch n := len(payload); {
+	case n <= 125:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = append(header, 126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.conn.Write(header); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(payload)
+	return err
+}
+
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+func (c *Conn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteText(data)
+}
+
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// SetReadDeadline makes ReadMessage fail once t is past.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline makes the writes fail once t is past.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// Close sends a close frame with code and reason and closes the connection,
+// without waiting for the peer's.
+func (c *Conn) Close(code int, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	payload = append(payload, reason...)
+	c.writeFrame(opClose, payload)
+	return c.conn.Close()
+}
@@ -0,0 +1,76 @@
+package annotatemessage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type AnnotateMessageInputDTO struct {
+	ChatID    string
+	MessageID string
+	UserID    string
+	Kind      string
+	Start     int
+	End       int
+	Text      string
+}
+
+type AnnotationOutputDTO struct {
+	ID        string
+	MessageID string
+	Kind      string
+	Start     int
+	End       int
+	Quote     string
+	Text      string
+}
+
+type AnnotateMessageUseCase struct {
+	ChatGateway       gateway.ChatGateway
+	AnnotationGateway gateway.AnnotationGateway
+	Clock             clock.Clock
+}
+
+func NewAnnotateMessageUseCase(chatGateway gateway.ChatGateway, annotationGateway gateway.AnnotationGateway, clk clock.Clock) *AnnotateMessageUseCase {
+	return &AnnotateMessageUseCase{
+		ChatGateway:       chatGateway,
+		AnnotationGateway: annotationGateway,
+		Clock:             clk,
+	}
+}
+
+func (uc *AnnotateMessageUseCase) Execute(ctx context.Context, input AnnotateMessageInputDTO) (*AnnotationOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	message, err := chat.FindMessage(input.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	annotation, err := entity.NewAnnotation(message, input.UserID, input.Kind, input.Start, input.End, input.Text, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating annotation: %w", err)
+	}
+	err = uc.AnnotationGateway.CreateAnnotation(ctx, annotation)
+	if err != nil {
+		return nil, fmt.Errorf("error persisting annotation: %w", err)
+	}
+	message.AddAnnotation(annotation)
+	return &AnnotationOutputDTO{
+		ID:        annotation.ID,
+		MessageID: annotation.MessageID,
+		Kind:      annotation.Kind,
+		Start:     annotation.Start,
+		End:       annotation.End,
+		Quote:     annotation.Quote(message),
+		Text:      annotation.Text,
+	}, nil
+}
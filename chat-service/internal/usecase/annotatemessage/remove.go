@@ -0,0 +1,50 @@
+package annotatemessage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type RemoveAnnotationInputDTO struct {
+	ChatID       string
+	MessageID    string
+	AnnotationID string
+	UserID       string
+}
+
+type RemoveAnnotationUseCase struct {
+	ChatGateway       gateway.ChatGateway
+	AnnotationGateway gateway.AnnotationGateway
+}
+
+func NewRemoveAnnotationUseCase(chatGateway gateway.ChatGateway, annotationGateway gateway.AnnotationGateway) *RemoveAnnotationUseCase {
+	return &RemoveAnnotationUseCase{
+		ChatGateway:       chatGateway,
+		AnnotationGateway: annotationGateway,
+	}
+}
+
+func (uc *RemoveAnnotationUseCase) Execute(ctx context.Context, input RemoveAnnotationInputDTO) error {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return entity.ErrChatForbidden
+	}
+	message, err := chat.FindMessage(input.MessageID)
+	if err != nil {
+		return err
+	}
+	if err := message.RemoveAnnotation(input.AnnotationID); err != nil {
+		return err
+	}
+	err = uc.AnnotationGateway.DeleteAnnotation(ctx, input.AnnotationID)
+	if err != nil {
+		return fmt.Errorf("error deleting annotation: %w", err)
+	}
+	return nil
+}
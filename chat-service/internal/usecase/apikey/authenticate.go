@@ -0,0 +1,48 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+// ErrInvalidAPIKey is returned for unknown and revoked keys alike, so a
+// caller can't tell which keys existed.
+var ErrInvalidAPIKey = errors.New("api key is invalid")
+
+type AuthenticateAPIKeyUseCase struct {
+	APIKeyGateway gateway.APIKeyGateway
+}
+
+func NewAuthenticateAPIKeyUseCase(apiKeyGateway gateway.APIKeyGateway) *AuthenticateAPIKeyUseCase {
+	return &AuthenticateAPIKeyUseCase{
+		APIKeyGateway: apiKeyGateway,
+	}
+}
+
+// Execute resolves key to the user and tenant its calls are made as.
+func (uc *AuthenticateAPIKeyUseCase) Execute(ctx context.Context, key string) (*PrincipalOutputDTO, error) {
+	if !strings.HasPrefix(key, entity.APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	apiKey, err := uc.APIKeyGateway.FindAPIKeyByHash(ctx, entity.HashAPIKey(key))
+	if errors.Is(err, gateway.ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching api key: %w", err)
+	}
+	if apiKey.IsRevoked() {
+		return nil, ErrInvalidAPIKey
+	}
+	return &PrincipalOutputDTO{
+		KeyID:  apiKey.ID,
+		UserID: apiKey.UserID,
+		OrgID:  apiKey.OrgID,
+		Scopes: apiKey.Scopes,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+type IssueAPIKeyInputDTO struct {
+	UserID string
+	OrgID  string
+	Name   string
+	Scopes []string
+}
+
+type RevokeAPIKeyInputDTO struct {
+	KeyID  string
+	UserID string
+}
+
+type APIKeyOutputDTO struct {
+	ID        string
+	Name      string
+	OrgID     string
+	Hint      string
+	Scopes    []string
+	CreatedAt time.Time
+	RevokedAt time.Time
+	// Key is only set when the key is issued, it can't be read back.
+	Key string
+}
+
+// PrincipalOutputDTO is who the calls of a key are made as.
+type PrincipalOutputDTO struct {
+	KeyID  string
+	UserID string
+	OrgID  string
+	Scopes []string
+}
+
+func newOutput(k *entity.APIKey) *APIKeyOutputDTO {
+	return &APIKeyOutputDTO{
+		ID:        k.ID,
+		Name:      k.Name,
+		OrgID:     k.OrgID,
+		Hint:      k.Hint,
+		Scopes:    k.Scopes,
+		CreatedAt: k.CreatedAt,
+		RevokedAt: k.RevokedAt,
+	}
+}
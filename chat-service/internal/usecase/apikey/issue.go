@@ -0,0 +1,36 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type IssueAPIKeyUseCase struct {
+	APIKeyGateway gateway.APIKeyGateway
+	Clock         clock.Clock
+}
+
+func NewIssueAPIKeyUseCase(apiKeyGateway gateway.APIKeyGateway, clk clock.Clock) *IssueAPIKeyUseCase {
+	return &IssueAPIKeyUseCase{
+		APIKeyGateway: apiKeyGateway,
+		Clock:         clk,
+	}
+}
+
+// Execute issues a key, the output is the only time the key is returned.
+func (uc *IssueAPIKeyUseCase) Execute(ctx context.Context, input IssueAPIKeyInputDTO) (*APIKeyOutputDTO, error) {
+	apiKey, key, err := entity.NewAPIKey(input.UserID, input.OrgID, input.Name, input.Scopes, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating api key: %w", err)
+	}
+	if err := uc.APIKeyGateway.CreateAPIKey(ctx, apiKey); err != nil {
+		return nil, fmt.Errorf("error persisting api key: %w", err)
+	}
+	output := newOutput(apiKey)
+	output.Key = key
+	return output, nil
+}
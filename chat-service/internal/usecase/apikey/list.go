@@ -0,0 +1,31 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type ListAPIKeysUseCase struct {
+	APIKeyGateway gateway.APIKeyGateway
+}
+
+func NewListAPIKeysUseCase(apiKeyGateway gateway.APIKeyGateway) *ListAPIKeysUseCase {
+	return &ListAPIKeysUseCase{
+		APIKeyGateway: apiKeyGateway,
+	}
+}
+
+// Execute returns the keys of the user newest first, revoked ones included.
+func (uc *ListAPIKeysUseCase) Execute(ctx context.Context, userID string) ([]*APIKeyOutputDTO, error) {
+	keys, err := uc.APIKeyGateway.ListAPIKeysByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing api keys: %w", err)
+	}
+	outputs := make([]*APIKeyOutputDTO, 0, len(keys))
+	for _, key := range keys {
+		outputs = append(outputs, newOutput(key))
+	}
+	return outputs, nil
+}
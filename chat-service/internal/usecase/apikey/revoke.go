@@ -0,0 +1,40 @@
+package apikey
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type RevokeAPIKeyUseCase struct {
+	APIKeyGateway gateway.APIKeyGateway
+	Clock         clock.Clock
+}
+
+func NewRevokeAPIKeyUseCase(apiKeyGateway gateway.APIKeyGateway, clk clock.Clock) *RevokeAPIKeyUseCase {
+	return &RevokeAPIKeyUseCase{
+		APIKeyGateway: apiKeyGateway,
+		Clock:         clk,
+	}
+}
+
+// Execute revokes the key for good, the calls made with it fail from then on.
+func (uc *RevokeAPIKeyUseCase) Execute(ctx context.Context, input RevokeAPIKeyInputDTO) (*APIKeyOutputDTO, error) {
+	key, err := uc.APIKeyGateway.FindAPIKeyByID(ctx, input.KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching api key: %w", err)
+	}
+	if key.UserID != input.UserID {
+		return nil, entity.ErrAPIKeyForbidden
+	}
+	if err := key.Revoke(uc.Clock.Now()); err != nil {
+		return nil, fmt.Errorf("error revoking api key: %w", err)
+	}
+	if err := uc.APIKeyGateway.RevokeAPIKey(ctx, key); err != nil {
+		return nil, fmt.Errorf("error persisting api key revocation: %w", err)
+	}
+	return newOutput(key), nil
+}
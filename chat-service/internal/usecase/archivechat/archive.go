@@ -0,0 +1,53 @@
+package archivechat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type ArchiveChatInputDTO struct {
+	ChatID string
+	UserID string
+}
+
+type ArchiveChatOutputDTO struct {
+	ChatID string
+	Status string
+}
+
+type ArchiveChatUseCase struct {
+	ChatGateway gateway.ChatGateway
+	Clock       clock.Clock
+}
+
+func NewArchiveChatUseCase(chatGateway gateway.ChatGateway, clk clock.Clock) *ArchiveChatUseCase {
+	return &ArchiveChatUseCase{
+		ChatGateway: chatGateway,
+		Clock:       clk,
+	}
+}
+
+func (uc *ArchiveChatUseCase) Execute(ctx context.Context, input ArchiveChatInputDTO) (*ArchiveChatOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	if err := chat.Archive(uc.Clock.Now()); err != nil {
+		return nil, fmt.Errorf("error archiving chat: %w", err)
+	}
+	err = uc.ChatGateway.SaveChat(ctx, chat)
+	if err != nil {
+		return nil, fmt.Errorf("error saving chat: %w", err)
+	}
+	return &ArchiveChatOutputDTO{
+		ChatID: chat.ID,
+		Status: chat.Status,
+	}, nil
+}
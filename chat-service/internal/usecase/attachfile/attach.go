@@ -0,0 +1,81 @@
+package attachfile
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type AttachFileInputDTO struct {
+	ChatID    string
+	MessageID string
+	UserID    string
+	Name      string
+	MimeType  string
+	Size      int64
+	Content   io.Reader
+}
+
+type AttachmentOutputDTO struct {
+	ID        string
+	MessageID string
+	Name      string
+	MimeType  string
+	Size      int64
+}
+
+type AttachFileUseCase struct {
+	ChatGateway       gateway.ChatGateway
+	AttachmentGateway gateway.AttachmentGateway
+	FileStorage       gateway.FileStorage
+	Clock             clock.Clock
+}
+
+func NewAttachFileUseCase(chatGateway gateway.ChatGateway, attachmentGateway gateway.AttachmentGateway, fileStorage gateway.FileStorage, clk clock.Clock) *AttachFileUseCase {
+	return &AttachFileUseCase{
+		ChatGateway:       chatGateway,
+		AttachmentGateway: attachmentGateway,
+		FileStorage:       fileStorage,
+		Clock:             clk,
+	}
+}
+
+func (uc *AttachFileUseCase) Execute(ctx context.Context, input AttachFileInputDTO) (*AttachmentOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	message, err := chat.FindMessage(input.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	attachment, err := entity.NewAttachment(chat.ID, message, input.Name, input.MimeType, input.Size, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating attachment: %w", err)
+	}
+	err = uc.FileStorage.Put(ctx, attachment.StorageKey, input.Content, attachment.Size, attachment.MimeType)
+	if err != nil {
+		return nil, fmt.Errorf("error storing attachment: %w", err)
+	}
+	err = uc.AttachmentGateway.CreateAttachment(ctx, attachment)
+	if err != nil {
+		// don't leave an orphan file behind, the upload can be retried
+		uc.FileStorage.Delete(ctx, attachment.StorageKey)
+		return nil, fmt.Errorf("error persisting attachment: %w", err)
+	}
+	message.AddAttachment(attachment)
+	return &AttachmentOutputDTO{
+		ID:        attachment.ID,
+		MessageID: attachment.MessageID,
+		Name:      attachment.Name,
+		MimeType:  attachment.MimeType,
+		Size:      attachment.Size,
+	}, nil
+}
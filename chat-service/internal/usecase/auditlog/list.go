@@ -0,0 +1,114 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// ListAuditEntriesInputDTO selects the entries created from From, included,
+// to To, excluded, the newest Limit of them. The empty fields match every
+// tenant, user, chat or time.
+type ListAuditEntriesInputDTO struct {
+	OrgID  string
+	UserID string
+	ChatID string
+	From   time.Time
+	To     time.Time
+	// Limit defaults to defaultLimit and is capped to maxLimit.
+	Limit int
+}
+
+type AuditEntryOutputDTO struct {
+	ID        string
+	CreatedAt time.Time
+	OrgID     string
+	UserID    string
+	ChatID    string
+	MessageID string
+	RequestID string
+	Model     string
+	// Redaction is how Prompt and Response were kept: hash, pii or full.
+	Redaction        string
+	Prompt           string
+	Response         string
+	PromptTokens     int
+	CompletionTokens int
+	FinishReason     string
+}
+
+// ListAuditEntriesOutputDTO holds the entries newest first.
+type ListAuditEntriesOutputDTO struct {
+	Entries []AuditEntryOutputDTO
+}
+
+// ListAuditEntriesUseCase reads the audit trail of the turns, for the
+// compliance teams to review who sent what to which model.
+type ListAuditEntriesUseCase struct {
+	AuditLogGateway gateway.AuditLogGateway
+}
+
+func NewListAuditEntriesUseCase(auditLogGateway gateway.AuditLogGateway) *ListAuditEntriesUseCase {
+	return &ListAuditEntriesUseCase{
+		AuditLogGateway: auditLogGateway,
+	}
+}
+
+func (uc *ListAuditEntriesUseCase) Execute(ctx context.Context, input ListAuditEntriesInputDTO) (*ListAuditEntriesOutputDTO, error) {
+	if !input.From.IsZero() && !input.To.IsZero() && !input.From.Before(input.To) {
+		return nil, errors.New("invalid audit range: from is not before to")
+	}
+	limit := input.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	entries, err := uc.AuditLogGateway.ListAuditEntries(ctx, gateway.AuditFilter{
+		OrgID:  input.OrgID,
+		UserID: input.UserID,
+		ChatID: input.ChatID,
+		From:   input.From,
+		To:     input.To,
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing audit entries: %w", err)
+	}
+	output := &ListAuditEntriesOutputDTO{
+		Entries: make([]AuditEntryOutputDTO, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		output.Entries = append(output.Entries, newAuditEntryOutputDTO(entry))
+	}
+	return output, nil
+}
+
+func newAuditEntryOutputDTO(entry *entity.AuditEntry) AuditEntryOutputDTO {
+	return AuditEntryOutputDTO{
+		ID:               entry.ID,
+		CreatedAt:        entry.CreatedAt,
+		OrgID:            entry.OrgID,
+		UserID:           entry.UserID,
+		ChatID:           entry.ChatID,
+		MessageID:        entry.MessageID,
+		RequestID:        entry.RequestID,
+		Model:            entry.Model,
+		Redaction:        string(entry.Redaction),
+		Prompt:           entry.Prompt,
+		Response:         entry.Response,
+		PromptTokens:     entry.PromptTokens,
+		CompletionTokens: entry.CompletionTokens,
+		FinishReason:     entry.FinishReason,
+	}
+}
@@ -0,0 +1,216 @@
+package backupchats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+const (
+	listBatchSize = 100
+	// partSize is the number of chats per part file.
+	partSize = 500
+)
+
+type BackupChatsInputDTO struct {
+	Namespaces []string
+	// Keep is how many backups of each namespace are kept, older ones are
+	// deleted once a new one is complete. Zero keeps them all.
+	Keep int
+}
+
+type BackupReportDTO struct {
+	Namespace string
+	BackupID  string
+	Chats     int
+	Parts     int
+	Pruned    int
+}
+
+type BackupChatsOutputDTO struct {
+	Backups []BackupReportDTO
+}
+
+// BackupChatsUseCase writes the chats of each namespace to Storage with their
+// messages, annotations and attachment metadata, not the attachment files.
+// Chats are read page by page, not in one transaction: a chat written during
+// the backup is in it as it was when its page was read. Ephemeral chats are
+// left out. Message content is written as the gateway returns it, decrypted:
+// the backup storage needs its own encryption. ChatGateway must not be scoped to a namespace, nor list from the
+// read model.
+type BackupChatsUseCase struct {
+	ChatGateway gateway.ChatGateway
+	Storage     gateway.FileStorage
+	Clock       clock.Clock
+}
+
+func NewBackupChatsUseCase(chatGateway gateway.ChatGateway, storage gateway.FileStorage, clk clock.Clock) *BackupChatsUseCase {
+	return &BackupChatsUseCase{
+		ChatGateway: chatGateway,
+		Storage:     storage,
+		Clock:       clk,
+	}
+}
+
+func (uc *BackupChatsUseCase) Execute(ctx context.Context, input BackupChatsInputDTO) (*BackupChatsOutputDTO, error) {
+	if len(input.Namespaces) == 0 {
+		return nil, errors.New("no namespace to back up")
+	}
+	for _, ns := range input.Namespaces {
+		if err := namespace.Validate(ns); err != nil {
+			return nil, err
+		}
+	}
+	output := &BackupChatsOutputDTO{}
+	for _, ns := range input.Namespaces {
+		report, err := uc.backup(namespace.NewContext(ctx, ns), ns, input.Keep)
+		if err != nil {
+			return output, fmt.Errorf("error backing up namespace %s: %w", ns, err)
+		}
+		output.Backups = append(output.Backups, *report)
+	}
+	return output, nil
+}
+
+func (uc *BackupChatsUseCase) backup(ctx context.Context, ns string, keep int) (*BackupReportDTO, error) {
+	now := uc.Clock.Now().UTC()
+	manifest := &ManifestDTO{
+		FormatVersion: FormatVersion,
+		ID:            now.Format("20060102T150405Z"),
+		Namespace:     ns,
+		CreatedAt:     now,
+	}
+	var part bytes.Buffer
+	inPart := 0
+	flush := func() error {
+		if inPart == 0 {
+			return nil
+		}
+		key := partKey(ns, manifest.ID, len(manifest.Parts)+1)
+		if err := uc.Storage.Put(ctx, key, bytes.NewReader(part.Bytes()), int64(part.Len()), "application/x-ndjson"); err != nil {
+			return fmt.Errorf("error writing %s: %w", key, err)
+		}
+		manifest.Parts = append(manifest.Parts, key)
+		part.Reset()
+		inPart = 0
+		return nil
+	}
+	seen := make(map[string]bool)
+	for offset := 0; ; offset += listBatchSize {
+		chats, err := uc.ChatGateway.ListChats(ctx, gateway.ChatFilter{Limit: listBatchSize, Offset: offset})
+		if err != nil {
+			return nil, fmt.Errorf("error listing chats: %w", err)
+		}
+		for _, listed := range chats {
+			// a chat updated while paging moves to the first page, it may be listed twice
+			if seen[listed.ID] || !listed.AllowsExport() {
+				continue
+			}
+			seen[listed.ID] = true
+			chat, err := uc.ChatGateway.FindChatByID(ctx, listed.ID)
+			if err != nil && errors.Is(err, gateway.ErrChatNotFound) {
+				// deleted since it was listed
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error fetching chat %s: %w", listed.ID, err)
+			}
+			line, err := json.Marshal(chat)
+			if err != nil {
+				return nil, fmt.Errorf("error encoding chat %s: %w", chat.ID, err)
+			}
+			part.Write(line)
+			part.WriteByte('\n')
+			inPart++
+			manifest.Chats++
+			if inPart == partSize {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if len(chats) < listBatchSize {
+			break
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if err := uc.putJSON(ctx, ManifestKey(ns, manifest.ID), manifest); err != nil {
+		return nil, err
+	}
+	report := &BackupReportDTO{Namespace: ns, BackupID: manifest.ID, Chats: manifest.Chats, Parts: len(manifest.Parts)}
+	index, err := ReadIndex(ctx, uc.Storage, ns)
+	if err != nil {
+		return nil, fmt.Errorf("error reading backup index: %w", err)
+	}
+	index.Backups = append(index.Backups, IndexEntryDTO{ID: manifest.ID, CreatedAt: manifest.CreatedAt, Chats: manifest.Chats})
+	var pruned []IndexEntryDTO
+	if keep > 0 && len(index.Backups) > keep {
+		pruned = index.Backups[:len(index.Backups)-keep]
+		index.Backups = index.Backups[len(index.Backups)-keep:]
+	}
+	index.FormatVersion = FormatVersion
+	if err := uc.putJSON(ctx, IndexKey(ns), index); err != nil {
+		return nil, err
+	}
+	for _, old := range pruned {
+		if err := uc.prune(ctx, ns, old.ID); err != nil {
+			return report, fmt.Errorf("error deleting backup %s: %w", old.ID, err)
+		}
+		report.Pruned++
+	}
+	return report, nil
+}
+
+// prune deletes the manifest first, a backup half deleted reads as incomplete.
+func (uc *BackupChatsUseCase) prune(ctx context.Context, ns, backupID string) error {
+	manifest, err := ReadManifest(ctx, uc.Storage, ns, backupID)
+	if err != nil {
+		return err
+	}
+	if err := uc.Storage.Delete(ctx, ManifestKey(ns, backupID)); err != nil {
+		return err
+	}
+	for _, key := range manifest.Parts {
+		if err := uc.Storage.Delete(ctx, key); err != nil && !errors.Is(err, gateway.ErrFileNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (uc *BackupChatsUseCase) putJSON(ctx context.Context, key string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := uc.Storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "application/json"); err != nil {
+		return fmt.Errorf("error writing %s: %w", key, err)
+	}
+	return nil
+}
+
+// Run backs the namespaces up every interval until ctx is done. Failed runs
+// are reported through onError and retried on the next tick.
+func (uc *BackupChatsUseCase) Run(ctx context.Context, interval time.Duration, input BackupChatsInputDTO, onError func(error)) {
+	ticker := uc.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if _, err := uc.Execute(ctx, input); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package backupchats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+// FormatVersion is the version of the backup layout and of its chat records.
+// Restores refuse backups of a newer version. The layout, under the namespace:
+//
+//	<namespace>/index.json                  the backups, oldest first
+//	<namespace>/<id>/manifest.json          written last, a backup without it is incomplete
+//	<namespace>/<id>/chats-00001.jsonl      one chat per line
+const FormatVersion = 1
+
+type ManifestDTO struct {
+	FormatVersion int       `json:"format_version"`
+	ID            string    `json:"id"`
+	Namespace     string    `json:"namespace"`
+	CreatedAt     time.Time `json:"created_at"`
+	Chats         int       `json:"chats"`
+	Parts         []string  `json:"parts"`
+}
+
+type IndexEntryDTO struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Chats     int       `json:"chats"`
+}
+
+type IndexDTO struct {
+	FormatVersion int             `json:"format_version"`
+	Backups       []IndexEntryDTO `json:"backups"`
+}
+
+func IndexKey(ns string) string {
+	return ns + "/index.json"
+}
+
+func ManifestKey(ns, backupID string) string {
+	return ns + "/" + backupID + "/manifest.json"
+}
+
+func partKey(ns, backupID string, part int) string {
+	return fmt.Sprintf("%s/%s/chats-%05d.jsonl", ns, backupID, part)
+}
+
+// ReadIndex returns an empty index when the namespace has no backup yet.
+func ReadIndex(ctx context.Context, storage gateway.FileStorage, ns string) (*IndexDTO, error) {
+	index := &IndexDTO{FormatVersion: FormatVersion}
+	err := readJSON(ctx, storage, IndexKey(ns), index)
+	if errors.Is(err, gateway.ErrFileNotFound) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func ReadManifest(ctx context.Context, storage gateway.FileStorage, ns, backupID string) (*ManifestDTO, error) {
+	manifest := &ManifestDTO{}
+	if err := readJSON(ctx, storage, ManifestKey(ns, backupID), manifest); err != nil {
+		if errors.Is(err, gateway.ErrFileNotFound) {
+			return nil, fmt.Errorf("backup %s not found or incomplete", backupID)
+		}
+		return nil, err
+	}
+	if manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("backup %s has format version %d, this service reads up to %d",
+			backupID, manifest.FormatVersion, FormatVersion)
+	}
+	return manifest, nil
+}
+
+func readJSON(ctx context.Context, storage gateway.FileStorage, key string, v interface{}) error {
+	r, err := storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("error decoding %s: %w", key, err)
+	}
+	return nil
+}
+
+// DecodeChat decodes a chat record of a part.
+func DecodeChat(line []byte) (*entity.Chat, error) {
+	chat := &entity.Chat{}
+	if err := json.Unmarshal(line, chat); err != nil {
+		return nil, err
+	}
+	// the initial system message is one of the chat messages, keep it that way
+	if chat.InitialSystemMessage != nil {
+		for _, m := range chat.Messages {
+			if m.ID == chat.InitialSystemMessage.ID {
+				chat.InitialSystemMessage = m
+				break
+			}
+		}
+	}
+	return chat, nil
+}
+
+// ReadPart calls fn with every chat of a part, in order.
+func ReadPart(ctx context.Context, storage gateway.FileStorage, key string, fn func(chat *entity.Chat) error) error {
+	r, err := storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	decoder := json.NewDecoder(r)
+	for {
+		var line json.RawMessage
+		if err := decoder.Decode(&line); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error reading %s: %w", key, err)
+		}
+		chat, err := DecodeChat(line)
+		if err != nil {
+			return fmt.Errorf("error decoding chat of %s: %w", key, err)
+		}
+		if err := fn(chat); err != nil {
+			return err
+		}
+	}
+}
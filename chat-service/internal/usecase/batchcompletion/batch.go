@@ -0,0 +1,166 @@
+package batchcompletion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/ratelimit"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/redact"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/chatcompletionstream"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	StatusOK    = "ok"
+	StatusError = "error"
+
+	maxPromptsPerRequest = 50
+	defaultConcurrency   = 4
+)
+
+type BatchCompletionInputDTO struct {
+	UserID  string
+	OrgID   string
+	Prompts []string
+	// Config is the model and parameters the prompts are answered with, its
+	// InitialSystemMessage is sent before each of them.
+	Config chatcompletionstream.ChatCompletionConfigInputDTO
+}
+
+type AnswerDTO struct {
+	Status           string
+	Content          string
+	Error            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+type BatchCompletionOutputDTO struct {
+	// Answers are in the order of the prompts.
+	Answers []AnswerDTO
+}
+
+// BatchCompletionUseCase answers prompts independently of one another and of
+// any chat, without streaming: nothing is stored. A prompt failing doesn't
+// fail the others, its answer has the error instead.
+type BatchCompletionUseCase struct {
+	OpenAIClient *openai.Client
+	// RateLimiter, when set, takes a token of the tenant, or of the user
+	// outside of one, for every prompt.
+	RateLimiter *ratelimit.Limiter
+	// Concurrency is how many prompts of a request are answered at once.
+	Concurrency int
+}
+
+func NewBatchCompletionUseCase(openAIClient *openai.Client, limiter *ratelimit.Limiter) *BatchCompletionUseCase {
+	return &BatchCompletionUseCase{
+		OpenAIClient: openAIClient,
+		RateLimiter:  limiter,
+		Concurrency:  defaultConcurrency,
+	}
+}
+
+func (uc *BatchCompletionUseCase) Execute(ctx context.Context, input BatchCompletionInputDTO) (*BatchCompletionOutputDTO, error) {
+	if err := validate(input); err != nil {
+		return nil, err
+	}
+	concurrency := uc.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	answers := make([]AnswerDTO, len(input.Prompts))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, prompt := range input.Prompts {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, prompt string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			answer, err := uc.answer(ctx, input, prompt)
+			if err != nil {
+				answers[i] = AnswerDTO{Status: StatusError, Error: err.Error()}
+				return
+			}
+			answers[i] = *answer
+		}(i, prompt)
+	}
+	wg.Wait()
+	return &BatchCompletionOutputDTO{Answers: answers}, nil
+}
+
+func (uc *BatchCompletionUseCase) answer(ctx context.Context, input BatchCompletionInputDTO, prompt string) (*AnswerDTO, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := uc.RateLimiter.Take(ctx, rateLimitKey(input)); err != nil {
+		return nil, err
+	}
+	content, _ := redact.Secrets(prompt)
+	var messages []openai.ChatCompletionMessage
+	if input.Config.InitialSystemMessage != "" {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: input.Config.InitialSystemMessage})
+	}
+	messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: content})
+	resp, err := uc.OpenAIClient.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:            input.Config.Model,
+		Messages:         messages,
+		Temperature:      requestTemperature(input.Config.Temperature),
+		TopP:             input.Config.TopP,
+		Stop:             input.Config.Stop,
+		MaxTokens:        input.Config.MaxTokens,
+		PresencePenalty:  input.Config.PresencePenalty,
+		FrequencyPenalty: input.Config.FrequencyPenalty,
+	})
+	if err != nil {
+		return nil, &entity.ProviderError{Op: "error creating chat completion", Err: err}
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("empty chat completion")
+	}
+	return &AnswerDTO{
+		Status:           StatusOK,
+		Content:          resp.Choices[0].Message.Content,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	}, nil
+}
+
+func rateLimitKey(input BatchCompletionInputDTO) string {
+	if input.OrgID != "" {
+		return "org:" + input.OrgID
+	}
+	return "user:" + input.UserID
+}
+
+// requestTemperature works around the omitempty on ChatCompletionRequest.Temperature,
+// which would make the API fall back to its default of 1 for a temperature of 0.
+func requestTemperature(temperature float32) float32 {
+	if temperature == 0 {
+		return math.SmallestNonzeroFloat32
+	}
+	return temperature
+}
+
+func validate(input BatchCompletionInputDTO) error {
+	if input.UserID == "" {
+		return errors.New("user id is empty")
+	}
+	if len(input.Prompts) == 0 {
+		return errors.New("no prompts")
+	}
+	if len(input.Prompts) > maxPromptsPerRequest {
+		return fmt.Errorf("at most %d prompts can be sent", maxPromptsPerRequest)
+	}
+	for i, prompt := range input.Prompts {
+		if strings.TrimSpace(prompt) == "" {
+			return fmt.Errorf("prompt %d is empty", i)
+		}
+	}
+	return nil
+}
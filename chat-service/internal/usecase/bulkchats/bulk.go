@@ -0,0 +1,142 @@
+package bulkchats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/jobs"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/archivechat"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/deletechat"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/exportchat"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/tagchat"
+)
+
+const (
+	OperationArchive = "archive"
+	OperationDelete  = "delete"
+	OperationTag     = "tag"
+	OperationExport  = "export"
+
+	StatusOK    = "ok"
+	StatusError = "error"
+
+	maxChatsPerRequest = 1000
+)
+
+type BulkChatsInputDTO struct {
+	UserID    string
+	Operation string
+	ChatIDs   []string
+	// Tag is required by the tag operation.
+	Tag string
+	// Async runs the operation as a background job; selections larger than the
+	// use case AsyncThreshold always do.
+	Async bool
+}
+
+type ItemResultDTO struct {
+	ChatID string
+	Status string
+	Error  string
+	Export *exportchat.ExportedChatDTO
+}
+
+type BulkChatsOutputDTO struct {
+	// JobID is set instead of Results when the operation runs asynchronously.
+	JobID   string
+	Results []ItemResultDTO
+}
+
+type BulkChatsUseCase struct {
+	Archive *archivechat.ArchiveChatUseCase
+	Delete  *deletechat.DeleteChatUseCase
+	Tag     *tagchat.AddTagUseCase
+	Export  *exportchat.ExportChatUseCase
+	Jobs    *jobs.Store[[]ItemResultDTO]
+	// AsyncThreshold is the selection size above which operations run as jobs.
+	AsyncThreshold int
+}
+
+func NewBulkChatsUseCase(chatGateway gateway.ChatGateway, clk clock.Clock, jobStore *jobs.Store[[]ItemResultDTO], asyncThreshold int) *BulkChatsUseCase {
+	return &BulkChatsUseCase{
+		Archive:        archivechat.NewArchiveChatUseCase(chatGateway, clk),
+		Delete:         deletechat.NewDeleteChatUseCase(chatGateway, clk),
+		Tag:            tagchat.NewAddTagUseCase(chatGateway),
+		Export:         exportchat.NewExportChatUseCase(chatGateway),
+		Jobs:           jobStore,
+		AsyncThreshold: asyncThreshold,
+	}
+}
+
+func (uc *BulkChatsUseCase) Execute(ctx context.Context, input BulkChatsInputDTO) (*BulkChatsOutputDTO, error) {
+	if err := validate(input); err != nil {
+		return nil, err
+	}
+	if input.Async || (uc.AsyncThreshold > 0 && len(input.ChatIDs) > uc.AsyncThreshold) {
+		jobID := uc.Jobs.Start(input.UserID, len(input.ChatIDs), func(ctx context.Context, progress func(int)) ([]ItemResultDTO, error) {
+			return uc.run(ctx, input, progress), nil
+		})
+		return &BulkChatsOutputDTO{JobID: jobID}, nil
+	}
+	return &BulkChatsOutputDTO{Results: uc.run(ctx, input, func(int) {})}, nil
+}
+
+func (uc *BulkChatsUseCase) run(ctx context.Context, input BulkChatsInputDTO, progress func(int)) []ItemResultDTO {
+	results := make([]ItemResultDTO, 0, len(input.ChatIDs))
+	for i, chatID := range input.ChatIDs {
+		result := ItemResultDTO{ChatID: chatID, Status: StatusOK}
+		if err := ctx.Err(); err != nil {
+			result.Status = StatusError
+			result.Error = err.Error()
+		} else if export, err := uc.apply(ctx, input, chatID); err != nil {
+			result.Status = StatusError
+			result.Error = err.Error()
+		} else {
+			result.Export = export
+		}
+		results = append(results, result)
+		progress(i + 1)
+	}
+	return results
+}
+
+func (uc *BulkChatsUseCase) apply(ctx context.Context, input BulkChatsInputDTO, chatID string) (*exportchat.ExportedChatDTO, error) {
+	switch input.Operation {
+	case OperationArchive:
+		_, err := uc.Archive.Execute(ctx, archivechat.ArchiveChatInputDTO{ChatID: chatID, UserID: input.UserID})
+		return nil, err
+	case OperationDelete:
+		return nil, uc.Delete.Execute(ctx, deletechat.DeleteChatInputDTO{ChatID: chatID, UserID: input.UserID})
+	case OperationTag:
+		_, err := uc.Tag.Execute(ctx, tagchat.TagChatInputDTO{ChatID: chatID, UserID: input.UserID, Tag: input.Tag})
+		return nil, err
+	case OperationExport:
+		return uc.Export.Execute(ctx, exportchat.ExportChatInputDTO{ChatID: chatID, UserID: input.UserID})
+	}
+	return nil, fmt.Errorf("unknown operation %s", input.Operation)
+}
+
+func validate(input BulkChatsInputDTO) error {
+	if input.UserID == "" {
+		return errors.New("user id is empty")
+	}
+	switch input.Operation {
+	case OperationArchive, OperationDelete, OperationExport:
+	case OperationTag:
+		if input.Tag == "" {
+			return errors.New("tag is empty")
+		}
+	default:
+		return fmt.Errorf("unknown operation %s", input.Operation)
+	}
+	if len(input.ChatIDs) == 0 {
+		return errors.New("no chats selected")
+	}
+	if len(input.ChatIDs) > maxChatsPerRequest {
+		return fmt.Errorf("at most %d chats can be selected", maxChatsPerRequest)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package bulkchats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/jobs"
+)
+
+type GetBulkJobInputDTO struct {
+	JobID  string
+	UserID string
+}
+
+type BulkJobOutputDTO struct {
+	JobID      string
+	Status     string
+	Total      int
+	Done       int
+	Results    []ItemResultDTO
+	Error      string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+func (uc *BulkChatsUseCase) GetJob(input GetBulkJobInputDTO) (*BulkJobOutputDTO, error) {
+	job, err := uc.Jobs.Get(input.JobID, input.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching bulk job: %w", err)
+	}
+	output := &BulkJobOutputDTO{
+		JobID:      job.ID,
+		Status:     job.Status,
+		Total:      job.Total,
+		Done:       job.Done,
+		Error:      job.Error,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if job.Status != jobs.StatusRunning {
+		output.Results = job.Result
+	}
+	return output, nil
+}
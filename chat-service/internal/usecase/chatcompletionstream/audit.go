@@ -0,0 +1,59 @@
+package chatcompletionstream
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/logging"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/redact"
+)
+
+// audit appends the turn t of req answered with output to the audit log,
+// its prompt and response redacted as AuditPolicy tells for the tenant. Best
+// effort like recordUsage, a failure is logged as an error for the operators
+// to notice the gap in the trail.
+func (uc *ChatCompletionUseCase) audit(ctx context.Context, t *turn, req completionRequest, chat *entity.Chat, answer *entity.Message, output *ChatCompletionOutputDTO) {
+	if uc.AuditLogGateway == nil {
+		return
+	}
+	entry, err := entity.NewAuditEntry(uc.Clock.Now(), req.orgID, req.userID, chat.ID, answer.ID, chat.Config.Model.GetModelName())
+	if err == nil {
+		entry.RequestID = t.requestID
+		entry.Redaction = uc.AuditPolicy.Redaction(req.orgID)
+		entry.Prompt = redactAudit(entry.Redaction, turnPrompt(chat, answer))
+		entry.Response = redactAudit(entry.Redaction, answer.Content)
+		entry.PromptTokens = output.PromptTokens
+		entry.CompletionTokens = output.CompletionTokens
+		entry.FinishReason = output.FinishReason
+		err = uc.AuditLogGateway.AppendAuditEntry(ctx, entry)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("error appending audit entry", logging.Err(err))
+	}
+}
+
+// turnPrompt is the content of the user message answer replies to, the last
+// one before it.
+func turnPrompt(chat *entity.Chat, answer *entity.Message) string {
+	prompt := ""
+	for _, m := range chat.GetMessages() {
+		if m.ID == answer.ID {
+			break
+		}
+		if m.Role == entity.RoleUser {
+			prompt = m.Content
+		}
+	}
+	return prompt
+}
+
+func redactAudit(redaction entity.AuditRedaction, content string) string {
+	switch redaction {
+	case entity.AuditRedactionFull:
+		return content
+	case entity.AuditRedactionPII:
+		stripped, _ := redact.PII(content)
+		return stripped
+	}
+	return entity.HashAuditContent(content)
+}
@@ -5,11 +5,24 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"strings"
+	"time"
 
 	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
 	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/dispatcher"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/errorreport"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/featureflag"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/logging"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/ratelimit"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/redact"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/requestid"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/streambuffer"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/tracing"
 	openai "github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type ChatCompletionConfigInputDTO struct {
@@ -23,70 +36,569 @@ type ChatCompletionConfigInputDTO struct {
 	PresencePenalty      float32
 	FrequencyPenalty     float32
 	InitialSystemMessage string
+	TitleModel           string
+	AnswerMode           string
+	Deterministic        bool
+	// SystemPromptTemplate names a stored template rendered with TemplateVariables
+	// into the initial system message, instead of InitialSystemMessage.
+	SystemPromptTemplate string
+	TemplateVariables    map[string]string
+	// EphemeralTTL creates an ephemeral chat that expires after it.
+	EphemeralTTL time.Duration
+	// MaxMessages caps the messages of the chat context, EvictionPolicy is one
+	// of reject (default), evict_oldest or summarize.
+	MaxMessages    int
+	EvictionPolicy string
 }
 
 type ChatCompletionInputDTO struct {
 	ChatID      string
 	UserID      string
+	OrgID       string
 	UserMessage string
-	Config      ChatCompletionConfigInputDTO
+	Tier        string
+	// Priority is interactive, the default, or batch for the requests no user
+	// waits for, which only get the dispatcher slots left by the interactive
+	// ones.
+	Priority string
+	// PersonaID creates the chat from a stored persona, Config is then only used
+	// for AnswerMode, Deterministic, EphemeralTTL, MaxMessages, EvictionPolicy
+	// and TitleModel.
+	PersonaID string
+	Config    ChatCompletionConfigInputDTO
+	// IdempotencyKey, when set, has a retry of the request with the same key
+	// return the answer already saved instead of generating another one.
+	IdempotencyKey string
 }
 
 type ChatCompletionOutputDTO struct {
 	ChatID  string
 	UserID  string
+	Event   string
 	Content string
+	// Delta is what a content chunk adds to the content of the previous one,
+	// for the consumers sending only that.
+	Delta string
+	// TurnID identifies the generation the chunk is part of, Seq numbers its
+	// chunks from 1 without gaps so consumers can spot a missing or repeated
+	// chunk and put them back in order.
+	TurnID        string
+	Seq           int
+	ResumeToken   string
+	QueuePosition int
+	// AwaitingConfirmation is set when Content is an outline that must be
+	// confirmed before the full answer is generated.
+	AwaitingConfirmation bool
+	// Stopped is set when the generation was stopped and Content is the
+	// partial answer saved.
+	Stopped bool
+	// IncompleteReason is set when the provider failed in the middle of the
+	// answer, on generation_incomplete chunks and the output, Content is
+	// then the partial answer saved.
+	IncompleteReason string
+	// FinishReason is why the turn ended, one of the Finish constants, on
+	// the generation_ended chunk and the output. Error is set on failures.
+	FinishReason string
+	Error        string
+	// RequestID is the ID of the request the turn runs for.
+	RequestID string
+	// PromptTokens, CompletionTokens and TotalTokens are the tokens the turn
+	// used, EstimatedCost their cost in US dollars at the list price of the
+	// model, the sum of PromptCost and CompletionCost. Priced is false for
+	// the models of no known price, their costs are 0. They are set on usage
+	// chunks and on the output.
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	EstimatedCost    float64
+	PromptCost       float64
+	CompletionCost   float64
+	Priced           bool
+	// MessageID is the ID of the answer, on the output.
+	MessageID string
+	// Replayed is set on the output of a request whose idempotency key was
+	// already answered, the output is that answer and nothing was generated.
+	Replayed bool
 }
 
 type ChatCompletionUseCase struct {
 	ChatGateway  gateway.ChatGateway
 	OpenAIClient *openai.Client
 	Stream       chan ChatCompletionOutputDTO
+	StreamBuffer *streambuffer.Buffer[ChatCompletionOutputDTO]
+	Dispatcher   *dispatcher.Dispatcher
+	// PromptTemplateGateway is required to create chats from a SystemPromptTemplate.
+	PromptTemplateGateway gateway.PromptTemplateGateway
+	// PersonaGateway is required to create chats from a persona.
+	PersonaGateway gateway.PersonaGateway
+	// CorrectionGateway feeds the corrections users made back into the context.
+	CorrectionGateway gateway.CorrectionGateway
+	Clock             clock.Clock
+	// FeatureFlags gates capabilities that are being rolled out, they are all
+	// available when nil.
+	FeatureFlags *featureflag.Flags
+	// DraftGateway, when set, has the user's draft of the chat cleared once
+	// the message is sent.
+	DraftGateway gateway.DraftGateway
+	// UnitOfWork, when set, commits the chat of a turn in one transaction,
+	// with its creation when the turn opens the chat, its events in the
+	// outbox and the usage of the turn.
+	UnitOfWork gateway.UnitOfWork
+	// Generations tracks the generations in flight so they can be stopped.
+	Generations *Generations
+	// RateLimiter, when set, limits the turns of each tenant, or of each
+	// user outside of one.
+	RateLimiter *ratelimit.Limiter
+	// WebhookGateway, when set, has the chats created, answers completed and
+	// generations failed posted to the webhooks of their tenant.
+	WebhookGateway gateway.WebhookGateway
+	// IdempotencyKeyGateway is required for the idempotency keys of the
+	// requests to be honoured, they are ignored without it.
+	IdempotencyKeyGateway gateway.IdempotencyKeyGateway
+	// UsageGateway, when set, meters the tokens and cost of the answered
+	// turns for each user.
+	UsageGateway gateway.UsageGateway
+	// AuditLogGateway, when set, keeps the audit trail of the answered
+	// turns, their content redacted as AuditPolicy tells for each tenant.
+	AuditLogGateway gateway.AuditLogGateway
+	AuditPolicy     entity.AuditPolicy
+	// StreamCapacity is the buffer of the streams ExecuteStreaming opens, so
+	// a consumer can lag behind by that many chunks. SlowConsumer is what a
+	// turn does with a chunk its stream has no room for, SlowConsumerWait by
+	// default, and SlowConsumerTimeout how long SlowConsumerFail waits.
+	StreamCapacity      int
+	SlowConsumer        SlowConsumerPolicy
+	SlowConsumerTimeout time.Duration
+	// FirstTokenTimeout and GenerationTimeout, when set, bound the wait for
+	// the first token of an answer and the whole generation, which then
+	// fails with a *TimeoutError.
+	FirstTokenTimeout time.Duration
+	GenerationTimeout time.Duration
+	// SlowGenerationWatchdog flags the generations slower than its
+	// thresholds, none are when they are zero.
+	SlowGenerationWatchdog SlowGenerationWatchdog
+	// Metrics, when set, counts what the generations went through.
+	Metrics *Metrics
+	// Tracer, when set, traces the stages of every turn: the chat lookup,
+	// the token counting, the wait for a slot, the provider stream and the
+	// persistence.
+	Tracer *tracing.Tracer
+	// ErrorReporter, when set, reports the turns that failed unexpectedly,
+	// not those over a limit or the client may fix or retry.
+	ErrorReporter *errorreport.Reporter
+	// StreamHooks run on every chunk of the turns, in order, before it is
+	// streamed.
+	StreamHooks []StreamHook
+	// HeartbeatInterval, when set, has the turns send a heartbeat chunk
+	// every time they go that long without one.
+	HeartbeatInterval time.Duration
 }
 
-func NewChatCompletionUseCase(chatGateway gateway.ChatGateway, openAIClient *openai.Client, stream chan ChatCompletionOutputDTO) *ChatCompletionUseCase {
-	return &ChatCompletionUseCase{
+type Option func(*ChatCompletionUseCase)
+
+// WithStreamBuffer keeps the streamed chunks in buffer so clients can resume
+// an interrupted stream with the resume token of its chunks.
+func WithStreamBuffer(buffer *streambuffer.Buffer[ChatCompletionOutputDTO]) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.StreamBuffer = buffer
+	}
+}
+
+// WithDispatcher makes every completion wait for a dispatcher slot. Queued
+// requests receive generation_thinking chunks with their QueuePosition.
+func WithDispatcher(d *dispatcher.Dispatcher) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.Dispatcher = d
+	}
+}
+
+func WithPromptTemplateGateway(promptTemplateGateway gateway.PromptTemplateGateway) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.PromptTemplateGateway = promptTemplateGateway
+	}
+}
+
+func WithPersonaGateway(personaGateway gateway.PersonaGateway) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.PersonaGateway = personaGateway
+	}
+}
+
+func WithCorrectionGateway(correctionGateway gateway.CorrectionGateway) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.CorrectionGateway = correctionGateway
+	}
+}
+
+// WithClock replaces the real clock, e.g. with a simulated one in sandbox mode.
+func WithClock(clk clock.Clock) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.Clock = clk
+	}
+}
+
+func WithDraftGateway(draftGateway gateway.DraftGateway) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.DraftGateway = draftGateway
+	}
+}
+
+func WithFeatureFlags(flags *featureflag.Flags) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.FeatureFlags = flags
+	}
+}
+
+func WithUnitOfWork(unitOfWork gateway.UnitOfWork) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.UnitOfWork = unitOfWork
+	}
+}
+
+func WithRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.RateLimiter = limiter
+	}
+}
+
+func WithWebhookGateway(webhookGateway gateway.WebhookGateway) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.WebhookGateway = webhookGateway
+	}
+}
+
+func WithUsageGateway(usageGateway gateway.UsageGateway) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.UsageGateway = usageGateway
+	}
+}
+
+// WithAuditLog appends every answered turn to the audit log of
+// auditLogGateway, redacted as policy tells.
+func WithAuditLog(auditLogGateway gateway.AuditLogGateway, policy entity.AuditPolicy) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.AuditLogGateway = auditLogGateway
+		uc.AuditPolicy = policy
+	}
+}
+
+func WithIdempotencyKeyGateway(idempotencyKeyGateway gateway.IdempotencyKeyGateway) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.IdempotencyKeyGateway = idempotencyKeyGateway
+	}
+}
+
+// WithSlowConsumerPolicy buffers the streams of ExecuteStreaming with
+// capacity chunks and applies policy to the chunks a stream has no room for.
+func WithSlowConsumerPolicy(capacity int, policy SlowConsumerPolicy, timeout time.Duration) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.StreamCapacity = capacity
+		uc.SlowConsumer = policy
+		uc.SlowConsumerTimeout = timeout
+	}
+}
+
+// WithTimeouts bounds the wait for the first token of every answer and its
+// whole generation, no bound when zero.
+func WithTimeouts(firstToken, total time.Duration) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.FirstTokenTimeout = firstToken
+		uc.GenerationTimeout = total
+	}
+}
+
+// WithSlowGenerationWatchdog flags the generations slower than the
+// thresholds of w.
+func WithSlowGenerationWatchdog(w SlowGenerationWatchdog) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.SlowGenerationWatchdog = w
+	}
+}
+
+// WithHeartbeat has the turns send a heartbeat chunk whenever they go
+// interval without one, while their generation runs.
+func WithHeartbeat(interval time.Duration) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.HeartbeatInterval = interval
+	}
+}
+
+func WithTracer(tracer *tracing.Tracer) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.Tracer = tracer
+	}
+}
+
+func WithErrorReporter(reporter *errorreport.Reporter) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.ErrorReporter = reporter
+	}
+}
+
+func WithMetrics(metrics *Metrics) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.Metrics = metrics
+	}
+}
+
+func NewChatCompletionUseCase(chatGateway gateway.ChatGateway, openAIClient *openai.Client, stream chan ChatCompletionOutputDTO, opts ...Option) *ChatCompletionUseCase {
+	uc := &ChatCompletionUseCase{
 		ChatGateway:  chatGateway,
 		OpenAIClient: openAIClient,
 		Stream:       stream,
+		Clock:        clock.Real(),
+		Generations:  NewGenerations(),
+	}
+	for _, opt := range opts {
+		opt(uc)
 	}
+	return uc
 }
 
-func (uc *ChatCompletionUseCase) Execute(ctx context.Context, input ChatCompletionInputDTO) (*ChatCompletionOutputDTO, error) {
-	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+func (uc *ChatCompletionUseCase) Execute(ctx context.Context, input ChatCompletionInputDTO) (output *ChatCompletionOutputDTO, err error) {
+	ctx = requestid.Ensure(ctx)
+	defer func() { err = requestid.Wrap(ctx, err) }()
+	ctx, span := uc.Tracer.Start(ctx, "ChatCompletion.Execute",
+		attribute.String("chat.id", input.ChatID),
+		attribute.String("user.id", input.UserID))
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+	if input.IdempotencyKey != "" && uc.IdempotencyKeyGateway != nil {
+		key, replayed, claimErr := uc.claimIdempotencyKey(ctx, input)
+		if claimErr != nil {
+			return nil, claimErr
+		}
+		if replayed != nil {
+			return replayed, nil
+		}
+		defer func() { uc.settleIdempotencyKey(ctx, key, output, err) }()
+	}
+	chat, newChat, err := uc.lookupChat(ctx, input)
 	if err != nil {
-		if err.Error() == "chat not found" {
-			chat, err = createNewChat(input)
-			if err != nil {
-				return nil, fmt.Errorf("error creating new chat: %s", err.Error())
-			}
-			err = uc.ChatGateway.CreateChat(ctx, chat)
-			if err != nil {
-				return nil, fmt.Errorf("error persisting new chat: %s", err.Error())
-			}
-		} else {
-			return nil, fmt.Errorf("error fetching existing new chat: %s", err.Error())
+		return nil, err
+	}
+	content, secrets := redact.Secrets(input.UserMessage)
+	userMessage, err := uc.addUserMessage(ctx, chat, content)
+	if err != nil {
+		return nil, err
+	}
+	output, err = uc.complete(ctx, chat, completionRequest{
+		userID:     input.UserID,
+		orgID:      input.OrgID,
+		tier:       input.Tier,
+		priority:   input.Priority,
+		titleModel: input.Config.TitleModel,
+		notices:    redactionNotices(secrets),
+		newChat:    newChat,
+		rebase:     rebaseUserMessage(uc.Clock, userMessage),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if uc.DraftGateway != nil {
+		// best effort, a stale draft is only an inconvenience
+		uc.DraftGateway.DeleteDraft(ctx, input.ChatID, input.UserID)
+	}
+	return output, nil
+}
+
+// rebaseUserMessage adds the message of the user again to the chat reloaded
+// after a conflict.
+func rebaseUserMessage(clk clock.Clock, userMessage *entity.Message) func(chat *entity.Chat) error {
+	return func(chat *entity.Chat) error {
+		return chat.AddMessage(userMessage, clk.Now())
+	}
+}
+
+// lookupChat finds the chat of input, or creates it when the turn opens it,
+// newChat tells which.
+func (uc *ChatCompletionUseCase) lookupChat(ctx context.Context, input ChatCompletionInputDTO) (chat *entity.Chat, newChat bool, err error) {
+	ctx, span := uc.Tracer.Start(ctx, "chat.lookup")
+	defer func() {
+		span.SetAttributes(attribute.Bool("chat.new", newChat))
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+	chat, err = uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err == nil {
+		if chat.UserID != input.UserID {
+			return nil, false, entity.ErrChatForbidden
 		}
+		return chat, false, nil
+	}
+	if !errors.Is(err, gateway.ErrChatNotFound) {
+		return nil, false, fmt.Errorf("error fetching existing new chat: %w", err)
+	}
+	// persisted with the first turn, so a failed turn leaves no empty chat behind
+	chat, err = uc.createNewChat(ctx, input)
+	if err != nil {
+		return nil, false, fmt.Errorf("error creating new chat: %w", err)
+	}
+	return chat, true, nil
+}
+
+// addUserMessage adds the message of the user to chat, counting its tokens
+// and fitting the context of the chat around it.
+func (uc *ChatCompletionUseCase) addUserMessage(ctx context.Context, chat *entity.Chat, content string) (_ *entity.Message, err error) {
+	_, span := uc.Tracer.Start(ctx, "tokens.count")
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+	now := uc.Clock.Now()
+	userMessage, err := entity.NewMessage(entity.RoleUser, content, chat.Config.Model, now)
+	if err != nil {
+		return nil, fmt.Errorf("error creating user message: %w", err)
 	}
-	userMessage, err := entity.NewMessage("user", input.UserMessage, chat.Config.Model)
+	span.SetAttributes(attribute.Int("message.tokens", userMessage.GetQtdTokens()))
+	err = chat.AddMessage(userMessage, now)
 	if err != nil {
-		return nil, fmt.Errorf("error creating user message: %s", err.Error())
+		return nil, fmt.Errorf("error adding new message: %w", err)
 	}
-	err = chat.AddMessage(userMessage)
+	return userMessage, nil
+}
+
+type completionRequest struct {
+	userID     string
+	tier       string
+	priority   string
+	titleModel string
+	// notices are sent to the user as system_notice chunks before the answer
+	notices []string
+	// confirmedOutline expands the outline the chat is awaiting confirmation for
+	confirmedOutline bool
+	// newChat is set when the chat is not persisted yet
+	newChat bool
+	// temperature overrides the temperature of the chat for this turn only
+	temperature *float32
+	// orgID is the tenant the turn is rate limited with, when known
+	orgID string
+	// rebase applies the change the turn was started with again, on the chat
+	// reloaded after a concurrent update
+	rebase func(chat *entity.Chat) error
+}
+
+// complete streams the assistant answer to the current chat messages, appends it
+// to the chat and persists the chat.
+func (uc *ChatCompletionUseCase) complete(ctx context.Context, chat *entity.Chat, req completionRequest) (output *ChatCompletionOutputDTO, err error) {
+	turnStartedAt := uc.Clock.Now()
+	ctx = logging.WithContext(ctx,
+		logging.String("chat_id", chat.ID),
+		logging.String("user_id", req.userID),
+		logging.String("model", chat.Config.Model.GetModelName()))
+	// the provider stream runs on genCtx, so stopping it leaves the rest of
+	// the turn to save the partial answer
+	genCtx, gen, untrack, err := uc.Generations.start(ctx, chat.ID, req.userID)
 	if err != nil {
-		return nil, fmt.Errorf("error adding new message: %s", err.Error())
+		return nil, err
 	}
+	defer untrack()
+	if err := uc.RateLimiter.Take(ctx, rateLimitKey(req)); err != nil {
+		return nil, err
+	}
+	labels := GenerationLabels{Model: chat.Config.Model.GetModelName(), Tenant: req.orgID}
+	uc.Metrics.observeStarted(labels)
+	defer func() { uc.Metrics.observeEnded(labels, err) }()
+	t := uc.newTurn(ctx, chat.ID, req.userID)
+	defer func() { t.finish(output, err) }()
+	logger := logging.FromContext(ctx).With(logging.String("turn_id", t.id))
+	defer func() {
+		if err != nil {
+			logger.Error("completion failed", logging.Err(err), logging.Duration("duration", uc.Clock.Since(turnStartedAt)))
+			return
+		}
+		logger.Info("completion finished",
+			logging.String("finish_reason", output.FinishReason),
+			logging.Int("prompt_tokens", output.PromptTokens),
+			logging.Int("completion_tokens", output.CompletionTokens),
+			logging.Duration("duration", uc.Clock.Since(turnStartedAt)))
+	}()
+	defer func() {
+		if err != nil {
+			uc.notifyGenerationFailed(ctx, t, req, err)
+			uc.reportError(ctx, t, req, chat, err)
+		}
+	}()
+	t.emit(ChatCompletionOutputDTO{Event: EventGenerationStarted})
+	stopHeartbeat := t.heartbeat(uc.HeartbeatInterval)
+	defer stopHeartbeat()
+	for _, notice := range req.notices {
+		t.emit(ChatCompletionOutputDTO{
+			Event:   EventSystemNotice,
+			Content: notice,
+		})
+	}
+	releaseSlot := func() {}
+	if uc.Dispatcher != nil {
+		waitStartedAt := uc.Clock.Now()
+		_, waitSpan := uc.Tracer.Start(ctx, "dispatcher.wait", attribute.String("priority", req.priority))
+		release, err := uc.Dispatcher.AcquireWithPriority(genCtx, req.userID, req.tier, dispatcher.Priority(req.priority), func(position int) {
+			t.emit(ChatCompletionOutputDTO{
+				Event:         EventGenerationThinking,
+				QueuePosition: position,
+			})
+		})
+		tracing.RecordError(waitSpan, err)
+		waitSpan.End()
+		if err != nil {
+			return nil, fmt.Errorf("error waiting for a completion slot: %w", err)
+		}
+		logger.Debug("completion slot acquired", logging.Duration("wait", uc.Clock.Since(waitStartedAt)))
+		releaseSlot = release
+		t.holdsSlot = true
+	}
+	defer releaseSlot()
+	uc.applyPendingSummary(ctx, chat, t)
 	messages := []openai.ChatCompletionMessage{}
 	for _, msg := range chat.Messages {
 		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    msg.Role,
+			Role:    msg.Role.String(),
 			Content: msg.Content,
 		})
 	}
-	resp, err := uc.OpenAIClient.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
-		Model:            chat.Config.Model.Name,
+	corrections, err := uc.correctionsPrompt(ctx, chat)
+	if err != nil {
+		return nil, err
+	}
+	if corrections != "" {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: corrections})
+	}
+	outline := chat.IsOutlineFirst() && !req.confirmedOutline
+	if outline {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: outlinePrompt})
+	} else if req.confirmedOutline {
+		messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: expandOutlinePrompt})
+	}
+	temperature := chat.Config.Temperature
+	if req.temperature != nil {
+		temperature = *req.temperature
+	}
+	promptTokens := chat.TokenUsage
+	startedAt := uc.Clock.Now()
+	gotFirstToken, stopTimeouts := uc.watchTimeouts(gen)
+	defer stopTimeouts()
+	sawFirstToken, stopWatchdog := uc.watchSlowness(ctx, SlowGeneration{
+		ChatID:         chat.ID,
+		TurnID:         t.id,
+		UserID:         req.userID,
+		OrgID:          req.orgID,
+		Model:          chat.Config.Model.GetModelName(),
+		PromptTokens:   promptTokens,
+		PromptMessages: len(messages),
+	})
+	defer stopWatchdog()
+	streamCtx, streamSpan := uc.Tracer.Start(genCtx, "provider.stream", attribute.String("model", chat.RequestModel()))
+	defer func() {
+		// ended already unless the stream failed
+		tracing.RecordError(streamSpan, err)
+		streamSpan.End()
+	}()
+	resp, err := uc.OpenAIClient.CreateChatCompletionStream(streamCtx, openai.ChatCompletionRequest{
+		Model:            chat.RequestModel(),
 		Messages:         messages,
-		Temperature:      chat.Config.Temperature,
+		Temperature:      requestTemperature(temperature),
 		TopP:             chat.Config.TopP,
 		N:                chat.Config.N,
 		Stop:             chat.Config.Stop,
@@ -95,64 +607,368 @@ func (uc *ChatCompletionUseCase) Execute(ctx context.Context, input ChatCompleti
 		FrequencyPenalty: chat.Config.FrequencyPenalty,
 		Stream:           true,
 	})
+	if timedOut := gen.timeoutErr(); timedOut != nil {
+		return nil, timedOut
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error creating chat completion: %s", err.Error())
+		uc.Metrics.observeProviderError(labels, err)
+		return nil, &entity.ProviderError{Op: "error creating chat completion", Err: err}
 	}
+	logger.Debug("provider stream opened", logging.Duration("open", uc.Clock.Since(startedAt)))
+	t.emit(ChatCompletionOutputDTO{Event: EventGenerationThinking})
+	var timeToFirstToken time.Duration
+	var servedModel string
 	var fullResponse strings.Builder
+	finishReason := FinishStop
+	stopped := false
+	incompleteReason := ""
 	for {
 		response, err := resp.Recv()
 		if errors.Is(err, io.EOF) {
 			break
 		}
+		if err != nil && gen.stopped() {
+			stopped = true
+			break
+		}
+		timedOut := gen.timeoutErr()
+		if err != nil && timedOut != nil && fullResponse.Len() == 0 {
+			return nil, timedOut
+		}
+		if err != nil && timedOut != nil {
+			incompleteReason = timedOut.Error()
+			break
+		}
+		if err != nil && ctx.Err() == nil {
+			uc.Metrics.observeProviderError(labels, err)
+		}
+		if err != nil && fullResponse.Len() > 0 && ctx.Err() == nil {
+			// what the provider generated so far is kept rather than lost
+			incompleteReason = fmt.Sprintf("error streaming response: %s", err.Error())
+			break
+		}
 		if err != nil {
-			return nil, fmt.Errorf("error streaming response: %s", err.Error())
+			return nil, &entity.ProviderError{Op: "error streaming response", Err: err}
+		}
+		if timeToFirstToken == 0 {
+			gotFirstToken()
+			sawFirstToken()
+			timeToFirstToken = uc.Clock.Since(startedAt)
+			uc.Metrics.observeTimeToFirstToken(labels, timeToFirstToken)
+			logger.Debug("first token received", logging.Duration("time_to_first_token", timeToFirstToken))
+			// go-openai doesn't expose system_fingerprint yet, the served model
+			// snapshot is the closest signal of a backend change we get.
+			servedModel = response.Model
+			if changed, previous := chat.RecordFingerprint(servedModel); changed && chat.Config.Deterministic {
+				t.emit(ChatCompletionOutputDTO{
+					Event:   EventWarning,
+					Content: fmt.Sprintf("The model snapshot changed from %s to %s, answers may not be reproducible.", previous, response.Model),
+				})
+			}
+		}
+		if reason := response.Choices[0].FinishReason; reason != "" {
+			finishReason = string(reason)
+		}
+		delta := response.Choices[0].Delta.Content
+		chunk, ok := t.transform(ChatCompletionOutputDTO{
+			Event:   EventContent,
+			Content: fullResponse.String() + delta,
+			Delta:   delta,
+		})
+		if ok {
+			fullResponse.WriteString(chunk.Delta)
+			chunk.Content = fullResponse.String()
+			t.publish(chunk)
 		}
-		fullResponse.WriteString(response.Choices[0].Delta.Content)
-		r := ChatCompletionOutputDTO{
-			ChatID:  chat.ID,
-			UserID:  input.UserID,
+		if t.err != nil {
+			// nobody takes the answer, the provider stream is closed
+			return nil, t.err
+		}
+	}
+	// the rest of the turn is not the provider's, its summary and title wait
+	// for batch slots
+	stopTimeouts()
+	stopHeartbeat()
+	releaseSlot()
+	t.holdsSlot = false
+	streamSpan.SetAttributes(
+		attribute.Int64("time_to_first_token_ms", timeToFirstToken.Milliseconds()),
+		attribute.String("finish_reason", finishReason),
+		attribute.Bool("stopped", stopped))
+	if incompleteReason != "" {
+		tracing.RecordError(streamSpan, errors.New(incompleteReason))
+	}
+	streamSpan.End()
+	uc.Metrics.observeStreamDuration(labels, uc.Clock.Since(startedAt))
+	logger.Debug("provider stream ended",
+		logging.String("finish_reason", finishReason),
+		logging.Bool("stopped", stopped),
+		logging.String("incomplete_reason", incompleteReason),
+		logging.Duration("stream", uc.Clock.Since(startedAt)))
+	if stopped {
+		if fullResponse.Len() == 0 {
+			return nil, errors.New("generation stopped before any content")
+		}
+		finishReason = FinishCancelled
+		t.emit(ChatCompletionOutputDTO{
+			Event:   EventGenerationStopped,
 			Content: fullResponse.String(),
+		})
+	}
+	if incompleteReason != "" {
+		finishReason = FinishError
+		t.emit(ChatCompletionOutputDTO{
+			Event:            EventGenerationIncomplete,
+			Content:          fullResponse.String(),
+			IncompleteReason: incompleteReason,
+		})
+	}
+	assistant, err := entity.NewMessage(entity.RoleAssistant, fullResponse.String(), chat.Config.Model, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating assistant message: %w", err)
+	}
+	assistant.SetGenerationMetadata(promptTokens, timeToFirstToken, uc.Clock.Since(startedAt))
+	if incompleteReason != "" {
+		assistant.MarkIncomplete(incompleteReason)
+	}
+	finish := func(chat *entity.Chat) error {
+		chat.RecordFingerprint(servedModel)
+		err := chat.AddMessage(assistant, uc.Clock.Now())
+		if err != nil {
+			return fmt.Errorf("error adding new message: %w", err)
+		}
+		uc.applyPendingSummary(ctx, chat, t)
+		if outline {
+			chat.AwaitConfirmation()
+		}
+		return nil
+	}
+	if err := finish(chat); err != nil {
+		return nil, err
+	}
+	usage := newUsageChunk(chat.Config.Model, promptTokens, assistant.GetQtdTokens())
+	// in a unit of work the usage commits with the answer, so no answer saved
+	// goes unmetered
+	var meter func(ctx context.Context) error
+	if uc.UnitOfWork != nil && uc.UsageGateway != nil {
+		model := chat.Config.Model
+		meter = func(ctx context.Context) error {
+			return uc.meterUsage(ctx, req, model, usage.PromptTokens, usage.CompletionTokens)
 		}
-		uc.Stream <- r
 	}
-	assistent, err := entity.NewMessage("assistent", fullResponse.String(), chat.Config.Model)
+	saveStartedAt := uc.Clock.Now()
+	saveCtx, saveSpan := uc.Tracer.Start(ctx, "chat.persist")
+	chat, err = uc.save(saveCtx, chat, req, finish, meter)
+	tracing.RecordError(saveSpan, err)
+	saveSpan.End()
 	if err != nil {
-		return nil, fmt.Errorf("error creating assistent message: %s", err.Error())
+		return nil, fmt.Errorf("error saving chat: %w", err)
+	}
+	logger.Debug("chat saved", logging.Duration("save", uc.Clock.Since(saveStartedAt)))
+	if chat.Title == "" {
+		// best effort: the answer is already saved, a failed title is retried next turn
+		_ = uc.generateTitle(ctx, chat, req.titleModel)
+	}
+	uc.notifyCompleted(ctx, t, req, assistant, stopped)
+	t.emit(usage)
+	uc.Metrics.observeTokens(labels, usage.PromptTokens, usage.CompletionTokens)
+	if meter == nil {
+		uc.recordUsage(ctx, req, chat.Config.Model, usage.PromptTokens, usage.CompletionTokens)
+	}
+	output = &ChatCompletionOutputDTO{
+		ChatID:               chat.ID,
+		UserID:               req.userID,
+		TurnID:               t.id,
+		MessageID:            assistant.ID,
+		Content:              fullResponse.String(),
+		AwaitingConfirmation: chat.IsAwaitingConfirmation(),
+		Stopped:              stopped,
+		IncompleteReason:     incompleteReason,
+		FinishReason:         finishReason,
+		RequestID:            t.requestID,
+	}
+	output.setUsage(usage)
+	uc.audit(ctx, t, req, chat, assistant, output)
+	return output, nil
+}
+
+// newUsageChunk is the usage chunk of a turn of model, with the cost of its
+// tokens.
+func newUsageChunk(model *entity.Model, promptTokens, completionTokens int) ChatCompletionOutputDTO {
+	cost := model.Cost(promptTokens, completionTokens)
+	return ChatCompletionOutputDTO{
+		Event:            EventUsage,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+		EstimatedCost:    cost.Total(),
+		PromptCost:       cost.Prompt,
+		CompletionCost:   cost.Completion,
+		Priced:           cost.Priced,
+	}
+}
+
+// setUsage copies the tokens and costs of the usage chunk to o.
+func (o *ChatCompletionOutputDTO) setUsage(usage ChatCompletionOutputDTO) {
+	o.PromptTokens = usage.PromptTokens
+	o.CompletionTokens = usage.CompletionTokens
+	o.TotalTokens = usage.TotalTokens
+	o.EstimatedCost = usage.EstimatedCost
+	o.PromptCost = usage.PromptCost
+	o.CompletionCost = usage.CompletionCost
+	o.Priced = usage.Priced
+}
+
+func rateLimitKey(req completionRequest) string {
+	if req.orgID != "" {
+		return "org:" + req.orgID
 	}
-	err = chat.AddMessage(assistent)
+	return "user:" + req.userID
+}
+
+const maxSaveAttempts = 3
+
+// save persists the chat of a turn. When another request updated it meanwhile,
+// the chat is reloaded and the turn applied to it again through req.rebase and
+// finish, instead of overwriting that update.
+func (uc *ChatCompletionUseCase) save(ctx context.Context, chat *entity.Chat, req completionRequest, finish func(chat *entity.Chat) error, meter func(ctx context.Context) error) (*entity.Chat, error) {
+	for attempt := 1; ; attempt++ {
+		err := uc.inUnitOfWork(ctx, func(ctx context.Context) error {
+			var err error
+			if req.newChat {
+				err = uc.ChatGateway.CreateChat(ctx, chat)
+			} else {
+				err = uc.ChatGateway.SaveChat(ctx, chat)
+			}
+			if err != nil || meter == nil {
+				return err
+			}
+			return meter(ctx)
+		})
+		if !errors.Is(err, gateway.ErrChatConflict) || req.rebase == nil || attempt == maxSaveAttempts {
+			return chat, err
+		}
+		chat, err = uc.ChatGateway.FindChatByID(ctx, chat.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error reloading chat: %w", err)
+		}
+		if err := req.rebase(chat); err != nil {
+			return nil, err
+		}
+		if err := finish(chat); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (uc *ChatCompletionUseCase) inUnitOfWork(ctx context.Context, fn func(ctx context.Context) error) error {
+	if uc.UnitOfWork == nil {
+		return fn(ctx)
+	}
+	return uc.UnitOfWork.Do(ctx, fn)
+}
+
+// requestTemperature works around the omitempty on ChatCompletionRequest.Temperature,
+// which would make the API fall back to its default of 1 for a temperature of 0.
+func requestTemperature(temperature float32) float32 {
+	if temperature == 0 {
+		return math.SmallestNonzeroFloat32
+	}
+	return temperature
+}
+
+func redactionNotices(secrets []string) []string {
+	if len(secrets) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("Your message looked like it contained secrets (%s). They were redacted before being sent or stored.", strings.Join(secrets, ", "))}
+}
+
+func (uc *ChatCompletionUseCase) createNewChat(ctx context.Context, input ChatCompletionInputDTO) (*entity.Chat, error) {
+	var chatConfig *entity.ChatConfig
+	var systemMessage string
+	if input.PersonaID != "" {
+		if uc.PersonaGateway == nil {
+			return nil, errors.New("personas are not enabled")
+		}
+		persona, err := uc.PersonaGateway.FindPersonaByID(ctx, input.PersonaID)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching persona: %w", err)
+		}
+		if len(persona.Tools) > 0 && !uc.isEnabled(ctx, featureflag.FlagTools, input) {
+			return nil, errors.New("persona tools are not enabled")
+		}
+		chatConfig = persona.NewChatConfig()
+		systemMessage = persona.SystemPrompt
+	} else {
+		chatConfig = &entity.ChatConfig{
+			Temperature:      input.Config.Temperature,
+			TopP:             input.Config.TopP,
+			N:                input.Config.N,
+			Stop:             input.Config.Stop,
+			MaxTokens:        input.Config.MaxTokens,
+			PresencePenalty:  input.Config.PresencePenalty,
+			FrequencyPenalty: input.Config.FrequencyPenalty,
+			Model:            entity.NewModel(input.Config.Model, input.Config.ModelMaxToken),
+		}
+		var err error
+		systemMessage, err = uc.initialSystemMessage(ctx, input.Config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if input.Config.AnswerMode == "outline_first" && !uc.isEnabled(ctx, featureflag.FlagOutlineFirst, input) {
+		return nil, errors.New("answer mode outline_first is not enabled")
+	}
+	if input.Config.EvictionPolicy == entity.EvictionSummarize && !uc.isEnabled(ctx, featureflag.FlagSummaryEviction, input) {
+		return nil, errors.New("eviction policy summarize is not enabled")
+	}
+	chatConfig.AnswerMode = input.Config.AnswerMode
+	chatConfig.MaxMessages = input.Config.MaxMessages
+	chatConfig.EvictionPolicy = input.Config.EvictionPolicy
+	if input.Config.Deterministic {
+		chatConfig.ApplyDeterministicProfile()
+	}
+	now := uc.Clock.Now()
+	initialMessage, err := entity.NewMessage(entity.RoleSystem, systemMessage, chatConfig.Model, now)
 	if err != nil {
-		return nil, fmt.Errorf("error adding new message: %s", err.Error())
+		return nil, fmt.Errorf("error creating initial message: %w", err)
 	}
-	err = uc.ChatGateway.SaveChat(ctx, chat)
+	chat, err := entity.NewChat(input.UserID, initialMessage, chatConfig, now)
 	if err != nil {
-		return nil, fmt.Errorf("error saving chat: %s", err.Error())
-	}
-	return &ChatCompletionOutputDTO{
-		ChatID:  chat.ID,
-		UserID:  input.UserID,
-		Content: fullResponse.String(),
-	}, nil
-}
-
-func createNewChat(input ChatCompletionInputDTO) (*entity.Chat, error) {
-	model := entity.NewModel(input.Config.Model, input.Config.ModelMaxToken)
-	chatConfig := &entity.ChatConfig{
-		Temperature:      input.Config.Temperature,
-		TopP:             input.Config.TopP,
-		N:                input.Config.N,
-		Stop:             input.Config.Stop,
-		MaxTokens:        input.Config.MaxTokens,
-		PresencePenalty:  input.Config.PresencePenalty,
-		FrequencyPenalty: input.Config.FrequencyPenalty,
-		Model:            model,
-	}
-	initialMessage, err := entity.NewMessage("system", input.Config.InitialSystemMessage, model)
+		return nil, fmt.Errorf("error creating new chat: %w", err)
+	}
+	chat.PersonaID = input.PersonaID
+	chat.OrgID = input.OrgID
+	if input.Config.EphemeralTTL > 0 {
+		if err := chat.MakeEphemeral(now, input.Config.EphemeralTTL); err != nil {
+			return nil, fmt.Errorf("error creating new chat: %w", err)
+		}
+	}
+	return chat, nil
+}
+
+func (uc *ChatCompletionUseCase) isEnabled(ctx context.Context, flag string, input ChatCompletionInputDTO) bool {
+	if uc.FeatureFlags == nil {
+		return true
+	}
+	return uc.FeatureFlags.IsEnabled(ctx, flag, featureflag.Target{OrgID: input.OrgID, UserID: input.UserID})
+}
+
+func (uc *ChatCompletionUseCase) initialSystemMessage(ctx context.Context, config ChatCompletionConfigInputDTO) (string, error) {
+	if config.SystemPromptTemplate == "" {
+		return config.InitialSystemMessage, nil
+	}
+	if uc.PromptTemplateGateway == nil {
+		return "", errors.New("prompt templates are not enabled")
+	}
+	template, err := uc.PromptTemplateGateway.FindPromptTemplateByName(ctx, config.SystemPromptTemplate)
 	if err != nil {
-		return nil, fmt.Errorf("error creating initial message: %s", err.Error())
+		return "", fmt.Errorf("error fetching prompt template: %w", err)
 	}
-	chat, err := entity.NewChat(input.UserID, initialMessage, chatConfig)
+	content, err := template.Render(config.TemplateVariables, uc.Clock.Now())
 	if err != nil {
-		return nil, fmt.Errorf("error creating new chat: %s", err.Error())
+		return "", fmt.Errorf("error rendering prompt template: %w", err)
 	}
-	return chat, nil
+	return content, nil
 }
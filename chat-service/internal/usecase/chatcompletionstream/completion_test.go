@@ -0,0 +1,167 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+// contents are the contents of the messages of chat, its system message
+// aside.
+func contents(chat *entity.Chat) []string {
+	var contents []string
+	for _, m := range chat.Messages {
+		if m.Role != entity.RoleSystem {
+			contents = append(contents, m.Content)
+		}
+	}
+	return contents
+}
+
+func TestSaveRebasesOnConflict(t *testing.T) {
+	tests := []struct {
+		name string
+		// history is the chat when the turn loaded it
+		history []string
+		// meanwhile is the write of another request the save conflicts with
+		meanwhile func(t *testing.T, chat *entity.Chat)
+		// turn applies the turn to chat before its answer is added, it
+		// returns the rebase of the turn
+		turn      func(t *testing.T, clk clock.Clock, chat *entity.Chat) func(chat *entity.Chat) error
+		conflicts int
+		want      []string
+		wantTags  []string
+		wantSaves int
+		wantErr   bool
+	}{
+		{
+			name:    "message added after the turn of another request",
+			history: []string{"first question", "first answer"},
+			meanwhile: func(t *testing.T, chat *entity.Chat) {
+				addMessage(t, chat, entity.RoleUser, "other question")
+				addMessage(t, chat, entity.RoleAssistant, "other answer")
+			},
+			turn: func(t *testing.T, clk clock.Clock, chat *entity.Chat) func(chat *entity.Chat) error {
+				return rebaseUserMessage(clk, addMessage(t, chat, entity.RoleUser, "question"))
+			},
+			conflicts: 1,
+			want:      []string{"first question", "first answer", "other question", "other answer", "question", "answer"},
+			wantSaves: 2,
+		},
+		{
+			name:    "answer regenerated in the chat tagged meanwhile",
+			history: []string{"question", "old answer"},
+			meanwhile: func(t *testing.T, chat *entity.Chat) {
+				if _, err := chat.AddTag("work"); err != nil {
+					t.Fatalf("AddTag(): %v", err)
+				}
+			},
+			turn: func(t *testing.T, clk clock.Clock, chat *entity.Chat) func(chat *entity.Chat) error {
+				discarded, err := chat.DiscardLastAnswer(clk.Now())
+				if err != nil {
+					t.Fatalf("DiscardLastAnswer(): %v", err)
+				}
+				return rebaseRegeneration(clk, discarded)
+			},
+			conflicts: 1,
+			want:      []string{"question", "answer"},
+			wantTags:  []string{"work"},
+			wantSaves: 2,
+		},
+		{
+			name:    "regeneration of an answer another turn followed",
+			history: []string{"question", "old answer"},
+			meanwhile: func(t *testing.T, chat *entity.Chat) {
+				addMessage(t, chat, entity.RoleUser, "other question")
+			},
+			turn: func(t *testing.T, clk clock.Clock, chat *entity.Chat) func(chat *entity.Chat) error {
+				discarded, err := chat.DiscardLastAnswer(clk.Now())
+				if err != nil {
+					t.Fatalf("DiscardLastAnswer(): %v", err)
+				}
+				return rebaseRegeneration(clk, discarded)
+			},
+			conflicts: 1,
+			want:      []string{"question", "old answer", "other question"},
+			wantSaves: 1,
+			wantErr:   true,
+		},
+		{
+			name:    "message edited in the chat answered meanwhile",
+			history: []string{"first question", "first answer", "question"},
+			meanwhile: func(t *testing.T, chat *entity.Chat) {
+				addMessage(t, chat, entity.RoleAssistant, "other answer")
+			},
+			turn: func(t *testing.T, clk clock.Clock, chat *entity.Chat) func(chat *entity.Chat) error {
+				edited := chat.Messages[len(chat.Messages)-1].ID
+				m, err := entity.NewMessage(entity.RoleUser, "edited question", model, clk.Now())
+				if err != nil {
+					t.Fatalf("NewMessage(): %v", err)
+				}
+				if err := chat.ReplaceMessage(edited, m, clk.Now()); err != nil {
+					t.Fatalf("ReplaceMessage(): %v", err)
+				}
+				return rebaseEdit(clk, edited, m)
+			},
+			conflicts: 1,
+			want:      []string{"first question", "first answer", "edited question", "answer"},
+			wantSaves: 2,
+		},
+		{
+			name:    "chat written by another request on every attempt",
+			history: []string{"first question", "first answer"},
+			meanwhile: func(t *testing.T, chat *entity.Chat) {
+				addMessage(t, chat, entity.RoleUser, "other question")
+			},
+			turn: func(t *testing.T, clk clock.Clock, chat *entity.Chat) func(chat *entity.Chat) error {
+				return rebaseUserMessage(clk, addMessage(t, chat, entity.RoleUser, "question"))
+			},
+			conflicts: maxSaveAttempts,
+			want:      []string{"first question", "first answer", "other question"},
+			wantSaves: maxSaveAttempts,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded := newTestChat(t, tt.history...)
+			stored := copyChat(loaded)
+			tt.meanwhile(t, stored)
+			chats := &chatStore{chat: stored, conflicts: tt.conflicts}
+			uc := newTestUseCase(chats, nil)
+
+			rebase := tt.turn(t, uc.Clock, loaded)
+			answer, err := entity.NewMessage(entity.RoleAssistant, "answer", model, start)
+			if err != nil {
+				t.Fatalf("NewMessage(): %v", err)
+			}
+			finish := func(chat *entity.Chat) error {
+				return chat.AddMessage(answer, start)
+			}
+			if err := finish(loaded); err != nil {
+				t.Fatalf("finish(): %v", err)
+			}
+			_, err = uc.save(context.Background(), loaded, completionRequest{userID: "user-1", rebase: rebase}, finish, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("save() = %v, want an error %v", err, tt.wantErr)
+			}
+			if tt.conflicts == maxSaveAttempts && !errors.Is(err, gateway.ErrChatConflict) {
+				t.Fatalf("save() = %v, want ErrChatConflict", err)
+			}
+			if chats.saves != tt.wantSaves {
+				t.Errorf("%d saves, want %d", chats.saves, tt.wantSaves)
+			}
+			if got := contents(chats.chat); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chat stored = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(chats.chat.Tags, tt.wantTags) {
+				t.Errorf("tags stored = %q, want %q", chats.chat.Tags, tt.wantTags)
+			}
+		})
+	}
+}
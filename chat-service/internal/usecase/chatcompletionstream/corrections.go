@@ -0,0 +1,77 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+const maxInjectedCorrections = 10
+
+// correctionsPrompt builds the system message listing the corrections relevant
+// to the next answer: every correction made in the chat, plus the user-scoped
+// ones sharing a keyword with the last user message.
+func (uc *ChatCompletionUseCase) correctionsPrompt(ctx context.Context, chat *entity.Chat) (string, error) {
+	if uc.CorrectionGateway == nil {
+		return "", nil
+	}
+	corrections, err := uc.CorrectionGateway.FindCorrectionsByChatID(ctx, chat.ID, maxInjectedCorrections)
+	if err != nil {
+		return "", fmt.Errorf("error fetching chat corrections: %w", err)
+	}
+	userCorrections, err := uc.CorrectionGateway.FindUserCorrections(ctx, chat.UserID, maxInjectedCorrections)
+	if err != nil {
+		return "", fmt.Errorf("error fetching user corrections: %w", err)
+	}
+	keywords := keywordsOf(lastUserMessage(chat))
+	for _, c := range userCorrections {
+		if len(corrections) >= maxInjectedCorrections {
+			break
+		}
+		if c.ChatID != chat.ID && sharesKeyword(keywords, c.Statement+" "+c.Correction) {
+			corrections = append(corrections, c)
+		}
+	}
+	if len(corrections) == 0 {
+		return "", nil
+	}
+	var prompt strings.Builder
+	prompt.WriteString("The user corrected earlier answers. Do not repeat these mistakes:\n")
+	for _, c := range corrections {
+		prompt.WriteString(fmt.Sprintf("- Wrong: %q. Correct: %q\n", c.Statement, c.Correction))
+	}
+	return prompt.String(), nil
+}
+
+func lastUserMessage(chat *entity.Chat) string {
+	for i := len(chat.Messages) - 1; i >= 0; i-- {
+		if chat.Messages[i].Role == entity.RoleUser {
+			return chat.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+func keywordsOf(text string) map[string]bool {
+	keywords := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) >= 4 {
+			keywords[word] = true
+		}
+	}
+	return keywords
+}
+
+func sharesKeyword(keywords map[string]bool, text string) bool {
+	for word := range keywordsOf(text) {
+		if keywords[word] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/redact"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/requestid"
+)
+
+type EditMessageInputDTO struct {
+	ChatID      string
+	UserID      string
+	MessageID   string
+	UserMessage string
+	Tier        string
+	TitleModel  string
+}
+
+// EditMessageUseCase replaces a user message of an existing chat and regenerates
+// the answer from that point, streaming it like ChatCompletionUseCase does.
+type EditMessageUseCase struct {
+	*ChatCompletionUseCase
+}
+
+func NewEditMessageUseCase(completion *ChatCompletionUseCase) *EditMessageUseCase {
+	return &EditMessageUseCase{
+		ChatCompletionUseCase: completion,
+	}
+}
+
+func (uc *EditMessageUseCase) Execute(ctx context.Context, input EditMessageInputDTO) (output *ChatCompletionOutputDTO, err error) {
+	ctx = requestid.Ensure(ctx)
+	defer func() { err = requestid.Wrap(ctx, err) }()
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	content, secrets := redact.Secrets(input.UserMessage)
+	userMessage, err := entity.NewMessage(entity.RoleUser, content, chat.Config.Model, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating user message: %w", err)
+	}
+	err = chat.ReplaceMessage(input.MessageID, userMessage, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error replacing message: %w", err)
+	}
+	return uc.complete(ctx, chat, completionRequest{
+		userID:     input.UserID,
+		tier:       input.Tier,
+		titleModel: input.TitleModel,
+		notices:    redactionNotices(secrets),
+		rebase:     rebaseEdit(uc.Clock, input.MessageID, userMessage),
+	})
+}
+
+// rebaseEdit replaces the message of messageID again in the chat reloaded
+// after a conflict.
+func rebaseEdit(clk clock.Clock, messageID string, userMessage *entity.Message) func(chat *entity.Chat) error {
+	return func(chat *entity.Chat) error {
+		return chat.ReplaceMessage(messageID, userMessage, clk.Now())
+	}
+}
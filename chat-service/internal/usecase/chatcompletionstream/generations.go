@@ -0,0 +1,205 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+// ErrShuttingDown is returned for the turns started once the instance began
+// to shut down.
+var ErrShuttingDown = errors.New("service is shutting down")
+
+// ErrGenerationNotFound is returned for stopping a chat generating nothing on
+// this instance.
+var ErrGenerationNotFound = errors.New("generation not found")
+
+// Generations tracks the generations in flight of this instance by chat, so
+// they can be stopped by another request, or drained on shutdown.
+type Generations struct {
+	mu       sync.Mutex
+	byChat   map[string][]*generation
+	inFlight int
+	draining bool
+	// idle is closed once draining and no generation is left
+	idle chan struct{}
+}
+
+func NewGenerations() *Generations {
+	return &Generations{byChat: map[string][]*generation{}}
+}
+
+type generation struct {
+	userID   string
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	halted   bool
+	timedOut *TimeoutError
+}
+
+// stopped tells whether the generation was stopped, rather than its context
+// ending with the request.
+func (g *generation) stopped() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.halted
+}
+
+func (g *generation) stop() {
+	g.mu.Lock()
+	g.halted = true
+	g.mu.Unlock()
+	g.cancel()
+}
+
+// timeout ends the generation with err, unless it was stopped already.
+func (g *generation) timeout(err *TimeoutError) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.halted || g.timedOut != nil {
+		return false
+	}
+	g.timedOut = err
+	g.cancel()
+	return true
+}
+
+// timeoutErr is the timeout that ended the generation, nil if none did.
+func (g *generation) timeoutErr() *TimeoutError {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.timedOut
+}
+
+// start registers a generation of chatID, it runs on the returned context
+// until the returned func untracks it. A nil Generations tracks nothing.
+// Once draining, no generation starts and ErrShuttingDown is returned.
+func (gs *Generations) start(ctx context.Context, chatID, userID string) (context.Context, *generation, func(), error) {
+	if gs == nil {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, &generation{userID: userID, cancel: cancel}, cancel, nil
+	}
+	gs.mu.Lock()
+	if gs.draining {
+		gs.mu.Unlock()
+		return nil, nil, nil, ErrShuttingDown
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	g := &generation{userID: userID, cancel: cancel}
+	gs.byChat[chatID] = append(gs.byChat[chatID], g)
+	gs.inFlight++
+	gs.mu.Unlock()
+	return ctx, g, func() {
+		cancel()
+		gs.mu.Lock()
+		defer gs.mu.Unlock()
+		gs.inFlight--
+		if gs.draining && gs.inFlight == 0 {
+			close(gs.idle)
+		}
+		running := gs.byChat[chatID]
+		for i := range running {
+			if running[i] == g {
+				running = append(running[:i:i], running[i+1:]...)
+				break
+			}
+		}
+		if len(running) == 0 {
+			delete(gs.byChat, chatID)
+		} else {
+			gs.byChat[chatID] = running
+		}
+	}, nil
+}
+
+// Stop stops the generations of chatID userID started, without waiting for
+// them to end.
+func (gs *Generations) Stop(chatID, userID string) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	running := gs.byChat[chatID]
+	if len(running) == 0 {
+		return ErrGenerationNotFound
+	}
+	for _, g := range running {
+		if g.userID != userID {
+			return entity.ErrChatForbidden
+		}
+	}
+	for _, g := range running {
+		g.stop()
+	}
+	return nil
+}
+
+// Drain stops new generations from starting and waits for those in flight to
+// end. The ones still running when ctx ends are stopped, so they save the
+// content streamed so far, and Drain returns an error telling how many; Wait
+// waits for them to be saved.
+func (gs *Generations) Drain(ctx context.Context) error {
+	idle := gs.beginDrain()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+	}
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	stopped := 0
+	for _, running := range gs.byChat {
+		for _, g := range running {
+			g.stop()
+			stopped++
+		}
+	}
+	if stopped == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d generations were stopped by the shutdown", stopped)
+}
+
+// Wait waits for the generations in flight of a draining Generations to end,
+// or for ctx to end.
+func (gs *Generations) Wait(ctx context.Context) error {
+	select {
+	case <-gs.beginDrain():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GenerationStats are the generations in flight of an instance, and the
+// chats they run in.
+type GenerationStats struct {
+	InFlight int  `json:"in_flight"`
+	Chats    int  `json:"chats"`
+	Draining bool `json:"draining"`
+}
+
+// Stats returns the generations in flight right now.
+func (gs *Generations) Stats() GenerationStats {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	return GenerationStats{
+		InFlight: gs.inFlight,
+		Chats:    len(gs.byChat),
+		Draining: gs.draining,
+	}
+}
+
+func (gs *Generations) beginDrain() <-chan struct{} {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if !gs.draining {
+		gs.draining = true
+		gs.idle = make(chan struct{})
+		if gs.inFlight == 0 {
+			close(gs.idle)
+		}
+	}
+	return gs.idle
+}
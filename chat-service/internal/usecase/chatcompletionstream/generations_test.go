@@ -0,0 +1,172 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+type running struct {
+	chatID string
+	userID string
+}
+
+// startAll starts a generation of each of gens on gs, it returns their
+// contexts and the funcs untracking them.
+func startAll(t *testing.T, gs *Generations, gens []running) ([]context.Context, []func()) {
+	t.Helper()
+	ctxs := make([]context.Context, len(gens))
+	dones := make([]func(), len(gens))
+	for i, g := range gens {
+		ctx, _, done, err := gs.start(context.Background(), g.chatID, g.userID)
+		if err != nil {
+			t.Fatalf("start() of generation %d: %v", i, err)
+		}
+		ctxs[i], dones[i] = ctx, done
+	}
+	return ctxs, dones
+}
+
+func TestGenerationsStop(t *testing.T) {
+	tests := []struct {
+		name    string
+		running []running
+		stop    running
+		wantErr error
+		// wantStopped are the generations of running stopped
+		wantStopped []bool
+	}{
+		{
+			name:    "nothing generating in the chat",
+			running: []running{{"chat-2", "user-1"}},
+			stop:    running{"chat-1", "user-1"},
+			wantErr: ErrGenerationNotFound,
+			// the generations of the other chats keep running
+			wantStopped: []bool{false},
+		},
+		{
+			name:        "generation of another user",
+			running:     []running{{"chat-1", "user-2"}},
+			stop:        running{"chat-1", "user-1"},
+			wantErr:     entity.ErrChatForbidden,
+			wantStopped: []bool{false},
+		},
+		{
+			name:        "every generation of the chat",
+			running:     []running{{"chat-1", "user-1"}, {"chat-2", "user-1"}, {"chat-1", "user-1"}},
+			stop:        running{"chat-1", "user-1"},
+			wantStopped: []bool{true, false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := NewGenerations()
+			ctxs, dones := startAll(t, gs, tt.running)
+			defer func() {
+				for _, done := range dones {
+					done()
+				}
+			}()
+			if err := gs.Stop(tt.stop.chatID, tt.stop.userID); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Stop() = %v, want %v", err, tt.wantErr)
+			}
+			for i, ctx := range ctxs {
+				if stopped := ctx.Err() != nil; stopped != tt.wantStopped[i] {
+					t.Errorf("generation %d stopped = %v, want %v", i, stopped, tt.wantStopped[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGenerationsStopTellsStoppedFromRequestEnd(t *testing.T) {
+	gs := NewGenerations()
+	requestCtx, cancel := context.WithCancel(context.Background())
+	_, ended, doneEnded, err := gs.start(requestCtx, "chat-1", "user-1")
+	if err != nil {
+		t.Fatalf("start(): %v", err)
+	}
+	defer doneEnded()
+	_, stopped, doneStopped, err := gs.start(context.Background(), "chat-2", "user-1")
+	if err != nil {
+		t.Fatalf("start(): %v", err)
+	}
+	defer doneStopped()
+
+	cancel()
+	if err := gs.Stop("chat-2", "user-1"); err != nil {
+		t.Fatalf("Stop(): %v", err)
+	}
+	if ended.stopped() {
+		t.Error("stopped() = true for the generation of a request that ended")
+	}
+	if !stopped.stopped() {
+		t.Error("stopped() = false for the generation stopped")
+	}
+}
+
+func TestGenerationsDrain(t *testing.T) {
+	tests := []struct {
+		name string
+		// ending are the generations in flight that end while draining,
+		// before the deadline, the others run past it
+		ending  []bool
+		wantErr bool
+	}{
+		{name: "nothing in flight", ending: nil},
+		{name: "generations ending in time", ending: []bool{true, true}},
+		{name: "generations running past the deadline", ending: []bool{true, false, false}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := NewGenerations()
+			gens := make([]running, len(tt.ending))
+			for i := range gens {
+				gens[i] = running{chatID: "chat-1", userID: "user-1"}
+			}
+			ctxs, dones := startAll(t, gs, gens)
+			for i, ending := range tt.ending {
+				if ending {
+					done := dones[i]
+					go func() {
+						time.Sleep(10 * time.Millisecond)
+						done()
+					}()
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			err := gs.Drain(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Drain() = %v, want an error %v", err, tt.wantErr)
+			}
+			if _, _, _, err := gs.start(context.Background(), "chat-2", "user-1"); !errors.Is(err, ErrShuttingDown) {
+				t.Fatalf("start() while draining = %v, want ErrShuttingDown", err)
+			}
+			for i, ending := range tt.ending {
+				if !ending && ctxs[i].Err() == nil {
+					t.Errorf("generation %d running past the deadline was not stopped", i)
+				}
+			}
+
+			// the stopped generations save what they streamed, then end
+			for i, ending := range tt.ending {
+				if !ending {
+					dones[i]()
+				}
+			}
+			waitCtx, cancelWait := context.WithTimeout(context.Background(), time.Second)
+			defer cancelWait()
+			if err := gs.Wait(waitCtx); err != nil {
+				t.Fatalf("Wait() = %v once every generation ended", err)
+			}
+			if stats := gs.Stats(); stats.InFlight != 0 || stats.Chats != 0 || !stats.Draining {
+				t.Fatalf("Stats() = %+v once drained, want none in flight and draining", stats)
+			}
+		})
+	}
+}
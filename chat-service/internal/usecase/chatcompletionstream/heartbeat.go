@@ -0,0 +1,59 @@
+package chatcompletionstream
+
+import (
+	"sync"
+	"time"
+)
+
+// heartbeat sends a heartbeat chunk at every interval the turn went without
+// a chunk, until stop is called. No interval sends none.
+func (t *turn) heartbeat(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+	ticker := t.uc.Clock.NewTicker(interval)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-ticker.C():
+				t.beat(interval)
+			case <-done:
+				return
+			case <-t.ctx.Done():
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			ticker.Stop()
+			<-exited
+		})
+	}
+}
+
+// beat sends a heartbeat when the last chunk is interval old, it never waits
+// for the stream.
+func (t *turn) beat(interval time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil || t.uc.Clock.Since(t.lastEmit) < interval {
+		return
+	}
+	select {
+	case t.uc.Stream <- ChatCompletionOutputDTO{
+		ChatID:      t.chatID,
+		UserID:      t.userID,
+		Event:       EventHeartbeat,
+		TurnID:      t.id,
+		ResumeToken: t.resumeToken,
+		RequestID:   t.requestID,
+	}:
+	default:
+	}
+}
@@ -0,0 +1,43 @@
+package chatcompletionstream
+
+import "context"
+
+// StreamHook observes and transforms the chunks of the turns before they are
+// numbered and streamed, the heartbeats aside. OnChunk returns the chunk to
+// stream in place of chunk, or false to drop it. The Deltas the hooks return
+// for the content chunks are what the answer saved is made of, a dropped one
+// is left out, and their Content is set from them after the hooks ran.
+type StreamHook interface {
+	OnChunk(ctx context.Context, chunk ChatCompletionOutputDTO) (ChatCompletionOutputDTO, bool)
+}
+
+type StreamHookFunc func(ctx context.Context, chunk ChatCompletionOutputDTO) (ChatCompletionOutputDTO, bool)
+
+func (f StreamHookFunc) OnChunk(ctx context.Context, chunk ChatCompletionOutputDTO) (ChatCompletionOutputDTO, bool) {
+	return f(ctx, chunk)
+}
+
+// WithStreamHooks runs hooks on every chunk, in order, after the hooks
+// already registered.
+func WithStreamHooks(hooks ...StreamHook) Option {
+	return func(uc *ChatCompletionUseCase) {
+		uc.StreamHooks = append(uc.StreamHooks, hooks...)
+	}
+}
+
+// transform runs the stream hooks of the usecase on r, it returns false when
+// one of them dropped it.
+func (t *turn) transform(r ChatCompletionOutputDTO) (ChatCompletionOutputDTO, bool) {
+	r.ChatID = t.chatID
+	r.UserID = t.userID
+	r.TurnID = t.id
+	r.ResumeToken = t.resumeToken
+	r.RequestID = t.requestID
+	for _, hook := range t.uc.StreamHooks {
+		var ok bool
+		if r, ok = hook.OnChunk(t.ctx, r); !ok {
+			return r, false
+		}
+	}
+	return r, true
+}
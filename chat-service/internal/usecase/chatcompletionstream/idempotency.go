@@ -0,0 +1,144 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/requestid"
+)
+
+const (
+	// idempotencyKeyTTL is how long a key replays its answer.
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyPendingTimeout is how long a key waits for its answer, an
+	// older pending key is of a request that died and goes to the next one.
+	idempotencyPendingTimeout = 10 * time.Minute
+	// idempotencySettleTimeout bounds settling a key once its turn is over.
+	idempotencySettleTimeout = 5 * time.Second
+)
+
+var (
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is in progress")
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used for another request")
+)
+
+// claimIdempotencyKey reserves the idempotency key of input for this
+// request. When an earlier request with the key was answered it returns the
+// output of that answer instead, nothing is to be generated.
+func (uc *ChatCompletionUseCase) claimIdempotencyKey(ctx context.Context, input ChatCompletionInputDTO) (*entity.IdempotencyKey, *ChatCompletionOutputDTO, error) {
+	now := uc.Clock.Now()
+	key, err := entity.NewIdempotencyKey(input.IdempotencyKey, input.UserID, input.ChatID, input.UserMessage, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	// a second attempt follows a stale key being removed
+	for attempt := 1; attempt <= 2; attempt++ {
+		err := uc.IdempotencyKeyGateway.CreateIdempotencyKey(ctx, key)
+		if err == nil {
+			return key, nil, nil
+		}
+		if !errors.Is(err, gateway.ErrIdempotencyKeyExists) {
+			return nil, nil, fmt.Errorf("error saving idempotency key: %w", err)
+		}
+		existing, err := uc.IdempotencyKeyGateway.FindIdempotencyKey(ctx, input.UserID, input.IdempotencyKey)
+		if errors.Is(err, gateway.ErrIdempotencyKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error fetching idempotency key: %w", err)
+		}
+		age := now.Sub(existing.CreatedAt)
+		if age > idempotencyKeyTTL || (!existing.IsCompleted() && age > idempotencyPendingTimeout) {
+			if err := uc.IdempotencyKeyGateway.DeleteIdempotencyKey(ctx, input.UserID, input.IdempotencyKey); err != nil {
+				return nil, nil, fmt.Errorf("error deleting idempotency key: %w", err)
+			}
+			continue
+		}
+		if existing.RequestHash != key.RequestHash {
+			return nil, nil, ErrIdempotencyKeyReused
+		}
+		if !existing.IsCompleted() {
+			return nil, nil, ErrIdempotencyKeyInProgress
+		}
+		output, err := uc.replay(ctx, existing)
+		return nil, output, err
+	}
+	return nil, nil, ErrIdempotencyKeyInProgress
+}
+
+// settleIdempotencyKey records the answer of key once the turn succeeded,
+// and releases key when it failed so the request can be retried. It runs
+// detached from the cancelation of ctx, canceled by then when the client
+// timed out and is about to retry. Both are best effort: a key left pending
+// is released after idempotencyPendingTimeout.
+func (uc *ChatCompletionUseCase) settleIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey, output *ChatCompletionOutputDTO, err error) {
+	ctx, cancel := context.WithTimeout(detachedContext{ctx}, idempotencySettleTimeout)
+	defer cancel()
+	if err != nil {
+		_ = uc.IdempotencyKeyGateway.DeleteIdempotencyKey(ctx, key.UserID, key.Key)
+		return
+	}
+	key.Complete(output.ChatID, output.MessageID)
+	_ = uc.IdempotencyKeyGateway.CompleteIdempotencyKey(ctx, key)
+}
+
+// detachedContext has the values of its parent, its namespace and request
+// ID, without its deadline and cancelation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// replay streams the answer of key again, in a single content chunk, and
+// rebuilds its output from its chat.
+func (uc *ChatCompletionUseCase) replay(ctx context.Context, key *entity.IdempotencyKey) (output *ChatCompletionOutputDTO, err error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, key.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat of idempotency key: %w", err)
+	}
+	answer, err := chat.FindMessage(key.MessageID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching answer of idempotency key: %w", err)
+	}
+	messages := chat.GetMessages()
+	last := len(messages) > 0 && messages[len(messages)-1].ID == answer.ID
+	t := uc.newTurn(ctx, chat.ID, key.UserID)
+	defer func() { t.finish(output, err) }()
+	finishReason := FinishStop
+	if answer.IsIncomplete() {
+		finishReason = FinishError
+	}
+	output = &ChatCompletionOutputDTO{
+		ChatID:               chat.ID,
+		UserID:               key.UserID,
+		TurnID:               t.id,
+		MessageID:            answer.ID,
+		Content:              answer.Content,
+		IncompleteReason:     answer.IncompleteReason,
+		FinishReason:         finishReason,
+		AwaitingConfirmation: last && chat.IsAwaitingConfirmation(),
+		RequestID:            requestid.FromContext(ctx),
+		Replayed:             true,
+	}
+	usage := newUsageChunk(chat.Config.Model, answer.PromptTokens, answer.GetQtdTokens())
+	output.setUsage(usage)
+	t.emit(ChatCompletionOutputDTO{Event: EventGenerationStarted})
+	t.emit(ChatCompletionOutputDTO{
+		Event:   EventContent,
+		Content: output.Content,
+		Delta:   output.Content,
+	})
+	t.emit(usage)
+	return output, nil
+}
@@ -0,0 +1,294 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+var (
+	start = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	model = entity.NewModel("gpt-3.5-turbo", 4096)
+)
+
+// newTestChat returns a chat of user-1 with a message of each of contents,
+// of the user and the assistant in turn.
+func newTestChat(t *testing.T, contents ...string) *entity.Chat {
+	t.Helper()
+	system, err := entity.NewMessage(entity.RoleSystem, "You are a helpful assistant.", model, start)
+	if err != nil {
+		t.Fatalf("NewMessage(): %v", err)
+	}
+	chat, err := entity.NewChat("user-1", system, &entity.ChatConfig{Model: model, Temperature: 0.5, N: 1, MaxTokens: 256}, start)
+	if err != nil {
+		t.Fatalf("NewChat(): %v", err)
+	}
+	for i, content := range contents {
+		role := entity.RoleUser
+		if i%2 == 1 {
+			role = entity.RoleAssistant
+		}
+		addMessage(t, chat, role, content)
+	}
+	return chat
+}
+
+func addMessage(t *testing.T, chat *entity.Chat, role entity.Role, content string) *entity.Message {
+	t.Helper()
+	m, err := entity.NewMessage(role, content, model, start)
+	if err != nil {
+		t.Fatalf("NewMessage(): %v", err)
+	}
+	if err := chat.AddMessage(m, start); err != nil {
+		t.Fatalf("AddMessage(): %v", err)
+	}
+	return m
+}
+
+// copyChat is chat as another load of it would return it.
+func copyChat(chat *entity.Chat) *entity.Chat {
+	loaded := *chat
+	loaded.Messages = append([]*entity.Message(nil), chat.Messages...)
+	return &loaded
+}
+
+// chatStore is a ChatGateway holding a single chat. Its SaveChat fails with
+// ErrChatConflict the first conflicts times, as if another request wrote
+// the chat in between.
+type chatStore struct {
+	gateway.ChatGateway
+	chat      *entity.Chat
+	conflicts int
+	saves     int
+}
+
+func (s *chatStore) FindChatByID(ctx context.Context, chatID string) (*entity.Chat, error) {
+	if s.chat == nil || s.chat.ID != chatID {
+		return nil, gateway.ErrChatNotFound
+	}
+	return copyChat(s.chat), nil
+}
+
+func (s *chatStore) SaveChat(ctx context.Context, chat *entity.Chat) error {
+	s.saves++
+	if s.saves <= s.conflicts {
+		return gateway.ErrChatConflict
+	}
+	s.chat = copyChat(chat)
+	return nil
+}
+
+type keyStore struct {
+	keys map[string]*entity.IdempotencyKey
+	// ctx is the context of the last call, ctxErr its error during the call
+	ctx    context.Context
+	ctxErr error
+}
+
+func newKeyStore(keys ...*entity.IdempotencyKey) *keyStore {
+	s := &keyStore{keys: map[string]*entity.IdempotencyKey{}}
+	for _, k := range keys {
+		s.keys[k.UserID+"/"+k.Key] = k
+	}
+	return s
+}
+
+func (s *keyStore) CreateIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error {
+	s.ctx, s.ctxErr = ctx, ctx.Err()
+	if _, ok := s.keys[key.UserID+"/"+key.Key]; ok {
+		return gateway.ErrIdempotencyKeyExists
+	}
+	k := *key
+	s.keys[key.UserID+"/"+key.Key] = &k
+	return nil
+}
+
+func (s *keyStore) FindIdempotencyKey(ctx context.Context, userID, key string) (*entity.IdempotencyKey, error) {
+	s.ctx, s.ctxErr = ctx, ctx.Err()
+	k, ok := s.keys[userID+"/"+key]
+	if !ok {
+		return nil, gateway.ErrIdempotencyKeyNotFound
+	}
+	found := *k
+	return &found, nil
+}
+
+func (s *keyStore) CompleteIdempotencyKey(ctx context.Context, key *entity.IdempotencyKey) error {
+	s.ctx, s.ctxErr = ctx, ctx.Err()
+	k := *key
+	s.keys[key.UserID+"/"+key.Key] = &k
+	return nil
+}
+
+func (s *keyStore) DeleteIdempotencyKey(ctx context.Context, userID, key string) error {
+	s.ctx, s.ctxErr = ctx, ctx.Err()
+	delete(s.keys, userID+"/"+key)
+	return nil
+}
+
+func newTestUseCase(chats gateway.ChatGateway, keys gateway.IdempotencyKeyGateway) *ChatCompletionUseCase {
+	return &ChatCompletionUseCase{
+		ChatGateway:           chats,
+		IdempotencyKeyGateway: keys,
+		Clock:                 clock.NewSimulated(start),
+		Stream:                make(chan ChatCompletionOutputDTO, 10),
+	}
+}
+
+func TestClaimIdempotencyKey(t *testing.T) {
+	chat := newTestChat(t, "hello", "hi, how can I help?")
+	answer := chat.Messages[len(chat.Messages)-1]
+	existing := func(message string, age time.Duration, messageID string) *entity.IdempotencyKey {
+		return &entity.IdempotencyKey{
+			Key:         "key-1",
+			UserID:      "user-1",
+			RequestHash: entity.HashIdempotentRequest(chat.ID, message),
+			ChatID:      chat.ID,
+			MessageID:   messageID,
+			CreatedAt:   start.Add(-age),
+		}
+	}
+	tests := []struct {
+		name     string
+		existing *entity.IdempotencyKey
+		// wantClaimed tells the request holds the key and generates
+		wantClaimed  bool
+		wantReplayed bool
+		wantErr      error
+	}{
+		{name: "first request", wantClaimed: true},
+		{
+			name:     "retry while the first request generates",
+			existing: existing("hello", time.Minute, ""),
+			wantErr:  ErrIdempotencyKeyInProgress,
+		},
+		{
+			name:     "key of another message",
+			existing: existing("goodbye", time.Minute, ""),
+			wantErr:  ErrIdempotencyKeyReused,
+		},
+		{
+			name:        "pending key of a request that died",
+			existing:    existing("hello", idempotencyPendingTimeout+time.Minute, ""),
+			wantClaimed: true,
+		},
+		{
+			name:        "answer no longer replayed",
+			existing:    existing("hello", idempotencyKeyTTL+time.Minute, answer.ID),
+			wantClaimed: true,
+		},
+		{
+			name:         "retry of an answered request",
+			existing:     existing("hello", time.Hour, answer.ID),
+			wantReplayed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := newKeyStore()
+			if tt.existing != nil {
+				keys = newKeyStore(tt.existing)
+			}
+			uc := newTestUseCase(&chatStore{chat: chat}, keys)
+			key, output, err := uc.claimIdempotencyKey(context.Background(), ChatCompletionInputDTO{
+				ChatID:         chat.ID,
+				UserID:         "user-1",
+				UserMessage:    "hello",
+				IdempotencyKey: "key-1",
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("claimIdempotencyKey() error = %v, want %v", err, tt.wantErr)
+			}
+			if claimed := key != nil; claimed != tt.wantClaimed {
+				t.Fatalf("claimIdempotencyKey() claimed = %v, want %v", claimed, tt.wantClaimed)
+			}
+			if tt.wantClaimed {
+				stored := keys.keys["user-1/key-1"]
+				if stored == nil || stored.IsCompleted() || !stored.CreatedAt.Equal(start) {
+					t.Fatalf("key stored = %+v, want a pending key created now", stored)
+				}
+			}
+			if replayed := output != nil; replayed != tt.wantReplayed {
+				t.Fatalf("claimIdempotencyKey() replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+			if tt.wantReplayed {
+				if !output.Replayed || output.MessageID != answer.ID || output.Content != answer.Content {
+					t.Fatalf("replayed output = %+v, want the answer %s %q", output, answer.ID, answer.Content)
+				}
+				if got := streamed(uc.Stream); got != answer.Content {
+					t.Fatalf("replay streamed %q, want the answer %q", got, answer.Content)
+				}
+			}
+		})
+	}
+}
+
+// streamed is the content of the chunks sent on stream so far.
+func streamed(stream chan ChatCompletionOutputDTO) string {
+	var content string
+	for {
+		select {
+		case chunk := <-stream:
+			if chunk.Event == EventContent {
+				content += chunk.Delta
+			}
+		default:
+			return content
+		}
+	}
+}
+
+type testContextKey struct{}
+
+func TestSettleIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantCompleted bool
+		wantDeleted   bool
+	}{
+		{name: "answered", wantCompleted: true},
+		{name: "failed", err: errors.New("provider is down"), wantDeleted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := entity.NewIdempotencyKey("key-1", "user-1", "chat-1", "hello", start)
+			if err != nil {
+				t.Fatalf("NewIdempotencyKey(): %v", err)
+			}
+			keys := newKeyStore(key)
+			uc := newTestUseCase(&chatStore{}, keys)
+			// the client timed out, canceling the request, and is about to retry
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), testContextKey{}, "namespace"))
+			cancel()
+
+			var output *ChatCompletionOutputDTO
+			if tt.err == nil {
+				output = &ChatCompletionOutputDTO{ChatID: "chat-1", MessageID: "message-1"}
+			}
+			uc.settleIdempotencyKey(ctx, key, output, tt.err)
+
+			if keys.ctxErr != nil {
+				t.Fatalf("key settled on a context ended with %v, want it detached from the request", keys.ctxErr)
+			}
+			if _, ok := keys.ctx.Deadline(); !ok {
+				t.Fatal("key settled on a context without deadline, want it bounded")
+			}
+			if keys.ctx.Value(testContextKey{}) != "namespace" {
+				t.Fatal("key settled on a context without the values of the request")
+			}
+			stored, ok := keys.keys["user-1/key-1"]
+			if deleted := !ok; deleted != tt.wantDeleted {
+				t.Fatalf("key deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if tt.wantCompleted && (stored.MessageID != "message-1" || stored.ChatID != "chat-1") {
+				t.Fatalf("key stored = %+v, want the answer message-1 of chat-1", stored)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package chatcompletionstream
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// MetricsSnapshot is what Metrics counted since the service started.
+type MetricsSnapshot struct {
+	// Timeouts counts the generations timed out, by TimeoutPhase.
+	Timeouts map[TimeoutPhase]int64
+	// Generations are the totals of the generations, by model and tenant.
+	Generations map[GenerationLabels]GenerationMetrics
+}
+
+// GenerationLabels are what the generation metrics are broken down by, the
+// tenant is empty for the turns of no known one.
+type GenerationLabels struct {
+	Model  string
+	Tenant string
+}
+
+// GenerationMetrics are the totals of the generations of a model and tenant.
+type GenerationMetrics struct {
+	Started   int64
+	Succeeded int64
+	Failed    int64
+	// PromptTokens and CompletionTokens are the tokens of the answered turns.
+	PromptTokens     int64
+	CompletionTokens int64
+	// TimeToFirstToken and StreamDuration are in seconds, the duration from
+	// the provider call to the end of its stream.
+	TimeToFirstToken Histogram
+	StreamDuration   Histogram
+	// ProviderErrors counts the failed provider calls by the code of the
+	// provider error, its HTTP status when it has none.
+	ProviderErrors map[string]int64
+	// SlowGenerations counts the generations the watchdog flagged, by phase.
+	SlowGenerations map[TimeoutPhase]int64
+}
+
+// Histogram counts observations into buckets: Counts[i] are those up to
+// Bounds[i] and above the previous bound, Count all of them.
+type Histogram struct {
+	Bounds []float64
+	Counts []int64
+	Sum    float64
+	Count  int64
+}
+
+func newHistogram(bounds []float64) Histogram {
+	return Histogram{Bounds: bounds, Counts: make([]int64, len(bounds))}
+}
+
+func (h *Histogram) observe(v float64) {
+	for i, bound := range h.Bounds {
+		if v <= bound {
+			h.Counts[i]++
+			break
+		}
+	}
+	h.Sum += v
+	h.Count++
+}
+
+func (h Histogram) clone() Histogram {
+	h.Counts = append([]int64(nil), h.Counts...)
+	return h
+}
+
+// timeToFirstTokenBounds and streamDurationBounds are the buckets of the
+// histograms, in seconds.
+var (
+	timeToFirstTokenBounds = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+	streamDurationBounds   = []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+)
+
+// Metrics counts what the generations of the usecase went through.
+type Metrics struct {
+	mu          sync.Mutex
+	timeouts    map[TimeoutPhase]int64
+	generations map[GenerationLabels]*GenerationMetrics
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		timeouts:    make(map[TimeoutPhase]int64),
+		generations: make(map[GenerationLabels]*GenerationMetrics),
+	}
+}
+
+func (m *Metrics) observeTimeout(phase TimeoutPhase) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timeouts[phase]++
+}
+
+// observe updates the totals of labels with fn.
+func (m *Metrics) observe(labels GenerationLabels, fn func(g *GenerationMetrics)) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g := m.generations[labels]
+	if g == nil {
+		g = &GenerationMetrics{
+			TimeToFirstToken: newHistogram(timeToFirstTokenBounds),
+			StreamDuration:   newHistogram(streamDurationBounds),
+			ProviderErrors:   make(map[string]int64),
+			SlowGenerations:  make(map[TimeoutPhase]int64),
+		}
+		m.generations[labels] = g
+	}
+	fn(g)
+}
+
+func (m *Metrics) observeStarted(labels GenerationLabels) {
+	m.observe(labels, func(g *GenerationMetrics) { g.Started++ })
+}
+
+// observeEnded counts the generation as failed with err, as succeeded when
+// it is nil.
+func (m *Metrics) observeEnded(labels GenerationLabels, err error) {
+	m.observe(labels, func(g *GenerationMetrics) {
+		if err != nil {
+			g.Failed++
+		} else {
+			g.Succeeded++
+		}
+	})
+}
+
+func (m *Metrics) observeTokens(labels GenerationLabels, promptTokens, completionTokens int) {
+	m.observe(labels, func(g *GenerationMetrics) {
+		g.PromptTokens += int64(promptTokens)
+		g.CompletionTokens += int64(completionTokens)
+	})
+}
+
+func (m *Metrics) observeTimeToFirstToken(labels GenerationLabels, d time.Duration) {
+	m.observe(labels, func(g *GenerationMetrics) { g.TimeToFirstToken.observe(d.Seconds()) })
+}
+
+func (m *Metrics) observeStreamDuration(labels GenerationLabels, d time.Duration) {
+	m.observe(labels, func(g *GenerationMetrics) { g.StreamDuration.observe(d.Seconds()) })
+}
+
+func (m *Metrics) observeProviderError(labels GenerationLabels, err error) {
+	code := providerErrorCode(err)
+	m.observe(labels, func(g *GenerationMetrics) { g.ProviderErrors[code]++ })
+}
+
+func (m *Metrics) observeSlowGeneration(labels GenerationLabels, phase TimeoutPhase) {
+	m.observe(labels, func(g *GenerationMetrics) { g.SlowGenerations[phase]++ })
+}
+
+// providerErrorCode is the code of the provider error err, its HTTP status
+// when it has none, and network for the calls that got no answer.
+func providerErrorCode(err error) string {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.Code != nil && *apiErr.Code != "" {
+			return *apiErr.Code
+		}
+		if apiErr.Type != "" {
+			return apiErr.Type
+		}
+		return strconv.Itoa(apiErr.StatusCode)
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return strconv.Itoa(reqErr.StatusCode)
+	}
+	return "network"
+}
+
+// Snapshot returns the metrics counted so far.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := MetricsSnapshot{
+		Timeouts:    make(map[TimeoutPhase]int64, len(m.timeouts)),
+		Generations: make(map[GenerationLabels]GenerationMetrics, len(m.generations)),
+	}
+	for phase, n := range m.timeouts {
+		snapshot.Timeouts[phase] = n
+	}
+	for labels, g := range m.generations {
+		copied := *g
+		copied.TimeToFirstToken = g.TimeToFirstToken.clone()
+		copied.StreamDuration = g.StreamDuration.clone()
+		copied.ProviderErrors = make(map[string]int64, len(g.ProviderErrors))
+		for code, n := range g.ProviderErrors {
+			copied.ProviderErrors[code] = n
+		}
+		copied.SlowGenerations = make(map[TimeoutPhase]int64, len(g.SlowGenerations))
+		for phase, n := range g.SlowGenerations {
+			copied.SlowGenerations[phase] = n
+		}
+		snapshot.Generations[labels] = copied
+	}
+	return snapshot
+}
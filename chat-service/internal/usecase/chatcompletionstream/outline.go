@@ -0,0 +1,57 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/requestid"
+)
+
+const (
+	outlinePrompt       = "Do not answer yet. Reply only with a short outline (a few bullet points) of the answer you plan to give, so the user can confirm it matches what they asked."
+	expandOutlinePrompt = "The user confirmed your outline. Now write the full answer following it."
+)
+
+type ConfirmOutlineInputDTO struct {
+	ChatID     string
+	UserID     string
+	Tier       string
+	TitleModel string
+}
+
+// ConfirmOutlineUseCase generates the full answer of an outline_first chat once
+// the user has confirmed the outline, streaming it like ChatCompletionUseCase does.
+type ConfirmOutlineUseCase struct {
+	*ChatCompletionUseCase
+}
+
+func NewConfirmOutlineUseCase(completion *ChatCompletionUseCase) *ConfirmOutlineUseCase {
+	return &ConfirmOutlineUseCase{
+		ChatCompletionUseCase: completion,
+	}
+}
+
+func (uc *ConfirmOutlineUseCase) Execute(ctx context.Context, input ConfirmOutlineInputDTO) (output *ChatCompletionOutputDTO, err error) {
+	ctx = requestid.Ensure(ctx)
+	defer func() { err = requestid.Wrap(ctx, err) }()
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	if err := chat.ConfirmOutline(); err != nil {
+		return nil, fmt.Errorf("error confirming outline: %w", err)
+	}
+	return uc.complete(ctx, chat, completionRequest{
+		userID:           input.UserID,
+		tier:             input.Tier,
+		titleModel:       input.TitleModel,
+		confirmedOutline: true,
+		rebase: func(chat *entity.Chat) error {
+			return chat.ConfirmOutline()
+		},
+	})
+}
@@ -0,0 +1,129 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/streambuffer"
+)
+
+// MaxPollWait is the longest a poll waits for events, below the idle
+// timeout of the usual proxies.
+const MaxPollWait = 25 * time.Second
+
+type PollEventsInputDTO struct {
+	ChatID string
+	UserID string
+	// TurnID and Since are the turn and the seq of the last chunk the client
+	// received. Since is ignored when TurnID isn't the latest turn of the
+	// chat anymore, its chunks are returned from the first.
+	TurnID string
+	Since  int
+	// Wait is how long to wait for a chunk when there is none after Since,
+	// MaxPollWait when zero or longer.
+	Wait time.Duration
+}
+
+type PollEventsOutputDTO struct {
+	// TurnID is the latest turn of the chat, empty when none is buffered.
+	TurnID string
+	Events []ChatCompletionOutputDTO
+	// NextSince is the seq to poll the turn from next.
+	NextSince int
+	// Finished is set once the turn is over and Events has its last chunks.
+	Finished bool
+}
+
+// PollEventsUseCase returns the buffered chunks of the latest turn of a
+// chat, waiting for the next ones when the client has them all. It serves
+// the clients that can't keep a stream open.
+type PollEventsUseCase struct {
+	StreamBuffer *streambuffer.Buffer[ChatCompletionOutputDTO]
+	Clock        clock.Clock
+}
+
+func NewPollEventsUseCase(streamBuffer *streambuffer.Buffer[ChatCompletionOutputDTO], clk clock.Clock) *PollEventsUseCase {
+	return &PollEventsUseCase{
+		StreamBuffer: streamBuffer,
+		Clock:        clk,
+	}
+}
+
+func (uc *PollEventsUseCase) Execute(ctx context.Context, input PollEventsInputDTO) (*PollEventsOutputDTO, error) {
+	wait := input.Wait
+	if wait <= 0 || wait > MaxPollWait {
+		wait = MaxPollWait
+	}
+	timer := uc.Clock.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		token, opened := uc.StreamBuffer.Latest(input.ChatID)
+		if token != "" {
+			return uc.poll(ctx, input, token, opened, timer)
+		}
+		// the turn may not have started yet
+		select {
+		case <-opened:
+		case <-timer.C():
+			return &PollEventsOutputDTO{}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// poll returns the chunks of the turn of token after the Since of input,
+// waiting on timer for one when there are none yet.
+func (uc *PollEventsUseCase) poll(ctx context.Context, input PollEventsInputDTO, token string, opened <-chan struct{}, timer clock.Timer) (*PollEventsOutputDTO, error) {
+	since := input.Since
+	if input.TurnID != "" && input.TurnID != token {
+		since = 0
+	}
+	replay, live, cancel, err := uc.StreamBuffer.Subscribe(token, input.UserID, since)
+	if err != nil {
+		return nil, fmt.Errorf("error polling events: %w", err)
+	}
+	defer cancel()
+	output := &PollEventsOutputDTO{TurnID: token, NextSince: since}
+	for _, ev := range replay {
+		output.Events = append(output.Events, ev.Value)
+		output.NextSince = ev.Seq
+	}
+	closed := false
+	if len(output.Events) == 0 {
+		select {
+		case ev, ok := <-live:
+			if !ok {
+				closed = true
+				break
+			}
+			output.Events = append(output.Events, ev.Value)
+			output.NextSince = ev.Seq
+		case <-opened:
+			// a newer turn started, the client polls it next
+		case <-timer.C():
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	// what else is there is returned without waiting
+drain:
+	for !closed {
+		select {
+		case ev, ok := <-live:
+			if !ok {
+				closed = true
+			} else if ev.Seq > output.NextSince {
+				output.Events = append(output.Events, ev.Value)
+				output.NextSince = ev.Seq
+			}
+		default:
+			break drain
+		}
+	}
+	// live is also closed on a client falling behind, which polls again
+	output.Finished = closed && uc.StreamBuffer.Finished(token)
+	return output, nil
+}
@@ -0,0 +1,84 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/requestid"
+)
+
+type RegenerateInputDTO struct {
+	ChatID     string
+	UserID     string
+	OrgID      string
+	Tier       string
+	TitleModel string
+	// Temperature, when set, replaces the temperature of the chat for the
+	// new answer only.
+	Temperature *float32
+}
+
+// RegenerateUseCase discards the last answer of a chat and generates it again
+// from the same history, streaming it like ChatCompletionUseCase does.
+type RegenerateUseCase struct {
+	*ChatCompletionUseCase
+}
+
+func NewRegenerateUseCase(completion *ChatCompletionUseCase) *RegenerateUseCase {
+	return &RegenerateUseCase{
+		ChatCompletionUseCase: completion,
+	}
+}
+
+func (uc *RegenerateUseCase) Execute(ctx context.Context, input RegenerateInputDTO) (output *ChatCompletionOutputDTO, err error) {
+	ctx = requestid.Ensure(ctx)
+	defer func() { err = requestid.Wrap(ctx, err) }()
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	if input.Temperature != nil {
+		if *input.Temperature < 0 || *input.Temperature > 2 {
+			return nil, errors.New("invalid temperature")
+		}
+		if chat.Config.Deterministic {
+			return nil, errors.New("temperature of a deterministic chat can't be changed")
+		}
+	}
+	discarded, err := chat.DiscardLastAnswer(uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error discarding answer: %w", err)
+	}
+	return uc.complete(ctx, chat, completionRequest{
+		userID:     input.UserID,
+		orgID:      input.OrgID,
+		tier:       input.Tier,
+		titleModel: input.TitleModel,
+		// an answer following another one is the expansion of a confirmed outline
+		confirmedOutline: endsWithAnswer(chat),
+		temperature:      input.Temperature,
+		rebase:           rebaseRegeneration(uc.Clock, discarded),
+	})
+}
+
+// rebaseRegeneration discards the answer again from the chat reloaded after
+// a conflict, which must still end with it.
+func rebaseRegeneration(clk clock.Clock, discarded *entity.Message) func(chat *entity.Chat) error {
+	return func(chat *entity.Chat) error {
+		if len(chat.Messages) == 0 || chat.Messages[len(chat.Messages)-1].ID != discarded.ID {
+			return errors.New("chat changed while regenerating its answer")
+		}
+		_, err := chat.DiscardLastAnswer(clk.Now())
+		return err
+	}
+}
+
+func endsWithAnswer(chat *entity.Chat) bool {
+	return len(chat.Messages) > 0 && chat.Messages[len(chat.Messages)-1].Role == entity.RoleAssistant
+}
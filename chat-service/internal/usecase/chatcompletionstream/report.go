@@ -0,0 +1,58 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/dispatcher"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/ratelimit"
+)
+
+// expectedErrors are the failures of the turns that are part of running the
+// service: the limits of the service and the provider, and the requests the
+// client may fix or retry. They aren't reported.
+var expectedErrors = []error{
+	entity.ErrProviderUnavailable,
+	entity.ErrTokenLimitExceeded,
+	entity.ErrInvalidRole,
+	gateway.ErrChatNotFound,
+	gateway.ErrChatConflict,
+	dispatcher.ErrUserLimit,
+	dispatcher.ErrQueueFull,
+	dispatcher.ErrQueueTimeout,
+	ErrShuttingDown,
+	ErrIdempotencyKeyInProgress,
+	ErrIdempotencyKeyReused,
+}
+
+// reportError reports the turn that failed unexpectedly to the error
+// reporter, with its chat, model and tenant, not the ones the client
+// canceled.
+func (uc *ChatCompletionUseCase) reportError(ctx context.Context, t *turn, req completionRequest, chat *entity.Chat, err error) {
+	if uc.ErrorReporter == nil || ctx.Err() != nil || !unexpected(err) {
+		return
+	}
+	uc.ErrorReporter.CaptureError(ctx, err, map[string]string{
+		"chat_id": chat.ID,
+		"turn_id": t.id,
+		"model":   chat.Config.Model.GetModelName(),
+		"tenant":  req.orgID,
+	})
+}
+
+func unexpected(err error) bool {
+	var limited *ratelimit.Error
+	var timedOut *TimeoutError
+	var slow *ConsumerTooSlowError
+	if errors.As(err, &limited) || errors.As(err, &timedOut) || errors.As(err, &slow) {
+		return false
+	}
+	for _, expected := range expectedErrors {
+		if errors.Is(err, expected) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/streambuffer"
+)
+
+type ResumeStreamInputDTO struct {
+	ResumeToken string
+	UserID      string
+	LastSeq     int
+}
+
+type ResumeStreamUseCase struct {
+	StreamBuffer *streambuffer.Buffer[ChatCompletionOutputDTO]
+	Stream       chan ChatCompletionOutputDTO
+}
+
+func NewResumeStreamUseCase(streamBuffer *streambuffer.Buffer[ChatCompletionOutputDTO], stream chan ChatCompletionOutputDTO) *ResumeStreamUseCase {
+	return &ResumeStreamUseCase{
+		StreamBuffer: streamBuffer,
+		Stream:       stream,
+	}
+}
+
+// StreamLaggedError ends a resumed stream whose consumer fell behind the
+// live chunks, the client resumes again after chunk Seq, the last it got.
+type StreamLaggedError struct {
+	ResumeToken string
+	Seq         int
+}
+
+func (e *StreamLaggedError) Error() string {
+	return fmt.Sprintf("stream consumer too slow, resume stream %s after chunk %d", e.ResumeToken, e.Seq)
+}
+
+// Execute replays the chunks after input.LastSeq and keeps forwarding live
+// chunks until the generation finishes, or fails with a *StreamLaggedError
+// when the caller can't keep up with them.
+func (uc *ResumeStreamUseCase) Execute(ctx context.Context, input ResumeStreamInputDTO) error {
+	replay, live, cancel, err := uc.StreamBuffer.Subscribe(input.ResumeToken, input.UserID, input.LastSeq)
+	if err != nil {
+		return fmt.Errorf("error resuming stream: %w", err)
+	}
+	defer cancel()
+	lastSeq := input.LastSeq
+	for _, ev := range replay {
+		if err := uc.send(ctx, ev.Value); err != nil {
+			return err
+		}
+		lastSeq = ev.Seq
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-live:
+			if !ok {
+				if uc.StreamBuffer.Lagged(input.ResumeToken, live) {
+					return &StreamLaggedError{ResumeToken: input.ResumeToken, Seq: lastSeq}
+				}
+				return nil
+			}
+			if ev.Seq <= lastSeq {
+				continue
+			}
+			if err := uc.send(ctx, ev.Value); err != nil {
+				return err
+			}
+			lastSeq = ev.Seq
+		}
+	}
+}
+
+func (uc *ResumeStreamUseCase) send(ctx context.Context, r ChatCompletionOutputDTO) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case uc.Stream <- r:
+		return nil
+	}
+}
@@ -0,0 +1,111 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/logging"
+)
+
+// SlowGeneration is a generation the watchdog flagged, its Phase going on
+// for longer than Threshold: the wait for the first token, or the whole
+// generation.
+type SlowGeneration struct {
+	Phase     TimeoutPhase
+	Threshold time.Duration
+	ChatID    string
+	TurnID    string
+	UserID    string
+	OrgID     string
+	Model     string
+	Provider  string
+	// PromptTokens and PromptMessages are the size of the prompt sent.
+	PromptTokens   int
+	PromptMessages int
+}
+
+// SlowGenerationWatchdog flags the generations whose first token takes
+// longer than FirstToken, or whose whole generation takes longer than Total,
+// zero leaving them unwatched, to catch a degrading provider before the
+// timeouts fail the turns. A flagged generation is logged, counted by the
+// metrics and passed to OnSlow, when set, to alert on. Provider names the
+// provider in the flags.
+type SlowGenerationWatchdog struct {
+	FirstToken time.Duration
+	Total      time.Duration
+	Provider   string
+	OnSlow     func(ctx context.Context, slow SlowGeneration)
+}
+
+// watchSlowness flags the generation slow once the thresholds of the
+// watchdog pass, each phase at most once. The returned firstToken is called
+// with the first token and stop once the generation is over.
+func (uc *ChatCompletionUseCase) watchSlowness(ctx context.Context, slow SlowGeneration) (firstToken func(), stop func()) {
+	w := uc.SlowGenerationWatchdog
+	if w.FirstToken <= 0 && w.Total <= 0 {
+		return func() {}, func() {}
+	}
+	slow.Provider = w.Provider
+	var timers []clock.Timer
+	var firstTokenC, totalC <-chan time.Time
+	if w.FirstToken > 0 {
+		timer := uc.Clock.NewTimer(w.FirstToken)
+		timers = append(timers, timer)
+		firstTokenC = timer.C()
+	}
+	if w.Total > 0 {
+		timer := uc.Clock.NewTimer(w.Total)
+		timers = append(timers, timer)
+		totalC = timer.C()
+	}
+	got := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		got := got
+		for firstTokenC != nil || totalC != nil {
+			select {
+			case <-got:
+				got, firstTokenC = nil, nil
+			case <-firstTokenC:
+				firstTokenC = nil
+				slow.Phase, slow.Threshold = TimeoutFirstToken, w.FirstToken
+				uc.flagSlow(ctx, slow)
+			case <-totalC:
+				totalC = nil
+				slow.Phase, slow.Threshold = TimeoutTotal, w.Total
+				uc.flagSlow(ctx, slow)
+			case <-done:
+				return
+			}
+		}
+	}()
+	var gotOnce, doneOnce sync.Once
+	firstToken = func() {
+		gotOnce.Do(func() { close(got) })
+	}
+	stop = func() {
+		doneOnce.Do(func() {
+			close(done)
+			for _, timer := range timers {
+				timer.Stop()
+			}
+		})
+	}
+	return firstToken, stop
+}
+
+func (uc *ChatCompletionUseCase) flagSlow(ctx context.Context, slow SlowGeneration) {
+	logging.FromContext(ctx).Warn("slow generation",
+		logging.String("phase", string(slow.Phase)),
+		logging.Duration("threshold", slow.Threshold),
+		logging.String("turn_id", slow.TurnID),
+		logging.String("provider", slow.Provider),
+		logging.Int("prompt_tokens", slow.PromptTokens),
+		logging.Int("prompt_messages", slow.PromptMessages))
+	uc.Metrics.observeSlowGeneration(GenerationLabels{Model: slow.Model, Tenant: slow.OrgID}, slow.Phase)
+	if uc.SlowGenerationWatchdog.OnSlow != nil {
+		uc.SlowGenerationWatchdog.OnSlow(ctx, slow)
+	}
+}
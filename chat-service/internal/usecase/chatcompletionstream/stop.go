@@ -0,0 +1,34 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"fmt"
+)
+
+type StopGenerationInputDTO struct {
+	ChatID string
+	UserID string
+}
+
+// StopGenerationUseCase aborts the answer a chat is generating. The turn ends
+// with a generation_stopped chunk and saves the content streamed so far.
+// Only the generations of this instance can be stopped.
+type StopGenerationUseCase struct {
+	Generations *Generations
+}
+
+func NewStopGenerationUseCase(completion *ChatCompletionUseCase) *StopGenerationUseCase {
+	return &StopGenerationUseCase{
+		Generations: completion.Generations,
+	}
+}
+
+func (uc *StopGenerationUseCase) Execute(ctx context.Context, input StopGenerationInputDTO) error {
+	if uc.Generations == nil {
+		return ErrGenerationNotFound
+	}
+	if err := uc.Generations.Stop(input.ChatID, input.UserID); err != nil {
+		return fmt.Errorf("error stopping generation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package chatcompletionstream
+
+import "context"
+
+// ExecuteStreaming runs Execute on a stream of its own and hands its chunks
+// to send as they are produced, so one usecase can serve concurrent
+// requests. Once send fails the chunks are dropped, the turn still
+// completes, and the error of send is returned if Execute succeeded.
+func (uc *ChatCompletionUseCase) ExecuteStreaming(ctx context.Context, input ChatCompletionInputDTO, send func(ChatCompletionOutputDTO) error) (*ChatCompletionOutputDTO, error) {
+	return uc.streaming(send, func(call *ChatCompletionUseCase) (*ChatCompletionOutputDTO, error) {
+		return call.Execute(ctx, input)
+	})
+}
+
+// ExecuteStreaming runs Execute on a stream of its own and hands the chunks
+// to send, see ChatCompletionUseCase.ExecuteStreaming.
+func (uc *RegenerateUseCase) ExecuteStreaming(ctx context.Context, input RegenerateInputDTO, send func(ChatCompletionOutputDTO) error) (*ChatCompletionOutputDTO, error) {
+	return uc.streaming(send, func(call *ChatCompletionUseCase) (*ChatCompletionOutputDTO, error) {
+		return NewRegenerateUseCase(call).Execute(ctx, input)
+	})
+}
+
+// streaming runs a turn with run on a copy of uc streaming to a channel of
+// its own, and hands the chunks of the turn to send.
+func (uc *ChatCompletionUseCase) streaming(send func(ChatCompletionOutputDTO) error, run func(call *ChatCompletionUseCase) (*ChatCompletionOutputDTO, error)) (*ChatCompletionOutputDTO, error) {
+	call := *uc
+	call.Stream = make(chan ChatCompletionOutputDTO, uc.StreamCapacity)
+	type result struct {
+		output *ChatCompletionOutputDTO
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		output, err := run(&call)
+		done <- result{output, err}
+	}()
+	var sendErr error
+	for {
+		select {
+		case chunk := <-call.Stream:
+			if sendErr == nil {
+				sendErr = send(chunk)
+			}
+		case r := <-done:
+			// the chunks still buffered go out first
+			for len(call.Stream) > 0 {
+				chunk := <-call.Stream
+				if sendErr == nil {
+					sendErr = send(chunk)
+				}
+			}
+			if r.err != nil {
+				return nil, r.err
+			}
+			return r.output, sendErr
+		}
+	}
+}
+
+// ExecuteStreaming runs Execute on a stream of its own and hands the chunks
+// to send, see ChatCompletionUseCase.ExecuteStreaming.
+func (uc *ResumeStreamUseCase) ExecuteStreaming(ctx context.Context, input ResumeStreamInputDTO, send func(ChatCompletionOutputDTO) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	call := *uc
+	call.Stream = make(chan ChatCompletionOutputDTO)
+	done := make(chan error, 1)
+	go func() {
+		done <- call.Execute(ctx, input)
+	}()
+	for {
+		select {
+		case chunk := <-call.Stream:
+			if err := send(chunk); err != nil {
+				// nothing is left to finish, unlike a turn
+				cancel()
+				<-done
+				return err
+			}
+		case err := <-done:
+			return err
+		}
+	}
+}
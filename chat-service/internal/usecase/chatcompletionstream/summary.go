@@ -0,0 +1,81 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/dispatcher"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	summaryMaxTokens = 512
+	summaryPrompt    = "Summarize the conversation below in a few sentences, keeping names, numbers, decisions and open questions. Reply with the summary only."
+	summaryPrefix    = "Summary of the earlier conversation: "
+)
+
+// applyPendingSummary folds the messages evicted by the summarize policy into
+// the chat summary. Failures are reported as a warning chunk, the messages
+// stay erased like with the evict_oldest policy.
+func (uc *ChatCompletionUseCase) applyPendingSummary(ctx context.Context, chat *entity.Chat, t *turn) {
+	previous, evicted := chat.PendingSummary()
+	if len(evicted) == 0 {
+		return
+	}
+	err := uc.inBackground(ctx, t.holdsSlot, func() error {
+		return uc.summarize(ctx, chat, previous, evicted)
+	})
+	if err != nil {
+		t.emit(ChatCompletionOutputDTO{
+			Event:   EventWarning,
+			Content: "Older messages were removed from the context but could not be summarized: " + err.Error(),
+		})
+	}
+}
+
+func (uc *ChatCompletionUseCase) summarize(ctx context.Context, chat *entity.Chat, previous *entity.Message, evicted []*entity.Message) error {
+	var conversation strings.Builder
+	if previous != nil {
+		conversation.WriteString(previous.Content + "\n")
+	}
+	for _, msg := range evicted {
+		conversation.WriteString(msg.Role.String() + ": " + msg.Content + "\n")
+	}
+	resp, err := uc.OpenAIClient.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: chat.Config.Model.GetModelName(),
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: summaryPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: conversation.String()},
+		},
+		MaxTokens: summaryMaxTokens,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating summary completion: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return errors.New("empty summary completion")
+	}
+	summary, err := entity.NewMessage(entity.RoleSystem, summaryPrefix+strings.TrimSpace(resp.Choices[0].Message.Content), chat.Config.Model, uc.Clock.Now())
+	if err != nil {
+		return fmt.Errorf("error creating summary message: %w", err)
+	}
+	return chat.ApplySummary(summary)
+}
+
+// inBackground runs the background completion fn on a batch slot of the
+// dispatcher, so it yields to the answers users wait for, or on the slot of
+// the turn while it holds one.
+func (uc *ChatCompletionUseCase) inBackground(ctx context.Context, holdsSlot bool, fn func() error) error {
+	if uc.Dispatcher == nil || holdsSlot {
+		return fn()
+	}
+	release, err := uc.Dispatcher.AcquireWithPriority(ctx, "", "", dispatcher.PriorityBatch, nil)
+	if err != nil {
+		return fmt.Errorf("error waiting for a completion slot: %w", err)
+	}
+	defer release()
+	return fn()
+}
@@ -0,0 +1,94 @@
+package chatcompletionstream
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+// TimeoutPhase is the part of a generation a timeout bounds.
+type TimeoutPhase string
+
+const (
+	// TimeoutFirstToken bounds the wait for the first token of the answer.
+	TimeoutFirstToken TimeoutPhase = "first_token"
+	// TimeoutTotal bounds the whole generation, from the request to the
+	// provider to its last token.
+	TimeoutTotal TimeoutPhase = "total"
+)
+
+// TimeoutError fails a generation the provider took longer than Timeout
+// for, in Phase. When part of the answer was generated, it is saved
+// incomplete instead.
+type TimeoutError struct {
+	Phase   TimeoutPhase
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Phase == TimeoutFirstToken {
+		return fmt.Sprintf("generation timed out: no token within %s", e.Timeout)
+	}
+	return fmt.Sprintf("generation timed out after %s", e.Timeout)
+}
+
+// watchTimeouts times gen out once FirstTokenTimeout passes without a token,
+// or GenerationTimeout passes. The returned firstToken is called with the
+// first token and stop once the generation is over.
+func (uc *ChatCompletionUseCase) watchTimeouts(gen *generation) (firstToken func(), stop func()) {
+	var timers []clock.Timer
+	var firstTokenC, totalC <-chan time.Time
+	if uc.FirstTokenTimeout > 0 {
+		timer := uc.Clock.NewTimer(uc.FirstTokenTimeout)
+		timers = append(timers, timer)
+		firstTokenC = timer.C()
+	}
+	if uc.GenerationTimeout > 0 {
+		timer := uc.Clock.NewTimer(uc.GenerationTimeout)
+		timers = append(timers, timer)
+		totalC = timer.C()
+	}
+	if len(timers) == 0 {
+		return func() {}, func() {}
+	}
+	got := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		got := got
+		for {
+			select {
+			case <-got:
+				got, firstTokenC = nil, nil
+			case <-firstTokenC:
+				uc.timedOut(gen, &TimeoutError{Phase: TimeoutFirstToken, Timeout: uc.FirstTokenTimeout})
+				return
+			case <-totalC:
+				uc.timedOut(gen, &TimeoutError{Phase: TimeoutTotal, Timeout: uc.GenerationTimeout})
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+	var gotOnce, doneOnce sync.Once
+	firstToken = func() {
+		gotOnce.Do(func() { close(got) })
+	}
+	stop = func() {
+		doneOnce.Do(func() {
+			close(done)
+			for _, timer := range timers {
+				timer.Stop()
+			}
+		})
+	}
+	return firstToken, stop
+}
+
+func (uc *ChatCompletionUseCase) timedOut(gen *generation, err *TimeoutError) {
+	if gen.timeout(err) {
+		uc.Metrics.observeTimeout(err.Phase)
+	}
+}
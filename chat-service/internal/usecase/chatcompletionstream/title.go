@@ -0,0 +1,58 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultTitleModel = "gpt-3.5-turbo"
+	titleMaxTokens    = 16
+	titlePrompt       = "Write a short title (at most 6 words) for the conversation below. Reply with the title only, without quotes or punctuation at the end."
+)
+
+func (uc *ChatCompletionUseCase) generateTitle(ctx context.Context, chat *entity.Chat, model string) error {
+	if model == "" {
+		model = defaultTitleModel
+	}
+	var conversation strings.Builder
+	for _, msg := range chat.Messages {
+		if msg.Role == entity.RoleSystem {
+			continue
+		}
+		conversation.WriteString(msg.Role.String() + ": " + msg.Content + "\n")
+	}
+	var resp openai.ChatCompletionResponse
+	err := uc.inBackground(ctx, false, func() (err error) {
+		resp, err = uc.OpenAIClient.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+			Model: model,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: titlePrompt},
+				{Role: openai.ChatMessageRoleUser, Content: conversation.String()},
+			},
+			MaxTokens:   titleMaxTokens,
+			Temperature: 0.2,
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error creating title completion: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return errors.New("empty title completion")
+	}
+	title := strings.Trim(strings.TrimSpace(resp.Choices[0].Message.Content), "\"'.")
+	if err := chat.SetTitle(title); err != nil {
+		return fmt.Errorf("error setting chat title: %w", err)
+	}
+	err = uc.ChatGateway.UpdateChatTitle(ctx, chat.ID, chat.Title)
+	if err != nil {
+		return fmt.Errorf("error updating chat title: %w", err)
+	}
+	return nil
+}
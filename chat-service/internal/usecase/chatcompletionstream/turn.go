@@ -0,0 +1,196 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/requestid"
+	"github.com/google/uuid"
+)
+
+const (
+	EventGenerationStarted  = "generation_started"
+	EventGenerationThinking = "generation_thinking"
+	EventSystemNotice       = "system_notice"
+	EventWarning            = "warning"
+	EventContent            = "content"
+	EventGenerationStopped  = "generation_stopped"
+	// EventGenerationIncomplete follows the content of an answer the
+	// provider failed in the middle of, which is saved as it is.
+	EventGenerationIncomplete = "generation_incomplete"
+	// EventUsage follows the answer saved, with the tokens it used.
+	EventUsage = "usage"
+	// EventGenerationEnded is the last chunk of every turn, its FinishReason
+	// tells why it ended.
+	EventGenerationEnded = "generation_ended"
+	// EventHeartbeat is sent when the turn went HeartbeatInterval without a
+	// chunk, so idle connections stay open. Heartbeats have no Seq and are
+	// not buffered, a stream without room for one skips it.
+	EventHeartbeat = "heartbeat"
+)
+
+// Finish reasons of the generation_ended chunks.
+const (
+	// FinishStop ends the answers the model completed.
+	FinishStop = "stop"
+	// FinishLength ends the answers cut at the max tokens of the chat.
+	FinishLength = "length"
+	// FinishContentFilter ends the answers the provider filtered out.
+	FinishContentFilter = "content_filter"
+	// FinishCancelled ends the turns stopped by the user, or whose client
+	// went away.
+	FinishCancelled = "cancelled"
+	// FinishError ends the turns that failed, Error telling why. An answer
+	// saved incomplete ends with it too.
+	FinishError = "error"
+)
+
+// SlowConsumerPolicy is what a turn does with a chunk its stream has no room
+// for.
+type SlowConsumerPolicy string
+
+const (
+	// SlowConsumerWait waits for the consumer to take the chunk, as long as
+	// the request lasts.
+	SlowConsumerWait SlowConsumerPolicy = "wait"
+	// SlowConsumerDrop drops the content chunks, the next ones carry the
+	// content so far and the gap shows in their Seq. The other chunks wait.
+	SlowConsumerDrop SlowConsumerPolicy = "drop"
+	// SlowConsumerFail fails the turn with a *ConsumerTooSlowError once a
+	// chunk waited for SlowConsumerTimeout.
+	SlowConsumerFail SlowConsumerPolicy = "fail"
+)
+
+const defaultSlowConsumerTimeout = 5 * time.Second
+
+// ConsumerTooSlowError fails a turn whose stream consumer didn't take chunk
+// Seq in time, under SlowConsumerFail.
+type ConsumerTooSlowError struct {
+	TurnID string
+	Seq    int
+}
+
+func (e *ConsumerTooSlowError) Error() string {
+	return fmt.Sprintf("stream consumer too slow, chunk %d of turn %s was not taken in time", e.Seq, e.TurnID)
+}
+
+// turn numbers and publishes the chunks of a single generation.
+type turn struct {
+	uc          *ChatCompletionUseCase
+	ctx         context.Context
+	id          string
+	chatID      string
+	userID      string
+	resumeToken string
+	requestID   string
+	// mu serializes the chunks with the heartbeats
+	mu  sync.Mutex
+	seq int
+	// lastEmit is when the last chunk was emitted
+	lastEmit time.Time
+	// err is why the chunks stopped reaching the stream, the turn is to
+	// fail with it
+	err error
+	// holdsSlot tells the turn holds its dispatcher slot, its summaries run
+	// on it
+	holdsSlot bool
+}
+
+func (uc *ChatCompletionUseCase) newTurn(ctx context.Context, chatID, userID string) *turn {
+	t := &turn{
+		uc:        uc,
+		ctx:       ctx,
+		id:        uuid.New().String(),
+		chatID:    chatID,
+		userID:    userID,
+		requestID: requestid.FromContext(ctx),
+	}
+	if uc.StreamBuffer != nil {
+		// the turn ID resumes its stream, which is the latest of the chat
+		t.resumeToken = t.id
+		uc.StreamBuffer.OpenInTopic(t.resumeToken, userID, chatID)
+	}
+	return t
+}
+
+// emit publishes r unless the stream hooks drop it.
+func (t *turn) emit(r ChatCompletionOutputDTO) {
+	if r, ok := t.transform(r); ok {
+		t.publish(r)
+	}
+}
+
+// publish numbers r, transformed already, and streams it.
+func (t *turn) publish(r ChatCompletionOutputDTO) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastEmit = t.uc.Clock.Now()
+	t.seq++
+	r.Seq = t.seq
+	if t.uc.StreamBuffer != nil {
+		t.uc.StreamBuffer.Publish(t.resumeToken, t.seq, r)
+	}
+	if t.err == nil {
+		t.err = t.send(r)
+	}
+}
+
+// send hands r to the stream under the SlowConsumer policy of the usecase,
+// giving up once the request is done.
+func (t *turn) send(r ChatCompletionOutputDTO) error {
+	select {
+	case t.uc.Stream <- r:
+		return nil
+	default:
+	}
+	var timeout <-chan time.Time
+	switch t.uc.SlowConsumer {
+	case SlowConsumerDrop:
+		if r.Event == EventContent {
+			return nil
+		}
+	case SlowConsumerFail:
+		d := t.uc.SlowConsumerTimeout
+		if d <= 0 {
+			d = defaultSlowConsumerTimeout
+		}
+		timer := t.uc.Clock.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+	select {
+	case t.uc.Stream <- r:
+		return nil
+	case <-timeout:
+		return &ConsumerTooSlowError{TurnID: t.id, Seq: r.Seq}
+	case <-t.ctx.Done():
+		return t.ctx.Err()
+	}
+}
+
+// finish ends the turn with its generation_ended chunk, telling why from the
+// output or the error of the turn, and closes its buffered stream.
+func (t *turn) finish(output *ChatCompletionOutputDTO, err error) {
+	end := ChatCompletionOutputDTO{Event: EventGenerationEnded}
+	switch {
+	case err != nil && t.ctx.Err() != nil:
+		end.FinishReason = FinishCancelled
+		end.Error = err.Error()
+	case err != nil:
+		end.FinishReason = FinishError
+		end.Error = err.Error()
+	default:
+		end.FinishReason = output.FinishReason
+		end.Error = output.IncompleteReason
+		end.Content = output.Content
+		end.AwaitingConfirmation = output.AwaitingConfirmation
+		end.Stopped = output.Stopped
+		end.IncompleteReason = output.IncompleteReason
+	}
+	t.emit(end)
+	if t.uc.StreamBuffer != nil {
+		t.uc.StreamBuffer.Finish(t.resumeToken)
+	}
+}
@@ -0,0 +1,30 @@
+package chatcompletionstream
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/logging"
+)
+
+// recordUsage meters the tokens of the turn of req answered by model. Best
+// effort: the answer is saved already, a failure to meter it is logged
+// rather than failing the turn.
+func (uc *ChatCompletionUseCase) recordUsage(ctx context.Context, req completionRequest, model *entity.Model, promptTokens, completionTokens int) {
+	if uc.UsageGateway == nil {
+		return
+	}
+	if err := uc.meterUsage(ctx, req, model, promptTokens, completionTokens); err != nil {
+		logging.FromContext(ctx).Warn("error recording usage", logging.Err(err))
+	}
+}
+
+// meterUsage records the usage of the turn, in the unit of work of ctx when
+// it is in one.
+func (uc *ChatCompletionUseCase) meterUsage(ctx context.Context, req completionRequest, model *entity.Model, promptTokens, completionTokens int) error {
+	usage, err := entity.NewTurnUsage(uc.Clock.Now(), req.orgID, req.userID, model, promptTokens, completionTokens)
+	if err != nil {
+		return err
+	}
+	return uc.UsageGateway.RecordUsage(ctx, usage)
+}
@@ -0,0 +1,109 @@
+package chatcompletionstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/infra/streambuffer"
+)
+
+type WatchChatInputDTO struct {
+	ChatID string
+	UserID string
+}
+
+// WatchChatUseCase follows the generations of a chat whatever the request
+// running them: the chunks of the turn in progress, from the oldest still
+// buffered, then those of the turns started after it. Any number of clients
+// can watch the same chat.
+type WatchChatUseCase struct {
+	StreamBuffer *streambuffer.Buffer[ChatCompletionOutputDTO]
+}
+
+func NewWatchChatUseCase(streamBuffer *streambuffer.Buffer[ChatCompletionOutputDTO]) *WatchChatUseCase {
+	return &WatchChatUseCase{
+		StreamBuffer: streamBuffer,
+	}
+}
+
+// Execute hands the chunks of the chat to send until ctx ends or send fails.
+func (uc *WatchChatUseCase) Execute(ctx context.Context, input WatchChatInputDTO, send func(ChatCompletionOutputDTO) error) error {
+	if uc.StreamBuffer == nil {
+		return errors.New("watching chats is not enabled")
+	}
+	// a turn already over when the watch starts is not replayed
+	watched, _ := uc.StreamBuffer.Latest(input.ChatID)
+	if watched != "" && !uc.StreamBuffer.Finished(watched) {
+		watched = ""
+	}
+	for {
+		token, opened := uc.StreamBuffer.Latest(input.ChatID)
+		if token != "" && token != watched {
+			if err := uc.follow(ctx, token, input.UserID, send); err != nil {
+				return err
+			}
+			watched = token
+			continue
+		}
+		select {
+		case <-opened:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// follow hands the chunks of the turn of token to send until it finishes,
+// catching up when the watcher fell behind.
+func (uc *WatchChatUseCase) follow(ctx context.Context, token, userID string, send func(ChatCompletionOutputDTO) error) error {
+	lastSeq := -1
+	for {
+		replay, live, cancel, err := uc.StreamBuffer.Subscribe(token, userID, lastSeq)
+		if errors.Is(err, streambuffer.ErrStreamNotFound) {
+			return nil
+		}
+		if errors.Is(err, streambuffer.ErrSequenceExpired) {
+			// the chunks missed are gone, the gap shows in the seq
+			lastSeq = -1
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error watching chat: %w", err)
+		}
+		err = forward(ctx, replay, live, &lastSeq, send)
+		cancel()
+		if err != nil || uc.StreamBuffer.Finished(token) {
+			return err
+		}
+	}
+}
+
+func forward(ctx context.Context, replay []streambuffer.Event[ChatCompletionOutputDTO], live <-chan streambuffer.Event[ChatCompletionOutputDTO], lastSeq *int, send func(ChatCompletionOutputDTO) error) error {
+	for _, ev := range replay {
+		if ev.Seq <= *lastSeq {
+			continue
+		}
+		if err := send(ev.Value); err != nil {
+			return err
+		}
+		*lastSeq = ev.Seq
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-live:
+			if !ok {
+				return nil
+			}
+			if ev.Seq <= *lastSeq {
+				continue
+			}
+			if err := send(ev.Value); err != nil {
+				return err
+			}
+			*lastSeq = ev.Seq
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package chatcompletionstream
+
+import (
+	"context"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+type chatCreatedData struct {
+	ChatID string `json:"chat_id"`
+	UserID string `json:"user_id"`
+}
+
+type messageCompletedData struct {
+	ChatID    string `json:"chat_id"`
+	UserID    string `json:"user_id"`
+	MessageID string `json:"message_id"`
+	Content   string `json:"content"`
+	Stopped   bool   `json:"stopped"`
+	// IncompleteReason is set on the answers the provider failed to finish.
+	IncompleteReason string `json:"incomplete_reason,omitempty"`
+	RequestID        string `json:"request_id"`
+}
+
+type generationFailedData struct {
+	ChatID    string `json:"chat_id,omitempty"`
+	UserID    string `json:"user_id"`
+	Error     string `json:"error"`
+	RequestID string `json:"request_id"`
+}
+
+func (uc *ChatCompletionUseCase) notifyCompleted(ctx context.Context, t *turn, req completionRequest, assistant *entity.Message, stopped bool) {
+	if req.newChat {
+		uc.notifyWebhooks(ctx, req.orgID, entity.WebhookChatCreated, chatCreatedData{ChatID: t.chatID, UserID: req.userID})
+	}
+	uc.notifyWebhooks(ctx, req.orgID, entity.WebhookMessageCompleted, messageCompletedData{
+		ChatID:           t.chatID,
+		UserID:           req.userID,
+		MessageID:        assistant.ID,
+		Content:          assistant.Content,
+		Stopped:          stopped,
+		IncompleteReason: assistant.IncompleteReason,
+		RequestID:        t.requestID,
+	})
+}
+
+// notifyGenerationFailed notifies the turns that failed, not the ones the
+// client canceled.
+func (uc *ChatCompletionUseCase) notifyGenerationFailed(ctx context.Context, t *turn, req completionRequest, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	data := generationFailedData{
+		UserID:    req.userID,
+		Error:     err.Error(),
+		RequestID: t.requestID,
+	}
+	if !req.newChat {
+		data.ChatID = t.chatID
+	}
+	uc.notifyWebhooks(ctx, req.orgID, entity.WebhookGenerationFailed, data)
+}
+
+// notifyWebhooks queues event for the webhooks of the tenant subscribing to
+// it. Best effort: the turn is over, a failure to queue doesn't fail it.
+func (uc *ChatCompletionUseCase) notifyWebhooks(ctx context.Context, orgID, event string, data interface{}) {
+	if uc.WebhookGateway == nil || orgID == "" {
+		return
+	}
+	webhooks, err := uc.WebhookGateway.ListWebhooksByOrg(ctx, orgID)
+	if err != nil {
+		return
+	}
+	var deliveries []*entity.WebhookDelivery
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(event) {
+			continue
+		}
+		delivery, err := entity.NewWebhookDelivery(webhook, event, data, uc.Clock.Now())
+		if err != nil {
+			return
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if len(deliveries) > 0 {
+		_ = uc.WebhookGateway.CreateWebhookDeliveries(ctx, deliveries)
+	}
+}
@@ -0,0 +1,47 @@
+package deletechat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type DeleteChatInputDTO struct {
+	ChatID string
+	UserID string
+}
+
+type DeleteChatUseCase struct {
+	ChatGateway gateway.ChatGateway
+	Clock       clock.Clock
+}
+
+func NewDeleteChatUseCase(chatGateway gateway.ChatGateway, clk clock.Clock) *DeleteChatUseCase {
+	return &DeleteChatUseCase{
+		ChatGateway: chatGateway,
+		Clock:       clk,
+	}
+}
+
+// Execute soft-deletes the chat. It disappears for the user right away but the
+// data is only removed by the purge job once the retention window is over.
+func (uc *DeleteChatUseCase) Execute(ctx context.Context, input DeleteChatInputDTO) error {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return entity.ErrChatForbidden
+	}
+	if err := chat.Delete(uc.Clock.Now()); err != nil {
+		return fmt.Errorf("error deleting chat: %w", err)
+	}
+	err = uc.ChatGateway.DeleteChat(ctx, chat.ID, chat.DeletedAt)
+	if err != nil {
+		return fmt.Errorf("error persisting chat deletion: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package draft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type SaveDraftInputDTO struct {
+	ChatID  string
+	UserID  string
+	Content string
+}
+
+type DraftInputDTO struct {
+	ChatID string
+	UserID string
+}
+
+type DraftOutputDTO struct {
+	ChatID    string
+	Content   string
+	UpdatedAt time.Time
+}
+
+type SaveDraftUseCase struct {
+	ChatGateway  gateway.ChatGateway
+	DraftGateway gateway.DraftGateway
+	Clock        clock.Clock
+}
+
+func NewSaveDraftUseCase(chatGateway gateway.ChatGateway, draftGateway gateway.DraftGateway, clk clock.Clock) *SaveDraftUseCase {
+	return &SaveDraftUseCase{
+		ChatGateway:  chatGateway,
+		DraftGateway: draftGateway,
+		Clock:        clk,
+	}
+}
+
+// Execute saves the draft, an empty content discards it.
+func (uc *SaveDraftUseCase) Execute(ctx context.Context, input SaveDraftInputDTO) (*DraftOutputDTO, error) {
+	if err := checkOwner(ctx, uc.ChatGateway, input.ChatID, input.UserID); err != nil {
+		return nil, err
+	}
+	draft, err := entity.NewDraft(input.ChatID, input.UserID, input.Content, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating draft: %w", err)
+	}
+	if draft.Content == "" {
+		err = uc.DraftGateway.DeleteDraft(ctx, draft.ChatID, draft.UserID)
+	} else {
+		err = uc.DraftGateway.SaveDraft(ctx, draft)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error persisting draft: %w", err)
+	}
+	return &DraftOutputDTO{
+		ChatID:    draft.ChatID,
+		Content:   draft.Content,
+		UpdatedAt: draft.UpdatedAt,
+	}, nil
+}
+
+type GetDraftUseCase struct {
+	ChatGateway  gateway.ChatGateway
+	DraftGateway gateway.DraftGateway
+}
+
+func NewGetDraftUseCase(chatGateway gateway.ChatGateway, draftGateway gateway.DraftGateway) *GetDraftUseCase {
+	return &GetDraftUseCase{
+		ChatGateway:  chatGateway,
+		DraftGateway: draftGateway,
+	}
+}
+
+// Execute returns an empty draft when the user has none for the chat.
+func (uc *GetDraftUseCase) Execute(ctx context.Context, input DraftInputDTO) (*DraftOutputDTO, error) {
+	if err := checkOwner(ctx, uc.ChatGateway, input.ChatID, input.UserID); err != nil {
+		return nil, err
+	}
+	draft, err := uc.DraftGateway.FindDraft(ctx, input.ChatID, input.UserID)
+	if errors.Is(err, gateway.ErrDraftNotFound) {
+		return &DraftOutputDTO{ChatID: input.ChatID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching draft: %w", err)
+	}
+	return &DraftOutputDTO{
+		ChatID:    draft.ChatID,
+		Content:   draft.Content,
+		UpdatedAt: draft.UpdatedAt,
+	}, nil
+}
+
+type DiscardDraftUseCase struct {
+	ChatGateway  gateway.ChatGateway
+	DraftGateway gateway.DraftGateway
+}
+
+func NewDiscardDraftUseCase(chatGateway gateway.ChatGateway, draftGateway gateway.DraftGateway) *DiscardDraftUseCase {
+	return &DiscardDraftUseCase{
+		ChatGateway:  chatGateway,
+		DraftGateway: draftGateway,
+	}
+}
+
+func (uc *DiscardDraftUseCase) Execute(ctx context.Context, input DraftInputDTO) error {
+	if err := checkOwner(ctx, uc.ChatGateway, input.ChatID, input.UserID); err != nil {
+		return err
+	}
+	if err := uc.DraftGateway.DeleteDraft(ctx, input.ChatID, input.UserID); err != nil {
+		return fmt.Errorf("error deleting draft: %w", err)
+	}
+	return nil
+}
+
+func checkOwner(ctx context.Context, chatGateway gateway.ChatGateway, chatID, userID string) error {
+	chat, err := chatGateway.FindChatByID(ctx, chatID)
+	if err != nil {
+		return fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != userID {
+		return entity.ErrChatForbidden
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package enforceretention
+
+import (
+	"sync"
+	"time"
+)
+
+// TenantMetrics are the totals of the runs of the policy of a tenant.
+// Matched counts dry runs too, Purged only the actual ones.
+type TenantMetrics struct {
+	Runs         int64
+	Failures     int64
+	Matched      int64
+	Purged       int64
+	FilesDeleted int64
+	FailedFiles  int64
+	LastRunAt    time.Time
+	// LastMatched is what the last run found, the backlog of a dry run.
+	LastMatched int64
+}
+
+// SweepMetrics are the totals of the sweeps run beside the policies: the
+// expired ephemeral chats archived and purged, and the soft-deleted chats
+// purged.
+type SweepMetrics struct {
+	Runs            int64
+	Failures        int64
+	ExpiredArchived int64
+	ExpiredPurged   int64
+	DeletedPurged   int64
+}
+
+// MetricsSnapshot is a copy of the metrics, the policies by tenant.
+type MetricsSnapshot struct {
+	Tenants map[string]TenantMetrics
+	Sweeps  SweepMetrics
+}
+
+// Metrics counts what the retention job did, by tenant.
+type Metrics struct {
+	mu      sync.Mutex
+	tenants map[string]TenantMetrics
+	sweeps  SweepMetrics
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		tenants: make(map[string]TenantMetrics),
+	}
+}
+
+func (m *Metrics) observe(report PolicyReportDTO, at time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := m.tenants[report.OrgID]
+	n.Runs++
+	if err != nil {
+		n.Failures++
+	}
+	n.Matched += report.Matched
+	n.Purged += report.Purged
+	n.FilesDeleted += report.FilesDeleted
+	n.FailedFiles += int64(len(report.FailedFiles))
+	n.LastRunAt = at
+	n.LastMatched = report.Matched
+	m.tenants[report.OrgID] = n
+}
+
+// ObserveSweep counts a sweep of the expired and soft-deleted chats, which
+// run outside of this use case.
+func (m *Metrics) ObserveSweep(expiredArchived, expiredPurged, deletedPurged int64, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweeps.Runs++
+	if err != nil {
+		m.sweeps.Failures++
+	}
+	m.sweeps.ExpiredArchived += expiredArchived
+	m.sweeps.ExpiredPurged += expiredPurged
+	m.sweeps.DeletedPurged += deletedPurged
+}
+
+// Snapshot returns a copy of the metrics.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := MetricsSnapshot{
+		Tenants: make(map[string]TenantMetrics, len(m.tenants)),
+		Sweeps:  m.sweeps,
+	}
+	for orgID, n := range m.tenants {
+		snapshot.Tenants[orgID] = n
+	}
+	return snapshot
+}
@@ -0,0 +1,142 @@
+package enforceretention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+const batchSize = 100
+
+// OtherTenants is the tenant of the policy of the tenants without one of
+// their own, which also covers the chats opened outside of a tenant.
+const OtherTenants = "*"
+
+// PolicyInputDTO purges the chats of the tenant OrgID idle for longer than
+// IdleAfter.
+type PolicyInputDTO struct {
+	OrgID     string
+	IdleAfter time.Duration
+}
+
+type EnforceRetentionInputDTO struct {
+	Policies []PolicyInputDTO
+	// DryRun only counts the chats the policies would purge.
+	DryRun bool
+}
+
+// PolicyReportDTO is the outcome of one policy. Matched is the count of idle
+// chats found, Purged stays 0 on a dry run. FailedFiles are the storage keys
+// of the attachment files that could not be deleted, to retry by hand.
+type PolicyReportDTO struct {
+	OrgID        string
+	IdleBefore   time.Time
+	Matched      int64
+	Purged       int64
+	FilesDeleted int64
+	FailedFiles  []string
+}
+
+type EnforceRetentionOutputDTO struct {
+	DryRun  bool
+	Reports []PolicyReportDTO
+}
+
+// EnforceRetentionUseCase hard-deletes the chats left idle past the retention
+// window of their tenant, in the namespace of the context.
+type EnforceRetentionUseCase struct {
+	RetentionGateway gateway.RetentionGateway
+	FileStorage      gateway.FileStorage
+	Clock            clock.Clock
+	// Metrics is optional.
+	Metrics *Metrics
+}
+
+func NewEnforceRetentionUseCase(retentionGateway gateway.RetentionGateway, fileStorage gateway.FileStorage, clk clock.Clock, metrics *Metrics) *EnforceRetentionUseCase {
+	return &EnforceRetentionUseCase{
+		RetentionGateway: retentionGateway,
+		FileStorage:      fileStorage,
+		Clock:            clk,
+		Metrics:          metrics,
+	}
+}
+
+func (uc *EnforceRetentionUseCase) Execute(ctx context.Context, input EnforceRetentionInputDTO) (*EnforceRetentionOutputDTO, error) {
+	var tenants []string
+	for _, policy := range input.Policies {
+		if policy.OrgID == "" {
+			return nil, errors.New("retention policy without a tenant")
+		}
+		if policy.IdleAfter <= 0 {
+			return nil, fmt.Errorf("retention of tenant %s must be positive", policy.OrgID)
+		}
+		if policy.OrgID != OtherTenants {
+			tenants = append(tenants, policy.OrgID)
+		}
+	}
+	now := uc.Clock.Now()
+	output := &EnforceRetentionOutputDTO{DryRun: input.DryRun}
+	for _, policy := range input.Policies {
+		scope := gateway.RetentionScope{OrgID: policy.OrgID}
+		if policy.OrgID == OtherTenants {
+			scope = gateway.RetentionScope{Others: true, Except: tenants}
+		}
+		report, err := uc.enforce(ctx, policy, scope, now.Add(-policy.IdleAfter), input.DryRun)
+		output.Reports = append(output.Reports, *report)
+		uc.Metrics.observe(*report, now, err)
+		if err != nil {
+			return output, fmt.Errorf("error enforcing retention of tenant %s: %w", policy.OrgID, err)
+		}
+	}
+	return output, nil
+}
+
+func (uc *EnforceRetentionUseCase) enforce(ctx context.Context, policy PolicyInputDTO, scope gateway.RetentionScope, idleBefore time.Time, dryRun bool) (*PolicyReportDTO, error) {
+	report := &PolicyReportDTO{OrgID: policy.OrgID, IdleBefore: idleBefore}
+	if dryRun {
+		matched, err := uc.RetentionGateway.CountIdleChats(ctx, scope, idleBefore)
+		report.Matched = matched
+		return report, err
+	}
+	for {
+		purge, err := uc.RetentionGateway.PurgeIdleChats(ctx, scope, idleBefore, batchSize)
+		if err != nil {
+			return report, err
+		}
+		report.Matched += int64(len(purge.ChatIDs))
+		report.Purged += int64(len(purge.ChatIDs))
+		// files go after each batch, nothing references them once it is committed
+		for _, key := range purge.StorageKeys {
+			err := uc.FileStorage.Delete(ctx, key)
+			if err != nil && !errors.Is(err, gateway.ErrFileNotFound) {
+				report.FailedFiles = append(report.FailedFiles, key)
+				continue
+			}
+			report.FilesDeleted++
+		}
+		if len(purge.ChatIDs) < batchSize {
+			return report, nil
+		}
+	}
+}
+
+// Run enforces the retention every interval until ctx is done. Failed runs
+// are reported through onError and retried on the next tick.
+func (uc *EnforceRetentionUseCase) Run(ctx context.Context, interval time.Duration, input EnforceRetentionInputDTO, onError func(error)) {
+	ticker := uc.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if _, err := uc.Execute(ctx, input); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
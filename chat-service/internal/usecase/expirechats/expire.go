@@ -0,0 +1,80 @@
+package expirechats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+const batchSize = 100
+
+type ExpireChatsInputDTO struct {
+	// PurgeAfter is how long an expired ephemeral chat stays archived before it is purged.
+	PurgeAfter time.Duration
+}
+
+type ExpireChatsOutputDTO struct {
+	Archived int
+	Purged   int64
+}
+
+type ExpireChatsUseCase struct {
+	ChatGateway gateway.ChatGateway
+	Clock       clock.Clock
+}
+
+func NewExpireChatsUseCase(chatGateway gateway.ChatGateway, clk clock.Clock) *ExpireChatsUseCase {
+	return &ExpireChatsUseCase{
+		ChatGateway: chatGateway,
+		Clock:       clk,
+	}
+}
+
+func (uc *ExpireChatsUseCase) Execute(ctx context.Context, input ExpireChatsInputDTO) (*ExpireChatsOutputDTO, error) {
+	now := uc.Clock.Now()
+	output := &ExpireChatsOutputDTO{}
+	for {
+		chats, err := uc.ChatGateway.FindExpiredChats(ctx, now, batchSize)
+		if err != nil {
+			return output, fmt.Errorf("error fetching expired chats: %w", err)
+		}
+		for _, chat := range chats {
+			if err := chat.Archive(now); err != nil {
+				return output, fmt.Errorf("error archiving chat %s: %w", chat.ID, err)
+			}
+			if err := uc.ChatGateway.SaveChat(ctx, chat); err != nil {
+				return output, fmt.Errorf("error saving chat %s: %w", chat.ID, err)
+			}
+			output.Archived++
+		}
+		if len(chats) < batchSize {
+			break
+		}
+	}
+	purged, err := uc.ChatGateway.PurgeExpiredChats(ctx, now.Add(-input.PurgeAfter))
+	if err != nil {
+		return output, fmt.Errorf("error purging expired chats: %w", err)
+	}
+	output.Purged = purged
+	return output, nil
+}
+
+// Run executes the expiry every interval until ctx is done. Failed runs are
+// reported through onError and retried on the next tick.
+func (uc *ExpireChatsUseCase) Run(ctx context.Context, interval time.Duration, input ExpireChatsInputDTO, onError func(error)) {
+	ticker := uc.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if _, err := uc.Execute(ctx, input); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
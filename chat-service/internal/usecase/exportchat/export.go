@@ -0,0 +1,98 @@
+package exportchat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+const FormatVersion = 1
+
+type ExportChatInputDTO struct {
+	ChatID string
+	UserID string
+}
+
+type ExportedMessageDTO struct {
+	ID        string    `json:"id"`
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Tokens    int       `json:"tokens"`
+	Erased    bool      `json:"erased,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportedChatDTO struct {
+	FormatVersion int                  `json:"format_version"`
+	ID            string               `json:"id"`
+	Title         string               `json:"title,omitempty"`
+	Status        string               `json:"status"`
+	Tags          []string             `json:"tags,omitempty"`
+	Model         string               `json:"model"`
+	ModelMaxToken int                  `json:"model_max_token"`
+	Temperature   float32              `json:"temperature"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+	Messages      []ExportedMessageDTO `json:"messages"`
+}
+
+type ExportChatUseCase struct {
+	ChatGateway gateway.ChatGateway
+}
+
+func NewExportChatUseCase(chatGateway gateway.ChatGateway) *ExportChatUseCase {
+	return &ExportChatUseCase{
+		ChatGateway: chatGateway,
+	}
+}
+
+func (uc *ExportChatUseCase) Execute(ctx context.Context, input ExportChatInputDTO) (*ExportedChatDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	if !chat.AllowsExport() {
+		return nil, errors.New("ephemeral chats can't be exported")
+	}
+	return NewExportedChat(chat), nil
+}
+
+func NewExportedChat(chat *entity.Chat) *ExportedChatDTO {
+	exported := &ExportedChatDTO{
+		FormatVersion: FormatVersion,
+		ID:            chat.ID,
+		Title:         chat.Title,
+		Status:        chat.Status,
+		Tags:          chat.Tags,
+		Model:         chat.Config.Model.GetModelName(),
+		ModelMaxToken: chat.Config.Model.GetModelMaxTokens(),
+		Temperature:   chat.Config.Temperature,
+		CreatedAt:     chat.CreatedAt,
+		UpdatedAt:     chat.UpdatedAt,
+	}
+	for _, msg := range chat.ErasedMessages {
+		exported.Messages = append(exported.Messages, newExportedMessage(msg, true))
+	}
+	for _, msg := range chat.Messages {
+		exported.Messages = append(exported.Messages, newExportedMessage(msg, false))
+	}
+	return exported
+}
+
+func newExportedMessage(msg *entity.Message, erased bool) ExportedMessageDTO {
+	return ExportedMessageDTO{
+		ID:        msg.ID,
+		Role:      msg.Role.String(),
+		Content:   msg.Content,
+		Tokens:    msg.GetQtdTokens(),
+		Erased:    erased,
+		CreatedAt: msg.CreatedAt,
+	}
+}
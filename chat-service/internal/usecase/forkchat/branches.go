@@ -0,0 +1,73 @@
+package forkchat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type ListBranchesInputDTO struct {
+	ChatID string
+	UserID string
+}
+
+type BranchOutputDTO struct {
+	ChatID              string
+	ParentChatID        string
+	ForkedFromMessageID string
+	Title               string
+	Branches            []BranchOutputDTO
+}
+
+// ListBranchesUseCase returns the tree of chats forked from a chat, recursively.
+type ListBranchesUseCase struct {
+	ChatGateway gateway.ChatGateway
+}
+
+func NewListBranchesUseCase(chatGateway gateway.ChatGateway) *ListBranchesUseCase {
+	return &ListBranchesUseCase{
+		ChatGateway: chatGateway,
+	}
+}
+
+func (uc *ListBranchesUseCase) Execute(ctx context.Context, input ListBranchesInputDTO) (*BranchOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	root := &BranchOutputDTO{
+		ChatID:              chat.ID,
+		ParentChatID:        chat.ParentChatID,
+		ForkedFromMessageID: chat.ForkedFromMessageID,
+		Title:               chat.Title,
+	}
+	if err := uc.fill(ctx, root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func (uc *ListBranchesUseCase) fill(ctx context.Context, node *BranchOutputDTO) error {
+	children, err := uc.ChatGateway.FindChatsByParentID(ctx, node.ChatID)
+	if err != nil {
+		return fmt.Errorf("error fetching branches: %w", err)
+	}
+	for _, child := range children {
+		branch := BranchOutputDTO{
+			ChatID:              child.ID,
+			ParentChatID:        child.ParentChatID,
+			ForkedFromMessageID: child.ForkedFromMessageID,
+			Title:               child.Title,
+		}
+		if err := uc.fill(ctx, &branch); err != nil {
+			return err
+		}
+		node.Branches = append(node.Branches, branch)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package forkchat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type ForkChatInputDTO struct {
+	ChatID    string
+	UserID    string
+	MessageID string
+}
+
+type ForkChatOutputDTO struct {
+	ChatID              string
+	ParentChatID        string
+	ForkedFromMessageID string
+	UserID              string
+}
+
+type ForkChatUseCase struct {
+	ChatGateway gateway.ChatGateway
+	Clock       clock.Clock
+}
+
+func NewForkChatUseCase(chatGateway gateway.ChatGateway, clk clock.Clock) *ForkChatUseCase {
+	return &ForkChatUseCase{
+		ChatGateway: chatGateway,
+		Clock:       clk,
+	}
+}
+
+func (uc *ForkChatUseCase) Execute(ctx context.Context, input ForkChatInputDTO) (*ForkChatOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	fork, err := chat.Fork(input.MessageID, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error forking chat: %w", err)
+	}
+	err = uc.ChatGateway.CreateChat(ctx, fork)
+	if err != nil {
+		return nil, fmt.Errorf("error persisting forked chat: %w", err)
+	}
+	return &ForkChatOutputDTO{
+		ChatID:              fork.ID,
+		ParentChatID:        fork.ParentChatID,
+		ForkedFromMessageID: fork.ForkedFromMessageID,
+		UserID:              fork.UserID,
+	}, nil
+}
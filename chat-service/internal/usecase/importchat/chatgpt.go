@@ -0,0 +1,123 @@
+package importchat
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// chatGPTConversation is a conversation of the conversations.json of a
+// ChatGPT data export. Messages form a tree through parent and children, the
+// branch shown to the user ends at current_node.
+type chatGPTConversation struct {
+	ID             string                 `json:"id"`
+	ConversationID string                 `json:"conversation_id"`
+	Title          string                 `json:"title"`
+	CreateTime     float64                `json:"create_time"`
+	UpdateTime     float64                `json:"update_time"`
+	CurrentNode    string                 `json:"current_node"`
+	Mapping        map[string]chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	ID      string          `json:"id"`
+	Parent  string          `json:"parent"`
+	Message *chatGPTMessage `json:"message"`
+}
+
+type chatGPTMessage struct {
+	ID     string `json:"id"`
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	CreateTime float64 `json:"create_time"`
+	Content    struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+	} `json:"content"`
+}
+
+func decodeChatGPT(items []json.RawMessage) ([]importedChat, error) {
+	chats := make([]importedChat, 0, len(items))
+	for i, item := range items {
+		var conversation chatGPTConversation
+		if err := json.Unmarshal(item, &conversation); err != nil {
+			return nil, fmt.Errorf("invalid conversation %d: %w", i, err)
+		}
+		id := conversation.ConversationID
+		if id == "" {
+			id = conversation.ID
+		}
+		chat := importedChat{
+			SourceID:    id,
+			Title:       conversation.Title,
+			Temperature: 1,
+			CreatedAt:   unixTime(conversation.CreateTime),
+			UpdatedAt:   unixTime(conversation.UpdateTime),
+		}
+		for _, node := range conversation.thread() {
+			m := node.Message
+			content := m.text()
+			if content == "" {
+				// hidden nodes and non text content such as images or code runs
+				continue
+			}
+			chat.Messages = append(chat.Messages, importedMessage{
+				ID:        m.ID,
+				Role:      m.Author.Role,
+				Content:   content,
+				CreatedAt: unixTime(m.CreateTime),
+			})
+		}
+		chats = append(chats, chat)
+	}
+	return chats, nil
+}
+
+// thread returns the nodes with a message from the root to the current node,
+// the branch of the conversation the user ended on.
+func (c chatGPTConversation) thread() []chatGPTNode {
+	var nodes []chatGPTNode
+	visited := make(map[string]bool)
+	for id := c.CurrentNode; id != "" && !visited[id]; {
+		visited[id] = true
+		node, ok := c.Mapping[id]
+		if !ok {
+			break
+		}
+		if node.Message != nil {
+			nodes = append(nodes, node)
+		}
+		id = node.Parent
+	}
+	for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}
+	return nodes
+}
+
+// text joins the string parts of a text message, other parts and content types
+// have no text to import.
+func (m *chatGPTMessage) text() string {
+	if m.Content.ContentType != "text" && m.Content.ContentType != "multimodal_text" {
+		return ""
+	}
+	var parts []string
+	for _, raw := range m.Content.Parts {
+		var part string
+		if err := json.Unmarshal(raw, &part); err == nil && strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func unixTime(seconds float64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC()
+}
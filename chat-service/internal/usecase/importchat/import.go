@@ -0,0 +1,342 @@
+package importchat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/redact"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/exportchat"
+)
+
+const (
+	FormatExport  = "export"
+	FormatChatGPT = "chatgpt"
+)
+
+// MaxChats is the most chats one import takes.
+const MaxChats = 1000
+
+type ImportConfigInputDTO struct {
+	Model         string
+	ModelMaxToken int
+	// InitialSystemMessage starts the imported chats that have no system
+	// message of their own.
+	InitialSystemMessage string
+}
+
+type ImportChatsInputDTO struct {
+	UserID string
+	// OrgID is the tenant the chats are imported in, empty outside of one.
+	OrgID string
+	// Data is one chat or an array of chats in the export format, or the
+	// conversations.json of a ChatGPT data export.
+	Data []byte
+}
+
+// ImportedChatDTO is a chat imported under ChatID. Redacted counts its
+// messages secrets were redacted from, like from the messages sent.
+type ImportedChatDTO struct {
+	SourceID string
+	ChatID   string
+	Title    string
+	Messages int
+	Redacted int
+}
+
+// SkippedItemDTO is a chat or a message left out of the import. MessageID is
+// empty when the whole chat was skipped.
+type SkippedItemDTO struct {
+	SourceID  string
+	MessageID string
+	Reason    string
+}
+
+type ImportChatsOutputDTO struct {
+	Format   string
+	Imported []ImportedChatDTO
+	Skipped  []SkippedItemDTO
+}
+
+// importedChat is a chat decoded from any supported format.
+type importedChat struct {
+	SourceID string
+	Title    string
+	Status   string
+	Tags     []string
+	// Temperature is 1 when the format has none.
+	Temperature float32
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Messages    []importedMessage
+}
+
+type importedMessage struct {
+	ID        string
+	Role      string
+	Content   string
+	CreatedAt time.Time
+}
+
+// ImportChatsUseCase is the inverse of the export: it creates the chats of a
+// file through the domain entities. Chats get IDs of their own and keep the
+// one of the file as source ID, so importing a file twice skips the chats the
+// user already imported from it as duplicates. Those the user deleted since
+// are imported again.
+type ImportChatsUseCase struct {
+	ChatGateway gateway.ChatGateway
+	Config      ImportConfigInputDTO
+	Clock       clock.Clock
+}
+
+func NewImportChatsUseCase(chatGateway gateway.ChatGateway, config ImportConfigInputDTO, clk clock.Clock) *ImportChatsUseCase {
+	return &ImportChatsUseCase{
+		ChatGateway: chatGateway,
+		Config:      config,
+		Clock:       clk,
+	}
+}
+
+func (uc *ImportChatsUseCase) Execute(ctx context.Context, input ImportChatsInputDTO) (*ImportChatsOutputDTO, error) {
+	if input.UserID == "" {
+		return nil, errors.New("user id is empty")
+	}
+	format, chats, err := decode(input.Data)
+	if err != nil {
+		return nil, err
+	}
+	if len(chats) > MaxChats {
+		return nil, fmt.Errorf("import has %d chats, at most %d are allowed", len(chats), MaxChats)
+	}
+	output := &ImportChatsOutputDTO{Format: format}
+	seen := make(map[string]bool)
+	for _, imported := range chats {
+		if imported.SourceID == "" {
+			output.Skipped = append(output.Skipped, SkippedItemDTO{Reason: "chat has no id"})
+			continue
+		}
+		if seen[imported.SourceID] {
+			output.Skipped = append(output.Skipped, SkippedItemDTO{SourceID: imported.SourceID, Reason: "duplicate chat in the import"})
+			continue
+		}
+		seen[imported.SourceID] = true
+		existing, err := uc.ChatGateway.ListChats(ctx, gateway.ChatFilter{UserID: input.UserID, SourceID: imported.SourceID, Limit: 1})
+		if err != nil {
+			return output, fmt.Errorf("error fetching chat: %w", err)
+		}
+		if len(existing) > 0 {
+			output.Skipped = append(output.Skipped, SkippedItemDTO{SourceID: imported.SourceID, Reason: "duplicate, chat already imported"})
+			continue
+		}
+		redacted := redactSecrets(&imported)
+		chat, skipped, err := uc.newChat(input, imported)
+		output.Skipped = append(output.Skipped, skipped...)
+		if err != nil {
+			output.Skipped = append(output.Skipped, SkippedItemDTO{SourceID: imported.SourceID, Reason: err.Error()})
+			continue
+		}
+		if err := uc.ChatGateway.CreateChat(ctx, chat); err != nil {
+			return output, fmt.Errorf("error creating chat: %w", err)
+		}
+		output.Imported = append(output.Imported, ImportedChatDTO{
+			SourceID: imported.SourceID,
+			ChatID:   chat.ID,
+			Title:    chat.Title,
+			Messages: len(chat.Messages) + len(chat.ErasedMessages),
+			Redacted: redacted,
+		})
+	}
+	return output, nil
+}
+
+// redactSecrets replaces the secrets of the messages of imported, it returns
+// how many messages had some.
+func redactSecrets(imported *importedChat) int {
+	redacted := 0
+	for i, m := range imported.Messages {
+		content, secrets := redact.Secrets(m.Content)
+		if len(secrets) > 0 {
+			imported.Messages[i].Content = content
+			redacted++
+		}
+	}
+	return redacted
+}
+
+// newChat builds the chat of imported. The invalid messages are skipped, a
+// chat left without any user or assistant message is not imported.
+func (uc *ImportChatsUseCase) newChat(input ImportChatsInputDTO, imported importedChat) (*entity.Chat, []SkippedItemDTO, error) {
+	model := entity.NewModel(uc.Config.Model, uc.Config.ModelMaxToken)
+	now := uc.Clock.Now()
+	var skipped []SkippedItemDTO
+	skip := func(m importedMessage, reason string) {
+		skipped = append(skipped, SkippedItemDTO{SourceID: imported.SourceID, MessageID: m.ID, Reason: reason})
+	}
+	systemMessage := uc.Config.InitialSystemMessage
+	var messages []*entity.Message
+	seen := make(map[string]bool)
+	for i, im := range imported.Messages {
+		if im.ID != "" && seen[im.ID] {
+			skip(im, "duplicate message")
+			continue
+		}
+		seen[im.ID] = true
+		role, err := entity.ParseRole(im.Role)
+		if err != nil {
+			skip(im, err.Error())
+			continue
+		}
+		if role == entity.RoleSystem {
+			if i == 0 && strings.TrimSpace(im.Content) != "" {
+				// the chat's own system message replaces the default one
+				systemMessage = im.Content
+			} else {
+				skip(im, "system message inside the chat")
+			}
+			continue
+		}
+		if role != entity.RoleUser && role != entity.RoleAssistant {
+			// tool results make no sense without the calls that produced them
+			skip(im, fmt.Sprintf("%s messages are not imported", role))
+			continue
+		}
+		m, err := entity.NewMessage(role, im.Content, model, now)
+		if err != nil {
+			skip(im, err.Error())
+			continue
+		}
+		if m.GetQtdTokens() > model.GetModelMaxTokens() {
+			skip(im, "message is longer than the model context")
+			continue
+		}
+		if !im.CreatedAt.IsZero() {
+			m.CreatedAt = im.CreatedAt
+		}
+		messages = append(messages, m)
+	}
+	if len(messages) == 0 {
+		return nil, skipped, errors.New("chat has no message to import")
+	}
+	initial, err := entity.NewMessage(entity.RoleSystem, systemMessage, model, now)
+	if err != nil {
+		return nil, skipped, fmt.Errorf("invalid system message: %w", err)
+	}
+	chat, err := entity.NewChat(input.UserID, initial, &entity.ChatConfig{
+		Model:       model,
+		Temperature: imported.Temperature,
+		TopP:        1,
+		N:           1,
+	}, now)
+	if err != nil {
+		return nil, skipped, err
+	}
+	chat.OrgID = input.OrgID
+	chat.SourceID = imported.SourceID
+	for _, m := range messages {
+		if err := chat.AddMessage(m, now); err != nil {
+			return nil, skipped, err
+		}
+	}
+	if title := strings.TrimSpace(imported.Title); title != "" {
+		if err := chat.SetTitle(title); err != nil {
+			skipped = append(skipped, SkippedItemDTO{SourceID: imported.SourceID, Reason: "title: " + err.Error()})
+		}
+	}
+	for _, tag := range imported.Tags {
+		if _, err := chat.AddTag(tag); err != nil {
+			skipped = append(skipped, SkippedItemDTO{SourceID: imported.SourceID, Reason: "tag " + tag + ": " + err.Error()})
+		}
+	}
+	switch imported.Status {
+	case entity.ChatStatusEnded:
+		err = chat.EndChat(now)
+	case entity.ChatStatusArchived:
+		err = chat.Archive(now)
+	}
+	if err != nil {
+		return nil, skipped, err
+	}
+	if !imported.CreatedAt.IsZero() {
+		chat.CreatedAt = imported.CreatedAt
+		initial.CreatedAt = imported.CreatedAt
+	}
+	if !imported.UpdatedAt.IsZero() {
+		chat.UpdatedAt = imported.UpdatedAt
+	}
+	return chat, skipped, nil
+}
+
+// decode detects the format of data: export files hold an object or an array
+// of objects with a format_version, ChatGPT ones an array of conversations
+// with a mapping.
+func decode(data []byte) (string, []importedChat, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return "", nil, errors.New("import is empty")
+	}
+	var items []json.RawMessage
+	if data[0] == '{' {
+		items = []json.RawMessage{data}
+	} else if err := json.Unmarshal(data, &items); err != nil {
+		return "", nil, fmt.Errorf("invalid import: %w", err)
+	}
+	if len(items) == 0 {
+		return "", nil, errors.New("import has no chats")
+	}
+	var probe struct {
+		FormatVersion int             `json:"format_version"`
+		Mapping       json.RawMessage `json:"mapping"`
+	}
+	if err := json.Unmarshal(items[0], &probe); err != nil {
+		return "", nil, fmt.Errorf("invalid import: %w", err)
+	}
+	switch {
+	case probe.FormatVersion > 0:
+		chats, err := decodeExport(items)
+		return FormatExport, chats, err
+	case len(probe.Mapping) > 0:
+		chats, err := decodeChatGPT(items)
+		return FormatChatGPT, chats, err
+	}
+	return "", nil, errors.New("unknown import format")
+}
+
+func decodeExport(items []json.RawMessage) ([]importedChat, error) {
+	chats := make([]importedChat, 0, len(items))
+	for i, item := range items {
+		var exported exportchat.ExportedChatDTO
+		if err := json.Unmarshal(item, &exported); err != nil {
+			return nil, fmt.Errorf("invalid chat %d: %w", i, err)
+		}
+		if exported.FormatVersion > exportchat.FormatVersion {
+			return nil, fmt.Errorf("chat %d has format version %d, this service reads up to %d",
+				i, exported.FormatVersion, exportchat.FormatVersion)
+		}
+		chat := importedChat{
+			SourceID:    exported.ID,
+			Title:       exported.Title,
+			Status:      exported.Status,
+			Tags:        exported.Tags,
+			Temperature: exported.Temperature,
+			CreatedAt:   exported.CreatedAt,
+			UpdatedAt:   exported.UpdatedAt,
+		}
+		for _, m := range exported.Messages {
+			chat.Messages = append(chat.Messages, importedMessage{
+				ID:        m.ID,
+				Role:      m.Role,
+				Content:   m.Content,
+				CreatedAt: m.CreatedAt,
+			})
+		}
+		chats = append(chats, chat)
+	}
+	return chats, nil
+}
@@ -0,0 +1,99 @@
+package listchats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type ListChatsByUserInputDTO struct {
+	UserID string
+	// Page starts at 1, Size defaults to 20 and is capped to 100.
+	Page int
+	Size int
+}
+
+type ChatPageOutputDTO struct {
+	Chats      []ChatSummaryOutputDTO
+	Page       int
+	Size       int
+	Total      int
+	TotalPages int
+}
+
+// ListChatsByUserUseCase pages through the chats of a user, most recently
+// updated first, for history sidebars.
+type ListChatsByUserUseCase struct {
+	ChatGateway gateway.ChatGateway
+}
+
+func NewListChatsByUserUseCase(chatGateway gateway.ChatGateway) *ListChatsByUserUseCase {
+	return &ListChatsByUserUseCase{
+		ChatGateway: chatGateway,
+	}
+}
+
+func (uc *ListChatsByUserUseCase) Execute(ctx context.Context, input ListChatsByUserInputDTO) (*ChatPageOutputDTO, error) {
+	if input.UserID == "" {
+		return nil, errors.New("user id is empty")
+	}
+	if input.Page < 1 {
+		input.Page = 1
+	}
+	if input.Size <= 0 {
+		input.Size = defaultLimit
+	}
+	if input.Size > maxLimit {
+		input.Size = maxLimit
+	}
+	page, err := uc.ChatGateway.ListChatsByUser(ctx, input.UserID, input.Page, input.Size)
+	if err != nil {
+		return nil, fmt.Errorf("error listing chats: %w", err)
+	}
+	output := &ChatPageOutputDTO{
+		Chats:      make([]ChatSummaryOutputDTO, 0, len(page.Chats)),
+		Page:       input.Page,
+		Size:       input.Size,
+		Total:      page.Total,
+		TotalPages: (page.Total + input.Size - 1) / input.Size,
+	}
+	for _, chat := range page.Chats {
+		preview, err := uc.lastMessagePreview(ctx, chat.ID)
+		if err != nil {
+			return nil, err
+		}
+		output.Chats = append(output.Chats, ChatSummaryOutputDTO{
+			ChatID:             chat.ID,
+			Title:              chat.Title,
+			Status:             chat.Status,
+			Tags:               chat.Tags,
+			CreatedAt:          chat.CreatedAt,
+			UpdatedAt:          chat.UpdatedAt,
+			LastMessagePreview: preview,
+		})
+	}
+	return output, nil
+}
+
+// previewLength is the most runes of a last message preview.
+const previewLength = 100
+
+// lastMessagePreview returns the start of the last message of the chat on
+// one line, empty for a chat without messages.
+func (uc *ListChatsByUserUseCase) lastMessagePreview(ctx context.Context, chatID string) (string, error) {
+	page, err := uc.ChatGateway.ListMessages(ctx, chatID, gateway.MessageCursor{Limit: 1})
+	if err != nil {
+		return "", fmt.Errorf("error fetching the last message of chat %s: %w", chatID, err)
+	}
+	if len(page.Messages) == 0 {
+		return "", nil
+	}
+	preview := []rune(strings.Join(strings.Fields(page.Messages[len(page.Messages)-1].Content), " "))
+	if len(preview) > previewLength {
+		return string(preview[:previewLength-1]) + "…", nil
+	}
+	return string(preview), nil
+}
@@ -0,0 +1,91 @@
+package listchats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type ListChatsInputDTO struct {
+	UserID        string
+	Tag           string
+	Status        string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Limit         int
+	Offset        int
+}
+
+type ChatSummaryOutputDTO struct {
+	ChatID    string
+	Title     string
+	Status    string
+	Tags      []string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// LastMessagePreview is the start of the last message, only set by
+	// ListChatsByUser.
+	LastMessagePreview string
+}
+
+type ListChatsUseCase struct {
+	ChatGateway gateway.ChatGateway
+}
+
+func NewListChatsUseCase(chatGateway gateway.ChatGateway) *ListChatsUseCase {
+	return &ListChatsUseCase{
+		ChatGateway: chatGateway,
+	}
+}
+
+func (uc *ListChatsUseCase) Execute(ctx context.Context, input ListChatsInputDTO) ([]ChatSummaryOutputDTO, error) {
+	if input.UserID == "" {
+		return nil, errors.New("user id is empty")
+	}
+	filter := gateway.ChatFilter{
+		UserID:        input.UserID,
+		Status:        input.Status,
+		CreatedAfter:  input.CreatedAfter,
+		CreatedBefore: input.CreatedBefore,
+		Limit:         input.Limit,
+		Offset:        input.Offset,
+	}
+	if input.Tag != "" {
+		tag, err := entity.NormalizeTag(input.Tag)
+		if err != nil {
+			return nil, err
+		}
+		filter.Tag = tag
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLimit
+	}
+	if filter.Limit > maxLimit {
+		filter.Limit = maxLimit
+	}
+	chats, err := uc.ChatGateway.ListChats(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error listing chats: %w", err)
+	}
+	output := make([]ChatSummaryOutputDTO, 0, len(chats))
+	for _, chat := range chats {
+		output = append(output, ChatSummaryOutputDTO{
+			ChatID:    chat.ID,
+			Title:     chat.Title,
+			Status:    chat.Status,
+			Tags:      chat.Tags,
+			CreatedAt: chat.CreatedAt,
+			UpdatedAt: chat.UpdatedAt,
+		})
+	}
+	return output, nil
+}
@@ -0,0 +1,94 @@
+package listmessages
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+type ListMessagesInputDTO struct {
+	ChatID string
+	UserID string
+	// Cursor is the NextCursor of the previous page, empty for the newest messages.
+	Cursor string
+	Limit  int
+}
+
+type MessageOutputDTO struct {
+	ID      string
+	Role    string
+	Content string
+	Tokens  int
+	Pinned  bool
+	Starred bool
+	// IncompleteReason is why the answer was cut short, empty when complete.
+	IncompleteReason string
+	AnnotationCount  int
+	AttachmentCount  int
+	CreatedAt        time.Time
+}
+
+type MessagePageOutputDTO struct {
+	// Messages are oldest first, NextCursor fetches the older ones and is
+	// empty on the first message of the chat.
+	Messages   []MessageOutputDTO
+	NextCursor string
+}
+
+type ListMessagesUseCase struct {
+	ChatGateway gateway.ChatGateway
+}
+
+func NewListMessagesUseCase(chatGateway gateway.ChatGateway) *ListMessagesUseCase {
+	return &ListMessagesUseCase{
+		ChatGateway: chatGateway,
+	}
+}
+
+func (uc *ListMessagesUseCase) Execute(ctx context.Context, input ListMessagesInputDTO) (*MessagePageOutputDTO, error) {
+	if input.Limit <= 0 {
+		input.Limit = defaultLimit
+	}
+	if input.Limit > maxLimit {
+		input.Limit = maxLimit
+	}
+	page, err := uc.ChatGateway.ListMessages(ctx, input.ChatID, gateway.MessageCursor{
+		Before: input.Cursor,
+		Limit:  input.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing messages: %w", err)
+	}
+	if page.ChatUserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	output := &MessagePageOutputDTO{
+		Messages: make([]MessageOutputDTO, 0, len(page.Messages)),
+	}
+	for _, m := range page.Messages {
+		output.Messages = append(output.Messages, MessageOutputDTO{
+			ID:               m.ID,
+			Role:             m.Role.String(),
+			Content:          m.Content,
+			Tokens:           m.Tokens,
+			Pinned:           m.Pinned,
+			Starred:          m.Starred,
+			IncompleteReason: m.IncompleteReason,
+			AnnotationCount:  len(m.Annotations),
+			AttachmentCount:  len(m.Attachments),
+			CreatedAt:        m.CreatedAt,
+		})
+	}
+	if page.Next != nil {
+		output.NextCursor = page.Next.Before
+	}
+	return output, nil
+}
@@ -0,0 +1,34 @@
+package persona
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type CreatePersonaUseCase struct {
+	PersonaGateway gateway.PersonaGateway
+	Clock          clock.Clock
+}
+
+func NewCreatePersonaUseCase(personaGateway gateway.PersonaGateway, clk clock.Clock) *CreatePersonaUseCase {
+	return &CreatePersonaUseCase{
+		PersonaGateway: personaGateway,
+		Clock:          clk,
+	}
+}
+
+func (uc *CreatePersonaUseCase) Execute(ctx context.Context, input PersonaInputDTO) (*PersonaOutputDTO, error) {
+	persona, err := entity.NewPersona(input.Name, input.SystemPrompt, input.chatConfig(), input.Tools, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating persona: %w", err)
+	}
+	err = uc.PersonaGateway.CreatePersona(ctx, persona)
+	if err != nil {
+		return nil, fmt.Errorf("error persisting persona: %w", err)
+	}
+	return newOutput(persona), nil
+}
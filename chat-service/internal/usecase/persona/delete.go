@@ -0,0 +1,26 @@
+package persona
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type DeletePersonaUseCase struct {
+	PersonaGateway gateway.PersonaGateway
+}
+
+func NewDeletePersonaUseCase(personaGateway gateway.PersonaGateway) *DeletePersonaUseCase {
+	return &DeletePersonaUseCase{
+		PersonaGateway: personaGateway,
+	}
+}
+
+func (uc *DeletePersonaUseCase) Execute(ctx context.Context, personaID string) error {
+	err := uc.PersonaGateway.DeletePersona(ctx, personaID)
+	if err != nil {
+		return fmt.Errorf("error deleting persona: %w", err)
+	}
+	return nil
+}
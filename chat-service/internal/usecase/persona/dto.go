@@ -0,0 +1,65 @@
+package persona
+
+import "github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+
+type PersonaInputDTO struct {
+	Name             string
+	SystemPrompt     string
+	Model            string
+	ModelMaxToken    int
+	Temperature      float32
+	TopP             float32
+	N                int
+	Stop             []string
+	MaxTokens        int
+	PresencePenalty  float32
+	FrequencyPenalty float32
+	Tools            []string
+}
+
+type PersonaOutputDTO struct {
+	ID               string
+	Name             string
+	SystemPrompt     string
+	Model            string
+	ModelMaxToken    int
+	Temperature      float32
+	TopP             float32
+	N                int
+	Stop             []string
+	MaxTokens        int
+	PresencePenalty  float32
+	FrequencyPenalty float32
+	Tools            []string
+}
+
+func (input PersonaInputDTO) chatConfig() entity.ChatConfig {
+	return entity.ChatConfig{
+		Model:            entity.NewModel(input.Model, input.ModelMaxToken),
+		Temperature:      input.Temperature,
+		TopP:             input.TopP,
+		N:                input.N,
+		Stop:             input.Stop,
+		MaxTokens:        input.MaxTokens,
+		PresencePenalty:  input.PresencePenalty,
+		FrequencyPenalty: input.FrequencyPenalty,
+	}
+}
+
+func newOutput(p *entity.Persona) *PersonaOutputDTO {
+	return &PersonaOutputDTO{
+		ID:               p.ID,
+		Name:             p.Name,
+		SystemPrompt:     p.SystemPrompt,
+		Model:            p.Config.Model.GetModelName(),
+		ModelMaxToken:    p.Config.Model.GetModelMaxTokens(),
+		Temperature:      p.Config.Temperature,
+		TopP:             p.Config.TopP,
+		N:                p.Config.N,
+		Stop:             p.Config.Stop,
+		MaxTokens:        p.Config.MaxTokens,
+		PresencePenalty:  p.Config.PresencePenalty,
+		FrequencyPenalty: p.Config.FrequencyPenalty,
+		Tools:            p.Tools,
+	}
+}
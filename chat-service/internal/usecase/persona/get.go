@@ -0,0 +1,26 @@
+package persona
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type GetPersonaUseCase struct {
+	PersonaGateway gateway.PersonaGateway
+}
+
+func NewGetPersonaUseCase(personaGateway gateway.PersonaGateway) *GetPersonaUseCase {
+	return &GetPersonaUseCase{
+		PersonaGateway: personaGateway,
+	}
+}
+
+func (uc *GetPersonaUseCase) Execute(ctx context.Context, personaID string) (*PersonaOutputDTO, error) {
+	persona, err := uc.PersonaGateway.FindPersonaByID(ctx, personaID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching persona: %w", err)
+	}
+	return newOutput(persona), nil
+}
@@ -0,0 +1,30 @@
+package persona
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type ListPersonasUseCase struct {
+	PersonaGateway gateway.PersonaGateway
+}
+
+func NewListPersonasUseCase(personaGateway gateway.PersonaGateway) *ListPersonasUseCase {
+	return &ListPersonasUseCase{
+		PersonaGateway: personaGateway,
+	}
+}
+
+func (uc *ListPersonasUseCase) Execute(ctx context.Context) ([]*PersonaOutputDTO, error) {
+	personas, err := uc.PersonaGateway.ListPersonas(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing personas: %w", err)
+	}
+	output := make([]*PersonaOutputDTO, 0, len(personas))
+	for _, persona := range personas {
+		output = append(output, newOutput(persona))
+	}
+	return output, nil
+}
@@ -0,0 +1,39 @@
+package persona
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type UpdatePersonaUseCase struct {
+	PersonaGateway gateway.PersonaGateway
+	Clock          clock.Clock
+}
+
+func NewUpdatePersonaUseCase(personaGateway gateway.PersonaGateway, clk clock.Clock) *UpdatePersonaUseCase {
+	return &UpdatePersonaUseCase{
+		PersonaGateway: personaGateway,
+		Clock:          clk,
+	}
+}
+
+// Execute replaces the persona definition. Chats already created from it keep
+// the config they were created with.
+func (uc *UpdatePersonaUseCase) Execute(ctx context.Context, personaID string, input PersonaInputDTO) (*PersonaOutputDTO, error) {
+	persona, err := uc.PersonaGateway.FindPersonaByID(ctx, personaID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching persona: %w", err)
+	}
+	err = persona.Update(input.Name, input.SystemPrompt, input.chatConfig(), input.Tools, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error updating persona: %w", err)
+	}
+	err = uc.PersonaGateway.SavePersona(ctx, persona)
+	if err != nil {
+		return nil, fmt.Errorf("error saving persona: %w", err)
+	}
+	return newOutput(persona), nil
+}
@@ -0,0 +1,63 @@
+package pinmessage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+// FlagMessageInputDTO changes the flags that are set, nil ones are left as they are.
+type FlagMessageInputDTO struct {
+	ChatID    string
+	MessageID string
+	UserID    string
+	Pinned    *bool
+	Starred   *bool
+}
+
+type FlagMessageOutputDTO struct {
+	MessageID string
+	Pinned    bool
+	Starred   bool
+}
+
+type FlagMessageUseCase struct {
+	ChatGateway gateway.ChatGateway
+}
+
+func NewFlagMessageUseCase(chatGateway gateway.ChatGateway) *FlagMessageUseCase {
+	return &FlagMessageUseCase{
+		ChatGateway: chatGateway,
+	}
+}
+
+func (uc *FlagMessageUseCase) Execute(ctx context.Context, input FlagMessageInputDTO) (*FlagMessageOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	message, err := chat.FindMessage(input.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	if input.Pinned != nil {
+		message.SetPinned(*input.Pinned)
+	}
+	if input.Starred != nil {
+		message.SetStarred(*input.Starred)
+	}
+	err = uc.ChatGateway.SetMessageFlags(ctx, chat.ID, message.ID, message.Pinned, message.Starred)
+	if err != nil {
+		return nil, fmt.Errorf("error persisting message flags: %w", err)
+	}
+	return &FlagMessageOutputDTO{
+		MessageID: message.ID,
+		Pinned:    message.Pinned,
+		Starred:   message.Starred,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package pinmessage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type ListPinnedMessagesInputDTO struct {
+	ChatID string
+	UserID string
+	// IncludeStarred also returns starred messages that are not pinned.
+	IncludeStarred bool
+}
+
+type PinnedMessageOutputDTO struct {
+	MessageID string
+	Role      string
+	Content   string
+	Pinned    bool
+	Starred   bool
+	CreatedAt time.Time
+}
+
+type ListPinnedMessagesUseCase struct {
+	ChatGateway gateway.ChatGateway
+}
+
+func NewListPinnedMessagesUseCase(chatGateway gateway.ChatGateway) *ListPinnedMessagesUseCase {
+	return &ListPinnedMessagesUseCase{
+		ChatGateway: chatGateway,
+	}
+}
+
+func (uc *ListPinnedMessagesUseCase) Execute(ctx context.Context, input ListPinnedMessagesInputDTO) ([]PinnedMessageOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	var output []PinnedMessageOutputDTO
+	// erased messages are older than the ones still in the context window
+	history := append(append([]*entity.Message(nil), chat.ErasedMessages...), chat.Messages...)
+	for _, msg := range history {
+		if !msg.Pinned && !(input.IncludeStarred && msg.Starred) {
+			continue
+		}
+		output = append(output, PinnedMessageOutputDTO{
+			MessageID: msg.ID,
+			Role:      msg.Role.String(),
+			Content:   msg.Content,
+			Pinned:    msg.Pinned,
+			Starred:   msg.Starred,
+			CreatedAt: msg.CreatedAt,
+		})
+	}
+	return output, nil
+}
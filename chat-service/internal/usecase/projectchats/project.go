@@ -0,0 +1,101 @@
+package projectchats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type ProjectChatsInputDTO struct {
+	ChatIDs []string
+}
+
+// ProjectChatsOutputDTO counts the summaries written and the ones removed for
+// chats gone from the write store.
+type ProjectChatsOutputDTO struct {
+	Projected int
+	Removed   int
+}
+
+// ProjectChatsUseCase refreshes the read model summaries of chats from their
+// current state in the write store. Events only tell which chats changed, the
+// summary is rebuilt from the chat, so projecting twice or out of order is
+// harmless.
+type ProjectChatsUseCase struct {
+	ChatGateway   gateway.ChatGateway
+	ChatReadModel gateway.ChatReadModel
+	Clock         clock.Clock
+}
+
+func NewProjectChatsUseCase(chatGateway gateway.ChatGateway, chatReadModel gateway.ChatReadModel, clk clock.Clock) *ProjectChatsUseCase {
+	return &ProjectChatsUseCase{
+		ChatGateway:   chatGateway,
+		ChatReadModel: chatReadModel,
+		Clock:         clk,
+	}
+}
+
+func (uc *ProjectChatsUseCase) Execute(ctx context.Context, input ProjectChatsInputDTO) (*ProjectChatsOutputDTO, error) {
+	output := &ProjectChatsOutputDTO{}
+	for _, chatID := range input.ChatIDs {
+		removed, err := uc.project(ctx, chatID)
+		if err != nil {
+			return output, err
+		}
+		if removed {
+			output.Removed++
+		} else {
+			output.Projected++
+		}
+	}
+	return output, nil
+}
+
+func (uc *ProjectChatsUseCase) project(ctx context.Context, chatID string) (removed bool, err error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, chatID)
+	if err != nil && errors.Is(err, gateway.ErrChatNotFound) {
+		// deleted, or purged
+		if err := uc.ChatReadModel.DeleteChatSummary(ctx, chatID); err != nil {
+			return false, fmt.Errorf("error deleting summary of chat %s: %w", chatID, err)
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error fetching chat %s: %w", chatID, err)
+	}
+	if err := uc.ChatReadModel.UpsertChatSummary(ctx, NewChatSummary(chat, uc.Clock)); err != nil {
+		return false, fmt.Errorf("error saving summary of chat %s: %w", chatID, err)
+	}
+	return false, nil
+}
+
+// NewChatSummary denormalizes chat into its read model summary.
+func NewChatSummary(chat *entity.Chat, clk clock.Clock) *gateway.ChatSummary {
+	summary := &gateway.ChatSummary{
+		ChatID:       chat.ID,
+		UserID:       chat.UserID,
+		ParentChatID: chat.ParentChatID,
+		Title:        chat.Title,
+		Status:       chat.Status,
+		Tags:         append([]string(nil), chat.Tags...),
+		MessageCount: chat.CountMessages(),
+		TokenUsage:   chat.TokenUsage,
+		CreatedAt:    chat.CreatedAt,
+		UpdatedAt:    chat.UpdatedAt,
+		Version:      chat.Version,
+		ProjectedAt:  clk.Now(),
+	}
+	if chat.Config != nil && chat.Config.Model != nil {
+		summary.Model = chat.Config.Model.GetModelName()
+	}
+	for _, m := range chat.Messages {
+		if m.CreatedAt.After(summary.LastMessageAt) {
+			summary.LastMessageAt = m.CreatedAt
+		}
+	}
+	return summary
+}
@@ -0,0 +1,70 @@
+package projectchats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+const rebuildBatchSize = 100
+
+type RebuildReadModelOutputDTO struct {
+	Projected int
+	// Purged counts the summaries of chats no longer in the write store.
+	Purged int64
+}
+
+// RebuildReadModelUseCase projects every chat of the namespace again, then
+// drops the summaries it didn't reach. It fills a new read model and repairs
+// the drift of one that missed events, like purges which publish none.
+// ChatGateway must list from the write store, not from the read model.
+type RebuildReadModelUseCase struct {
+	ChatGateway   gateway.ChatGateway
+	ChatReadModel gateway.ChatReadModel
+	Clock         clock.Clock
+}
+
+func NewRebuildReadModelUseCase(chatGateway gateway.ChatGateway, chatReadModel gateway.ChatReadModel, clk clock.Clock) *RebuildReadModelUseCase {
+	return &RebuildReadModelUseCase{
+		ChatGateway:   chatGateway,
+		ChatReadModel: chatReadModel,
+		Clock:         clk,
+	}
+}
+
+func (uc *RebuildReadModelUseCase) Execute(ctx context.Context) (*RebuildReadModelOutputDTO, error) {
+	startedAt := uc.Clock.Now()
+	project := NewProjectChatsUseCase(uc.ChatGateway, uc.ChatReadModel, uc.Clock)
+	output := &RebuildReadModelOutputDTO{}
+	for offset := 0; ; offset += rebuildBatchSize {
+		chats, err := uc.ChatGateway.ListChats(ctx, gateway.ChatFilter{Limit: rebuildBatchSize, Offset: offset})
+		if err != nil {
+			return output, fmt.Errorf("error listing chats: %w", err)
+		}
+		// the listing has no messages, each chat is loaded to count them
+		input := ProjectChatsInputDTO{ChatIDs: make([]string, 0, len(chats))}
+		for _, chat := range chats {
+			input.ChatIDs = append(input.ChatIDs, chat.ID)
+		}
+		projected, err := project.Execute(ctx, input)
+		if projected != nil {
+			output.Projected += projected.Projected
+		}
+		if err != nil {
+			return output, err
+		}
+		if len(chats) < rebuildBatchSize {
+			break
+		}
+	}
+	// a chat written during the rebuild is projected after startedAt either
+	// way, by the rebuild or by its events
+	purged, err := uc.ChatReadModel.PurgeStaleChatSummaries(ctx, startedAt)
+	if err != nil {
+		return output, fmt.Errorf("error purging stale summaries: %w", err)
+	}
+	output.Purged = purged
+	return output, nil
+}
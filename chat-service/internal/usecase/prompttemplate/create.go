@@ -0,0 +1,55 @@
+package prompttemplate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type CreatePromptTemplateInputDTO struct {
+	Name    string
+	Content string
+}
+
+type PromptTemplateOutputDTO struct {
+	ID        string
+	Name      string
+	Content   string
+	Variables []string
+}
+
+type CreatePromptTemplateUseCase struct {
+	PromptTemplateGateway gateway.PromptTemplateGateway
+	Clock                 clock.Clock
+}
+
+func NewCreatePromptTemplateUseCase(promptTemplateGateway gateway.PromptTemplateGateway, clk clock.Clock) *CreatePromptTemplateUseCase {
+	return &CreatePromptTemplateUseCase{
+		PromptTemplateGateway: promptTemplateGateway,
+		Clock:                 clk,
+	}
+}
+
+func (uc *CreatePromptTemplateUseCase) Execute(ctx context.Context, input CreatePromptTemplateInputDTO) (*PromptTemplateOutputDTO, error) {
+	template, err := entity.NewPromptTemplate(input.Name, input.Content, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating prompt template: %w", err)
+	}
+	err = uc.PromptTemplateGateway.CreatePromptTemplate(ctx, template)
+	if err != nil {
+		return nil, fmt.Errorf("error persisting prompt template: %w", err)
+	}
+	return newOutput(template), nil
+}
+
+func newOutput(template *entity.PromptTemplate) *PromptTemplateOutputDTO {
+	return &PromptTemplateOutputDTO{
+		ID:        template.ID,
+		Name:      template.Name,
+		Content:   template.Content,
+		Variables: template.Variables(),
+	}
+}
@@ -0,0 +1,26 @@
+package prompttemplate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type DeletePromptTemplateUseCase struct {
+	PromptTemplateGateway gateway.PromptTemplateGateway
+}
+
+func NewDeletePromptTemplateUseCase(promptTemplateGateway gateway.PromptTemplateGateway) *DeletePromptTemplateUseCase {
+	return &DeletePromptTemplateUseCase{
+		PromptTemplateGateway: promptTemplateGateway,
+	}
+}
+
+func (uc *DeletePromptTemplateUseCase) Execute(ctx context.Context, name string) error {
+	err := uc.PromptTemplateGateway.DeletePromptTemplate(ctx, name)
+	if err != nil {
+		return fmt.Errorf("error deleting prompt template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package prompttemplate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type ListPromptTemplatesUseCase struct {
+	PromptTemplateGateway gateway.PromptTemplateGateway
+}
+
+func NewListPromptTemplatesUseCase(promptTemplateGateway gateway.PromptTemplateGateway) *ListPromptTemplatesUseCase {
+	return &ListPromptTemplatesUseCase{
+		PromptTemplateGateway: promptTemplateGateway,
+	}
+}
+
+func (uc *ListPromptTemplatesUseCase) Execute(ctx context.Context) ([]*PromptTemplateOutputDTO, error) {
+	templates, err := uc.PromptTemplateGateway.ListPromptTemplates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing prompt templates: %w", err)
+	}
+	output := make([]*PromptTemplateOutputDTO, 0, len(templates))
+	for _, template := range templates {
+		output = append(output, newOutput(template))
+	}
+	return output, nil
+}
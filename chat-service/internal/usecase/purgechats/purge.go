@@ -0,0 +1,58 @@
+package purgechats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type PurgeChatsInputDTO struct {
+	// Retention is how long a soft-deleted chat is kept before being purged.
+	Retention time.Duration
+}
+
+type PurgeChatsOutputDTO struct {
+	Purged int64
+}
+
+type PurgeChatsUseCase struct {
+	ChatGateway gateway.ChatGateway
+	Clock       clock.Clock
+}
+
+func NewPurgeChatsUseCase(chatGateway gateway.ChatGateway, clk clock.Clock) *PurgeChatsUseCase {
+	return &PurgeChatsUseCase{
+		ChatGateway: chatGateway,
+		Clock:       clk,
+	}
+}
+
+func (uc *PurgeChatsUseCase) Execute(ctx context.Context, input PurgeChatsInputDTO) (*PurgeChatsOutputDTO, error) {
+	purged, err := uc.ChatGateway.PurgeDeletedChats(ctx, uc.Clock.Now().Add(-input.Retention))
+	if err != nil {
+		return nil, fmt.Errorf("error purging deleted chats: %w", err)
+	}
+	return &PurgeChatsOutputDTO{
+		Purged: purged,
+	}, nil
+}
+
+// Run executes the purge every interval until ctx is done. Failed runs are
+// reported through onError and retried on the next tick.
+func (uc *PurgeChatsUseCase) Run(ctx context.Context, interval time.Duration, input PurgeChatsInputDTO, onError func(error)) {
+	ticker := uc.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if _, err := uc.Execute(ctx, input); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package purgenamespace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+)
+
+type PurgeNamespaceInputDTO struct {
+	Namespace string
+	// Confirmation must repeat Namespace, as a guard against purging by mistake.
+	Confirmation string
+}
+
+type PurgeNamespaceOutputDTO struct {
+	Namespace string
+	Purged    int64
+}
+
+type PurgeNamespaceUseCase struct {
+	NamespaceGateway gateway.NamespaceGateway
+	// CurrentNamespace is the namespace this process serves, it can't be purged.
+	CurrentNamespace string
+}
+
+func NewPurgeNamespaceUseCase(namespaceGateway gateway.NamespaceGateway, currentNamespace string) *PurgeNamespaceUseCase {
+	return &PurgeNamespaceUseCase{
+		NamespaceGateway: namespaceGateway,
+		CurrentNamespace: currentNamespace,
+	}
+}
+
+func (uc *PurgeNamespaceUseCase) Execute(ctx context.Context, input PurgeNamespaceInputDTO) (*PurgeNamespaceOutputDTO, error) {
+	if err := namespace.Validate(input.Namespace); err != nil {
+		return nil, err
+	}
+	if input.Confirmation != input.Namespace {
+		return nil, errors.New("confirmation does not match the namespace")
+	}
+	if input.Namespace == uc.CurrentNamespace {
+		return nil, errors.New("can't purge the namespace this process is serving")
+	}
+	purged, err := uc.NamespaceGateway.PurgeNamespace(ctx, input.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("error purging namespace: %w", err)
+	}
+	return &PurgeNamespaceOutputDTO{
+		Namespace: input.Namespace,
+		Purged:    purged,
+	}, nil
+}
@@ -0,0 +1,84 @@
+package purgeuserdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type PurgeUserDataInputDTO struct {
+	UserID string
+	// Confirmation must repeat UserID, as a guard against erasing by mistake.
+	Confirmation string
+	// RequestedBy is who asked for the erasure, recorded with it.
+	RequestedBy string
+}
+
+// PurgeUserDataOutputDTO is the deletion report. FailedFiles are the storage
+// keys of the attachment files that could not be deleted, to retry by hand:
+// the rows referencing them are gone.
+type PurgeUserDataOutputDTO struct {
+	ErasureID    string
+	UserID       string
+	Chats        int64
+	Messages     int64
+	Annotations  int64
+	Attachments  int64
+	Embeddings   int64
+	FilesDeleted int64
+	FailedFiles  []string
+}
+
+// PurgeUserDataUseCase erases the data of a user, for right to erasure
+// requests: the chats with their messages, annotations and attachment files,
+// and the embeddings. An audit record of the erasure is stored with the purge.
+type PurgeUserDataUseCase struct {
+	UserDataGateway gateway.UserDataGateway
+	FileStorage     gateway.FileStorage
+	Clock           clock.Clock
+}
+
+func NewPurgeUserDataUseCase(userDataGateway gateway.UserDataGateway, fileStorage gateway.FileStorage, clk clock.Clock) *PurgeUserDataUseCase {
+	return &PurgeUserDataUseCase{
+		UserDataGateway: userDataGateway,
+		FileStorage:     fileStorage,
+		Clock:           clk,
+	}
+}
+
+func (uc *PurgeUserDataUseCase) Execute(ctx context.Context, input PurgeUserDataInputDTO) (*PurgeUserDataOutputDTO, error) {
+	if input.Confirmation != input.UserID {
+		return nil, errors.New("confirmation does not match the user id")
+	}
+	erasure, err := entity.NewUserErasure(input.UserID, input.RequestedBy, uc.Clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	purge, err := uc.UserDataGateway.PurgeUserData(ctx, erasure)
+	if err != nil {
+		return nil, fmt.Errorf("error purging user data: %w", err)
+	}
+	output := &PurgeUserDataOutputDTO{
+		ErasureID:   erasure.ID,
+		UserID:      erasure.UserID,
+		Chats:       erasure.Chats,
+		Messages:    erasure.Messages,
+		Annotations: erasure.Annotations,
+		Attachments: erasure.Attachments,
+		Embeddings:  erasure.Embeddings,
+	}
+	// files go last, nothing references them once the purge is committed
+	for _, key := range purge.StorageKeys {
+		err := uc.FileStorage.Delete(ctx, key)
+		if err != nil && !errors.Is(err, gateway.ErrFileNotFound) {
+			output.FailedFiles = append(output.FailedFiles, key)
+			continue
+		}
+		output.FilesDeleted++
+	}
+	return output, nil
+}
@@ -0,0 +1,103 @@
+package relayoutbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+const defaultBatchSize = 100
+
+type RelayOutboxInputDTO struct {
+	BatchSize int
+	// MaxAttempts bounds the attempts to publish an entry before it is
+	// dead-lettered, entity.OutboxMaxAttempts by default.
+	MaxAttempts int
+}
+
+type RelayOutboxOutputDTO struct {
+	Published int
+	Failed    int
+	// DeadLettered counts the failed entries out of attempts.
+	DeadLettered int
+}
+
+type RelayOutboxUseCase struct {
+	OutboxGateway gateway.OutboxGateway
+	Broker        gateway.EventBroker
+	Clock         clock.Clock
+}
+
+func NewRelayOutboxUseCase(outboxGateway gateway.OutboxGateway, broker gateway.EventBroker, clk clock.Clock) *RelayOutboxUseCase {
+	return &RelayOutboxUseCase{
+		OutboxGateway: outboxGateway,
+		Broker:        broker,
+		Clock:         clk,
+	}
+}
+
+// Execute publishes one batch of unpublished entries. Once an entry of a chat
+// fails, the next entries of that chat wait for the following run so consumers
+// see the events of a chat in order. An entry failing its last attempt is
+// dead-lettered and the chat goes on with the next one.
+func (uc *RelayOutboxUseCase) Execute(ctx context.Context, input RelayOutboxInputDTO) (*RelayOutboxOutputDTO, error) {
+	if input.BatchSize <= 0 {
+		input.BatchSize = defaultBatchSize
+	}
+	if input.MaxAttempts <= 0 {
+		input.MaxAttempts = entity.OutboxMaxAttempts
+	}
+	entries, err := uc.OutboxGateway.FindUnpublishedOutboxEntries(ctx, input.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching outbox entries: %w", err)
+	}
+	output := &RelayOutboxOutputDTO{}
+	blocked := make(map[string]bool)
+	for _, entry := range entries {
+		if blocked[entry.ChatID] {
+			continue
+		}
+		if err := uc.Broker.Publish(ctx, entry); err != nil {
+			output.Failed++
+			if entry.Attempts+1 >= input.MaxAttempts {
+				output.DeadLettered++
+				if err := uc.OutboxGateway.MarkOutboxEntryDeadLettered(ctx, entry.ID, err.Error(), uc.Clock.Now()); err != nil {
+					return output, fmt.Errorf("error dead-lettering outbox entry: %w", err)
+				}
+				continue
+			}
+			blocked[entry.ChatID] = true
+			if err := uc.OutboxGateway.MarkOutboxEntryFailed(ctx, entry.ID, err.Error()); err != nil {
+				return output, fmt.Errorf("error recording outbox entry failure: %w", err)
+			}
+			continue
+		}
+		if err := uc.OutboxGateway.MarkOutboxEntryPublished(ctx, entry.ID, uc.Clock.Now()); err != nil {
+			// the entry will be published again, which consumers tolerate
+			return output, fmt.Errorf("error marking outbox entry as published: %w", err)
+		}
+		output.Published++
+	}
+	return output, nil
+}
+
+// Run relays the outbox every interval until ctx is done. Failed runs are
+// reported through onError and retried on the next tick.
+func (uc *RelayOutboxUseCase) Run(ctx context.Context, interval time.Duration, input RelayOutboxInputDTO, onError func(error)) {
+	ticker := uc.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if _, err := uc.Execute(ctx, input); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
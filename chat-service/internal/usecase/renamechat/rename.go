@@ -0,0 +1,53 @@
+package renamechat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type RenameChatInputDTO struct {
+	ChatID string
+	UserID string
+	Title  string
+}
+
+type RenameChatOutputDTO struct {
+	ChatID string
+	Title  string
+}
+
+// RenameChatUseCase sets the title of a chat by hand. Titles are only
+// generated for chats without one, so it is never overwritten afterwards.
+type RenameChatUseCase struct {
+	ChatGateway gateway.ChatGateway
+}
+
+func NewRenameChatUseCase(chatGateway gateway.ChatGateway) *RenameChatUseCase {
+	return &RenameChatUseCase{
+		ChatGateway: chatGateway,
+	}
+}
+
+func (uc *RenameChatUseCase) Execute(ctx context.Context, input RenameChatInputDTO) (*RenameChatOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	if err := chat.SetTitle(input.Title); err != nil {
+		return nil, fmt.Errorf("error setting chat title: %w", err)
+	}
+	err = uc.ChatGateway.UpdateChatTitle(ctx, chat.ID, chat.Title)
+	if err != nil {
+		return nil, fmt.Errorf("error persisting chat title: %w", err)
+	}
+	return &RenameChatOutputDTO{
+		ChatID: chat.ID,
+		Title:  chat.Title,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package restorechats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/namespace"
+	"github.com/alecanutto/fclx/chat-service/internal/usecase/backupchats"
+)
+
+type RestoreChatsInputDTO struct {
+	Namespace string
+	// BackupID defaults to the latest backup of the namespace.
+	BackupID string
+}
+
+// FailedChatDTO is a chat of the backup that could not be restored.
+type FailedChatDTO struct {
+	ChatID string
+	Reason string
+}
+
+type RestoreChatsOutputDTO struct {
+	BackupID string
+	Restored int
+	// Skipped counts the chats already there, restoring again resumes an
+	// interrupted restore.
+	Skipped int
+	Failed  []FailedChatDTO
+}
+
+// RestoreChatsUseCase creates the chats of a backup that are missing from the
+// namespace, with their annotations and attachment metadata. Chats already
+// there are left as they are. ChatGateway and the other gateways must not be
+// scoped to a namespace.
+type RestoreChatsUseCase struct {
+	ChatGateway       gateway.ChatGateway
+	AnnotationGateway gateway.AnnotationGateway
+	AttachmentGateway gateway.AttachmentGateway
+	Storage           gateway.FileStorage
+}
+
+func NewRestoreChatsUseCase(chatGateway gateway.ChatGateway, annotationGateway gateway.AnnotationGateway, attachmentGateway gateway.AttachmentGateway, storage gateway.FileStorage) *RestoreChatsUseCase {
+	return &RestoreChatsUseCase{
+		ChatGateway:       chatGateway,
+		AnnotationGateway: annotationGateway,
+		AttachmentGateway: attachmentGateway,
+		Storage:           storage,
+	}
+}
+
+func (uc *RestoreChatsUseCase) Execute(ctx context.Context, input RestoreChatsInputDTO) (*RestoreChatsOutputDTO, error) {
+	if err := namespace.Validate(input.Namespace); err != nil {
+		return nil, err
+	}
+	ctx = namespace.NewContext(ctx, input.Namespace)
+	if input.BackupID == "" {
+		index, err := backupchats.ReadIndex(ctx, uc.Storage, input.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("error reading backup index: %w", err)
+		}
+		if len(index.Backups) == 0 {
+			return nil, fmt.Errorf("namespace %s has no backup", input.Namespace)
+		}
+		input.BackupID = index.Backups[len(index.Backups)-1].ID
+	}
+	manifest, err := backupchats.ReadManifest(ctx, uc.Storage, input.Namespace, input.BackupID)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Namespace != input.Namespace {
+		return nil, fmt.Errorf("backup %s is of namespace %s", manifest.ID, manifest.Namespace)
+	}
+	output := &RestoreChatsOutputDTO{BackupID: manifest.ID}
+	for _, key := range manifest.Parts {
+		err := backupchats.ReadPart(ctx, uc.Storage, key, func(chat *entity.Chat) error {
+			restored, err := uc.restore(ctx, chat)
+			if err != nil {
+				output.Failed = append(output.Failed, FailedChatDTO{ChatID: chat.ID, Reason: err.Error()})
+			} else if restored {
+				output.Restored++
+			} else {
+				output.Skipped++
+			}
+			return nil
+		})
+		if err != nil {
+			return output, err
+		}
+	}
+	return output, nil
+}
+
+func (uc *RestoreChatsUseCase) restore(ctx context.Context, chat *entity.Chat) (bool, error) {
+	if _, err := uc.ChatGateway.FindChatByID(ctx, chat.ID); err == nil {
+		return false, nil
+	} else if !errors.Is(err, gateway.ErrChatNotFound) {
+		return false, err
+	}
+	if err := chat.Validate(); err != nil {
+		return false, err
+	}
+	// annotations and attachments go through their own gateways, the chat
+	// gateways don't write them
+	var annotations []*entity.Annotation
+	var attachments []*entity.Attachment
+	for _, messages := range [][]*entity.Message{chat.ErasedMessages, chat.Messages} {
+		for _, m := range messages {
+			annotations = append(annotations, m.Annotations...)
+			attachments = append(attachments, m.Attachments...)
+			m.Annotations, m.Attachments = nil, nil
+		}
+	}
+	if err := uc.ChatGateway.CreateChat(ctx, chat); err != nil {
+		return false, err
+	}
+	for _, a := range annotations {
+		if err := uc.AnnotationGateway.CreateAnnotation(ctx, a); err != nil {
+			return true, fmt.Errorf("error restoring annotation %s: %w", a.ID, err)
+		}
+	}
+	for _, a := range attachments {
+		if err := uc.AttachmentGateway.CreateAttachment(ctx, a); err != nil {
+			return true, fmt.Errorf("error restoring attachment %s: %w", a.ID, err)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,117 @@
+package searchchats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+const (
+	defaultLimit   = 20
+	maxLimit       = 100
+	maxQueryLength = 256
+)
+
+type SearchChatsInputDTO struct {
+	UserID string
+	// Query matches the titles containing it, ignoring case.
+	Query  string
+	Tag    string
+	Status string
+	Limit  int
+	Offset int
+}
+
+type ChatSummaryOutputDTO struct {
+	ChatID        string
+	Title         string
+	Status        string
+	Tags          []string
+	Model         string
+	MessageCount  int
+	TokenUsage    int
+	LastMessageAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type SearchChatsOutputDTO struct {
+	Chats []ChatSummaryOutputDTO
+	Total int
+	// Limit is the limit the search ran with, once defaulted and capped.
+	Limit int
+}
+
+// SearchChatsUseCase searches the chats of a user on the read model, so UI
+// searches don't load the write store.
+type SearchChatsUseCase struct {
+	ChatReadModel gateway.ChatReadModel
+}
+
+func NewSearchChatsUseCase(chatReadModel gateway.ChatReadModel) *SearchChatsUseCase {
+	return &SearchChatsUseCase{
+		ChatReadModel: chatReadModel,
+	}
+}
+
+func (uc *SearchChatsUseCase) Execute(ctx context.Context, input SearchChatsInputDTO) (*SearchChatsOutputDTO, error) {
+	if input.UserID == "" {
+		return nil, errors.New("user id is empty")
+	}
+	filter := gateway.ChatSummaryFilter{
+		UserID: input.UserID,
+		Query:  strings.TrimSpace(input.Query),
+		Status: input.Status,
+		Limit:  input.Limit,
+		Offset: input.Offset,
+	}
+	if len(filter.Query) > maxQueryLength {
+		return nil, fmt.Errorf("query is longer than %d characters", maxQueryLength)
+	}
+	if input.Tag != "" {
+		tag, err := entity.NormalizeTag(input.Tag)
+		if err != nil {
+			return nil, err
+		}
+		filter.Tag = tag
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLimit
+	}
+	if filter.Limit > maxLimit {
+		filter.Limit = maxLimit
+	}
+	summaries, err := uc.ChatReadModel.ListChatSummaries(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error searching chats: %w", err)
+	}
+	total, err := uc.ChatReadModel.CountChatSummaries(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error counting chats: %w", err)
+	}
+	output := &SearchChatsOutputDTO{
+		Chats: make([]ChatSummaryOutputDTO, 0, len(summaries)),
+		Total: total,
+		Limit: filter.Limit,
+	}
+	for _, s := range summaries {
+		output.Chats = append(output.Chats, ChatSummaryOutputDTO{
+			ChatID:        s.ChatID,
+			Title:         s.Title,
+			Status:        s.Status,
+			Tags:          s.Tags,
+			Model:         s.Model,
+			MessageCount:  s.MessageCount,
+			TokenUsage:    s.TokenUsage,
+			LastMessageAt: s.LastMessageAt,
+			CreatedAt:     s.CreatedAt,
+			UpdatedAt:     s.UpdatedAt,
+		})
+	}
+	return output, nil
+}
@@ -0,0 +1,102 @@
+package searchmessages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+const (
+	defaultLimit   = 20
+	maxLimit       = 100
+	maxQueryLength = 256
+)
+
+type SearchMessagesInputDTO struct {
+	UserID string
+	Query  string
+	Limit  int
+	Offset int
+}
+
+type HighlightDTO struct {
+	Start int
+	End   int
+}
+
+type SearchHitOutputDTO struct {
+	ChatID        string
+	ChatTitle     string
+	ChatUpdatedAt time.Time
+	MessageID     string
+	Role          string
+	CreatedAt     time.Time
+	// Snippet is the part of the message around the matches, Highlights are
+	// their character ranges in it.
+	Snippet    string
+	Highlights []HighlightDTO
+}
+
+type SearchMessagesUseCase struct {
+	SearchGateway gateway.SearchGateway
+}
+
+func NewSearchMessagesUseCase(searchGateway gateway.SearchGateway) *SearchMessagesUseCase {
+	return &SearchMessagesUseCase{
+		SearchGateway: searchGateway,
+	}
+}
+
+func (uc *SearchMessagesUseCase) Execute(ctx context.Context, input SearchMessagesInputDTO) ([]SearchHitOutputDTO, error) {
+	if input.UserID == "" {
+		return nil, errors.New("user id is empty")
+	}
+	query := strings.TrimSpace(input.Query)
+	if query == "" {
+		return nil, errors.New("search query is empty")
+	}
+	if utf8.RuneCountInString(query) > maxQueryLength {
+		return nil, fmt.Errorf("search query is longer than %d characters", maxQueryLength)
+	}
+	if input.Limit <= 0 {
+		input.Limit = defaultLimit
+	}
+	if input.Limit > maxLimit {
+		input.Limit = maxLimit
+	}
+	if input.Offset < 0 {
+		input.Offset = 0
+	}
+	hits, err := uc.SearchGateway.SearchMessages(ctx, gateway.MessageSearchQuery{
+		UserID: input.UserID,
+		Text:   query,
+		Limit:  input.Limit,
+		Offset: input.Offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error searching messages: %w", err)
+	}
+	output := make([]SearchHitOutputDTO, 0, len(hits))
+	for _, hit := range hits {
+		highlights := make([]HighlightDTO, 0, len(hit.Highlights))
+		for _, h := range hit.Highlights {
+			highlights = append(highlights, HighlightDTO(h))
+		}
+		output = append(output, SearchHitOutputDTO{
+			ChatID:        hit.ChatID,
+			ChatTitle:     hit.ChatTitle,
+			ChatUpdatedAt: hit.ChatUpdatedAt,
+			MessageID:     hit.Message.ID,
+			Role:          hit.Message.Role.String(),
+			CreatedAt:     hit.Message.CreatedAt,
+			Snippet:       hit.Snippet,
+			Highlights:    highlights,
+		})
+	}
+	return output, nil
+}
@@ -0,0 +1,75 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type CreateSnapshotInputDTO struct {
+	ChatID string
+	UserID string
+	Name   string
+}
+
+type SnapshotOutputDTO struct {
+	ID            string
+	ChatID        string
+	Name          string
+	LastMessageID string
+	MessageCount  int
+	TokenUsage    int
+	CreatedAt     time.Time
+}
+
+type CreateSnapshotUseCase struct {
+	ChatGateway     gateway.ChatGateway
+	SnapshotGateway gateway.SnapshotGateway
+	Clock           clock.Clock
+}
+
+func NewCreateSnapshotUseCase(chatGateway gateway.ChatGateway, snapshotGateway gateway.SnapshotGateway, clk clock.Clock) *CreateSnapshotUseCase {
+	return &CreateSnapshotUseCase{
+		ChatGateway:     chatGateway,
+		SnapshotGateway: snapshotGateway,
+		Clock:           clk,
+	}
+}
+
+func (uc *CreateSnapshotUseCase) Execute(ctx context.Context, input CreateSnapshotInputDTO) (*SnapshotOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	snapshot, err := chat.Snapshot(input.Name, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating snapshot: %w", err)
+	}
+	err = uc.SnapshotGateway.CreateSnapshot(ctx, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("error persisting snapshot: %w", err)
+	}
+	return newOutput(snapshot), nil
+}
+
+func newOutput(s *entity.Snapshot) *SnapshotOutputDTO {
+	output := &SnapshotOutputDTO{
+		ID:           s.ID,
+		ChatID:       s.ChatID,
+		Name:         s.Name,
+		MessageCount: len(s.Messages),
+		TokenUsage:   s.TokenUsage,
+		CreatedAt:    s.CreatedAt,
+	}
+	if len(s.Messages) > 0 {
+		output.LastMessageID = s.LastMessageID()
+	}
+	return output
+}
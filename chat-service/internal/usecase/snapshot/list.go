@@ -0,0 +1,45 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type ListSnapshotsInputDTO struct {
+	ChatID string
+	UserID string
+}
+
+type ListSnapshotsUseCase struct {
+	ChatGateway     gateway.ChatGateway
+	SnapshotGateway gateway.SnapshotGateway
+}
+
+func NewListSnapshotsUseCase(chatGateway gateway.ChatGateway, snapshotGateway gateway.SnapshotGateway) *ListSnapshotsUseCase {
+	return &ListSnapshotsUseCase{
+		ChatGateway:     chatGateway,
+		SnapshotGateway: snapshotGateway,
+	}
+}
+
+func (uc *ListSnapshotsUseCase) Execute(ctx context.Context, input ListSnapshotsInputDTO) ([]*SnapshotOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	snapshots, err := uc.SnapshotGateway.ListSnapshotsByChatID(ctx, chat.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing snapshots: %w", err)
+	}
+	output := make([]*SnapshotOutputDTO, 0, len(snapshots))
+	for _, s := range snapshots {
+		output = append(output, newOutput(s))
+	}
+	return output, nil
+}
@@ -0,0 +1,59 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type RestoreSnapshotInputDTO struct {
+	SnapshotID string
+	UserID     string
+}
+
+type RestoreSnapshotOutputDTO struct {
+	ChatID       string
+	ParentChatID string
+	SnapshotID   string
+}
+
+type RestoreSnapshotUseCase struct {
+	ChatGateway     gateway.ChatGateway
+	SnapshotGateway gateway.SnapshotGateway
+	Clock           clock.Clock
+}
+
+func NewRestoreSnapshotUseCase(chatGateway gateway.ChatGateway, snapshotGateway gateway.SnapshotGateway, clk clock.Clock) *RestoreSnapshotUseCase {
+	return &RestoreSnapshotUseCase{
+		ChatGateway:     chatGateway,
+		SnapshotGateway: snapshotGateway,
+		Clock:           clk,
+	}
+}
+
+// Execute restores a snapshot as a new branch of its chat; the chat itself is left untouched.
+func (uc *RestoreSnapshotUseCase) Execute(ctx context.Context, input RestoreSnapshotInputDTO) (*RestoreSnapshotOutputDTO, error) {
+	snapshot, err := uc.SnapshotGateway.FindSnapshotByID(ctx, input.SnapshotID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching snapshot: %w", err)
+	}
+	if snapshot.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	chat, err := snapshot.Restore(uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error restoring snapshot: %w", err)
+	}
+	err = uc.ChatGateway.CreateChat(ctx, chat)
+	if err != nil {
+		return nil, fmt.Errorf("error persisting restored chat: %w", err)
+	}
+	return &RestoreSnapshotOutputDTO{
+		ChatID:       chat.ID,
+		ParentChatID: chat.ParentChatID,
+		SnapshotID:   snapshot.ID,
+	}, nil
+}
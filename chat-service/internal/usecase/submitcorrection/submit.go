@@ -0,0 +1,73 @@
+package submitcorrection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type SubmitCorrectionInputDTO struct {
+	ChatID     string
+	MessageID  string
+	UserID     string
+	Statement  string
+	Correction string
+	// ApplyToUser feeds the correction into every chat of the user, not only this one.
+	ApplyToUser bool
+}
+
+type SubmitCorrectionOutputDTO struct {
+	ID    string
+	Scope string
+}
+
+type SubmitCorrectionUseCase struct {
+	ChatGateway       gateway.ChatGateway
+	CorrectionGateway gateway.CorrectionGateway
+	Clock             clock.Clock
+}
+
+func NewSubmitCorrectionUseCase(chatGateway gateway.ChatGateway, correctionGateway gateway.CorrectionGateway, clk clock.Clock) *SubmitCorrectionUseCase {
+	return &SubmitCorrectionUseCase{
+		ChatGateway:       chatGateway,
+		CorrectionGateway: correctionGateway,
+		Clock:             clk,
+	}
+}
+
+func (uc *SubmitCorrectionUseCase) Execute(ctx context.Context, input SubmitCorrectionInputDTO) (*SubmitCorrectionOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	message, err := chat.FindMessage(input.MessageID)
+	if err != nil {
+		return nil, err
+	}
+	if input.ApplyToUser && !chat.AllowsMemoryExtraction() {
+		return nil, errors.New("corrections made in ephemeral chats can't apply to the user")
+	}
+	scope := entity.CorrectionScopeChat
+	if input.ApplyToUser {
+		scope = entity.CorrectionScopeUser
+	}
+	correction, err := entity.NewCorrection(chat, message, input.Statement, input.Correction, scope, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating correction: %w", err)
+	}
+	err = uc.CorrectionGateway.CreateCorrection(ctx, correction)
+	if err != nil {
+		return nil, fmt.Errorf("error persisting correction: %w", err)
+	}
+	return &SubmitCorrectionOutputDTO{
+		ID:    correction.ID,
+		Scope: correction.Scope,
+	}, nil
+}
@@ -0,0 +1,84 @@
+package tagchat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type TagChatInputDTO struct {
+	ChatID string
+	UserID string
+	Tag    string
+}
+
+type TagChatOutputDTO struct {
+	ChatID string
+	Tags   []string
+}
+
+type AddTagUseCase struct {
+	ChatGateway gateway.ChatGateway
+}
+
+func NewAddTagUseCase(chatGateway gateway.ChatGateway) *AddTagUseCase {
+	return &AddTagUseCase{
+		ChatGateway: chatGateway,
+	}
+}
+
+func (uc *AddTagUseCase) Execute(ctx context.Context, input TagChatInputDTO) (*TagChatOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	tag, err := chat.AddTag(input.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("error adding tag: %w", err)
+	}
+	err = uc.ChatGateway.AddChatTag(ctx, chat.ID, tag)
+	if err != nil {
+		return nil, fmt.Errorf("error persisting tag: %w", err)
+	}
+	return &TagChatOutputDTO{
+		ChatID: chat.ID,
+		Tags:   chat.Tags,
+	}, nil
+}
+
+type RemoveTagUseCase struct {
+	ChatGateway gateway.ChatGateway
+}
+
+func NewRemoveTagUseCase(chatGateway gateway.ChatGateway) *RemoveTagUseCase {
+	return &RemoveTagUseCase{
+		ChatGateway: chatGateway,
+	}
+}
+
+func (uc *RemoveTagUseCase) Execute(ctx context.Context, input TagChatInputDTO) (*TagChatOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	tag, err := chat.RemoveTag(input.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("error removing tag: %w", err)
+	}
+	err = uc.ChatGateway.RemoveChatTag(ctx, chat.ID, tag)
+	if err != nil {
+		return nil, fmt.Errorf("error persisting tag removal: %w", err)
+	}
+	return &TagChatOutputDTO{
+		ChatID: chat.ID,
+		Tags:   chat.Tags,
+	}, nil
+}
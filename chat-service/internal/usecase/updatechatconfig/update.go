@@ -0,0 +1,93 @@
+package updatechatconfig
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+// UpdateChatConfigInputDTO changes the settings that are set, nil ones are kept.
+type UpdateChatConfigInputDTO struct {
+	ChatID        string
+	UserID        string
+	Temperature   *float32
+	MaxTokens     *int
+	Stop          *[]string
+	SystemMessage *string
+}
+
+type ConfigChangeOutputDTO struct {
+	Field    string
+	OldValue string
+	NewValue string
+}
+
+type UpdateChatConfigOutputDTO struct {
+	ChatID  string
+	Changes []ConfigChangeOutputDTO
+}
+
+type UpdateChatConfigUseCase struct {
+	ChatGateway      gateway.ChatGateway
+	ChatAuditGateway gateway.ChatAuditGateway
+	Clock            clock.Clock
+}
+
+func NewUpdateChatConfigUseCase(chatGateway gateway.ChatGateway, chatAuditGateway gateway.ChatAuditGateway, clk clock.Clock) *UpdateChatConfigUseCase {
+	return &UpdateChatConfigUseCase{
+		ChatGateway:      chatGateway,
+		ChatAuditGateway: chatAuditGateway,
+		Clock:            clk,
+	}
+}
+
+func (uc *UpdateChatConfigUseCase) Execute(ctx context.Context, input UpdateChatConfigInputDTO) (*UpdateChatConfigOutputDTO, error) {
+	chat, err := uc.ChatGateway.FindChatByID(ctx, input.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching chat: %w", err)
+	}
+	if chat.UserID != input.UserID {
+		return nil, entity.ErrChatForbidden
+	}
+	now := uc.Clock.Now()
+	changes, err := chat.UpdateConfig(entity.ChatConfigUpdate{
+		Temperature: input.Temperature,
+		MaxTokens:   input.MaxTokens,
+		Stop:        input.Stop,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error updating chat config: %w", err)
+	}
+	if input.SystemMessage != nil {
+		systemMessage, err := entity.NewMessage(entity.RoleSystem, *input.SystemMessage, chat.Config.Model, now)
+		if err != nil {
+			return nil, fmt.Errorf("error creating system message: %w", err)
+		}
+		change, err := chat.ChangeSystemMessage(systemMessage)
+		if err != nil {
+			return nil, fmt.Errorf("error changing system message: %w", err)
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	output := &UpdateChatConfigOutputDTO{ChatID: chat.ID}
+	if len(changes) == 0 {
+		return output, nil
+	}
+	err = uc.ChatGateway.SaveChat(ctx, chat)
+	if err != nil {
+		return nil, fmt.Errorf("error saving chat: %w", err)
+	}
+	err = uc.ChatAuditGateway.CreateConfigAuditEntry(ctx, entity.NewConfigAuditEntry(chat.ID, input.UserID, changes, now))
+	if err != nil {
+		return nil, fmt.Errorf("error persisting config audit entry: %w", err)
+	}
+	for _, change := range changes {
+		output.Changes = append(output.Changes, ConfigChangeOutputDTO(change))
+	}
+	return output, nil
+}
@@ -0,0 +1,124 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+const (
+	// defaultDays is the range of a query without From, ending with today.
+	defaultDays = 30
+	// maxDays bounds the range of a query.
+	maxDays = 366
+)
+
+// GetUsageInputDTO selects the usage of the days from From to To, both
+// included and truncated to their UTC day. The empty fields match every
+// tenant, user or model.
+type GetUsageInputDTO struct {
+	OrgID  string
+	UserID string
+	Model  string
+	// From defaults to defaultDays before To, To to today.
+	From time.Time
+	To   time.Time
+}
+
+type UsageRecordOutputDTO struct {
+	Day              time.Time
+	OrgID            string
+	UserID           string
+	Model            string
+	Turns            int64
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalTokens      int64
+	Cost             float64
+	// UnpricedTurns are the turns of a model of no known price, missing
+	// from Cost.
+	UnpricedTurns int64
+}
+
+// GetUsageOutputDTO holds the records of the range by day, then tenant, user
+// and model, and their totals under Total, which has no day nor labels.
+type GetUsageOutputDTO struct {
+	From    time.Time
+	To      time.Time
+	Records []UsageRecordOutputDTO
+	Total   UsageRecordOutputDTO
+}
+
+// GetUsageUseCase reports the tokens and cost consumed per user, tenant and
+// model and day, for operators to meter and bill them.
+type GetUsageUseCase struct {
+	UsageGateway gateway.UsageGateway
+	Clock        clock.Clock
+}
+
+func NewGetUsageUseCase(usageGateway gateway.UsageGateway, clk clock.Clock) *GetUsageUseCase {
+	return &GetUsageUseCase{
+		UsageGateway: usageGateway,
+		Clock:        clk,
+	}
+}
+
+func (uc *GetUsageUseCase) Execute(ctx context.Context, input GetUsageInputDTO) (*GetUsageOutputDTO, error) {
+	to := entity.UsageDay(uc.Clock.Now())
+	if !input.To.IsZero() {
+		to = entity.UsageDay(input.To)
+	}
+	from := to.AddDate(0, 0, -(defaultDays - 1))
+	if !input.From.IsZero() {
+		from = entity.UsageDay(input.From)
+	}
+	if from.After(to) {
+		return nil, errors.New("invalid usage range: from is after to")
+	}
+	if to.Sub(from) >= maxDays*24*time.Hour {
+		return nil, fmt.Errorf("invalid usage range: longer than %d days", maxDays)
+	}
+	records, err := uc.UsageGateway.ListUsage(ctx, gateway.UsageFilter{
+		OrgID:  input.OrgID,
+		UserID: input.UserID,
+		Model:  input.Model,
+		From:   from,
+		To:     to.AddDate(0, 0, 1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing usage: %w", err)
+	}
+	output := &GetUsageOutputDTO{
+		From:    from,
+		To:      to,
+		Records: make([]UsageRecordOutputDTO, 0, len(records)),
+	}
+	total := &entity.UsageRecord{}
+	for _, record := range records {
+		output.Records = append(output.Records, newUsageRecordOutputDTO(record))
+		total.Add(record)
+	}
+	output.Total = newUsageRecordOutputDTO(total)
+	output.Total.Day = time.Time{}
+	return output, nil
+}
+
+func newUsageRecordOutputDTO(record *entity.UsageRecord) UsageRecordOutputDTO {
+	return UsageRecordOutputDTO{
+		Day:              record.Day,
+		OrgID:            record.OrgID,
+		UserID:           record.UserID,
+		Model:            record.Model,
+		Turns:            record.Turns,
+		PromptTokens:     record.PromptTokens,
+		CompletionTokens: record.CompletionTokens,
+		TotalTokens:      record.TotalTokens(),
+		Cost:             record.Cost,
+		UnpricedTurns:    record.UnpricedTurns,
+	}
+}
@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+// deadLettersLimit is how many dead deliveries are listed.
+const deadLettersLimit = 100
+
+type ListDeadLettersUseCase struct {
+	WebhookGateway gateway.WebhookGateway
+}
+
+func NewListDeadLettersUseCase(webhookGateway gateway.WebhookGateway) *ListDeadLettersUseCase {
+	return &ListDeadLettersUseCase{
+		WebhookGateway: webhookGateway,
+	}
+}
+
+// Execute returns the latest deliveries of the webhook that ran out of
+// attempts.
+func (uc *ListDeadLettersUseCase) Execute(ctx context.Context, input WebhookInputDTO) ([]*DeliveryOutputDTO, error) {
+	if _, err := findWebhook(ctx, uc.WebhookGateway, input); err != nil {
+		return nil, err
+	}
+	deliveries, err := uc.WebhookGateway.ListDeadWebhookDeliveries(ctx, input.WebhookID, deadLettersLimit)
+	if err != nil {
+		return nil, fmt.Errorf("error listing dead deliveries: %w", err)
+	}
+	outputs := make([]*DeliveryOutputDTO, 0, len(deliveries))
+	for _, d := range deliveries {
+		outputs = append(outputs, &DeliveryOutputDTO{
+			ID:        d.ID,
+			Event:     d.Event,
+			Payload:   d.Payload,
+			Attempts:  d.Attempts,
+			LastError: d.LastError,
+			CreatedAt: d.CreatedAt,
+			FailedAt:  d.FailedAt,
+		})
+	}
+	return outputs, nil
+}
@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type DeleteWebhookUseCase struct {
+	WebhookGateway gateway.WebhookGateway
+}
+
+func NewDeleteWebhookUseCase(webhookGateway gateway.WebhookGateway) *DeleteWebhookUseCase {
+	return &DeleteWebhookUseCase{
+		WebhookGateway: webhookGateway,
+	}
+}
+
+// Execute deletes the webhook, its pending deliveries are dropped with it.
+func (uc *DeleteWebhookUseCase) Execute(ctx context.Context, input WebhookInputDTO) error {
+	if _, err := findWebhook(ctx, uc.WebhookGateway, input); err != nil {
+		return err
+	}
+	if err := uc.WebhookGateway.DeleteWebhook(ctx, input.WebhookID); err != nil {
+		return fmt.Errorf("error deleting webhook: %w", err)
+	}
+	return nil
+}
+
+// findWebhook returns the webhook of input, if it is of the tenant of input.
+func findWebhook(ctx context.Context, webhookGateway gateway.WebhookGateway, input WebhookInputDTO) (*entity.Webhook, error) {
+	webhook, err := webhookGateway.FindWebhookByID(ctx, input.WebhookID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching webhook: %w", err)
+	}
+	if webhook.OrgID != input.OrgID {
+		return nil, entity.ErrWebhookForbidden
+	}
+	return webhook, nil
+}
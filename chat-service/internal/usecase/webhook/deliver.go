@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+const defaultBatchSize = 100
+
+type DeliverWebhooksInputDTO struct {
+	BatchSize int
+}
+
+type DeliverWebhooksOutputDTO struct {
+	Delivered int
+	Failed    int
+	// Dead is how many of the failed deliveries ran out of attempts.
+	Dead int
+}
+
+type DeliverWebhooksUseCase struct {
+	WebhookGateway gateway.WebhookGateway
+	Sender         gateway.WebhookSender
+	Clock          clock.Clock
+}
+
+func NewDeliverWebhooksUseCase(webhookGateway gateway.WebhookGateway, sender gateway.WebhookSender, clk clock.Clock) *DeliverWebhooksUseCase {
+	return &DeliverWebhooksUseCase{
+		WebhookGateway: webhookGateway,
+		Sender:         sender,
+		Clock:          clk,
+	}
+}
+
+// Execute tries one batch of the deliveries due. The failed ones are tried
+// again after their backoff, until they run out of attempts.
+func (uc *DeliverWebhooksUseCase) Execute(ctx context.Context, input DeliverWebhooksInputDTO) (*DeliverWebhooksOutputDTO, error) {
+	if input.BatchSize <= 0 {
+		input.BatchSize = defaultBatchSize
+	}
+	deliveries, err := uc.WebhookGateway.FindDueWebhookDeliveries(ctx, uc.Clock.Now(), input.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching webhook deliveries: %w", err)
+	}
+	output := &DeliverWebhooksOutputDTO{}
+	webhooks := make(map[string]*entity.Webhook)
+	for _, delivery := range deliveries {
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			webhook, err = uc.WebhookGateway.FindWebhookByID(ctx, delivery.WebhookID)
+			if err != nil && !errors.Is(err, gateway.ErrWebhookNotFound) {
+				return output, fmt.Errorf("error fetching webhook: %w", err)
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+		if webhook == nil {
+			// deleted while the delivery was due
+			continue
+		}
+		if err := uc.Sender.Send(ctx, webhook, delivery); err != nil {
+			delivery.RecordFailed(err.Error(), uc.Clock.Now())
+			output.Failed++
+			if delivery.IsDead() {
+				output.Dead++
+			}
+		} else {
+			delivery.RecordDelivered(uc.Clock.Now())
+			output.Delivered++
+		}
+		if err := uc.WebhookGateway.SaveWebhookDelivery(ctx, delivery); err != nil {
+			// a delivered one will be posted again, which receivers tolerate
+			return output, fmt.Errorf("error saving webhook delivery: %w", err)
+		}
+	}
+	return output, nil
+}
+
+// Run delivers the webhooks every interval until ctx is done. Failed runs are
+// reported through onError and retried on the next tick.
+func (uc *DeliverWebhooksUseCase) Run(ctx context.Context, interval time.Duration, input DeliverWebhooksInputDTO, onError func(error)) {
+	ticker := uc.Clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if _, err := uc.Execute(ctx, input); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+)
+
+type RegisterWebhookInputDTO struct {
+	OrgID  string
+	UserID string
+	URL    string
+	Events []string
+}
+
+type WebhookInputDTO struct {
+	WebhookID string
+	OrgID     string
+}
+
+type WebhookOutputDTO struct {
+	ID        string
+	URL       string
+	Events    []string
+	CreatedBy string
+	CreatedAt time.Time
+	// Secret is only set when the webhook is registered.
+	Secret string
+}
+
+type DeliveryOutputDTO struct {
+	ID        string
+	Event     string
+	Payload   []byte
+	Attempts  int
+	LastError string
+	CreatedAt time.Time
+	FailedAt  time.Time
+}
+
+func newOutput(w *entity.Webhook) *WebhookOutputDTO {
+	return &WebhookOutputDTO{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.Events,
+		CreatedBy: w.CreatedBy,
+		CreatedAt: w.CreatedAt,
+	}
+}
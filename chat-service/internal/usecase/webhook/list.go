@@ -0,0 +1,34 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+)
+
+type ListWebhooksUseCase struct {
+	WebhookGateway gateway.WebhookGateway
+}
+
+func NewListWebhooksUseCase(webhookGateway gateway.WebhookGateway) *ListWebhooksUseCase {
+	return &ListWebhooksUseCase{
+		WebhookGateway: webhookGateway,
+	}
+}
+
+func (uc *ListWebhooksUseCase) Execute(ctx context.Context, orgID string) ([]*WebhookOutputDTO, error) {
+	if orgID == "" {
+		return nil, errors.New("webhooks need a tenant")
+	}
+	webhooks, err := uc.WebhookGateway.ListWebhooksByOrg(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhooks: %w", err)
+	}
+	outputs := make([]*WebhookOutputDTO, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		outputs = append(outputs, newOutput(webhook))
+	}
+	return outputs, nil
+}
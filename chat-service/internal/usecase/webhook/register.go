@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alecanutto/fclx/chat-service/internal/domain/entity"
+	"github.com/alecanutto/fclx/chat-service/internal/domain/gateway"
+	"github.com/alecanutto/fclx/chat-service/internal/infra/clock"
+)
+
+type RegisterWebhookUseCase struct {
+	WebhookGateway gateway.WebhookGateway
+	Clock          clock.Clock
+}
+
+func NewRegisterWebhookUseCase(webhookGateway gateway.WebhookGateway, clk clock.Clock) *RegisterWebhookUseCase {
+	return &RegisterWebhookUseCase{
+		WebhookGateway: webhookGateway,
+		Clock:          clk,
+	}
+}
+
+// Execute registers a webhook of the tenant, the output is the only time its
+// signing secret is returned.
+func (uc *RegisterWebhookUseCase) Execute(ctx context.Context, input RegisterWebhookInputDTO) (*WebhookOutputDTO, error) {
+	webhook, err := entity.NewWebhook(input.OrgID, input.UserID, input.URL, input.Events, uc.Clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error creating webhook: %w", err)
+	}
+	if err := uc.WebhookGateway.CreateWebhook(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("error persisting webhook: %w", err)
+	}
+	output := newOutput(webhook)
+	output.Secret = webhook.Secret
+	return output, nil
+}